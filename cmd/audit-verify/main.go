@@ -0,0 +1,199 @@
+// Command audit-verify walks the audit log hash chain and reports the first
+// broken link. It reads either the database configured by the usual
+// environment variables or a JSON lines export from
+// GET /api/admin/audit/export?format=jsonl.
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/ed25519"
+	"drive/internal/auditchain"
+	"drive/internal/config"
+	"drive/internal/database"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const verifyBatch = 1000
+
+func main() {
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(2)
+	}
+
+	file := flag.String("file", "", "Verify a JSON lines export instead of the database")
+	checkpointsFile := flag.String("checkpoints", "", "Checkpoints exported from /api/admin/audit/checkpoints (defaults to the database checkpoints when reading the database)")
+	hmacKey := flag.String("hmac-key", cfg.Audit.HMACKey, "Server HMAC key; empty skips HMAC checks")
+	publicKey := flag.String("public-key", "", "Base64 Ed25519 public key for checkpoints (defaults to the key derived from AUDIT_SIGNING_KEY)")
+	flag.Parse()
+
+	verifier := auditchain.NewVerifier([]byte(*hmacKey))
+
+	key, err := checkpointKey(*publicKey, cfg.Audit.SigningKey)
+	if err != nil {
+		fmt.Printf("Invalid checkpoint key: %v\n", err)
+		os.Exit(2)
+	}
+
+	if *file != "" {
+		err = verifyFile(verifier, *file, *checkpointsFile, key)
+	} else {
+		err = verifyDatabase(cfg, verifier, *checkpointsFile, key)
+	}
+	if err == nil {
+		err = verifier.Finish()
+	}
+
+	var broken *auditchain.BrokenLinkError
+	switch {
+	case errors.As(err, &broken):
+		fmt.Printf("FAILED after %d valid entries: %v\n", verifier.Count(), broken)
+		os.Exit(1)
+	case err != nil:
+		fmt.Printf("Verification error: %v\n", err)
+		os.Exit(2)
+	}
+
+	fmt.Printf("OK: %d entries verified, %d checkpoints confirmed\n", verifier.Count(), verifier.CheckpointsMatched())
+}
+
+// checkpointKey resolves the public key used to verify checkpoints
+func checkpointKey(publicKey, signingKey string) (ed25519.PublicKey, error) {
+	if publicKey != "" {
+		return auditchain.ParsePublicKey(publicKey)
+	}
+	private, err := auditchain.ParseSigningKey(signingKey)
+	if err != nil || private == nil {
+		return nil, err
+	}
+	return private.Public().(ed25519.PublicKey), nil
+}
+
+// verifyDatabase walks the chain stored in the database
+func verifyDatabase(cfg *config.Config, verifier *auditchain.Verifier, checkpointsFile string, key ed25519.PublicKey) error {
+	logger := util.NewLogger(zapcore.WarnLevel)
+	db, err := database.InitDatabase(cfg, logger)
+	if err != nil {
+		return err
+	}
+	repo := repository.NewAuditRepository(db)
+	ctx := context.Background()
+
+	var checkpoints []model.AuditCheckpoint
+	if checkpointsFile != "" {
+		checkpoints, err = readCheckpoints(checkpointsFile)
+	} else {
+		checkpoints, err = repo.ListCheckpoints(ctx)
+	}
+	if err != nil {
+		return err
+	}
+	if err := addCheckpoints(verifier, checkpoints, key); err != nil {
+		return err
+	}
+
+	filter := &model.AuditLogFilter{}
+	var afterID uint
+	for {
+		entries, err := repo.ListAfter(ctx, filter, afterID, verifyBatch)
+		if err != nil {
+			return err
+		}
+		for i := range entries {
+			if err := verifier.Verify(&entries[i]); err != nil {
+				return err
+			}
+			afterID = entries[i].ID
+		}
+		if len(entries) < verifyBatch {
+			return nil
+		}
+	}
+}
+
+// verifyFile walks the chain in a JSON lines export
+func verifyFile(verifier *auditchain.Verifier, path, checkpointsFile string, key ed25519.PublicKey) error {
+	if checkpointsFile != "" {
+		checkpoints, err := readCheckpoints(checkpointsFile)
+		if err != nil {
+			return err
+		}
+		if err := addCheckpoints(verifier, checkpoints, key); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(f))
+	for {
+		var entry model.AuditLog
+		if err := decoder.Decode(&entry); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("error reading %s: %w", path, err)
+		}
+		if err := verifier.Verify(&entry); err != nil {
+			return err
+		}
+	}
+}
+
+// addCheckpoints verifies each checkpoint signature and registers it with
+// the verifier
+func addCheckpoints(verifier *auditchain.Verifier, checkpoints []model.AuditCheckpoint, key ed25519.PublicKey) error {
+	if len(checkpoints) == 0 {
+		return nil
+	}
+	if key == nil {
+		return errors.New("checkpoints found but no public key; pass -public-key or set AUDIT_SIGNING_KEY")
+	}
+	for i := range checkpoints {
+		if err := verifier.AddCheckpoint(&checkpoints[i], key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readCheckpoints loads a checkpoint export, accepting either the API
+// response envelope or the bare export object
+func readCheckpoints(path string) ([]model.AuditCheckpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope struct {
+		Data *model.AuditCheckpointExport `json:"data"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	if envelope.Data != nil {
+		return envelope.Data.Checkpoints, nil
+	}
+
+	var export model.AuditCheckpointExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	return export.Checkpoints, nil
+}
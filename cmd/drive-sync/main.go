@@ -0,0 +1,97 @@
+// Command drive-sync keeps a local directory in two-way sync with a folder
+// in the drive. It watches the directory for changes (polling where file
+// system notifications are unavailable), follows the server's change feed,
+// and keeps its state in .drive-sync inside the directory. When a file was
+// changed on both sides the local version is kept as a conflicted copy.
+//
+// It uses the server and tokens saved by drivectl login.
+//
+// Usage:
+//
+//	drive-sync [flags] DIRECTORY
+package main
+
+import (
+	"context"
+	"drive/internal/syncer"
+	"drive/internal/util"
+	"drive/pkg/client"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func main() {
+	configPath := flag.String("config", client.DefaultConfigPath(), "Config file written by drivectl login")
+	server := flag.String("server", "", "Server URL (defaults to the server logged in to)")
+	remote := flag.String("remote", "/", "Drive folder to sync with, created if missing")
+	stateDir := flag.String("state", "", "State directory (defaults to .drive-sync inside DIRECTORY)")
+	interval := flag.Duration("interval", 0, "Time between full scans (defaults to 5m with file system notifications, 10s without)")
+	poll := flag.Bool("poll", false, "Poll instead of using file system notifications")
+	once := flag.Bool("once", false, "Sync once and exit")
+	logLevel := flag.String("log-level", "info", "Log level: debug, info, warn or error")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: drive-sync [flags] DIRECTORY\n\nFlags:\n")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	level, err := zapcore.ParseLevel(*logLevel)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "drive-sync: %v\n", err)
+		os.Exit(2)
+	}
+	logger := util.NewLogger(level)
+
+	cfg, err := client.LoadConfig(*configPath)
+	if err != nil {
+		logger.Fatal("Failed to load config", util.WithError(err))
+	}
+	if *server != "" {
+		cfg.Server = *server
+	}
+	if cfg.Server == "" || cfg.RefreshToken == "" {
+		fmt.Fprintln(os.Stderr, "drive-sync: not logged in; run drivectl login first")
+		os.Exit(1)
+	}
+	c := cfg.NewClient(func(err error) {
+		logger.Warn("Failed to save refreshed tokens", util.WithError(err))
+	})
+
+	engine, err := syncer.New(c, syncer.Options{
+		LocalDir:       flag.Arg(0),
+		RemotePath:     *remote,
+		StateDir:       *stateDir,
+		PollInterval:   *interval,
+		DisableWatcher: *poll,
+	}, logger)
+	if err != nil {
+		logger.Fatal("Failed to start sync", util.WithError(err))
+	}
+	defer engine.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *once {
+		if err := engine.SyncOnce(ctx); err != nil {
+			logger.Error("Sync failed", util.WithError(err))
+			engine.Close()
+			os.Exit(1)
+		}
+		return
+	}
+
+	logger.Info("Syncing", util.WithPath(flag.Arg(0)))
+	engine.Run(ctx)
+	logger.Info("Stopped")
+}
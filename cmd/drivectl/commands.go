@@ -0,0 +1,606 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"drive/pkg/client"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// newFlagSet creates the flag set for a subcommand; errors are reported by
+// the caller
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return fs
+}
+
+// parseFlags parses a subcommand's flags, turning mistakes into usage errors
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if err := fs.Parse(args); err != nil {
+		return usageError(err.Error())
+	}
+	return nil
+}
+
+func runLogin(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("login")
+	email := fs.String("email", a.cfg.Email, "Account email")
+	passwordStdin := fs.Bool("password-stdin", false, "Read the password from standard input")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	stdin := bufio.NewReader(os.Stdin)
+	if *email == "" {
+		if *passwordStdin || !isTerminal(os.Stdin) {
+			return usageError("-email is required when not running interactively")
+		}
+		fmt.Fprint(os.Stderr, "Email: ")
+		line, err := stdin.ReadString('\n')
+		if err != nil && line == "" {
+			return err
+		}
+		*email = strings.TrimSpace(line)
+	}
+
+	var password string
+	if *passwordStdin {
+		data, err := io.ReadAll(stdin)
+		if err != nil {
+			return fmt.Errorf("reading password: %w", err)
+		}
+		password = strings.TrimRight(string(data), "\r\n")
+	} else {
+		if !isTerminal(os.Stdin) {
+			return usageError("use -password-stdin when not running interactively")
+		}
+		fmt.Fprint(os.Stderr, "Password: ")
+		var err error
+		password, err = readPassword(os.Stdin)
+		fmt.Fprintln(os.Stderr)
+		if err != nil {
+			return fmt.Errorf("reading password: %w", err)
+		}
+	}
+
+	user, err := a.client.Login(ctx, *email, password)
+	if err != nil {
+		return err
+	}
+	a.cfg.Email = user.Email
+	if err := a.cfg.Save(); err != nil {
+		return err
+	}
+
+	if a.jsonOut {
+		return printJSON(user)
+	}
+	fmt.Printf("Logged in to %s as %s\n", a.cfg.Server, user.Email)
+	return nil
+}
+
+func runList(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("ls")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() > 1 {
+		return usageError("ls takes at most one path")
+	}
+
+	contents, err := a.client.List(ctx, fs.Arg(0))
+	if errors.Is(err, client.ErrNotFolder) {
+		// Listing a file shows just that file, like ls(1)
+		entry, statErr := a.client.Stat(ctx, fs.Arg(0))
+		if statErr != nil {
+			return statErr
+		}
+		contents = &client.FolderContents{Files: []client.File{*entry.File}}
+	} else if err != nil {
+		return err
+	}
+
+	if a.jsonOut {
+		return printJSON(contents)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, folder := range contents.Folders {
+		fmt.Fprintf(tw, "%d\t-\t%s\t%s/\n", folder.ID, formatTime(folder.UpdatedAt), folder.FolderName)
+	}
+	for _, file := range contents.Files {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", file.ID, formatSize(file.FileSize), formatTime(file.UpdatedAt), file.FileName)
+	}
+	return tw.Flush()
+}
+
+func runUpload(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("upload")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError("upload needs at least one file")
+	}
+
+	locals, remote := fs.Args(), "/"
+	if len(locals) > 1 {
+		locals, remote = locals[:len(locals)-1], locals[len(locals)-1]
+	}
+	folder, err := a.folderAt(ctx, remote)
+	if err != nil {
+		return err
+	}
+
+	uploaded := make([]*client.File, 0, len(locals))
+	for _, local := range locals {
+		file, err := a.upload(ctx, folder.ID, local)
+		if err != nil {
+			return fmt.Errorf("%s: %w", local, err)
+		}
+		uploaded = append(uploaded, file)
+		if !a.jsonOut {
+			fmt.Printf("Uploaded %s (%s) as file %d\n", file.FileName, formatSize(file.FileSize), file.ID)
+		}
+	}
+
+	if a.jsonOut {
+		return printJSON(uploaded)
+	}
+	return nil
+}
+
+// upload sends one local file into a folder
+func (a *app) upload(ctx context.Context, folderID uint, local string) (*client.File, error) {
+	f, err := os.Open(local)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, errors.New("is a directory")
+	}
+
+	name := filepath.Base(local)
+	bar := newProgress(a.progress, name, info.Size())
+	file, err := a.client.Upload(ctx, folderID, name, info.Size(), bar.reader(f))
+	bar.finish()
+	return file, err
+}
+
+func runDownload(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("download")
+	resume := fs.Bool("c", false, "Continue a partial download")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return usageError("download takes a remote file and an optional local path")
+	}
+
+	entry, err := a.client.Stat(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if entry.IsDir() {
+		return fmt.Errorf("%s: is a folder", fs.Arg(0))
+	}
+	file := entry.File
+
+	local := fs.Arg(1)
+	if local == "" {
+		local = file.FileName
+	} else if info, err := os.Stat(local); err == nil && info.IsDir() {
+		local = filepath.Join(local, file.FileName)
+	}
+
+	var out io.Writer = os.Stdout
+	var offset int64
+	if local != "-" {
+		flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+		if *resume {
+			if info, err := os.Stat(local); err == nil {
+				offset = info.Size()
+				flags = os.O_WRONLY | os.O_APPEND
+			}
+		}
+		if offset > 0 && offset >= file.FileSize {
+			return a.downloaded(file, local, 0)
+		}
+		f, err := os.OpenFile(local, flags, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	body, _, err := a.client.Download(ctx, file.ID, offset)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	bar := newProgress(a.progress && local != "-", file.FileName, file.FileSize)
+	if bar != nil {
+		bar.done = offset
+	}
+	n, err := io.Copy(out, bar.reader(body))
+	bar.finish()
+	if err != nil {
+		return err
+	}
+	if f, ok := out.(*os.File); ok && f != os.Stdout {
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+	return a.downloaded(file, local, n)
+}
+
+// downloaded reports a finished download
+func (a *app) downloaded(file *client.File, local string, written int64) error {
+	if a.jsonOut {
+		return printJSON(map[string]interface{}{"file": file, "path": local, "bytes_written": written})
+	}
+	if local != "-" {
+		fmt.Fprintf(os.Stderr, "Downloaded %s to %s\n", file.FileName, local)
+	}
+	return nil
+}
+
+func runMkdir(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("mkdir")
+	parents := fs.Bool("p", false, "Create missing parent folders and accept existing folders")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError("mkdir needs at least one path")
+	}
+
+	created := make([]*client.Folder, 0, fs.NArg())
+	for _, p := range fs.Args() {
+		var folder *client.Folder
+		var err error
+		if *parents {
+			folder, err = a.client.MkdirAll(ctx, p)
+		} else {
+			folder, err = a.mkdir(ctx, p)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		created = append(created, folder)
+	}
+
+	if a.jsonOut {
+		return printJSON(created)
+	}
+	return nil
+}
+
+// mkdir creates one folder inside an existing parent
+func (a *app) mkdir(ctx context.Context, p string) (*client.Folder, error) {
+	parentPath, name := path.Split(path.Clean("/" + p))
+	if name == "" {
+		return nil, errors.New("the root folder already exists")
+	}
+	if _, err := a.client.Stat(ctx, p); err == nil {
+		return nil, errors.New("already exists")
+	} else if !client.IsNotFound(err) {
+		return nil, err
+	}
+
+	parent, err := a.folderAt(ctx, parentPath)
+	if err != nil {
+		return nil, err
+	}
+	return a.client.CreateFolder(ctx, parent.ID, name)
+}
+
+func runMove(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("mv")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return usageError("mv takes a source and a destination")
+	}
+
+	source, err := a.client.Stat(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	// Moving onto an existing folder moves the source into it; otherwise
+	// the destination names the new parent and name
+	var parentID uint
+	var name string
+	dest, err := a.client.Stat(ctx, fs.Arg(1))
+	switch {
+	case err == nil && dest.IsDir():
+		parentID = dest.Folder.ID
+	case err == nil:
+		return fmt.Errorf("%s: already exists", fs.Arg(1))
+	case client.IsNotFound(err):
+		parentPath, base := path.Split(path.Clean("/" + fs.Arg(1)))
+		parent, err := a.folderAt(ctx, parentPath)
+		if err != nil {
+			return err
+		}
+		parentID, name = parent.ID, base
+	default:
+		return err
+	}
+
+	if source.IsDir() {
+		req := &client.UpdateFolderRequest{}
+		if name != "" && name != source.Folder.FolderName {
+			req.Name = &name
+		}
+		if source.Folder.ParentFolderID == nil || *source.Folder.ParentFolderID != parentID {
+			req.ParentID = &parentID
+		}
+		folder, err := a.client.UpdateFolder(ctx, source.Folder.ID, req)
+		if err != nil {
+			return err
+		}
+		if a.jsonOut {
+			return printJSON(folder)
+		}
+		return nil
+	}
+
+	req := &client.UpdateFileRequest{}
+	if name != "" && name != source.File.FileName {
+		req.Name = &name
+	}
+	if source.File.FolderID != parentID {
+		req.FolderID = &parentID
+	}
+	file, err := a.client.UpdateFile(ctx, source.File.ID, req)
+	if err != nil {
+		return err
+	}
+	if a.jsonOut {
+		return printJSON(file)
+	}
+	return nil
+}
+
+func runRemove(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("rm")
+	recursive := fs.Bool("r", false, "Remove folders and their contents")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError("rm needs at least one path")
+	}
+
+	removed := make([]*client.Entry, 0, fs.NArg())
+	for _, p := range fs.Args() {
+		entry, err := a.client.Stat(ctx, p)
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			if !*recursive {
+				return fmt.Errorf("%s: is a folder (use -r)", p)
+			}
+			err = a.client.DeleteFolder(ctx, entry.Folder.ID)
+		} else {
+			err = a.client.DeleteFile(ctx, entry.File.ID)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", p, err)
+		}
+		removed = append(removed, entry)
+	}
+
+	if a.jsonOut {
+		return printJSON(removed)
+	}
+	return nil
+}
+
+func runShare(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("share")
+	write := fs.Bool("write", false, "Allow the recipient to change the contents")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return usageError("share takes a path and an email")
+	}
+
+	entry, err := a.client.Stat(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	req := &client.ShareRequest{SharedWithEmail: fs.Arg(1), Permission: "read"}
+	if *write {
+		req.Permission = "write"
+	}
+	if entry.IsDir() {
+		req.FolderID = entry.Folder.ID
+	} else {
+		req.FileID = entry.File.ID
+	}
+
+	share, err := a.client.CreateShare(ctx, req)
+	if err != nil {
+		return err
+	}
+	if a.jsonOut {
+		return printJSON(share)
+	}
+	fmt.Printf("Shared %s with %s (%s)\n", fs.Arg(0), fs.Arg(1), share.Permission)
+	return nil
+}
+
+func runLock(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("lock")
+	shared := fs.Bool("shared", false, "Take a shared lock, which others may also hold")
+	note := fs.String("note", "", "Note shown to others, e.g. what you are working on")
+	duration := fs.Duration("for", 0, "How long the lock lasts (default 1h, at most 168h)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("lock takes a file")
+	}
+
+	file, err := a.fileAt(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	req := &client.LockRequest{Note: *note, DurationSeconds: int64(duration.Seconds())}
+	if *shared {
+		req.Scope = "shared"
+	}
+	lock, err := a.client.LockFile(ctx, file.ID, req)
+	if err != nil {
+		return err
+	}
+
+	if a.jsonOut {
+		return printJSON(lock)
+	}
+	fmt.Printf("Locked %s (%s) until %s\n", fs.Arg(0), lock.Scope, formatTime(lock.ExpiresAt))
+	return nil
+}
+
+func runUnlock(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("unlock")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("unlock takes a file")
+	}
+
+	file, err := a.fileAt(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	return a.client.UnlockFile(ctx, file.ID)
+}
+
+func runLocks(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("locks")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("locks takes a file")
+	}
+
+	file, err := a.fileAt(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	locks, err := a.client.FileLocks(ctx, file.ID)
+	if err != nil {
+		return err
+	}
+
+	if a.jsonOut {
+		return printJSON(locks)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, lock := range locks {
+		holder := fmt.Sprintf("user %d", lock.UserID)
+		if lock.User != nil {
+			holder = lock.User.Email
+		}
+		fmt.Fprintf(tw, "%s\t%s\tuntil %s\t%s\n", holder, lock.Scope, formatTime(lock.ExpiresAt), lock.Note)
+	}
+	return tw.Flush()
+}
+
+func runSearch(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("search")
+	fileType := fs.String("type", "", "Only files of this type: image, video, audio, document or other")
+	page := fs.Int("page", 1, "Page of results")
+	perPage := fs.Int("per-page", 20, "Results per page (at most 100)")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return usageError("search needs a query")
+	}
+
+	results, meta, err := a.client.Search(ctx, &client.SearchQuery{
+		Query:    strings.Join(fs.Args(), " "),
+		FileType: *fileType,
+		Page:     *page,
+		PerPage:  *perPage,
+	})
+	if err != nil {
+		return err
+	}
+
+	if a.jsonOut {
+		return printJSON(map[string]interface{}{"results": results, "meta": meta})
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, result := range results {
+		size := "-"
+		if result.Type == "file" {
+			size = formatSize(result.FileSize)
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\n", result.Type, result.ID, size, result.Name)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if meta != nil && meta.TotalPages > 1 {
+		fmt.Fprintf(os.Stderr, "Page %d of %d (%d results)\n", meta.Page, meta.TotalPages, meta.TotalCount)
+	}
+	return nil
+}
+
+// folderAt resolves a path that must name a folder
+func (a *app) folderAt(ctx context.Context, p string) (*client.Folder, error) {
+	entry, err := a.client.Stat(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.IsDir() {
+		return nil, fmt.Errorf("%s: %w", p, client.ErrNotFolder)
+	}
+	return entry.Folder, nil
+}
+
+// fileAt resolves a path that must name a file
+func (a *app) fileAt(ctx context.Context, p string) (*client.File, error) {
+	entry, err := a.client.Stat(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if entry.IsDir() {
+		return nil, fmt.Errorf("%s: is a folder", p)
+	}
+	return entry.File, nil
+}
+
+// formatTime renders a timestamp in local time
+func formatTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04")
+}
@@ -0,0 +1,152 @@
+// Command drivectl is a command-line client for the drive server. It logs in
+// once, keeps the tokens in a config file readable only by the user and
+// refreshes them as they expire. Paths are slash-separated and start at the
+// user's root folder; -json prints machine-readable output for scripts.
+//
+// Usage:
+//
+//	drivectl [-server URL] [-config FILE] [-json] <command> [arguments]
+package main
+
+import (
+	"context"
+	"drive/pkg/client"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+)
+
+// app carries the global options and the API client into the commands
+type app struct {
+	cfg      *client.Config
+	client   *client.Client
+	jsonOut  bool
+	progress bool
+}
+
+// command is a drivectl subcommand
+type command struct {
+	usage   string
+	summary string
+	run     func(ctx context.Context, a *app, args []string) error
+}
+
+var commands = map[string]*command{
+	"login":    {"login [-email EMAIL] [-password-stdin]", "Sign in and store the tokens", runLogin},
+	"ls":       {"ls [PATH]", "List a folder", runList},
+	"upload":   {"upload LOCAL_FILE... [REMOTE_FOLDER]", "Upload files into a folder", runUpload},
+	"download": {"download [-c] REMOTE_FILE [LOCAL_PATH|-]", "Download a file", runDownload},
+	"mkdir":    {"mkdir [-p] PATH...", "Create folders", runMkdir},
+	"mv":       {"mv SOURCE DESTINATION", "Rename or move a file or folder", runMove},
+	"rm":       {"rm [-r] PATH...", "Move files or folders to the trash", runRemove},
+	"share":    {"share [-write] PATH EMAIL", "Share a file or folder with another user", runShare},
+	"lock":     {"lock [-shared] [-note NOTE] [-for DURATION] FILE", "Check out a file so others cannot change it", runLock},
+	"unlock":   {"unlock FILE", "Release your lock on a file", runUnlock},
+	"locks":    {"locks FILE", "Show who has a file checked out", runLocks},
+	"search":   {"search [-type TYPE] [-page N] [-per-page N] QUERY", "Search files and folders", runSearch},
+	"vault":    {"vault init|create|ls|mkdir|upload|download|share [arguments]", "Work with end-to-end encrypted vaults", runVault},
+}
+
+func main() {
+	flag.Usage = usage
+	server := flag.String("server", os.Getenv("DRIVE_SERVER"), "Server URL (defaults to the server logged in to, then http://localhost:8080)")
+	configPath := flag.String("config", client.DefaultConfigPath(), "Config file holding the server and tokens")
+	jsonOut := flag.Bool("json", false, "Print JSON output for scripting")
+	quiet := flag.Bool("quiet", false, "Do not draw progress bars")
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[flag.Arg(0)]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "drivectl: unknown command %q\n\n", flag.Arg(0))
+		usage()
+		os.Exit(2)
+	}
+
+	cfg, err := client.LoadConfig(*configPath)
+	if err != nil {
+		fail(*jsonOut, err)
+	}
+	if *server != "" {
+		cfg.Server = *server
+	}
+	if cfg.Server == "" {
+		cfg.Server = "http://localhost:8080"
+	}
+
+	a := &app{
+		cfg:      cfg,
+		jsonOut:  *jsonOut,
+		progress: !*jsonOut && !*quiet,
+	}
+	a.client = cfg.NewClient(func(err error) {
+		fmt.Fprintf(os.Stderr, "drivectl: warning: %v\n", err)
+	})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := cmd.run(ctx, a, flag.Args()[1:]); err != nil {
+		var usageErr usageError
+		if errors.As(err, &usageErr) {
+			fmt.Fprintf(os.Stderr, "drivectl: %v\nusage: drivectl %s\n", err, cmd.usage)
+			os.Exit(2)
+		}
+		if errors.Is(err, client.ErrNotAuthenticated) {
+			err = errors.New("not logged in; run drivectl login")
+		}
+		fail(*jsonOut, err)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: drivectl [flags] <command> [arguments]\n\nCommands:\n")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nFlags:\n")
+	flag.PrintDefaults()
+}
+
+// usageError is a mistake in the command line
+type usageError string
+
+func (e usageError) Error() string {
+	return string(e)
+}
+
+// fail reports an error and exits
+func fail(jsonOut bool, err error) {
+	if jsonOut {
+		out := map[string]interface{}{"error": err.Error()}
+		var apiErr *client.APIError
+		if errors.As(err, &apiErr) {
+			out["status"] = apiErr.StatusCode
+			out["code"] = apiErr.Code
+		}
+		json.NewEncoder(os.Stderr).Encode(out)
+	} else {
+		fmt.Fprintf(os.Stderr, "drivectl: %v\n", err)
+	}
+	os.Exit(1)
+}
+
+// printJSON writes v to stdout as indented JSON
+func printJSON(v interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,13 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package main
+
+import (
+	"errors"
+	"os"
+)
+
+// readPassword is not supported here; use -password-stdin instead
+func readPassword(tty *os.File) (string, error) {
+	return "", errors.New("reading a password from the terminal is not supported on this platform; use -password-stdin")
+}
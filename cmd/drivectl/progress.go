@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	progressWidth    = 30
+	progressInterval = 100 * time.Millisecond
+)
+
+// progress draws a transfer progress bar on a terminal. A nil progress
+// draws nothing, so callers need not check whether output is enabled.
+type progress struct {
+	out   io.Writer
+	label string
+	total int64
+
+	mu      sync.Mutex
+	done    int64
+	started time.Time
+	drawn   time.Time
+}
+
+// newProgress returns a progress bar for a transfer of total bytes (-1 if
+// unknown), or nil when stderr is not a terminal or enabled is false
+func newProgress(enabled bool, label string, total int64) *progress {
+	if !enabled || !isTerminal(os.Stderr) {
+		return nil
+	}
+	return &progress{out: os.Stderr, label: label, total: total, started: time.Now()}
+}
+
+// reader counts bytes read from r towards the transfer
+func (p *progress) reader(r io.Reader) io.Reader {
+	if p == nil {
+		return r
+	}
+	return &progressReader{r: r, p: p}
+}
+
+func (p *progress) add(n int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.done += int64(n)
+	if time.Since(p.drawn) >= progressInterval {
+		p.draw()
+	}
+}
+
+// finish draws the final state and ends the line
+func (p *progress) finish() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.draw()
+	fmt.Fprintln(p.out)
+}
+
+func (p *progress) draw() {
+	p.drawn = time.Now()
+	elapsed := p.drawn.Sub(p.started).Seconds()
+	rate := ""
+	if elapsed > 0 {
+		rate = formatSize(int64(float64(p.done)/elapsed)) + "/s"
+	}
+
+	if p.total <= 0 {
+		fmt.Fprintf(p.out, "\r%s  %s  %s\033[K", p.label, formatSize(p.done), rate)
+		return
+	}
+	fraction := float64(p.done) / float64(p.total)
+	if fraction > 1 {
+		fraction = 1
+	}
+	filled := int(fraction * progressWidth)
+	bar := strings.Repeat("=", filled) + strings.Repeat(" ", progressWidth-filled)
+	if filled < progressWidth && filled > 0 {
+		bar = bar[:filled-1] + ">" + bar[filled:]
+	}
+	fmt.Fprintf(p.out, "\r%s [%s] %3.0f%%  %s/%s  %s\033[K",
+		p.label, bar, fraction*100, formatSize(p.done), formatSize(p.total), rate)
+}
+
+type progressReader struct {
+	r io.Reader
+	p *progress
+}
+
+func (r *progressReader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	if n > 0 {
+		r.p.add(n)
+	}
+	return n, err
+}
+
+// isTerminal reports whether f is a character device such as a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// formatSize renders a byte count with a binary unit
+func formatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,349 @@
+package main
+
+import (
+	"context"
+	"drive/pkg/client"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"text/tabwriter"
+)
+
+// vaultCommands are the subcommands of drivectl vault. Paths use the names
+// vault members see; they are encrypted and decrypted on this machine.
+var vaultCommands = map[string]func(ctx context.Context, a *app, args []string) error{
+	"init":     runVaultInit,
+	"create":   runVaultCreate,
+	"ls":       runVaultList,
+	"mkdir":    runVaultMkdir,
+	"upload":   runVaultUpload,
+	"download": runVaultDownload,
+	"share":    runVaultShare,
+}
+
+func runVault(ctx context.Context, a *app, args []string) error {
+	if len(args) == 0 {
+		return usageError("vault needs a subcommand")
+	}
+	run, ok := vaultCommands[args[0]]
+	if !ok {
+		return usageError(fmt.Sprintf("unknown vault subcommand %q", args[0]))
+	}
+	return run(ctx, a, args[1:])
+}
+
+// runVaultInit creates the user's vault identity and registers its public
+// key, so vaults can be created and shared with them
+func runVaultInit(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("vault init")
+	force := fs.Bool("force", false, "Replace an existing identity; vaults shared with the old one become unreadable")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	// Without -force an existing identity is kept and its key published
+	// again, in case registration failed the first time
+	id, err := a.identity()
+	if err != nil && !errors.Is(err, client.ErrNoIdentity) && !*force {
+		return err
+	}
+	if err != nil || *force {
+		if id, err = client.GenerateIdentity(); err != nil {
+			return err
+		}
+		a.cfg.VaultKey = id.String()
+		if err := a.cfg.Save(); err != nil {
+			return err
+		}
+	}
+
+	key, err := a.client.RegisterPublicKey(ctx, id)
+	if err != nil {
+		return err
+	}
+	if a.jsonOut {
+		return printJSON(key)
+	}
+	fmt.Println("Vault identity registered; back up the vault_key in your config file, vaults cannot be opened without it")
+	return nil
+}
+
+func runVaultCreate(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("vault create")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("vault create takes a path")
+	}
+	id, err := a.identity()
+	if err != nil {
+		return err
+	}
+
+	parentPath, name := path.Split(path.Clean("/" + fs.Arg(0)))
+	if name == "" {
+		return usageError("vault create needs a folder name")
+	}
+	parent, err := a.folderAt(ctx, parentPath)
+	if err != nil {
+		return err
+	}
+	_, folder, err := a.client.CreateVault(ctx, id, parent.ID, name)
+	if err != nil {
+		return err
+	}
+
+	if a.jsonOut {
+		return printJSON(folder)
+	}
+	fmt.Printf("Created vault %s as folder %d\n", fs.Arg(0), folder.ID)
+	return nil
+}
+
+func runVaultList(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("vault ls")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("vault ls takes a path inside a vault")
+	}
+
+	folder, vault, err := a.vaultFolderAt(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	contents, err := vault.List(ctx, folder.ID)
+	if err != nil {
+		return err
+	}
+
+	if a.jsonOut {
+		return printJSON(contents)
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, folder := range contents.Folders {
+		fmt.Fprintf(tw, "%d\t-\t%s\t%s/\n", folder.ID, formatTime(folder.UpdatedAt), folder.FolderName)
+	}
+	for _, file := range contents.Files {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\n", file.ID, formatSize(file.FileSize), formatTime(file.UpdatedAt), file.FileName)
+	}
+	return tw.Flush()
+}
+
+func runVaultMkdir(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("vault mkdir")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return usageError("vault mkdir takes a path inside a vault")
+	}
+
+	parentPath, name := path.Split(path.Clean("/" + fs.Arg(0)))
+	parent, vault, err := a.vaultFolderAt(ctx, parentPath)
+	if err != nil {
+		return err
+	}
+	folder, err := vault.Mkdir(ctx, parent.ID, name)
+	if err != nil {
+		return err
+	}
+
+	if a.jsonOut {
+		return printJSON(folder)
+	}
+	return nil
+}
+
+func runVaultUpload(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("vault upload")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return usageError("vault upload takes local files and a folder inside a vault")
+	}
+
+	locals, remote := fs.Args()[:fs.NArg()-1], fs.Arg(fs.NArg()-1)
+	folder, vault, err := a.vaultFolderAt(ctx, remote)
+	if err != nil {
+		return err
+	}
+
+	uploaded := make([]*client.File, 0, len(locals))
+	for _, local := range locals {
+		file, err := a.vaultUpload(ctx, vault, folder.ID, local)
+		if err != nil {
+			return fmt.Errorf("%s: %w", local, err)
+		}
+		uploaded = append(uploaded, file)
+		if !a.jsonOut {
+			fmt.Printf("Uploaded %s (%s) as file %d\n", file.FileName, formatSize(file.FileSize), file.ID)
+		}
+	}
+
+	if a.jsonOut {
+		return printJSON(uploaded)
+	}
+	return nil
+}
+
+// vaultUpload encrypts and sends one local file into a vault folder
+func (a *app) vaultUpload(ctx context.Context, vault *client.Vault, folderID uint, local string) (*client.File, error) {
+	f, err := os.Open(local)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return nil, errors.New("is a directory")
+	}
+
+	name := filepath.Base(local)
+	bar := newProgress(a.progress, name, info.Size())
+	file, err := vault.Upload(ctx, folderID, name, info.Size(), bar.reader(f))
+	bar.finish()
+	return file, err
+}
+
+func runVaultDownload(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("vault download")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() < 1 || fs.NArg() > 2 {
+		return usageError("vault download takes a file inside a vault and an optional local path")
+	}
+
+	entry, vault, err := a.vaultStat(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if entry.IsDir() {
+		return fmt.Errorf("%s: is a folder", fs.Arg(0))
+	}
+	file := entry.File
+
+	local := fs.Arg(1)
+	if local == "" {
+		local = file.FileName
+	} else if info, err := os.Stat(local); err == nil && info.IsDir() {
+		local = filepath.Join(local, file.FileName)
+	}
+
+	body, err := vault.Download(ctx, file.ID)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	var out io.Writer = os.Stdout
+	if local != "-" {
+		// Decrypt into a temporary file so tampered content is never left
+		// behind under the real name
+		f, err := os.CreateTemp(filepath.Dir(local), ".drivectl-*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(f.Name())
+		defer f.Close()
+		out = f
+	}
+
+	bar := newProgress(a.progress && local != "-", file.FileName, file.FileSize)
+	n, err := io.Copy(out, bar.reader(body))
+	bar.finish()
+	if err != nil {
+		return err
+	}
+	if f, ok := out.(*os.File); ok && f != os.Stdout {
+		if err := f.Close(); err != nil {
+			return err
+		}
+		if err := os.Rename(f.Name(), local); err != nil {
+			return err
+		}
+	}
+	return a.downloaded(file, local, n)
+}
+
+func runVaultShare(ctx context.Context, a *app, args []string) error {
+	fs := newFlagSet("vault share")
+	write := fs.Bool("write", false, "Allow the recipient to change the contents")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 2 {
+		return usageError("vault share takes a path inside a vault and an email")
+	}
+
+	entry, vault, err := a.vaultStat(ctx, fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	req := &client.ShareRequest{SharedWithEmail: fs.Arg(1), Permission: "read"}
+	if *write {
+		req.Permission = "write"
+	}
+	if entry.IsDir() {
+		req.FolderID = entry.Folder.ID
+	} else {
+		req.FileID = entry.File.ID
+	}
+
+	share, err := vault.Share(ctx, req)
+	if err != nil {
+		return err
+	}
+	if a.jsonOut {
+		return printJSON(share)
+	}
+	fmt.Printf("Shared %s with %s (%s)\n", fs.Arg(0), fs.Arg(1), share.Permission)
+	return nil
+}
+
+// identity returns the vault identity stored in the config
+func (a *app) identity() (*client.Identity, error) {
+	if a.cfg.VaultKey == "" {
+		return nil, fmt.Errorf("%w; run drivectl vault init", client.ErrNoIdentity)
+	}
+	return client.ParseIdentity(a.cfg.VaultKey)
+}
+
+// vaultStat resolves a path that must lie inside a vault
+func (a *app) vaultStat(ctx context.Context, p string) (*client.Entry, *client.Vault, error) {
+	id, err := a.identity()
+	if err != nil {
+		return nil, nil, err
+	}
+	entry, vault, err := a.client.VaultStat(ctx, id, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	if vault == nil {
+		return nil, nil, fmt.Errorf("%s: not inside a vault", p)
+	}
+	return entry, vault, nil
+}
+
+// vaultFolderAt resolves a path that must name a folder inside a vault
+func (a *app) vaultFolderAt(ctx context.Context, p string) (*client.Folder, *client.Vault, error) {
+	entry, vault, err := a.vaultStat(ctx, p)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !entry.IsDir() {
+		return nil, nil, fmt.Errorf("%s: %w", p, client.ErrNotFolder)
+	}
+	return entry.Folder, vault, nil
+}
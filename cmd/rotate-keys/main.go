@@ -0,0 +1,109 @@
+// Command rotate-keys re-wraps the data keys of encrypted files under the
+// primary master key (STORAGE_ENCRYPTION_KEY_ID). Blob content is not
+// touched, so rotation is quick however much data is stored.
+//
+// To rotate, add a new key to STORAGE_ENCRYPTION_KEYS, make it the primary
+// and restart the server so new uploads use it, then run this command. Once
+// it reports no files left, the old key can be removed from the list.
+package main
+
+import (
+	"context"
+	"drive/internal/config"
+	"drive/internal/database"
+	"drive/internal/encryption"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"flag"
+	"fmt"
+	"os"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func main() {
+	batchSize := flag.Int("batch", 500, "Number of files re-wrapped per query")
+	dryRun := flag.Bool("dry-run", false, "Count the files that need re-wrapping without changing them")
+	flag.Parse()
+	if *batchSize < 1 {
+		*batchSize = 500
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(2)
+	}
+	keys, err := encryption.ParseKeyring(cfg.Storage.EncryptionKeys, cfg.Storage.EncryptionKeyID)
+	if err != nil {
+		fmt.Printf("Invalid encryption keys: %v\n", err)
+		os.Exit(2)
+	}
+	if keys == nil {
+		fmt.Println("No encryption keys configured; set STORAGE_ENCRYPTION_KEYS")
+		os.Exit(2)
+	}
+
+	logger := util.NewLogger(zapcore.WarnLevel)
+	db, err := database.InitDatabase(cfg, logger)
+	if err != nil {
+		fmt.Printf("Failed to connect to database: %v\n", err)
+		os.Exit(2)
+	}
+
+	rotated, failed, err := rotate(context.Background(), repository.NewFileRepository(db), keys, *batchSize, *dryRun)
+	if err != nil {
+		fmt.Printf("Rotation error after %d files: %v\n", rotated, err)
+		os.Exit(2)
+	}
+
+	if *dryRun {
+		fmt.Printf("%d files need re-wrapping under key %q\n", rotated, keys.Primary())
+		return
+	}
+	fmt.Printf("Re-wrapped %d files under key %q\n", rotated, keys.Primary())
+	if failed > 0 {
+		fmt.Printf("FAILED: %d files could not be re-wrapped; their master keys must stay configured\n", failed)
+		os.Exit(1)
+	}
+}
+
+// rotate re-wraps every data key not wrapped by the primary key, returning
+// the number of files re-wrapped and the number whose keys could not be
+// unwrapped
+func rotate(ctx context.Context, files repository.FileRepository, keys *encryption.Keyring, batchSize int, dryRun bool) (int, int, error) {
+	rotated, failed := 0, 0
+	var afterID uint
+	for {
+		batch, err := files.ListWrappedByOtherKey(ctx, keys.Primary(), afterID, batchSize)
+		if err != nil {
+			return rotated, failed, err
+		}
+		for _, file := range batch {
+			afterID = file.ID
+			if dryRun {
+				rotated++
+				continue
+			}
+
+			wrapped, keyID, err := keys.Rewrap(file.EncryptionKeyID, file.DataKey)
+			if err != nil {
+				fmt.Printf("File %d: %v\n", file.ID, err)
+				failed++
+				continue
+			}
+			// A file replaced meanwhile already has a new data key
+			ok, err := files.UpdateDataKey(ctx, file.ID, file.EncryptionKeyID, keyID, wrapped)
+			if err != nil {
+				return rotated, failed, err
+			}
+			if ok {
+				rotated++
+			}
+		}
+		if len(batch) < batchSize {
+			return rotated, failed, nil
+		}
+	}
+}
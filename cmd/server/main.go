@@ -48,6 +48,9 @@ func main() {
 		Addr:    cfg.Server.Address,
 		Handler: app.Router,
 	}
+	// Event streams never finish on their own; end them so Shutdown can
+	// drain the remaining connections
+	srv.RegisterOnShutdown(app.Services.Events.Close)
 
 	// Start server in a goroutine
 	go func() {
@@ -72,5 +75,10 @@ func main() {
 		logger.Fatal("Server forced to shutdown", util.WithError(err))
 	}
 
+	// Stop background workers
+	if err := app.Shutdown(ctx); err != nil {
+		logger.Error("Background workers did not stop in time", util.WithError(err))
+	}
+
 	logger.Info("Server exited properly")
 }
@@ -0,0 +1,125 @@
+// Command storage-check runs the storage maintenance passes the server
+// also runs on a schedule:
+//
+//	storage-check gc [-grace 24h] [-dry-run]
+//	storage-check scrub [-all] [-max-bytes N]
+//
+// gc removes blobs that no file references, trashed files included, once
+// they are older than the grace period. scrub re-hashes stored content,
+// records each file as intact, corrupt or missing and exits with status 1
+// when any is damaged; damaged files are listed by GET /api/admin/integrity.
+package main
+
+import (
+	"context"
+	"drive/internal/config"
+	"drive/internal/database"
+	"drive/internal/encryption"
+	"drive/internal/repository"
+	"drive/internal/service"
+	"drive/internal/storage"
+	"drive/internal/util"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(2)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	switch os.Args[1] {
+	case "gc":
+		flags := flag.NewFlagSet("gc", flag.ExitOnError)
+		grace := flags.Duration("grace", cfg.Maintenance.BlobGCGrace, "Only remove orphaned blobs older than this")
+		dryRun := flags.Bool("dry-run", false, "Count orphaned blobs without removing them")
+		flags.Parse(os.Args[2:])
+
+		integrity := newIntegrityService(cfg)
+		report, err := integrity.CollectGarbage(ctx, service.GarbageOptions{GracePeriod: *grace, DryRun: *dryRun})
+		if err != nil {
+			fmt.Printf("Collection error: %v\n", err)
+			os.Exit(2)
+		}
+		if *dryRun {
+			fmt.Printf("%d orphaned blobs (%d bytes) among %d listed\n", report.Orphaned, report.OrphanedBytes, report.Scanned)
+			return
+		}
+		fmt.Println(report)
+		if report.Removed < report.Orphaned {
+			fmt.Printf("FAILED: %d orphaned blobs could not be removed\n", report.Orphaned-report.Removed)
+			os.Exit(1)
+		}
+
+	case "scrub":
+		flags := flag.NewFlagSet("scrub", flag.ExitOnError)
+		all := flags.Bool("all", false, "Check every file, not only those due for verification")
+		maxBytes := flags.Int64("max-bytes", 0, "Stop after reading this many bytes; 0 means no limit")
+		flags.Parse(os.Args[2:])
+
+		opts := service.ScrubOptions{Interval: cfg.Maintenance.ScrubInterval, MaxBytes: *maxBytes}
+		if *all {
+			opts.Interval = 0
+		}
+		integrity := newIntegrityService(cfg)
+		report, err := integrity.Scrub(ctx, opts)
+		if err != nil {
+			fmt.Printf("Scrub error after %s: %v\n", report, err)
+			os.Exit(2)
+		}
+		fmt.Println(report)
+		if report.Corrupt > 0 || report.Missing > 0 {
+			fmt.Println("FAILED: damaged files found; list them with GET /api/admin/integrity")
+			os.Exit(1)
+		}
+
+	default:
+		usage()
+	}
+}
+
+// usage prints the commands and exits
+func usage() {
+	fmt.Println("Usage: storage-check gc [-grace 24h] [-dry-run]")
+	fmt.Println("       storage-check scrub [-all] [-max-bytes N]")
+	os.Exit(2)
+}
+
+// newIntegrityService connects to the database and storage configured by
+// the usual environment variables
+func newIntegrityService(cfg *config.Config) service.IntegrityService {
+	keys, err := encryption.ParseKeyring(cfg.Storage.EncryptionKeys, cfg.Storage.EncryptionKeyID)
+	if err != nil {
+		fmt.Printf("Invalid encryption keys: %v\n", err)
+		os.Exit(2)
+	}
+	store, err := storage.NewLocalStorage(cfg.Storage.Path)
+	if err != nil {
+		fmt.Printf("Failed to open storage: %v\n", err)
+		os.Exit(2)
+	}
+
+	logger := util.NewLogger(zapcore.WarnLevel)
+	db, err := database.InitDatabase(cfg, logger)
+	if err != nil {
+		fmt.Printf("Failed to connect to database: %v\n", err)
+		os.Exit(2)
+	}
+
+	return service.NewIntegrityService(repository.NewFileRepository(db), repository.NewMaintenanceRepository(db), store, keys, logger)
+}
@@ -0,0 +1,129 @@
+package auditchain
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"drive/internal/model"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SignatureAlgorithm names the algorithm used to sign checkpoints
+const SignatureAlgorithm = "ed25519"
+
+var (
+	ErrInvalidSigningKey = errors.New("invalid audit signing key")
+	ErrInvalidPublicKey  = errors.New("invalid audit public key")
+	ErrBadSignature      = errors.New("checkpoint signature does not verify")
+)
+
+// checkpointPayload is the canonical form of a checkpoint that is signed
+type checkpointPayload struct {
+	Version     string `json:"v"`
+	CreatedAt   string `json:"created_at"`
+	LastEntryID uint   `json:"last_entry_id"`
+	LastHash    string `json:"last_hash"`
+	EntryCount  int64  `json:"entry_count"`
+	KeyID       string `json:"key_id"`
+}
+
+// ParseSigningKey decodes a base64 encoded Ed25519 seed or private key. An
+// empty string returns a nil key, which disables checkpoints.
+func ParseSigningKey(encoded string) (ed25519.PrivateKey, error) {
+	encoded = strings.TrimSpace(encoded)
+	if encoded == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSigningKey, err)
+	}
+	switch len(raw) {
+	case ed25519.SeedSize:
+		return ed25519.NewKeyFromSeed(raw), nil
+	case ed25519.PrivateKeySize:
+		return ed25519.PrivateKey(raw), nil
+	default:
+		return nil, fmt.Errorf("%w: expected %d or %d bytes, got %d",
+			ErrInvalidSigningKey, ed25519.SeedSize, ed25519.PrivateKeySize, len(raw))
+	}
+}
+
+// ParsePublicKey decodes a base64 encoded Ed25519 public key
+func ParsePublicKey(encoded string) (ed25519.PublicKey, error) {
+	raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidPublicKey, err)
+	}
+	if len(raw) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrInvalidPublicKey, ed25519.PublicKeySize, len(raw))
+	}
+	return ed25519.PublicKey(raw), nil
+}
+
+// EncodePublicKey returns the base64 encoding of a public key
+func EncodePublicKey(key ed25519.PublicKey) string {
+	return base64.StdEncoding.EncodeToString(key)
+}
+
+// KeyID returns a short fingerprint identifying a public key
+func KeyID(key ed25519.PublicKey) string {
+	sum := sha256.Sum256(key)
+	return hex.EncodeToString(sum[:8])
+}
+
+// NewCheckpoint builds and signs a checkpoint for the given chain head
+func NewCheckpoint(head *model.AuditChainHead, key ed25519.PrivateKey) (*model.AuditCheckpoint, error) {
+	checkpoint := &model.AuditCheckpoint{
+		CreatedAt:   NormalizeTime(time.Now()),
+		LastEntryID: head.LastEntryID,
+		LastHash:    head.LastHash,
+		EntryCount:  head.EntryCount,
+		KeyID:       KeyID(key.Public().(ed25519.PublicKey)),
+	}
+
+	payload, err := checkpointBytes(checkpoint)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, payload))
+	return checkpoint, nil
+}
+
+// VerifyCheckpoint checks a checkpoint's signature against key
+func VerifyCheckpoint(checkpoint *model.AuditCheckpoint, key ed25519.PublicKey) error {
+	if checkpoint.KeyID != KeyID(key) {
+		return fmt.Errorf("%w: signed by key %s, expected %s", ErrBadSignature, checkpoint.KeyID, KeyID(key))
+	}
+
+	signature, err := base64.StdEncoding.DecodeString(checkpoint.Signature)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadSignature, err)
+	}
+	payload, err := checkpointBytes(checkpoint)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(key, payload, signature) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+// checkpointBytes returns the canonical encoding of a checkpoint for signing
+func checkpointBytes(checkpoint *model.AuditCheckpoint) ([]byte, error) {
+	return json.Marshal(checkpointPayload{
+		Version:     Version,
+		CreatedAt:   formatTime(checkpoint.CreatedAt),
+		LastEntryID: checkpoint.LastEntryID,
+		LastHash:    checkpoint.LastHash,
+		EntryCount:  checkpoint.EntryCount,
+		KeyID:       checkpoint.KeyID,
+	})
+}
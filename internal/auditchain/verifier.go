@@ -0,0 +1,131 @@
+package auditchain
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"drive/internal/model"
+	"fmt"
+)
+
+// BrokenLinkError reports the first entry at which the chain fails to verify
+type BrokenLinkError struct {
+	EntryID uint
+	Reason  string
+}
+
+func (e *BrokenLinkError) Error() string {
+	return fmt.Sprintf("audit chain broken at entry %d: %s", e.EntryID, e.Reason)
+}
+
+// Verifier walks the audit log in ID order and checks every link of the
+// chain, the HMACs when a key is known, and any signed checkpoints
+type Verifier struct {
+	hmacKey     []byte
+	checkpoints map[uint][]*model.AuditCheckpoint
+	matched     int
+	prevHash    string
+	lastID      uint
+	count       int64
+}
+
+// NewVerifier creates a verifier. A nil hmacKey skips HMAC checks.
+func NewVerifier(hmacKey []byte) *Verifier {
+	return &Verifier{
+		hmacKey:     hmacKey,
+		checkpoints: make(map[uint][]*model.AuditCheckpoint),
+		prevHash:    GenesisHash,
+	}
+}
+
+// AddCheckpoint verifies the checkpoint's signature and records it so the
+// chain head it describes is checked during the walk
+func (v *Verifier) AddCheckpoint(checkpoint *model.AuditCheckpoint, key ed25519.PublicKey) error {
+	if err := VerifyCheckpoint(checkpoint, key); err != nil {
+		return fmt.Errorf("checkpoint %d: %w", checkpoint.ID, err)
+	}
+	v.checkpoints[checkpoint.LastEntryID] = append(v.checkpoints[checkpoint.LastEntryID], checkpoint)
+	return nil
+}
+
+// Verify checks the next entry of the chain. Entries must be passed in
+// ascending ID order, starting from the first entry.
+func (v *Verifier) Verify(entry *model.AuditLog) error {
+	if entry.ID <= v.lastID {
+		return &BrokenLinkError{EntryID: entry.ID, Reason: fmt.Sprintf("entry is out of order after entry %d", v.lastID)}
+	}
+	if entry.Hash == "" {
+		return &BrokenLinkError{EntryID: entry.ID, Reason: "entry has not been sealed"}
+	}
+	if entry.PrevHash != v.prevHash {
+		return &BrokenLinkError{EntryID: entry.ID, Reason: fmt.Sprintf("previous hash does not match entry %d; an entry was removed or reordered", v.lastID)}
+	}
+
+	hash, err := Hash(entry, entry.PrevHash)
+	if err != nil {
+		return &BrokenLinkError{EntryID: entry.ID, Reason: fmt.Sprintf("cannot hash entry: %v", err)}
+	}
+	if hash != entry.Hash {
+		return &BrokenLinkError{EntryID: entry.ID, Reason: "hash does not match entry contents; the entry was modified"}
+	}
+
+	if len(v.hmacKey) > 0 {
+		if entry.HMAC == "" {
+			return &BrokenLinkError{EntryID: entry.ID, Reason: "entry has no HMAC"}
+		}
+		if !hmac.Equal([]byte(Sign(v.hmacKey, entry.Hash)), []byte(entry.HMAC)) {
+			return &BrokenLinkError{EntryID: entry.ID, Reason: "HMAC does not verify; the chain was recomputed without the server key"}
+		}
+	}
+
+	v.count++
+	for _, checkpoint := range v.checkpoints[entry.ID] {
+		if checkpoint.LastHash != entry.Hash {
+			return &BrokenLinkError{EntryID: entry.ID, Reason: fmt.Sprintf("hash does not match checkpoint %d", checkpoint.ID)}
+		}
+		if checkpoint.EntryCount != v.count {
+			return &BrokenLinkError{EntryID: entry.ID, Reason: fmt.Sprintf("checkpoint %d expects %d entries, found %d", checkpoint.ID, checkpoint.EntryCount, v.count)}
+		}
+		v.matched++
+	}
+	delete(v.checkpoints, entry.ID)
+
+	v.prevHash = entry.Hash
+	v.lastID = entry.ID
+	return nil
+}
+
+// Finish reports checkpoints whose entry was never reached, which means the
+// entry was removed or the end of the log was truncated
+func (v *Verifier) Finish() error {
+	var missing uint
+	for entryID := range v.checkpoints {
+		if missing == 0 || entryID < missing {
+			missing = entryID
+		}
+	}
+	if missing == 0 {
+		return nil
+	}
+
+	checkpoint := v.checkpoints[missing][0]
+	if missing > v.lastID {
+		return &BrokenLinkError{
+			EntryID: missing,
+			Reason:  fmt.Sprintf("entry covered by checkpoint %d is missing; the log was truncated after entry %d", checkpoint.ID, v.lastID),
+		}
+	}
+	return &BrokenLinkError{
+		EntryID: missing,
+		Reason:  fmt.Sprintf("entry covered by checkpoint %d is missing", checkpoint.ID),
+	}
+}
+
+// Count returns the number of entries verified so far
+func (v *Verifier) Count() int64 {
+	return v.count
+}
+
+// CheckpointsMatched returns the number of checkpoints confirmed so far
+func (v *Verifier) CheckpointsMatched() int {
+	return v.matched
+}
@@ -1,12 +1,14 @@
 package bootstrap
 
 import (
+	"context"
 	"drive/internal/config"
 	"drive/internal/database"
 	"drive/internal/handler"
 	"drive/internal/repository"
 	"drive/internal/routes"
 	"drive/internal/service"
+	"drive/internal/storage"
 	"drive/internal/util"
 	"fmt"
 	"net/http"
@@ -20,6 +22,7 @@ type App struct {
 	Database *gorm.DB
 	Router   http.Handler
 	Logger   *util.Logger
+	Services *service.Services
 }
 
 func NewApp(cfg *config.Config) (*App, error) {
@@ -45,10 +48,27 @@ func NewApp(cfg *config.Config) (*App, error) {
 		RefreshExpiry: cfg.JWT.RefreshExpiresIn,
 	})
 
+	store, err := storage.NewLocalStorage(cfg.Storage.Path)
+	if err != nil {
+		logger.Error("Failed to initialize storage", zap.Error(err))
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
 	repo := repository.NewRepositories(db)
-	services := service.NewServices(*repo, jwtService, logger, cfg)
-	handler := handler.NewHandler(services)
-	routes := routes.SetupRoutes(handler, services.Auth)
+	services, err := service.NewServices(*repo, store, jwtService, logger, cfg)
+	if err != nil {
+		logger.Error("Failed to initialize services", zap.Error(err))
+		return nil, fmt.Errorf("failed to initialize services: %w", err)
+	}
+	handler := handler.NewHandler(services, cfg, logger)
+	routes := routes.SetupRoutes(handler, services.Auth, services.AppPassword)
+
+	// Start background workers
+	services.Hash.Start(context.Background())
+	services.Batch.Start(context.Background())
+	services.Jobs.Start(context.Background())
+	services.Scheduler.Start(context.Background())
+	services.Audit.Start(context.Background())
 
 	logger.Info("Application initialized successfully")
 
@@ -57,5 +77,28 @@ func NewApp(cfg *config.Config) (*App, error) {
 		Database: db,
 		Router:   routes,
 		Logger:   logger,
+		Services: services,
 	}, nil
 }
+
+// Shutdown stops the background workers started by NewApp
+func (a *App) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		// Batches, jobs and scheduled tasks run operations that feed the
+		// other workers
+		a.Services.Batch.Stop()
+		a.Services.Jobs.Stop()
+		a.Services.Scheduler.Stop()
+		a.Services.Hash.Stop()
+		a.Services.Audit.Stop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -11,6 +13,9 @@ import (
 // Server holds server configuration
 type Server struct {
 	Address string
+	// AllowedOrigins are the browser origins, such as https://app.example.com,
+	// besides the server's own that may open event WebSockets
+	AllowedOrigins []string
 }
 
 // Database holds database configuration
@@ -40,6 +45,118 @@ type OAuth struct {
 	FacebookAppSecret string
 }
 
+// Storage holds blob storage configuration
+type Storage struct {
+	// Path is the root directory for the local blob store
+	Path string
+	// MaxUploadSize is the largest accepted upload in bytes
+	MaxUploadSize int64
+	// EncryptionKeys lists master keys as comma separated id:base64-key
+	// pairs. New blobs are stored unencrypted when it is empty.
+	EncryptionKeys string
+	// EncryptionKeyID names the master key for new blobs, defaulting to the
+	// first one listed
+	EncryptionKeyID string
+}
+
+// Indexer holds content indexing configuration
+type Indexer struct {
+	// Timeout bounds the time spent extracting text from a single file
+	Timeout time.Duration
+	// MaxFileSize is the largest file in bytes that will be indexed
+	MaxFileSize int64
+	// MaxTextSize is the maximum amount of extracted text stored per file
+	MaxTextSize int64
+}
+
+// Scanner holds malware scanning configuration
+type Scanner struct {
+	// ClamdAddress is where clamd listens, as tcp://host:port or
+	// unix:///path/to/clamd.sock. Scanning is disabled when it is empty.
+	ClamdAddress string
+	// Timeout bounds the time spent scanning a single file
+	Timeout time.Duration
+	// MaxFileSize is the largest file in bytes that will be scanned
+	MaxFileSize int64
+	// FailClosed withholds files from download and preview until their
+	// scan reaches a verdict, so nothing unscanned is served while clamd
+	// is down. By default pending files are served.
+	FailClosed bool
+}
+
+// Preview holds file preview configuration
+type Preview struct {
+	// MaxFileSize is the largest file in bytes that will be previewed
+	MaxFileSize int64
+	// Timeout bounds the time spent rendering a single file
+	Timeout time.Duration
+}
+
+// Photos holds photo library configuration
+type Photos struct {
+	// StripLocation drops the GPS coordinates read from every user's photos
+	// instead of storing them. Users can also choose this for their own.
+	StripLocation bool
+}
+
+// Webhook holds outgoing webhook delivery configuration
+type Webhook struct {
+	// Timeout bounds a single delivery attempt
+	Timeout time.Duration
+	// MaxAttempts is the number of attempts before a delivery is marked failed
+	MaxAttempts int
+	// AllowPrivateNetworks permits delivering to loopback and private addresses
+	AllowPrivateNetworks bool
+}
+
+// Jobs holds background job queue configuration
+type Jobs struct {
+	Workers int
+	// PollInterval is how often idle workers look for due jobs
+	PollInterval time.Duration
+	// Timeout bounds a single attempt at a job
+	Timeout time.Duration
+	// MaxAttempts is the default number of attempts before a job is dead
+	MaxAttempts int
+}
+
+// Maintenance holds the schedules of the recurring maintenance tasks as
+// five field cron expressions evaluated in UTC. An empty schedule disables
+// the task.
+type Maintenance struct {
+	TrashPurgeSchedule     string
+	ExpiredLocksSchedule   string
+	QuotaReconcileSchedule string
+	StaleUploadsSchedule   string
+	BlobGCSchedule         string
+	ScrubSchedule          string
+	RetentionSchedule      string
+	RequeueSchedule        string
+	// TrashRetention is how long items stay in the trash before they are
+	// purged
+	TrashRetention time.Duration
+	// StaleUploadAge is how old an unfinished upload must be to be removed
+	StaleUploadAge time.Duration
+	// BlobGCGrace is how old an unreferenced blob must be to be collected
+	BlobGCGrace time.Duration
+	// ScrubInterval is how often each file's content is re-verified
+	ScrubInterval time.Duration
+	// ScrubMaxBytes bounds how much content one scrub reads; 0 means no
+	// limit
+	ScrubMaxBytes int64
+}
+
+// Audit holds audit log integrity configuration
+type Audit struct {
+	// HMACKey authenticates every entry hash when set
+	HMACKey string
+	// SigningKey is a base64 Ed25519 seed used to sign chain checkpoints.
+	// Checkpoints are disabled when it is empty.
+	SigningKey string
+	// CheckpointInterval is how often a signed checkpoint is written
+	CheckpointInterval time.Duration
+}
+
 // Logging holds logging configuration
 type Logging struct {
 	Level zapcore.Level
@@ -47,11 +164,20 @@ type Logging struct {
 
 // Config holds all application configuration
 type Config struct {
-	Server   Server
-	Database Database
-	JWT      JWT
-	OAuth    OAuth
-	Logging  Logging
+	Server      Server
+	Database    Database
+	JWT         JWT
+	OAuth       OAuth
+	Storage     Storage
+	Indexer     Indexer
+	Scanner     Scanner
+	Preview     Preview
+	Photos      Photos
+	Webhook     Webhook
+	Jobs        Jobs
+	Maintenance Maintenance
+	Audit       Audit
+	Logging     Logging
 }
 
 // Load loads configuration from environment variables
@@ -61,7 +187,8 @@ func Load() (*Config, error) {
 
 	return &Config{
 		Server: Server{
-			Address: getEnv("SERVER_ADDRESS", ":8080"),
+			Address:        getEnv("SERVER_ADDRESS", ":8080"),
+			AllowedOrigins: getEnvAsList("SERVER_ALLOWED_ORIGINS"),
 		},
 		Database: Database{
 			Host:     getEnv("DB_HOST", "localhost"),
@@ -82,6 +209,61 @@ func Load() (*Config, error) {
 			FacebookAppID:      getEnv("FACEBOOK_APP_ID", ""),
 			FacebookAppSecret:  getEnv("FACEBOOK_APP_SECRET", ""),
 		},
+		Storage: Storage{
+			Path:            getEnv("STORAGE_PATH", "./storage"),
+			MaxUploadSize:   getEnvAsInt64("STORAGE_MAX_UPLOAD_SIZE", 1<<30),
+			EncryptionKeys:  getEnv("STORAGE_ENCRYPTION_KEYS", ""),
+			EncryptionKeyID: getEnv("STORAGE_ENCRYPTION_KEY_ID", ""),
+		},
+		Indexer: Indexer{
+			Timeout:     getEnvAsDuration("INDEXER_TIMEOUT", 30*time.Second),
+			MaxFileSize: getEnvAsInt64("INDEXER_MAX_FILE_SIZE", 20<<20),
+			MaxTextSize: getEnvAsInt64("INDEXER_MAX_TEXT_SIZE", 1<<20),
+		},
+		Scanner: Scanner{
+			ClamdAddress: getEnv("SCANNER_CLAMD_ADDRESS", ""),
+			Timeout:      getEnvAsDuration("SCANNER_TIMEOUT", 2*time.Minute),
+			MaxFileSize:  getEnvAsInt64("SCANNER_MAX_FILE_SIZE", 100<<20),
+			FailClosed:   getEnvAsBool("SCANNER_FAIL_CLOSED", false),
+		},
+		Preview: Preview{
+			MaxFileSize: getEnvAsInt64("PREVIEW_MAX_FILE_SIZE", 50<<20),
+			Timeout:     getEnvAsDuration("PREVIEW_TIMEOUT", 30*time.Second),
+		},
+		Photos: Photos{
+			StripLocation: getEnvAsBool("PHOTOS_STRIP_LOCATION", false),
+		},
+		Webhook: Webhook{
+			Timeout:              getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+			MaxAttempts:          getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 8),
+			AllowPrivateNetworks: getEnvAsBool("WEBHOOK_ALLOW_PRIVATE_NETWORKS", false),
+		},
+		Jobs: Jobs{
+			Workers:      getEnvAsInt("JOB_WORKERS", 4),
+			PollInterval: getEnvAsDuration("JOB_POLL_INTERVAL", 2*time.Second),
+			Timeout:      getEnvAsDuration("JOB_TIMEOUT", 10*time.Minute),
+			MaxAttempts:  getEnvAsInt("JOB_MAX_ATTEMPTS", 5),
+		},
+		Maintenance: Maintenance{
+			TrashPurgeSchedule:     getEnv("MAINTENANCE_TRASH_PURGE_SCHEDULE", "0 3 * * *"),
+			ExpiredLocksSchedule:   getEnv("MAINTENANCE_EXPIRED_LOCKS_SCHEDULE", "*/15 * * * *"),
+			QuotaReconcileSchedule: getEnv("MAINTENANCE_QUOTA_RECONCILE_SCHEDULE", "30 4 * * *"),
+			StaleUploadsSchedule:   getEnv("MAINTENANCE_STALE_UPLOADS_SCHEDULE", "0 * * * *"),
+			BlobGCSchedule:         getEnv("MAINTENANCE_BLOB_GC_SCHEDULE", "0 5 * * *"),
+			ScrubSchedule:          getEnv("MAINTENANCE_SCRUB_SCHEDULE", "0 1 * * *"),
+			RetentionSchedule:      getEnv("MAINTENANCE_RETENTION_SCHEDULE", "0 2 * * *"),
+			RequeueSchedule:        getEnv("MAINTENANCE_REQUEUE_SCHEDULE", "*/5 * * * *"),
+			TrashRetention:         getEnvAsDuration("MAINTENANCE_TRASH_RETENTION", 30*24*time.Hour),
+			StaleUploadAge:         getEnvAsDuration("MAINTENANCE_STALE_UPLOAD_AGE", 24*time.Hour),
+			BlobGCGrace:            getEnvAsDuration("MAINTENANCE_BLOB_GC_GRACE", 24*time.Hour),
+			ScrubInterval:          getEnvAsDuration("MAINTENANCE_SCRUB_INTERVAL", 30*24*time.Hour),
+			ScrubMaxBytes:          getEnvAsInt64("MAINTENANCE_SCRUB_MAX_BYTES", 10<<30),
+		},
+		Audit: Audit{
+			HMACKey:            getEnv("AUDIT_HMAC_KEY", ""),
+			SigningKey:         getEnv("AUDIT_SIGNING_KEY", ""),
+			CheckpointInterval: getEnvAsDuration("AUDIT_CHECKPOINT_INTERVAL", time.Hour),
+		},
 		Logging: Logging{
 			Level: getLogLevel(getEnv("LOG_LEVEL", "info")),
 		},
@@ -105,6 +287,41 @@ func getEnvAsInt(key string, fallback int) int {
 	return fallback
 }
 
+// getEnvAsInt64 retrieves environment variables as 64-bit integers with fallback values
+func getEnvAsInt64(key string, fallback int64) int64 {
+	if value, err := strconv.ParseInt(getEnv(key, ""), 10, 64); err == nil {
+		return value
+	}
+	return fallback
+}
+
+// getEnvAsDuration retrieves environment variables as durations (e.g. "30s") with fallback values
+func getEnvAsDuration(key string, fallback time.Duration) time.Duration {
+	if value, err := time.ParseDuration(getEnv(key, "")); err == nil {
+		return value
+	}
+	return fallback
+}
+
+// getEnvAsBool retrieves environment variables as booleans with fallback values
+func getEnvAsBool(key string, fallback bool) bool {
+	if value, err := strconv.ParseBool(getEnv(key, "")); err == nil {
+		return value
+	}
+	return fallback
+}
+
+// getEnvAsList retrieves comma-separated environment variables, ignoring empty items
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(getEnv(key, ""), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // getLogLevel converts a string log level to zapcore.Level
 func getLogLevel(level string) zapcore.Level {
 	switch level {
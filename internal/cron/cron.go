@@ -0,0 +1,202 @@
+// Package cron parses standard five field cron expressions and computes
+// when they next fire.
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidExpression is returned for expressions that cannot be parsed
+var ErrInvalidExpression = errors.New("invalid cron expression")
+
+// descriptors are the supported shorthand expressions
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// field describes the range of one field of an expression
+type field struct {
+	name     string
+	min, max int
+}
+
+var fields = []field{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 6},
+}
+
+// maxSearch bounds the search for the next match, so expressions that can
+// never fire, such as 0 0 30 2 *, do not loop forever
+const maxSearch = 5 * 366 * 24 * time.Hour
+
+// Schedule is a parsed expression. Each field is a bit set of the values it
+// matches.
+type Schedule struct {
+	expr                          string
+	minute, hour, dom, month, dow uint64
+	domRestricted, dowRestricted  bool
+}
+
+// Parse parses an expression of the form "minute hour day-of-month month
+// day-of-week". Fields accept *, values, ranges (1-5), lists (1,3,5) and
+// steps (*/15, 0-30/10); day of week 7 is Sunday like 0. The @hourly,
+// @daily, @midnight, @weekly, @monthly, @yearly and @annually shorthands
+// are also accepted.
+func Parse(expr string) (*Schedule, error) {
+	spec := strings.TrimSpace(expr)
+	if d, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = d
+	}
+	parts := strings.Fields(spec)
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("%w %q: expected %d fields", ErrInvalidExpression, expr, len(fields))
+	}
+
+	sets := make([]uint64, len(fields))
+	for i, part := range parts {
+		f := fields[i]
+		if i == 4 {
+			// Accept 7 for Sunday while parsing, then fold it onto 0
+			f.max = 7
+		}
+		set, err := parseField(part, f)
+		if err != nil {
+			return nil, fmt.Errorf("%w %q: %v", ErrInvalidExpression, expr, err)
+		}
+		sets[i] = set
+	}
+	if sets[4]&(1<<7) != 0 {
+		sets[4] = sets[4]&^(1<<7) | 1
+	}
+
+	return &Schedule{
+		expr:          expr,
+		minute:        sets[0],
+		hour:          sets[1],
+		dom:           sets[2],
+		month:         sets[3],
+		dow:           sets[4],
+		domRestricted: parts[2] != "*",
+		dowRestricted: parts[4] != "*",
+	}, nil
+}
+
+// String returns the expression the schedule was parsed from
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// Next returns the first time after t that the schedule fires, in t's
+// location, or the zero time if it never does
+func (s *Schedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxSearch)
+
+	for t.Before(limit) {
+		if !has(s.month, int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(s.hour, t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if !has(s.minute, t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// dayMatches applies the usual cron rule: when both day fields are
+// restricted a day matching either one fires
+func (s *Schedule) dayMatches(t time.Time) bool {
+	dom := has(s.dom, t.Day())
+	dow := has(s.dow, int(t.Weekday()))
+	if s.domRestricted && s.dowRestricted {
+		return dom || dow
+	}
+	return dom && dow
+}
+
+// parseField parses one comma separated field into a bit set
+func parseField(spec string, f field) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(spec, ",") {
+		lo, hi, step := f.min, f.max, 1
+
+		rangeSpec := part
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			rangeSpec = part[:i]
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, fmt.Errorf("invalid step in %s field %q", f.name, part)
+			}
+			step = n
+		}
+
+		switch {
+		case rangeSpec == "*":
+		case strings.Contains(rangeSpec, "-"):
+			bounds := strings.SplitN(rangeSpec, "-", 2)
+			var err error
+			if lo, err = parseValue(bounds[0], f); err != nil {
+				return 0, err
+			}
+			if hi, err = parseValue(bounds[1], f); err != nil {
+				return 0, err
+			}
+			if lo > hi {
+				return 0, fmt.Errorf("invalid range in %s field %q", f.name, part)
+			}
+		default:
+			v, err := parseValue(rangeSpec, f)
+			if err != nil {
+				return 0, err
+			}
+			lo = v
+			// A single value with a step runs from the value to the end
+			if step == 1 {
+				hi = v
+			}
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << uint(v)
+		}
+	}
+	return set, nil
+}
+
+// parseValue parses a number within the field's range
+func parseValue(s string, f field) (int, error) {
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("%s must be between %d and %d, got %q", f.name, f.min, f.max, s)
+	}
+	return v, nil
+}
+
+// has reports whether v is in the bit set
+func has(set uint64, v int) bool {
+	return set&(1<<uint(v)) != 0
+}
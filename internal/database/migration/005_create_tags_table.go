@@ -0,0 +1,25 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateTagsTable migration creates the tags and file_tags tables
+type CreateTagsTable struct{}
+
+// ID returns the migration ID
+func (m *CreateTagsTable) ID() string {
+	return "005_create_tags_table"
+}
+
+// Migrate runs the migration
+func (m *CreateTagsTable) Migrate(tx *gorm.DB) error {
+	return tx.AutoMigrate(&model.Tag{})
+}
+
+// Rollback runs the migration rollback
+func (m *CreateTagsTable) Rollback(tx *gorm.DB) error {
+	return tx.Migrator().DropTable("file_tags", "tags")
+}
@@ -0,0 +1,55 @@
+package migration
+
+import (
+	"gorm.io/gorm"
+)
+
+// AddSearchIndexes migration enables pg_trgm and adds full-text and trigram
+// indexes on file and folder names
+type AddSearchIndexes struct{}
+
+// ID returns the migration ID
+func (m *AddSearchIndexes) ID() string {
+	return "006_add_search_indexes"
+}
+
+// Migrate runs the migration
+func (m *AddSearchIndexes) Migrate(tx *gorm.DB) error {
+	statements := []string{
+		`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+		`CREATE INDEX IF NOT EXISTS idx_files_file_name_fts ON files USING GIN (to_tsvector('simple', file_name))`,
+		`CREATE INDEX IF NOT EXISTS idx_files_file_name_trgm ON files USING GIN (file_name gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_folders_folder_name_fts ON folders USING GIN (to_tsvector('simple', folder_name))`,
+		`CREATE INDEX IF NOT EXISTS idx_folders_folder_name_trgm ON folders USING GIN (folder_name gin_trgm_ops)`,
+		`CREATE INDEX IF NOT EXISTS idx_files_folder_id ON files (folder_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_folders_parent_folder_id ON folders (parent_folder_id)`,
+		`CREATE INDEX IF NOT EXISTS idx_shares_shared_with_id ON shares (shared_with_id)`,
+	}
+
+	for _, stmt := range statements {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rollback runs the migration rollback
+func (m *AddSearchIndexes) Rollback(tx *gorm.DB) error {
+	statements := []string{
+		`DROP INDEX IF EXISTS idx_shares_shared_with_id`,
+		`DROP INDEX IF EXISTS idx_folders_parent_folder_id`,
+		`DROP INDEX IF EXISTS idx_files_folder_id`,
+		`DROP INDEX IF EXISTS idx_folders_folder_name_trgm`,
+		`DROP INDEX IF EXISTS idx_folders_folder_name_fts`,
+		`DROP INDEX IF EXISTS idx_files_file_name_trgm`,
+		`DROP INDEX IF EXISTS idx_files_file_name_fts`,
+	}
+
+	for _, stmt := range statements {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,28 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// AddFilesMimeType migration adds the mime_type column to the files table
+type AddFilesMimeType struct{}
+
+// ID returns the migration ID
+func (m *AddFilesMimeType) ID() string {
+	return "007_add_files_mime_type"
+}
+
+// Migrate runs the migration
+func (m *AddFilesMimeType) Migrate(tx *gorm.DB) error {
+	if tx.Migrator().HasColumn(&model.File{}, "MimeType") {
+		return nil
+	}
+	return tx.Migrator().AddColumn(&model.File{}, "MimeType")
+}
+
+// Rollback runs the migration rollback
+func (m *AddFilesMimeType) Rollback(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&model.File{}, "MimeType")
+}
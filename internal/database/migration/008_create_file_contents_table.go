@@ -0,0 +1,29 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateFileContentsTable migration creates the file_contents table and its
+// full-text index
+type CreateFileContentsTable struct{}
+
+// ID returns the migration ID
+func (m *CreateFileContentsTable) ID() string {
+	return "008_create_file_contents_table"
+}
+
+// Migrate runs the migration
+func (m *CreateFileContentsTable) Migrate(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&model.FileContent{}); err != nil {
+		return err
+	}
+	return tx.Exec(`CREATE INDEX IF NOT EXISTS idx_file_contents_content_tsv ON file_contents USING GIN (content_tsv)`).Error
+}
+
+// Rollback runs the migration rollback
+func (m *CreateFileContentsTable) Rollback(tx *gorm.DB) error {
+	return tx.Migrator().DropTable("file_contents")
+}
@@ -0,0 +1,28 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// AddUsersIsAdmin migration adds the is_admin column to the users table
+type AddUsersIsAdmin struct{}
+
+// ID returns the migration ID
+func (m *AddUsersIsAdmin) ID() string {
+	return "009_add_users_is_admin"
+}
+
+// Migrate runs the migration
+func (m *AddUsersIsAdmin) Migrate(tx *gorm.DB) error {
+	if tx.Migrator().HasColumn(&model.User{}, "IsAdmin") {
+		return nil
+	}
+	return tx.Migrator().AddColumn(&model.User{}, "IsAdmin")
+}
+
+// Rollback runs the migration rollback
+func (m *AddUsersIsAdmin) Rollback(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&model.User{}, "IsAdmin")
+}
@@ -0,0 +1,27 @@
+package migration
+
+import (
+	"gorm.io/gorm"
+)
+
+// UpdateSharesFileID migration stores folder shares with a NULL file_id so
+// they satisfy the foreign key to files, and indexes the column
+type UpdateSharesFileID struct{}
+
+// ID returns the migration ID
+func (m *UpdateSharesFileID) ID() string {
+	return "010_update_shares_file_id"
+}
+
+// Migrate runs the migration
+func (m *UpdateSharesFileID) Migrate(tx *gorm.DB) error {
+	if err := tx.Exec(`UPDATE shares SET file_id = NULL WHERE file_id = 0`).Error; err != nil {
+		return err
+	}
+	return tx.Exec(`CREATE INDEX IF NOT EXISTS idx_shares_file_id ON shares (file_id)`).Error
+}
+
+// Rollback runs the migration rollback
+func (m *UpdateSharesFileID) Rollback(tx *gorm.DB) error {
+	return tx.Exec(`DROP INDEX IF EXISTS idx_shares_file_id`).Error
+}
@@ -0,0 +1,50 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateAuditLogsTable migration creates the audit_logs table and a trigger
+// that rejects updates and deletes so the log stays append-only
+type CreateAuditLogsTable struct{}
+
+// ID returns the migration ID
+func (m *CreateAuditLogsTable) ID() string {
+	return "011_create_audit_logs_table"
+}
+
+// Migrate runs the migration
+func (m *CreateAuditLogsTable) Migrate(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&model.AuditLog{}); err != nil {
+		return err
+	}
+
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs`,
+		`CREATE TRIGGER audit_logs_append_only
+	BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_logs
+	FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only()`,
+	}
+
+	for _, stmt := range statements {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rollback runs the migration rollback
+func (m *CreateAuditLogsTable) Rollback(tx *gorm.DB) error {
+	if err := tx.Migrator().DropTable("audit_logs"); err != nil {
+		return err
+	}
+	return tx.Exec(`DROP FUNCTION IF EXISTS audit_logs_append_only()`).Error
+}
@@ -0,0 +1,85 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// AddAuditLogChain migration adds the hash chain columns to audit_logs and
+// creates the audit_checkpoints table. The append-only trigger becomes a row
+// trigger so entries written before the chain existed can be sealed once;
+// any other update or delete is still rejected.
+type AddAuditLogChain struct{}
+
+// ID returns the migration ID
+func (m *AddAuditLogChain) ID() string {
+	return "012_add_audit_log_chain"
+}
+
+// Migrate runs the migration
+func (m *AddAuditLogChain) Migrate(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&model.AuditLog{}, &model.AuditCheckpoint{}); err != nil {
+		return err
+	}
+
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+	-- Entries written before the hash chain existed may be sealed exactly once
+	IF TG_TABLE_NAME = 'audit_logs' AND TG_OP = 'UPDATE' THEN
+		IF OLD.hash = '' AND NEW.hash <> ''
+			AND to_jsonb(NEW) - 'prev_hash' - 'hash' - 'hmac' = to_jsonb(OLD) - 'prev_hash' - 'hash' - 'hmac' THEN
+			RETURN NEW;
+		END IF;
+	END IF;
+	RAISE EXCEPTION '% is append-only', TG_TABLE_NAME;
+END;
+$$ LANGUAGE plpgsql`,
+		`DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs`,
+		`CREATE TRIGGER audit_logs_append_only
+	BEFORE UPDATE OR DELETE ON audit_logs
+	FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only()`,
+		`CREATE TRIGGER audit_logs_no_truncate
+	BEFORE TRUNCATE ON audit_logs
+	FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only()`,
+		`CREATE TRIGGER audit_checkpoints_append_only
+	BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_checkpoints
+	FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only()`,
+	}
+
+	for _, stmt := range statements {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rollback runs the migration rollback
+func (m *AddAuditLogChain) Rollback(tx *gorm.DB) error {
+	if err := tx.Migrator().DropTable("audit_checkpoints"); err != nil {
+		return err
+	}
+
+	statements := []string{
+		`DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs`,
+		`DROP TRIGGER IF EXISTS audit_logs_append_only ON audit_logs`,
+		`CREATE OR REPLACE FUNCTION audit_logs_append_only() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql`,
+		`CREATE TRIGGER audit_logs_append_only
+	BEFORE UPDATE OR DELETE OR TRUNCATE ON audit_logs
+	FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only()`,
+		`ALTER TABLE audit_logs DROP COLUMN IF EXISTS prev_hash, DROP COLUMN IF EXISTS hash, DROP COLUMN IF EXISTS hmac`,
+	}
+
+	for _, stmt := range statements {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateWebhooksTables migration creates the webhooks and webhook_deliveries
+// tables along with the index the dispatcher uses to find due deliveries
+type CreateWebhooksTables struct{}
+
+// ID returns the migration ID
+func (m *CreateWebhooksTables) ID() string {
+	return "013_create_webhooks_tables"
+}
+
+// Migrate runs the migration
+func (m *CreateWebhooksTables) Migrate(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&model.Webhook{}, &model.WebhookDelivery{}); err != nil {
+		return err
+	}
+	return tx.Exec(`CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due
+	ON webhook_deliveries (next_attempt_at) WHERE status = 'pending'`).Error
+}
+
+// Rollback runs the migration rollback
+func (m *CreateWebhooksTables) Rollback(tx *gorm.DB) error {
+	return tx.Migrator().DropTable("webhook_deliveries", "webhooks")
+}
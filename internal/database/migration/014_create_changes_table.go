@@ -0,0 +1,47 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateChangesTable migration creates the change journal and seeds it with
+// a created entry for every existing folder and file in its owner's journal,
+// folders first. Items shared before the journal existed are not seeded.
+type CreateChangesTable struct{}
+
+// ID returns the migration ID
+func (m *CreateChangesTable) ID() string {
+	return "014_create_changes_table"
+}
+
+// Migrate runs the migration
+func (m *CreateChangesTable) Migrate(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&model.Change{}); err != nil {
+		return err
+	}
+
+	statements := []string{
+		`INSERT INTO changes (user_id, change, item_type, item_id, parent_id, name, size, mime_type, modified_at, created_at)
+	SELECT user_id, 'created', 'folder', id, parent_folder_id, folder_name, 0, '', updated_at, NOW()
+	FROM folders WHERE deleted_at IS NULL
+	ORDER BY id`,
+		`INSERT INTO changes (user_id, change, item_type, item_id, parent_id, name, size, mime_type, modified_at, created_at)
+	SELECT user_id, 'created', 'file', id, folder_id, file_name, file_size, COALESCE(mime_type, ''), updated_at, NOW()
+	FROM files WHERE deleted_at IS NULL
+	ORDER BY id`,
+	}
+
+	for _, stmt := range statements {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rollback runs the migration rollback
+func (m *CreateChangesTable) Rollback(tx *gorm.DB) error {
+	return tx.Migrator().DropTable("changes")
+}
@@ -0,0 +1,26 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateAppPasswordsTable migration creates the app_passwords table used to
+// authenticate WebDAV and other non-interactive clients
+type CreateAppPasswordsTable struct{}
+
+// ID returns the migration ID
+func (m *CreateAppPasswordsTable) ID() string {
+	return "015_create_app_passwords_table"
+}
+
+// Migrate runs the migration
+func (m *CreateAppPasswordsTable) Migrate(tx *gorm.DB) error {
+	return tx.AutoMigrate(&model.AppPassword{})
+}
+
+// Rollback runs the migration rollback
+func (m *CreateAppPasswordsTable) Rollback(tx *gorm.DB) error {
+	return tx.Migrator().DropTable("app_passwords")
+}
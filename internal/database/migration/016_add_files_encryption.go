@@ -0,0 +1,42 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// AddFilesEncryption migration adds the columns holding each file's wrapped
+// data key and the ID of the master key that wrapped it
+type AddFilesEncryption struct{}
+
+// ID returns the migration ID
+func (m *AddFilesEncryption) ID() string {
+	return "016_add_files_encryption"
+}
+
+// Migrate runs the migration
+func (m *AddFilesEncryption) Migrate(tx *gorm.DB) error {
+	for _, field := range []string{"EncryptionKeyID", "DataKey"} {
+		if tx.Migrator().HasColumn(&model.File{}, field) {
+			continue
+		}
+		if err := tx.Migrator().AddColumn(&model.File{}, field); err != nil {
+			return err
+		}
+	}
+	if tx.Migrator().HasIndex(&model.File{}, "EncryptionKeyID") {
+		return nil
+	}
+	return tx.Migrator().CreateIndex(&model.File{}, "EncryptionKeyID")
+}
+
+// Rollback runs the migration rollback
+func (m *AddFilesEncryption) Rollback(tx *gorm.DB) error {
+	for _, field := range []string{"EncryptionKeyID", "DataKey"} {
+		if err := tx.Migrator().DropColumn(&model.File{}, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
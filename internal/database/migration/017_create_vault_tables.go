@@ -0,0 +1,37 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateVaultTables migration adds the vault_id column to folders and
+// creates the tables holding users' public keys and wrapped vault keys
+type CreateVaultTables struct{}
+
+// ID returns the migration ID
+func (m *CreateVaultTables) ID() string {
+	return "017_create_vault_tables"
+}
+
+// Migrate runs the migration
+func (m *CreateVaultTables) Migrate(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn(&model.Folder{}, "VaultID") {
+		if err := tx.Migrator().AddColumn(&model.Folder{}, "VaultID"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().CreateIndex(&model.Folder{}, "VaultID"); err != nil {
+			return err
+		}
+	}
+	return tx.AutoMigrate(&model.UserPublicKey{}, &model.VaultKey{})
+}
+
+// Rollback runs the migration rollback
+func (m *CreateVaultTables) Rollback(tx *gorm.DB) error {
+	if err := tx.Migrator().DropTable("vault_keys", "user_public_keys"); err != nil {
+		return err
+	}
+	return tx.Migrator().DropColumn(&model.Folder{}, "VaultID")
+}
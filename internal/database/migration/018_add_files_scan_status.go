@@ -0,0 +1,42 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// AddFilesScanStatus migration adds the columns recording the malware scan
+// of each file. Existing files are marked as not scanned.
+type AddFilesScanStatus struct{}
+
+// ID returns the migration ID
+func (m *AddFilesScanStatus) ID() string {
+	return "018_add_files_scan_status"
+}
+
+// Migrate runs the migration
+func (m *AddFilesScanStatus) Migrate(tx *gorm.DB) error {
+	for _, field := range []string{"ScanStatus", "ScanSignature", "ScannedAt"} {
+		if tx.Migrator().HasColumn(&model.File{}, field) {
+			continue
+		}
+		if err := tx.Migrator().AddColumn(&model.File{}, field); err != nil {
+			return err
+		}
+	}
+	if tx.Migrator().HasIndex(&model.File{}, "ScanStatus") {
+		return nil
+	}
+	return tx.Migrator().CreateIndex(&model.File{}, "ScanStatus")
+}
+
+// Rollback runs the migration rollback
+func (m *AddFilesScanStatus) Rollback(tx *gorm.DB) error {
+	for _, field := range []string{"ScanStatus", "ScanSignature", "ScannedAt"} {
+		if err := tx.Migrator().DropColumn(&model.File{}, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
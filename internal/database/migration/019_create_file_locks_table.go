@@ -0,0 +1,26 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateFileLocksTable migration creates the file_locks table holding file
+// check-outs and WebDAV locks
+type CreateFileLocksTable struct{}
+
+// ID returns the migration ID
+func (m *CreateFileLocksTable) ID() string {
+	return "019_create_file_locks_table"
+}
+
+// Migrate runs the migration
+func (m *CreateFileLocksTable) Migrate(tx *gorm.DB) error {
+	return tx.AutoMigrate(&model.FileLock{})
+}
+
+// Rollback runs the migration rollback
+func (m *CreateFileLocksTable) Rollback(tx *gorm.DB) error {
+	return tx.Migrator().DropTable("file_locks")
+}
@@ -0,0 +1,26 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateCommentsTables migration creates the tables holding file comments,
+// their mentions and their edit history
+type CreateCommentsTables struct{}
+
+// ID returns the migration ID
+func (m *CreateCommentsTables) ID() string {
+	return "020_create_comments_tables"
+}
+
+// Migrate runs the migration
+func (m *CreateCommentsTables) Migrate(tx *gorm.DB) error {
+	return tx.AutoMigrate(&model.Comment{}, &model.CommentMention{}, &model.CommentRevision{})
+}
+
+// Rollback runs the migration rollback
+func (m *CreateCommentsTables) Rollback(tx *gorm.DB) error {
+	return tx.Migrator().DropTable("comment_revisions", "comment_mentions", "comments")
+}
@@ -0,0 +1,32 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreatePhotoTables migration creates the tables holding photo metadata and
+// albums, and indexes files by owner and type for the photo timeline
+type CreatePhotoTables struct{}
+
+// ID returns the migration ID
+func (m *CreatePhotoTables) ID() string {
+	return "021_create_photo_tables"
+}
+
+// Migrate runs the migration
+func (m *CreatePhotoTables) Migrate(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&model.PhotoMetadata{}, &model.Album{}, &model.AlbumPhoto{}); err != nil {
+		return err
+	}
+	return tx.Exec(`CREATE INDEX IF NOT EXISTS idx_files_user_id_file_type ON files (user_id, file_type) WHERE deleted_at IS NULL`).Error
+}
+
+// Rollback runs the migration rollback
+func (m *CreatePhotoTables) Rollback(tx *gorm.DB) error {
+	if err := tx.Exec(`DROP INDEX IF EXISTS idx_files_user_id_file_type`).Error; err != nil {
+		return err
+	}
+	return tx.Migrator().DropTable("album_photos", "albums", "photo_metadata")
+}
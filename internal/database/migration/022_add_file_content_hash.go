@@ -0,0 +1,34 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// AddFileContentHash migration adds the content hash used to find duplicate
+// files. Existing files are hashed in the background once the server runs.
+type AddFileContentHash struct{}
+
+// ID returns the migration ID
+func (m *AddFileContentHash) ID() string {
+	return "022_add_file_content_hash"
+}
+
+// Migrate runs the migration
+func (m *AddFileContentHash) Migrate(tx *gorm.DB) error {
+	if !tx.Migrator().HasColumn(&model.File{}, "ContentHash") {
+		if err := tx.Migrator().AddColumn(&model.File{}, "ContentHash"); err != nil {
+			return err
+		}
+	}
+	if tx.Migrator().HasIndex(&model.File{}, "ContentHash") {
+		return nil
+	}
+	return tx.Migrator().CreateIndex(&model.File{}, "ContentHash")
+}
+
+// Rollback runs the migration rollback
+func (m *AddFileContentHash) Rollback(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&model.File{}, "ContentHash")
+}
@@ -0,0 +1,26 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateBatchTables migration creates the tables holding starred items and
+// background batch jobs
+type CreateBatchTables struct{}
+
+// ID returns the migration ID
+func (m *CreateBatchTables) ID() string {
+	return "023_create_batch_tables"
+}
+
+// Migrate runs the migration
+func (m *CreateBatchTables) Migrate(tx *gorm.DB) error {
+	return tx.AutoMigrate(&model.Star{}, &model.BatchJob{})
+}
+
+// Rollback runs the migration rollback
+func (m *CreateBatchTables) Rollback(tx *gorm.DB) error {
+	return tx.Migrator().DropTable("batch_jobs", "stars")
+}
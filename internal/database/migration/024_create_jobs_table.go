@@ -0,0 +1,44 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateJobsTable migration creates the background job queue along with the
+// indexes workers use to claim due jobs and recover expired leases, and the
+// index enforcing unique keys among unfinished jobs
+type CreateJobsTable struct{}
+
+// ID returns the migration ID
+func (m *CreateJobsTable) ID() string {
+	return "024_create_jobs_table"
+}
+
+// Migrate runs the migration
+func (m *CreateJobsTable) Migrate(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&model.Job{}); err != nil {
+		return err
+	}
+	statements := []string{
+		`CREATE INDEX IF NOT EXISTS idx_jobs_due
+	ON jobs (run_at) WHERE status = 'queued'`,
+		`CREATE INDEX IF NOT EXISTS idx_jobs_leases
+	ON jobs (locked_until) WHERE status = 'running'`,
+		`CREATE INDEX IF NOT EXISTS idx_jobs_status ON jobs (status, id)`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_jobs_unique_key
+	ON jobs (unique_key) WHERE unique_key IS NOT NULL AND status IN ('queued', 'running')`,
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rollback runs the migration rollback
+func (m *CreateJobsTable) Rollback(tx *gorm.DB) error {
+	return tx.Migrator().DropTable("jobs")
+}
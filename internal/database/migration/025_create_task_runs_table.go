@@ -0,0 +1,25 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateTaskRunsTable migration creates the history of scheduled task runs
+type CreateTaskRunsTable struct{}
+
+// ID returns the migration ID
+func (m *CreateTaskRunsTable) ID() string {
+	return "025_create_task_runs_table"
+}
+
+// Migrate runs the migration
+func (m *CreateTaskRunsTable) Migrate(tx *gorm.DB) error {
+	return tx.AutoMigrate(&model.TaskRun{})
+}
+
+// Rollback runs the migration rollback
+func (m *CreateTaskRunsTable) Rollback(tx *gorm.DB) error {
+	return tx.Migrator().DropTable("task_runs")
+}
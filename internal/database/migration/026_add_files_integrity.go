@@ -0,0 +1,51 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// AddFilesIntegrity migration adds the columns recording the last integrity
+// scrub of each file, and an index on blob keys for the orphaned blob
+// collector. Existing files are marked as unverified.
+type AddFilesIntegrity struct{}
+
+// ID returns the migration ID
+func (m *AddFilesIntegrity) ID() string {
+	return "026_add_files_integrity"
+}
+
+// Migrate runs the migration
+func (m *AddFilesIntegrity) Migrate(tx *gorm.DB) error {
+	for _, field := range []string{"IntegrityStatus", "VerifiedAt"} {
+		if tx.Migrator().HasColumn(&model.File{}, field) {
+			continue
+		}
+		if err := tx.Migrator().AddColumn(&model.File{}, field); err != nil {
+			return err
+		}
+	}
+	for _, field := range []string{"IntegrityStatus", "FileURL"} {
+		if tx.Migrator().HasIndex(&model.File{}, field) {
+			continue
+		}
+		if err := tx.Migrator().CreateIndex(&model.File{}, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rollback runs the migration rollback
+func (m *AddFilesIntegrity) Rollback(tx *gorm.DB) error {
+	if err := tx.Migrator().DropIndex(&model.File{}, "FileURL"); err != nil {
+		return err
+	}
+	for _, field := range []string{"IntegrityStatus", "VerifiedAt"} {
+		if err := tx.Migrator().DropColumn(&model.File{}, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateRetentionTables migration creates the retention policy and legal
+// hold tables, along with the index used to find the holds in force
+type CreateRetentionTables struct{}
+
+// ID returns the migration ID
+func (m *CreateRetentionTables) ID() string {
+	return "027_create_retention_tables"
+}
+
+// Migrate runs the migration
+func (m *CreateRetentionTables) Migrate(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&model.RetentionPolicy{}, &model.LegalHold{}); err != nil {
+		return err
+	}
+	return tx.Exec(`CREATE INDEX IF NOT EXISTS idx_legal_holds_active
+	ON legal_holds (folder_id, user_id) WHERE released_at IS NULL`).Error
+}
+
+// Rollback runs the migration rollback
+func (m *CreateRetentionTables) Rollback(tx *gorm.DB) error {
+	return tx.Migrator().DropTable("legal_holds", "retention_policies")
+}
@@ -0,0 +1,45 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateWorkspaceTables migration creates the workspace and membership
+// tables and adds the workspace_id column to folders and files
+type CreateWorkspaceTables struct{}
+
+// ID returns the migration ID
+func (m *CreateWorkspaceTables) ID() string {
+	return "028_create_workspace_tables"
+}
+
+// Migrate runs the migration
+func (m *CreateWorkspaceTables) Migrate(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&model.Workspace{}, &model.WorkspaceMember{}); err != nil {
+		return err
+	}
+	for _, table := range []interface{}{&model.Folder{}, &model.File{}} {
+		if tx.Migrator().HasColumn(table, "WorkspaceID") {
+			continue
+		}
+		if err := tx.Migrator().AddColumn(table, "WorkspaceID"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().CreateIndex(table, "WorkspaceID"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rollback runs the migration rollback
+func (m *CreateWorkspaceTables) Rollback(tx *gorm.DB) error {
+	for _, table := range []interface{}{&model.Folder{}, &model.File{}} {
+		if err := tx.Migrator().DropColumn(table, "WorkspaceID"); err != nil {
+			return err
+		}
+	}
+	return tx.Migrator().DropTable("workspace_members", "workspaces")
+}
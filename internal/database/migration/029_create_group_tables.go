@@ -0,0 +1,49 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// CreateGroupTables migration creates the group and membership tables and
+// lets shares target a group instead of a user
+type CreateGroupTables struct{}
+
+// ID returns the migration ID
+func (m *CreateGroupTables) ID() string {
+	return "029_create_group_tables"
+}
+
+// Migrate runs the migration
+func (m *CreateGroupTables) Migrate(tx *gorm.DB) error {
+	if err := tx.AutoMigrate(&model.Group{}, &model.GroupMember{}); err != nil {
+		return err
+	}
+	if !tx.Migrator().HasColumn(&model.Share{}, "GroupID") {
+		if err := tx.Migrator().AddColumn(&model.Share{}, "GroupID"); err != nil {
+			return err
+		}
+		if err := tx.Migrator().CreateIndex(&model.Share{}, "GroupID"); err != nil {
+			return err
+		}
+	}
+	return tx.Exec(`ALTER TABLE shares ALTER COLUMN shared_with_id DROP NOT NULL`).Error
+}
+
+// Rollback runs the migration rollback
+func (m *CreateGroupTables) Rollback(tx *gorm.DB) error {
+	statements := []string{
+		`DELETE FROM shares WHERE shared_with_id IS NULL`,
+		`ALTER TABLE shares ALTER COLUMN shared_with_id SET NOT NULL`,
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+	if err := tx.Migrator().DropColumn(&model.Share{}, "GroupID"); err != nil {
+		return err
+	}
+	return tx.Migrator().DropTable("group_members", "groups")
+}
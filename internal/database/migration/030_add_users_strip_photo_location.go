@@ -0,0 +1,29 @@
+package migration
+
+import (
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// AddUsersStripPhotoLocation migration adds the strip_photo_location column
+// to the users table
+type AddUsersStripPhotoLocation struct{}
+
+// ID returns the migration ID
+func (m *AddUsersStripPhotoLocation) ID() string {
+	return "030_add_users_strip_photo_location"
+}
+
+// Migrate runs the migration
+func (m *AddUsersStripPhotoLocation) Migrate(tx *gorm.DB) error {
+	if tx.Migrator().HasColumn(&model.User{}, "StripPhotoLocation") {
+		return nil
+	}
+	return tx.Migrator().AddColumn(&model.User{}, "StripPhotoLocation")
+}
+
+// Rollback runs the migration rollback
+func (m *AddUsersStripPhotoLocation) Rollback(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&model.User{}, "StripPhotoLocation")
+}
@@ -15,6 +15,32 @@ func RegisterMigrations(db *gorm.DB, logger *util.Logger) *Migrator {
 	migrator.AddMigration(&CreateFoldersTable{})
 	migrator.AddMigration(&CreateFilesTable{})
 	migrator.AddMigration(&CreateSharesTable{})
+	migrator.AddMigration(&CreateTagsTable{})
+	migrator.AddMigration(&AddSearchIndexes{})
+	migrator.AddMigration(&AddFilesMimeType{})
+	migrator.AddMigration(&CreateFileContentsTable{})
+	migrator.AddMigration(&AddUsersIsAdmin{})
+	migrator.AddMigration(&UpdateSharesFileID{})
+	migrator.AddMigration(&CreateAuditLogsTable{})
+	migrator.AddMigration(&AddAuditLogChain{})
+	migrator.AddMigration(&CreateWebhooksTables{})
+	migrator.AddMigration(&CreateChangesTable{})
+	migrator.AddMigration(&CreateAppPasswordsTable{})
+	migrator.AddMigration(&AddFilesEncryption{})
+	migrator.AddMigration(&CreateVaultTables{})
+	migrator.AddMigration(&AddFilesScanStatus{})
+	migrator.AddMigration(&CreateFileLocksTable{})
+	migrator.AddMigration(&CreateCommentsTables{})
+	migrator.AddMigration(&CreatePhotoTables{})
+	migrator.AddMigration(&AddFileContentHash{})
+	migrator.AddMigration(&CreateBatchTables{})
+	migrator.AddMigration(&CreateJobsTable{})
+	migrator.AddMigration(&CreateTaskRunsTable{})
+	migrator.AddMigration(&AddFilesIntegrity{})
+	migrator.AddMigration(&CreateRetentionTables{})
+	migrator.AddMigration(&CreateWorkspaceTables{})
+	migrator.AddMigration(&CreateGroupTables{})
+	migrator.AddMigration(&AddUsersStripPhotoLocation{})
 
 	return migrator
 }
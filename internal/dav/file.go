@@ -0,0 +1,337 @@
+package dav
+
+import (
+	"context"
+	"drive/internal/model"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+var errNotSupported = errors.New("operation not supported")
+
+// node is a resolved folder or file. It implements os.FileInfo along with
+// the webdav.ContentTyper and webdav.ETager extensions, so PROPFIND never
+// has to read file content.
+type node struct {
+	folder *model.Folder
+	file   *model.File
+	root   bool
+}
+
+func (n *node) isRoot() bool { return n.root }
+
+func (n *node) Name() string {
+	if n.folder != nil {
+		return n.folder.FolderName
+	}
+	return n.file.FileName
+}
+
+func (n *node) Size() int64 {
+	if n.file != nil {
+		return n.file.FileSize
+	}
+	return 0
+}
+
+func (n *node) Mode() os.FileMode {
+	if n.folder != nil {
+		return os.ModeDir | 0o755
+	}
+	return 0o644
+}
+
+func (n *node) ModTime() time.Time {
+	if n.folder != nil {
+		return n.folder.UpdatedAt
+	}
+	return n.file.UpdatedAt
+}
+
+func (n *node) IsDir() bool { return n.folder != nil }
+
+func (n *node) Sys() interface{} { return nil }
+
+// ContentType implements webdav.ContentTyper
+func (n *node) ContentType(ctx context.Context) (string, error) {
+	if n.file != nil && n.file.MimeType != "" {
+		return n.file.MimeType, nil
+	}
+	return "application/octet-stream", nil
+}
+
+// ETag implements webdav.ETager
+func (n *node) ETag(ctx context.Context) (string, error) {
+	if n.file != nil {
+		return n.file.ETag(), nil
+	}
+	return fmt.Sprintf(`"%x-%x-%x"`, n.ModTime().UnixNano(), n.Size(), n.id()), nil
+}
+
+func (n *node) id() uint {
+	if n.folder != nil {
+		return n.folder.ID
+	}
+	return n.file.ID
+}
+
+// readFile is a folder or file opened for reading. File content is only
+// opened, and the download audited, once it is actually read.
+type readFile struct {
+	ctx  context.Context
+	fs   *fileSystem
+	node *node
+
+	content  io.ReadSeekCloser
+	offset   int64
+	children []os.FileInfo
+	listed   bool
+}
+
+// Read implements io.Reader
+func (f *readFile) Read(p []byte) (int, error) {
+	if f.node.file == nil {
+		return 0, errNotSupported
+	}
+	if f.content == nil {
+		_, content, err := f.fs.files.Download(f.ctx, f.fs.userID, f.node.file.ID)
+		if err != nil {
+			return 0, mapError(err)
+		}
+		if _, err := content.Seek(f.offset, io.SeekStart); err != nil {
+			content.Close()
+			return 0, err
+		}
+		f.content = content
+	}
+	n, err := f.content.Read(p)
+	f.offset += int64(n)
+	return n, err
+}
+
+// Seek implements io.Seeker. Before the content is opened the offset is
+// tracked locally, which lets http.ServeContent find the size cheaply.
+func (f *readFile) Seek(offset int64, whence int) (int64, error) {
+	if f.node.file == nil {
+		return 0, errNotSupported
+	}
+	if f.content != nil {
+		n, err := f.content.Seek(offset, whence)
+		if err == nil {
+			f.offset = n
+		}
+		return n, err
+	}
+
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += f.offset
+	case io.SeekEnd:
+		offset += f.node.file.FileSize
+	default:
+		return 0, errNotSupported
+	}
+	if offset < 0 {
+		return 0, os.ErrInvalid
+	}
+	f.offset = offset
+	return offset, nil
+}
+
+// Readdir implements http.File with the semantics of os.File.Readdir
+func (f *readFile) Readdir(count int) ([]os.FileInfo, error) {
+	if f.node.folder == nil {
+		return nil, errNotSupported
+	}
+	if !f.listed {
+		contents, err := f.fs.list(f.ctx, f.node.folder.ID)
+		if err != nil {
+			return nil, err
+		}
+		for i := range contents.Folders {
+			f.children = append(f.children, &node{folder: &contents.Folders[i]})
+		}
+		for i := range contents.Files {
+			f.children = append(f.children, &node{file: &contents.Files[i]})
+		}
+		f.listed = true
+	}
+
+	if count <= 0 {
+		children := f.children
+		f.children = nil
+		return children, nil
+	}
+	if len(f.children) == 0 {
+		return nil, io.EOF
+	}
+	if count > len(f.children) {
+		count = len(f.children)
+	}
+	children := f.children[:count]
+	f.children = f.children[count:]
+	return children, nil
+}
+
+// Stat implements http.File
+func (f *readFile) Stat() (os.FileInfo, error) {
+	return f.node, nil
+}
+
+// Write implements io.Writer; files opened for reading are read-only
+func (f *readFile) Write(p []byte) (int, error) {
+	return 0, os.ErrPermission
+}
+
+// Close implements io.Closer
+func (f *readFile) Close() error {
+	if f.content != nil {
+		return f.content.Close()
+	}
+	return nil
+}
+
+// uploadFile is a file opened for writing. Writes are piped into an upload
+// running in the background, which completes when the file is closed.
+type uploadFile struct {
+	pipe *io.PipeWriter
+	// body is the PUT request body being copied in, if any. The upload is
+	// only committed once all of it has been received.
+	body    *requestBody
+	written int64
+
+	closed sync.Once
+	done   chan struct{}
+	result *model.File
+	err    error
+}
+
+// startUpload begins streaming into store, which receives everything
+// written to the returned file
+func (fs *fileSystem) startUpload(store func(r io.Reader) (*model.File, error)) *uploadFile {
+	reader, writer := io.Pipe()
+	f := &uploadFile{
+		pipe: writer,
+		body: fs.body,
+		done: make(chan struct{}),
+	}
+
+	go func() {
+		defer close(f.done)
+		defer fs.invalidate()
+		f.result, f.err = store(reader)
+		f.err = mapError(f.err)
+		// Unblock the writer if the upload stopped reading early
+		if f.err != nil {
+			reader.CloseWithError(f.err)
+		} else {
+			reader.Close()
+		}
+	}()
+
+	return f
+}
+
+// Write implements io.Writer
+func (f *uploadFile) Write(p []byte) (int, error) {
+	n, err := f.pipe.Write(p)
+	f.written += int64(n)
+	return n, err
+}
+
+// Close finishes the upload and reports whether it succeeded. An upload
+// whose request body was cut short is abandoned rather than stored.
+func (f *uploadFile) Close() error {
+	f.closed.Do(func() {
+		if f.body != nil && !f.body.received(f.written) {
+			f.pipe.CloseWithError(io.ErrUnexpectedEOF)
+		} else {
+			f.pipe.Close()
+		}
+		<-f.done
+	})
+	return f.err
+}
+
+// Stat implements http.File. webdav.Handler calls it before Close, and only
+// asks the result for the new ETag after closing, so the description is
+// read from the stored file once the upload has finished.
+func (f *uploadFile) Stat() (os.FileInfo, error) {
+	return &uploadInfo{upload: f}, nil
+}
+
+// uploadInfo describes a file opened for writing: the stored file once the
+// upload has finished, or the bytes written so far before that
+type uploadInfo struct {
+	upload *uploadFile
+}
+
+// stored returns the uploaded file, or nil while the upload is running or
+// if it failed
+func (i *uploadInfo) stored() *node {
+	select {
+	case <-i.upload.done:
+		if i.upload.result != nil {
+			return &node{file: i.upload.result}
+		}
+	default:
+	}
+	return nil
+}
+
+func (i *uploadInfo) Name() string {
+	if n := i.stored(); n != nil {
+		return n.Name()
+	}
+	return ""
+}
+
+func (i *uploadInfo) Size() int64 {
+	if n := i.stored(); n != nil {
+		return n.Size()
+	}
+	return i.upload.written
+}
+
+func (i *uploadInfo) Mode() os.FileMode { return 0o644 }
+
+func (i *uploadInfo) ModTime() time.Time {
+	if n := i.stored(); n != nil {
+		return n.ModTime()
+	}
+	return time.Time{}
+}
+
+func (i *uploadInfo) IsDir() bool { return false }
+
+func (i *uploadInfo) Sys() interface{} { return nil }
+
+// ETag implements webdav.ETager
+func (i *uploadInfo) ETag(ctx context.Context) (string, error) {
+	if n := i.stored(); n != nil {
+		return n.ETag(ctx)
+	}
+	return "", webdav.ErrNotImplemented
+}
+
+// Read implements io.Reader; files opened for writing are write-only
+func (f *uploadFile) Read(p []byte) (int, error) {
+	return 0, errNotSupported
+}
+
+// Seek implements io.Seeker
+func (f *uploadFile) Seek(offset int64, whence int) (int64, error) {
+	return 0, errNotSupported
+}
+
+// Readdir implements http.File
+func (f *uploadFile) Readdir(count int) ([]os.FileInfo, error) {
+	return nil, errNotSupported
+}
@@ -0,0 +1,278 @@
+package dav
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/service"
+	"errors"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	"golang.org/x/net/webdav"
+)
+
+// fileSystem maps WebDAV paths onto the folders and files one user can
+// reach from a root folder. A new one is made for every request, so folder
+// listings are cached for the length of the request only.
+type fileSystem struct {
+	userID uint
+	// rootID is the folder served as the root, 0 for the user's own
+	rootID     uint
+	uploadSize int64
+	body       *requestBody
+	files      service.FileService
+	folders    service.FolderService
+
+	contents map[uint]*model.FolderContents
+	root     *model.FolderContents
+}
+
+// newFileSystem creates a file system for one request. body is the body of
+// a PUT request, whose declared length reserves quota for the upload and
+// must be received in full before it is stored.
+func newFileSystem(userID, rootID uint, body *requestBody, files service.FileService, folders service.FolderService) *fileSystem {
+	var uploadSize int64
+	if body != nil && body.size > 0 {
+		uploadSize = body.size
+	}
+	return &fileSystem{
+		userID:     userID,
+		rootID:     rootID,
+		uploadSize: uploadSize,
+		body:       body,
+		files:      files,
+		folders:    folders,
+		contents:   make(map[uint]*model.FolderContents),
+	}
+}
+
+// Mkdir implements webdav.FileSystem
+func (fs *fileSystem) Mkdir(ctx context.Context, name string, perm os.FileMode) error {
+	parent, base, err := fs.resolveParent(ctx, name)
+	if err != nil {
+		return err
+	}
+	if _, err := fs.child(ctx, parent, base); err == nil {
+		return os.ErrExist
+	}
+
+	defer fs.invalidate()
+	_, err = fs.folders.Create(ctx, fs.userID, &model.CreateFolderRequest{Name: base, ParentID: parent.ID})
+	return mapError(err)
+}
+
+// OpenFile implements webdav.FileSystem. Opening for writing streams the
+// content into a new file, or replaces the content of an existing one,
+// when the file is closed.
+func (fs *fileSystem) OpenFile(ctx context.Context, name string, flag int, perm os.FileMode) (webdav.File, error) {
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC) == 0 {
+		n, err := fs.resolve(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+		return &readFile{ctx: ctx, fs: fs, node: n}, nil
+	}
+
+	n, err := fs.resolve(ctx, name)
+	switch {
+	case err == nil && n.folder != nil:
+		return nil, os.ErrExist
+	case err == nil:
+		if flag&os.O_EXCL != 0 {
+			return nil, os.ErrExist
+		}
+		fileID := n.file.ID
+		return fs.startUpload(func(r io.Reader) (*model.File, error) {
+			return fs.files.Replace(ctx, fs.userID, fileID, fs.uploadSize, r, nil)
+		}), nil
+	case !errors.Is(err, os.ErrNotExist) || flag&os.O_CREATE == 0:
+		return nil, err
+	}
+
+	parent, base, err := fs.resolveParent(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return fs.startUpload(func(r io.Reader) (*model.File, error) {
+		return fs.files.Upload(ctx, fs.userID, parent.ID, base, fs.uploadSize, r)
+	}), nil
+}
+
+// RemoveAll implements webdav.FileSystem. Folders are moved to the trash
+// along with everything beneath them.
+func (fs *fileSystem) RemoveAll(ctx context.Context, name string) error {
+	n, err := fs.resolve(ctx, name)
+	if err != nil {
+		return err
+	}
+	if n.isRoot() {
+		return os.ErrPermission
+	}
+
+	defer fs.invalidate()
+	if n.folder != nil {
+		return mapError(fs.folders.Delete(ctx, fs.userID, n.folder.ID))
+	}
+	return mapError(fs.files.Delete(ctx, fs.userID, n.file.ID))
+}
+
+// Rename implements webdav.FileSystem as a rename and/or move
+func (fs *fileSystem) Rename(ctx context.Context, oldName, newName string) error {
+	n, err := fs.resolve(ctx, oldName)
+	if err != nil {
+		return err
+	}
+	if n.isRoot() {
+		return os.ErrPermission
+	}
+	parent, base, err := fs.resolveParent(ctx, newName)
+	if err != nil {
+		return err
+	}
+
+	defer fs.invalidate()
+	if n.folder != nil {
+		_, err = fs.folders.Update(ctx, fs.userID, n.folder.ID, &model.UpdateFolderRequest{Name: &base, ParentID: &parent.ID})
+	} else {
+		_, err = fs.files.Update(ctx, fs.userID, n.file.ID, &model.UpdateFileRequest{Name: &base, FolderID: &parent.ID})
+	}
+	return mapError(err)
+}
+
+// Stat implements webdav.FileSystem
+func (fs *fileSystem) Stat(ctx context.Context, name string) (os.FileInfo, error) {
+	n, err := fs.resolve(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// resolve walks a path from the root folder
+func (fs *fileSystem) resolve(ctx context.Context, name string) (*node, error) {
+	root, err := fs.list(ctx, fs.rootID)
+	if err != nil {
+		return nil, err
+	}
+
+	current := &node{folder: root.Folder, root: true}
+	for _, segment := range splitPath(name) {
+		if current.folder == nil {
+			return nil, os.ErrNotExist
+		}
+		if current, err = fs.child(ctx, current.folder, segment); err != nil {
+			return nil, err
+		}
+	}
+	return current, nil
+}
+
+// resolveParent resolves the folder a path would live in, returning it
+// along with the last path segment
+func (fs *fileSystem) resolveParent(ctx context.Context, name string) (*model.Folder, string, error) {
+	segments := splitPath(name)
+	if len(segments) == 0 {
+		return nil, "", os.ErrPermission
+	}
+	parent, err := fs.resolve(ctx, "/"+strings.Join(segments[:len(segments)-1], "/"))
+	if err != nil {
+		return nil, "", err
+	}
+	if parent.folder == nil {
+		return nil, "", os.ErrNotExist
+	}
+	return parent.folder, segments[len(segments)-1], nil
+}
+
+// child finds an item by name in a folder. A folder wins over a file with
+// the same name, and the oldest of several files with the same name wins.
+func (fs *fileSystem) child(ctx context.Context, folder *model.Folder, name string) (*node, error) {
+	contents, err := fs.list(ctx, folder.ID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range contents.Folders {
+		if contents.Folders[i].FolderName == name {
+			return &node{folder: &contents.Folders[i]}, nil
+		}
+	}
+	var match *model.File
+	for i := range contents.Files {
+		if f := &contents.Files[i]; f.FileName == name && (match == nil || f.ID < match.ID) {
+			match = f
+		}
+	}
+	if match == nil {
+		return nil, os.ErrNotExist
+	}
+	return &node{file: match}, nil
+}
+
+// list returns a folder's children, caching them for the rest of the
+// request. A zero folderID lists the user's root folder. Vaults are left
+// out: their content is encrypted by the client and cannot be served or
+// written in plain form.
+func (fs *fileSystem) list(ctx context.Context, folderID uint) (*model.FolderContents, error) {
+	if folderID == 0 && fs.root != nil {
+		return fs.root, nil
+	}
+	if contents, ok := fs.contents[folderID]; ok {
+		return contents, nil
+	}
+
+	contents, err := fs.folders.Get(ctx, fs.userID, folderID)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	if contents.Folder.VaultID != nil {
+		return nil, os.ErrNotExist
+	}
+	folders := contents.Folders[:0]
+	for _, folder := range contents.Folders {
+		if folder.VaultID == nil {
+			folders = append(folders, folder)
+		}
+	}
+	contents.Folders = folders
+	fs.contents[contents.Folder.ID] = contents
+	if folderID == 0 {
+		fs.root = contents
+	}
+	return contents, nil
+}
+
+// invalidate drops cached listings after a change
+func (fs *fileSystem) invalidate() {
+	fs.contents = make(map[uint]*model.FolderContents)
+	fs.root = nil
+}
+
+// splitPath breaks a WebDAV path into its segments
+func splitPath(name string) []string {
+	name = strings.Trim(path.Clean("/"+name), "/")
+	if name == "" {
+		return nil
+	}
+	return strings.Split(name, "/")
+}
+
+// mapError translates service errors into the os errors webdav.Handler
+// turns into status codes
+func mapError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, service.ErrFileNotFound), errors.Is(err, service.ErrFolderNotFound):
+		return os.ErrNotExist
+	case errors.Is(err, service.ErrPermissionDenied), errors.Is(err, service.ErrRootFolder),
+		errors.Is(err, service.ErrFileQuarantined), errors.Is(err, service.ErrFileNotScanned),
+		errors.Is(err, service.ErrFileLocked),
+		errors.Is(err, service.ErrLegalHold), errors.Is(err, service.ErrRetentionPeriod),
+		errors.Is(err, service.ErrWorkspaceBoundary):
+		return os.ErrPermission
+	default:
+		return err
+	}
+}
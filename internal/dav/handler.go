@@ -0,0 +1,171 @@
+// Package dav serves the drive over WebDAV. Paths are resolved from the
+// user's root folder, or from a workspace's, and every operation goes
+// through the file and folder services, so storage, quotas and permissions
+// behave as in the REST API.
+package dav
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"go.uber.org/zap"
+	"golang.org/x/net/webdav"
+)
+
+// Handler serves WebDAV requests for the authenticated user
+type Handler struct {
+	prefix     string
+	files      service.FileService
+	folders    service.FolderService
+	fileLocks  service.LockService
+	workspaces service.WorkspaceService
+	logger     *util.Logger
+
+	mu    sync.Mutex
+	locks map[lockTable]webdav.LockSystem
+}
+
+// lockTable identifies an in-memory lock table: one per user and tree
+type lockTable struct {
+	userID uint
+	rootID uint
+}
+
+// NewHandler creates a WebDAV handler serving each user's own drive under
+// prefix
+func NewHandler(prefix string, files service.FileService, folders service.FolderService, fileLocks service.LockService, logger *util.Logger) *Handler {
+	return &Handler{
+		prefix:    prefix,
+		files:     files,
+		folders:   folders,
+		fileLocks: fileLocks,
+		logger:    logger,
+		locks:     make(map[lockTable]webdav.LockSystem),
+	}
+}
+
+// NewWorkspaceHandler creates a WebDAV handler serving every workspace the
+// user belongs to as its own tree under prefix/{id}
+func NewWorkspaceHandler(prefix string, files service.FileService, folders service.FolderService, fileLocks service.LockService, workspaces service.WorkspaceService, logger *util.Logger) *Handler {
+	h := NewHandler(prefix, files, folders, fileLocks, logger)
+	h.workspaces = workspaces
+	return h
+}
+
+// ServeHTTP implements http.Handler. It must run after middleware.DAVAuth.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	prefix, rootID, err := h.mount(r, userID)
+	if err != nil {
+		if errors.Is(err, service.ErrWorkspaceNotFound) {
+			http.NotFound(w, r)
+			return
+		}
+		h.logger.Error("Error resolving WebDAV workspace", util.WithUserID(userID), util.WithError(err))
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	var body *requestBody
+	if r.Method == http.MethodPut {
+		body = &requestBody{ReadCloser: r.Body, size: r.ContentLength}
+		r.Body = body
+	}
+	fs := newFileSystem(userID, rootID, body, h.files, h.folders)
+	server := &webdav.Handler{
+		Prefix:     prefix,
+		FileSystem: fs,
+		LockSystem: &lockSystem{
+			ctx:    r.Context(),
+			userID: userID,
+			method: r.Method,
+			path:   requestPath(r, prefix),
+			fs:     fs,
+			locks:  h.fileLocks,
+			mem:    h.lockSystem(lockTable{userID: userID, rootID: rootID}),
+		},
+		Logger: func(r *http.Request, err error) {
+			if err != nil {
+				h.logger.Debug("WebDAV request failed", util.WithUserID(userID), zap.String("method", r.Method), util.WithPath(r.URL.Path), util.WithError(err))
+			}
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+// requestBody records whether a request body was read to its end, so an
+// upload the client broke off is never stored
+type requestBody struct {
+	io.ReadCloser
+	// size is the declared length, or -1 when unknown
+	size int64
+	eof  bool
+}
+
+// Read implements io.Reader
+func (b *requestBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	if err == io.EOF {
+		b.eof = true
+	}
+	return n, err
+}
+
+// received reports whether the whole body arrived and written bytes of it
+// were passed on
+func (b *requestBody) received(written int64) bool {
+	return b.eof && (b.size < 0 || written == b.size)
+}
+
+// mount returns the prefix the request's tree is served under and the
+// folder at its root, 0 for the user's own root folder. Workspaces are
+// mounted at prefix/{id} for their members only.
+func (h *Handler) mount(r *http.Request, userID uint) (string, uint, error) {
+	if h.workspaces == nil {
+		return h.prefix, 0, nil
+	}
+	segment, _, _ := strings.Cut(strings.TrimPrefix(requestPath(r, h.prefix), "/"), "/")
+	workspaceID, err := strconv.ParseUint(segment, 10, 0)
+	if err != nil || workspaceID == 0 {
+		return "", 0, service.ErrWorkspaceNotFound
+	}
+	workspace, err := h.workspaces.Get(r.Context(), userID, uint(workspaceID))
+	if err != nil {
+		return "", 0, err
+	}
+	return h.prefix + "/" + segment, workspace.RootFolderID, nil
+}
+
+// requestPath returns the path of a request below prefix
+func requestPath(r *http.Request, prefix string) string {
+	if p := strings.TrimPrefix(r.URL.Path, prefix); p != "" {
+		return p
+	}
+	return "/"
+}
+
+// lockSystem returns the in-memory lock table of a user's tree. Lock paths
+// are relative to the tree's root, so neither users nor trees can share
+// one table.
+func (h *Handler) lockSystem(table lockTable) webdav.LockSystem {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ls, ok := h.locks[table]
+	if !ok {
+		ls = webdav.NewMemLS()
+		h.locks[table] = ls
+	}
+	return ls
+}
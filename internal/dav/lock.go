@@ -0,0 +1,215 @@
+package dav
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/service"
+	"errors"
+	"time"
+
+	"golang.org/x/net/webdav"
+)
+
+// lockSystem stores WebDAV locks on files as file locks, so a file checked
+// out over WebDAV cannot be changed through the REST API by anyone else and
+// the other way round. Locks on folders and on paths that do not exist yet,
+// as well as the short-lived locks webdav.Handler takes around every
+// change, stay in the user's in-memory table. A new one is made for every
+// request.
+type lockSystem struct {
+	ctx    context.Context
+	userID uint
+	method string
+	// path is the request path, which is the root of a refreshed lock
+	path  string
+	fs    *fileSystem
+	locks service.LockService
+	mem   webdav.LockSystem
+}
+
+// Confirm implements webdav.LockSystem. Tokens of file locks presented in
+// an If header must belong to the user; files they cover need no further
+// locking. Files locked by others cannot be changed without one of their
+// tokens.
+func (ls *lockSystem) Confirm(now time.Time, name0, name1 string, conditions ...webdav.Condition) (func(), error) {
+	held := make(map[uint]bool)
+	var rest []webdav.Condition
+	for _, c := range conditions {
+		lock, err := ls.fileLock(c.Token)
+		if err != nil {
+			return nil, err
+		}
+		if lock == nil {
+			rest = append(rest, c)
+			continue
+		}
+		if lock.UserID != ls.userID || c.Not {
+			return nil, webdav.ErrConfirmationFailed
+		}
+		held[lock.FileID] = true
+	}
+
+	var names []string
+	for _, name := range []string{name0, name1} {
+		if name == "" {
+			continue
+		}
+		file := ls.file(name)
+		if file != nil && held[file.ID] {
+			continue
+		}
+		if file != nil {
+			if err := ls.locks.CheckFile(ls.ctx, ls.userID, file.ID); errors.Is(err, service.ErrFileLocked) {
+				return nil, webdav.ErrConfirmationFailed
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		names = append(names, name)
+	}
+
+	switch {
+	case len(names) == 0:
+		return func() {}, nil
+	case len(rest) > 0 || len(held) == 0:
+		names = append(names, "")
+		return ls.mem.Confirm(now, names[0], names[1], rest...)
+	default:
+		// Only file lock tokens were presented, so the other names are
+		// claimed as if no If header had been sent
+		return ls.claim(now, names)
+	}
+}
+
+// Create implements webdav.LockSystem. A LOCK request on a file checks it
+// out exclusively; webdav.Handler's own short-lived locks only check that
+// nobody else has.
+func (ls *lockSystem) Create(now time.Time, details webdav.LockDetails) (string, error) {
+	file := ls.file(details.Root)
+	if file == nil {
+		return ls.mem.Create(now, details)
+	}
+
+	if ls.method != "LOCK" {
+		if err := ls.locks.CheckFile(ls.ctx, ls.userID, file.ID); err != nil {
+			return "", mapLockError(err)
+		}
+		return ls.mem.Create(now, details)
+	}
+
+	lock, err := ls.locks.Lock(ls.ctx, ls.userID, file.ID, &model.LockFileRequest{
+		Scope:           model.LockExclusive,
+		DurationSeconds: lockSeconds(details.Duration),
+	})
+	if errors.Is(err, service.ErrPermissionDenied) {
+		// Users who cannot write to the file cannot check it out
+		return ls.mem.Create(now, details)
+	}
+	if err != nil {
+		return "", mapLockError(err)
+	}
+	return lock.Token, nil
+}
+
+// Refresh implements webdav.LockSystem
+func (ls *lockSystem) Refresh(now time.Time, token string, duration time.Duration) (webdav.LockDetails, error) {
+	lock, err := ls.fileLock(token)
+	if err != nil {
+		return webdav.LockDetails{}, err
+	}
+	if lock == nil {
+		return ls.mem.Refresh(now, token, duration)
+	}
+	if lock.UserID != ls.userID {
+		return webdav.LockDetails{}, webdav.ErrNoSuchLock
+	}
+
+	lock, err = ls.locks.Refresh(ls.ctx, ls.userID, token, time.Duration(lockSeconds(duration))*time.Second)
+	if err != nil {
+		return webdav.LockDetails{}, mapLockError(err)
+	}
+	return webdav.LockDetails{
+		Root:      ls.path,
+		Duration:  lock.ExpiresAt.Sub(now).Round(time.Second),
+		ZeroDepth: true,
+	}, nil
+}
+
+// Unlock implements webdav.LockSystem
+func (ls *lockSystem) Unlock(now time.Time, token string) error {
+	lock, err := ls.fileLock(token)
+	if err != nil {
+		return err
+	}
+	if lock == nil {
+		return ls.mem.Unlock(now, token)
+	}
+	return mapLockError(ls.locks.Release(ls.ctx, ls.userID, token))
+}
+
+// claim takes short-lived locks on names, released together
+func (ls *lockSystem) claim(now time.Time, names []string) (func(), error) {
+	var tokens []string
+	release := func() {
+		for _, token := range tokens {
+			ls.mem.Unlock(now, token)
+		}
+	}
+	for _, name := range names {
+		token, err := ls.mem.Create(now, webdav.LockDetails{Root: name, Duration: -1, ZeroDepth: true})
+		if err != nil {
+			release()
+			if errors.Is(err, webdav.ErrLocked) {
+				return nil, webdav.ErrConfirmationFailed
+			}
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+	return release, nil
+}
+
+// fileLock returns the active file lock with a token, or nil for tokens of
+// the in-memory table
+func (ls *lockSystem) fileLock(token string) (*model.FileLock, error) {
+	if token == "" {
+		return nil, nil
+	}
+	return ls.locks.LockByToken(ls.ctx, token)
+}
+
+// file resolves a lock name to a file, or nil if it names a folder or
+// nothing
+func (ls *lockSystem) file(name string) *model.File {
+	n, err := ls.fs.resolve(ls.ctx, name)
+	if err != nil || n.file == nil {
+		return nil
+	}
+	return n.file
+}
+
+// lockSeconds converts a WebDAV timeout, negative for infinite, into a
+// lock duration in seconds
+func lockSeconds(d time.Duration) int64 {
+	if d < 0 || d > service.MaxLockDuration {
+		d = service.MaxLockDuration
+	}
+	return int64(d / time.Second)
+}
+
+// mapLockError translates lock service errors into the errors
+// webdav.Handler turns into status codes
+func mapLockError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, service.ErrFileLocked):
+		return webdav.ErrLocked
+	case errors.Is(err, service.ErrLockNotFound):
+		return webdav.ErrNoSuchLock
+	case errors.Is(err, service.ErrPermissionDenied):
+		return webdav.ErrForbidden
+	default:
+		return err
+	}
+}
@@ -0,0 +1,176 @@
+// Package encryption implements envelope encryption for stored blobs.
+//
+// Every blob is encrypted with its own random data key. The data key is then
+// wrapped (encrypted) with a master key from the server configuration and
+// stored next to the file's metadata along with the master key's ID. Master
+// keys can therefore be rotated by re-wrapping the small data keys, without
+// touching the blobs themselves.
+//
+// Blob content is split into fixed size chunks sealed individually with
+// AES-256-GCM, so any byte range can be read by decrypting only the chunks
+// that cover it.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+// KeySize is the length of master and data keys in bytes
+const KeySize = 32
+
+var (
+	ErrInvalidKeyring = errors.New("invalid encryption keyring")
+	ErrUnknownKey     = errors.New("unknown master key")
+	ErrUnwrapFailed   = errors.New("data key could not be unwrapped")
+)
+
+// Keyring holds the configured master keys. The primary key wraps new data
+// keys; the others are only kept to unwrap data keys that have not been
+// rotated yet.
+type Keyring struct {
+	primary string
+	keys    map[string]cipher.AEAD
+}
+
+// ParseKeyring reads master keys given as a comma separated list of
+// id:base64-key pairs. primary names the key used for new data keys and
+// defaults to the first one listed. An empty list returns a nil keyring,
+// which disables encryption.
+func ParseKeyring(spec, primary string) (*Keyring, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		if primary != "" {
+			return nil, fmt.Errorf("%w: primary key %q is not configured", ErrInvalidKeyring, primary)
+		}
+		return nil, nil
+	}
+
+	k := &Keyring{keys: make(map[string]cipher.AEAD)}
+	for _, pair := range strings.Split(spec, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if !ok || id == "" || strings.ContainsAny(id, " \t") {
+			return nil, fmt.Errorf("%w: expected id:base64-key, got %q", ErrInvalidKeyring, pair)
+		}
+		if _, exists := k.keys[id]; exists {
+			return nil, fmt.Errorf("%w: key %q is listed twice", ErrInvalidKeyring, id)
+		}
+		raw, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %q: %v", ErrInvalidKeyring, id, err)
+		}
+		if len(raw) != KeySize {
+			return nil, fmt.Errorf("%w: key %q: expected %d bytes, got %d", ErrInvalidKeyring, id, KeySize, len(raw))
+		}
+		aead, err := newAEAD(raw)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[id] = aead
+		if k.primary == "" {
+			k.primary = id
+		}
+	}
+
+	if primary != "" {
+		if _, ok := k.keys[primary]; !ok {
+			return nil, fmt.Errorf("%w: primary key %q is not configured", ErrInvalidKeyring, primary)
+		}
+		k.primary = primary
+	}
+	return k, nil
+}
+
+// Primary returns the ID of the key that wraps new data keys
+func (k *Keyring) Primary() string {
+	return k.primary
+}
+
+// NewDataKey generates a random data key and returns it along with its
+// wrapped form and the ID of the master key that wrapped it
+func (k *Keyring) NewDataKey() (key, wrapped []byte, keyID string, err error) {
+	key = make([]byte, KeySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, nil, "", fmt.Errorf("error generating data key: %w", err)
+	}
+	wrapped, err = k.wrap(k.primary, key)
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return key, wrapped, k.primary, nil
+}
+
+// Unwrap recovers a data key wrapped by the master key keyID. A nil
+// keyring knows no keys.
+func (k *Keyring) Unwrap(keyID string, wrapped []byte) ([]byte, error) {
+	if k == nil {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	aead, ok := k.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	size := aead.NonceSize()
+	if len(wrapped) < size {
+		return nil, ErrUnwrapFailed
+	}
+	// The key ID is authenticated so a wrapped key cannot be relabelled
+	key, err := aead.Open(nil, wrapped[:size], wrapped[size:], []byte(keyID))
+	if err != nil || len(key) != KeySize {
+		return nil, ErrUnwrapFailed
+	}
+	return key, nil
+}
+
+// Rewrap moves a data key under the primary master key, returning the new
+// wrapped key and key ID
+func (k *Keyring) Rewrap(keyID string, wrapped []byte) ([]byte, string, error) {
+	key, err := k.Unwrap(keyID, wrapped)
+	if err != nil {
+		return nil, "", err
+	}
+	rewrapped, err := k.wrap(k.primary, key)
+	if err != nil {
+		return nil, "", err
+	}
+	return rewrapped, k.primary, nil
+}
+
+// wrap encrypts a data key with a master key, prefixing the random nonce
+func (k *Keyring) wrap(keyID string, key []byte) ([]byte, error) {
+	aead := k.keys[keyID]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(key)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("error wrapping data key: %w", err)
+	}
+	return aead.Seal(nonce, nonce, key, []byte(keyID)), nil
+}
+
+// DeriveKey derives a subkey of a data key with HKDF-SHA256, separated by
+// label. Anything stored alongside a blob must be encrypted with a subkey,
+// never the data key itself, since Encrypt's nonces repeat across streams.
+func DeriveKey(key []byte, label string) ([]byte, error) {
+	subkey := make([]byte, KeySize)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, key, nil, []byte(label)), subkey); err != nil {
+		return nil, err
+	}
+	return subkey, nil
+}
+
+// newAEAD returns AES-256-GCM keyed with key
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
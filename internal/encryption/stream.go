@@ -0,0 +1,350 @@
+package encryption
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// ChunkSize is the amount of plaintext sealed per chunk
+	ChunkSize = 64 << 10
+	// maxChunkSize bounds the chunk size accepted from a blob header
+	maxChunkSize = 16 << 20
+
+	// headerSize is the length of the blob header: the magic followed by
+	// the chunk size as a big-endian uint32
+	headerSize = 8
+	// tagSize is the length of the GCM tag added to every chunk
+	tagSize = 16
+)
+
+// magic identifies an encrypted blob and its format version
+var magic = []byte("DRE1")
+
+// ErrCorrupt is returned when an encrypted blob fails to decrypt, which
+// means it was damaged, truncated or sealed with another key
+var ErrCorrupt = errors.New("encrypted blob is corrupt")
+
+// Encrypt returns a reader producing the encrypted form of r under key.
+//
+// The output is a short header followed by the plaintext in ChunkSize
+// pieces, each sealed with AES-256-GCM. A chunk's nonce is its index plus a
+// flag marking the last chunk, so chunks cannot be reordered, and dropping
+// chunks from the end is detected. Nonces repeat across blobs, which is safe
+// only as long as a key never encrypts more than one stream: derive a
+// separate key with DeriveKey for anything stored next to a blob.
+func Encrypt(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	header := newHeader(ChunkSize)
+	return &encryptReader{
+		src:    r,
+		aead:   aead,
+		header: header,
+		out:    header,
+		plain:  make([]byte, ChunkSize+1),
+		sealed: make([]byte, 0, ChunkSize+aead.Overhead()),
+	}, nil
+}
+
+type encryptReader struct {
+	src    io.Reader
+	aead   cipher.AEAD
+	header []byte
+
+	// plain holds one chunk plus a byte of lookahead, which tells whether
+	// the chunk is the last one
+	plain   []byte
+	pending int
+	sealed  []byte
+	// out is the sealed data not yet returned
+	out   []byte
+	index uint64
+	done  bool
+}
+
+// Read implements io.Reader
+func (e *encryptReader) Read(p []byte) (int, error) {
+	for len(e.out) == 0 {
+		if e.done {
+			return 0, io.EOF
+		}
+		if err := e.seal(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, e.out)
+	e.out = e.out[n:]
+	return n, nil
+}
+
+// seal reads and seals the next chunk
+func (e *encryptReader) seal() error {
+	n, err := io.ReadFull(e.src, e.plain[e.pending:])
+	n += e.pending
+	switch {
+	case err == nil:
+		// More data follows this chunk; carry the lookahead byte over
+		e.out = e.aead.Seal(e.sealed[:0], chunkNonce(e.index, false), e.plain[:ChunkSize], e.header)
+		e.plain[0] = e.plain[ChunkSize]
+		e.pending = 1
+		e.index++
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		e.out = e.aead.Seal(e.sealed[:0], chunkNonce(e.index, true), e.plain[:n], e.header)
+		e.done = true
+	default:
+		return err
+	}
+	return nil
+}
+
+// SealedSize returns the length of the encrypted form of size bytes of
+// plaintext, as produced by Encrypt
+func SealedSize(size int64) int64 {
+	chunks := max(1, (size+ChunkSize-1)/ChunkSize)
+	return headerSize + size + chunks*tagSize
+}
+
+// Decrypt returns a seekable reader over the plaintext of an encrypted blob.
+// Only the chunks covering the bytes read are decrypted.
+func Decrypt(r io.ReadSeekCloser, key []byte) (io.ReadSeekCloser, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, headerSize)
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: reading header: %v", ErrCorrupt, err)
+	}
+	chunkSize, err := parseHeader(header)
+	if err != nil {
+		return nil, err
+	}
+
+	end, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	// Every blob ends with a final chunk, which may be short or empty
+	overhead := int64(aead.Overhead())
+	body := end - headerSize
+	chunks := body / (chunkSize + overhead)
+	if rem := body % (chunkSize + overhead); rem != 0 {
+		if rem < overhead {
+			return nil, fmt.Errorf("%w: truncated chunk", ErrCorrupt)
+		}
+		chunks++
+	}
+	if chunks == 0 {
+		return nil, fmt.Errorf("%w: no chunks", ErrCorrupt)
+	}
+
+	return &decryptReader{
+		src:       r,
+		aead:      aead,
+		header:    header,
+		chunkSize: chunkSize,
+		chunks:    chunks,
+		end:       end,
+		size:      body - chunks*overhead,
+		loaded:    -1,
+		sealed:    make([]byte, chunkSize+overhead),
+	}, nil
+}
+
+type decryptReader struct {
+	src       io.ReadSeekCloser
+	aead      cipher.AEAD
+	header    []byte
+	chunkSize int64
+	chunks    int64
+	// end is the size of the encrypted blob, size that of the plaintext
+	end  int64
+	size int64
+
+	offset int64
+	// loaded is the index of the chunk decrypted into plain
+	loaded int64
+	plain  []byte
+	sealed []byte
+}
+
+// Read implements io.Reader
+func (d *decryptReader) Read(p []byte) (int, error) {
+	if d.offset >= d.size {
+		return 0, io.EOF
+	}
+	index := d.offset / d.chunkSize
+	if index != d.loaded {
+		if err := d.load(index); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, d.plain[d.offset-index*d.chunkSize:])
+	d.offset += int64(n)
+	return n, nil
+}
+
+// load decrypts one chunk into plain
+func (d *decryptReader) load(index int64) error {
+	start := headerSize + index*(d.chunkSize+int64(d.aead.Overhead()))
+	length := min(int64(len(d.sealed)), d.end-start)
+	if _, err := d.src.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.ReadFull(d.src, d.sealed[:length]); err != nil {
+		return fmt.Errorf("%w: reading chunk %d: %v", ErrCorrupt, index, err)
+	}
+	plain, err := d.aead.Open(d.plain[:0], chunkNonce(uint64(index), index == d.chunks-1), d.sealed[:length], d.header)
+	if err != nil {
+		d.loaded = -1
+		return fmt.Errorf("%w: chunk %d", ErrCorrupt, index)
+	}
+	d.plain, d.loaded = plain, index
+	return nil
+}
+
+// Seek implements io.Seeker over the plaintext
+func (d *decryptReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += d.offset
+	case io.SeekEnd:
+		offset += d.size
+	default:
+		return 0, errors.New("encryption: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("encryption: negative position")
+	}
+	d.offset = offset
+	return offset, nil
+}
+
+// Close closes the underlying blob
+func (d *decryptReader) Close() error {
+	return d.src.Close()
+}
+
+// DecryptStream returns a reader over the plaintext of an encrypted blob
+// read front to back, for blobs that cannot be seeked such as a download
+func DecryptStream(r io.Reader, key []byte) (io.Reader, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("%w: reading header: %v", ErrCorrupt, err)
+	}
+	chunkSize, err := parseHeader(header)
+	if err != nil {
+		return nil, err
+	}
+	return &streamReader{
+		src:    r,
+		aead:   aead,
+		header: header,
+		sealed: make([]byte, chunkSize+int64(aead.Overhead())+1),
+	}, nil
+}
+
+type streamReader struct {
+	src    io.Reader
+	aead   cipher.AEAD
+	header []byte
+
+	// sealed holds one sealed chunk plus a byte of lookahead, which tells
+	// whether the chunk is the last one
+	sealed  []byte
+	pending int
+	out     []byte
+	index   uint64
+	done    bool
+}
+
+// Read implements io.Reader
+func (s *streamReader) Read(p []byte) (int, error) {
+	for len(s.out) == 0 {
+		if s.done {
+			return 0, io.EOF
+		}
+		if err := s.open(); err != nil {
+			return 0, err
+		}
+	}
+	n := copy(p, s.out)
+	s.out = s.out[n:]
+	return n, nil
+}
+
+// open reads and decrypts the next chunk
+func (s *streamReader) open() error {
+	full := len(s.sealed) - 1
+	n, err := io.ReadFull(s.src, s.sealed[s.pending:])
+	n += s.pending
+	last := false
+	switch {
+	case err == nil:
+		n = full
+	case errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF):
+		last = true
+	default:
+		return err
+	}
+
+	plain, err := s.aead.Open(nil, chunkNonce(s.index, last), s.sealed[:n], s.header)
+	if err != nil {
+		return fmt.Errorf("%w: chunk %d", ErrCorrupt, s.index)
+	}
+	s.out, s.done = plain, last
+	if !last {
+		// Carry the lookahead byte over to the next chunk
+		s.sealed[0] = s.sealed[full]
+		s.pending = 1
+		s.index++
+	}
+	return nil
+}
+
+// parseHeader checks a blob header and returns its chunk size
+func parseHeader(header []byte) (int64, error) {
+	if string(header[:len(magic)]) != string(magic) {
+		return 0, fmt.Errorf("%w: unknown format", ErrCorrupt)
+	}
+	chunkSize := int64(binary.BigEndian.Uint32(header[len(magic):]))
+	if chunkSize == 0 || chunkSize > maxChunkSize {
+		return 0, fmt.Errorf("%w: invalid chunk size %d", ErrCorrupt, chunkSize)
+	}
+	return chunkSize, nil
+}
+
+// newHeader builds the blob header, which is authenticated with every chunk
+func newHeader(chunkSize uint32) []byte {
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	binary.BigEndian.PutUint32(header[len(magic):], chunkSize)
+	return header
+}
+
+// chunkNonce derives the nonce of a chunk from its index and whether it is
+// the last one
+func chunkNonce(index uint64, last bool) []byte {
+	nonce := make([]byte, 12)
+	binary.BigEndian.PutUint64(nonce, index)
+	if last {
+		nonce[8] = 1
+	}
+	return nonce
+}
@@ -0,0 +1,459 @@
+// Package exif reads the EXIF metadata of photos: when they were taken,
+// the camera that took them, their orientation, size and location. JPEG,
+// PNG and TIFF images are supported.
+package exif
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// maxTIFFSize bounds how much of a TIFF image is read, as its metadata may
+// be stored anywhere in the file
+const maxTIFFSize = 32 << 20
+
+var (
+	// ErrUnsupported is returned for images in formats that are not parsed
+	ErrUnsupported = errors.New("unsupported image format")
+	// ErrMalformed is returned when the image or its metadata is corrupt
+	ErrMalformed = errors.New("malformed image metadata")
+)
+
+// Metadata is what was recovered from an image. Fields are left empty when
+// the image does not record them.
+type Metadata struct {
+	// TakenAt is the camera's local time when the photo was taken. Cameras
+	// rarely record their time zone, so it is expressed as UTC.
+	TakenAt *time.Time
+	// TimeOffset is the local time's offset from UTC, such as "+02:00",
+	// when the camera recorded it
+	TimeOffset  string
+	CameraMake  string
+	CameraModel string
+	LensModel   string
+	// Orientation is the EXIF orientation, 1 to 8, or 0 if unknown
+	Orientation int
+	Width       int
+	Height      int
+	Latitude    *float64
+	Longitude   *float64
+}
+
+// Decode reads the metadata of an image. Images without EXIF data return
+// their dimensions alone.
+func Decode(r io.Reader) (*Metadata, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(8)
+	if err != nil && len(magic) < 4 {
+		return nil, ErrUnsupported
+	}
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0xFF, 0xD8}):
+		return decodeJPEG(br)
+	case bytes.HasPrefix(magic, pngSignature):
+		return decodePNG(br)
+	case bytes.HasPrefix(magic, []byte("II*\x00")), bytes.HasPrefix(magic, []byte("MM\x00*")):
+		data, err := io.ReadAll(io.LimitReader(br, maxTIFFSize))
+		if err != nil {
+			return nil, err
+		}
+		m := &Metadata{}
+		if err := parseTIFF(data, m); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	return nil, ErrUnsupported
+}
+
+// decodeJPEG walks the segments before the image data, reading the EXIF
+// segment and the frame header
+func decodeJPEG(r *bufio.Reader) (*Metadata, error) {
+	m := &Metadata{}
+	if _, err := r.Discard(2); err != nil {
+		return nil, err
+	}
+
+	for {
+		marker, err := nextMarker(r)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case marker == 0xD9 || marker == 0xDA:
+			// End of image or start of scan: no metadata follows
+			return m, nil
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			// Markers without a payload
+			continue
+		}
+
+		var length uint16
+		if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		if length < 2 {
+			return nil, ErrMalformed
+		}
+		size := int(length) - 2
+
+		switch {
+		case marker == 0xE1:
+			segment := make([]byte, size)
+			if _, err := io.ReadFull(r, segment); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+			}
+			// XMP also lives in APP1 segments, under a different header
+			if data, ok := bytes.CutPrefix(segment, []byte("Exif\x00\x00")); ok {
+				if err := parseTIFF(data, m); err != nil {
+					return nil, err
+				}
+			}
+		case isFrameMarker(marker):
+			frame := make([]byte, size)
+			if _, err := io.ReadFull(r, frame); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+			}
+			if len(frame) >= 5 {
+				m.Height = int(binary.BigEndian.Uint16(frame[1:3]))
+				m.Width = int(binary.BigEndian.Uint16(frame[3:5]))
+			}
+		default:
+			if _, err := r.Discard(size); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+			}
+		}
+	}
+}
+
+// nextMarker skips to the next JPEG marker and returns its code
+func nextMarker(r *bufio.Reader) (byte, error) {
+	b, err := r.ReadByte()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrMalformed, err)
+	}
+	if b != 0xFF {
+		return 0, ErrMalformed
+	}
+	// Any number of 0xFF bytes may pad a marker
+	for b == 0xFF {
+		if b, err = r.ReadByte(); err != nil {
+			return 0, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+	}
+	return b, nil
+}
+
+// isFrameMarker reports whether a marker starts a frame header, which
+// carries the image dimensions
+func isFrameMarker(marker byte) bool {
+	return marker >= 0xC0 && marker <= 0xCF && marker != 0xC4 && marker != 0xC8 && marker != 0xCC
+}
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// decodePNG reads the header chunk and the eXIf chunk, which must come
+// before the image data
+func decodePNG(r *bufio.Reader) (*Metadata, error) {
+	m := &Metadata{}
+	if _, err := r.Discard(len(pngSignature)); err != nil {
+		return nil, err
+	}
+
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			if errors.Is(err, io.EOF) {
+				return m, nil
+			}
+			return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+		length := binary.BigEndian.Uint32(header[:4])
+		if length > 1<<31-1 {
+			return nil, ErrMalformed
+		}
+
+		switch string(header[4:]) {
+		case "IHDR", "eXIf":
+			if length > maxTIFFSize {
+				return nil, ErrMalformed
+			}
+			chunk := make([]byte, length)
+			if _, err := io.ReadFull(r, chunk); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+			}
+			if string(header[4:]) == "eXIf" {
+				if err := parseTIFF(chunk, m); err != nil {
+					return nil, err
+				}
+			} else if len(chunk) >= 8 {
+				m.Width = int(binary.BigEndian.Uint32(chunk[0:4]))
+				m.Height = int(binary.BigEndian.Uint32(chunk[4:8]))
+			}
+		case "IDAT", "IEND":
+			return m, nil
+		default:
+			if _, err := r.Discard(int(length)); err != nil {
+				return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+			}
+		}
+		// Skip the CRC
+		if _, err := r.Discard(4); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+	}
+}
+
+// Tags read from the image, EXIF and GPS directories
+const (
+	tagImageWidth       = 0x0100
+	tagImageLength      = 0x0101
+	tagMake             = 0x010F
+	tagModel            = 0x0110
+	tagOrientation      = 0x0112
+	tagDateTime         = 0x0132
+	tagExifIFD          = 0x8769
+	tagGPSIFD           = 0x8825
+	tagDateTimeOriginal = 0x9003
+	tagOffsetTimeOrig   = 0x9011
+	tagSubSecTimeOrig   = 0x9291
+	tagPixelXDimension  = 0xA002
+	tagPixelYDimension  = 0xA003
+	tagLensModel        = 0xA434
+	tagGPSLatitudeRef   = 0x0001
+	tagGPSLatitude      = 0x0002
+	tagGPSLongitudeRef  = 0x0003
+	tagGPSLongitude     = 0x0004
+)
+
+const (
+	// maxDirectoryEntries bounds the size of a directory, which in valid
+	// images holds a few dozen fields
+	maxDirectoryEntries  = 1000
+	exifDateTimeLayout   = "2006:01:02 15:04:05"
+	exifOffsetTimeLayout = "-07:00"
+)
+
+// Field types and their sizes in bytes
+const (
+	typeByte      = 1
+	typeASCII     = 2
+	typeShort     = 3
+	typeLong      = 4
+	typeRational  = 5
+	typeUndefined = 7
+	typeSLong     = 9
+	typeSRational = 10
+)
+
+var typeSizes = map[uint16]int{
+	typeByte: 1, typeASCII: 1, typeShort: 2, typeLong: 4, typeRational: 8,
+	typeUndefined: 1, typeSLong: 4, typeSRational: 8,
+}
+
+// field is a directory entry whose value has been located in the data
+type field struct {
+	typ   uint16
+	count int
+	value []byte
+}
+
+// tiff reads directories from TIFF structured data
+type tiff struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// parseTIFF reads the image directory and the EXIF and GPS directories it
+// points to into m
+func parseTIFF(data []byte, m *Metadata) error {
+	if len(data) < 8 {
+		return ErrMalformed
+	}
+	t := &tiff{data: data}
+	switch string(data[:2]) {
+	case "II":
+		t.order = binary.LittleEndian
+	case "MM":
+		t.order = binary.BigEndian
+	default:
+		return ErrMalformed
+	}
+	if t.order.Uint16(data[2:4]) != 42 {
+		return ErrMalformed
+	}
+
+	ifd0, err := t.directory(t.order.Uint32(data[4:8]))
+	if err != nil {
+		return err
+	}
+	m.CameraMake = ifd0.text(tagMake)
+	m.CameraModel = ifd0.text(tagModel)
+	if orientation, ok := t.uint(ifd0[tagOrientation]); ok && orientation >= 1 && orientation <= 8 {
+		m.Orientation = int(orientation)
+	}
+	if width, ok := t.uint(ifd0[tagImageWidth]); ok && m.Width == 0 {
+		m.Width = int(width)
+	}
+	if height, ok := t.uint(ifd0[tagImageLength]); ok && m.Height == 0 {
+		m.Height = int(height)
+	}
+	taken := ifd0.text(tagDateTime)
+	var offset, subsec string
+
+	if pointer, ok := t.uint(ifd0[tagExifIFD]); ok {
+		exifIFD, err := t.directory(pointer)
+		if err != nil {
+			return err
+		}
+		if original := exifIFD.text(tagDateTimeOriginal); original != "" {
+			taken = original
+			offset = exifIFD.text(tagOffsetTimeOrig)
+			subsec = exifIFD.text(tagSubSecTimeOrig)
+		}
+		m.LensModel = exifIFD.text(tagLensModel)
+		if width, ok := t.uint(exifIFD[tagPixelXDimension]); ok && m.Width == 0 {
+			m.Width = int(width)
+		}
+		if height, ok := t.uint(exifIFD[tagPixelYDimension]); ok && m.Height == 0 {
+			m.Height = int(height)
+		}
+	}
+	m.TakenAt = parseDateTime(taken, subsec)
+	if m.TakenAt != nil {
+		if _, err := time.Parse(exifOffsetTimeLayout, offset); err == nil {
+			m.TimeOffset = offset
+		}
+	}
+
+	if pointer, ok := t.uint(ifd0[tagGPSIFD]); ok {
+		gps, err := t.directory(pointer)
+		if err != nil {
+			return err
+		}
+		lat, latOK := t.coordinate(gps[tagGPSLatitude], gps.text(tagGPSLatitudeRef), "S", 90)
+		lon, lonOK := t.coordinate(gps[tagGPSLongitude], gps.text(tagGPSLongitudeRef), "W", 180)
+		if latOK && lonOK {
+			m.Latitude, m.Longitude = &lat, &lon
+		}
+	}
+	return nil
+}
+
+// directory holds the fields of an image file directory by tag
+type directory map[uint16]*field
+
+// text returns an ASCII field's value without its padding
+func (d directory) text(tag uint16) string {
+	f := d[tag]
+	if f == nil || f.typ != typeASCII {
+		return ""
+	}
+	value := f.value
+	if i := bytes.IndexByte(value, 0); i >= 0 {
+		value = value[:i]
+	}
+	return strings.TrimSpace(strings.ToValidUTF8(string(value), ""))
+}
+
+// directory reads the directory at offset. Fields whose values fall outside
+// the data are ignored.
+func (t *tiff) directory(offset uint32) (directory, error) {
+	if uint64(offset)+2 > uint64(len(t.data)) {
+		return nil, ErrMalformed
+	}
+	count := int(t.order.Uint16(t.data[offset:]))
+	if count > maxDirectoryEntries || uint64(offset)+2+uint64(count)*12 > uint64(len(t.data)) {
+		return nil, ErrMalformed
+	}
+
+	d := make(directory, count)
+	for i := 0; i < count; i++ {
+		entry := t.data[int(offset)+2+i*12:][:12]
+		f := &field{typ: t.order.Uint16(entry[2:4])}
+		size, ok := typeSizes[f.typ]
+		if !ok {
+			continue
+		}
+		n := uint64(t.order.Uint32(entry[4:8]))
+		length := n * uint64(size)
+		if length <= 4 {
+			f.value = entry[8 : 8+length]
+		} else {
+			start := uint64(t.order.Uint32(entry[8:12]))
+			if start+length > uint64(len(t.data)) {
+				continue
+			}
+			f.value = t.data[start : start+length]
+		}
+		f.count = int(n)
+		d[t.order.Uint16(entry[0:2])] = f
+	}
+	return d, nil
+}
+
+// uint returns the first value of an integer field
+func (t *tiff) uint(f *field) (uint32, bool) {
+	if f == nil || f.count < 1 {
+		return 0, false
+	}
+	switch f.typ {
+	case typeByte:
+		return uint32(f.value[0]), true
+	case typeShort:
+		return uint32(t.order.Uint16(f.value)), true
+	case typeLong:
+		return t.order.Uint32(f.value), true
+	}
+	return 0, false
+}
+
+// coordinate converts a GPS degrees, minutes, seconds field to decimal
+// degrees, negated when ref is the negative hemisphere
+func (t *tiff) coordinate(f *field, ref, negative string, limit float64) (float64, bool) {
+	if f == nil || f.typ != typeRational || f.count < 3 {
+		return 0, false
+	}
+	var parts [3]float64
+	for i := range parts {
+		numerator := t.order.Uint32(f.value[i*8:])
+		denominator := t.order.Uint32(f.value[i*8+4:])
+		if denominator == 0 {
+			return 0, false
+		}
+		parts[i] = float64(numerator) / float64(denominator)
+	}
+	degrees := parts[0] + parts[1]/60 + parts[2]/3600
+	if degrees > limit {
+		return 0, false
+	}
+	if strings.EqualFold(ref, negative) {
+		degrees = -degrees
+	}
+	return degrees, true
+}
+
+// parseDateTime parses an EXIF timestamp with its optional fractional
+// seconds. Unset timestamps are recorded as blanks or zeros.
+func parseDateTime(value, subsec string) *time.Time {
+	if value == "" || strings.HasPrefix(value, "0000") {
+		return nil
+	}
+	t, err := time.Parse(exifDateTimeLayout, value)
+	if err != nil {
+		return nil
+	}
+	if subsec != "" && len(subsec) <= 9 && strings.Trim(subsec, "0123456789") == "" {
+		var nanos int
+		fmt.Sscan((subsec + "000000000")[:9], &nanos)
+		t = t.Add(time.Duration(nanos))
+	}
+	return &t
+}
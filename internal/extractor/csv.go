@@ -0,0 +1,55 @@
+package extractor
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+)
+
+// CSVExtractor joins the cells of each CSV record with spaces
+type CSVExtractor struct{}
+
+// NewCSVExtractor creates a new CSV extractor
+func NewCSVExtractor() *CSVExtractor {
+	return &CSVExtractor{}
+}
+
+// Name returns the extractor name
+func (e *CSVExtractor) Name() string {
+	return "csv"
+}
+
+// Supports reports whether the file is CSV
+func (e *CSVExtractor) Supports(mimeType, fileName string) bool {
+	return mimeType == "text/csv" || hasExtension(fileName, ".csv", ".tsv")
+}
+
+// Extract writes one line of text per record
+func (e *CSVExtractor) Extract(ctx context.Context, r io.Reader, w io.Writer) error {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		line := strings.TrimSpace(strings.Join(record, " "))
+		if line == "" {
+			continue
+		}
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+}
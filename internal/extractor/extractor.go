@@ -0,0 +1,107 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+)
+
+var (
+	// ErrUnsupported is returned when no extractor handles a file
+	ErrUnsupported = errors.New("unsupported file format")
+	// ErrTooLarge is returned when a file, or the data compressed inside it,
+	// is larger than an extractor is willing to read
+	ErrTooLarge = errors.New("file expands beyond the extraction limit")
+)
+
+const (
+	// MaxArchiveSize is the largest OOXML archive that is buffered
+	MaxArchiveSize = 100 << 20
+	// MaxDecodedSize bounds the data inflated from a single file, whether
+	// PDF streams or OOXML parts, so small compressed files cannot expand
+	// without limit
+	MaxDecodedSize = 64 << 20
+)
+
+// Extractor pulls plain text out of a file so it can be indexed
+type Extractor interface {
+	// Name identifies the extractor, e.g. "pdf"
+	Name() string
+	// Supports reports whether the extractor handles a file with the given
+	// MIME type and name
+	Supports(mimeType, fileName string) bool
+	// Extract reads the file from r and writes its text to w. Implementations
+	// should return promptly once ctx is done.
+	Extract(ctx context.Context, r io.Reader, w io.Writer) error
+}
+
+// Registry holds the available extractors in priority order
+type Registry struct {
+	extractors []Extractor
+}
+
+// NewRegistry creates a registry with the given extractors
+func NewRegistry(extractors ...Extractor) *Registry {
+	return &Registry{extractors: extractors}
+}
+
+// NewDefaultRegistry creates a registry with every built-in extractor
+func NewDefaultRegistry() *Registry {
+	return NewRegistry(
+		NewPDFExtractor(),
+		NewDocxExtractor(),
+		NewXlsxExtractor(),
+		NewHTMLExtractor(),
+		NewMarkdownExtractor(),
+		NewCSVExtractor(),
+		NewTextExtractor(),
+	)
+}
+
+// Register adds an extractor. Later registrations take precedence so callers
+// can override the built-in handling of a format.
+func (r *Registry) Register(e Extractor) {
+	r.extractors = append([]Extractor{e}, r.extractors...)
+}
+
+// Find returns the first extractor that supports the file
+func (r *Registry) Find(mimeType, fileName string) (Extractor, error) {
+	mimeType = baseMimeType(mimeType)
+	for _, e := range r.extractors {
+		if e.Supports(mimeType, fileName) {
+			return e, nil
+		}
+	}
+	return nil, ErrUnsupported
+}
+
+// readLimited reads r to the end, failing with ErrTooLarge rather than
+// returning more than limit bytes
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if int64(len(data)) > limit {
+		return nil, ErrTooLarge
+	}
+	return data, err
+}
+
+// baseMimeType strips parameters such as "; charset=utf-8"
+func baseMimeType(mimeType string) string {
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// hasExtension reports whether the file name ends with one of the extensions
+func hasExtension(fileName string, extensions ...string) bool {
+	ext := strings.ToLower(path.Ext(fileName))
+	for _, e := range extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// HTMLExtractor collects the visible text of an HTML document
+type HTMLExtractor struct{}
+
+// NewHTMLExtractor creates a new HTML extractor
+func NewHTMLExtractor() *HTMLExtractor {
+	return &HTMLExtractor{}
+}
+
+// Name returns the extractor name
+func (e *HTMLExtractor) Name() string {
+	return "html"
+}
+
+// Supports reports whether the file is HTML
+func (e *HTMLExtractor) Supports(mimeType, fileName string) bool {
+	return mimeType == "text/html" || mimeType == "application/xhtml+xml" ||
+		hasExtension(fileName, ".html", ".htm", ".xhtml")
+}
+
+// Extract tokenizes the document and writes text nodes outside of script,
+// style and similar non-visible elements
+func (e *HTMLExtractor) Extract(ctx context.Context, r io.Reader, w io.Writer) error {
+	tokenizer := html.NewTokenizer(r)
+	skipDepth := 0
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if errors.Is(tokenizer.Err(), io.EOF) {
+				return nil
+			}
+			return tokenizer.Err()
+		case html.StartTagToken:
+			if isHiddenElement(tokenizer) {
+				skipDepth++
+			}
+		case html.EndTagToken:
+			if skipDepth > 0 && isHiddenElement(tokenizer) {
+				skipDepth--
+			}
+		case html.TextToken:
+			if skipDepth > 0 {
+				continue
+			}
+			text := strings.Join(strings.Fields(string(tokenizer.Text())), " ")
+			if text == "" {
+				continue
+			}
+			if _, err := io.WriteString(w, text+"\n"); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// isHiddenElement reports whether the current tag's content is not displayed
+func isHiddenElement(tokenizer *html.Tokenizer) bool {
+	name, _ := tokenizer.TagName()
+	switch string(name) {
+	case "script", "style", "noscript", "template":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package extractor
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"path"
+	"sort"
+	"strings"
+)
+
+const (
+	docxMimeType = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	xlsxMimeType = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// DocxExtractor reads the paragraphs of a Word (OOXML) document
+type DocxExtractor struct{}
+
+// NewDocxExtractor creates a new docx extractor
+func NewDocxExtractor() *DocxExtractor {
+	return &DocxExtractor{}
+}
+
+// Name returns the extractor name
+func (e *DocxExtractor) Name() string {
+	return "docx"
+}
+
+// Supports reports whether the file is a Word document
+func (e *DocxExtractor) Supports(mimeType, fileName string) bool {
+	return mimeType == docxMimeType || hasExtension(fileName, ".docx")
+}
+
+// Extract writes the text runs of the main document, one paragraph per line
+func (e *DocxExtractor) Extract(ctx context.Context, r io.Reader, w io.Writer) error {
+	archive, err := openZip(r)
+	if err != nil {
+		return err
+	}
+	return extractXMLParts(ctx, archive, w, []string{"word/document.xml"}, "p")
+}
+
+// XlsxExtractor reads the cell text of an Excel (OOXML) workbook
+type XlsxExtractor struct{}
+
+// NewXlsxExtractor creates a new xlsx extractor
+func NewXlsxExtractor() *XlsxExtractor {
+	return &XlsxExtractor{}
+}
+
+// Name returns the extractor name
+func (e *XlsxExtractor) Name() string {
+	return "xlsx"
+}
+
+// Supports reports whether the file is an Excel workbook
+func (e *XlsxExtractor) Supports(mimeType, fileName string) bool {
+	return mimeType == xlsxMimeType || hasExtension(fileName, ".xlsx")
+}
+
+// Extract writes the shared strings table followed by any inline strings
+// stored directly in the worksheets
+func (e *XlsxExtractor) Extract(ctx context.Context, r io.Reader, w io.Writer) error {
+	archive, err := openZip(r)
+	if err != nil {
+		return err
+	}
+
+	parts := []string{"xl/sharedStrings.xml"}
+	var sheets []string
+	for _, f := range archive.File {
+		if strings.HasPrefix(f.Name, "xl/worksheets/") && path.Ext(f.Name) == ".xml" {
+			sheets = append(sheets, f.Name)
+		}
+	}
+	sort.Strings(sheets)
+	parts = append(parts, sheets...)
+
+	return extractXMLParts(ctx, archive, w, parts, "si", "is")
+}
+
+// openZip buffers r so the archive's central directory can be read. Archives
+// larger than MaxArchiveSize are rejected with ErrTooLarge.
+func openZip(r io.Reader) (*zip.Reader, error) {
+	data, err := readLimited(r, MaxArchiveSize)
+	if err != nil {
+		return nil, err
+	}
+	return zip.NewReader(bytes.NewReader(data), int64(len(data)))
+}
+
+// extractXMLParts writes the character data of every <t> element found in
+// the given archive parts. A newline is written whenever one of the block
+// elements closes. Missing parts are ignored. The parts may hold at most
+// MaxDecodedSize bytes between them.
+func extractXMLParts(ctx context.Context, archive *zip.Reader, w io.Writer, parts []string, blocks ...string) error {
+	budget := uint64(MaxDecodedSize)
+	for _, name := range parts {
+		f := findZipFile(archive, name)
+		if f == nil {
+			continue
+		}
+		// archive/zip fails reads that go past the declared size
+		if f.UncompressedSize64 > budget {
+			return ErrTooLarge
+		}
+		budget -= f.UncompressedSize64
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		err = extractXMLText(ctx, rc, w, blocks)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractXMLText streams an OOXML part and writes the text of <t> elements
+func extractXMLText(ctx context.Context, r io.Reader, w io.Writer, blocks []string) error {
+	decoder := xml.NewDecoder(r)
+	inText := false
+	var line strings.Builder
+
+	flush := func() error {
+		text := strings.TrimSpace(line.String())
+		line.Reset()
+		if text == "" {
+			return nil
+		}
+		_, err := io.WriteString(w, text+"\n")
+		return err
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return flush()
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				line.WriteByte(' ')
+			}
+		case xml.EndElement:
+			if t.Name.Local == "t" {
+				inText = false
+				continue
+			}
+			for _, block := range blocks {
+				if t.Name.Local == block {
+					if err := flush(); err != nil {
+						return err
+					}
+				}
+			}
+		case xml.CharData:
+			if inText {
+				line.Write(t)
+			}
+		}
+	}
+}
+
+// findZipFile returns the archive entry with the given name
+func findZipFile(archive *zip.Reader, name string) *zip.File {
+	for _, f := range archive.File {
+		if f.Name == name {
+			return f
+		}
+	}
+	return nil
+}
@@ -0,0 +1,445 @@
+package extractor
+
+import (
+	"bytes"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// PDFExtractor pulls text out of PDF content streams. It understands
+// uncompressed and Flate-compressed streams and the common text showing
+// operators; fonts with custom encodings are only decoded on a best-effort
+// basis.
+type PDFExtractor struct{}
+
+// NewPDFExtractor creates a new PDF extractor
+func NewPDFExtractor() *PDFExtractor {
+	return &PDFExtractor{}
+}
+
+// Name returns the extractor name
+func (e *PDFExtractor) Name() string {
+	return "pdf"
+}
+
+// Supports reports whether the file is a PDF
+func (e *PDFExtractor) Supports(mimeType, fileName string) bool {
+	return mimeType == "application/pdf" || hasExtension(fileName, ".pdf")
+}
+
+// Extract writes the text of every content stream in document order
+func (e *PDFExtractor) Extract(ctx context.Context, r io.Reader, w io.Writer) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return ExtractPDFText(ctx, data, w, 0)
+}
+
+// ExtractPDFText writes the text of the document's content streams to w.
+// When maxStreams is positive only that many text-bearing streams are read.
+// At most MaxDecodedSize bytes are inflated across all streams.
+func ExtractPDFText(ctx context.Context, data []byte, w io.Writer, maxStreams int) error {
+	seen := 0
+	budget := int64(MaxDecodedSize)
+	for _, stream := range pdfStreams(data) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		content, err := decodePDFStream(stream, budget)
+		if err != nil {
+			return err
+		}
+		budget -= int64(len(content))
+		if !bytes.Contains(content, []byte("BT")) {
+			continue
+		}
+		text := strings.TrimSpace(pdfContentText(content))
+		if text == "" {
+			continue
+		}
+		if _, err := io.WriteString(w, text+"\n"); err != nil {
+			return err
+		}
+		seen++
+		if maxStreams > 0 && seen >= maxStreams {
+			return nil
+		}
+	}
+	return nil
+}
+
+// pdfStream is a raw stream along with its dictionary
+type pdfStream struct {
+	dict []byte
+	data []byte
+}
+
+// pdfStreams finds every "<< ... >> stream ... endstream" object in the file
+func pdfStreams(data []byte) []pdfStream {
+	var streams []pdfStream
+	offset := 0
+	for {
+		i := bytes.Index(data[offset:], []byte("stream"))
+		if i < 0 {
+			return streams
+		}
+		start := offset + i
+		offset = start + len("stream")
+
+		// Skip "endstream" and keywords that merely contain "stream"
+		if start >= 3 && string(data[start-3:start]) == "end" {
+			continue
+		}
+		dictEnd := bytes.LastIndex(data[:start], []byte(">>"))
+		if dictEnd < 0 || strings.TrimSpace(string(data[dictEnd+2:start])) != "" {
+			continue
+		}
+		dictStart := matchingDictStart(data, dictEnd+1)
+		if dictStart < 0 {
+			continue
+		}
+
+		body := offset
+		if body < len(data) && data[body] == '\r' {
+			body++
+		}
+		if body < len(data) && data[body] == '\n' {
+			body++
+		}
+		end := bytes.Index(data[body:], []byte("endstream"))
+		if end < 0 {
+			return streams
+		}
+		streams = append(streams, pdfStream{
+			dict: data[dictStart : dictEnd+2],
+			data: bytes.TrimRight(data[body:body+end], "\r\n"),
+		})
+		offset = body + end + len("endstream")
+	}
+}
+
+// matchingDictStart walks back from the ">>" at end to its opening "<<"
+func matchingDictStart(data []byte, end int) int {
+	depth := 0
+	for i := end; i > 0; i-- {
+		switch {
+		case data[i] == '>' && data[i-1] == '>':
+			depth++
+			i--
+		case data[i] == '<' && data[i-1] == '<':
+			depth--
+			i--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// decodePDFStream returns the decoded stream content, skipping images,
+// fonts, cross-reference and object streams which never contain page text.
+// Skipped and undecodable streams yield no content. It fails with
+// ErrTooLarge when the stream inflates to more than limit bytes.
+func decodePDFStream(stream pdfStream, limit int64) ([]byte, error) {
+	dict := string(stream.dict)
+	for _, skip := range []string{"/Image", "/XRef", "/ObjStm", "/Metadata", "/FontFile", "/Length1", "/EmbeddedFile"} {
+		if strings.Contains(dict, skip) {
+			return nil, nil
+		}
+	}
+
+	if !strings.Contains(dict, "/Filter") {
+		return stream.data, nil
+	}
+	if !strings.Contains(dict, "/FlateDecode") || strings.Contains(dict, "/DCTDecode") ||
+		strings.Contains(dict, "/ASCII85Decode") || strings.Contains(dict, "/LZWDecode") {
+		return nil, nil
+	}
+
+	zr, err := zlib.NewReader(bytes.NewReader(stream.data))
+	if err != nil {
+		return nil, nil
+	}
+	defer zr.Close()
+	content, err := readLimited(zr, limit)
+	switch {
+	case errors.Is(err, ErrTooLarge):
+		return nil, err
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, zlib.ErrChecksum):
+		// Keep whatever could be inflated; truncated streams are common
+		return content, nil
+	case err != nil:
+		return nil, nil
+	}
+	return content, nil
+}
+
+// pdfContentText interprets the text operators of a content stream
+func pdfContentText(content []byte) string {
+	lexer := &pdfLexer{data: content}
+	var out strings.Builder
+	var operands []pdfToken
+	inText := false
+
+	newline := func() {
+		if out.Len() > 0 && !strings.HasSuffix(out.String(), "\n") {
+			out.WriteByte('\n')
+		}
+	}
+
+	for {
+		tok := lexer.next()
+		if tok.kind == pdfEOF {
+			break
+		}
+		if tok.kind != pdfOperator {
+			operands = append(operands, tok)
+			continue
+		}
+
+		switch tok.value {
+		case "BT":
+			inText = true
+		case "ET":
+			inText = false
+			newline()
+		case "Tj", "'", "\"":
+			if inText {
+				if tok.value != "Tj" {
+					newline()
+				}
+				for _, op := range operands {
+					if op.kind == pdfString {
+						out.WriteString(op.value)
+					}
+				}
+			}
+		case "TJ":
+			if inText {
+				for _, op := range operands {
+					switch op.kind {
+					case pdfString:
+						out.WriteString(op.value)
+					case pdfNumber:
+						// Large negative kerning usually separates words
+						if n, err := strconv.ParseFloat(op.value, 64); err == nil && n < -200 {
+							out.WriteByte(' ')
+						}
+					}
+				}
+			}
+		case "Td", "TD", "T*", "Tm":
+			if inText {
+				newline()
+			}
+		}
+		operands = operands[:0]
+	}
+
+	return out.String()
+}
+
+type pdfTokenKind int
+
+const (
+	pdfEOF pdfTokenKind = iota
+	pdfString
+	pdfNumber
+	pdfName
+	pdfOperator
+	pdfDelimiter
+)
+
+type pdfToken struct {
+	kind  pdfTokenKind
+	value string
+}
+
+// pdfLexer tokenizes PDF content streams
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+// next returns the next token, skipping whitespace and comments
+func (l *pdfLexer) next() pdfToken {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case isPDFWhitespace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		case c == '(':
+			return pdfToken{kind: pdfString, value: decodePDFString(l.literalString())}
+		case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+			l.pos += 2
+			return pdfToken{kind: pdfDelimiter, value: "<<"}
+		case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+			l.pos += 2
+			return pdfToken{kind: pdfDelimiter, value: ">>"}
+		case c == '<':
+			return pdfToken{kind: pdfString, value: decodePDFString(l.hexString())}
+		case c == '[' || c == ']' || c == '{' || c == '}' || c == '>' || c == ')':
+			l.pos++
+			return pdfToken{kind: pdfDelimiter, value: string(c)}
+		case c == '/':
+			l.pos++
+			return pdfToken{kind: pdfName, value: l.word()}
+		case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+			return pdfToken{kind: pdfNumber, value: l.word()}
+		default:
+			return pdfToken{kind: pdfOperator, value: l.word()}
+		}
+	}
+	return pdfToken{kind: pdfEOF}
+}
+
+// word reads until the next whitespace or delimiter
+func (l *pdfLexer) word() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFWhitespace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	if l.pos == start {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+// literalString reads a balanced "( ... )" string, resolving escapes
+func (l *pdfLexer) literalString() []byte {
+	l.pos++ // opening parenthesis
+	var buf []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '\\':
+			if l.pos >= len(l.data) {
+				return buf
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'b':
+				buf = append(buf, '\b')
+			case 'f':
+				buf = append(buf, '\f')
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					n := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					buf = append(buf, byte(n))
+				} else {
+					buf = append(buf, e)
+				}
+			}
+		case '(':
+			depth++
+			buf = append(buf, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return buf
+			}
+			buf = append(buf, c)
+		default:
+			buf = append(buf, c)
+		}
+	}
+	return buf
+}
+
+// hexString reads a "< ... >" string
+func (l *pdfLexer) hexString() []byte {
+	l.pos++ // opening angle bracket
+	var digits []byte
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		if c := l.data[l.pos]; isHexDigit(c) {
+			digits = append(digits, c)
+		}
+		l.pos++
+	}
+	l.pos++ // closing angle bracket
+	if len(digits)%2 == 1 {
+		digits = append(digits, '0')
+	}
+	buf := make([]byte, len(digits)/2)
+	for i := range buf {
+		n, _ := strconv.ParseUint(string(digits[2*i:2*i+2]), 16, 8)
+		buf[i] = byte(n)
+	}
+	return buf
+}
+
+// decodePDFString converts a PDF string to UTF-8. UTF-16BE strings with a
+// byte order mark are decoded, anything else is treated as Latin-1 with
+// non-printable bytes dropped.
+func decodePDFString(b []byte) string {
+	if len(b) >= 2 && b[0] == 0xFE && b[1] == 0xFF {
+		units := make([]uint16, 0, len(b)/2)
+		for i := 2; i+1 < len(b); i += 2 {
+			units = append(units, uint16(b[i])<<8|uint16(b[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	if utf8.Valid(b) {
+		return strings.Map(printableRune, string(b))
+	}
+	var sb strings.Builder
+	for _, c := range b {
+		if r := printableRune(rune(c)); r >= 0 {
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// printableRune drops control characters other than whitespace
+func printableRune(r rune) rune {
+	if r == '\n' || r == '\t' || r == ' ' {
+		return r
+	}
+	if r < 0x20 || r == 0x7F || (r >= 0x80 && r < 0xA0) {
+		return -1
+	}
+	return r
+}
+
+func isPDFWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
@@ -0,0 +1,89 @@
+package extractor
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// TextExtractor copies plain text files as they are
+type TextExtractor struct{}
+
+// NewTextExtractor creates a new plain text extractor
+func NewTextExtractor() *TextExtractor {
+	return &TextExtractor{}
+}
+
+// Name returns the extractor name
+func (e *TextExtractor) Name() string {
+	return "text"
+}
+
+// Supports reports whether the file is plain text
+func (e *TextExtractor) Supports(mimeType, fileName string) bool {
+	return mimeType == "text/plain" || hasExtension(fileName, ".txt", ".log", ".text")
+}
+
+// Extract copies the text line by line
+func (e *TextExtractor) Extract(ctx context.Context, r io.Reader, w io.Writer) error {
+	return copyLines(ctx, r, w, func(line string) string { return line })
+}
+
+var (
+	markdownLinkRegex    = regexp.MustCompile(`!?\[([^\]]*)\]\([^)]*\)`)
+	markdownHeadingRegex = regexp.MustCompile(`^\s{0,3}(#{1,6}\s+|>\s*|[-*+]\s+|\d+\.\s+)`)
+	markdownMarkupRegex  = regexp.MustCompile("[*_`~]+")
+	markdownFenceRegex   = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// MarkdownExtractor strips Markdown syntax, keeping the readable text
+type MarkdownExtractor struct{}
+
+// NewMarkdownExtractor creates a new Markdown extractor
+func NewMarkdownExtractor() *MarkdownExtractor {
+	return &MarkdownExtractor{}
+}
+
+// Name returns the extractor name
+func (e *MarkdownExtractor) Name() string {
+	return "markdown"
+}
+
+// Supports reports whether the file is Markdown
+func (e *MarkdownExtractor) Supports(mimeType, fileName string) bool {
+	return mimeType == "text/markdown" || mimeType == "text/x-markdown" ||
+		hasExtension(fileName, ".md", ".markdown", ".mdown")
+}
+
+// Extract removes headings, emphasis and link targets from each line
+func (e *MarkdownExtractor) Extract(ctx context.Context, r io.Reader, w io.Writer) error {
+	return copyLines(ctx, r, w, func(line string) string {
+		if markdownFenceRegex.MatchString(line) {
+			return ""
+		}
+		line = markdownHeadingRegex.ReplaceAllString(line, "")
+		line = markdownLinkRegex.ReplaceAllString(line, "$1")
+		return markdownMarkupRegex.ReplaceAllString(line, "")
+	})
+}
+
+// copyLines writes each transformed line of r to w, checking ctx between lines
+func copyLines(ctx context.Context, r io.Reader, w io.Writer, transform func(string) string) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		line := strings.TrimSpace(transform(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		if _, err := io.WriteString(w, line+"\n"); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,239 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// AlbumHandler handles photo album requests
+type AlbumHandler struct {
+	albumService service.AlbumService
+}
+
+// NewAlbumHandler creates a new album handler
+func NewAlbumHandler(albumService service.AlbumService) *AlbumHandler {
+	return &AlbumHandler{
+		albumService: albumService,
+	}
+}
+
+// List handles GET /api/albums
+func (h *AlbumHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	albums, err := h.albumService.List(r.Context(), userID)
+	if err != nil {
+		writeAlbumError(w, err, "Failed to list albums")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, albums)
+}
+
+// Create handles POST /api/albums
+func (h *AlbumHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	var req model.CreateAlbumRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	album, err := h.albumService.Create(r.Context(), userID, &req)
+	if err != nil {
+		writeAlbumError(w, err, "Failed to create album")
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, album)
+}
+
+// Get handles GET /api/albums/{id}
+func (h *AlbumHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	albumID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid album ID")
+		return
+	}
+
+	album, err := h.albumService.Get(r.Context(), userID, albumID)
+	if err != nil {
+		writeAlbumError(w, err, "Failed to get album")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, album)
+}
+
+// Update handles PATCH /api/albums/{id}
+func (h *AlbumHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	albumID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid album ID")
+		return
+	}
+
+	var req model.UpdateAlbumRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	album, err := h.albumService.Update(r.Context(), userID, albumID, &req)
+	if err != nil {
+		writeAlbumError(w, err, "Failed to update album")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, album)
+}
+
+// Delete handles DELETE /api/albums/{id}
+func (h *AlbumHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	albumID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid album ID")
+		return
+	}
+
+	if err := h.albumService.Delete(r.Context(), userID, albumID); err != nil {
+		writeAlbumError(w, err, "Failed to delete album")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListPhotos handles GET /api/albums/{id}/photos?page=&per_page=
+func (h *AlbumHandler) ListPhotos(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	albumID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid album ID")
+		return
+	}
+
+	q := r.URL.Query()
+	fieldErrors := make(map[string]string)
+	filter := &model.AlbumPhotoFilter{
+		Page:    queryInt(q, "page", fieldErrors),
+		PerPage: queryInt(q, "per_page", fieldErrors),
+	}
+	if len(fieldErrors) > 0 {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+	if fieldErrors := util.ValidateStructWithFields(filter); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	photos, total, err := h.albumService.ListPhotos(r.Context(), userID, albumID, filter)
+	if err != nil {
+		writeAlbumError(w, err, "Failed to list album photos")
+		return
+	}
+
+	response.WithPagination(w, http.StatusOK, photos, filter.Page, filter.PerPage, int(total))
+}
+
+// AddPhotos handles POST /api/albums/{id}/photos
+func (h *AlbumHandler) AddPhotos(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	albumID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid album ID")
+		return
+	}
+
+	var req model.AddAlbumPhotosRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	album, err := h.albumService.AddPhotos(r.Context(), userID, albumID, &req)
+	if err != nil {
+		writeAlbumError(w, err, "Failed to add photos to album")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, album)
+}
+
+// RemovePhoto handles DELETE /api/albums/{id}/photos/{fileID}
+func (h *AlbumHandler) RemovePhoto(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	albumID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid album ID")
+		return
+	}
+	fileID, ok := urlParamUint(r, "fileID")
+	if !ok {
+		response.BadRequest(w, "Invalid file ID")
+		return
+	}
+
+	if err := h.albumService.RemovePhoto(r.Context(), userID, albumID, fileID); err != nil {
+		writeAlbumError(w, err, "Failed to remove photo from album")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeAlbumError maps album service errors to responses
+func writeAlbumError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrAlbumNotFound):
+		response.NotFound(w, "Album not found")
+	case errors.Is(err, service.ErrAlbumPhotoNotFound):
+		response.NotFound(w, "Photo is not in the album")
+	case errors.Is(err, service.ErrInvalidAlbumPhoto), errors.Is(err, service.ErrCoverNotInAlbum):
+		response.BadRequest(w, err.Error())
+	default:
+		response.Error(w, http.StatusInternalServerError, response.ErrInternalServer, message)
+	}
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// AppPasswordHandler handles app password requests
+type AppPasswordHandler struct {
+	appPasswordService service.AppPasswordService
+}
+
+// NewAppPasswordHandler creates a new app password handler
+func NewAppPasswordHandler(appPasswordService service.AppPasswordService) *AppPasswordHandler {
+	return &AppPasswordHandler{
+		appPasswordService: appPasswordService,
+	}
+}
+
+// Create handles POST /api/app-passwords
+func (h *AppPasswordHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	var req model.CreateAppPasswordRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	password, err := h.appPasswordService.Create(r.Context(), userID, &req)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, response.ErrInternalServer, "Failed to create app password")
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, password)
+}
+
+// List handles GET /api/app-passwords
+func (h *AppPasswordHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	passwords, err := h.appPasswordService.List(r.Context(), userID)
+	if err != nil {
+		response.InternalError(w)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, passwords)
+}
+
+// Delete handles DELETE /api/app-passwords/{id}
+func (h *AppPasswordHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	id, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid app password ID")
+		return
+	}
+
+	if err := h.appPasswordService.Delete(r.Context(), userID, id); err != nil {
+		if errors.Is(err, service.ErrAppPasswordNotFound) {
+			response.NotFound(w, "App password not found")
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, response.ErrInternalServer, "Failed to delete app password")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// AuditHandler handles the admin audit log endpoints
+type AuditHandler struct {
+	auditService service.AuditService
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(auditService service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// List handles GET /api/admin/audit
+func (h *AuditHandler) List(w http.ResponseWriter, r *http.Request) {
+	filter, ok := parseAuditFilter(w, r)
+	if !ok {
+		return
+	}
+
+	entries, total, err := h.auditService.List(r.Context(), filter)
+	if err != nil {
+		response.InternalError(w)
+		return
+	}
+
+	response.WithPagination(w, http.StatusOK, entries, filter.Page, filter.PerPage, int(total))
+}
+
+// Export handles GET /api/admin/audit/export?format=csv|jsonl, streaming
+// every matching entry
+func (h *AuditHandler) Export(w http.ResponseWriter, r *http.Request) {
+	filter, ok := parseAuditFilter(w, r)
+	if !ok {
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "jsonl"
+	}
+	if format != "csv" && format != "jsonl" {
+		response.ValidationErrorWithFields(w, map[string]string{"format": "format must be one of csv jsonl"})
+		return
+	}
+
+	filename := "audit-" + time.Now().UTC().Format("20060102-150405") + "." + format
+	w.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	// Headers are already sent once streaming starts, so errors past this
+	// point can only truncate the export
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		writer := csv.NewWriter(w)
+		writer.Write(auditCSVHeader)
+		h.auditService.Export(r.Context(), filter, func(entry *model.AuditLog) error {
+			return writer.Write(auditCSVRecord(entry))
+		})
+		writer.Flush()
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	encoder := json.NewEncoder(w)
+	h.auditService.Export(r.Context(), filter, func(entry *model.AuditLog) error {
+		return encoder.Encode(entry)
+	})
+}
+
+// Checkpoints handles GET /api/admin/audit/checkpoints, returning the signed
+// chain checkpoints and the public key that verifies them
+func (h *AuditHandler) Checkpoints(w http.ResponseWriter, r *http.Request) {
+	export, err := h.auditService.Checkpoints(r.Context())
+	if err != nil {
+		response.InternalError(w)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, export)
+}
+
+var auditCSVHeader = []string{
+	"id", "created_at", "action", "outcome", "actor_id", "actor_email",
+	"target_type", "target_id", "ip", "request_id", "user_agent", "metadata",
+	"prev_hash", "hash", "hmac",
+}
+
+// auditCSVRecord flattens an entry into a CSV row
+func auditCSVRecord(entry *model.AuditLog) []string {
+	metadata, _ := json.Marshal(entry.Metadata)
+	return []string{
+		strconv.FormatUint(uint64(entry.ID), 10),
+		entry.CreatedAt.UTC().Format(time.RFC3339Nano),
+		string(entry.Action),
+		string(entry.Outcome),
+		optionalID(entry.ActorID),
+		entry.ActorEmail,
+		string(entry.TargetType),
+		optionalID(entry.TargetID),
+		entry.IP,
+		entry.RequestID,
+		entry.UserAgent,
+		string(metadata),
+		entry.PrevHash,
+		entry.Hash,
+		entry.HMAC,
+	}
+}
+
+// optionalID formats a nullable ID for CSV output
+func optionalID(id *uint) string {
+	if id == nil {
+		return ""
+	}
+	return strconv.FormatUint(uint64(*id), 10)
+}
+
+// parseAuditFilter reads and validates the audit filters from the query
+// string, writing a validation error response when they are invalid
+func parseAuditFilter(w http.ResponseWriter, r *http.Request) (*model.AuditLogFilter, bool) {
+	q := r.URL.Query()
+	fieldErrors := make(map[string]string)
+	filter := &model.AuditLogFilter{
+		Action:     model.AuditAction(q.Get("action")),
+		Outcome:    model.AuditOutcome(q.Get("outcome")),
+		ActorID:    queryUint(q, "actor_id", fieldErrors),
+		TargetType: model.AuditTarget(q.Get("target_type")),
+		TargetID:   queryUint(q, "target_id", fieldErrors),
+		IP:         q.Get("ip"),
+		RequestID:  q.Get("request_id"),
+		From:       q.Get("from"),
+		To:         q.Get("to"),
+		Page:       queryInt(q, "page", fieldErrors),
+		PerPage:    queryInt(q, "per_page", fieldErrors),
+	}
+	if len(fieldErrors) > 0 {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return nil, false
+	}
+
+	if fieldErrors := util.ValidateStructWithFields(filter); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return nil, false
+	}
+
+	return filter, true
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// BatchHandler handles bulk operations over files and folders
+type BatchHandler struct {
+	batchService service.BatchService
+}
+
+// NewBatchHandler creates a new batch handler
+func NewBatchHandler(batchService service.BatchService) *BatchHandler {
+	return &BatchHandler{
+		batchService: batchService,
+	}
+}
+
+// Run handles POST /api/batch. Batches run during the request are returned
+// with 200; queued ones with 202 and an ID to poll.
+func (h *BatchHandler) Run(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	var req model.BatchRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	job, async, err := h.batchService.Run(r.Context(), userID, &req)
+	if err != nil {
+		writeBatchError(w, err, "Failed to run batch")
+		return
+	}
+
+	status := http.StatusOK
+	if async {
+		status = http.StatusAccepted
+	}
+	response.JSON(w, status, job)
+}
+
+// Get handles GET /api/batch/{id}
+func (h *BatchHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	jobID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid batch ID")
+		return
+	}
+
+	job, err := h.batchService.Get(r.Context(), userID, jobID)
+	if err != nil {
+		writeBatchError(w, err, "Failed to get batch")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, job)
+}
+
+// writeBatchError maps batch service errors to responses
+func writeBatchError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrBatchNotFound):
+		response.NotFound(w, "Batch not found")
+	default:
+		response.Error(w, http.StatusInternalServerError, response.ErrInternalServer, message)
+	}
+}
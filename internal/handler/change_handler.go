@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// ChangeHandler serves the change journal to sync clients
+type ChangeHandler struct {
+	changeService service.ChangeService
+}
+
+// NewChangeHandler creates a new change handler
+func NewChangeHandler(changeService service.ChangeService) *ChangeHandler {
+	return &ChangeHandler{
+		changeService: changeService,
+	}
+}
+
+// List handles GET /api/changes?cursor=&limit=&wait=
+func (h *ChangeHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	q := r.URL.Query()
+	fieldErrors := make(map[string]string)
+	filter := &model.ChangeFilter{
+		Cursor: q.Get("cursor"),
+		Limit:  queryInt(q, "limit", fieldErrors),
+		Wait:   queryInt(q, "wait", fieldErrors),
+	}
+	if len(fieldErrors) > 0 {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+	if fieldErrors := util.ValidateStructWithFields(filter); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	changes, err := h.changeService.List(r.Context(), userID, filter)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			response.ValidationErrorWithFields(w, map[string]string{"cursor": "cursor is not valid"})
+			return
+		}
+		response.Error(w, http.StatusInternalServerError, response.ErrInternalServer, "Failed to list changes")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, changes)
+}
+
+// Latest handles GET /api/changes/latest
+func (h *ChangeHandler) Latest(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	cursor, err := h.changeService.Latest(r.Context(), userID)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, response.ErrInternalServer, "Failed to read the change journal")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, map[string]string{"cursor": cursor})
+}
@@ -0,0 +1,192 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// CommentHandler handles file comment requests
+type CommentHandler struct {
+	commentService service.CommentService
+}
+
+// NewCommentHandler creates a new comment handler
+func NewCommentHandler(commentService service.CommentService) *CommentHandler {
+	return &CommentHandler{
+		commentService: commentService,
+	}
+}
+
+// List handles GET /api/files/{id}/comments
+func (h *CommentHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	fileID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid file ID")
+		return
+	}
+
+	threads, err := h.commentService.List(r.Context(), userID, fileID)
+	if err != nil {
+		writeCommentError(w, err, "Failed to list comments")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, threads)
+}
+
+// Create handles POST /api/files/{id}/comments
+func (h *CommentHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	fileID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid file ID")
+		return
+	}
+
+	var req model.CreateCommentRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	comment, err := h.commentService.Create(r.Context(), userID, fileID, &req)
+	if err != nil {
+		writeCommentError(w, err, "Failed to create comment")
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, comment)
+}
+
+// Update handles PATCH /api/comments/{id}
+func (h *CommentHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	commentID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid comment ID")
+		return
+	}
+
+	var req model.UpdateCommentRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	comment, err := h.commentService.Update(r.Context(), userID, commentID, &req)
+	if err != nil {
+		writeCommentError(w, err, "Failed to update comment")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, comment)
+}
+
+// Delete handles DELETE /api/comments/{id}
+func (h *CommentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	commentID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid comment ID")
+		return
+	}
+
+	if err := h.commentService.Delete(r.Context(), userID, commentID); err != nil {
+		writeCommentError(w, err, "Failed to delete comment")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Resolve handles POST /api/comments/{id}/resolve
+func (h *CommentHandler) Resolve(w http.ResponseWriter, r *http.Request) {
+	h.setResolved(w, r, true)
+}
+
+// Reopen handles POST /api/comments/{id}/reopen
+func (h *CommentHandler) Reopen(w http.ResponseWriter, r *http.Request) {
+	h.setResolved(w, r, false)
+}
+
+func (h *CommentHandler) setResolved(w http.ResponseWriter, r *http.Request, resolved bool) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	commentID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid comment ID")
+		return
+	}
+
+	var thread *model.Comment
+	if resolved {
+		thread, err = h.commentService.Resolve(r.Context(), userID, commentID)
+	} else {
+		thread, err = h.commentService.Reopen(r.Context(), userID, commentID)
+	}
+	if err != nil {
+		writeCommentError(w, err, "Failed to update comment thread")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, thread)
+}
+
+// History handles GET /api/comments/{id}/history
+func (h *CommentHandler) History(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	commentID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid comment ID")
+		return
+	}
+
+	revisions, err := h.commentService.History(r.Context(), userID, commentID)
+	if err != nil {
+		writeCommentError(w, err, "Failed to get comment history")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, revisions)
+}
+
+// writeCommentError maps comment service errors onto HTTP responses
+func writeCommentError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrCommentNotFound):
+		response.NotFound(w, "Comment not found")
+	case errors.Is(err, service.ErrInvalidCommentParent):
+		response.BadRequest(w, err.Error())
+	default:
+		writeFileError(w, err, message)
+	}
+}
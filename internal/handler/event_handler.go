@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/response"
+	"drive/internal/service"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// eventHeartbeatInterval keeps idle streams alive through proxies
+	eventHeartbeatInterval = 30 * time.Second
+	// eventWriteTimeout drops WebSocket clients that stop reading
+	eventWriteTimeout = 10 * time.Second
+)
+
+// EventHandler streams change events to connected clients
+type EventHandler struct {
+	events         service.EventBus
+	allowedOrigins []string
+}
+
+// NewEventHandler creates a new event handler. WebSockets may be opened from
+// the server's own origin and from allowedOrigins.
+func NewEventHandler(events service.EventBus, allowedOrigins []string) *EventHandler {
+	return &EventHandler{
+		events:         events,
+		allowedOrigins: allowedOrigins,
+	}
+}
+
+// Stream handles GET /api/events as a Server-Sent Events stream. Each event
+// is sent with its type as the SSE event name and its ID as the SSE id; a
+// comment line is sent as a heartbeat while the stream is idle.
+func (h *EventHandler) Stream(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	// The server write timeout would otherwise end long-lived streams
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	sub := h.events.Subscribe(userID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 5000\n\n")
+	if err := rc.Flush(); err != nil {
+		return
+	}
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+		case event, ok := <-sub.Events():
+			if !ok {
+				if err := sub.Err(); err != nil {
+					fmt.Fprintf(w, "event: close\ndata: %q\n\n", err.Error())
+					rc.Flush()
+				}
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+// WebSocket handles GET /api/events/ws. Events are sent as JSON text
+// messages in the same shape as the SSE data; {"type":"ping"} is sent as a
+// heartbeat. Messages from the client are ignored.
+func (h *EventHandler) WebSocket(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	server := websocket.Server{
+		Handshake: func(_ *websocket.Config, r *http.Request) error {
+			return h.checkOrigin(r)
+		},
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close()
+			h.serveWebSocket(ws, userID)
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+// checkOrigin stops other sites from opening a WebSocket with a token they
+// obtained from the browser. Clients that are not browsers send no Origin.
+func (h *EventHandler) checkOrigin(r *http.Request) error {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return fmt.Errorf("invalid origin %q", origin)
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return nil
+	}
+	for _, allowed := range h.allowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), u.Scheme+"://"+u.Host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("origin %q not allowed", origin)
+}
+
+// serveWebSocket forwards events to a WebSocket until either side closes it
+func (h *EventHandler) serveWebSocket(ws *websocket.Conn, userID uint) {
+	sub := h.events.Subscribe(userID)
+	defer sub.Close()
+
+	// Reading is the only way to notice the client going away
+	disconnected := make(chan struct{})
+	go func() {
+		defer close(disconnected)
+		for {
+			var message string
+			if err := websocket.Message.Receive(ws, &message); err != nil {
+				return
+			}
+		}
+	}()
+
+	heartbeat := time.NewTicker(eventHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		var message any
+		select {
+		case <-disconnected:
+			return
+		case <-heartbeat.C:
+			message = map[string]string{"type": "ping"}
+		case event, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			message = event
+		}
+
+		ws.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+		if err := websocket.JSON.Send(ws, message); err != nil {
+			return
+		}
+	}
+}
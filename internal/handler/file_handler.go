@@ -0,0 +1,266 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FileHandler handles file requests
+type FileHandler struct {
+	fileService service.FileService
+}
+
+// NewFileHandler creates a new file handler
+func NewFileHandler(fileService service.FileService) *FileHandler {
+	return &FileHandler{
+		fileService: fileService,
+	}
+}
+
+// Upload handles POST /api/files. The request is a multipart form with an
+// optional "folder_id" field followed by a "file" part, streamed straight to
+// storage.
+func (h *FileHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		response.BadRequest(w, "Request must be multipart/form-data", err.Error())
+		return
+	}
+
+	var folderID uint
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			response.BadRequest(w, "Invalid multipart body", err.Error())
+			return
+		}
+
+		switch part.FormName() {
+		case "folder_id":
+			value, _ := io.ReadAll(io.LimitReader(part, 32))
+			id, err := strconv.ParseUint(strings.TrimSpace(string(value)), 10, 64)
+			if err != nil {
+				response.ValidationErrorWithFields(w, map[string]string{"folder_id": "folder_id must be a positive integer"})
+				return
+			}
+			folderID = uint(id)
+		case "file":
+			// The part's length is not known up front; the multipart
+			// reader fails on a body cut short before its closing boundary
+			file, err := h.fileService.Upload(r.Context(), userID, folderID, part.FileName(), 0, part)
+			if err != nil {
+				writeFileError(w, err, "Failed to upload file")
+				return
+			}
+			response.JSON(w, http.StatusCreated, file)
+			return
+		}
+	}
+
+	response.ValidationErrorWithFields(w, map[string]string{"file": "file is required"})
+}
+
+// Get handles GET /api/files/{id}
+func (h *FileHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	fileID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid file ID")
+		return
+	}
+
+	file, err := h.fileService.GetFile(r.Context(), userID, fileID)
+	if err != nil {
+		writeFileError(w, err, "Failed to get file")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, file)
+}
+
+// Download handles GET /api/files/{id}/download. Range requests are
+// supported through http.ServeContent.
+func (h *FileHandler) Download(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	fileID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid file ID")
+		return
+	}
+
+	file, content, err := h.fileService.Download(r.Context(), userID, fileID)
+	if err != nil {
+		writeFileError(w, err, "Failed to download file")
+		return
+	}
+	defer content.Close()
+
+	if file.MimeType != "" {
+		w.Header().Set("Content-Type", file.MimeType)
+	}
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": file.FileName}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("ETag", file.ETag())
+	http.ServeContent(w, r, file.FileName, file.UpdatedAt, content)
+}
+
+// ReplaceContent handles PUT /api/files/{id}/content. The request body is
+// the new content. With an If-Match header carrying the file's ETag the
+// content is only replaced if the file has not changed since it was read.
+func (h *FileHandler) ReplaceContent(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	fileID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid file ID")
+		return
+	}
+
+	var ifUpdatedAt *time.Time
+	if match := r.Header.Get("If-Match"); match != "" && match != "*" {
+		current, err := h.fileService.GetFile(r.Context(), userID, fileID)
+		if err != nil {
+			writeFileError(w, err, "Failed to replace file content")
+			return
+		}
+		if !etagMatches(match, current.ETag()) {
+			writeFileError(w, service.ErrFileModified, "Failed to replace file content")
+			return
+		}
+		ifUpdatedAt = &current.UpdatedAt
+	}
+
+	file, err := h.fileService.Replace(r.Context(), userID, fileID, r.ContentLength, r.Body, ifUpdatedAt)
+	if err != nil {
+		writeFileError(w, err, "Failed to replace file content")
+		return
+	}
+
+	w.Header().Set("ETag", file.ETag())
+	response.JSON(w, http.StatusOK, file)
+}
+
+// Update handles PATCH /api/files/{id}
+func (h *FileHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	fileID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid file ID")
+		return
+	}
+
+	var req model.UpdateFileRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	file, err := h.fileService.Update(r.Context(), userID, fileID, &req)
+	if err != nil {
+		writeFileError(w, err, "Failed to update file")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, file)
+}
+
+// Delete handles DELETE /api/files/{id}
+func (h *FileHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	fileID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid file ID")
+		return
+	}
+
+	if err := h.fileService.Delete(r.Context(), userID, fileID); err != nil {
+		writeFileError(w, err, "Failed to delete file")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeFileError maps file and folder service errors onto HTTP responses
+func writeFileError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrFileNotFound):
+		response.NotFound(w, "File not found")
+	case errors.Is(err, service.ErrFolderNotFound):
+		response.NotFound(w, "Folder not found")
+	case errors.Is(err, service.ErrPermissionDenied):
+		response.Forbidden(w, "You do not have permission to perform this action")
+	case errors.Is(err, service.ErrFileQuarantined):
+		response.Forbidden(w, "File is quarantined because malware was found in it")
+	case errors.Is(err, service.ErrFileNotScanned):
+		response.Error(w, http.StatusConflict, response.ErrScanPending, "File has not been scanned for malware yet, try again later")
+	case errors.Is(err, service.ErrQuotaExceeded):
+		response.Error(w, http.StatusInsufficientStorage, response.ErrQuotaExceeded, "Storage quota exceeded")
+	case errors.Is(err, service.ErrFileTooLarge):
+		response.Error(w, http.StatusRequestEntityTooLarge, response.ErrBadRequest, "File exceeds the maximum upload size")
+	case errors.Is(err, service.ErrInvalidFileName):
+		response.BadRequest(w, "Invalid file name")
+	case errors.Is(err, service.ErrIncompleteUpload):
+		response.BadRequest(w, "Upload ended before its declared size")
+	case errors.Is(err, service.ErrFileLocked):
+		response.Error(w, http.StatusLocked, response.ErrLocked, "File is locked by another user")
+	case errors.Is(err, service.ErrFileModified):
+		response.Error(w, http.StatusPreconditionFailed, response.ErrPreconditionFailed, "File was modified since it was last read")
+	case errors.Is(err, service.ErrLegalHold), errors.Is(err, service.ErrRetentionPeriod):
+		response.Error(w, http.StatusConflict, response.ErrRetained, err.Error())
+	case errors.Is(err, service.ErrVaultBoundary), errors.Is(err, service.ErrNestedVault),
+		errors.Is(err, service.ErrVaultKeyRequired), errors.Is(err, service.ErrNotInVault),
+		errors.Is(err, service.ErrWorkspaceBoundary):
+		response.BadRequest(w, err.Error())
+	default:
+		response.Error(w, http.StatusInternalServerError, response.ErrInternalServer, message)
+	}
+}
+
+// etagMatches reports whether an If-Match header lists etag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		if strings.TrimSpace(candidate) == etag {
+			return true
+		}
+	}
+	return false
+}
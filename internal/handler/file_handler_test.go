@@ -0,0 +1,252 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+
+	"drive/internal/handler"
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/routes"
+	"drive/internal/service"
+	"drive/pkg/client"
+)
+
+const (
+	ownerToken  = "owner-token"
+	viewerToken = "viewer-token"
+	ownerID     = 1
+	viewerID    = 2
+	fileID      = 7
+)
+
+// stubAuth accepts the test tokens
+type stubAuth struct {
+	service.AuthService
+}
+
+func (stubAuth) GetUserByToken(ctx context.Context, token string) (*model.User, error) {
+	switch token {
+	case ownerToken:
+		return &model.User{ID: ownerID}, nil
+	case viewerToken:
+		return &model.User{ID: viewerID}, nil
+	}
+	return nil, errors.New("invalid token")
+}
+
+// stubFiles holds a single file the owner can write to and the viewer can
+// only read. Every replace moves its update time forward.
+type stubFiles struct {
+	service.FileService
+
+	mu      sync.Mutex
+	file    model.File
+	content []byte
+}
+
+func newStubFiles() *stubFiles {
+	return &stubFiles{
+		file:    model.File{ID: fileID, FileName: "notes.txt", UserID: ownerID, FileSize: 5, UpdatedAt: time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC)},
+		content: []byte("hello"),
+	}
+}
+
+func (s *stubFiles) permission(userID uint) model.Permission {
+	switch userID {
+	case ownerID:
+		return model.PermissionOwner
+	case viewerID:
+		return model.PermissionRead
+	}
+	return ""
+}
+
+func (s *stubFiles) GetFile(ctx context.Context, userID, id uint) (*model.File, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if id != fileID || s.permission(userID) == "" {
+		return nil, service.ErrFileNotFound
+	}
+	file := s.file
+	return &file, nil
+}
+
+func (s *stubFiles) Download(ctx context.Context, userID, id uint) (*model.File, io.ReadSeekCloser, error) {
+	file, err := s.GetFile(ctx, userID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return file, readSeekNopCloser{bytes.NewReader(s.content)}, nil
+}
+
+func (s *stubFiles) Replace(ctx context.Context, userID, id uint, size int64, r io.Reader, ifUpdatedAt *time.Time) (*model.File, error) {
+	if _, err := s.GetFile(ctx, userID, id); err != nil {
+		return nil, err
+	}
+	if s.permission(userID) != model.PermissionOwner {
+		return nil, service.ErrPermissionDenied
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ifUpdatedAt != nil && !ifUpdatedAt.Equal(s.file.UpdatedAt) {
+		return nil, service.ErrFileModified
+	}
+	s.content = content
+	s.file.FileSize = int64(len(content))
+	s.file.UpdatedAt = s.file.UpdatedAt.Add(time.Second)
+	file := s.file
+	return &file, nil
+}
+
+type readSeekNopCloser struct {
+	io.ReadSeeker
+}
+
+func (readSeekNopCloser) Close() error { return nil }
+
+// newFileServer serves the file routes behind the real authentication
+// middleware
+func newFileServer(t *testing.T, files service.FileService) *httptest.Server {
+	t.Helper()
+	h := &handler.Handler{FileHandler: handler.NewFileHandler(files)}
+	r := chi.NewRouter()
+	r.Route("/api", func(r chi.Router) {
+		r.Use(middleware.Auth(stubAuth{}))
+		routes.FileRoutes(r, h)
+	})
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func newClient(server *httptest.Server, token string) *client.Client {
+	return client.New(server.URL, client.WithTokens(client.Tokens{AccessToken: token}))
+}
+
+func TestDownloadSendsETag(t *testing.T) {
+	files := newStubFiles()
+	server := newFileServer(t, files)
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/api/files/7/download", nil)
+	req.Header.Set("Authorization", "Bearer "+viewerToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if string(body) != "hello" {
+		t.Errorf("body = %q, want %q", body, "hello")
+	}
+	if got, want := resp.Header.Get("ETag"), files.file.ETag(); got != want {
+		t.Errorf("ETag = %s, want %s", got, want)
+	}
+}
+
+func TestReplaceContent(t *testing.T) {
+	files := newStubFiles()
+	server := newFileServer(t, files)
+	owner := newClient(server, ownerToken)
+	ctx := context.Background()
+
+	current, err := owner.File(ctx, fileID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	etag := current.ETag()
+
+	replaced, err := owner.ReplaceContent(ctx, fileID, etag, 5, strings.NewReader("world"))
+	if err != nil {
+		t.Fatalf("replace with current ETag: %v", err)
+	}
+	if replaced.ETag() == etag {
+		t.Error("ETag did not change after the content was replaced")
+	}
+	if string(files.content) != "world" {
+		t.Errorf("content = %q, want %q", files.content, "world")
+	}
+
+	// The file changed since etag was read
+	_, err = owner.ReplaceContent(ctx, fileID, etag, 3, strings.NewReader("old"))
+	if !client.IsModified(err) {
+		t.Fatalf("replace with stale ETag: err = %v, want a failed precondition", err)
+	}
+	if string(files.content) != "world" {
+		t.Errorf("stale replace changed the content to %q", files.content)
+	}
+
+	// Without an ETag the content is replaced unconditionally
+	if _, err := owner.ReplaceContent(ctx, fileID, "", 3, strings.NewReader("new")); err != nil {
+		t.Fatalf("unconditional replace: %v", err)
+	}
+	if string(files.content) != "new" {
+		t.Errorf("content = %q, want %q", files.content, "new")
+	}
+}
+
+func TestReplaceContentRejections(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		path   string
+		ifTag  string
+		status int
+	}{
+		{name: "no token", path: "/api/files/7/content", status: http.StatusUnauthorized},
+		{name: "invalid token", token: "bogus", path: "/api/files/7/content", status: http.StatusUnauthorized},
+		{name: "read only", token: viewerToken, path: "/api/files/7/content", status: http.StatusForbidden},
+		{name: "unknown file", token: ownerToken, path: "/api/files/8/content", status: http.StatusNotFound},
+		{name: "unknown file with ETag", token: ownerToken, path: "/api/files/8/content", ifTag: `"x"`, status: http.StatusNotFound},
+		{name: "invalid ID", token: ownerToken, path: "/api/files/abc/content", status: http.StatusBadRequest},
+		{name: "stale ETag", token: ownerToken, path: "/api/files/7/content", ifTag: `"stale"`, status: http.StatusPreconditionFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := newStubFiles()
+			server := newFileServer(t, files)
+
+			req, _ := http.NewRequest(http.MethodPut, server.URL+tt.path, strings.NewReader("evil"))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			if tt.ifTag != "" {
+				req.Header.Set("If-Match", tt.ifTag)
+			}
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tt.status {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.status)
+			}
+			if string(files.content) != "hello" {
+				t.Errorf("content = %q, want it unchanged", files.content)
+			}
+		})
+	}
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// FolderHandler handles folder requests
+type FolderHandler struct {
+	folderService service.FolderService
+}
+
+// NewFolderHandler creates a new folder handler
+func NewFolderHandler(folderService service.FolderService) *FolderHandler {
+	return &FolderHandler{
+		folderService: folderService,
+	}
+}
+
+// Create handles POST /api/folders
+func (h *FolderHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	var req model.CreateFolderRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	folder, err := h.folderService.Create(r.Context(), userID, &req)
+	if err != nil {
+		writeFolderError(w, err, "Failed to create folder")
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, folder)
+}
+
+// GetRoot handles GET /api/folders/root
+func (h *FolderHandler) GetRoot(w http.ResponseWriter, r *http.Request) {
+	h.get(w, r, 0)
+}
+
+// Get handles GET /api/folders/{id}
+func (h *FolderHandler) Get(w http.ResponseWriter, r *http.Request) {
+	folderID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid folder ID")
+		return
+	}
+	h.get(w, r, folderID)
+}
+
+// get writes a folder and its children
+func (h *FolderHandler) get(w http.ResponseWriter, r *http.Request, folderID uint) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	contents, err := h.folderService.Get(r.Context(), userID, folderID)
+	if err != nil {
+		writeFolderError(w, err, "Failed to get folder")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, contents)
+}
+
+// Update handles PATCH /api/folders/{id}
+func (h *FolderHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	folderID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid folder ID")
+		return
+	}
+
+	var req model.UpdateFolderRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	folder, err := h.folderService.Update(r.Context(), userID, folderID, &req)
+	if err != nil {
+		writeFolderError(w, err, "Failed to update folder")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, folder)
+}
+
+// Delete handles DELETE /api/folders/{id}
+func (h *FolderHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	folderID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid folder ID")
+		return
+	}
+
+	if err := h.folderService.Delete(r.Context(), userID, folderID); err != nil {
+		writeFolderError(w, err, "Failed to delete folder")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeFolderError maps folder service errors onto HTTP responses
+func writeFolderError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrInvalidFolderName):
+		response.ValidationErrorWithFields(w, map[string]string{"name": "name must not be empty or contain slashes"})
+	case errors.Is(err, service.ErrInvalidFolderMove):
+		response.BadRequest(w, err.Error())
+	case errors.Is(err, service.ErrRootFolder):
+		response.BadRequest(w, err.Error())
+	default:
+		writeFileError(w, err, message)
+	}
+}
@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// GroupHandler handles the group and group member endpoints
+type GroupHandler struct {
+	groupService service.GroupService
+}
+
+// NewGroupHandler creates a new group handler
+func NewGroupHandler(groupService service.GroupService) *GroupHandler {
+	return &GroupHandler{
+		groupService: groupService,
+	}
+}
+
+// List handles GET /api/groups
+func (h *GroupHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	groups, err := h.groupService.List(r.Context(), userID)
+	if err != nil {
+		response.InternalError(w)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, groups)
+}
+
+// Create handles POST /api/groups
+func (h *GroupHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user, err := middleware.GetUserFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	var req model.CreateGroupRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	group, err := h.groupService.Create(r.Context(), user, &req)
+	if err != nil {
+		writeGroupError(w, err, "Failed to create group")
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, group)
+}
+
+// Get handles GET /api/groups/{id}
+func (h *GroupHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	groupID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid group ID")
+		return
+	}
+
+	group, err := h.groupService.Get(r.Context(), userID, groupID)
+	if err != nil {
+		writeGroupError(w, err, "Failed to get group")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, group)
+}
+
+// Update handles PATCH /api/groups/{id}
+func (h *GroupHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user, groupID, ok := groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateGroupRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	group, err := h.groupService.Update(r.Context(), user, groupID, &req)
+	if err != nil {
+		writeGroupError(w, err, "Failed to update group")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, group)
+}
+
+// Delete handles DELETE /api/groups/{id}
+func (h *GroupHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, groupID, ok := groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.groupService.Delete(r.Context(), user, groupID); err != nil {
+		writeGroupError(w, err, "Failed to delete group")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListMembers handles GET /api/groups/{id}/members
+func (h *GroupHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	groupID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid group ID")
+		return
+	}
+
+	members, err := h.groupService.ListMembers(r.Context(), userID, groupID)
+	if err != nil {
+		writeGroupError(w, err, "Failed to list group members")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, members)
+}
+
+// AddMember handles POST /api/groups/{id}/members
+func (h *GroupHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	user, groupID, ok := groupRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.AddGroupMemberRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	member, err := h.groupService.AddMember(r.Context(), user, groupID, &req)
+	if err != nil {
+		writeGroupError(w, err, "Failed to add group member")
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, member)
+}
+
+// RemoveMember handles DELETE /api/groups/{id}/members/{userID}
+func (h *GroupHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	user, groupID, ok := groupRequest(w, r)
+	if !ok {
+		return
+	}
+	memberID, ok := urlParamUint(r, "userID")
+	if !ok {
+		response.BadRequest(w, "Invalid user ID")
+		return
+	}
+
+	if err := h.groupService.RemoveMember(r.Context(), user, groupID, memberID); err != nil {
+		writeGroupError(w, err, "Failed to remove group member")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// groupRequest reads the caller and the group ID of a request that manages
+// a group, writing an error response when either is missing
+func groupRequest(w http.ResponseWriter, r *http.Request) (*model.User, uint, bool) {
+	user, err := middleware.GetUserFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return nil, 0, false
+	}
+	groupID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid group ID")
+		return nil, 0, false
+	}
+	return user, groupID, true
+}
+
+// writeGroupError maps group service errors to responses
+func writeGroupError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrGroupNotFound):
+		response.NotFound(w, "Group not found")
+	case errors.Is(err, service.ErrGroupMemberNotFound):
+		response.NotFound(w, "Group member not found")
+	case errors.Is(err, service.ErrUserNotFound):
+		response.NotFound(w, "User not found")
+	case errors.Is(err, service.ErrInvalidGroupName):
+		response.BadRequest(w, "Invalid group name")
+	case errors.Is(err, service.ErrAlreadyGroupMember):
+		response.Error(w, http.StatusConflict, response.ErrDuplicateEntry, err.Error())
+	default:
+		writeFileError(w, err, message)
+	}
+}
@@ -1,15 +1,72 @@
 package handler
 
-import "drive/internal/service"
+import (
+	"drive/internal/config"
+	"drive/internal/dav"
+	"drive/internal/service"
+	"drive/internal/util"
+)
 
 type Handler struct {
-	UserHandler  *UserHandler
-	OAuthHandler *OAuthHandler
+	UserHandler         *UserHandler
+	OAuthHandler        *OAuthHandler
+	FileHandler         *FileHandler
+	FolderHandler       *FolderHandler
+	ShareHandler        *ShareHandler
+	SearchHandler       *SearchHandler
+	AuditHandler        *AuditHandler
+	WebhookHandler      *WebhookHandler
+	EventHandler        *EventHandler
+	ChangeHandler       *ChangeHandler
+	AppPasswordHandler  *AppPasswordHandler
+	VaultHandler        *VaultHandler
+	LockHandler         *LockHandler
+	CommentHandler      *CommentHandler
+	PreviewHandler      *PreviewHandler
+	PhotoHandler        *PhotoHandler
+	AlbumHandler        *AlbumHandler
+	InsightsHandler     *InsightsHandler
+	BatchHandler        *BatchHandler
+	StarHandler         *StarHandler
+	JobHandler          *JobHandler
+	TaskHandler         *TaskHandler
+	IntegrityHandler    *IntegrityHandler
+	RetentionHandler    *RetentionHandler
+	WorkspaceHandler    *WorkspaceHandler
+	GroupHandler        *GroupHandler
+	DAVHandler          *dav.Handler
+	WorkspaceDAVHandler *dav.Handler
 }
 
-func NewHandler(services *service.Services) *Handler {
+func NewHandler(services *service.Services, cfg *config.Config, logger *util.Logger) *Handler {
 	return &Handler{
-		UserHandler:  NewUserHandler(services.Auth),
-		OAuthHandler: NewOAuthHandler(services.OAuth),
+		UserHandler:         NewUserHandler(services.Auth),
+		OAuthHandler:        NewOAuthHandler(services.OAuth),
+		FileHandler:         NewFileHandler(services.File),
+		FolderHandler:       NewFolderHandler(services.Folder),
+		ShareHandler:        NewShareHandler(services.Share),
+		SearchHandler:       NewSearchHandler(services.Search),
+		AuditHandler:        NewAuditHandler(services.Audit),
+		WebhookHandler:      NewWebhookHandler(services.Webhook),
+		EventHandler:        NewEventHandler(services.Events, cfg.Server.AllowedOrigins),
+		ChangeHandler:       NewChangeHandler(services.Changes),
+		AppPasswordHandler:  NewAppPasswordHandler(services.AppPassword),
+		VaultHandler:        NewVaultHandler(services.Vault),
+		LockHandler:         NewLockHandler(services.Lock),
+		CommentHandler:      NewCommentHandler(services.Comment),
+		PreviewHandler:      NewPreviewHandler(services.Preview),
+		PhotoHandler:        NewPhotoHandler(services.Photo),
+		AlbumHandler:        NewAlbumHandler(services.Album),
+		InsightsHandler:     NewInsightsHandler(services.Insights),
+		BatchHandler:        NewBatchHandler(services.Batch),
+		StarHandler:         NewStarHandler(services.Star),
+		JobHandler:          NewJobHandler(services.Jobs),
+		TaskHandler:         NewTaskHandler(services.Scheduler),
+		IntegrityHandler:    NewIntegrityHandler(services.Integrity),
+		RetentionHandler:    NewRetentionHandler(services.Retention),
+		WorkspaceHandler:    NewWorkspaceHandler(services.Workspace),
+		GroupHandler:        NewGroupHandler(services.Group),
+		DAVHandler:          dav.NewHandler("/dav", services.File, services.Folder, services.Lock, logger),
+		WorkspaceDAVHandler: dav.NewWorkspaceHandler("/dav-workspaces", services.File, services.Folder, services.Lock, services.Workspace, logger),
 	}
 }
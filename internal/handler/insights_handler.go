@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// InsightsHandler serves storage insights and duplicate cleanup
+type InsightsHandler struct {
+	insightsService service.InsightsService
+}
+
+// NewInsightsHandler creates a new insights handler
+func NewInsightsHandler(insightsService service.InsightsService) *InsightsHandler {
+	return &InsightsHandler{
+		insightsService: insightsService,
+	}
+}
+
+// Get handles GET /api/insights
+func (h *InsightsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	insights, err := h.insightsService.Insights(r.Context(), userID)
+	if err != nil {
+		writeInsightsError(w, err, "Failed to build storage insights")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, insights)
+}
+
+// Duplicates handles GET /api/insights/duplicates?page=&per_page=
+func (h *InsightsHandler) Duplicates(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	q := r.URL.Query()
+	fieldErrors := make(map[string]string)
+	filter := &model.DuplicateFilter{
+		Page:    queryInt(q, "page", fieldErrors),
+		PerPage: queryInt(q, "per_page", fieldErrors),
+	}
+	if len(fieldErrors) > 0 {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+	if fieldErrors := util.ValidateStructWithFields(filter); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	groups, total, err := h.insightsService.Duplicates(r.Context(), userID, filter)
+	if err != nil {
+		writeInsightsError(w, err, "Failed to list duplicate files")
+		return
+	}
+
+	response.WithPagination(w, http.StatusOK, groups, filter.Page, filter.PerPage, int(total))
+}
+
+// ResolveDuplicates handles POST /api/insights/duplicates/resolve
+func (h *InsightsHandler) ResolveDuplicates(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	var req model.ResolveDuplicatesRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	result, err := h.insightsService.ResolveDuplicates(r.Context(), userID, &req)
+	if err != nil {
+		writeInsightsError(w, err, "Failed to resolve duplicate files")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, result)
+}
+
+// writeInsightsError maps insights service errors to responses
+func writeInsightsError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		response.NotFound(w, "User not found")
+	case errors.Is(err, service.ErrInvalidDuplicate), errors.Is(err, service.ErrConflictingKeepers):
+		response.BadRequest(w, err.Error())
+	default:
+		response.Error(w, http.StatusInternalServerError, response.ErrInternalServer, message)
+	}
+}
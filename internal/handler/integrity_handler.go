@@ -0,0 +1,48 @@
+package handler
+
+import (
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"net/http"
+)
+
+// IntegrityHandler handles the admin storage integrity endpoints
+type IntegrityHandler struct {
+	integrityService service.IntegrityService
+}
+
+// NewIntegrityHandler creates a new integrity handler
+func NewIntegrityHandler(integrityService service.IntegrityService) *IntegrityHandler {
+	return &IntegrityHandler{
+		integrityService: integrityService,
+	}
+}
+
+// Damaged handles GET /api/admin/integrity?status=&page=&per_page=
+func (h *IntegrityHandler) Damaged(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	fieldErrors := make(map[string]string)
+	filter := &model.IntegrityFilter{
+		Status:  model.IntegrityStatus(q.Get("status")),
+		Page:    queryInt(q, "page", fieldErrors),
+		PerPage: queryInt(q, "per_page", fieldErrors),
+	}
+	if len(fieldErrors) > 0 {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+	if fieldErrors := util.ValidateStructWithFields(filter); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	files, total, err := h.integrityService.Damaged(r.Context(), filter)
+	if err != nil {
+		response.InternalError(w)
+		return
+	}
+
+	response.WithPagination(w, http.StatusOK, files, filter.Page, filter.PerPage, int(total))
+}
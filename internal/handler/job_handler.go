@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// JobHandler handles the admin background job endpoints
+type JobHandler struct {
+	jobService service.JobService
+}
+
+// NewJobHandler creates a new job handler
+func NewJobHandler(jobService service.JobService) *JobHandler {
+	return &JobHandler{
+		jobService: jobService,
+	}
+}
+
+// List handles GET /api/admin/jobs?status=&type=&page=&per_page=
+func (h *JobHandler) List(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	fieldErrors := make(map[string]string)
+	filter := &model.JobFilter{
+		Status:  model.JobStatus(q.Get("status")),
+		Type:    q.Get("type"),
+		Page:    queryInt(q, "page", fieldErrors),
+		PerPage: queryInt(q, "per_page", fieldErrors),
+	}
+	if len(fieldErrors) > 0 {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+	if fieldErrors := util.ValidateStructWithFields(filter); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	jobs, total, err := h.jobService.List(r.Context(), filter)
+	if err != nil {
+		response.InternalError(w)
+		return
+	}
+
+	response.WithPagination(w, http.StatusOK, jobs, filter.Page, filter.PerPage, int(total))
+}
+
+// Stats handles GET /api/admin/jobs/stats
+func (h *JobHandler) Stats(w http.ResponseWriter, r *http.Request) {
+	stats, err := h.jobService.Stats(r.Context())
+	if err != nil {
+		response.InternalError(w)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, stats)
+}
+
+// Get handles GET /api/admin/jobs/{id}
+func (h *JobHandler) Get(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid job ID")
+		return
+	}
+
+	job, err := h.jobService.Get(r.Context(), jobID)
+	if err != nil {
+		writeJobError(w, err, "Failed to get job")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, job)
+}
+
+// Retry handles POST /api/admin/jobs/{id}/retry
+func (h *JobHandler) Retry(w http.ResponseWriter, r *http.Request) {
+	jobID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid job ID")
+		return
+	}
+
+	job, err := h.jobService.Retry(r.Context(), jobID)
+	if err != nil {
+		writeJobError(w, err, "Failed to retry job")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, job)
+}
+
+// writeJobError maps job service errors to responses
+func writeJobError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrJobNotFound):
+		response.NotFound(w, "Job not found")
+	case errors.Is(err, service.ErrJobNotRetryable):
+		response.BadRequest(w, err.Error())
+	case errors.Is(err, service.ErrJobDuplicate):
+		response.Error(w, http.StatusConflict, response.ErrDuplicateEntry, err.Error())
+	default:
+		response.Error(w, http.StatusInternalServerError, response.ErrInternalServer, message)
+	}
+}
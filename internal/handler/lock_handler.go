@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// LockHandler handles file lock requests
+type LockHandler struct {
+	lockService service.LockService
+}
+
+// NewLockHandler creates a new lock handler
+func NewLockHandler(lockService service.LockService) *LockHandler {
+	return &LockHandler{
+		lockService: lockService,
+	}
+}
+
+// Lock handles POST /api/files/{id}/lock
+func (h *LockHandler) Lock(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	fileID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid file ID")
+		return
+	}
+
+	var req model.LockFileRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	lock, err := h.lockService.Lock(r.Context(), userID, fileID, &req)
+	if err != nil {
+		writeLockError(w, err, "Failed to lock file")
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, lock)
+}
+
+// List handles GET /api/files/{id}/locks
+func (h *LockHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	fileID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid file ID")
+		return
+	}
+
+	locks, err := h.lockService.List(r.Context(), userID, fileID)
+	if err != nil {
+		writeLockError(w, err, "Failed to list locks")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, locks)
+}
+
+// Unlock handles DELETE /api/files/{id}/lock
+func (h *LockHandler) Unlock(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	fileID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid file ID")
+		return
+	}
+
+	if err := h.lockService.Unlock(r.Context(), userID, fileID); err != nil {
+		writeLockError(w, err, "Failed to unlock file")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListAll handles GET /api/admin/locks?file_id=&user_id=
+func (h *LockHandler) ListAll(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	fieldErrors := map[string]string{}
+	fileID := queryUint(q, "file_id", fieldErrors)
+	userID := queryUint(q, "user_id", fieldErrors)
+	if len(fieldErrors) > 0 {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	locks, err := h.lockService.ListAll(r.Context(), fileID, userID)
+	if err != nil {
+		response.InternalError(w)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, locks)
+}
+
+// ForceUnlock handles DELETE /api/admin/locks/{id}
+func (h *LockHandler) ForceUnlock(w http.ResponseWriter, r *http.Request) {
+	adminID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	lockID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid lock ID")
+		return
+	}
+
+	if err := h.lockService.ForceUnlock(r.Context(), adminID, lockID); err != nil {
+		writeLockError(w, err, "Failed to unlock file")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeLockError maps lock service errors onto HTTP responses
+func writeLockError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrLockNotFound):
+		response.NotFound(w, "Lock not found")
+	default:
+		writeFileError(w, err, message)
+	}
+}
@@ -0,0 +1,63 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// queryInt reads an integer query parameter, recording a field error when it
+// is malformed
+func queryInt(q url.Values, key string, fieldErrors map[string]string) int {
+	value := q.Get(key)
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		fieldErrors[key] = key + " must be an integer"
+		return 0
+	}
+	return n
+}
+
+// queryInt64 reads a 64-bit integer query parameter, recording a field error
+// when it is malformed
+func queryInt64(q url.Values, key string, fieldErrors map[string]string) int64 {
+	value := q.Get(key)
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		fieldErrors[key] = key + " must be an integer"
+		return 0
+	}
+	return n
+}
+
+// queryUint reads an ID query parameter, recording a field error when it is
+// malformed
+func queryUint(q url.Values, key string, fieldErrors map[string]string) uint {
+	value := q.Get(key)
+	if value == "" {
+		return 0
+	}
+	n, err := strconv.ParseUint(value, 10, 64)
+	if err != nil {
+		fieldErrors[key] = key + " must be a positive integer"
+		return 0
+	}
+	return uint(n)
+}
+
+// urlParamUint reads an ID from the route, reporting whether it is valid
+func urlParamUint(r *http.Request, key string) (uint, bool) {
+	n, err := strconv.ParseUint(chi.URLParam(r, key), 10, 64)
+	if err != nil || n == 0 {
+		return 0, false
+	}
+	return uint(n), true
+}
@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// PhotoHandler serves the photo timeline
+type PhotoHandler struct {
+	photoService service.PhotoService
+}
+
+// NewPhotoHandler creates a new photo handler
+func NewPhotoHandler(photoService service.PhotoService) *PhotoHandler {
+	return &PhotoHandler{
+		photoService: photoService,
+	}
+}
+
+// Timeline handles GET /api/photos/timeline?granularity=month|year
+func (h *PhotoHandler) Timeline(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	req := &model.TimelineRequest{Granularity: r.URL.Query().Get("granularity")}
+	if fieldErrors := util.ValidateStructWithFields(req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	buckets, err := h.photoService.Timeline(r.Context(), userID, req)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, response.ErrInternalServer, "Failed to build the photo timeline")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, buckets)
+}
+
+// List handles GET /api/photos?year=&month=&page=&per_page=
+func (h *PhotoHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	q := r.URL.Query()
+	fieldErrors := make(map[string]string)
+	filter := &model.PhotoFilter{
+		Year:    queryInt(q, "year", fieldErrors),
+		Month:   queryInt(q, "month", fieldErrors),
+		Page:    queryInt(q, "page", fieldErrors),
+		PerPage: queryInt(q, "per_page", fieldErrors),
+	}
+	if len(fieldErrors) > 0 {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+	if fieldErrors := util.ValidateStructWithFields(filter); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	photos, total, err := h.photoService.List(r.Context(), userID, filter)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, response.ErrInternalServer, "Failed to list photos")
+		return
+	}
+
+	response.WithPagination(w, http.StatusOK, photos, filter.Page, filter.PerPage, int(total))
+}
+
+// Settings handles GET /api/photos/settings
+func (h *PhotoHandler) Settings(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	settings, err := h.photoService.Settings(r.Context(), userID)
+	if err != nil {
+		writePhotoSettingsError(w, err, "Failed to get photo settings")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, settings)
+}
+
+// UpdateSettings handles PUT /api/photos/settings
+func (h *PhotoHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	var req model.UpdatePhotoSettingsRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	settings, err := h.photoService.UpdateSettings(r.Context(), userID, &req)
+	if err != nil {
+		writePhotoSettingsError(w, err, "Failed to update photo settings")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, settings)
+}
+
+// writePhotoSettingsError maps photo settings errors onto HTTP responses
+func writePhotoSettingsError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, service.ErrUserNotFound) {
+		response.NotFound(w, "User not found")
+		return
+	}
+	response.Error(w, http.StatusInternalServerError, response.ErrInternalServer, message)
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// PreviewHandler handles file preview requests
+type PreviewHandler struct {
+	previewService service.PreviewService
+}
+
+// NewPreviewHandler creates a new preview handler
+func NewPreviewHandler(previewService service.PreviewService) *PreviewHandler {
+	return &PreviewHandler{
+		previewService: previewService,
+	}
+}
+
+// Preview handles GET /api/files/{id}/preview?page=&per_page=
+func (h *PreviewHandler) Preview(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	fileID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid file ID")
+		return
+	}
+
+	q := r.URL.Query()
+	fieldErrors := make(map[string]string)
+	req := &model.PreviewRequest{
+		Page:    queryInt(q, "page", fieldErrors),
+		PerPage: queryInt(q, "per_page", fieldErrors),
+	}
+	if len(fieldErrors) > 0 {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+	if fieldErrors := util.ValidateStructWithFields(req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	preview, err := h.previewService.Preview(r.Context(), userID, fileID, req)
+	if err != nil {
+		writePreviewError(w, err)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, preview)
+}
+
+// writePreviewError maps preview service errors onto HTTP responses
+func writePreviewError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, service.ErrPreviewUnsupported):
+		response.Error(w, http.StatusUnsupportedMediaType, response.ErrBadRequest, "No preview is available for this file type")
+	case errors.Is(err, service.ErrPreviewTooLarge):
+		response.Error(w, http.StatusUnprocessableEntity, response.ErrBadRequest, "File is too large to preview")
+	case errors.Is(err, service.ErrPreviewFailed):
+		response.Error(w, http.StatusUnprocessableEntity, response.ErrBadRequest, "File could not be previewed")
+	default:
+		writeFileError(w, err, "Failed to preview file")
+	}
+}
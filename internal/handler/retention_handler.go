@@ -0,0 +1,208 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// RetentionHandler handles the admin retention policy and legal hold
+// endpoints
+type RetentionHandler struct {
+	retentionService service.RetentionService
+}
+
+// NewRetentionHandler creates a new retention handler
+func NewRetentionHandler(retentionService service.RetentionService) *RetentionHandler {
+	return &RetentionHandler{
+		retentionService: retentionService,
+	}
+}
+
+// ListPolicies handles GET /api/admin/retention?page=&per_page=
+func (h *RetentionHandler) ListPolicies(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	fieldErrors := make(map[string]string)
+	filter := &model.RetentionPolicyFilter{
+		Page:    queryInt(q, "page", fieldErrors),
+		PerPage: queryInt(q, "per_page", fieldErrors),
+	}
+	if len(fieldErrors) > 0 {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+	if fieldErrors := util.ValidateStructWithFields(filter); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	policies, total, err := h.retentionService.ListPolicies(r.Context(), filter)
+	if err != nil {
+		response.InternalError(w)
+		return
+	}
+
+	response.WithPagination(w, http.StatusOK, policies, filter.Page, filter.PerPage, int(total))
+}
+
+// SetPolicy handles PUT /api/admin/folders/{id}/retention
+func (h *RetentionHandler) SetPolicy(w http.ResponseWriter, r *http.Request) {
+	adminID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	folderID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid folder ID")
+		return
+	}
+
+	var req model.SetRetentionPolicyRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	policy, err := h.retentionService.SetPolicy(r.Context(), adminID, folderID, &req)
+	if err != nil {
+		writeRetentionError(w, err, "Failed to set retention policy")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, policy)
+}
+
+// DeletePolicy handles DELETE /api/admin/folders/{id}/retention
+func (h *RetentionHandler) DeletePolicy(w http.ResponseWriter, r *http.Request) {
+	adminID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	folderID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid folder ID")
+		return
+	}
+
+	if err := h.retentionService.DeletePolicy(r.Context(), adminID, folderID); err != nil {
+		writeRetentionError(w, err, "Failed to delete retention policy")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListHolds handles GET /api/admin/legal-holds?status=&user_id=&folder_id=&page=&per_page=
+func (h *RetentionHandler) ListHolds(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	fieldErrors := make(map[string]string)
+	filter := &model.LegalHoldFilter{
+		Status:   q.Get("status"),
+		UserID:   queryUint(q, "user_id", fieldErrors),
+		FolderID: queryUint(q, "folder_id", fieldErrors),
+		Page:     queryInt(q, "page", fieldErrors),
+		PerPage:  queryInt(q, "per_page", fieldErrors),
+	}
+	if len(fieldErrors) > 0 {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+	if fieldErrors := util.ValidateStructWithFields(filter); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	holds, total, err := h.retentionService.ListHolds(r.Context(), filter)
+	if err != nil {
+		response.InternalError(w)
+		return
+	}
+
+	response.WithPagination(w, http.StatusOK, holds, filter.Page, filter.PerPage, int(total))
+}
+
+// PlaceHold handles POST /api/admin/legal-holds
+func (h *RetentionHandler) PlaceHold(w http.ResponseWriter, r *http.Request) {
+	adminID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	var req model.CreateLegalHoldRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	hold, err := h.retentionService.PlaceHold(r.Context(), adminID, &req)
+	if err != nil {
+		writeRetentionError(w, err, "Failed to place legal hold")
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, hold)
+}
+
+// GetHold handles GET /api/admin/legal-holds/{id}
+func (h *RetentionHandler) GetHold(w http.ResponseWriter, r *http.Request) {
+	holdID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid legal hold ID")
+		return
+	}
+
+	hold, err := h.retentionService.GetHold(r.Context(), holdID)
+	if err != nil {
+		writeRetentionError(w, err, "Failed to get legal hold")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, hold)
+}
+
+// ReleaseHold handles POST /api/admin/legal-holds/{id}/release
+func (h *RetentionHandler) ReleaseHold(w http.ResponseWriter, r *http.Request) {
+	adminID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	holdID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid legal hold ID")
+		return
+	}
+
+	hold, err := h.retentionService.ReleaseHold(r.Context(), adminID, holdID)
+	if err != nil {
+		writeRetentionError(w, err, "Failed to release legal hold")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, hold)
+}
+
+// writeRetentionError maps retention service errors to responses
+func writeRetentionError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrPolicyNotFound):
+		response.NotFound(w, "Retention policy not found")
+	case errors.Is(err, service.ErrLegalHoldNotFound):
+		response.NotFound(w, "Legal hold not found")
+	case errors.Is(err, service.ErrUserNotFound):
+		response.NotFound(w, "User not found")
+	case errors.Is(err, service.ErrInvalidRetention), errors.Is(err, service.ErrInvalidLegalHold):
+		response.BadRequest(w, err.Error())
+	case errors.Is(err, service.ErrLegalHoldReleased):
+		response.Error(w, http.StatusConflict, response.ErrBadRequest, err.Error())
+	default:
+		writeFileError(w, err, message)
+	}
+}
@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// SearchHandler handles search requests
+type SearchHandler struct {
+	searchService service.SearchService
+}
+
+// NewSearchHandler creates a new search handler
+func NewSearchHandler(searchService service.SearchService) *SearchHandler {
+	return &SearchHandler{
+		searchService: searchService,
+	}
+}
+
+// Search handles GET /api/search
+func (h *SearchHandler) Search(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	q := r.URL.Query()
+	fieldErrors := make(map[string]string)
+	req := model.SearchRequest{
+		Query:    q.Get("q"),
+		FileType: model.FileType(q.Get("type")),
+		MinSize:  queryInt64(q, "min_size", fieldErrors),
+		MaxSize:  queryInt64(q, "max_size", fieldErrors),
+		From:     q.Get("from"),
+		To:       q.Get("to"),
+		OwnerID:  queryUint(q, "owner_id", fieldErrors),
+		FolderID: queryUint(q, "folder_id", fieldErrors),
+		Tag:      q.Get("tag"),
+		Page:     queryInt(q, "page", fieldErrors),
+		PerPage:  queryInt(q, "per_page", fieldErrors),
+	}
+	if len(fieldErrors) > 0 {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	// Validate request with field errors
+	if fieldErrors := util.ValidateStructWithFields(&req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	results, total, err := h.searchService.Search(r.Context(), userID, &req)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSearchFilter) {
+			response.BadRequest(w, "Invalid search filter", err.Error())
+			return
+		}
+		response.InternalError(w)
+		return
+	}
+
+	response.WithPagination(w, http.StatusOK, results, req.Page, req.PerPage, int(total))
+}
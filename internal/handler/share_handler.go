@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// ShareHandler handles share requests
+type ShareHandler struct {
+	shareService service.ShareService
+}
+
+// NewShareHandler creates a new share handler
+func NewShareHandler(shareService service.ShareService) *ShareHandler {
+	return &ShareHandler{
+		shareService: shareService,
+	}
+}
+
+// Create handles POST /api/shares
+func (h *ShareHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	var req model.CreateShareRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	share, err := h.shareService.Create(r.Context(), userID, &req)
+	if err != nil {
+		writeShareError(w, err, "Failed to create share")
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, share)
+}
+
+// List handles GET /api/shares?file_id= or ?folder_id=
+func (h *ShareHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	fieldErrors := make(map[string]string)
+	fileID := queryUint(r.URL.Query(), "file_id", fieldErrors)
+	folderID := queryUint(r.URL.Query(), "folder_id", fieldErrors)
+	if fileID == 0 && folderID == 0 {
+		fieldErrors["file_id"] = "file_id or folder_id is required"
+	}
+	if len(fieldErrors) > 0 {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	shares, err := h.shareService.ListForItem(r.Context(), userID, fileID, folderID)
+	if err != nil {
+		writeShareError(w, err, "Failed to list shares")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, shares)
+}
+
+// ListReceived handles GET /api/shares/received
+func (h *ShareHandler) ListReceived(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	shares, err := h.shareService.ListReceived(r.Context(), userID)
+	if err != nil {
+		writeShareError(w, err, "Failed to list shares")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, shares)
+}
+
+// Update handles PATCH /api/shares/{id}
+func (h *ShareHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	shareID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid share ID")
+		return
+	}
+
+	var req model.UpdateShareRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	share, err := h.shareService.UpdatePermission(r.Context(), userID, shareID, req.Permission)
+	if err != nil {
+		writeShareError(w, err, "Failed to update share")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, share)
+}
+
+// Delete handles DELETE /api/shares/{id}
+func (h *ShareHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	shareID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid share ID")
+		return
+	}
+
+	if err := h.shareService.Delete(r.Context(), userID, shareID); err != nil {
+		writeShareError(w, err, "Failed to delete share")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeShareError maps share service errors onto HTTP responses
+func writeShareError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrShareNotFound):
+		response.NotFound(w, "Share not found")
+	case errors.Is(err, service.ErrUserNotFound):
+		response.NotFound(w, "User not found")
+	case errors.Is(err, service.ErrGroupNotFound):
+		response.NotFound(w, "Group not found")
+	case errors.Is(err, service.ErrShareAlreadyExists):
+		response.Error(w, http.StatusConflict, response.ErrDuplicateEntry, "Item is already shared with this user or group")
+	case errors.Is(err, service.ErrShareWithSelf):
+		response.BadRequest(w, "Cannot share an item with yourself")
+	case errors.Is(err, service.ErrShareRecipient), errors.Is(err, service.ErrGroupShareInVault):
+		response.BadRequest(w, err.Error())
+	default:
+		writeFileError(w, err, message)
+	}
+}
@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/response"
+	"drive/internal/service"
+	"net/http"
+)
+
+// StarHandler serves users' starred files and folders
+type StarHandler struct {
+	starService service.StarService
+}
+
+// NewStarHandler creates a new star handler
+func NewStarHandler(starService service.StarService) *StarHandler {
+	return &StarHandler{
+		starService: starService,
+	}
+}
+
+// List handles GET /api/starred
+func (h *StarHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	items, err := h.starService.List(r.Context(), userID)
+	if err != nil {
+		response.Error(w, http.StatusInternalServerError, response.ErrInternalServer, "Failed to list starred items")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, items)
+}
@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"net/http"
+)
+
+// TaskHandler handles the admin scheduled task endpoints
+type TaskHandler struct {
+	schedulerService service.SchedulerService
+}
+
+// NewTaskHandler creates a new task handler
+func NewTaskHandler(schedulerService service.SchedulerService) *TaskHandler {
+	return &TaskHandler{
+		schedulerService: schedulerService,
+	}
+}
+
+// List handles GET /api/admin/tasks
+func (h *TaskHandler) List(w http.ResponseWriter, r *http.Request) {
+	tasks, err := h.schedulerService.Tasks(r.Context())
+	if err != nil {
+		response.InternalError(w)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, tasks)
+}
+
+// Runs handles GET /api/admin/tasks/runs?task=&status=&page=&per_page=
+func (h *TaskHandler) Runs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	fieldErrors := make(map[string]string)
+	filter := &model.TaskRunFilter{
+		Task:    q.Get("task"),
+		Status:  model.TaskRunStatus(q.Get("status")),
+		Page:    queryInt(q, "page", fieldErrors),
+		PerPage: queryInt(q, "per_page", fieldErrors),
+	}
+	if len(fieldErrors) > 0 {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+	if fieldErrors := util.ValidateStructWithFields(filter); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	runs, total, err := h.schedulerService.Runs(r.Context(), filter)
+	if err != nil {
+		response.InternalError(w)
+		return
+	}
+
+	response.WithPagination(w, http.StatusOK, runs, filter.Page, filter.PerPage, int(total))
+}
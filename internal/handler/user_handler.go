@@ -67,3 +67,21 @@ func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
 		"refresh_token": token.RefreshToken,
 	})
 }
+
+func (h *UserHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req model.RefreshRequest
+
+	// Validate request with field errors
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	tokens, err := h.authService.RefreshTokens(r.Context(), req.RefreshToken)
+	if err != nil {
+		response.Unauthorized(w, "Invalid or expired refresh token")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, tokens)
+}
@@ -0,0 +1,127 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// VaultHandler handles vault key requests
+type VaultHandler struct {
+	vaultService service.VaultService
+}
+
+// NewVaultHandler creates a new vault handler
+func NewVaultHandler(vaultService service.VaultService) *VaultHandler {
+	return &VaultHandler{
+		vaultService: vaultService,
+	}
+}
+
+// SetPublicKey handles PUT /api/vault/public-key
+func (h *VaultHandler) SetPublicKey(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	var req model.SetPublicKeyRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	key, err := h.vaultService.SetPublicKey(r.Context(), userID, &req)
+	if err != nil {
+		writeVaultError(w, err, "Failed to save public key")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, key)
+}
+
+// GetOwnPublicKey handles GET /api/vault/public-key
+func (h *VaultHandler) GetOwnPublicKey(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	key, err := h.vaultService.PublicKey(r.Context(), userID, "")
+	if err != nil {
+		writeVaultError(w, err, "Failed to get public key")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, key)
+}
+
+// GetPublicKey handles GET /api/vault/public-keys?user_id=|email=
+func (h *VaultHandler) GetPublicKey(w http.ResponseWriter, r *http.Request) {
+	if _, err := middleware.GetUserIDFromContext(r); err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	q := r.URL.Query()
+	fieldErrors := map[string]string{}
+	userID := queryUint(q, "user_id", fieldErrors)
+	email := q.Get("email")
+	if userID == 0 && email == "" && len(fieldErrors) == 0 {
+		fieldErrors["user_id"] = "user_id or email is required"
+	}
+	if len(fieldErrors) > 0 {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	key, err := h.vaultService.PublicKey(r.Context(), userID, email)
+	if err != nil {
+		writeVaultError(w, err, "Failed to get public key")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, key)
+}
+
+// GetKey handles GET /api/vault/keys/{folderID}
+func (h *VaultHandler) GetKey(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	folderID, ok := urlParamUint(r, "folderID")
+	if !ok {
+		response.BadRequest(w, "Invalid folder ID")
+		return
+	}
+
+	key, err := h.vaultService.Key(r.Context(), userID, folderID)
+	if err != nil {
+		writeVaultError(w, err, "Failed to get vault key")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, key)
+}
+
+// writeVaultError maps vault service errors onto HTTP responses
+func writeVaultError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrPublicKeyNotFound):
+		response.NotFound(w, "Public key not found")
+	case errors.Is(err, service.ErrVaultKeyNotFound):
+		response.NotFound(w, "Vault key not found")
+	case errors.Is(err, service.ErrUserNotFound):
+		response.NotFound(w, "User not found")
+	default:
+		writeFileError(w, err, message)
+	}
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// WebhookHandler handles webhook management requests
+type WebhookHandler struct {
+	webhookService service.WebhookService
+}
+
+// NewWebhookHandler creates a new webhook handler
+func NewWebhookHandler(webhookService service.WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService: webhookService,
+	}
+}
+
+// Create handles POST /api/webhooks
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user, err := middleware.GetUserFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	var req model.CreateWebhookRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	webhook, err := h.webhookService.Create(r.Context(), user, &req)
+	if err != nil {
+		writeWebhookError(w, err, "Failed to create webhook")
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, webhook)
+}
+
+// List handles GET /api/webhooks
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	webhooks, err := h.webhookService.List(r.Context(), userID)
+	if err != nil {
+		response.InternalError(w)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, webhooks)
+}
+
+// Get handles GET /api/webhooks/{id}
+func (h *WebhookHandler) Get(w http.ResponseWriter, r *http.Request) {
+	user, webhookID, ok := webhookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.webhookService.Get(r.Context(), user, webhookID)
+	if err != nil {
+		writeWebhookError(w, err, "Failed to get webhook")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, webhook)
+}
+
+// Update handles PATCH /api/webhooks/{id}
+func (h *WebhookHandler) Update(w http.ResponseWriter, r *http.Request) {
+	user, webhookID, ok := webhookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	var req model.UpdateWebhookRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	webhook, err := h.webhookService.Update(r.Context(), user, webhookID, &req)
+	if err != nil {
+		writeWebhookError(w, err, "Failed to update webhook")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, webhook)
+}
+
+// Delete handles DELETE /api/webhooks/{id}
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	user, webhookID, ok := webhookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookService.Delete(r.Context(), user, webhookID); err != nil {
+		writeWebhookError(w, err, "Failed to delete webhook")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeliveries handles GET /api/webhooks/{id}/deliveries
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) {
+	user, webhookID, ok := webhookRequest(w, r)
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	fieldErrors := make(map[string]string)
+	filter := &model.WebhookDeliveryFilter{
+		Status:  model.DeliveryStatus(q.Get("status")),
+		Page:    queryInt(q, "page", fieldErrors),
+		PerPage: queryInt(q, "per_page", fieldErrors),
+	}
+	if len(fieldErrors) > 0 {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+	if fieldErrors := util.ValidateStructWithFields(filter); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	deliveries, total, err := h.webhookService.ListDeliveries(r.Context(), user, webhookID, filter)
+	if err != nil {
+		writeWebhookError(w, err, "Failed to list deliveries")
+		return
+	}
+
+	response.WithPagination(w, http.StatusOK, deliveries, filter.Page, filter.PerPage, int(total))
+}
+
+// Redeliver handles POST /api/webhooks/{id}/deliveries/{deliveryID}/redeliver
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) {
+	user, webhookID, ok := webhookRequest(w, r)
+	if !ok {
+		return
+	}
+	deliveryID, ok := urlParamUint(r, "deliveryID")
+	if !ok {
+		response.BadRequest(w, "Invalid delivery ID")
+		return
+	}
+
+	delivery, err := h.webhookService.Redeliver(r.Context(), user, webhookID, deliveryID)
+	if err != nil {
+		writeWebhookError(w, err, "Failed to redeliver")
+		return
+	}
+
+	response.JSON(w, http.StatusAccepted, delivery)
+}
+
+// webhookRequest reads the authenticated user and the webhook ID, writing
+// an error response when either is missing
+func webhookRequest(w http.ResponseWriter, r *http.Request) (*model.User, uint, bool) {
+	user, err := middleware.GetUserFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return nil, 0, false
+	}
+	webhookID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid webhook ID")
+		return nil, 0, false
+	}
+	return user, webhookID, true
+}
+
+// writeWebhookError maps webhook service errors onto HTTP responses
+func writeWebhookError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrWebhookNotFound):
+		response.NotFound(w, "Webhook not found")
+	case errors.Is(err, service.ErrDeliveryNotFound):
+		response.NotFound(w, "Delivery not found")
+	case errors.Is(err, service.ErrWebhookURLNotAllowed):
+		response.ValidationErrorWithFields(w, map[string]string{"url": "url must be an http or https URL on a public network"})
+	case errors.Is(err, service.ErrPermissionDenied):
+		response.Forbidden(w, "Only administrators can create global webhooks")
+	default:
+		response.Error(w, http.StatusInternalServerError, response.ErrInternalServer, message)
+	}
+}
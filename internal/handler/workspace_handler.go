@@ -0,0 +1,287 @@
+package handler
+
+import (
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/response"
+	"drive/internal/service"
+	"drive/internal/util"
+	"errors"
+	"net/http"
+)
+
+// WorkspaceHandler handles the workspace and workspace member endpoints
+type WorkspaceHandler struct {
+	workspaceService service.WorkspaceService
+}
+
+// NewWorkspaceHandler creates a new workspace handler
+func NewWorkspaceHandler(workspaceService service.WorkspaceService) *WorkspaceHandler {
+	return &WorkspaceHandler{
+		workspaceService: workspaceService,
+	}
+}
+
+// List handles GET /api/workspaces, returning the user's memberships
+func (h *WorkspaceHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	memberships, err := h.workspaceService.ListMine(r.Context(), userID)
+	if err != nil {
+		response.InternalError(w)
+		return
+	}
+
+	response.JSON(w, http.StatusOK, memberships)
+}
+
+// Get handles GET /api/workspaces/{id}
+func (h *WorkspaceHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	workspaceID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid workspace ID")
+		return
+	}
+
+	workspace, err := h.workspaceService.Get(r.Context(), userID, workspaceID)
+	if err != nil {
+		writeWorkspaceError(w, err, "Failed to get workspace")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, workspace)
+}
+
+// Update handles PATCH /api/workspaces/{id}
+func (h *WorkspaceHandler) Update(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	workspaceID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid workspace ID")
+		return
+	}
+
+	var req model.UpdateWorkspaceRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	workspace, err := h.workspaceService.Update(r.Context(), userID, workspaceID, &req)
+	if err != nil {
+		writeWorkspaceError(w, err, "Failed to update workspace")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, workspace)
+}
+
+// ListMembers handles GET /api/workspaces/{id}/members
+func (h *WorkspaceHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	workspaceID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid workspace ID")
+		return
+	}
+
+	members, err := h.workspaceService.ListMembers(r.Context(), userID, workspaceID)
+	if err != nil {
+		writeWorkspaceError(w, err, "Failed to list workspace members")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, members)
+}
+
+// SetMember handles PUT /api/workspaces/{id}/members/{userID}
+func (h *WorkspaceHandler) SetMember(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	workspaceID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid workspace ID")
+		return
+	}
+	memberID, ok := urlParamUint(r, "userID")
+	if !ok {
+		response.BadRequest(w, "Invalid user ID")
+		return
+	}
+
+	var req model.SetWorkspaceMemberRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	member, err := h.workspaceService.SetMember(r.Context(), userID, workspaceID, memberID, &req)
+	if err != nil {
+		writeWorkspaceError(w, err, "Failed to set workspace member")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, member)
+}
+
+// RemoveMember handles DELETE /api/workspaces/{id}/members/{userID}
+func (h *WorkspaceHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	userID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	workspaceID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid workspace ID")
+		return
+	}
+	memberID, ok := urlParamUint(r, "userID")
+	if !ok {
+		response.BadRequest(w, "Invalid user ID")
+		return
+	}
+
+	if err := h.workspaceService.RemoveMember(r.Context(), userID, workspaceID, memberID); err != nil {
+		writeWorkspaceError(w, err, "Failed to remove workspace member")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListAll handles GET /api/admin/workspaces?page=&per_page=
+func (h *WorkspaceHandler) ListAll(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	fieldErrors := make(map[string]string)
+	filter := &model.WorkspaceFilter{
+		Page:    queryInt(q, "page", fieldErrors),
+		PerPage: queryInt(q, "per_page", fieldErrors),
+	}
+	if len(fieldErrors) > 0 {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+	if fieldErrors := util.ValidateStructWithFields(filter); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	workspaces, total, err := h.workspaceService.List(r.Context(), filter)
+	if err != nil {
+		response.InternalError(w)
+		return
+	}
+
+	response.WithPagination(w, http.StatusOK, workspaces, filter.Page, filter.PerPage, int(total))
+}
+
+// Create handles POST /api/admin/workspaces
+func (h *WorkspaceHandler) Create(w http.ResponseWriter, r *http.Request) {
+	adminID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+
+	var req model.CreateWorkspaceRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	workspace, err := h.workspaceService.Create(r.Context(), adminID, &req)
+	if err != nil {
+		writeWorkspaceError(w, err, "Failed to create workspace")
+		return
+	}
+
+	response.JSON(w, http.StatusCreated, workspace)
+}
+
+// Configure handles PATCH /api/admin/workspaces/{id}
+func (h *WorkspaceHandler) Configure(w http.ResponseWriter, r *http.Request) {
+	adminID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	workspaceID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid workspace ID")
+		return
+	}
+
+	var req model.UpdateWorkspaceRequest
+	if fieldErrors := util.ValidateRequestWithFields(r, &req); fieldErrors != nil {
+		response.ValidationErrorWithFields(w, fieldErrors)
+		return
+	}
+
+	workspace, err := h.workspaceService.Configure(r.Context(), adminID, workspaceID, &req)
+	if err != nil {
+		writeWorkspaceError(w, err, "Failed to update workspace")
+		return
+	}
+
+	response.JSON(w, http.StatusOK, workspace)
+}
+
+// Delete handles DELETE /api/admin/workspaces/{id}
+func (h *WorkspaceHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	adminID, err := middleware.GetUserIDFromContext(r)
+	if err != nil {
+		response.Unauthorized(w, err.Error())
+		return
+	}
+	workspaceID, ok := urlParamUint(r, "id")
+	if !ok {
+		response.BadRequest(w, "Invalid workspace ID")
+		return
+	}
+
+	if err := h.workspaceService.Delete(r.Context(), adminID, workspaceID); err != nil {
+		writeWorkspaceError(w, err, "Failed to delete workspace")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeWorkspaceError maps workspace service errors to responses
+func writeWorkspaceError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, service.ErrWorkspaceNotFound):
+		response.NotFound(w, "Workspace not found")
+	case errors.Is(err, service.ErrWorkspaceMemberNotFound):
+		response.NotFound(w, "Workspace member not found")
+	case errors.Is(err, service.ErrUserNotFound):
+		response.NotFound(w, "User not found")
+	case errors.Is(err, service.ErrInvalidWorkspaceName):
+		response.BadRequest(w, "Invalid workspace name")
+	case errors.Is(err, service.ErrLastManager):
+		response.Error(w, http.StatusConflict, response.ErrBadRequest, err.Error())
+	default:
+		writeFileError(w, err, message)
+	}
+}
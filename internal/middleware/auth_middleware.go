@@ -54,6 +54,38 @@ func Auth(authService service.AuthService) func(http.Handler) http.Handler {
 	}
 }
 
+// RequireAdmin only lets through users flagged as administrators. It must be
+// used after Auth.
+func RequireAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, err := GetUserFromContext(r)
+		if err != nil {
+			http.Error(w, "Authentication required", http.StatusUnauthorized)
+			return
+		}
+		if !user.IsAdmin {
+			http.Error(w, "Administrator access required", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GetUserFromContext retrieves the authenticated user from the request context
+func GetUserFromContext(r *http.Request) (*model.User, error) {
+	user := r.Context().Value(userKey)
+	if user == nil {
+		return nil, errors.New("user not found in context")
+	}
+
+	u, ok := user.(*model.User)
+	if !ok {
+		return nil, errors.New("invalid user type in context")
+	}
+
+	return u, nil
+}
+
 // GetUserIDFromContext retrieves the user ID from the request context
 func GetUserIDFromContext(r *http.Request) (uint, error) {
 	user := r.Context().Value(userKey)
@@ -69,3 +101,41 @@ func GetUserIDFromContext(r *http.Request) (uint, error) {
 
 	return u.ID, nil
 }
+
+// QueryToken lets clients that cannot set headers, such as browser
+// EventSource and WebSocket connections, pass the access token as the
+// access_token query parameter, which Logger redacts. It must run before
+// Auth.
+func QueryToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token := r.URL.Query().Get("access_token"); token != "" && r.Header.Get("Authorization") == "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// DAVAuth authenticates WebDAV clients. They send HTTP Basic credentials made
+// of the user's email address and an app password, or a bearer token like
+// the REST API. Failures ask for Basic credentials so file managers prompt.
+func DAVAuth(authService service.AuthService, appPasswords service.AppPasswordService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var user *model.User
+			err := errors.New("credentials required")
+			if email, password, ok := r.BasicAuth(); ok {
+				user, err = appPasswords.Authenticate(r.Context(), email, password)
+			} else if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok && token != "" {
+				user, err = authService.GetUserByToken(r.Context(), token)
+			}
+			if err != nil || user == nil {
+				w.Header().Set("WWW-Authenticate", `Basic realm="drive", charset="UTF-8"`)
+				http.Error(w, "Authentication required", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), userKey, user)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// redactedParams are query parameters whose values never reach the access log
+var redactedParams = []string{"access_token"}
+
+// Logger logs each request like chimiddleware.Logger, but with credentials
+// passed in the query string, such as QueryToken's access_token, redacted.
+var Logger = chimiddleware.RequestLogger(&redactingFormatter{
+	LogFormatter: &chimiddleware.DefaultLogFormatter{Logger: log.New(os.Stdout, "", log.LstdFlags), NoColor: false},
+})
+
+type redactingFormatter struct {
+	chimiddleware.LogFormatter
+}
+
+// NewLogEntry hands the wrapped formatter a copy of the request whose
+// RequestURI has the secret parameters replaced; the request served is
+// unchanged.
+func (f *redactingFormatter) NewLogEntry(r *http.Request) chimiddleware.LogEntry {
+	if uri, ok := redactQuery(r.URL); ok {
+		r = r.Clone(r.Context())
+		r.RequestURI = uri
+	}
+	return f.LogFormatter.NewLogEntry(r)
+}
+
+// redactQuery returns the request URI with secret query values replaced, and
+// whether anything was replaced
+func redactQuery(u *url.URL) (string, bool) {
+	if u.RawQuery == "" {
+		return "", false
+	}
+	query := u.Query()
+	redacted := false
+	for _, name := range redactedParams {
+		if query.Has(name) {
+			query.Set(name, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return "", false
+	}
+	redactedURL := *u
+	redactedURL.RawQuery = query.Encode()
+	return redactedURL.RequestURI(), true
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+
+	"drive/internal/util"
+
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// RequestMeta stores the client IP, request ID and user agent in the request
+// context so services can attach them to audit records. It must run after
+// chimiddleware.RequestID and chimiddleware.RealIP.
+func RequestMeta(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+
+		ctx := util.WithRequestMeta(r.Context(), util.RequestMeta{
+			IP:        ip,
+			RequestID: chimiddleware.GetReqID(r.Context()),
+			UserAgent: r.UserAgent(),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// AppPassword is a revocable password a user generates for a single client,
+// such as a WebDAV mount, that cannot use the interactive login. Only a
+// hash of the password is stored.
+type AppPassword struct {
+	ID           uint       `gorm:"primaryKey" json:"id"`
+	UserID       uint       `gorm:"not null;index" json:"user_id"`
+	Name         string     `gorm:"not null" json:"name"`
+	PasswordHash string     `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	LastUsedAt   *time.Time `json:"last_used_at"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// CreateAppPasswordRequest names a new app password
+type CreateAppPasswordRequest struct {
+	Name string `json:"name" validate:"required,max=100"`
+}
+
+// CreateAppPasswordResponse includes the generated password, which is only
+// returned when the app password is created
+type CreateAppPasswordResponse struct {
+	*AppPassword
+	Password string `json:"password"`
+}
@@ -0,0 +1,135 @@
+package model
+
+import "time"
+
+// AuditAction identifies a security-relevant action
+type AuditAction string
+
+const (
+	AuditLogin                 AuditAction = "auth.login"
+	AuditLoginFailed           AuditAction = "auth.login_failed"
+	AuditOAuthLogin            AuditAction = "auth.oauth_login"
+	AuditOAuthLoginFailed      AuditAction = "auth.oauth_login_failed"
+	AuditTokenRefresh          AuditAction = "auth.token_refresh"
+	AuditTokenRefreshFailed    AuditAction = "auth.token_refresh_failed"
+	AuditAppPasswordCreate     AuditAction = "auth.app_password_create"
+	AuditAppPasswordDelete     AuditAction = "auth.app_password_delete"
+	AuditAppPasswordFailed     AuditAction = "auth.app_password_failed"
+	AuditFileUpload            AuditAction = "file.upload"
+	AuditFileDownload          AuditAction = "file.download"
+	AuditFileCopy              AuditAction = "file.copy"
+	AuditFileDelete            AuditAction = "file.delete"
+	AuditFileRestore           AuditAction = "file.restore"
+	AuditFilePurge             AuditAction = "file.purge"
+	AuditFileQuarantine        AuditAction = "file.quarantine"
+	AuditFileForceUnlock       AuditAction = "file.force_unlock"
+	AuditFolderDelete          AuditAction = "folder.delete"
+	AuditFolderRestore         AuditAction = "folder.restore"
+	AuditShareCreate           AuditAction = "share.create"
+	AuditSharePermission       AuditAction = "share.permission_change"
+	AuditShareDelete           AuditAction = "share.delete"
+	AuditRetentionPolicySet    AuditAction = "retention.policy_set"
+	AuditRetentionPolicyDelete AuditAction = "retention.policy_delete"
+	AuditRetentionBlock        AuditAction = "retention.block"
+	AuditRetentionExpire       AuditAction = "retention.expire"
+	AuditLegalHoldPlace        AuditAction = "legal_hold.place"
+	AuditLegalHoldRelease      AuditAction = "legal_hold.release"
+	AuditWorkspaceCreate       AuditAction = "workspace.create"
+	AuditWorkspaceUpdate       AuditAction = "workspace.update"
+	AuditWorkspaceDelete       AuditAction = "workspace.delete"
+	AuditWorkspaceMemberSet    AuditAction = "workspace.member_set"
+	AuditWorkspaceMemberRemove AuditAction = "workspace.member_remove"
+	AuditWorkspaceTransfer     AuditAction = "workspace.transfer"
+	AuditGroupCreate           AuditAction = "group.create"
+	AuditGroupUpdate           AuditAction = "group.update"
+	AuditGroupDelete           AuditAction = "group.delete"
+	AuditGroupMemberAdd        AuditAction = "group.member_add"
+	AuditGroupMemberRemove     AuditAction = "group.member_remove"
+)
+
+// AuditOutcome records whether the audited action succeeded
+type AuditOutcome string
+
+const (
+	AuditSuccess AuditOutcome = "success"
+	AuditFailure AuditOutcome = "failure"
+)
+
+// AuditTarget identifies the kind of object an action was performed on
+type AuditTarget string
+
+const (
+	AuditTargetUser   AuditTarget = "user"
+	AuditTargetFile   AuditTarget = "file"
+	AuditTargetFolder AuditTarget = "folder"
+	AuditTargetShare  AuditTarget = "share"
+	// AuditTargetLegalHold entries target a legal hold by its ID
+	AuditTargetLegalHold AuditTarget = "legal_hold"
+	AuditTargetWorkspace AuditTarget = "workspace"
+	AuditTargetGroup     AuditTarget = "group"
+)
+
+// AuditLog is an append-only record of a security-relevant action. Rows are
+// never updated or deleted; the database rejects both. Each entry carries a
+// hash chained to the previous entry so edits can be detected.
+type AuditLog struct {
+	ID         uint         `gorm:"primaryKey" json:"id"`
+	CreatedAt  time.Time    `gorm:"not null;index" json:"created_at"`
+	Action     AuditAction  `gorm:"type:varchar(50);not null;index" json:"action"`
+	Outcome    AuditOutcome `gorm:"type:varchar(20);not null" json:"outcome"`
+	ActorID    *uint        `gorm:"index" json:"actor_id"`
+	ActorEmail string       `gorm:"type:varchar(255)" json:"actor_email,omitempty"`
+	TargetType AuditTarget  `gorm:"type:varchar(20);index:idx_audit_logs_target" json:"target_type,omitempty"`
+	TargetID   *uint        `gorm:"index:idx_audit_logs_target" json:"target_id,omitempty"`
+	IP         string       `gorm:"type:varchar(45)" json:"ip"`
+	RequestID  string       `gorm:"type:varchar(100)" json:"request_id"`
+	UserAgent  string       `gorm:"type:text" json:"user_agent"`
+	Metadata   JSONMap      `gorm:"type:jsonb" json:"metadata,omitempty"`
+	PrevHash   string       `gorm:"type:varchar(64);not null;default:''" json:"prev_hash"`
+	Hash       string       `gorm:"type:varchar(64);not null;default:'';index" json:"hash"`
+	HMAC       string       `gorm:"column:hmac;type:varchar(64);not null;default:''" json:"hmac,omitempty"`
+}
+
+// AuditChainHead describes the last entry of the audit hash chain
+type AuditChainHead struct {
+	LastEntryID uint
+	LastHash    string
+	EntryCount  int64
+}
+
+// AuditCheckpoint is a signed statement of the audit chain head at a point
+// in time. A checkpoint pins the chain so truncating or rewriting entries
+// before it can be detected even by someone holding the database.
+type AuditCheckpoint struct {
+	ID          uint      `gorm:"primaryKey" json:"id"`
+	CreatedAt   time.Time `gorm:"not null" json:"created_at"`
+	LastEntryID uint      `gorm:"not null;index" json:"last_entry_id"`
+	LastHash    string    `gorm:"type:varchar(64);not null" json:"last_hash"`
+	EntryCount  int64     `gorm:"not null" json:"entry_count"`
+	KeyID       string    `gorm:"type:varchar(16);not null" json:"key_id"`
+	Signature   string    `gorm:"type:varchar(100);not null" json:"signature"`
+}
+
+// AuditCheckpointExport is the exportable set of checkpoints along with the
+// public key needed to verify them
+type AuditCheckpointExport struct {
+	Algorithm   string            `json:"algorithm"`
+	PublicKey   string            `json:"public_key"`
+	KeyID       string            `json:"key_id"`
+	Checkpoints []AuditCheckpoint `json:"checkpoints"`
+}
+
+// AuditLogFilter holds the filters accepted by the audit query endpoints
+type AuditLogFilter struct {
+	Action     AuditAction  `json:"action" validate:"max=50"`
+	Outcome    AuditOutcome `json:"outcome" validate:"omitempty,oneof=success failure"`
+	ActorID    uint         `json:"actor_id"`
+	TargetType AuditTarget  `json:"target_type" validate:"omitempty,oneof=user file folder share legal_hold workspace group"`
+	TargetID   uint         `json:"target_id"`
+	IP         string       `json:"ip" validate:"omitempty,ip_address"`
+	RequestID  string       `json:"request_id" validate:"max=100"`
+	From       string       `json:"from" validate:"omitempty,date"`
+	To         string       `json:"to" validate:"omitempty,date"`
+	Page       int          `json:"page" validate:"gte=0"`
+	PerPage    int          `json:"per_page" validate:"gte=0,lte=500"`
+}
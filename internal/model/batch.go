@@ -0,0 +1,163 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// BatchOp is an operation a batch applies to a file or folder
+type BatchOp string
+
+const (
+	BatchMove    BatchOp = "move"
+	BatchCopy    BatchOp = "copy"
+	BatchDelete  BatchOp = "delete"
+	BatchRestore BatchOp = "restore"
+	BatchStar    BatchOp = "star"
+	BatchUnstar  BatchOp = "unstar"
+	BatchTag     BatchOp = "tag"
+	BatchUntag   BatchOp = "untag"
+	BatchShare   BatchOp = "share"
+)
+
+// BatchStatus is the state of a batch
+type BatchStatus string
+
+const (
+	BatchQueued  BatchStatus = "queued"
+	BatchRunning BatchStatus = "running"
+	// BatchCompleted batches ran every operation, though some may have
+	// failed
+	BatchCompleted BatchStatus = "completed"
+	// BatchFailed batches were rolled back or interrupted
+	BatchFailed BatchStatus = "failed"
+)
+
+// BatchItemStatus is the outcome of one operation of a batch
+type BatchItemStatus string
+
+const (
+	BatchItemSucceeded BatchItemStatus = "succeeded"
+	BatchItemFailed    BatchItemStatus = "failed"
+	// BatchItemRolledBack operations succeeded but were undone because a
+	// later operation of an atomic batch failed
+	BatchItemRolledBack BatchItemStatus = "rolled_back"
+	// BatchItemSkipped operations were not attempted
+	BatchItemSkipped BatchItemStatus = "skipped"
+)
+
+// BatchOperation applies an operation to a file or a folder. DestinationID
+// is the folder items are moved or copied into, Tag names the tag to apply
+// or remove, and the share fields describe the share to create.
+type BatchOperation struct {
+	Op              BatchOp    `json:"op" validate:"required,oneof=move copy delete restore star unstar tag untag share"`
+	FileID          uint       `json:"file_id,omitempty" validate:"required_without=FolderID,excluded_with=FolderID"`
+	FolderID        uint       `json:"folder_id,omitempty" validate:"required_without=FileID"`
+	DestinationID   uint       `json:"destination_id,omitempty"`
+	Tag             string     `json:"tag,omitempty" validate:"max=255"`
+	SharedWithID    uint       `json:"shared_with_id,omitempty"`
+	SharedWithEmail string     `json:"shared_with_email,omitempty" validate:"omitempty,email"`
+	GroupID         uint       `json:"group_id,omitempty"`
+	Permission      Permission `json:"permission,omitempty" validate:"omitempty,oneof=read write"`
+}
+
+// BatchRequest applies a list of operations in order. Atomic batches stop at
+// the first failure and undo the operations already applied. Async batches,
+// and batches too long to run during the request, run in the background.
+type BatchRequest struct {
+	Operations []BatchOperation `json:"operations" validate:"required,min=1,max=1000,dive"`
+	Atomic     bool             `json:"atomic"`
+	Async      bool             `json:"async"`
+}
+
+// BatchItemResult is the outcome of one operation, in request order.
+// CreatedID is the ID of the copy or share the operation created.
+type BatchItemResult struct {
+	Index     int             `json:"index"`
+	Op        BatchOp         `json:"op"`
+	FileID    uint            `json:"file_id,omitempty"`
+	FolderID  uint            `json:"folder_id,omitempty"`
+	Status    BatchItemStatus `json:"status"`
+	Error     string          `json:"error,omitempty"`
+	CreatedID uint            `json:"created_id,omitempty"`
+}
+
+// BatchJob is a batch and its progress. Batches run during the request are
+// returned in the same form without being stored.
+type BatchJob struct {
+	ID         uint               `gorm:"primaryKey" json:"id,omitempty"`
+	UserID     uint               `gorm:"not null;index" json:"-"`
+	Status     BatchStatus        `gorm:"type:varchar(16);not null;index" json:"status"`
+	Atomic     bool               `gorm:"not null" json:"atomic"`
+	Total      int                `gorm:"not null" json:"total"`
+	Processed  int                `gorm:"not null" json:"processed"`
+	Failed     int                `gorm:"not null" json:"failed"`
+	Operations BatchOperationList `gorm:"type:jsonb;not null" json:"-"`
+	Results    BatchResultList    `gorm:"type:jsonb;not null" json:"results"`
+	Error      string             `gorm:"type:text" json:"error,omitempty"`
+	CreatedAt  time.Time          `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time          `gorm:"autoUpdateTime" json:"updated_at"`
+	StartedAt  *time.Time         `json:"started_at,omitempty"`
+	FinishedAt *time.Time         `json:"finished_at,omitempty"`
+
+	User *User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Finished reports whether the batch has stopped running
+func (j *BatchJob) Finished() bool {
+	return j.Status == BatchCompleted || j.Status == BatchFailed
+}
+
+// BatchOperationList is a list of batch operations stored as a jsonb array
+type BatchOperationList []BatchOperation
+
+// Value implements driver.Valuer
+func (l BatchOperationList) Value() (driver.Value, error) {
+	return jsonArrayValue(l, l == nil)
+}
+
+// Scan implements sql.Scanner
+func (l *BatchOperationList) Scan(value interface{}) error {
+	return scanJSON(value, l)
+}
+
+// BatchResultList is a list of batch results stored as a jsonb array
+type BatchResultList []BatchItemResult
+
+// Value implements driver.Valuer
+func (l BatchResultList) Value() (driver.Value, error) {
+	return jsonArrayValue(l, l == nil)
+}
+
+// Scan implements sql.Scanner
+func (l *BatchResultList) Scan(value interface{}) error {
+	return scanJSON(value, l)
+}
+
+// jsonArrayValue encodes v as a JSON array, storing nil lists as empty ones
+func jsonArrayValue(v interface{}, isNil bool) (driver.Value, error) {
+	if isNil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// scanJSON decodes a JSON column into dest
+func scanJSON(value interface{}, dest interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return json.Unmarshal(v, dest)
+	case string:
+		return json.Unmarshal([]byte(v), dest)
+	default:
+		return fmt.Errorf("cannot scan %T into %T", value, dest)
+	}
+}
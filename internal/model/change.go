@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// ChangeType describes what happened to an item in a change journal entry
+type ChangeType string
+
+const (
+	ChangeCreated  ChangeType = "created"
+	ChangeModified ChangeType = "modified"
+	ChangeMoved    ChangeType = "moved"
+	ChangeDeleted  ChangeType = "deleted"
+)
+
+// ChangeItemType identifies the kind of item a change refers to
+type ChangeItemType string
+
+const (
+	ChangeItemFile   ChangeItemType = "file"
+	ChangeItemFolder ChangeItemType = "folder"
+)
+
+// Change is an entry in a user's change journal. IDs increase
+// monotonically, so the last ID a client has seen is its sync cursor.
+// Entries are never updated; deletions are recorded as tombstones with
+// DeletedAt set.
+type Change struct {
+	ID         uint           `gorm:"primaryKey;index:idx_changes_user_cursor,priority:2" json:"id"`
+	UserID     uint           `gorm:"not null;index:idx_changes_user_cursor,priority:1" json:"-"`
+	Change     ChangeType     `gorm:"type:varchar(16);not null" json:"change"`
+	ItemType   ChangeItemType `gorm:"type:varchar(16);not null" json:"item_type"`
+	ItemID     uint           `gorm:"not null" json:"item_id"`
+	ParentID   *uint          `json:"parent_id"`
+	Name       string         `gorm:"not null" json:"name"`
+	Size       int64          `gorm:"not null;default:0" json:"size,omitempty"`
+	MimeType   string         `gorm:"type:varchar(255)" json:"mime_type,omitempty"`
+	ModifiedAt time.Time      `json:"modified_at"`
+	DeletedAt  *time.Time     `json:"deleted_at,omitempty"`
+	CreatedAt  time.Time      `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// ChangeFilter holds the parameters accepted when reading the change journal
+type ChangeFilter struct {
+	Cursor string `json:"cursor"`
+	Limit  int    `json:"limit" validate:"gte=0,lte=1000"`
+	// Wait is the number of seconds to hold the request open when there
+	// are no changes yet
+	Wait int `json:"wait" validate:"gte=0,lte=60"`
+}
+
+// ChangeSet is a page of the change journal. Cursor is passed back to fetch
+// the next page; HasMore reports whether it is already available.
+type ChangeSet struct {
+	Changes []Change `json:"changes"`
+	Cursor  string   `json:"cursor"`
+	HasMore bool     `json:"has_more"`
+}
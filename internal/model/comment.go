@@ -0,0 +1,79 @@
+package model
+
+import (
+	"regexp"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Comment is a remark on a file. Top-level comments start threads, which
+// other comments join as replies; threads are resolved and reopened as a
+// whole.
+type Comment struct {
+	ID       uint   `gorm:"primaryKey" json:"id"`
+	FileID   uint   `gorm:"not null;index" json:"file_id"`
+	UserID   uint   `gorm:"not null;index" json:"user_id"`
+	ParentID *uint  `gorm:"index" json:"parent_id,omitempty"`
+	Body     string `gorm:"type:text;not null" json:"body"`
+
+	// AnchorPage and AnchorMillis pin a thread to a page of a document or
+	// a moment of a recording
+	AnchorPage   *int   `json:"anchor_page,omitempty"`
+	AnchorMillis *int64 `json:"anchor_millis,omitempty"`
+
+	ResolvedAt   *time.Time     `json:"resolved_at,omitempty"`
+	ResolvedByID *uint          `json:"resolved_by_id,omitempty"`
+	EditedAt     *time.Time     `json:"edited_at,omitempty"`
+	CreatedAt    time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+
+	User     *User            `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Mentions []CommentMention `gorm:"foreignKey:CommentID" json:"mentions,omitempty"`
+	Replies  []Comment        `gorm:"foreignKey:ParentID" json:"replies,omitempty"`
+}
+
+// MentionedIDs returns the IDs of the users the comment mentions
+func (c *Comment) MentionedIDs() []uint {
+	ids := make([]uint, 0, len(c.Mentions))
+	for _, m := range c.Mentions {
+		ids = append(ids, m.UserID)
+	}
+	return ids
+}
+
+// CommentMention records a user mentioned in a comment
+type CommentMention struct {
+	CommentID uint `gorm:"primaryKey" json:"-"`
+	UserID    uint `gorm:"primaryKey;index" json:"user_id"`
+
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// CommentRevision keeps a comment's body as it was before an edit
+type CommentRevision struct {
+	ID        uint   `gorm:"primaryKey" json:"id"`
+	CommentID uint   `gorm:"not null;index" json:"comment_id"`
+	Body      string `gorm:"type:text;not null" json:"body"`
+	// CreatedAt is when the body was replaced
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+// mentionPattern matches @username, but not the domain part of an email
+// address
+var mentionPattern = regexp.MustCompile(`(?:^|[^A-Za-z0-9@])@([A-Za-z0-9]{3,20})\b`)
+
+// ParseMentions returns the usernames mentioned in a comment body, each
+// once, in order of appearance
+func ParseMentions(body string) []string {
+	seen := make(map[string]bool)
+	var usernames []string
+	for _, match := range mentionPattern.FindAllStringSubmatch(body, -1) {
+		if !seen[match[1]] {
+			seen[match[1]] = true
+			usernames = append(usernames, match[1])
+		}
+	}
+	return usernames
+}
@@ -0,0 +1,15 @@
+package model
+
+// CreateCommentRequest starts a thread on a file, or replies to one when
+// ParentID is set. Only threads can be anchored.
+type CreateCommentRequest struct {
+	Body         string `json:"body" validate:"required,max=10000"`
+	ParentID     uint   `json:"parent_id"`
+	AnchorPage   *int   `json:"anchor_page" validate:"omitempty,min=1,excluded_with=ParentID"`
+	AnchorMillis *int64 `json:"anchor_millis" validate:"omitempty,min=0,excluded_with=ParentID"`
+}
+
+// UpdateCommentRequest edits a comment's body
+type UpdateCommentRequest struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
@@ -0,0 +1,64 @@
+package model
+
+import "time"
+
+// EventType identifies a change in the drive that can be delivered to
+// subscribers
+type EventType string
+
+const (
+	EventFileCreated     EventType = "file.created"
+	EventFileUpdated     EventType = "file.updated"
+	EventFileMoved       EventType = "file.moved"
+	EventFileDeleted     EventType = "file.deleted"
+	EventFileRestored    EventType = "file.restored"
+	EventFileQuarantined EventType = "file.quarantined"
+	EventFolderCreated   EventType = "folder.created"
+	EventFolderUpdated   EventType = "folder.updated"
+	EventFolderMoved     EventType = "folder.moved"
+	EventFolderDeleted   EventType = "folder.deleted"
+	EventFolderRestored  EventType = "folder.restored"
+	EventCommentCreated  EventType = "comment.created"
+	EventCommentUpdated  EventType = "comment.updated"
+	EventCommentDeleted  EventType = "comment.deleted"
+	EventCommentMention  EventType = "comment.mention"
+	EventShareCreated    EventType = "share.created"
+	EventShareUpdated    EventType = "share.updated"
+	EventShareDeleted    EventType = "share.deleted"
+	EventUserRegistered  EventType = "user.registered"
+)
+
+// EventTypes lists every event type subscribers may register for
+var EventTypes = []EventType{
+	EventFileCreated,
+	EventFileUpdated,
+	EventFileMoved,
+	EventFileDeleted,
+	EventFileRestored,
+	EventFileQuarantined,
+	EventFolderCreated,
+	EventFolderUpdated,
+	EventFolderMoved,
+	EventFolderDeleted,
+	EventFolderRestored,
+	EventCommentCreated,
+	EventCommentUpdated,
+	EventCommentDeleted,
+	EventCommentMention,
+	EventShareCreated,
+	EventShareUpdated,
+	EventShareDeleted,
+	EventUserRegistered,
+}
+
+// Event describes a change in the drive
+type Event struct {
+	ID         string      `json:"id"`
+	Type       EventType   `json:"type"`
+	OccurredAt time.Time   `json:"occurred_at"`
+	ActorID    uint        `json:"actor_id,omitempty"`
+	Data       interface{} `json:"data"`
+	// Audience lists the users the event concerns. Events without an
+	// audience are only delivered to global subscribers.
+	Audience []uint `json:"-"`
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -16,14 +17,67 @@ const (
 	FileTypeOther FileType = "other"
 )
 
+// ScanStatus is the outcome of scanning a file for malware
+type ScanStatus string
+
+const (
+	// ScanStatusNotScanned marks files stored while scanning was disabled,
+	// and vault files, whose content the server cannot read
+	ScanStatusNotScanned ScanStatus = "not_scanned"
+	ScanStatusPending    ScanStatus = "pending"
+	ScanStatusClean      ScanStatus = "clean"
+	// ScanStatusInfected files are quarantined: they cannot be downloaded
+	// or shared
+	ScanStatusInfected ScanStatus = "infected"
+	// ScanStatusSkipped files were too large to scan
+	ScanStatusSkipped ScanStatus = "skipped"
+	ScanStatusFailed  ScanStatus = "failed"
+)
+
+// IntegrityStatus is the outcome of checking a file's stored content
+// against its content hash
+type IntegrityStatus string
+
+const (
+	IntegrityUnverified IntegrityStatus = "unverified"
+	IntegrityOK         IntegrityStatus = "ok"
+	// IntegrityCorrupt files' content no longer matches their hash or can no
+	// longer be decrypted
+	IntegrityCorrupt IntegrityStatus = "corrupt"
+	// IntegrityMissing files' blob is gone from storage
+	IntegrityMissing IntegrityStatus = "missing"
+)
+
 type File struct {
 	ID       uint     `gorm:"primaryKey" json:"id"`
 	FileName string   `gorm:"not null" json:"file_name"`
 	FileType FileType `gorm:"not null" json:"file_type"`
 	FileSize int64    `gorm:"not null" json:"file_size"`
-	FileURL  string   `gorm:"not null" json:"file_url"`
+	MimeType string   `gorm:"type:varchar(255)" json:"mime_type"`
+	FileURL  string   `gorm:"not null;index" json:"file_url"`
 	FolderID uint     `gorm:"not null" json:"folder_id"`
-	UserID   uint     `gorm:"not null" json:"user_id"`
+	// UserID is the file's owner or, for a file in a workspace, the member
+	// who added it
+	UserID uint `gorm:"not null" json:"user_id"`
+	// WorkspaceID is set on files in a workspace, which owns them and is
+	// charged for their storage
+	WorkspaceID *uint `gorm:"index" json:"workspace_id,omitempty"`
+	// EncryptionKeyID names the master key that wrapped DataKey. Both are
+	// empty for blobs stored before encryption was enabled.
+	EncryptionKeyID string `gorm:"type:varchar(64);index" json:"-"`
+	DataKey         []byte `json:"-"`
+
+	ScanStatus ScanStatus `gorm:"type:varchar(16);not null;default:'not_scanned';index" json:"scan_status"`
+	// ScanSignature names the malware found in an infected file
+	ScanSignature string     `gorm:"type:varchar(255)" json:"scan_signature,omitempty"`
+	ScannedAt     *time.Time `json:"scanned_at,omitempty"`
+
+	// ContentHash is the hex SHA-256 of the file's plaintext content. It is
+	// empty until computed for files stored before hashing was introduced.
+	ContentHash string `gorm:"type:varchar(64);not null;default:'';index" json:"content_hash,omitempty"`
+	// IntegrityStatus is the result of the last scrub of the file's blob
+	IntegrityStatus IntegrityStatus `gorm:"type:varchar(16);not null;default:'unverified';index" json:"integrity_status"`
+	VerifiedAt      *time.Time      `json:"verified_at,omitempty"`
 
 	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
@@ -32,4 +86,19 @@ type File struct {
 	Folder *Folder `gorm:"foreignKey:FolderID" json:"folder"`
 	User   *User   `gorm:"foreignKey:UserID" json:"user"`
 	Shares []Share `gorm:"many2many:file_shares;" json:"shares"`
+	Tags   []*Tag  `gorm:"many2many:file_tags;" json:"tags,omitempty"`
+	// Photo is the EXIF metadata of an image, when loaded
+	Photo *PhotoMetadata `gorm:"foreignKey:FileID" json:"photo,omitempty"`
+}
+
+// Quarantined reports whether the file was found to be infected
+func (f *File) Quarantined() bool {
+	return f.ScanStatus == ScanStatusInfected
+}
+
+// ETag identifies the current version of a file's content and metadata. The
+// update time is truncated to the microsecond precision the database keeps,
+// so the tag is stable whether the file was just saved or read back.
+func (f *File) ETag() string {
+	return fmt.Sprintf(`"%x-%x-%x"`, f.UpdatedAt.Truncate(time.Microsecond).UnixNano(), f.FileSize, f.ID)
 }
@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// IndexStatus represents the state of content extraction for a file
+type IndexStatus string
+
+const (
+	IndexStatusPending IndexStatus = "pending"
+	IndexStatusIndexed IndexStatus = "indexed"
+	IndexStatusSkipped IndexStatus = "skipped"
+	IndexStatusFailed  IndexStatus = "failed"
+)
+
+// FileContent holds the text extracted from a file for full-text search
+type FileContent struct {
+	FileID    uint        `gorm:"primaryKey;autoIncrement:false" json:"file_id"`
+	Status    IndexStatus `gorm:"type:varchar(20);not null;default:'pending';index" json:"status"`
+	Extractor string      `gorm:"type:varchar(50)" json:"extractor"`
+	Content   string      `gorm:"type:text" json:"-"`
+	// ContentTSV is maintained by the repository with to_tsvector
+	ContentTSV string    `gorm:"column:content_tsv;type:tsvector;->" json:"-"`
+	Truncated  bool      `gorm:"not null;default:false" json:"truncated"`
+	Error      string    `json:"error,omitempty"`
+	IndexedAt  time.Time `json:"indexed_at"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+
+	File *File `gorm:"foreignKey:FileID;constraint:OnDelete:CASCADE" json:"-"`
+}
@@ -0,0 +1,14 @@
+package model
+
+// UpdateFileRequest renames or moves a file; omitted fields are left
+// unchanged
+type UpdateFileRequest struct {
+	Name     *string `json:"name" validate:"omitempty,min=1,max=1024"`
+	FolderID *uint   `json:"folder_id" validate:"omitempty,gt=0"`
+}
+
+// PreviewRequest selects the page of rows returned for table previews
+type PreviewRequest struct {
+	Page    int `json:"page" validate:"gte=0"`
+	PerPage int `json:"per_page" validate:"gte=0,lte=500"`
+}
@@ -9,14 +9,20 @@ import (
 type Folder struct {
 	ID             uint           `gorm:"primaryKey" json:"id"`
 	FolderName     string         `gorm:"not null;default:'/'" json:"folder_name"`
-	ParentFolderID *uint          `gorm:"not null;default:0" json:"parent_folder_id"`
+	ParentFolderID *uint          `gorm:"index" json:"parent_folder_id"`
 	UserID         uint           `gorm:"not null" json:"user_id"`
 	CreatedAt      time.Time      `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt      time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
 	DeletedAt      gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 
+	// VaultID is set on every folder of an end-to-end encrypted vault, the
+	// vault's root included, and holds the root's ID
+	VaultID *uint `gorm:"index" json:"vault_id,omitempty"`
+	// WorkspaceID is set on every folder of a workspace, its root included
+	WorkspaceID *uint `gorm:"index" json:"workspace_id,omitempty"`
+
 	ParentFolder *Folder   `gorm:"foreignKey:ParentFolderID" json:"parent_folder"`
 	SubFolders   []*Folder `gorm:"foreignKey:ParentFolderID" json:"sub_folders"`
-	Files        []*File   `gorm:"foreignKey:ParentFolderID" json:"files"`
+	Files        []*File   `gorm:"foreignKey:FolderID" json:"files"`
 	User         *User     `gorm:"foreignKey:UserID" json:"user"`
 }
@@ -0,0 +1,27 @@
+package model
+
+// CreateFolderRequest creates a folder. A zero ParentID creates it in the
+// user's root folder. With Vault set it creates the root of an end-to-end
+// encrypted vault, and WrappedKey holds the vault's key wrapped for the
+// creator's public key.
+type CreateFolderRequest struct {
+	Name       string `json:"name" validate:"required,max=1024"`
+	ParentID   uint   `json:"parent_id"`
+	Vault      bool   `json:"vault"`
+	WrappedKey []byte `json:"wrapped_key" validate:"omitempty,max=256"`
+}
+
+// UpdateFolderRequest renames or moves a folder; omitted fields are left
+// unchanged
+type UpdateFolderRequest struct {
+	Name     *string `json:"name" validate:"omitempty,min=1,max=1024"`
+	ParentID *uint   `json:"parent_id" validate:"omitempty,gt=0"`
+}
+
+// FolderContents is a folder along with its immediate children
+type FolderContents struct {
+	Folder     *Folder    `json:"folder"`
+	Permission Permission `json:"permission"`
+	Folders    []Folder   `json:"folders"`
+	Files      []File     `json:"files"`
+}
@@ -0,0 +1,55 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Group is a named set of users that items can be shared with at once.
+// Members reach what is shared with the group for as long as they belong to
+// it. A group is managed by the user who created it or, when AdminManaged,
+// by administrators; every user can see and share with admin-managed groups.
+type Group struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	Name         string `gorm:"type:varchar(255);not null" json:"name"`
+	Description  string `gorm:"type:varchar(1000)" json:"description"`
+	OwnerID      uint   `gorm:"not null;index" json:"owner_id"`
+	AdminManaged bool   `gorm:"not null;default:false" json:"admin_managed"`
+
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// GroupMember adds a user to a group
+type GroupMember struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	GroupID   uint      `gorm:"not null;uniqueIndex:idx_group_members_group_user" json:"group_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_group_members_group_user;index" json:"user_id"`
+	AddedBy   uint      `gorm:"not null" json:"added_by"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// CreateGroupRequest creates a group. Only administrators may create
+// admin-managed groups.
+type CreateGroupRequest struct {
+	Name         string `json:"name" validate:"required,max=255"`
+	Description  string `json:"description" validate:"max=1000"`
+	AdminManaged bool   `json:"admin_managed"`
+}
+
+// UpdateGroupRequest changes a group; omitted fields are left unchanged
+type UpdateGroupRequest struct {
+	Name        *string `json:"name" validate:"omitempty,min=1,max=255"`
+	Description *string `json:"description" validate:"omitempty,max=1000"`
+}
+
+// AddGroupMemberRequest adds a user, identified either by ID or by email,
+// to a group
+type AddGroupMemberRequest struct {
+	UserID uint   `json:"user_id" validate:"required_without=Email"`
+	Email  string `json:"email" validate:"omitempty,email"`
+}
@@ -0,0 +1,100 @@
+package model
+
+// AgeBucket groups files by how long ago their content last changed
+type AgeBucket string
+
+const (
+	AgeUnder30Days   AgeBucket = "under_30_days"
+	Age30To90Days    AgeBucket = "30_to_90_days"
+	Age90DaysTo1Year AgeBucket = "90_days_to_1_year"
+	AgeOver1Year     AgeBucket = "over_1_year"
+)
+
+// AgeBuckets lists the age buckets from newest to oldest
+var AgeBuckets = []AgeBucket{AgeUnder30Days, Age30To90Days, Age90DaysTo1Year, AgeOver1Year}
+
+// StorageUsage totals the files a user is charged for. Files in the trash
+// still count against the quota until the trash is emptied.
+type StorageUsage struct {
+	TotalFiles int64 `json:"total_files"`
+	TotalSize  int64 `json:"total_size"`
+	TrashFiles int64 `json:"trash_files"`
+	TrashSize  int64 `json:"trash_size"`
+}
+
+// UsageByType totals a user's files of one type
+type UsageByType struct {
+	FileType FileType `json:"file_type"`
+	Files    int64    `json:"files"`
+	Size     int64    `json:"size"`
+}
+
+// UsageByFolder totals a user's files in a folder and its subfolders
+type UsageByFolder struct {
+	FolderID   uint   `json:"folder_id"`
+	FolderName string `json:"folder_name"`
+	Files      int64  `json:"files"`
+	Size       int64  `json:"size"`
+}
+
+// UsageByAge totals a user's files last modified within an age bucket
+type UsageByAge struct {
+	Age   AgeBucket `json:"age"`
+	Files int64     `json:"files"`
+	Size  int64     `json:"size"`
+}
+
+// DuplicateUsage totals the space taken by redundant copies of files
+type DuplicateUsage struct {
+	Groups     int64 `json:"groups"`
+	WastedSize int64 `json:"wasted_size"`
+}
+
+// StorageInsights breaks down what a user's storage is used by. Sizes are in
+// bytes, except StorageUsed and StorageLimit which are in megabytes like
+// the user's quota. The breakdowns cover files outside the trash.
+type StorageInsights struct {
+	StorageUsed  float64 `json:"storage_used"`
+	StorageLimit float64 `json:"storage_limit"`
+	StorageUsage
+	Duplicates   DuplicateUsage  `json:"duplicates"`
+	ByType       []UsageByType   `json:"by_type"`
+	ByFolder     []UsageByFolder `json:"by_folder"`
+	ByAge        []UsageByAge    `json:"by_age"`
+	LargestFiles []File          `json:"largest_files"`
+}
+
+// DuplicateGroup is a set of a user's files with identical content
+type DuplicateGroup struct {
+	ContentHash string `json:"content_hash"`
+	FileSize    int64  `json:"file_size"`
+	Count       int64  `json:"count"`
+	// WastedSize is the space the copies beyond the first take up
+	WastedSize int64  `json:"wasted_size"`
+	Files      []File `gorm:"-" json:"files"`
+}
+
+// DuplicateFilter selects a page of duplicate groups
+type DuplicateFilter struct {
+	Page    int `json:"page" validate:"gte=0"`
+	PerPage int `json:"per_page" validate:"gte=0,lte=100"`
+}
+
+// ResolveDuplicatesRequest keeps the given files and moves the other copies
+// of each to the trash
+type ResolveDuplicatesRequest struct {
+	KeepFileIDs []uint `json:"keep_file_ids" validate:"required,min=1,max=100,dive,gt=0"`
+}
+
+// ResolveDuplicatesResult reports the copies moved to the trash and those
+// that could not be, such as locked files
+type ResolveDuplicatesResult struct {
+	TrashedFileIDs []uint             `json:"trashed_file_ids"`
+	Failed         []DuplicateFailure `json:"failed"`
+}
+
+// DuplicateFailure is a copy that could not be moved to the trash
+type DuplicateFailure struct {
+	FileID uint   `json:"file_id"`
+	Error  string `json:"error"`
+}
@@ -0,0 +1,41 @@
+package model
+
+import "fmt"
+
+// GarbageReport summarises a pass of the orphaned blob collector
+type GarbageReport struct {
+	// Scanned counts the blobs listed, Orphaned those no file references
+	Scanned       int   `json:"scanned"`
+	Orphaned      int   `json:"orphaned"`
+	OrphanedBytes int64 `json:"orphaned_bytes"`
+	// Removed is lower than Orphaned on a dry run or when deletes fail
+	Removed int `json:"removed"`
+}
+
+// String summarises the pass
+func (r *GarbageReport) String() string {
+	return fmt.Sprintf("removed %d of %d orphaned blobs (%d bytes) among %d listed", r.Removed, r.Orphaned, r.OrphanedBytes, r.Scanned)
+}
+
+// ScrubReport summarises a pass of the integrity scrubber
+type ScrubReport struct {
+	Checked int   `json:"checked"`
+	Bytes   int64 `json:"bytes"`
+	Corrupt int   `json:"corrupt"`
+	Missing int   `json:"missing"`
+	// Failed counts files that could not be checked, such as when their
+	// master key is not configured. They are retried on the next pass.
+	Failed int `json:"failed"`
+}
+
+// String summarises the pass
+func (r *ScrubReport) String() string {
+	return fmt.Sprintf("checked %d files (%d bytes): %d corrupt, %d missing, %d could not be checked", r.Checked, r.Bytes, r.Corrupt, r.Missing, r.Failed)
+}
+
+// IntegrityFilter holds the filters accepted when listing damaged files
+type IntegrityFilter struct {
+	Status  IntegrityStatus `json:"status" validate:"omitempty,oneof=corrupt missing"`
+	Page    int             `json:"page" validate:"gte=0"`
+	PerPage int             `json:"per_page" validate:"gte=0,lte=100"`
+}
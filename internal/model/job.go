@@ -0,0 +1,80 @@
+package model
+
+import "time"
+
+// JobStatus tracks a background job through its attempts
+type JobStatus string
+
+const (
+	// JobQueued jobs wait for their RunAt time, including failed jobs
+	// waiting to be retried
+	JobQueued    JobStatus = "queued"
+	JobRunning   JobStatus = "running"
+	JobSucceeded JobStatus = "succeeded"
+	// JobDead jobs ran out of attempts, failed permanently or have no
+	// handler. They are kept until an administrator retries them.
+	JobDead JobStatus = "dead"
+)
+
+// Job is a unit of background work run by the handler registered for its
+// type. Jobs sharing a UniqueKey are not queued twice while one of them is
+// queued or running.
+type Job struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	Type        string     `gorm:"type:varchar(100);not null;index" json:"type"`
+	Payload     string     `gorm:"type:jsonb;not null" json:"payload"`
+	Status      JobStatus  `gorm:"type:varchar(16);not null" json:"status"`
+	UniqueKey   *string    `gorm:"type:varchar(255)" json:"unique_key,omitempty"`
+	Attempts    int        `gorm:"not null;default:0" json:"attempts"`
+	MaxAttempts int        `gorm:"not null" json:"max_attempts"`
+	RunAt       time.Time  `gorm:"not null" json:"run_at"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+	LastError   string     `gorm:"type:text" json:"last_error,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	CreatedAt   time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// JobFilter holds the filters accepted when listing jobs
+type JobFilter struct {
+	Status  JobStatus `json:"status" validate:"omitempty,oneof=queued running succeeded dead"`
+	Type    string    `json:"type" validate:"max=100"`
+	Page    int       `json:"page" validate:"gte=0"`
+	PerPage int       `json:"per_page" validate:"gte=0,lte=100"`
+}
+
+// JobStats counts jobs by status
+type JobStats struct {
+	Queued    int64 `json:"queued"`
+	Running   int64 `json:"running"`
+	Succeeded int64 `json:"succeeded"`
+	Dead      int64 `json:"dead"`
+}
+
+// Job types run by the server
+const (
+	// JobIndexFile extracts the text and photo metadata of a file
+	JobIndexFile = "index_file"
+	// JobScanFile scans a file for malware
+	JobScanFile = "scan_file"
+	// JobDeliverWebhook sends a webhook delivery
+	JobDeliverWebhook = "deliver_webhook"
+	// JobDeleteBlob removes a blob that is no longer referenced
+	JobDeleteBlob = "delete_blob"
+)
+
+// FileJob is the payload of jobs about a single file
+type FileJob struct {
+	FileID uint `json:"file_id"`
+}
+
+// DeliveryJob is the payload of JobDeliverWebhook
+type DeliveryJob struct {
+	DeliveryID uint `json:"delivery_id"`
+}
+
+// BlobJob is the payload of JobDeleteBlob
+type BlobJob struct {
+	Key string `json:"key"`
+}
@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// LockScope is how a lock shares a file with other lock holders
+type LockScope string
+
+const (
+	// LockExclusive gives a single user the right to change a file
+	LockExclusive LockScope = "exclusive"
+	// LockShared lets several users check out a file together, keeping
+	// everyone else from changing it
+	LockShared LockScope = "shared"
+)
+
+// FileLock checks out a file until it expires or is released. While a file
+// has active locks, only their holders can replace, move, rename or delete
+// it. WebDAV LOCK requests on files create the same locks.
+type FileLock struct {
+	ID     uint      `gorm:"primaryKey" json:"id"`
+	FileID uint      `gorm:"not null;index" json:"file_id"`
+	UserID uint      `gorm:"not null;index" json:"user_id"`
+	Scope  LockScope `gorm:"type:varchar(16);not null" json:"scope"`
+	// Token identifies the lock to WebDAV clients
+	Token     string    `gorm:"type:varchar(64);not null;uniqueIndex" json:"-"`
+	Note      string    `gorm:"type:varchar(255)" json:"note,omitempty"`
+	ExpiresAt time.Time `gorm:"not null;index" json:"expires_at"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+
+	User *User `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// Active reports whether the lock has not expired at now
+func (l *FileLock) Active(now time.Time) bool {
+	return now.Before(l.ExpiresAt)
+}
+
+// Conflicts reports whether l cannot be held alongside other. A user's own
+// locks never conflict, and shared locks only conflict with exclusive ones.
+func (l *FileLock) Conflicts(other *FileLock) bool {
+	return l.UserID != other.UserID && (l.Scope == LockExclusive || other.Scope == LockExclusive)
+}
+
+// LocksBlock reports whether a file's active locks keep userID from
+// changing it, which is the case unless the user holds one of them
+func LocksBlock(locks []FileLock, userID uint) bool {
+	for _, lock := range locks {
+		if lock.UserID == userID {
+			return false
+		}
+	}
+	return len(locks) > 0
+}
+
+// LockFileRequest checks out a file. The duration defaults to an hour.
+type LockFileRequest struct {
+	Scope LockScope `json:"scope" validate:"omitempty,oneof=exclusive shared"`
+	Note  string    `json:"note" validate:"max=255"`
+	// DurationSeconds is how long the lock lasts, up to a week
+	DurationSeconds int64 `json:"duration_seconds" validate:"omitempty,min=1,max=604800"`
+}
@@ -0,0 +1,71 @@
+package model
+
+import "time"
+
+// PhotoMetadata holds what was read from an image file's EXIF data. It is
+// filled in by the indexer once the file has been uploaded.
+type PhotoMetadata struct {
+	FileID uint `gorm:"primaryKey;autoIncrement:false" json:"-"`
+	// TakenAt is the camera's local time when the photo was taken,
+	// expressed as UTC. TimeOffset is its offset from UTC when recorded.
+	TakenAt     *time.Time `gorm:"index" json:"taken_at,omitempty"`
+	TimeOffset  string     `gorm:"type:varchar(6)" json:"time_offset,omitempty"`
+	CameraMake  string     `gorm:"type:varchar(255)" json:"camera_make,omitempty"`
+	CameraModel string     `gorm:"type:varchar(255)" json:"camera_model,omitempty"`
+	LensModel   string     `gorm:"type:varchar(255)" json:"lens_model,omitempty"`
+	Orientation int        `json:"orientation,omitempty"`
+	Width       int        `json:"width,omitempty"`
+	Height      int        `json:"height,omitempty"`
+	// Latitude and Longitude are left empty when the server or the photo's
+	// owner strips photo locations
+	Latitude  *float64  `json:"latitude,omitempty"`
+	Longitude *float64  `json:"longitude,omitempty"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"-"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"-"`
+
+	File *File `gorm:"foreignKey:FileID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TableName keeps the table name singular, as metadata has no plural
+func (PhotoMetadata) TableName() string {
+	return "photo_metadata"
+}
+
+// TimelineBucket counts the photos taken in a year, or in a month of it.
+// Photos without a capture time are placed by their upload time.
+type TimelineBucket struct {
+	Year  int   `json:"year"`
+	Month int   `json:"month,omitempty"`
+	Count int64 `json:"count"`
+	// CoverFileID is the most recent photo in the bucket
+	CoverFileID uint `json:"cover_file_id"`
+}
+
+// Album is a user's collection of photos. Albums reference photos rather
+// than copying them, so a photo can be in any number of albums.
+type Album struct {
+	ID          uint   `gorm:"primaryKey" json:"id"`
+	UserID      uint   `gorm:"not null;index" json:"user_id"`
+	Name        string `gorm:"type:varchar(255);not null" json:"name"`
+	Description string `gorm:"type:text" json:"description"`
+	// CoverFileID is the photo chosen to represent the album, which must be
+	// one of its photos
+	CoverFileID *uint     `json:"cover_file_id,omitempty"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+
+	// PhotoCount is computed when albums are listed
+	PhotoCount int64 `gorm:"->;-:migration" json:"photo_count"`
+
+	CoverFile *File `gorm:"foreignKey:CoverFileID;constraint:OnDelete:SET NULL" json:"-"`
+}
+
+// AlbumPhoto places a photo in an album
+type AlbumPhoto struct {
+	AlbumID uint      `gorm:"primaryKey" json:"album_id"`
+	FileID  uint      `gorm:"primaryKey;index" json:"file_id"`
+	AddedAt time.Time `gorm:"autoCreateTime" json:"added_at"`
+
+	Album *Album `gorm:"foreignKey:AlbumID;constraint:OnDelete:CASCADE" json:"-"`
+	File  *File  `gorm:"foreignKey:FileID;constraint:OnDelete:CASCADE" json:"-"`
+}
@@ -0,0 +1,55 @@
+package model
+
+// TimelineRequest selects how the photo timeline is grouped
+type TimelineRequest struct {
+	Granularity string `json:"granularity" validate:"omitempty,oneof=month year"`
+}
+
+// PhotoFilter selects a page of photos, optionally those taken in a given
+// year or month
+type PhotoFilter struct {
+	Year    int `json:"year" validate:"omitempty,min=1,max=9999,required_with=Month"`
+	Month   int `json:"month" validate:"omitempty,min=1,max=12"`
+	Page    int `json:"page" validate:"gte=0"`
+	PerPage int `json:"per_page" validate:"gte=0,lte=500"`
+}
+
+// PhotoSettings are a user's photo privacy settings
+type PhotoSettings struct {
+	// StripLocation is whether the user chose to strip the GPS coordinates
+	// of their photos
+	StripLocation bool `json:"strip_location"`
+	// StripLocationEnforced is set when the server strips every photo's
+	// coordinates, whatever the user chose
+	StripLocationEnforced bool `json:"strip_location_enforced"`
+}
+
+// UpdatePhotoSettingsRequest changes a user's photo privacy settings
+type UpdatePhotoSettingsRequest struct {
+	StripLocation *bool `json:"strip_location" validate:"required"`
+}
+
+// CreateAlbumRequest creates an empty album
+type CreateAlbumRequest struct {
+	Name        string `json:"name" validate:"required,max=255"`
+	Description string `json:"description" validate:"max=2000"`
+}
+
+// UpdateAlbumRequest renames an album or changes its description or cover;
+// omitted fields are left unchanged. A cover of 0 clears it.
+type UpdateAlbumRequest struct {
+	Name        *string `json:"name" validate:"omitempty,min=1,max=255"`
+	Description *string `json:"description" validate:"omitempty,max=2000"`
+	CoverFileID *uint   `json:"cover_file_id"`
+}
+
+// AddAlbumPhotosRequest adds photos to an album
+type AddAlbumPhotosRequest struct {
+	FileIDs []uint `json:"file_ids" validate:"required,min=1,max=500,dive,gt=0"`
+}
+
+// AlbumPhotoFilter selects a page of an album's photos
+type AlbumPhotoFilter struct {
+	Page    int `json:"page" validate:"gte=0"`
+	PerPage int `json:"per_page" validate:"gte=0,lte=500"`
+}
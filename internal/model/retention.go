@@ -0,0 +1,72 @@
+package model
+
+import "time"
+
+// RetentionPolicy governs how long the files in a folder, and in the
+// folders beneath it, are kept. Ages are counted from when each file was
+// created. Where policies nest, the longest minimum and the shortest
+// maximum apply.
+type RetentionPolicy struct {
+	ID       uint `gorm:"primaryKey" json:"id"`
+	FolderID uint `gorm:"not null;uniqueIndex" json:"folder_id"`
+	// MinDays keeps files from being deleted until they are this many days
+	// old; 0 sets no minimum
+	MinDays int `gorm:"not null;default:0" json:"min_days"`
+	// MaxDays moves files to the trash once they are this many days old; 0
+	// sets no maximum
+	MaxDays     int       `gorm:"not null;default:0" json:"max_days"`
+	Description string    `gorm:"type:varchar(500)" json:"description,omitempty"`
+	CreatedBy   uint      `gorm:"not null" json:"created_by"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+
+	Folder *Folder `gorm:"foreignKey:FolderID" json:"folder,omitempty"`
+}
+
+// LegalHold keeps everything a user owns, or a folder and everything
+// beneath it, from being deleted or overwritten until it is released.
+// Released holds are kept as a record.
+type LegalHold struct {
+	ID         uint       `gorm:"primaryKey" json:"id"`
+	UserID     *uint      `gorm:"index" json:"user_id,omitempty"`
+	FolderID   *uint      `gorm:"index" json:"folder_id,omitempty"`
+	Reason     string     `gorm:"type:text;not null" json:"reason"`
+	CreatedBy  uint       `gorm:"not null" json:"created_by"`
+	CreatedAt  time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	ReleasedAt *time.Time `gorm:"index" json:"released_at,omitempty"`
+	ReleasedBy *uint      `json:"released_by,omitempty"`
+}
+
+// Active reports whether the hold is still in place
+func (h *LegalHold) Active() bool {
+	return h.ReleasedAt == nil
+}
+
+// SetRetentionPolicyRequest creates or replaces a folder's retention policy
+type SetRetentionPolicyRequest struct {
+	MinDays     int    `json:"min_days" validate:"gte=0,lte=36500"`
+	MaxDays     int    `json:"max_days" validate:"gte=0,lte=36500"`
+	Description string `json:"description" validate:"max=500"`
+}
+
+// CreateLegalHoldRequest places a hold on either a user or a folder
+type CreateLegalHoldRequest struct {
+	UserID   *uint  `json:"user_id" validate:"required_without=FolderID,excluded_with=FolderID"`
+	FolderID *uint  `json:"folder_id"`
+	Reason   string `json:"reason" validate:"required,max=2000"`
+}
+
+// RetentionPolicyFilter holds the pagination accepted when listing policies
+type RetentionPolicyFilter struct {
+	Page    int `json:"page" validate:"gte=0"`
+	PerPage int `json:"per_page" validate:"gte=0,lte=100"`
+}
+
+// LegalHoldFilter holds the filters accepted when listing legal holds
+type LegalHoldFilter struct {
+	Status   string `json:"status" validate:"omitempty,oneof=active released"`
+	UserID   uint   `json:"user_id"`
+	FolderID uint   `json:"folder_id"`
+	Page     int    `json:"page" validate:"gte=0"`
+	PerPage  int    `json:"per_page" validate:"gte=0,lte=100"`
+}
@@ -0,0 +1,44 @@
+package model
+
+import "time"
+
+// SearchItemType distinguishes files from folders in search results
+type SearchItemType string
+
+const (
+	SearchItemFile   SearchItemType = "file"
+	SearchItemFolder SearchItemType = "folder"
+)
+
+// SearchRequest holds the filters accepted by the search endpoint
+type SearchRequest struct {
+	Query    string   `json:"q" validate:"max=255"`
+	FileType FileType `json:"type" validate:"omitempty,oneof=image video audio document other"`
+	MinSize  int64    `json:"min_size" validate:"gte=0"`
+	MaxSize  int64    `json:"max_size" validate:"gte=0"`
+	From     string   `json:"from" validate:"omitempty,date"`
+	To       string   `json:"to" validate:"omitempty,date"`
+	OwnerID  uint     `json:"owner_id"`
+	FolderID uint     `json:"folder_id"`
+	Tag      string   `json:"tag" validate:"max=100"`
+	Page     int      `json:"page" validate:"gte=0"`
+	PerPage  int      `json:"per_page" validate:"gte=0,lte=100"`
+}
+
+// SearchResult is a single file or folder matched by a search. Highlight and
+// Snippet are safe HTML: the name or content is escaped and matched terms
+// are wrapped in <mark> tags.
+type SearchResult struct {
+	Type      SearchItemType `json:"type"`
+	ID        uint           `json:"id"`
+	Name      string         `json:"name"`
+	Highlight string         `json:"highlight"`
+	Snippet   string         `json:"snippet,omitempty"`
+	FileType  FileType       `json:"file_type,omitempty"`
+	FileSize  int64          `json:"file_size,omitempty"`
+	FolderID  *uint          `json:"folder_id"`
+	OwnerID   uint           `json:"owner_id"`
+	Rank      float64        `json:"rank"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
@@ -14,12 +14,27 @@ const (
 	PermissionOwner Permission = "owner"
 )
 
+// permissionLevels orders permissions from weakest to strongest
+var permissionLevels = map[Permission]int{
+	PermissionRead:  1,
+	PermissionWrite: 2,
+	PermissionOwner: 3,
+}
+
+// Allows reports whether p grants at least the required permission
+func (p Permission) Allows(required Permission) bool {
+	return permissionLevels[p] > 0 && permissionLevels[p] >= permissionLevels[required]
+}
+
+// Share grants a user, or every member of a group, access to a file or
+// folder. Exactly one of SharedWithID and GroupID is set.
 type Share struct {
 	ID           uint       `gorm:"primaryKey" json:"id"`
 	FolderID     uint       `gorm:"not null" json:"folder_id"`
-	FileID       uint       `gorm:"" json:"file_id"`
+	FileID       *uint      `gorm:"index" json:"file_id"`
 	OwnerID      uint       `gorm:"not null" json:"owner_id"`
-	SharedWithID uint       `gorm:"not null" json:"shared_with_id"`
+	SharedWithID *uint      `json:"shared_with_id"`
+	GroupID      *uint      `gorm:"index" json:"group_id,omitempty"`
 	Permission   Permission `gorm:"not null" json:"permission"`
 
 	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
@@ -30,4 +45,5 @@ type Share struct {
 	File       *File   `gorm:"foreignKey:FileID" json:"file"`
 	Owner      *User   `gorm:"foreignKey:OwnerID" json:"owner"`
 	SharedWith *User   `gorm:"foreignKey:SharedWithID" json:"shared_with"`
+	Group      *Group  `gorm:"foreignKey:GroupID" json:"group,omitempty"`
 }
@@ -0,0 +1,20 @@
+package model
+
+// CreateShareRequest shares a file or folder with another user, identified
+// either by ID or by email, or with a group. Items in a vault also need
+// WrappedKey, the vault's key wrapped for the recipient's public key, and
+// cannot be shared with groups.
+type CreateShareRequest struct {
+	FileID          uint       `json:"file_id" validate:"required_without=FolderID"`
+	FolderID        uint       `json:"folder_id" validate:"required_without=FileID"`
+	SharedWithID    uint       `json:"shared_with_id" validate:"required_without_all=SharedWithEmail GroupID"`
+	SharedWithEmail string     `json:"shared_with_email" validate:"omitempty,email"`
+	GroupID         uint       `json:"group_id"`
+	Permission      Permission `json:"permission" validate:"required,oneof=read write"`
+	WrappedKey      []byte     `json:"wrapped_key" validate:"omitempty,max=256"`
+}
+
+// UpdateShareRequest changes the permission granted by a share
+type UpdateShareRequest struct {
+	Permission Permission `json:"permission" validate:"required,oneof=read write"`
+}
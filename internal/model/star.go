@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// ItemType identifies whether an item is a file or a folder
+type ItemType string
+
+const (
+	ItemTypeFile   ItemType = "file"
+	ItemTypeFolder ItemType = "folder"
+)
+
+// Star marks a file or folder as a favourite of a user. Stars are private
+// to the user who set them.
+type Star struct {
+	UserID    uint      `gorm:"primaryKey" json:"-"`
+	ItemType  ItemType  `gorm:"primaryKey;type:varchar(16)" json:"item_type"`
+	ItemID    uint      `gorm:"primaryKey" json:"item_id"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+
+	User *User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// StarredItems lists the files and folders a user starred that they can
+// still read
+type StarredItems struct {
+	Files   []File   `json:"files"`
+	Folders []Folder `json:"folders"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// Tag is a user-defined label that can be attached to files
+type Tag struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Name      string    `gorm:"not null;uniqueIndex:idx_tags_user_name" json:"name"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_tags_user_name" json:"user_id"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+
+	User  *User   `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Files []*File `gorm:"many2many:file_tags;" json:"files,omitempty"`
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// TaskRunStatus is the state of one run of a scheduled task
+type TaskRunStatus string
+
+const (
+	TaskRunRunning   TaskRunStatus = "running"
+	TaskRunSucceeded TaskRunStatus = "succeeded"
+	TaskRunFailed    TaskRunStatus = "failed"
+)
+
+// TaskRun records one run of a scheduled maintenance task. A task runs at
+// most once per scheduled time across every server instance.
+type TaskRun struct {
+	ID          uint          `gorm:"primaryKey" json:"id"`
+	Task        string        `gorm:"type:varchar(100);not null;uniqueIndex:idx_task_runs_slot" json:"task"`
+	ScheduledAt time.Time     `gorm:"not null;uniqueIndex:idx_task_runs_slot" json:"scheduled_at"`
+	Status      TaskRunStatus `gorm:"type:varchar(16);not null;index" json:"status"`
+	// Instance names the server that ran the task
+	Instance   string     `gorm:"type:varchar(255);not null" json:"instance"`
+	StartedAt  time.Time  `gorm:"not null;index" json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	DurationMS int64      `gorm:"not null;default:0" json:"duration_ms"`
+	// Result summarises what the task did
+	Result string `gorm:"type:text" json:"result,omitempty"`
+	Error  string `gorm:"type:text" json:"error,omitempty"`
+}
+
+// TaskRunFilter holds the filters accepted when listing task runs
+type TaskRunFilter struct {
+	Task    string        `json:"task" validate:"max=100"`
+	Status  TaskRunStatus `json:"status" validate:"omitempty,oneof=running succeeded failed"`
+	Page    int           `json:"page" validate:"gte=0"`
+	PerPage int           `json:"per_page" validate:"gte=0,lte=100"`
+}
+
+// ScheduledTask describes a registered task with its next and most recent
+// runs
+type ScheduledTask struct {
+	Name      string    `json:"name"`
+	Schedule  string    `json:"schedule"`
+	NextRunAt time.Time `json:"next_run_at"`
+	LastRun   *TaskRun  `json:"last_run,omitempty"`
+}
@@ -27,6 +27,7 @@ type User struct {
 	LastName     string         `json:"last_name"`
 	StorageUsed  float64        `gorm:"default:0" json:"storage_used"`
 	StorageLimit float64        `gorm:"default:15000"  json:"storage_limit"`
+	IsAdmin      bool           `gorm:"not null;default:false" json:"is_admin"`
 	CreatedAt    time.Time      `gorm:"autoCreateTime" json:"created_at"`
 	UpdatedAt    time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
 	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at"`
@@ -34,8 +35,12 @@ type User struct {
 	Provider   AuthProvider `gorm:"type:varchar(20);default:'local'" json:"provider"`
 	ProviderId string       `gorm:"index" json:"-"`
 
+	// StripPhotoLocation is the user's choice not to keep the GPS
+	// coordinates of their photos
+	StripPhotoLocation bool `gorm:"not null;default:false" json:"strip_photo_location"`
+
 	Folders       []*Folder `gorm:"foreignKey:UserID" json:"folders"`
 	Files         []*File   `gorm:"foreignKey:UserID" json:"files"`
-	ShareFile     []*Share  `gorm:"foreignKey:OwnerId" json:"shared_file"`
-	ReceivedFiles []*Share  `gorm:"foreignKey:SharedWithId" json:"received_files"`
+	ShareFile     []*Share  `gorm:"foreignKey:OwnerID" json:"shared_file"`
+	ReceivedFiles []*Share  `gorm:"foreignKey:SharedWithID" json:"received_files"`
 }
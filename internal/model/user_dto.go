@@ -15,6 +15,11 @@ type LoginRequest struct {
 	Password string `json:"password" validate:"required"`
 }
 
+// RefreshRequest contains the refresh token to exchange for new tokens
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
 // OAuthRequest contains the OAuth provider token
 type OAuthRequest struct {
 	Token    string `json:"token" validate:"required"`
@@ -39,6 +44,7 @@ type UserResponse struct {
 	StorageUsed  float64      `json:"storage_used"`
 	StorageLimit float64      `json:"storage_limit"`
 	Provider     AuthProvider `json:"provider"`
+	IsAdmin      bool         `json:"is_admin"`
 	CreatedAt    time.Time    `json:"created_at"`
 	UpdatedAt    time.Time    `json:"updated_at"`
 }
@@ -53,6 +59,7 @@ func (u *User) ToResponse() *UserResponse {
 		StorageUsed:  u.StorageUsed,
 		StorageLimit: u.StorageLimit,
 		Provider:     u.Provider,
+		IsAdmin:      u.IsAdmin,
 		CreatedAt:    u.CreatedAt,
 		UpdatedAt:    u.UpdatedAt,
 	}
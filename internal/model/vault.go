@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// VaultKeyAlgorithm names the only supported way of wrapping vault keys: a
+// libsodium compatible sealed box (X25519, XSalsa20-Poly1305) addressed to
+// the member's public key
+const VaultKeyAlgorithm = "x25519-sealedbox"
+
+// UserPublicKey is the public half of a key pair a user generates on their
+// own device to receive vault keys. The private half never reaches the
+// server.
+type UserPublicKey struct {
+	UserID    uint      `gorm:"primaryKey;autoIncrement:false" json:"user_id"`
+	Algorithm string    `gorm:"type:varchar(32);not null" json:"algorithm"`
+	PublicKey []byte    `gorm:"not null" json:"public_key"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// VaultKey is a vault's folder key wrapped for one member. Only clients
+// holding the member's private key can unwrap it.
+type VaultKey struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	VaultID    uint      `gorm:"not null;uniqueIndex:idx_vault_keys_vault_user" json:"vault_id"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_vault_keys_vault_user;index" json:"user_id"`
+	WrappedKey []byte    `gorm:"not null" json:"wrapped_key"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+// SetPublicKeyRequest registers the caller's public key. Keys are base64
+// encoded in JSON.
+type SetPublicKeyRequest struct {
+	Algorithm string `json:"algorithm" validate:"required,oneof=x25519-sealedbox"`
+	PublicKey []byte `json:"public_key" validate:"required,len=32"`
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Webhook is an HTTP endpoint that receives events as signed JSON POSTs.
+// A user's webhooks receive events concerning their drive; global webhooks,
+// which only administrators can register, receive every event.
+type Webhook struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	UserID      uint       `gorm:"not null;index" json:"user_id"`
+	URL         string     `gorm:"type:text;not null" json:"url"`
+	Description string     `gorm:"type:varchar(255)" json:"description"`
+	Secret      string     `gorm:"type:varchar(100);not null" json:"-"`
+	Events      StringList `gorm:"type:jsonb;not null" json:"events"`
+	Global      bool       `gorm:"not null;default:false" json:"global"`
+	Active      bool       `gorm:"not null;default:true" json:"active"`
+
+	CreatedAt time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	User *User `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// DeliveryStatus tracks a webhook delivery through its retries
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// WebhookDelivery records one event sent to one webhook, including the
+// outcome of the most recent attempt. Pending deliveries are persisted so
+// they survive restarts.
+type WebhookDelivery struct {
+	ID             uint           `gorm:"primaryKey" json:"id"`
+	WebhookID      uint           `gorm:"not null;index" json:"webhook_id"`
+	EventID        string         `gorm:"type:varchar(36);not null" json:"event_id"`
+	EventType      EventType      `gorm:"type:varchar(50);not null" json:"event_type"`
+	Payload        string         `gorm:"type:jsonb;not null" json:"payload"`
+	Status         DeliveryStatus `gorm:"type:varchar(20);not null" json:"status"`
+	Attempts       int            `gorm:"not null;default:0" json:"attempts"`
+	NextAttemptAt  *time.Time     `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time     `json:"last_attempt_at,omitempty"`
+	ResponseStatus int            `json:"response_status,omitempty"`
+	ResponseBody   string         `gorm:"type:text" json:"response_body,omitempty"`
+	Error          string         `gorm:"type:text" json:"error,omitempty"`
+	DurationMS     int64          `json:"duration_ms"`
+	RedeliveryOf   *uint          `json:"redelivery_of,omitempty"`
+
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+
+	Webhook *Webhook `gorm:"foreignKey:WebhookID" json:"-"`
+}
@@ -0,0 +1,32 @@
+package model
+
+// CreateWebhookRequest registers a webhook. Only administrators may create
+// global webhooks.
+type CreateWebhookRequest struct {
+	URL         string      `json:"url" validate:"required,url,max=2048"`
+	Description string      `json:"description" validate:"max=255"`
+	Events      []EventType `json:"events" validate:"required,min=1,dive,oneof=file.created file.updated file.moved file.deleted file.quarantined folder.created folder.updated folder.moved folder.deleted comment.created comment.updated comment.deleted comment.mention share.created share.updated share.deleted user.registered"`
+	Global      bool        `json:"global"`
+}
+
+// UpdateWebhookRequest changes a webhook; omitted fields are left unchanged
+type UpdateWebhookRequest struct {
+	URL         *string     `json:"url" validate:"omitempty,url,max=2048"`
+	Description *string     `json:"description" validate:"omitempty,max=255"`
+	Events      []EventType `json:"events" validate:"omitempty,min=1,dive,oneof=file.created file.updated file.moved file.deleted file.quarantined folder.created folder.updated folder.moved folder.deleted comment.created comment.updated comment.deleted comment.mention share.created share.updated share.deleted user.registered"`
+	Active      *bool       `json:"active"`
+}
+
+// CreateWebhookResponse includes the signing secret, which is only revealed
+// when the webhook is created
+type CreateWebhookResponse struct {
+	*Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookDeliveryFilter holds the filters accepted when listing deliveries
+type WebhookDeliveryFilter struct {
+	Status  DeliveryStatus `json:"status" validate:"omitempty,oneof=pending succeeded failed"`
+	Page    int            `json:"page" validate:"gte=0"`
+	PerPage int            `json:"per_page" validate:"gte=0,lte=100"`
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WorkspaceRole is a member's role in a workspace
+type WorkspaceRole string
+
+const (
+	// WorkspaceManager members manage the workspace and its members and
+	// hold owner permission on its content
+	WorkspaceManager WorkspaceRole = "manager"
+	// WorkspaceEditor members can add, change and remove content
+	WorkspaceEditor WorkspaceRole = "editor"
+	// WorkspaceViewer members can only read content
+	WorkspaceViewer WorkspaceRole = "viewer"
+)
+
+// Permission returns the permission the role grants on workspace content
+func (r WorkspaceRole) Permission() Permission {
+	switch r {
+	case WorkspaceManager:
+		return PermissionOwner
+	case WorkspaceEditor:
+		return PermissionWrite
+	case WorkspaceViewer:
+		return PermissionRead
+	default:
+		return ""
+	}
+}
+
+// Workspace is a shared drive belonging to the organization rather than to
+// any user. Its folders and files carry its ID, its members reach them
+// through their role and their storage is charged to the workspace, so
+// content stays when the people who added it leave.
+type Workspace struct {
+	ID           uint   `gorm:"primaryKey" json:"id"`
+	Name         string `gorm:"type:varchar(255);not null" json:"name"`
+	RootFolderID uint   `gorm:"not null;default:0" json:"root_folder_id"`
+	// StorageUsed and StorageLimit are in megabytes, like a user's
+	StorageUsed  float64        `gorm:"not null;default:0" json:"storage_used"`
+	StorageLimit float64        `gorm:"not null;default:100000" json:"storage_limit"`
+	CreatedBy    uint           `gorm:"not null" json:"created_by"`
+	CreatedAt    time.Time      `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt    time.Time      `gorm:"autoUpdateTime" json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"-"`
+}
+
+// WorkspaceMember gives a user a role in a workspace
+type WorkspaceMember struct {
+	ID          uint          `gorm:"primaryKey" json:"id"`
+	WorkspaceID uint          `gorm:"not null;uniqueIndex:idx_workspace_members_workspace_user" json:"workspace_id"`
+	UserID      uint          `gorm:"not null;uniqueIndex:idx_workspace_members_workspace_user;index" json:"user_id"`
+	Role        WorkspaceRole `gorm:"type:varchar(16);not null" json:"role"`
+	AddedBy     uint          `gorm:"not null" json:"added_by"`
+	CreatedAt   time.Time     `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt   time.Time     `gorm:"autoUpdateTime" json:"updated_at"`
+
+	Workspace *Workspace `gorm:"foreignKey:WorkspaceID" json:"workspace,omitempty"`
+	User      *User      `gorm:"foreignKey:UserID" json:"user,omitempty"`
+}
+
+// CreateWorkspaceRequest creates a workspace with its first manager. A zero
+// StorageLimit uses the default.
+type CreateWorkspaceRequest struct {
+	Name         string  `json:"name" validate:"required,max=255"`
+	ManagerID    uint    `json:"manager_id" validate:"required"`
+	StorageLimit float64 `json:"storage_limit" validate:"gte=0"`
+}
+
+// UpdateWorkspaceRequest renames a workspace or, for administrators,
+// changes its quota; omitted fields are left unchanged
+type UpdateWorkspaceRequest struct {
+	Name         *string  `json:"name" validate:"omitempty,min=1,max=255"`
+	StorageLimit *float64 `json:"storage_limit" validate:"omitempty,gt=0"`
+}
+
+// SetWorkspaceMemberRequest adds a member or changes their role
+type SetWorkspaceMemberRequest struct {
+	Role WorkspaceRole `json:"role" validate:"required,oneof=manager editor viewer"`
+}
+
+// WorkspaceFilter holds the pagination accepted when listing workspaces
+type WorkspaceFilter struct {
+	Page    int `json:"page" validate:"gte=0"`
+	PerPage int `json:"per_page" validate:"gte=0,lte=100"`
+}
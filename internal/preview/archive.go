@@ -0,0 +1,145 @@
+package preview
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"strings"
+)
+
+// maxArchiveEntries is the number of entries listed per archive
+const maxArchiveEntries = 10000
+
+// ArchiveRenderer lists the contents of ZIP and tar archives, including
+// gzip-compressed tarballs
+type ArchiveRenderer struct{}
+
+// NewArchiveRenderer creates a new archive renderer
+func NewArchiveRenderer() *ArchiveRenderer {
+	return &ArchiveRenderer{}
+}
+
+// Name returns the renderer name
+func (a *ArchiveRenderer) Name() string {
+	return "archive"
+}
+
+// Supports reports whether the file is a ZIP or tar archive
+func (a *ArchiveRenderer) Supports(mimeType, fileName string) bool {
+	return isZip(mimeType, fileName) || isTar(mimeType, fileName) || isTarball(fileName)
+}
+
+// Render lists the archive's entries in the order they are stored. Files
+// named neither .tar nor .tar.gz are read as ZIP archives first and as tar
+// archives when that fails.
+func (a *ArchiveRenderer) Render(ctx context.Context, fileName string, r io.ReadSeeker, size int64) (*Preview, error) {
+	switch {
+	case isTarball(fileName):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return listTar(ctx, gz)
+	case hasExtension(fileName, ".tar"):
+		return listTar(ctx, r)
+	}
+
+	p, err := listZip(ctx, r, size)
+	if !errors.Is(err, zip.ErrFormat) {
+		return p, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return listTar(ctx, r)
+}
+
+func isZip(mimeType, fileName string) bool {
+	return mimeType == "application/zip" || hasExtension(fileName, ".zip")
+}
+
+func isTar(mimeType, fileName string) bool {
+	return mimeType == "application/x-tar" || hasExtension(fileName, ".tar")
+}
+
+func isTarball(fileName string) bool {
+	name := strings.ToLower(fileName)
+	return strings.HasSuffix(name, ".tar.gz") || strings.HasSuffix(name, ".tgz")
+}
+
+// listZip reads a ZIP archive's central directory
+func listZip(ctx context.Context, r io.ReadSeeker, size int64) (*Preview, error) {
+	archive, err := zip.NewReader(&readerAt{r: r}, size)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Preview{Kind: KindArchive}
+	for _, f := range archive.File {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if len(p.Entries) == maxArchiveEntries {
+			p.Truncated = true
+			break
+		}
+		p.Entries = append(p.Entries, ArchiveEntry{
+			Name:     f.Name,
+			Size:     int64(f.UncompressedSize64),
+			Modified: f.Modified,
+			Dir:      f.FileInfo().IsDir(),
+		})
+	}
+	return p, nil
+}
+
+// listTar reads the headers of a tar stream
+func listTar(ctx context.Context, r io.Reader) (*Preview, error) {
+	reader := tar.NewReader(r)
+	p := &Preview{Kind: KindArchive}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		header, err := reader.Next()
+		if errors.Is(err, io.EOF) {
+			return p, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(p.Entries) == maxArchiveEntries {
+			p.Truncated = true
+			return p, nil
+		}
+		p.Entries = append(p.Entries, ArchiveEntry{
+			Name:     header.Name,
+			Size:     header.Size,
+			Modified: header.ModTime,
+			Dir:      header.Typeflag == tar.TypeDir,
+		})
+	}
+}
+
+// readerAt adapts a seekable reader for archive/zip. It is not safe for
+// concurrent use, which archive/zip does not need when reading the
+// directory.
+type readerAt struct {
+	r io.ReadSeeker
+}
+
+// ReadAt implements io.ReaderAt
+func (ra *readerAt) ReadAt(p []byte, off int64) (int, error) {
+	if _, err := ra.r.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(ra.r, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
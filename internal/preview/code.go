@@ -0,0 +1,331 @@
+package preview
+
+import (
+	"context"
+	"html"
+	"io"
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxCodeSize is the amount of source code highlighted per file
+const maxCodeSize = 512 << 10
+
+// language describes enough of a programming language's lexical syntax to
+// highlight keywords, strings, comments and numbers
+type language struct {
+	name         string
+	aliases      []string
+	extensions   []string
+	fileNames    []string
+	keywords     string
+	lineComments []string
+	blockComment [2]string
+	quotes       string
+	// caseless languages match keywords regardless of case
+	caseless bool
+
+	keywordSet map[string]bool
+}
+
+var cKeywords = "auto break case char const continue default do double else enum extern float for goto if inline int long register restrict return short signed sizeof static struct switch typedef union unsigned void volatile while bool true false NULL nullptr"
+
+var languages = []*language{
+	{name: "go", aliases: []string{"golang"}, extensions: []string{".go"},
+		keywords:     "break case chan const continue default defer else fallthrough for func go goto if import interface map package range return select struct switch type var true false nil iota",
+		lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: "\"'`"},
+	{name: "python", aliases: []string{"py"}, extensions: []string{".py", ".pyw"},
+		keywords:     "and as assert async await break class continue def del elif else except finally for from global if import in is lambda nonlocal not or pass raise return try while with yield True False None self",
+		lineComments: []string{"#"}, quotes: "\"'"},
+	{name: "javascript", aliases: []string{"js", "jsx"}, extensions: []string{".js", ".mjs", ".cjs", ".jsx"},
+		keywords:     "async await break case catch class const continue debugger default delete do else export extends finally for function if import in instanceof let new of return static super switch this throw try typeof var void while with yield true false null undefined",
+		lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: "\"'`"},
+	{name: "typescript", aliases: []string{"ts", "tsx"}, extensions: []string{".ts", ".tsx"},
+		keywords:     "abstract any as async await boolean break case catch class const continue declare default delete do else enum export extends finally for function if implements import in instanceof interface keyof let namespace never new number of private protected public readonly return static string super switch this throw try type typeof unknown var void while yield true false null undefined",
+		lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: "\"'`"},
+	{name: "java", extensions: []string{".java"},
+		keywords:     "abstract assert boolean break byte case catch char class const continue default do double else enum extends final finally float for if implements import instanceof int interface long native new package private protected public return short static super switch synchronized this throw throws try void volatile while var record true false null",
+		lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: "\"'"},
+	{name: "kotlin", aliases: []string{"kt"}, extensions: []string{".kt", ".kts"},
+		keywords:     "as break class continue do else false for fun if in interface is null object package return super this throw true try typealias val var when while data sealed open override private protected public internal companion import",
+		lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: "\"'"},
+	{name: "c", extensions: []string{".c", ".h"},
+		keywords:     cKeywords,
+		lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: "\"'"},
+	{name: "cpp", aliases: []string{"c++", "cxx"}, extensions: []string{".cc", ".cpp", ".cxx", ".hpp", ".hh", ".hxx"},
+		keywords:     cKeywords + " alignas alignof catch class constexpr decltype delete explicit friend mutable namespace new noexcept operator override private protected public template this throw try typename using virtual",
+		lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: "\"'"},
+	{name: "csharp", aliases: []string{"cs", "c#"}, extensions: []string{".cs"},
+		keywords:     "abstract as async await base bool break byte case catch char class const continue decimal default delegate do double else enum event explicit extern false finally float for foreach get if implicit in int interface internal is lock long namespace new null object out override params private protected public readonly ref return sealed set short static string struct switch this throw true try typeof uint ulong using var virtual void while",
+		lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: "\"'"},
+	{name: "rust", aliases: []string{"rs"}, extensions: []string{".rs"},
+		keywords:     "as async await break const continue crate dyn else enum extern false fn for if impl in let loop match mod move mut pub ref return self Self static struct super trait true type unsafe use where while",
+		lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: "\""},
+	{name: "swift", extensions: []string{".swift"},
+		keywords:     "associatedtype break case catch class continue default defer do else enum extension fallthrough false for func guard if import in init inout internal let nil private protocol public repeat return self static struct subscript super switch throw throws true try var where while",
+		lineComments: []string{"//"}, blockComment: [2]string{"/*", "*/"}, quotes: "\""},
+	{name: "ruby", aliases: []string{"rb"}, extensions: []string{".rb"}, fileNames: []string{"Gemfile", "Rakefile"},
+		keywords:     "BEGIN END alias and begin break case class def defined? do else elsif end ensure false for if in module next nil not or redo rescue retry return self super then true undef unless until when while yield require attr_accessor",
+		lineComments: []string{"#"}, quotes: "\"'"},
+	{name: "php", extensions: []string{".php"},
+		keywords:     "abstract and array as break callable case catch class clone const continue declare default do echo else elseif empty enddeclare endfor endforeach endif endswitch endwhile extends final finally fn for foreach function global goto if implements include instanceof interface isset list match namespace new or print private protected public readonly require return static switch throw trait try unset use var while yield true false null",
+		lineComments: []string{"//", "#"}, blockComment: [2]string{"/*", "*/"}, quotes: "\"'"},
+	{name: "shell", aliases: []string{"sh", "bash", "zsh"}, extensions: []string{".sh", ".bash", ".zsh"},
+		keywords:     "if then else elif fi case esac for while until do done in function return local export readonly set unset shift exit break continue",
+		lineComments: []string{"#"}, quotes: "\"'"},
+	{name: "sql", extensions: []string{".sql"}, caseless: true,
+		keywords:     "select from where and or not insert into values update set delete create table index view drop alter add column primary key foreign references join left right inner outer full on as group by order having limit offset distinct union all case when then else end null is in exists like between with returning default constraint unique begin commit rollback",
+		lineComments: []string{"--"}, blockComment: [2]string{"/*", "*/"}, quotes: "'\""},
+	{name: "css", extensions: []string{".css", ".scss", ".less"},
+		keywords:     "important inherit initial unset none auto",
+		blockComment: [2]string{"/*", "*/"}, quotes: "\"'"},
+	{name: "html", aliases: []string{"xml", "svg"}, extensions: []string{".html", ".htm", ".xml", ".svg", ".xhtml"},
+		blockComment: [2]string{"<!--", "-->"}, quotes: "\""},
+	{name: "yaml", aliases: []string{"yml"}, extensions: []string{".yaml", ".yml"},
+		keywords:     "true false null yes no on off",
+		lineComments: []string{"#"}, quotes: "\"'"},
+	{name: "toml", extensions: []string{".toml", ".ini", ".cfg", ".conf"},
+		keywords:     "true false",
+		lineComments: []string{"#", ";"}, quotes: "\"'"},
+	{name: "json", extensions: []string{".json"},
+		keywords: "true false null", quotes: "\""},
+	{name: "dockerfile", aliases: []string{"docker"}, fileNames: []string{"Dockerfile"}, caseless: true,
+		keywords:     "from as run cmd label expose env add copy entrypoint volume user workdir arg onbuild stopsignal healthcheck shell",
+		lineComments: []string{"#"}, quotes: "\"'"},
+	{name: "makefile", aliases: []string{"make"}, fileNames: []string{"Makefile", "GNUmakefile"},
+		keywords:     "ifeq ifneq ifdef ifndef else endif include define endef export",
+		lineComments: []string{"#"}, quotes: "\"'"},
+	{name: "text", extensions: []string{".txt", ".log", ".text"}},
+}
+
+func init() {
+	for _, lang := range languages {
+		lang.keywordSet = make(map[string]bool)
+		for _, keyword := range strings.Fields(lang.keywords) {
+			if lang.caseless {
+				keyword = strings.ToLower(keyword)
+			}
+			lang.keywordSet[keyword] = true
+		}
+	}
+}
+
+// languageByName finds a language by its name or an alias, as used on
+// Markdown code fences
+func languageByName(name string) *language {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, lang := range languages {
+		if lang.name == name {
+			return lang
+		}
+		for _, alias := range lang.aliases {
+			if alias == name {
+				return lang
+			}
+		}
+	}
+	return nil
+}
+
+// languageForFile finds the language of a file from its name
+func languageForFile(fileName string) *language {
+	base := path.Base(fileName)
+	ext := extension(fileName)
+	for _, lang := range languages {
+		for _, name := range lang.fileNames {
+			if base == name {
+				return lang
+			}
+		}
+		for _, e := range lang.extensions {
+			if ext == e {
+				return lang
+			}
+		}
+	}
+	return nil
+}
+
+// CodeRenderer highlights source code and shows plain text files as they are
+type CodeRenderer struct{}
+
+// NewCodeRenderer creates a new source code renderer
+func NewCodeRenderer() *CodeRenderer {
+	return &CodeRenderer{}
+}
+
+// Name returns the renderer name
+func (c *CodeRenderer) Name() string {
+	return "code"
+}
+
+// Supports reports whether the file is source code or plain text
+func (c *CodeRenderer) Supports(mimeType, fileName string) bool {
+	return languageForFile(fileName) != nil || mimeType == "text/plain"
+}
+
+// Render highlights the start of the file
+func (c *CodeRenderer) Render(ctx context.Context, fileName string, r io.ReadSeeker, size int64) (*Preview, error) {
+	data, truncated, err := readLimited(r, maxCodeSize)
+	if err != nil {
+		return nil, err
+	}
+	lang := languageForFile(fileName)
+	if lang == nil {
+		lang = languageByName("text")
+	}
+	return &Preview{
+		Kind:      KindCode,
+		Language:  lang.name,
+		HTML:      highlightBlock(string(data), lang),
+		Truncated: truncated,
+	}, nil
+}
+
+// highlightBlock renders code as a highlighted <pre> block. A nil language
+// leaves the code unhighlighted.
+func highlightBlock(code string, lang *language) string {
+	var b strings.Builder
+	b.WriteString(`<pre class="code">`)
+	if lang != nil {
+		b.WriteString(`<code class="language-` + lang.name + `">`)
+	} else {
+		b.WriteString(`<code>`)
+	}
+	highlight(&b, code, lang)
+	b.WriteString("</code></pre>")
+	return b.String()
+}
+
+// Token classes, used as CSS classes on the <span> elements
+const (
+	tokenKeyword = "tok-kw"
+	tokenString  = "tok-str"
+	tokenComment = "tok-com"
+	tokenNumber  = "tok-num"
+)
+
+// highlight writes code to b as escaped HTML, wrapping keywords, strings,
+// comments and numbers in spans
+func highlight(b *strings.Builder, code string, lang *language) {
+	if lang == nil {
+		b.WriteString(html.EscapeString(code))
+		return
+	}
+
+	plainStart := 0
+	flush := func(end int) {
+		b.WriteString(html.EscapeString(code[plainStart:end]))
+	}
+	emit := func(start, end int, class string) {
+		flush(start)
+		b.WriteString(`<span class="` + class + `">`)
+		b.WriteString(html.EscapeString(code[start:end]))
+		b.WriteString("</span>")
+		plainStart = end
+	}
+
+	for i := 0; i < len(code); {
+		rest := code[i:]
+		if end := lang.comment(rest); end > 0 {
+			emit(i, i+end, tokenComment)
+			i += end
+			continue
+		}
+
+		r, width := utf8.DecodeRuneInString(rest)
+		switch {
+		case strings.ContainsRune(lang.quotes, r):
+			end := stringEnd(rest, r)
+			emit(i, i+end, tokenString)
+			i += end
+		case unicode.IsDigit(r) && (i == 0 || !isIdentByte(code[i-1])):
+			end := numberEnd(rest)
+			emit(i, i+end, tokenNumber)
+			i += end
+		case r == '_' || unicode.IsLetter(r):
+			end := identEnd(rest)
+			word := rest[:end]
+			if lang.caseless {
+				word = strings.ToLower(word)
+			}
+			if lang.keywordSet[word] {
+				emit(i, i+end, tokenKeyword)
+			}
+			i += end
+		default:
+			i += width
+		}
+	}
+	flush(len(code))
+}
+
+// comment returns the length of the comment starting s, or 0
+func (l *language) comment(s string) int {
+	for _, prefix := range l.lineComments {
+		if strings.HasPrefix(s, prefix) {
+			if end := strings.IndexByte(s, '\n'); end >= 0 {
+				return end
+			}
+			return len(s)
+		}
+	}
+	if open, close := l.blockComment[0], l.blockComment[1]; open != "" && strings.HasPrefix(s, open) {
+		if end := strings.Index(s[len(open):], close); end >= 0 {
+			return len(open) + end + len(close)
+		}
+		return len(s)
+	}
+	return 0
+}
+
+// stringEnd returns the length of the string literal starting s. Strings
+// other than backtick-quoted ones end at the end of the line.
+func stringEnd(s string, quote rune) int {
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if quote != '`' {
+				i++
+			}
+		case '\n':
+			if quote != '`' {
+				return i
+			}
+		case byte(quote):
+			return i + 1
+		}
+	}
+	return len(s)
+}
+
+// numberEnd returns the length of the number starting s
+func numberEnd(s string) int {
+	for i := 1; i < len(s); i++ {
+		if c := s[i]; !isIdentByte(c) && c != '.' {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// identEnd returns the length of the identifier starting s. A trailing "?"
+// is included for Ruby's defined?.
+func identEnd(s string) int {
+	for i, r := range s {
+		if i > 0 && r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if r == '?' {
+				return i + 1
+			}
+			return i
+		}
+	}
+	return len(s)
+}
+
+// isIdentByte reports whether c can be part of an ASCII identifier
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
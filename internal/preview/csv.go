@@ -0,0 +1,65 @@
+package preview
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+)
+
+// maxTableRows is the number of CSV records kept per file
+const maxTableRows = 10000
+
+// CSVRenderer shows CSV and TSV files as tables, taking the first record as
+// the header
+type CSVRenderer struct{}
+
+// NewCSVRenderer creates a new CSV renderer
+func NewCSVRenderer() *CSVRenderer {
+	return &CSVRenderer{}
+}
+
+// Name returns the renderer name
+func (c *CSVRenderer) Name() string {
+	return "csv"
+}
+
+// Supports reports whether the file is CSV or TSV
+func (c *CSVRenderer) Supports(mimeType, fileName string) bool {
+	return mimeType == "text/csv" || mimeType == "text/tab-separated-values" || hasExtension(fileName, ".csv", ".tsv")
+}
+
+// Render reads up to maxTableRows records after the header
+func (c *CSVRenderer) Render(ctx context.Context, fileName string, r io.ReadSeeker, size int64) (*Preview, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	if hasExtension(fileName, ".tsv") {
+		reader.Comma = '\t'
+	}
+
+	p := &Preview{Kind: KindTable}
+	for {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if p.Columns == nil {
+			p.Columns = record
+			continue
+		}
+		if len(p.Rows) == maxTableRows {
+			p.Truncated = true
+			break
+		}
+		p.Rows = append(p.Rows, record)
+	}
+	p.TotalRows = len(p.Rows)
+	return p, nil
+}
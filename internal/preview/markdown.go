@@ -0,0 +1,460 @@
+package preview
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxMarkdownSize is the amount of Markdown rendered per file
+	maxMarkdownSize = 1 << 20
+	// maxMarkdownDepth bounds the nesting of block quotes and lists
+	maxMarkdownDepth = 16
+)
+
+// MarkdownRenderer renders Markdown as HTML. Raw HTML in the source is
+// escaped rather than passed through and only http, https and mailto links
+// are kept, so the output is safe to embed.
+type MarkdownRenderer struct{}
+
+// NewMarkdownRenderer creates a new Markdown renderer
+func NewMarkdownRenderer() *MarkdownRenderer {
+	return &MarkdownRenderer{}
+}
+
+// Name returns the renderer name
+func (m *MarkdownRenderer) Name() string {
+	return "markdown"
+}
+
+// Supports reports whether the file is Markdown
+func (m *MarkdownRenderer) Supports(mimeType, fileName string) bool {
+	return mimeType == "text/markdown" || mimeType == "text/x-markdown" ||
+		hasExtension(fileName, ".md", ".markdown", ".mdown")
+}
+
+// Render converts the start of the file to HTML
+func (m *MarkdownRenderer) Render(ctx context.Context, fileName string, r io.ReadSeeker, size int64) (*Preview, error) {
+	data, truncated, err := readLimited(r, maxMarkdownSize)
+	if err != nil {
+		return nil, err
+	}
+	return &Preview{
+		Kind:      KindHTML,
+		HTML:      RenderMarkdown(string(data)),
+		Truncated: truncated,
+	}, nil
+}
+
+var (
+	fenceRegex     = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})\\s*([^`\\s]*)")
+	headingRegex   = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	ruleRegex      = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	quoteRegex     = regexp.MustCompile(`^ {0,3}> ?`)
+	listItemRegex  = regexp.MustCompile(`^( {0,3})([-*+]|\d{1,9}[.)])[ \t]+(.*)$`)
+	tableRuleRegex = regexp.MustCompile(`^ *\|? *:?-+:? *(?:\| *:?-+:? *)*\|? *$`)
+)
+
+// RenderMarkdown converts Markdown to HTML. It supports headings,
+// paragraphs, emphasis, code spans and fenced code, block quotes, lists,
+// tables, rules, links and images.
+func RenderMarkdown(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\x00", "")
+	var b strings.Builder
+	renderBlocks(&b, strings.Split(src, "\n"), 0)
+	return b.String()
+}
+
+// renderBlocks renders lines as a sequence of block elements
+func renderBlocks(b *strings.Builder, lines []string, depth int) {
+	var paragraph []string
+	flush := func() {
+		if len(paragraph) > 0 {
+			b.WriteString("<p>" + renderInline(strings.Join(paragraph, "\n")) + "</p>\n")
+			paragraph = nil
+		}
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			flush()
+			i++
+
+		case fenceRegex.MatchString(line):
+			flush()
+			match := fenceRegex.FindStringSubmatch(line)
+			fence, info := match[1], match[2]
+			var code []string
+			for i++; i < len(lines); i++ {
+				if strings.HasPrefix(strings.TrimSpace(lines[i]), fence) {
+					i++
+					break
+				}
+				code = append(code, lines[i])
+			}
+			b.WriteString(highlightBlock(strings.Join(code, "\n"), languageByName(info)) + "\n")
+
+		case headingRegex.MatchString(line):
+			flush()
+			match := headingRegex.FindStringSubmatch(line)
+			level := len(match[1])
+			fmt.Fprintf(b, "<h%d>%s</h%d>\n", level, renderInline(match[2]), level)
+			i++
+
+		case ruleRegex.MatchString(line):
+			flush()
+			b.WriteString("<hr>\n")
+			i++
+
+		case quoteRegex.MatchString(line) && depth < maxMarkdownDepth:
+			flush()
+			var quoted []string
+			for ; i < len(lines) && quoteRegex.MatchString(lines[i]); i++ {
+				quoted = append(quoted, quoteRegex.ReplaceAllString(lines[i], ""))
+			}
+			b.WriteString("<blockquote>\n")
+			renderBlocks(b, quoted, depth+1)
+			b.WriteString("</blockquote>\n")
+
+		case listItemRegex.MatchString(line) && depth < maxMarkdownDepth:
+			flush()
+			i = renderList(b, lines, i, depth)
+
+		case i+1 < len(lines) && strings.Contains(line, "|") && tableRuleRegex.MatchString(lines[i+1]):
+			flush()
+			i = renderTable(b, lines, i)
+
+		case len(paragraph) == 0 && (strings.HasPrefix(line, "    ") || strings.HasPrefix(line, "\t")):
+			var code []string
+			for ; i < len(lines); i++ {
+				l := lines[i]
+				if strings.HasPrefix(l, "    ") {
+					l = l[4:]
+				} else if strings.HasPrefix(l, "\t") {
+					l = l[1:]
+				} else if strings.TrimSpace(l) != "" {
+					break
+				}
+				code = append(code, l)
+			}
+			for len(code) > 0 && strings.TrimSpace(code[len(code)-1]) == "" {
+				code = code[:len(code)-1]
+			}
+			b.WriteString(highlightBlock(strings.Join(code, "\n"), nil) + "\n")
+
+		default:
+			paragraph = append(paragraph, line)
+			i++
+		}
+	}
+	flush()
+}
+
+// renderList renders the list starting at lines[start], returning the index
+// of the first line after it. Lines indented past an item's marker belong
+// to the item, so nested lists and multi-paragraph items work.
+func renderList(b *strings.Builder, lines []string, start, depth int) int {
+	first := listItemRegex.FindStringSubmatch(lines[start])
+	ordered := !strings.ContainsAny(first[2][:1], "-*+")
+	delimiter := first[2][len(first[2])-1:]
+
+	tag := "ul"
+	if ordered {
+		tag = "ol"
+		if n, _ := strconv.Atoi(first[2][:len(first[2])-1]); n != 1 {
+			fmt.Fprintf(b, "<ol start=\"%d\">\n", n)
+		} else {
+			b.WriteString("<ol>\n")
+		}
+	} else {
+		b.WriteString("<ul>\n")
+	}
+
+	i := start
+	for i < len(lines) {
+		match := listItemRegex.FindStringSubmatch(lines[i])
+		if match == nil || len(match[1]) > 3 || match[2][len(match[2])-1:] != delimiter ||
+			ordered == strings.ContainsAny(match[2][:1], "-*+") {
+			break
+		}
+		indent := len(match[1]) + len(match[2]) + 1
+		item := []string{match[3]}
+		loose := false
+		for i++; i < len(lines); i++ {
+			line := lines[i]
+			if strings.TrimSpace(line) == "" {
+				// A blank line continues the item only if indented
+				// content follows
+				if i+1 < len(lines) && leadingSpaces(lines[i+1]) >= indent {
+					item = append(item, "")
+					loose = true
+					continue
+				}
+				break
+			}
+			if leadingSpaces(line) >= indent {
+				item = append(item, dedent(line, indent))
+				continue
+			}
+			if listItemRegex.MatchString(line) {
+				break
+			}
+			// Lazy continuation of the item's paragraph
+			item = append(item, strings.TrimSpace(line))
+		}
+
+		b.WriteString("<li>")
+		if loose {
+			b.WriteString("\n")
+			renderBlocks(b, item, depth+1)
+		} else {
+			// Tight items keep their text out of paragraphs
+			text := 1
+			for text < len(item) && !listItemRegex.MatchString(item[text]) {
+				text++
+			}
+			b.WriteString(renderInline(strings.Join(item[:text], "\n")))
+			if text < len(item) {
+				b.WriteString("\n")
+				renderBlocks(b, item[text:], depth+1)
+			}
+		}
+		b.WriteString("</li>\n")
+
+		if i < len(lines) && strings.TrimSpace(lines[i]) == "" {
+			if i+1 < len(lines) && listItemRegex.MatchString(lines[i+1]) {
+				i++
+				continue
+			}
+			break
+		}
+	}
+
+	b.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// renderTable renders the pipe table starting at lines[start], returning
+// the index of the first line after it
+func renderTable(b *strings.Builder, lines []string, start int) int {
+	header := tableCells(lines[start])
+	var aligns []string
+	for _, cell := range tableCells(lines[start+1]) {
+		switch {
+		case strings.HasPrefix(cell, ":") && strings.HasSuffix(cell, ":"):
+			aligns = append(aligns, "center")
+		case strings.HasSuffix(cell, ":"):
+			aligns = append(aligns, "right")
+		case strings.HasPrefix(cell, ":"):
+			aligns = append(aligns, "left")
+		default:
+			aligns = append(aligns, "")
+		}
+	}
+
+	writeRow := func(cells []string, tag string) {
+		b.WriteString("<tr>")
+		for j := range header {
+			cell := ""
+			if j < len(cells) {
+				cell = cells[j]
+			}
+			if j < len(aligns) && aligns[j] != "" {
+				fmt.Fprintf(b, "<%s style=\"text-align: %s\">", tag, aligns[j])
+			} else {
+				b.WriteString("<" + tag + ">")
+			}
+			b.WriteString(renderInline(cell) + "</" + tag + ">")
+		}
+		b.WriteString("</tr>\n")
+	}
+
+	b.WriteString("<table>\n<thead>\n")
+	writeRow(header, "th")
+	b.WriteString("</thead>\n<tbody>\n")
+	i := start + 2
+	for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" && strings.Contains(lines[i], "|"); i++ {
+		writeRow(tableCells(lines[i]), "td")
+	}
+	b.WriteString("</tbody>\n</table>\n")
+	return i
+}
+
+// tableCells splits a table row on unescaped pipes
+func tableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "|")
+	if strings.HasSuffix(line, "|") && !strings.HasSuffix(line, `\|`) {
+		line = line[:len(line)-1]
+	}
+
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// dedent removes up to n columns of indentation from a line
+func dedent(line string, n int) string {
+	for i, c := range line {
+		switch {
+		case n <= 0:
+			return line[i:]
+		case c == ' ':
+			n--
+		case c == '\t':
+			n -= 4
+		default:
+			return line[i:]
+		}
+	}
+	return ""
+}
+
+// leadingSpaces counts the indentation of a line, with tabs as four spaces
+func leadingSpaces(line string) int {
+	n := 0
+	for _, c := range line {
+		switch c {
+		case ' ':
+			n++
+		case '\t':
+			n += 4
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+var (
+	codeSpanRegex = regexp.MustCompile("``(.+?)``|`([^`]+)`")
+	autolinkRegex = regexp.MustCompile(`<((?:https?://|mailto:)[^>\s]+)>`)
+	imageRegex    = regexp.MustCompile(`!\[([^\]]*)\]\(\s*([^)\s]+)(?:\s+"([^"]*)")?\s*\)`)
+	linkRegex     = regexp.MustCompile(`\[([^\]]+)\]\(\s*([^)\s]+)(?:\s+"([^"]*)")?\s*\)`)
+	strongRegex   = regexp.MustCompile(`\*\*(\S(?:.*?\S)?)\*\*|(?:^|\b)__(\S(?:.*?\S)?)__(?:\b|$)`)
+	emRegex       = regexp.MustCompile(`\*(\S(?:.*?\S)?)\*|(?:^|\b)_(\S(?:.*?\S)?)_(?:\b|$)`)
+	strikeRegex   = regexp.MustCompile(`~~(\S(?:.*?\S)?)~~`)
+	placeholder   = regexp.MustCompile("\x00([0-9]+)\x00")
+)
+
+// renderInline renders the inline markup of a block's text. Code spans,
+// links and images are rendered first and swapped for placeholders, so
+// emphasis never applies inside them.
+func renderInline(text string) string {
+	var rendered []string
+	hold := func(s string) string {
+		rendered = append(rendered, s)
+		return "\x00" + strconv.Itoa(len(rendered)-1) + "\x00"
+	}
+
+	text = codeSpanRegex.ReplaceAllStringFunc(text, func(m string) string {
+		match := codeSpanRegex.FindStringSubmatch(m)
+		code := match[1] + match[2]
+		return hold("<code>" + html.EscapeString(strings.TrimSpace(code)) + "</code>")
+	})
+	text = autolinkRegex.ReplaceAllStringFunc(text, func(m string) string {
+		target := autolinkRegex.FindStringSubmatch(m)[1]
+		if !safeURL(target, false) {
+			return hold(html.EscapeString(m))
+		}
+		return hold(link(target, "", html.EscapeString(target)))
+	})
+	text = imageRegex.ReplaceAllStringFunc(text, func(m string) string {
+		match := imageRegex.FindStringSubmatch(m)
+		alt, target, title := match[1], match[2], match[3]
+		if !safeURL(target, true) {
+			return hold(html.EscapeString(alt))
+		}
+		img := `<img src="` + html.EscapeString(target) + `" alt="` + html.EscapeString(alt) + `"`
+		if title != "" {
+			img += ` title="` + html.EscapeString(title) + `"`
+		}
+		return hold(img + ` loading="lazy">`)
+	})
+	text = linkRegex.ReplaceAllStringFunc(text, func(m string) string {
+		match := linkRegex.FindStringSubmatch(m)
+		label, target, title := renderEmphasis(html.EscapeString(match[1])), match[2], match[3]
+		if !safeURL(target, false) {
+			return hold(label)
+		}
+		return hold(link(target, title, label))
+	})
+
+	text = renderEmphasis(html.EscapeString(text))
+	text = strings.ReplaceAll(text, "  \n", "<br>\n")
+	// Link labels may hold code spans, which were held before them
+	var expand func(string) string
+	expand = func(s string) string {
+		return placeholder.ReplaceAllStringFunc(s, func(m string) string {
+			n, _ := strconv.Atoi(m[1 : len(m)-1])
+			return expand(rendered[n])
+		})
+	}
+	return expand(text)
+}
+
+// renderEmphasis applies strong, emphasis and strikethrough markup to
+// escaped text
+func renderEmphasis(text string) string {
+	text = strongRegex.ReplaceAllStringFunc(text, func(m string) string {
+		match := strongRegex.FindStringSubmatch(m)
+		return strings.Replace(m, strings.TrimSpace(m), "<strong>"+match[1]+match[2]+"</strong>", 1)
+	})
+	text = emRegex.ReplaceAllStringFunc(text, func(m string) string {
+		match := emRegex.FindStringSubmatch(m)
+		return strings.Replace(m, strings.TrimSpace(m), "<em>"+match[1]+match[2]+"</em>", 1)
+	})
+	return strikeRegex.ReplaceAllString(text, "<del>$1</del>")
+}
+
+// link renders an anchor to an external target
+func link(target, title, label string) string {
+	a := `<a href="` + html.EscapeString(target) + `"`
+	if title != "" {
+		a += ` title="` + html.EscapeString(title) + `"`
+	}
+	return a + ` rel="nofollow noopener noreferrer">` + label + "</a>"
+}
+
+// safeURL reports whether a link target can be emitted: relative
+// references and http, https and, for links, mailto URLs
+func safeURL(target string, image bool) bool {
+	u, err := url.Parse(target)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "":
+		// Browsers read a colon before any slash, query or fragment as
+		// the end of a scheme
+		colon := strings.IndexByte(target, ':')
+		return colon < 0 || strings.ContainsAny(target[:colon], "/?#")
+	case "http", "https":
+		return true
+	case "mailto":
+		return !image
+	default:
+		return false
+	}
+}
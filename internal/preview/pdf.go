@@ -0,0 +1,48 @@
+package preview
+
+import (
+	"context"
+	"drive/internal/extractor"
+	"io"
+	"strings"
+)
+
+// maxPDFText is the amount of first-page text kept per PDF
+const maxPDFText = 64 << 10
+
+// PDFRenderer shows the text of a PDF's first page
+type PDFRenderer struct{}
+
+// NewPDFRenderer creates a new PDF renderer
+func NewPDFRenderer() *PDFRenderer {
+	return &PDFRenderer{}
+}
+
+// Name returns the renderer name
+func (p *PDFRenderer) Name() string {
+	return "pdf"
+}
+
+// Supports reports whether the file is a PDF
+func (p *PDFRenderer) Supports(mimeType, fileName string) bool {
+	return mimeType == "application/pdf" || hasExtension(fileName, ".pdf")
+}
+
+// Render extracts the text of the first content stream that shows any,
+// which is the first page's in all but unusually structured files
+func (p *PDFRenderer) Render(ctx context.Context, fileName string, r io.ReadSeeker, size int64) (*Preview, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var text strings.Builder
+	if err := extractor.ExtractPDFText(ctx, data, &text, 1); err != nil {
+		return nil, err
+	}
+
+	preview := &Preview{Kind: KindText, Text: text.String()}
+	if len(preview.Text) > maxPDFText {
+		preview.Text, preview.Truncated = strings.ToValidUTF8(preview.Text[:maxPDFText], ""), true
+	}
+	return preview, nil
+}
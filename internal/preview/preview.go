@@ -0,0 +1,150 @@
+// Package preview renders files for viewing in the browser: Markdown as
+// sanitized HTML, source code with syntax highlighting, CSV as tables,
+// archive listings and the first page of PDFs.
+package preview
+
+import (
+	"context"
+	"errors"
+	"io"
+	"path"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrUnsupported is returned when no renderer handles a file
+	ErrUnsupported = errors.New("unsupported file format")
+)
+
+// Kind says how a preview is presented
+type Kind string
+
+const (
+	// KindHTML previews carry sanitized HTML
+	KindHTML Kind = "html"
+	// KindCode previews carry highlighted source code as HTML
+	KindCode Kind = "code"
+	// KindTable previews carry columns and rows
+	KindTable Kind = "table"
+	// KindArchive previews list the entries of an archive
+	KindArchive Kind = "archive"
+	// KindText previews carry plain text
+	KindText Kind = "text"
+)
+
+// Preview is the rendered form of a file
+type Preview struct {
+	Kind Kind `json:"kind"`
+	// HTML is set for html and code previews. Everything taken from the
+	// file is escaped, so it can be inserted into a page as is.
+	HTML     string `json:"html,omitempty"`
+	Language string `json:"language,omitempty"`
+	Text     string `json:"text,omitempty"`
+
+	Columns []string   `json:"columns,omitempty"`
+	Rows    [][]string `json:"rows,omitempty"`
+	// Page, PerPage and TotalRows describe the slice of a table returned
+	Page      int `json:"page,omitempty"`
+	PerPage   int `json:"per_page,omitempty"`
+	TotalRows int `json:"total_rows,omitempty"`
+
+	Entries []ArchiveEntry `json:"entries,omitempty"`
+
+	// Truncated reports that the preview only covers the start of the file
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// ArchiveEntry describes a file or directory inside an archive
+type ArchiveEntry struct {
+	Name     string    `json:"name"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	Dir      bool      `json:"dir,omitempty"`
+}
+
+// Renderer turns a file into a preview
+type Renderer interface {
+	// Name identifies the renderer, e.g. "markdown"
+	Name() string
+	// Supports reports whether the renderer handles a file with the given
+	// MIME type and name
+	Supports(mimeType, fileName string) bool
+	// Render reads the file, size bytes named fileName, from r.
+	// Implementations should return promptly once ctx is done.
+	Render(ctx context.Context, fileName string, r io.ReadSeeker, size int64) (*Preview, error)
+}
+
+// Registry holds the available renderers in priority order
+type Registry struct {
+	renderers []Renderer
+}
+
+// NewRegistry creates a registry with the given renderers
+func NewRegistry(renderers ...Renderer) *Registry {
+	return &Registry{renderers: renderers}
+}
+
+// NewDefaultRegistry creates a registry with every built-in renderer
+func NewDefaultRegistry() *Registry {
+	return NewRegistry(
+		NewPDFRenderer(),
+		NewMarkdownRenderer(),
+		NewCSVRenderer(),
+		NewArchiveRenderer(),
+		NewCodeRenderer(),
+	)
+}
+
+// Register adds a renderer. Later registrations take precedence so callers
+// can override the built-in handling of a format.
+func (r *Registry) Register(renderer Renderer) {
+	r.renderers = append([]Renderer{renderer}, r.renderers...)
+}
+
+// Find returns the first renderer that supports the file
+func (r *Registry) Find(mimeType, fileName string) (Renderer, error) {
+	mimeType = baseMimeType(mimeType)
+	for _, renderer := range r.renderers {
+		if renderer.Supports(mimeType, fileName) {
+			return renderer, nil
+		}
+	}
+	return nil, ErrUnsupported
+}
+
+// baseMimeType strips parameters such as "; charset=utf-8"
+func baseMimeType(mimeType string) string {
+	if i := strings.IndexByte(mimeType, ';'); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// extension returns a file name's lower-cased extension
+func extension(fileName string) string {
+	return strings.ToLower(path.Ext(fileName))
+}
+
+// hasExtension reports whether the file name ends with one of the extensions
+func hasExtension(fileName string, extensions ...string) bool {
+	ext := extension(fileName)
+	for _, e := range extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// readLimited reads up to limit bytes, reporting whether more followed
+func readLimited(r io.Reader, limit int64) ([]byte, bool, error) {
+	data, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, false, err
+	}
+	if int64(len(data)) > limit {
+		return data[:limit], true, nil
+	}
+	return data, false, nil
+}
@@ -0,0 +1,43 @@
+package repository
+
+// accessCTE defines the common table expressions used to resolve what a user
+// can see. Prefix it with "WITH RECURSIVE" and pass a named @user_id argument.
+//
+//   - shared_folders: folders shared with the user, directly or through a
+//     group, and all their descendants
+//   - accessible_folders: folders the user owns, the folders of workspaces
+//     they are a member of and shared_folders
+//   - accessible_files: files the user owns, files inside accessible folders
+//     and files shared with the user directly or through a group
+const accessCTE = `
+shared_folders AS (
+	SELECT s.folder_id AS id
+	FROM shares s
+	JOIN folders f ON f.id = s.folder_id AND f.deleted_at IS NULL
+	WHERE ` + sharedWithUserSQL + ` AND s.deleted_at IS NULL AND COALESCE(s.file_id, 0) = 0
+	UNION
+	SELECT f.id
+	FROM folders f
+	JOIN shared_folders sf ON f.parent_folder_id = sf.id
+	WHERE f.deleted_at IS NULL
+),
+accessible_folders AS (
+	SELECT id FROM folders WHERE user_id = @user_id AND workspace_id IS NULL AND deleted_at IS NULL
+	UNION
+	SELECT f.id
+	FROM folders f
+	JOIN workspace_members m ON m.workspace_id = f.workspace_id
+	WHERE m.user_id = @user_id AND f.deleted_at IS NULL
+	UNION
+	SELECT id FROM shared_folders
+),
+accessible_files AS (
+	SELECT f.id
+	FROM files f
+	WHERE f.deleted_at IS NULL
+		AND ((f.user_id = @user_id AND f.workspace_id IS NULL) OR f.folder_id IN (SELECT id FROM accessible_folders))
+	UNION
+	SELECT s.file_id
+	FROM shares s
+	WHERE ` + sharedWithUserSQL + ` AND s.deleted_at IS NULL AND COALESCE(s.file_id, 0) <> 0
+)`
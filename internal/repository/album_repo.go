@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// albumPhotosCondition restricts album_photos (ap) to photos the album's
+// owner can still read. Pass the owner as a named @user_id argument and
+// prefix the query with accessCTE.
+const albumPhotosCondition = `f.deleted_at IS NULL AND f.scan_status <> @infected
+	AND f.id IN (SELECT id FROM accessible_files)`
+
+type AlbumRepository interface {
+	Create(ctx context.Context, album *model.Album) error
+	// FindByID returns an album with the count of photos its owner can read
+	FindByID(ctx context.Context, id uint) (*model.Album, error)
+	// ListByUser returns a user's albums, most recently updated first, with
+	// their photo counts
+	ListByUser(ctx context.Context, userID uint) ([]model.Album, error)
+	Update(ctx context.Context, album *model.Album) error
+	// Delete removes an album; its photos are left alone
+	Delete(ctx context.Context, album *model.Album) error
+	// AddPhotos places files in an album, skipping those already in it, and
+	// returns how many were added
+	AddPhotos(ctx context.Context, albumID uint, fileIDs []uint) (int64, error)
+	// RemovePhoto takes a file out of an album, clearing the cover if it was
+	// the file, and reports whether it was in the album
+	RemovePhoto(ctx context.Context, albumID, fileID uint) (bool, error)
+	// HasPhoto reports whether a file is in an album
+	HasPhoto(ctx context.Context, albumID, fileID uint) (bool, error)
+	// ListPhotos returns a page of the photos in an album that its owner can
+	// read, most recently added first, along with the total count. The album
+	// must have been loaded with FindByID, which counts them.
+	ListPhotos(ctx context.Context, album *model.Album, page, perPage int) ([]model.File, int64, error)
+	// FilterReadablePhotos returns the files among fileIDs that are images
+	// the user can read and that are not in the trash or quarantined
+	FilterReadablePhotos(ctx context.Context, userID uint, fileIDs []uint) ([]uint, error)
+}
+
+type albumRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewAlbumRepository(db *gorm.DB) AlbumRepository {
+	return &albumRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *albumRepositoryImpl) Create(ctx context.Context, album *model.Album) error {
+	return r.db.WithContext(ctx).Create(album).Error
+}
+
+func (r *albumRepositoryImpl) FindByID(ctx context.Context, id uint) (*model.Album, error) {
+	var album model.Album
+	err := r.db.WithContext(ctx).First(&album, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	err = r.db.WithContext(ctx).Raw(`
+WITH RECURSIVE `+accessCTE+`
+SELECT COUNT(*)
+FROM album_photos ap
+JOIN files f ON f.id = ap.file_id AND `+albumPhotosCondition+`
+WHERE ap.album_id = @album_id`, albumArgs(&album)).Scan(&album.PhotoCount).Error
+	return &album, err
+}
+
+func (r *albumRepositoryImpl) ListByUser(ctx context.Context, userID uint) ([]model.Album, error) {
+	var albums []model.Album
+	err := r.db.WithContext(ctx).Raw(`
+WITH RECURSIVE `+accessCTE+`
+SELECT a.*, COUNT(f.id) AS photo_count
+FROM albums a
+LEFT JOIN album_photos ap ON ap.album_id = a.id
+LEFT JOIN files f ON f.id = ap.file_id AND `+albumPhotosCondition+`
+WHERE a.user_id = @user_id
+GROUP BY a.id
+ORDER BY a.updated_at DESC, a.id DESC`, map[string]interface{}{
+		"user_id":  userID,
+		"infected": model.ScanStatusInfected,
+	}).Scan(&albums).Error
+	return albums, err
+}
+
+func (r *albumRepositoryImpl) Update(ctx context.Context, album *model.Album) error {
+	return r.db.WithContext(ctx).Save(album).Error
+}
+
+func (r *albumRepositoryImpl) Delete(ctx context.Context, album *model.Album) error {
+	return r.db.WithContext(ctx).Delete(album).Error
+}
+
+func (r *albumRepositoryImpl) AddPhotos(ctx context.Context, albumID uint, fileIDs []uint) (int64, error) {
+	photos := make([]model.AlbumPhoto, 0, len(fileIDs))
+	for _, id := range fileIDs {
+		photos = append(photos, model.AlbumPhoto{AlbumID: albumID, FileID: id})
+	}
+
+	var added int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&photos)
+		if result.Error != nil {
+			return result.Error
+		}
+		added = result.RowsAffected
+		// Bump the album so recently changed albums list first
+		return tx.Model(&model.Album{}).Where("id = ?", albumID).Update("updated_at", gorm.Expr("NOW()")).Error
+	})
+	return added, err
+}
+
+func (r *albumRepositoryImpl) RemovePhoto(ctx context.Context, albumID, fileID uint) (bool, error) {
+	var removed bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Delete(&model.AlbumPhoto{}, "album_id = ? AND file_id = ?", albumID, fileID)
+		if result.Error != nil {
+			return result.Error
+		}
+		removed = result.RowsAffected > 0
+		if !removed {
+			return nil
+		}
+		return tx.Model(&model.Album{}).Where("id = ?", albumID).Updates(map[string]interface{}{
+			"cover_file_id": gorm.Expr("NULLIF(cover_file_id, ?)", fileID),
+			"updated_at":    gorm.Expr("NOW()"),
+		}).Error
+	})
+	return removed, err
+}
+
+func (r *albumRepositoryImpl) HasPhoto(ctx context.Context, albumID, fileID uint) (bool, error) {
+	var photo model.AlbumPhoto
+	err := r.db.WithContext(ctx).First(&photo, "album_id = ? AND file_id = ?", albumID, fileID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *albumRepositoryImpl) ListPhotos(ctx context.Context, album *model.Album, page, perPage int) ([]model.File, int64, error) {
+	args := albumArgs(album)
+	args["limit"] = perPage
+	args["offset"] = (page - 1) * perPage
+
+	var photos []model.File
+	err := r.db.WithContext(ctx).Raw(`
+WITH RECURSIVE `+accessCTE+`
+SELECT f.*
+FROM album_photos ap
+JOIN files f ON f.id = ap.file_id AND `+albumPhotosCondition+`
+WHERE ap.album_id = @album_id
+ORDER BY ap.added_at DESC, f.id DESC
+LIMIT @limit OFFSET @offset`, args).Scan(&photos).Error
+	if err != nil || len(photos) == 0 {
+		return photos, album.PhotoCount, err
+	}
+
+	ids := make([]uint, len(photos))
+	for i := range photos {
+		ids[i] = photos[i].ID
+	}
+	var metadata []model.PhotoMetadata
+	if err := r.db.WithContext(ctx).Where("file_id IN ?", ids).Find(&metadata).Error; err != nil {
+		return nil, 0, err
+	}
+	byFile := make(map[uint]*model.PhotoMetadata, len(metadata))
+	for i := range metadata {
+		byFile[metadata[i].FileID] = &metadata[i]
+	}
+	for i := range photos {
+		photos[i].Photo = byFile[photos[i].ID]
+	}
+	return photos, album.PhotoCount, nil
+}
+
+func (r *albumRepositoryImpl) FilterReadablePhotos(ctx context.Context, userID uint, fileIDs []uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Raw(`
+WITH RECURSIVE `+accessCTE+`
+SELECT f.id
+FROM files f
+WHERE f.id IN @file_ids AND f.file_type = @image AND `+albumPhotosCondition,
+		map[string]interface{}{
+			"user_id":  userID,
+			"file_ids": fileIDs,
+			"image":    model.FileTypeImage,
+			"infected": model.ScanStatusInfected,
+		}).Scan(&ids).Error
+	return ids, err
+}
+
+// albumArgs returns the named arguments selecting an album's photos that its
+// owner can read
+func albumArgs(album *model.Album) map[string]interface{} {
+	return map[string]interface{}{
+		"user_id":  album.UserID,
+		"album_id": album.ID,
+		"infected": model.ScanStatusInfected,
+	}
+}
@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type AppPasswordRepository interface {
+	Create(ctx context.Context, password *model.AppPassword) error
+	ListByUser(ctx context.Context, userID uint) ([]model.AppPassword, error)
+	FindByHash(ctx context.Context, hash string) (*model.AppPassword, error)
+	// Delete removes one of the user's app passwords, reporting whether it existed
+	Delete(ctx context.Context, userID, id uint) (bool, error)
+	Touch(ctx context.Context, id uint, usedAt time.Time) error
+}
+
+type appPasswordRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewAppPasswordRepository(db *gorm.DB) AppPasswordRepository {
+	return &appPasswordRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *appPasswordRepositoryImpl) Create(ctx context.Context, password *model.AppPassword) error {
+	return r.db.WithContext(ctx).Create(password).Error
+}
+
+func (r *appPasswordRepositoryImpl) ListByUser(ctx context.Context, userID uint) ([]model.AppPassword, error) {
+	var passwords []model.AppPassword
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id ASC").Find(&passwords).Error
+	return passwords, err
+}
+
+func (r *appPasswordRepositoryImpl) FindByHash(ctx context.Context, hash string) (*model.AppPassword, error) {
+	var password model.AppPassword
+	err := r.db.WithContext(ctx).Where("password_hash = ?", hash).First(&password).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &password, nil
+}
+
+func (r *appPasswordRepositoryImpl) Delete(ctx context.Context, userID, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Where("user_id = ?", userID).Delete(&model.AppPassword{}, id)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *appPasswordRepositoryImpl) Touch(ctx context.Context, id uint, usedAt time.Time) error {
+	return r.db.WithContext(ctx).Model(&model.AppPassword{}).Where("id = ?", id).Update("last_used_at", usedAt).Error
+}
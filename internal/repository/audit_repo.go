@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// auditChainLock is the advisory lock key serialising appends to the audit
+// hash chain
+const auditChainLock = 0x61756474
+
+const auditSealBatch = 500
+
+// AuditSealFunc fills in the chain fields of an entry so it follows the
+// entry with hash prevHash. prevHash is empty for the first entry.
+type AuditSealFunc func(entry *model.AuditLog, prevHash string) error
+
+type AuditRepository interface {
+	// Append inserts entry at the end of the hash chain. Appends are
+	// serialised so that ID order and chain order agree.
+	Append(ctx context.Context, entry *model.AuditLog, seal AuditSealFunc) error
+	// Head seals any entries written before the chain existed and returns
+	// the last entry of the chain
+	Head(ctx context.Context, seal AuditSealFunc) (*model.AuditChainHead, error)
+	CreateCheckpoint(ctx context.Context, checkpoint *model.AuditCheckpoint) error
+	LatestCheckpoint(ctx context.Context) (*model.AuditCheckpoint, error)
+	ListCheckpoints(ctx context.Context) ([]model.AuditCheckpoint, error)
+	List(ctx context.Context, filter *model.AuditLogFilter) ([]model.AuditLog, int64, error)
+	ListAfter(ctx context.Context, filter *model.AuditLogFilter, afterID uint, limit int) ([]model.AuditLog, error)
+}
+
+type auditRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *auditRepositoryImpl) Append(ctx context.Context, entry *model.AuditLog, seal AuditSealFunc) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		prevHash, err := r.lockTail(tx, seal)
+		if err != nil {
+			return err
+		}
+		if err := seal(entry, prevHash); err != nil {
+			return err
+		}
+		return tx.Create(entry).Error
+	})
+}
+
+func (r *auditRepositoryImpl) Head(ctx context.Context, seal AuditSealFunc) (*model.AuditChainHead, error) {
+	head := &model.AuditChainHead{}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if _, err := r.lockTail(tx, seal); err != nil {
+			return err
+		}
+
+		var tail model.AuditLog
+		result := tx.Order("id DESC").Limit(1).Find(&tail)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		head.LastEntryID = tail.ID
+		head.LastHash = tail.Hash
+		return tx.Model(&model.AuditLog{}).Where("id <= ?", tail.ID).Count(&head.EntryCount).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return head, nil
+}
+
+// lockTail takes the chain lock for the rest of the transaction and returns
+// the hash of the last entry, sealing any unsealed entries first
+func (r *auditRepositoryImpl) lockTail(tx *gorm.DB, seal AuditSealFunc) (string, error) {
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", auditChainLock).Error; err != nil {
+		return "", err
+	}
+
+	var tail model.AuditLog
+	result := tx.Select("id", "hash").Order("id DESC").Limit(1).Find(&tail)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return "", result.Error
+	}
+	if tail.Hash != "" {
+		return tail.Hash, nil
+	}
+	return r.sealPending(tx, seal)
+}
+
+// sealPending chains entries that were written before the hash chain was
+// introduced, in ID order, and returns the hash of the last one
+func (r *auditRepositoryImpl) sealPending(tx *gorm.DB, seal AuditSealFunc) (string, error) {
+	var last model.AuditLog
+	if err := tx.Select("id", "hash").Where("hash <> ''").Order("id DESC").Limit(1).Find(&last).Error; err != nil {
+		return "", err
+	}
+	prevHash := last.Hash
+
+	afterID := last.ID
+	for {
+		var entries []model.AuditLog
+		err := tx.Where("hash = '' AND id > ?", afterID).
+			Order("id ASC").
+			Limit(auditSealBatch).
+			Find(&entries).Error
+		if err != nil {
+			return "", err
+		}
+
+		for i := range entries {
+			entry := &entries[i]
+			if err := seal(entry, prevHash); err != nil {
+				return "", err
+			}
+			err := tx.Model(&model.AuditLog{}).Where("id = ?", entry.ID).Updates(map[string]interface{}{
+				"prev_hash": entry.PrevHash,
+				"hash":      entry.Hash,
+				"hmac":      entry.HMAC,
+			}).Error
+			if err != nil {
+				return "", err
+			}
+			prevHash = entry.Hash
+			afterID = entry.ID
+		}
+
+		if len(entries) < auditSealBatch {
+			return prevHash, nil
+		}
+	}
+}
+
+func (r *auditRepositoryImpl) CreateCheckpoint(ctx context.Context, checkpoint *model.AuditCheckpoint) error {
+	return r.db.WithContext(ctx).Create(checkpoint).Error
+}
+
+// LatestCheckpoint returns the most recent checkpoint, or nil if none exist
+func (r *auditRepositoryImpl) LatestCheckpoint(ctx context.Context) (*model.AuditCheckpoint, error) {
+	var checkpoint model.AuditCheckpoint
+	result := r.db.WithContext(ctx).Order("id DESC").Limit(1).Find(&checkpoint)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return nil, result.Error
+	}
+	return &checkpoint, nil
+}
+
+// ListCheckpoints returns every checkpoint, oldest first
+func (r *auditRepositoryImpl) ListCheckpoints(ctx context.Context) ([]model.AuditCheckpoint, error) {
+	var checkpoints []model.AuditCheckpoint
+	err := r.db.WithContext(ctx).Order("id ASC").Find(&checkpoints).Error
+	return checkpoints, err
+}
+
+// List returns a page of audit entries, newest first, along with the total count
+func (r *auditRepositoryImpl) List(ctx context.Context, filter *model.AuditLogFilter) ([]model.AuditLog, int64, error) {
+	query := r.filtered(ctx, filter)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entries []model.AuditLog
+	err := query.Order("id DESC").
+		Limit(filter.PerPage).
+		Offset((filter.Page - 1) * filter.PerPage).
+		Find(&entries).Error
+	return entries, total, err
+}
+
+// ListAfter returns up to limit entries with an ID greater than afterID in
+// ascending order, for streaming exports
+func (r *auditRepositoryImpl) ListAfter(ctx context.Context, filter *model.AuditLogFilter, afterID uint, limit int) ([]model.AuditLog, error) {
+	var entries []model.AuditLog
+	err := r.filtered(ctx, filter).
+		Where("id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&entries).Error
+	return entries, err
+}
+
+// filtered applies the filter conditions to an audit_logs query
+func (r *auditRepositoryImpl) filtered(ctx context.Context, filter *model.AuditLogFilter) *gorm.DB {
+	query := r.db.WithContext(ctx).Model(&model.AuditLog{})
+
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.IP != "" {
+		query = query.Where("ip = ?", filter.IP)
+	}
+	if filter.RequestID != "" {
+		query = query.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.From != "" {
+		query = query.Where("created_at >= CAST(? AS date)", filter.From)
+	}
+	if filter.To != "" {
+		query = query.Where("created_at < CAST(? AS date) + 1", filter.To)
+	}
+
+	return query
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type BatchRepository interface {
+	Create(ctx context.Context, job *model.BatchJob) error
+	FindByID(ctx context.Context, id uint) (*model.BatchJob, error)
+	// Update saves a job's progress
+	Update(ctx context.Context, job *model.BatchJob) error
+	// Claim marks a queued job as running, reporting whether it was still
+	// queued
+	Claim(ctx context.Context, job *model.BatchJob) (bool, error)
+	// ListQueuedIDs returns up to limit queued jobs, oldest first
+	ListQueuedIDs(ctx context.Context, limit int) ([]uint, error)
+	// FailRunning marks every running job as failed with the given error,
+	// returning how many were
+	FailRunning(ctx context.Context, message string) (int64, error)
+	// DeleteFinishedBefore removes jobs that finished before t
+	DeleteFinishedBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+type batchRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewBatchRepository(db *gorm.DB) BatchRepository {
+	return &batchRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *batchRepositoryImpl) Create(ctx context.Context, job *model.BatchJob) error {
+	return r.db.WithContext(ctx).Omit("User").Create(job).Error
+}
+
+func (r *batchRepositoryImpl) FindByID(ctx context.Context, id uint) (*model.BatchJob, error) {
+	var job model.BatchJob
+	err := r.db.WithContext(ctx).First(&job, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *batchRepositoryImpl) Update(ctx context.Context, job *model.BatchJob) error {
+	return r.db.WithContext(ctx).Omit("User").Save(job).Error
+}
+
+func (r *batchRepositoryImpl) Claim(ctx context.Context, job *model.BatchJob) (bool, error) {
+	now := time.Now()
+	result := r.db.WithContext(ctx).
+		Model(job).
+		Where("status = ?", model.BatchQueued).
+		Updates(map[string]interface{}{"status": model.BatchRunning, "started_at": now})
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false, result.Error
+	}
+	job.Status = model.BatchRunning
+	job.StartedAt = &now
+	return true, nil
+}
+
+func (r *batchRepositoryImpl) ListQueuedIDs(ctx context.Context, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).
+		Model(&model.BatchJob{}).
+		Where("status = ?", model.BatchQueued).
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *batchRepositoryImpl) FailRunning(ctx context.Context, message string) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.BatchJob{}).
+		Where("status = ?", model.BatchRunning).
+		Updates(map[string]interface{}{
+			"status":      model.BatchFailed,
+			"error":       message,
+			"finished_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+func (r *batchRepositoryImpl) DeleteFinishedBefore(ctx context.Context, t time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Delete(&model.BatchJob{}, "finished_at < ?", t)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// changeJournalLock is the advisory lock key serialising writes to the change
+// journal. Without it a transaction could commit a lower ID after a client
+// has already read past it, and the client would never see that change.
+const changeJournalLock = 0x63686e67
+
+type ChangeRepository interface {
+	CreateBatch(ctx context.Context, changes []model.Change) error
+	// ListAfter returns up to limit of the user's changes with an ID above
+	// cursor, oldest first
+	ListAfter(ctx context.Context, userID, cursor uint, limit int) ([]model.Change, error)
+	// Latest returns the ID of the user's newest change, or 0 if there is none
+	Latest(ctx context.Context, userID uint) (uint, error)
+}
+
+type changeRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewChangeRepository(db *gorm.DB) ChangeRepository {
+	return &changeRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *changeRepositoryImpl) CreateBatch(ctx context.Context, changes []model.Change) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", changeJournalLock).Error; err != nil {
+			return err
+		}
+		return tx.Create(&changes).Error
+	})
+}
+
+func (r *changeRepositoryImpl) ListAfter(ctx context.Context, userID, cursor uint, limit int) ([]model.Change, error) {
+	var changes []model.Change
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND id > ?", userID, cursor).
+		Order("id ASC").
+		Limit(limit).
+		Find(&changes).Error
+	return changes, err
+}
+
+func (r *changeRepositoryImpl) Latest(ctx context.Context, userID uint) (uint, error) {
+	var latest uint
+	err := r.db.WithContext(ctx).
+		Model(&model.Change{}).
+		Where("user_id = ?", userID).
+		Select("COALESCE(MAX(id), 0)").
+		Scan(&latest).Error
+	return latest, err
+}
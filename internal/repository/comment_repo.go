@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type CommentRepository interface {
+	// Create stores a comment along with its mentions
+	Create(ctx context.Context, comment *model.Comment) error
+	// FindByID returns a comment with its author and mentions
+	FindByID(ctx context.Context, id uint) (*model.Comment, error)
+	// ListThreads returns a file's threads, oldest first, each with its
+	// replies, authors and mentions
+	ListThreads(ctx context.Context, fileID uint) ([]model.Comment, error)
+	// UpdateBody saves an edited comment, keeping the previous body as a
+	// revision and replacing the mentions
+	UpdateBody(ctx context.Context, comment *model.Comment, previous string) error
+	// SetResolved saves a thread's resolution
+	SetResolved(ctx context.Context, comment *model.Comment) error
+	// Delete removes a comment along with its replies
+	Delete(ctx context.Context, comment *model.Comment) error
+	// ListRevisions returns a comment's earlier bodies, oldest first
+	ListRevisions(ctx context.Context, commentID uint) ([]model.CommentRevision, error)
+}
+
+type commentRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewCommentRepository(db *gorm.DB) CommentRepository {
+	return &commentRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *commentRepositoryImpl) Create(ctx context.Context, comment *model.Comment) error {
+	return r.db.WithContext(ctx).Create(comment).Error
+}
+
+func (r *commentRepositoryImpl) FindByID(ctx context.Context, id uint) (*model.Comment, error) {
+	var comment model.Comment
+	err := r.db.WithContext(ctx).Preload("User").Preload("Mentions.User").First(&comment, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func (r *commentRepositoryImpl) ListThreads(ctx context.Context, fileID uint) ([]model.Comment, error) {
+	var threads []model.Comment
+	err := r.db.WithContext(ctx).
+		Preload("User").
+		Preload("Mentions.User").
+		Preload("Replies", func(db *gorm.DB) *gorm.DB { return db.Order("id") }).
+		Preload("Replies.User").
+		Preload("Replies.Mentions.User").
+		Where("file_id = ? AND parent_id IS NULL", fileID).
+		Order("id").
+		Find(&threads).Error
+	return threads, err
+}
+
+func (r *commentRepositoryImpl) UpdateBody(ctx context.Context, comment *model.Comment, previous string) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		revision := &model.CommentRevision{CommentID: comment.ID, Body: previous}
+		if err := tx.Create(revision).Error; err != nil {
+			return err
+		}
+		err := tx.Model(comment).Updates(map[string]interface{}{
+			"body":      comment.Body,
+			"edited_at": comment.EditedAt,
+		}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("comment_id = ?", comment.ID).Delete(&model.CommentMention{}).Error; err != nil {
+			return err
+		}
+		if len(comment.Mentions) == 0 {
+			return nil
+		}
+		for i := range comment.Mentions {
+			comment.Mentions[i].CommentID = comment.ID
+		}
+		return tx.Omit("User").Create(&comment.Mentions).Error
+	})
+}
+
+func (r *commentRepositoryImpl) SetResolved(ctx context.Context, comment *model.Comment) error {
+	return r.db.WithContext(ctx).Model(comment).Updates(map[string]interface{}{
+		"resolved_at":    comment.ResolvedAt,
+		"resolved_by_id": comment.ResolvedByID,
+	}).Error
+}
+
+func (r *commentRepositoryImpl) Delete(ctx context.Context, comment *model.Comment) error {
+	return r.db.WithContext(ctx).Where("id = ? OR parent_id = ?", comment.ID, comment.ID).Delete(&model.Comment{}).Error
+}
+
+func (r *commentRepositoryImpl) ListRevisions(ctx context.Context, commentID uint) ([]model.CommentRevision, error) {
+	var revisions []model.CommentRevision
+	err := r.db.WithContext(ctx).Where("comment_id = ?", commentID).Order("id").Find(&revisions).Error
+	return revisions, err
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type FileContentRepository interface {
+	FindByFileID(ctx context.Context, fileID uint) (*model.FileContent, error)
+	MarkPending(ctx context.Context, fileID uint) error
+	Save(ctx context.Context, content *model.FileContent) error
+	ListPendingFileIDs(ctx context.Context, limit int) ([]uint, error)
+}
+
+type fileContentRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewFileContentRepository(db *gorm.DB) FileContentRepository {
+	return &fileContentRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *fileContentRepositoryImpl) FindByFileID(ctx context.Context, fileID uint) (*model.FileContent, error) {
+	var content model.FileContent
+	err := r.db.WithContext(ctx).First(&content, "file_id = ?", fileID).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &content, nil
+}
+
+// MarkPending resets a file's index entry so it gets extracted again
+func (r *fileContentRepositoryImpl) MarkPending(ctx context.Context, fileID uint) error {
+	content := model.FileContent{FileID: fileID, Status: model.IndexStatusPending}
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "file_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"status": model.IndexStatusPending, "error": ""}),
+	}).Create(&content).Error
+}
+
+// Save stores the extracted content and rebuilds its tsvector
+func (r *fileContentRepositoryImpl) Save(ctx context.Context, content *model.FileContent) error {
+	return r.db.WithContext(ctx).Exec(`
+INSERT INTO file_contents (file_id, status, extractor, content, content_tsv, truncated, error, indexed_at, created_at, updated_at)
+VALUES (@file_id, @status, @extractor, @content, to_tsvector('english', @content), @truncated, @error, @indexed_at, NOW(), NOW())
+ON CONFLICT (file_id) DO UPDATE SET
+	status = EXCLUDED.status,
+	extractor = EXCLUDED.extractor,
+	content = EXCLUDED.content,
+	content_tsv = EXCLUDED.content_tsv,
+	truncated = EXCLUDED.truncated,
+	error = EXCLUDED.error,
+	indexed_at = EXCLUDED.indexed_at,
+	updated_at = NOW()`, map[string]interface{}{
+		"file_id":    content.FileID,
+		"status":     content.Status,
+		"extractor":  content.Extractor,
+		"content":    content.Content,
+		"truncated":  content.Truncated,
+		"error":      content.Error,
+		"indexed_at": content.IndexedAt,
+	}).Error
+}
+
+// ListPendingFileIDs returns files that are waiting to be indexed, including
+// files that have never been seen by the indexer
+func (r *fileContentRepositoryImpl) ListPendingFileIDs(ctx context.Context, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Raw(`
+SELECT f.id
+FROM files f
+LEFT JOIN file_contents fc ON fc.file_id = f.id
+WHERE f.deleted_at IS NULL AND (fc.file_id IS NULL OR fc.status = ?)
+ORDER BY f.id
+LIMIT ?`, model.IndexStatusPending, limit).Scan(&ids).Error
+	return ids, err
+}
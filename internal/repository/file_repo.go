@@ -0,0 +1,236 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type FileRepository interface {
+	Create(ctx context.Context, file *model.File) error
+	FindByID(ctx context.Context, id uint) (*model.File, error)
+	Update(ctx context.Context, file *model.File) error
+	// UpdateIfUnmodified saves a file only if it was last updated at
+	// updatedAt, reporting whether it was saved
+	UpdateIfUnmodified(ctx context.Context, file *model.File, updatedAt time.Time) (bool, error)
+	// Delete moves a file to the trash, setting its DeletedAt
+	Delete(ctx context.Context, file *model.File) error
+	// FindDeleted returns a file that is in the trash, or nil
+	FindDeleted(ctx context.Context, id uint) (*model.File, error)
+	// Restore takes a file out of the trash
+	Restore(ctx context.Context, file *model.File) error
+	ListByFolder(ctx context.Context, folderID uint) ([]model.File, error)
+	// ListWrappedByOtherKey returns up to limit encrypted files, trashed ones
+	// included, whose data key is not wrapped by keyID, ordered by ID and
+	// starting after afterID
+	ListWrappedByOtherKey(ctx context.Context, keyID string, afterID uint, limit int) ([]model.File, error)
+	// UpdateDataKey replaces a file's wrapped data key if it is still wrapped
+	// by previousKeyID, reporting whether it was replaced. The update time is
+	// left alone as the content has not changed.
+	UpdateDataKey(ctx context.Context, fileID uint, previousKeyID, keyID string, dataKey []byte) (bool, error)
+	// ListPendingScanIDs returns up to limit files waiting for a malware scan
+	ListPendingScanIDs(ctx context.Context, limit int) ([]uint, error)
+	// UpdateScanResult saves a file's scan status, signature and scan time if
+	// its content is still the blob that was scanned, reporting whether it
+	// was saved. The update time is left alone.
+	UpdateScanResult(ctx context.Context, file *model.File) (bool, error)
+	// ListUnhashed returns up to limit files, trashed ones included, whose
+	// content hash has not been computed, ordered by ID and starting after
+	// afterID
+	ListUnhashed(ctx context.Context, afterID uint, limit int) ([]model.File, error)
+	// UpdateContentHash saves a file's content hash if its content is still
+	// the blob that was hashed, reporting whether it was saved. The update
+	// time is left alone.
+	UpdateContentHash(ctx context.Context, file *model.File) (bool, error)
+	// ListUnverified returns up to limit hashed files, trashed ones included,
+	// not scrubbed since verifiedBefore, ordered by ID and starting after
+	// afterID
+	ListUnverified(ctx context.Context, verifiedBefore time.Time, afterID uint, limit int) ([]model.File, error)
+	// UpdateIntegrity saves a file's integrity status and verification time
+	// if its content is still the blob that was checked, reporting whether
+	// it was saved. The update time is left alone.
+	UpdateIntegrity(ctx context.Context, file *model.File) (bool, error)
+	// ListDamaged returns a page of files, trashed ones included, found
+	// corrupt or missing by the scrubber, along with the total
+	ListDamaged(ctx context.Context, filter *model.IntegrityFilter) ([]model.File, int64, error)
+}
+
+type fileRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewFileRepository(db *gorm.DB) FileRepository {
+	return &fileRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *fileRepositoryImpl) Create(ctx context.Context, file *model.File) error {
+	return r.db.WithContext(ctx).Create(file).Error
+}
+
+func (r *fileRepositoryImpl) FindByID(ctx context.Context, id uint) (*model.File, error) {
+	var file model.File
+	err := r.db.WithContext(ctx).First(&file, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &file, nil
+}
+
+func (r *fileRepositoryImpl) Update(ctx context.Context, file *model.File) error {
+	return r.db.WithContext(ctx).Save(file).Error
+}
+
+func (r *fileRepositoryImpl) UpdateIfUnmodified(ctx context.Context, file *model.File, updatedAt time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(file).
+		Where("updated_at = ?", updatedAt).
+		Select("*").
+		Omit("created_at").
+		Updates(file)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *fileRepositoryImpl) Delete(ctx context.Context, file *model.File) error {
+	return r.db.WithContext(ctx).Delete(file).Error
+}
+
+func (r *fileRepositoryImpl) FindDeleted(ctx context.Context, id uint) (*model.File, error) {
+	var file model.File
+	err := r.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL").First(&file, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &file, nil
+}
+
+func (r *fileRepositoryImpl) Restore(ctx context.Context, file *model.File) error {
+	if err := r.db.WithContext(ctx).Unscoped().Model(file).UpdateColumn("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	file.DeletedAt = gorm.DeletedAt{}
+	return nil
+}
+
+func (r *fileRepositoryImpl) ListByFolder(ctx context.Context, folderID uint) ([]model.File, error) {
+	var files []model.File
+	err := r.db.WithContext(ctx).Where("folder_id = ?", folderID).Order("file_name ASC").Find(&files).Error
+	return files, err
+}
+
+func (r *fileRepositoryImpl) ListWrappedByOtherKey(ctx context.Context, keyID string, afterID uint, limit int) ([]model.File, error) {
+	var files []model.File
+	err := r.db.WithContext(ctx).
+		Unscoped().
+		Where("encryption_key_id <> '' AND encryption_key_id <> ? AND id > ?", keyID, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&files).Error
+	return files, err
+}
+
+func (r *fileRepositoryImpl) UpdateDataKey(ctx context.Context, fileID uint, previousKeyID, keyID string, dataKey []byte) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Unscoped().
+		Model(&model.File{}).
+		Where("id = ? AND encryption_key_id = ?", fileID, previousKeyID).
+		UpdateColumns(map[string]interface{}{"encryption_key_id": keyID, "data_key": dataKey})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *fileRepositoryImpl) ListPendingScanIDs(ctx context.Context, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).
+		Model(&model.File{}).
+		Where("scan_status = ?", model.ScanStatusPending).
+		Order("id ASC").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+func (r *fileRepositoryImpl) UpdateScanResult(ctx context.Context, file *model.File) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.File{}).
+		Where("id = ? AND file_url = ?", file.ID, file.FileURL).
+		UpdateColumns(map[string]interface{}{
+			"scan_status":    file.ScanStatus,
+			"scan_signature": file.ScanSignature,
+			"scanned_at":     file.ScannedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *fileRepositoryImpl) ListUnhashed(ctx context.Context, afterID uint, limit int) ([]model.File, error) {
+	var files []model.File
+	err := r.db.WithContext(ctx).
+		Unscoped().
+		Where("content_hash = '' AND id > ?", afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&files).Error
+	return files, err
+}
+
+func (r *fileRepositoryImpl) UpdateContentHash(ctx context.Context, file *model.File) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Unscoped().
+		Model(&model.File{}).
+		Where("id = ? AND file_url = ?", file.ID, file.FileURL).
+		UpdateColumn("content_hash", file.ContentHash)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *fileRepositoryImpl) ListUnverified(ctx context.Context, verifiedBefore time.Time, afterID uint, limit int) ([]model.File, error) {
+	var files []model.File
+	err := r.db.WithContext(ctx).
+		Unscoped().
+		Where("content_hash <> '' AND (verified_at IS NULL OR verified_at < ?) AND id > ?", verifiedBefore, afterID).
+		Order("id ASC").
+		Limit(limit).
+		Find(&files).Error
+	return files, err
+}
+
+func (r *fileRepositoryImpl) UpdateIntegrity(ctx context.Context, file *model.File) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Unscoped().
+		Model(&model.File{}).
+		Where("id = ? AND file_url = ?", file.ID, file.FileURL).
+		UpdateColumns(map[string]interface{}{
+			"integrity_status": file.IntegrityStatus,
+			"verified_at":      file.VerifiedAt,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *fileRepositoryImpl) ListDamaged(ctx context.Context, filter *model.IntegrityFilter) ([]model.File, int64, error) {
+	query := r.db.WithContext(ctx).Unscoped().Model(&model.File{})
+	if filter.Status != "" {
+		query = query.Where("integrity_status = ?", filter.Status)
+	} else {
+		query = query.Where("integrity_status IN ?", []model.IntegrityStatus{model.IntegrityCorrupt, model.IntegrityMissing})
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var files []model.File
+	err := query.Order("id ASC").
+		Limit(filter.PerPage).
+		Offset((filter.Page - 1) * filter.PerPage).
+		Find(&files).Error
+	return files, total, err
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type FolderRepository interface {
+	Create(ctx context.Context, folder *model.Folder) error
+	FindByID(ctx context.Context, id uint) (*model.Folder, error)
+	FindRoot(ctx context.Context, userID uint) (*model.Folder, error)
+	Update(ctx context.Context, folder *model.Folder) error
+	// Delete moves a folder to the trash, setting its DeletedAt
+	Delete(ctx context.Context, folder *model.Folder) error
+	// FindDeleted returns a folder that is in the trash, or nil
+	FindDeleted(ctx context.Context, id uint) (*model.Folder, error)
+	// Restore takes a folder, and with it everything beneath it, out of the
+	// trash
+	Restore(ctx context.Context, folder *model.Folder) error
+	ListChildren(ctx context.Context, folderID uint) ([]model.Folder, error)
+	// IsDescendant reports whether folderID is ancestorID or lies beneath it
+	IsDescendant(ctx context.Context, folderID, ancestorID uint) (bool, error)
+}
+
+type folderRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewFolderRepository(db *gorm.DB) FolderRepository {
+	return &folderRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *folderRepositoryImpl) Create(ctx context.Context, folder *model.Folder) error {
+	return r.db.WithContext(ctx).Create(folder).Error
+}
+
+func (r *folderRepositoryImpl) FindByID(ctx context.Context, id uint) (*model.Folder, error) {
+	var folder model.Folder
+	err := r.db.WithContext(ctx).First(&folder, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// FindRoot returns the user's root folder, the one folder without a parent
+// outside any workspace
+func (r *folderRepositoryImpl) FindRoot(ctx context.Context, userID uint) (*model.Folder, error) {
+	var folder model.Folder
+	err := r.db.WithContext(ctx).
+		Where("user_id = ? AND parent_folder_id IS NULL AND workspace_id IS NULL", userID).
+		Order("id").
+		First(&folder).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &folder, nil
+}
+
+func (r *folderRepositoryImpl) Update(ctx context.Context, folder *model.Folder) error {
+	return r.db.WithContext(ctx).Save(folder).Error
+}
+
+func (r *folderRepositoryImpl) Delete(ctx context.Context, folder *model.Folder) error {
+	return r.db.WithContext(ctx).Delete(folder).Error
+}
+
+func (r *folderRepositoryImpl) FindDeleted(ctx context.Context, id uint) (*model.Folder, error) {
+	var folder model.Folder
+	err := r.db.WithContext(ctx).Unscoped().Where("deleted_at IS NOT NULL").First(&folder, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &folder, nil
+}
+
+func (r *folderRepositoryImpl) Restore(ctx context.Context, folder *model.Folder) error {
+	if err := r.db.WithContext(ctx).Unscoped().Model(folder).UpdateColumn("deleted_at", nil).Error; err != nil {
+		return err
+	}
+	folder.DeletedAt = gorm.DeletedAt{}
+	return nil
+}
+
+func (r *folderRepositoryImpl) ListChildren(ctx context.Context, folderID uint) ([]model.Folder, error) {
+	var folders []model.Folder
+	err := r.db.WithContext(ctx).Where("parent_folder_id = ?", folderID).Order("folder_name ASC").Find(&folders).Error
+	return folders, err
+}
+
+func (r *folderRepositoryImpl) IsDescendant(ctx context.Context, folderID, ancestorID uint) (bool, error) {
+	sql := `
+WITH RECURSIVE ancestors AS (
+	SELECT id, parent_folder_id FROM folders WHERE id = @folder_id
+	UNION
+	SELECT f.id, f.parent_folder_id
+	FROM folders f
+	JOIN ancestors a ON f.id = a.parent_folder_id
+)
+SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = @ancestor_id)`
+
+	var found bool
+	err := r.db.WithContext(ctx).Raw(sql, map[string]interface{}{
+		"folder_id":   folderID,
+		"ancestor_id": ancestorID,
+	}).Scan(&found).Error
+	return found, err
+}
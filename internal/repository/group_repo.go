@@ -0,0 +1,104 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type GroupRepository interface {
+	Create(ctx context.Context, group *model.Group) error
+	FindByID(ctx context.Context, id uint) (*model.Group, error)
+	Update(ctx context.Context, group *model.Group) error
+	// Delete deletes a group along with its memberships and the shares made
+	// with it
+	Delete(ctx context.Context, group *model.Group) error
+	// ListVisible returns the groups a user owns or belongs to and every
+	// admin-managed group, by name
+	ListVisible(ctx context.Context, userID uint) ([]model.Group, error)
+
+	FindMember(ctx context.Context, groupID, userID uint) (*model.GroupMember, error)
+	// ListMembers returns a group's members with their users
+	ListMembers(ctx context.Context, groupID uint) ([]model.GroupMember, error)
+	AddMember(ctx context.Context, member *model.GroupMember) error
+	DeleteMember(ctx context.Context, member *model.GroupMember) error
+}
+
+type groupRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewGroupRepository(db *gorm.DB) GroupRepository {
+	return &groupRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *groupRepositoryImpl) Create(ctx context.Context, group *model.Group) error {
+	return r.db.WithContext(ctx).Create(group).Error
+}
+
+func (r *groupRepositoryImpl) FindByID(ctx context.Context, id uint) (*model.Group, error) {
+	var group model.Group
+	err := r.db.WithContext(ctx).First(&group, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &group, nil
+}
+
+func (r *groupRepositoryImpl) Update(ctx context.Context, group *model.Group) error {
+	return r.db.WithContext(ctx).Save(group).Error
+}
+
+func (r *groupRepositoryImpl) Delete(ctx context.Context, group *model.Group) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("group_id = ?", group.ID).Delete(&model.Share{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("group_id = ?", group.ID).Delete(&model.GroupMember{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(group).Error
+	})
+}
+
+func (r *groupRepositoryImpl) ListVisible(ctx context.Context, userID uint) ([]model.Group, error) {
+	var groups []model.Group
+	err := r.db.WithContext(ctx).
+		Where("owner_id = ? OR admin_managed OR id IN (SELECT group_id FROM group_members WHERE user_id = ?)", userID, userID).
+		Order("name ASC, id ASC").
+		Find(&groups).Error
+	return groups, err
+}
+
+func (r *groupRepositoryImpl) FindMember(ctx context.Context, groupID, userID uint) (*model.GroupMember, error) {
+	var member model.GroupMember
+	err := r.db.WithContext(ctx).Where("group_id = ? AND user_id = ?", groupID, userID).First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *groupRepositoryImpl) ListMembers(ctx context.Context, groupID uint) ([]model.GroupMember, error) {
+	var members []model.GroupMember
+	err := r.db.WithContext(ctx).Preload("User").Where("group_id = ?", groupID).Order("id").Find(&members).Error
+	return members, err
+}
+
+func (r *groupRepositoryImpl) AddMember(ctx context.Context, member *model.GroupMember) error {
+	return r.db.WithContext(ctx).Omit("User").Create(member).Error
+}
+
+func (r *groupRepositoryImpl) DeleteMember(ctx context.Context, member *model.GroupMember) error {
+	return r.db.WithContext(ctx).Delete(member).Error
+}
@@ -0,0 +1,259 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// ownedFilesCTE defines owned_files, every file a user is charged for with
+// whether it is in the trash, either directly or because one of its folders
+// is. Prefix it with "WITH RECURSIVE" and pass a named @user_id argument.
+//
+//   - folder_ancestry: each folder holding the user's files paired with
+//     itself and every ancestor below the root
+//   - trashed_folders: those folders with a deleted folder in their ancestry
+const ownedFilesCTE = `
+folder_ancestry AS (
+	SELECT DISTINCT f.folder_id, f.folder_id AS ancestor_id
+	FROM files f
+	WHERE f.user_id = @user_id AND f.workspace_id IS NULL
+	UNION
+	SELECT a.folder_id, p.parent_folder_id
+	FROM folder_ancestry a
+	JOIN folders p ON p.id = a.ancestor_id
+	WHERE p.parent_folder_id IS NOT NULL
+),
+trashed_folders AS (
+	SELECT DISTINCT a.folder_id
+	FROM folder_ancestry a
+	JOIN folders fo ON fo.id = a.ancestor_id
+	WHERE fo.deleted_at IS NOT NULL
+),
+owned_files AS (
+	SELECT f.id, f.folder_id, f.file_type, f.file_size, f.content_hash, f.updated_at,
+		(f.deleted_at IS NOT NULL OR f.folder_id IN (SELECT folder_id FROM trashed_folders)) AS trashed
+	FROM files f
+	WHERE f.user_id = @user_id AND f.workspace_id IS NULL
+)`
+
+// duplicateGroupsQuery groups the user's files outside the trash by content.
+// Empty files and files not yet hashed are left out.
+const duplicateGroupsQuery = `
+SELECT content_hash, MAX(file_size) AS file_size, COUNT(*) AS count,
+	MAX(file_size) * (COUNT(*) - 1) AS wasted_size
+FROM owned_files
+WHERE NOT trashed AND content_hash <> '' AND file_size > 0
+GROUP BY content_hash
+HAVING COUNT(*) > 1`
+
+type InsightsRepository interface {
+	// Usage totals the files a user is charged for, in and out of the trash
+	Usage(ctx context.Context, userID uint) (*model.StorageUsage, error)
+	// UsageByType totals a user's files by type, largest first
+	UsageByType(ctx context.Context, userID uint) ([]model.UsageByType, error)
+	// UsageByFolder returns the limit folders whose subtrees hold the most
+	// of a user's files, largest first. The root folder is left out.
+	UsageByFolder(ctx context.Context, userID uint, limit int) ([]model.UsageByFolder, error)
+	// UsageByAge totals a user's files by how long ago they were last
+	// modified, newest first. Every bucket is returned, empty or not.
+	UsageByAge(ctx context.Context, userID uint) ([]model.UsageByAge, error)
+	// LargestFiles returns a user's limit largest files
+	LargestFiles(ctx context.Context, userID uint, limit int) ([]model.File, error)
+	// DuplicateUsage totals the space taken by redundant copies of a user's
+	// files
+	DuplicateUsage(ctx context.Context, userID uint) (*model.DuplicateUsage, error)
+	// ListDuplicates returns a page of a user's duplicate groups, those
+	// wasting the most space first, with their files oldest first, along
+	// with the total number of groups
+	ListDuplicates(ctx context.Context, userID uint, page, perPage int) ([]model.DuplicateGroup, int64, error)
+	// ListCopies returns the other files of the owner of file that have the
+	// same content and are not in the trash
+	ListCopies(ctx context.Context, file *model.File) ([]model.File, error)
+	// InTrash reports whether a file is in the trash, either directly or
+	// because one of its folders is
+	InTrash(ctx context.Context, file *model.File) (bool, error)
+}
+
+type insightsRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewInsightsRepository(db *gorm.DB) InsightsRepository {
+	return &insightsRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *insightsRepositoryImpl) Usage(ctx context.Context, userID uint) (*model.StorageUsage, error) {
+	var usage model.StorageUsage
+	err := r.db.WithContext(ctx).Raw(`
+WITH RECURSIVE `+ownedFilesCTE+`
+SELECT
+	COUNT(*) FILTER (WHERE NOT trashed) AS total_files,
+	COALESCE(SUM(file_size) FILTER (WHERE NOT trashed), 0) AS total_size,
+	COUNT(*) FILTER (WHERE trashed) AS trash_files,
+	COALESCE(SUM(file_size) FILTER (WHERE trashed), 0) AS trash_size
+FROM owned_files`, map[string]interface{}{"user_id": userID}).Scan(&usage).Error
+	return &usage, err
+}
+
+func (r *insightsRepositoryImpl) UsageByType(ctx context.Context, userID uint) ([]model.UsageByType, error) {
+	var usage []model.UsageByType
+	err := r.db.WithContext(ctx).Raw(`
+WITH RECURSIVE `+ownedFilesCTE+`
+SELECT file_type, COUNT(*) AS files, SUM(file_size) AS size
+FROM owned_files
+WHERE NOT trashed
+GROUP BY file_type
+ORDER BY size DESC, file_type`, map[string]interface{}{"user_id": userID}).Scan(&usage).Error
+	return usage, err
+}
+
+func (r *insightsRepositoryImpl) UsageByFolder(ctx context.Context, userID uint, limit int) ([]model.UsageByFolder, error) {
+	var usage []model.UsageByFolder
+	err := r.db.WithContext(ctx).Raw(`
+WITH RECURSIVE `+ownedFilesCTE+`
+SELECT fo.id AS folder_id, fo.folder_name, COUNT(*) AS files, SUM(o.file_size) AS size
+FROM owned_files o
+JOIN folder_ancestry a ON a.folder_id = o.folder_id
+JOIN folders fo ON fo.id = a.ancestor_id
+WHERE NOT o.trashed AND fo.parent_folder_id IS NOT NULL
+GROUP BY fo.id, fo.folder_name
+ORDER BY size DESC, fo.id
+LIMIT @limit`, map[string]interface{}{
+		"user_id": userID,
+		"limit":   limit,
+	}).Scan(&usage).Error
+	return usage, err
+}
+
+func (r *insightsRepositoryImpl) UsageByAge(ctx context.Context, userID uint) ([]model.UsageByAge, error) {
+	var rows []model.UsageByAge
+	err := r.db.WithContext(ctx).Raw(`
+WITH RECURSIVE `+ownedFilesCTE+`
+SELECT
+	CASE
+		WHEN updated_at > NOW() - INTERVAL '30 days' THEN @under_30_days
+		WHEN updated_at > NOW() - INTERVAL '90 days' THEN @to_90_days
+		WHEN updated_at > NOW() - INTERVAL '1 year' THEN @to_1_year
+		ELSE @over_1_year
+	END AS age,
+	COUNT(*) AS files, SUM(file_size) AS size
+FROM owned_files
+WHERE NOT trashed
+GROUP BY 1`, map[string]interface{}{
+		"user_id":       userID,
+		"under_30_days": model.AgeUnder30Days,
+		"to_90_days":    model.Age30To90Days,
+		"to_1_year":     model.Age90DaysTo1Year,
+		"over_1_year":   model.AgeOver1Year,
+	}).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	byAge := make(map[model.AgeBucket]model.UsageByAge, len(rows))
+	for _, row := range rows {
+		byAge[row.Age] = row
+	}
+	usage := make([]model.UsageByAge, len(model.AgeBuckets))
+	for i, age := range model.AgeBuckets {
+		usage[i] = byAge[age]
+		usage[i].Age = age
+	}
+	return usage, nil
+}
+
+func (r *insightsRepositoryImpl) LargestFiles(ctx context.Context, userID uint, limit int) ([]model.File, error) {
+	var files []model.File
+	err := r.db.WithContext(ctx).
+		Preload("Folder").
+		Where("files.id IN (WITH RECURSIVE "+ownedFilesCTE+" SELECT id FROM owned_files WHERE NOT trashed)",
+			map[string]interface{}{"user_id": userID}).
+		Order("file_size DESC, id").
+		Limit(limit).
+		Find(&files).Error
+	return files, err
+}
+
+func (r *insightsRepositoryImpl) DuplicateUsage(ctx context.Context, userID uint) (*model.DuplicateUsage, error) {
+	var usage model.DuplicateUsage
+	err := r.db.WithContext(ctx).Raw(`
+WITH RECURSIVE `+ownedFilesCTE+`,
+duplicate_groups AS (`+duplicateGroupsQuery+`)
+SELECT COUNT(*) AS groups, COALESCE(SUM(wasted_size), 0) AS wasted_size
+FROM duplicate_groups`, map[string]interface{}{"user_id": userID}).Scan(&usage).Error
+	return &usage, err
+}
+
+func (r *insightsRepositoryImpl) ListDuplicates(ctx context.Context, userID uint, page, perPage int) ([]model.DuplicateGroup, int64, error) {
+	usage, err := r.DuplicateUsage(ctx, userID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var groups []model.DuplicateGroup
+	err = r.db.WithContext(ctx).Raw(`
+WITH RECURSIVE `+ownedFilesCTE+`
+`+duplicateGroupsQuery+`
+ORDER BY wasted_size DESC, content_hash
+LIMIT @limit OFFSET @offset`, map[string]interface{}{
+		"user_id": userID,
+		"limit":   perPage,
+		"offset":  (page - 1) * perPage,
+	}).Scan(&groups).Error
+	if err != nil || len(groups) == 0 {
+		return groups, usage.Groups, err
+	}
+
+	hashes := make([]string, len(groups))
+	for i := range groups {
+		hashes[i] = groups[i].ContentHash
+	}
+	var files []model.File
+	err = r.db.WithContext(ctx).
+		Preload("Folder").
+		Where("files.content_hash IN ? AND files.file_size > 0", hashes).
+		Where("files.id IN (WITH RECURSIVE "+ownedFilesCTE+" SELECT id FROM owned_files WHERE NOT trashed)",
+			map[string]interface{}{"user_id": userID}).
+		Order("created_at, id").
+		Find(&files).Error
+	if err != nil {
+		return nil, 0, err
+	}
+
+	byHash := make(map[string]*model.DuplicateGroup, len(groups))
+	for i := range groups {
+		byHash[groups[i].ContentHash] = &groups[i]
+	}
+	for _, file := range files {
+		if group := byHash[file.ContentHash]; group != nil {
+			group.Files = append(group.Files, file)
+		}
+	}
+	return groups, usage.Groups, nil
+}
+
+func (r *insightsRepositoryImpl) ListCopies(ctx context.Context, file *model.File) ([]model.File, error) {
+	var files []model.File
+	err := r.db.WithContext(ctx).
+		Where("files.content_hash = ? AND files.id <> ?", file.ContentHash, file.ID).
+		Where("files.id IN (WITH RECURSIVE "+ownedFilesCTE+" SELECT id FROM owned_files WHERE NOT trashed)",
+			map[string]interface{}{"user_id": file.UserID}).
+		Order("id").
+		Find(&files).Error
+	return files, err
+}
+
+func (r *insightsRepositoryImpl) InTrash(ctx context.Context, file *model.File) (bool, error) {
+	var trashed bool
+	err := r.db.WithContext(ctx).Raw(`
+WITH RECURSIVE `+ownedFilesCTE+`
+SELECT trashed FROM owned_files WHERE id = @file_id`, map[string]interface{}{
+		"user_id": file.UserID,
+		"file_id": file.ID,
+	}).Scan(&trashed).Error
+	return trashed, err
+}
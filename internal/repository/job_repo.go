@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type JobRepository interface {
+	// Enqueue inserts a job, reporting false without inserting anything when
+	// it has a unique key already held by a queued or running job
+	Enqueue(ctx context.Context, job *model.Job) (bool, error)
+	FindByID(ctx context.Context, id uint) (*model.Job, error)
+	// FindActiveByUniqueKey returns the queued or running job holding key
+	FindActiveByUniqueKey(ctx context.Context, key string) (*model.Job, error)
+	// List returns a page of jobs, newest first, along with the total
+	List(ctx context.Context, filter *model.JobFilter) ([]model.Job, int64, error)
+	Stats(ctx context.Context) (*model.JobStats, error)
+	// Claim locks up to limit due jobs of the given types, marks them
+	// running and counts the attempt. The lease is how long they stay
+	// claimed before they are presumed abandoned.
+	Claim(ctx context.Context, types []string, limit int, lease time.Duration) ([]model.Job, error)
+	// Finish records the outcome of a claimed job, reporting false when the
+	// claim identified by lockedUntil no longer holds, such as when its lease
+	// expired and the job was requeued
+	Finish(ctx context.Context, job *model.Job, lockedUntil time.Time) (bool, error)
+	// RecoverExpired requeues running jobs whose lease has expired, or moves
+	// them to the dead letter state when they are out of attempts
+	RecoverExpired(ctx context.Context) (int64, error)
+	// Retry requeues a dead or waiting job to run now with a fresh set of
+	// attempts, reporting false when the job is not in either state or
+	// another job holds its unique key
+	Retry(ctx context.Context, id uint) (bool, error)
+	// DeleteSucceededBefore removes jobs that succeeded before t
+	DeleteSucceededBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+type jobRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewJobRepository(db *gorm.DB) JobRepository {
+	return &jobRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *jobRepositoryImpl) Enqueue(ctx context.Context, job *model.Job) (bool, error) {
+	query := r.db.WithContext(ctx)
+	if job.UniqueKey != nil {
+		query = query.Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "unique_key"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "unique_key IS NOT NULL AND status IN ('queued', 'running')"}}},
+			DoNothing:   true,
+		})
+	}
+	result := query.Create(job)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *jobRepositoryImpl) FindByID(ctx context.Context, id uint) (*model.Job, error) {
+	var job model.Job
+	err := r.db.WithContext(ctx).First(&job, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *jobRepositoryImpl) FindActiveByUniqueKey(ctx context.Context, key string) (*model.Job, error) {
+	var job model.Job
+	err := r.db.WithContext(ctx).
+		Where("unique_key = ? AND status IN ?", key, []model.JobStatus{model.JobQueued, model.JobRunning}).
+		First(&job).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (r *jobRepositoryImpl) List(ctx context.Context, filter *model.JobFilter) ([]model.Job, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Job{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Type != "" {
+		query = query.Where("type = ?", filter.Type)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var jobs []model.Job
+	err := query.Order("id DESC").
+		Limit(filter.PerPage).
+		Offset((filter.Page - 1) * filter.PerPage).
+		Find(&jobs).Error
+	return jobs, total, err
+}
+
+func (r *jobRepositoryImpl) Stats(ctx context.Context) (*model.JobStats, error) {
+	var stats model.JobStats
+	err := r.db.WithContext(ctx).Raw(`
+SELECT
+	COUNT(*) FILTER (WHERE status = @queued) AS queued,
+	COUNT(*) FILTER (WHERE status = @running) AS running,
+	COUNT(*) FILTER (WHERE status = @succeeded) AS succeeded,
+	COUNT(*) FILTER (WHERE status = @dead) AS dead
+FROM jobs`, map[string]interface{}{
+		"queued":    model.JobQueued,
+		"running":   model.JobRunning,
+		"succeeded": model.JobSucceeded,
+		"dead":      model.JobDead,
+	}).Scan(&stats).Error
+	return &stats, err
+}
+
+func (r *jobRepositoryImpl) Claim(ctx context.Context, types []string, limit int, lease time.Duration) ([]model.Job, error) {
+	var jobs []model.Job
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND run_at <= now() AND type IN ?", model.JobQueued, types).
+			Order("run_at ASC, id ASC").
+			Limit(limit).
+			Find(&jobs).Error
+		if err != nil || len(jobs) == 0 {
+			return err
+		}
+
+		ids := make([]uint, len(jobs))
+		for i := range jobs {
+			ids[i] = jobs[i].ID
+		}
+		// Postgres keeps microseconds, and the lease identifies the claim
+		// when it finishes
+		now := time.Now()
+		lockedUntil := now.Add(lease).Truncate(time.Microsecond)
+		err = tx.Model(&model.Job{}).
+			Where("id IN ?", ids).
+			Updates(map[string]interface{}{
+				"status":       model.JobRunning,
+				"attempts":     gorm.Expr("attempts + 1"),
+				"locked_until": lockedUntil,
+				"started_at":   now,
+			}).Error
+		if err != nil {
+			return err
+		}
+		for i := range jobs {
+			jobs[i].Status = model.JobRunning
+			jobs[i].Attempts++
+			jobs[i].LockedUntil = &lockedUntil
+			jobs[i].StartedAt = &now
+		}
+		return nil
+	})
+	return jobs, err
+}
+
+func (r *jobRepositoryImpl) Finish(ctx context.Context, job *model.Job, lockedUntil time.Time) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(job).
+		Where("status = ? AND locked_until = ?", model.JobRunning, lockedUntil).
+		Select("status", "attempts", "run_at", "locked_until", "last_error", "finished_at", "updated_at").
+		Updates(job)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *jobRepositoryImpl) RecoverExpired(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`
+UPDATE jobs SET
+	status = CASE WHEN attempts >= max_attempts THEN @dead ELSE @queued END,
+	finished_at = CASE WHEN attempts >= max_attempts THEN now() END,
+	run_at = now(),
+	locked_until = NULL,
+	last_error = 'worker stopped before the job finished',
+	updated_at = now()
+WHERE status = @running AND locked_until < now()`, map[string]interface{}{
+		"dead":    model.JobDead,
+		"queued":  model.JobQueued,
+		"running": model.JobRunning,
+	})
+	return result.RowsAffected, result.Error
+}
+
+func (r *jobRepositoryImpl) Retry(ctx context.Context, id uint) (bool, error) {
+	result := r.db.WithContext(ctx).Exec(`
+UPDATE jobs SET
+	status = @queued,
+	attempts = 0,
+	run_at = now(),
+	finished_at = NULL,
+	updated_at = now()
+WHERE id = @id AND status IN (@queued, @dead)
+	AND (unique_key IS NULL OR NOT EXISTS (
+		SELECT 1 FROM jobs other
+		WHERE other.unique_key = jobs.unique_key AND other.id <> jobs.id
+			AND other.status IN (@queued, @running)
+	))`, map[string]interface{}{
+		"id":      id,
+		"queued":  model.JobQueued,
+		"dead":    model.JobDead,
+		"running": model.JobRunning,
+	})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *jobRepositoryImpl) DeleteSucceededBefore(ctx context.Context, t time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Delete(&model.Job{}, "status = ? AND finished_at < ?", model.JobSucceeded, t)
+	return result.RowsAffected, result.Error
+}
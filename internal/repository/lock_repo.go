@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type LockRepository interface {
+	// Acquire stores a new lock unless it conflicts with another active lock
+	// on the file, reporting whether it was stored. The user's previous lock
+	// on the file, if any, is replaced. Concurrent calls for the same file
+	// are serialized.
+	Acquire(ctx context.Context, lock *model.FileLock) (bool, error)
+	FindByID(ctx context.Context, id uint) (*model.FileLock, error)
+	FindByToken(ctx context.Context, token string) (*model.FileLock, error)
+	// ListActive returns the locks on a file that have not expired, oldest
+	// first, with their holders
+	ListActive(ctx context.Context, fileID uint) ([]model.FileLock, error)
+	// ListAllActive returns every unexpired lock, optionally limited to one
+	// file and/or holder
+	ListAllActive(ctx context.Context, fileID, userID uint) ([]model.FileLock, error)
+	// Refresh moves a lock's expiry
+	Refresh(ctx context.Context, lock *model.FileLock) error
+	Delete(ctx context.Context, lock *model.FileLock) error
+	// DeleteExpired removes locks that expired before t
+	DeleteExpired(ctx context.Context, t time.Time) (int64, error)
+	// BlockedUnderFolder reports whether any file in a folder or its
+	// descendants has active locks of which the user holds none
+	BlockedUnderFolder(ctx context.Context, folderID, userID uint) (bool, error)
+}
+
+type lockRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewLockRepository(db *gorm.DB) LockRepository {
+	return &lockRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *lockRepositoryImpl) Acquire(ctx context.Context, lock *model.FileLock) (bool, error) {
+	acquired := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Locking the file row serializes lock changes on the file
+		var file model.File
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&file, lock.FileID).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		var active []model.FileLock
+		if err := tx.Where("file_id = ? AND expires_at > ?", lock.FileID, now).Find(&active).Error; err != nil {
+			return err
+		}
+		for i := range active {
+			if lock.Conflicts(&active[i]) {
+				return nil
+			}
+		}
+
+		// Expired locks and the user's previous lock make way for the new one
+		err := tx.Where("file_id = ? AND (expires_at <= ? OR user_id = ?)", lock.FileID, now, lock.UserID).
+			Delete(&model.FileLock{}).Error
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(lock).Error; err != nil {
+			return err
+		}
+		acquired = true
+		return nil
+	})
+	return acquired, err
+}
+
+func (r *lockRepositoryImpl) FindByID(ctx context.Context, id uint) (*model.FileLock, error) {
+	var lock model.FileLock
+	err := r.db.WithContext(ctx).Preload("User").First(&lock, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &lock, nil
+}
+
+func (r *lockRepositoryImpl) FindByToken(ctx context.Context, token string) (*model.FileLock, error) {
+	var lock model.FileLock
+	err := r.db.WithContext(ctx).Where("token = ?", token).First(&lock).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &lock, nil
+}
+
+func (r *lockRepositoryImpl) ListActive(ctx context.Context, fileID uint) ([]model.FileLock, error) {
+	var locks []model.FileLock
+	err := r.db.WithContext(ctx).Preload("User").
+		Where("file_id = ? AND expires_at > ?", fileID, time.Now()).
+		Order("id").
+		Find(&locks).Error
+	return locks, err
+}
+
+func (r *lockRepositoryImpl) ListAllActive(ctx context.Context, fileID, userID uint) ([]model.FileLock, error) {
+	query := r.db.WithContext(ctx).Preload("User").Where("expires_at > ?", time.Now())
+	if fileID != 0 {
+		query = query.Where("file_id = ?", fileID)
+	}
+	if userID != 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var locks []model.FileLock
+	err := query.Order("id").Find(&locks).Error
+	return locks, err
+}
+
+func (r *lockRepositoryImpl) Refresh(ctx context.Context, lock *model.FileLock) error {
+	return r.db.WithContext(ctx).Model(lock).Update("expires_at", lock.ExpiresAt).Error
+}
+
+func (r *lockRepositoryImpl) Delete(ctx context.Context, lock *model.FileLock) error {
+	return r.db.WithContext(ctx).Delete(lock).Error
+}
+
+func (r *lockRepositoryImpl) BlockedUnderFolder(ctx context.Context, folderID, userID uint) (bool, error) {
+	sql := `
+WITH RECURSIVE subtree AS (
+	SELECT id FROM folders WHERE id = @folder_id
+	UNION
+	SELECT f.id
+	FROM folders f
+	JOIN subtree s ON f.parent_folder_id = s.id
+	WHERE f.deleted_at IS NULL
+)
+SELECT EXISTS (
+	SELECT 1
+	FROM file_locks l
+	JOIN files fi ON fi.id = l.file_id AND fi.deleted_at IS NULL
+	WHERE fi.folder_id IN (SELECT id FROM subtree)
+	AND l.expires_at > @now
+	AND NOT EXISTS (
+		SELECT 1 FROM file_locks own
+		WHERE own.file_id = l.file_id AND own.user_id = @user_id AND own.expires_at > @now
+	)
+)`
+
+	var blocked bool
+	err := r.db.WithContext(ctx).Raw(sql, map[string]interface{}{
+		"folder_id": folderID,
+		"user_id":   userID,
+		"now":       time.Now(),
+	}).Scan(&blocked).Error
+	return blocked, err
+}
+
+func (r *lockRepositoryImpl) DeleteExpired(ctx context.Context, t time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Delete(&model.FileLock{}, "expires_at < ?", t)
+	return result.RowsAffected, result.Error
+}
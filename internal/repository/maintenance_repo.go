@@ -0,0 +1,264 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// expiredFoldersCTE defines expired_folders, every folder trashed before
+// @cutoff along with everything below it. Prefix it with "WITH RECURSIVE".
+const expiredFoldersCTE = `
+expired_folders AS (
+	SELECT id FROM folders WHERE deleted_at < @cutoff
+	UNION
+	SELECT c.id
+	FROM folders c
+	JOIN expired_folders p ON c.parent_folder_id = p.id
+)`
+
+// trashedFoldersCTE defines trashed_folders, the folders of @folder_ids that
+// are in the trash along with everything below them. Prefix it with "WITH
+// RECURSIVE".
+const trashedFoldersCTE = `
+trashed_folders AS (
+	SELECT id FROM folders WHERE id IN @folder_ids AND deleted_at IS NOT NULL
+	UNION
+	SELECT c.id
+	FROM folders c
+	JOIN trashed_folders p ON c.parent_folder_id = p.id
+)`
+
+type MaintenanceRepository interface {
+	// ListExpiredFiles returns up to limit files that have been in the
+	// trash since before cutoff, either directly or because one of their
+	// folders has, ordered by ID and starting after afterID
+	ListExpiredFiles(ctx context.Context, cutoff time.Time, afterID uint, limit int) ([]model.File, error)
+	// PurgeFiles permanently deletes files along with their comments, locks,
+	// shares, tags and stars. Their blobs are left to the caller.
+	PurgeFiles(ctx context.Context, ids []uint) error
+	// PurgeExpiredFolders permanently deletes the folders in the trash since
+	// before cutoff, and the folders below them, once they hold no files.
+	// Folders under a legal hold are kept, along with their ancestors.
+	PurgeExpiredFolders(ctx context.Context, cutoff time.Time) (int64, error)
+	// ListTrashedFiles returns the files of fileIDs that are in the trash
+	// and every file below the folders of folderIDs that are, ordered by ID
+	ListTrashedFiles(ctx context.Context, fileIDs, folderIDs []uint) ([]model.File, error)
+	// PurgeFolders permanently deletes the folders of folderIDs that are in
+	// the trash, and the folders below them, once they hold no files
+	PurgeFolders(ctx context.Context, folderIDs []uint) (int64, error)
+	// ReconcileStorageUsed sets every user's storage usage to the total size
+	// of their files outside workspaces, returning how many users were
+	// corrected
+	ReconcileStorageUsed(ctx context.Context) (int64, error)
+	// ReconcileWorkspaceStorageUsed sets every workspace's storage usage to
+	// the total size of its files, returning how many were corrected
+	ReconcileWorkspaceStorageUsed(ctx context.Context) (int64, error)
+	// ReferencedBlobs returns which of the blob keys belong to a file,
+	// trashed files included
+	ReferencedBlobs(ctx context.Context, keys []string) (map[string]bool, error)
+}
+
+type maintenanceRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewMaintenanceRepository(db *gorm.DB) MaintenanceRepository {
+	return &maintenanceRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *maintenanceRepositoryImpl) ListExpiredFiles(ctx context.Context, cutoff time.Time, afterID uint, limit int) ([]model.File, error) {
+	var files []model.File
+	err := r.db.WithContext(ctx).
+		Unscoped().
+		Where("(files.deleted_at < @cutoff OR files.folder_id IN (WITH RECURSIVE "+expiredFoldersCTE+" SELECT id FROM expired_folders)) AND files.id > @after_id",
+			map[string]interface{}{"cutoff": cutoff, "after_id": afterID}).
+		Order("id").
+		Limit(limit).
+		Find(&files).Error
+	return files, err
+}
+
+func (r *maintenanceRepositoryImpl) PurgeFiles(ctx context.Context, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		statements := []string{
+			`DELETE FROM comment_mentions WHERE comment_id IN (SELECT id FROM comments WHERE file_id IN @ids)`,
+			`DELETE FROM comment_revisions WHERE comment_id IN (SELECT id FROM comments WHERE file_id IN @ids)`,
+			`DELETE FROM comments WHERE file_id IN @ids`,
+			`DELETE FROM file_locks WHERE file_id IN @ids`,
+			`DELETE FROM file_shares WHERE file_id IN @ids`,
+			`DELETE FROM shares WHERE file_id IN @ids`,
+			`DELETE FROM file_tags WHERE file_id IN @ids`,
+			`DELETE FROM stars WHERE item_type = @item_type AND item_id IN @ids`,
+			`DELETE FROM files WHERE id IN @ids`,
+		}
+		args := map[string]interface{}{"ids": ids, "item_type": model.ItemTypeFile}
+		for _, statement := range statements {
+			if err := tx.Exec(statement, args).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (r *maintenanceRepositoryImpl) PurgeExpiredFolders(ctx context.Context, cutoff time.Time) (int64, error) {
+	var purged int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Remove the subtrees' leaves first, one level per pass. Folders
+		// still holding files, referenced by a share of a file that was
+		// moved out, or under a legal hold are kept and block their
+		// ancestors.
+		for {
+			var ids []uint
+			err := tx.Raw(`
+WITH RECURSIVE `+expiredFoldersCTE+`,
+held_folders AS (
+	SELECT folder_id AS id FROM legal_holds WHERE released_at IS NULL AND folder_id IS NOT NULL
+	UNION
+	SELECT c.id
+	FROM folders c
+	JOIN held_folders h ON c.parent_folder_id = h.id
+)
+SELECT f.id
+FROM folders f
+WHERE f.id IN (SELECT id FROM expired_folders)
+	AND f.id NOT IN (SELECT id FROM held_folders)
+	AND NOT EXISTS (
+		SELECT 1 FROM legal_holds h
+		WHERE h.released_at IS NULL AND h.user_id = f.user_id AND f.workspace_id IS NULL
+	)
+	AND NOT EXISTS (SELECT 1 FROM folders c WHERE c.parent_folder_id = f.id)
+	AND NOT EXISTS (SELECT 1 FROM files fi WHERE fi.folder_id = f.id)
+	AND NOT EXISTS (
+		SELECT 1 FROM shares s JOIN files fi ON fi.id = s.file_id
+		WHERE s.folder_id = f.id
+	)`, map[string]interface{}{"cutoff": cutoff}).Scan(&ids).Error
+			if err != nil || len(ids) == 0 {
+				return err
+			}
+
+			if err := deleteFolders(tx, ids); err != nil {
+				return err
+			}
+			purged += int64(len(ids))
+		}
+	})
+	return purged, err
+}
+
+func (r *maintenanceRepositoryImpl) ListTrashedFiles(ctx context.Context, fileIDs, folderIDs []uint) ([]model.File, error) {
+	var files []model.File
+	err := r.db.WithContext(ctx).
+		Unscoped().
+		Where("(files.id IN @file_ids AND files.deleted_at IS NOT NULL) OR files.folder_id IN (WITH RECURSIVE "+trashedFoldersCTE+" SELECT id FROM trashed_folders)",
+			map[string]interface{}{"file_ids": fileIDs, "folder_ids": folderIDs}).
+		Order("id").
+		Find(&files).Error
+	return files, err
+}
+
+func (r *maintenanceRepositoryImpl) PurgeFolders(ctx context.Context, folderIDs []uint) (int64, error) {
+	if len(folderIDs) == 0 {
+		return 0, nil
+	}
+	var purged int64
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Leaves first, as for expired folders
+		for {
+			var ids []uint
+			err := tx.Raw(`
+WITH RECURSIVE `+trashedFoldersCTE+`
+SELECT f.id
+FROM folders f
+WHERE f.id IN (SELECT id FROM trashed_folders)
+	AND NOT EXISTS (SELECT 1 FROM folders c WHERE c.parent_folder_id = f.id)
+	AND NOT EXISTS (SELECT 1 FROM files fi WHERE fi.folder_id = f.id)
+	AND NOT EXISTS (
+		SELECT 1 FROM shares s JOIN files fi ON fi.id = s.file_id
+		WHERE s.folder_id = f.id
+	)`, map[string]interface{}{"folder_ids": folderIDs}).Scan(&ids).Error
+			if err != nil || len(ids) == 0 {
+				return err
+			}
+			if err := deleteFolders(tx, ids); err != nil {
+				return err
+			}
+			purged += int64(len(ids))
+		}
+	})
+	return purged, err
+}
+
+// deleteFolders permanently deletes folders along with their shares, stars
+// and retention policies
+func deleteFolders(tx *gorm.DB, ids []uint) error {
+	args := map[string]interface{}{"ids": ids, "item_type": model.ItemTypeFolder}
+	statements := []string{
+		`DELETE FROM shares WHERE folder_id IN @ids`,
+		`DELETE FROM stars WHERE item_type = @item_type AND item_id IN @ids`,
+		`DELETE FROM retention_policies WHERE folder_id IN @ids`,
+		`DELETE FROM folders WHERE id IN @ids`,
+	}
+	for _, statement := range statements {
+		if err := tx.Exec(statement, args).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *maintenanceRepositoryImpl) ReconcileStorageUsed(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`
+UPDATE users u SET storage_used = t.used
+FROM (
+	SELECT owner.id, COALESCE(SUM(f.file_size), 0) / 1048576.0 AS used
+	FROM users owner
+	LEFT JOIN files f ON f.user_id = owner.id AND f.workspace_id IS NULL
+	GROUP BY owner.id
+) t
+WHERE t.id = u.id AND abs(u.storage_used - t.used) > 0.000001`)
+	return result.RowsAffected, result.Error
+}
+
+func (r *maintenanceRepositoryImpl) ReconcileWorkspaceStorageUsed(ctx context.Context) (int64, error) {
+	result := r.db.WithContext(ctx).Exec(`
+UPDATE workspaces w SET storage_used = t.used
+FROM (
+	SELECT ws.id, COALESCE(SUM(f.file_size), 0) / 1048576.0 AS used
+	FROM workspaces ws
+	LEFT JOIN files f ON f.workspace_id = ws.id
+	GROUP BY ws.id
+) t
+WHERE t.id = w.id AND abs(w.storage_used - t.used) > 0.000001`)
+	return result.RowsAffected, result.Error
+}
+
+func (r *maintenanceRepositoryImpl) ReferencedBlobs(ctx context.Context, keys []string) (map[string]bool, error) {
+	referenced := make(map[string]bool)
+	if len(keys) == 0 {
+		return referenced, nil
+	}
+
+	var found []string
+	err := r.db.WithContext(ctx).
+		Unscoped().
+		Model(&model.File{}).
+		Where("file_url IN ?", keys).
+		Distinct().
+		Pluck("file_url", &found).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range found {
+		referenced[key] = true
+	}
+	return referenced, nil
+}
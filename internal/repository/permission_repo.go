@@ -0,0 +1,220 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+)
+
+// PermissionRepository resolves the effective permission a user has on an item
+type PermissionRepository interface {
+	// FolderPermission returns the user's permission on a folder, or "" for none
+	FolderPermission(ctx context.Context, userID, folderID uint) (model.Permission, error)
+	// FilePermission returns the user's permission on a file, or "" for none
+	FilePermission(ctx context.Context, userID, fileID uint) (model.Permission, error)
+	// FileAudience returns every user with access to a file: its owner, the
+	// owners of its ancestor folders or the members of its workspace, and
+	// the users it is shared with directly or through a folder, including
+	// the members of groups it is shared with
+	FileAudience(ctx context.Context, fileID uint) ([]uint, error)
+	// FolderAudience returns every user with access to a folder: the owners
+	// of the folder and its ancestors or the members of its workspace, and
+	// the users any of them is shared with, including through groups
+	FolderAudience(ctx context.Context, folderID uint) ([]uint, error)
+}
+
+type permissionRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewPermissionRepository(db *gorm.DB) PermissionRepository {
+	return &permissionRepositoryImpl{
+		db: db,
+	}
+}
+
+// folderPermissionSQL walks up from @folder_id and picks the strongest grant:
+// owning the folder or any ancestor makes the user an owner, otherwise the
+// user's workspace role or the strongest folder share on the folder or an
+// ancestor, made with the user or a group they belong to, applies. Nobody
+// owns workspace folders.
+const folderPermissionSQL = `
+WITH RECURSIVE ancestors AS (
+	SELECT id, parent_folder_id, user_id, workspace_id FROM folders WHERE id = @folder_id AND deleted_at IS NULL
+	UNION
+	SELECT f.id, f.parent_folder_id, f.user_id, f.workspace_id
+	FROM folders f
+	JOIN ancestors a ON f.id = a.parent_folder_id
+	WHERE f.deleted_at IS NULL
+),
+grants AS (
+	SELECT 'owner' AS permission FROM ancestors WHERE user_id = @user_id AND workspace_id IS NULL
+	UNION ALL
+	SELECT ` + memberPermissionSQL + `
+	FROM workspace_members m
+	WHERE m.workspace_id IN (SELECT workspace_id FROM ancestors) AND m.user_id = @user_id
+	UNION ALL
+	SELECT s.permission
+	FROM shares s
+	WHERE s.folder_id IN (SELECT id FROM ancestors)
+		AND ` + sharedWithUserSQL + `
+		AND s.deleted_at IS NULL
+		AND COALESCE(s.file_id, 0) = 0
+)`
+
+// memberPermissionSQL is the permission a workspace member m's role grants
+const memberPermissionSQL = `CASE m.role WHEN 'manager' THEN 'owner' WHEN 'editor' THEN 'write' ELSE 'read' END`
+
+// sharedWithUserSQL matches the shares s granted to @user_id, directly or
+// through a group they belong to
+const sharedWithUserSQL = `(s.shared_with_id = @user_id OR s.group_id IN (SELECT gm.group_id FROM group_members gm WHERE gm.user_id = @user_id))`
+
+// shareRecipientsSQL joins shares s to their recipients r: the user each is
+// shared with, or every member of the group it is shared with
+const shareRecipientsSQL = `
+JOIN LATERAL (
+	SELECT s.shared_with_id AS user_id WHERE s.shared_with_id IS NOT NULL
+	UNION ALL
+	SELECT gm.user_id FROM group_members gm WHERE gm.group_id = s.group_id
+) r ON true`
+
+const strongestGrantSQL = `
+SELECT permission FROM grants
+ORDER BY CASE permission WHEN 'owner' THEN 3 WHEN 'write' THEN 2 WHEN 'read' THEN 1 ELSE 0 END DESC
+LIMIT 1`
+
+// FolderPermission returns the user's permission on a folder, or "" for none
+func (r *permissionRepositoryImpl) FolderPermission(ctx context.Context, userID, folderID uint) (model.Permission, error) {
+	var permissions []model.Permission
+	err := r.db.WithContext(ctx).Raw(folderPermissionSQL+strongestGrantSQL, map[string]interface{}{
+		"user_id":   userID,
+		"folder_id": folderID,
+	}).Scan(&permissions).Error
+	if err != nil || len(permissions) == 0 {
+		return "", err
+	}
+	return permissions[0], nil
+}
+
+// FilePermission returns the user's permission on a file, or "" for none.
+// Ownership of the file, a direct file share or the permission on the
+// containing folder all count; files in a workspace are owned by nobody.
+func (r *permissionRepositoryImpl) FilePermission(ctx context.Context, userID, fileID uint) (model.Permission, error) {
+	sql := `
+WITH RECURSIVE target AS (
+	SELECT id, folder_id, user_id, workspace_id FROM files WHERE id = @file_id AND deleted_at IS NULL
+),
+ancestors AS (
+	SELECT f.id, f.parent_folder_id, f.user_id, f.workspace_id
+	FROM folders f
+	JOIN target t ON f.id = t.folder_id
+	WHERE f.deleted_at IS NULL
+	UNION
+	SELECT f.id, f.parent_folder_id, f.user_id, f.workspace_id
+	FROM folders f
+	JOIN ancestors a ON f.id = a.parent_folder_id
+	WHERE f.deleted_at IS NULL
+),
+grants AS (
+	SELECT 'owner' AS permission FROM target WHERE user_id = @user_id AND workspace_id IS NULL
+	UNION ALL
+	SELECT 'owner' FROM ancestors WHERE user_id = @user_id AND workspace_id IS NULL AND EXISTS (SELECT 1 FROM target)
+	UNION ALL
+	SELECT ` + memberPermissionSQL + `
+	FROM workspace_members m
+	WHERE m.workspace_id IN (SELECT workspace_id FROM target) AND m.user_id = @user_id
+	UNION ALL
+	SELECT s.permission
+	FROM shares s
+	WHERE s.file_id IN (SELECT id FROM target)
+		AND ` + sharedWithUserSQL + `
+		AND s.deleted_at IS NULL
+	UNION ALL
+	SELECT s.permission
+	FROM shares s
+	WHERE s.folder_id IN (SELECT id FROM ancestors)
+		AND ` + sharedWithUserSQL + `
+		AND s.deleted_at IS NULL
+		AND COALESCE(s.file_id, 0) = 0
+		AND EXISTS (SELECT 1 FROM target)
+)` + strongestGrantSQL
+
+	var permissions []model.Permission
+	err := r.db.WithContext(ctx).Raw(sql, map[string]interface{}{
+		"user_id": userID,
+		"file_id": fileID,
+	}).Scan(&permissions).Error
+	if err != nil || len(permissions) == 0 {
+		return "", err
+	}
+	return permissions[0], nil
+}
+
+// FileAudience returns every user with access to a file
+func (r *permissionRepositoryImpl) FileAudience(ctx context.Context, fileID uint) ([]uint, error) {
+	sql := `
+WITH RECURSIVE target AS (
+	SELECT id, folder_id, user_id, workspace_id FROM files WHERE id = @file_id
+),
+ancestors AS (
+	SELECT f.id, f.parent_folder_id, f.user_id, f.workspace_id
+	FROM folders f
+	JOIN target t ON f.id = t.folder_id
+	WHERE f.deleted_at IS NULL
+	UNION
+	SELECT f.id, f.parent_folder_id, f.user_id, f.workspace_id
+	FROM folders f
+	JOIN ancestors a ON f.id = a.parent_folder_id
+	WHERE f.deleted_at IS NULL
+)
+SELECT user_id FROM target WHERE workspace_id IS NULL
+UNION
+SELECT user_id FROM ancestors WHERE workspace_id IS NULL
+UNION
+SELECT m.user_id FROM workspace_members m WHERE m.workspace_id IN (SELECT workspace_id FROM target)
+UNION
+SELECT r.user_id
+FROM shares s` + shareRecipientsSQL + `
+WHERE s.file_id IN (SELECT id FROM target) AND s.deleted_at IS NULL
+UNION
+SELECT r.user_id
+FROM shares s` + shareRecipientsSQL + `
+WHERE s.folder_id IN (SELECT id FROM ancestors)
+	AND s.deleted_at IS NULL
+	AND COALESCE(s.file_id, 0) = 0`
+
+	var userIDs []uint
+	err := r.db.WithContext(ctx).Raw(sql, map[string]interface{}{
+		"file_id": fileID,
+	}).Scan(&userIDs).Error
+	return userIDs, err
+}
+
+// FolderAudience returns every user with access to a folder
+func (r *permissionRepositoryImpl) FolderAudience(ctx context.Context, folderID uint) ([]uint, error) {
+	sql := `
+WITH RECURSIVE ancestors AS (
+	SELECT id, parent_folder_id, user_id, workspace_id FROM folders WHERE id = @folder_id
+	UNION
+	SELECT f.id, f.parent_folder_id, f.user_id, f.workspace_id
+	FROM folders f
+	JOIN ancestors a ON f.id = a.parent_folder_id
+	WHERE f.deleted_at IS NULL
+)
+SELECT user_id FROM ancestors WHERE workspace_id IS NULL
+UNION
+SELECT m.user_id FROM workspace_members m WHERE m.workspace_id IN (SELECT workspace_id FROM ancestors)
+UNION
+SELECT r.user_id
+FROM shares s` + shareRecipientsSQL + `
+WHERE s.folder_id IN (SELECT id FROM ancestors)
+	AND s.deleted_at IS NULL
+	AND COALESCE(s.file_id, 0) = 0`
+
+	var userIDs []uint
+	err := r.db.WithContext(ctx).Raw(sql, map[string]interface{}{
+		"folder_id": folderID,
+	}).Scan(&userIDs).Error
+	return userIDs, err
+}
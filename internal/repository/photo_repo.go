@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// capturedAtExpr is when a photo was taken, falling back to when it was
+// uploaded
+const capturedAtExpr = "COALESCE(pm.taken_at, files.created_at)"
+
+type PhotoRepository interface {
+	// SaveMetadata stores the metadata read from an image, replacing any
+	// read from earlier content. The coordinates are dropped when the
+	// file's owner strips photo locations.
+	SaveMetadata(ctx context.Context, metadata *model.PhotoMetadata) error
+	// SetStripLocation records whether a user strips photo locations,
+	// clearing the coordinates already stored for their photos when they do
+	SetStripLocation(ctx context.Context, userID uint, strip bool) error
+	// DeleteMetadata removes a file's metadata, once it is no longer an image
+	DeleteMetadata(ctx context.Context, fileID uint) error
+	// Timeline counts a user's photos by the year, or month, they were taken
+	// in, newest first
+	Timeline(ctx context.Context, userID uint, byMonth bool) ([]model.TimelineBucket, error)
+	// List returns a page of a user's photos with their metadata, newest
+	// first, along with the total count
+	List(ctx context.Context, userID uint, filter *model.PhotoFilter) ([]model.File, int64, error)
+}
+
+type photoRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewPhotoRepository(db *gorm.DB) PhotoRepository {
+	return &photoRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *photoRepositoryImpl) SaveMetadata(ctx context.Context, metadata *model.PhotoMetadata) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Holding the owner's row keeps SetStripLocation from clearing the
+		// coordinates between this check and the write
+		var strip bool
+		err := tx.Raw("SELECT users.strip_photo_location FROM files JOIN users ON users.id = files.user_id "+
+			"WHERE files.id = ? FOR SHARE OF users", metadata.FileID).Scan(&strip).Error
+		if err != nil {
+			return err
+		}
+		if strip {
+			metadata.Latitude, metadata.Longitude = nil, nil
+		}
+
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "file_id"}},
+			UpdateAll: true,
+		}).Omit("File").Create(metadata).Error
+	})
+}
+
+func (r *photoRepositoryImpl) SetStripLocation(ctx context.Context, userID uint, strip bool) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.User{}).Where("id = ?", userID).Update("strip_photo_location", strip).Error; err != nil {
+			return err
+		}
+		if !strip {
+			return nil
+		}
+		// Trashed photos and those added to workspaces are the user's too
+		return tx.Model(&model.PhotoMetadata{}).
+			Where("(latitude IS NOT NULL OR longitude IS NOT NULL) AND file_id IN (?)",
+				tx.Unscoped().Model(&model.File{}).Select("id").Where("user_id = ?", userID)).
+			Updates(map[string]interface{}{"latitude": nil, "longitude": nil}).Error
+	})
+}
+
+func (r *photoRepositoryImpl) DeleteMetadata(ctx context.Context, fileID uint) error {
+	return r.db.WithContext(ctx).Delete(&model.PhotoMetadata{}, "file_id = ?", fileID).Error
+}
+
+func (r *photoRepositoryImpl) Timeline(ctx context.Context, userID uint, byMonth bool) ([]model.TimelineBucket, error) {
+	month := "0"
+	if byMonth {
+		month = "EXTRACT(MONTH FROM captured_at)::int"
+	}
+
+	photos := r.photos(ctx, userID).
+		Select("files.id, " + capturedAtExpr + " AT TIME ZONE 'UTC' AS captured_at")
+
+	var buckets []model.TimelineBucket
+	err := r.db.WithContext(ctx).Table("(?) AS photos", photos).
+		Select("EXTRACT(YEAR FROM captured_at)::int AS year, " + month + " AS month, COUNT(*) AS count, " +
+			"(ARRAY_AGG(id ORDER BY captured_at DESC, id DESC))[1] AS cover_file_id").
+		Group("1, 2").
+		Order("1 DESC, 2 DESC").
+		Scan(&buckets).Error
+	return buckets, err
+}
+
+func (r *photoRepositoryImpl) List(ctx context.Context, userID uint, filter *model.PhotoFilter) ([]model.File, int64, error) {
+	query := r.photos(ctx, userID)
+	if filter.Year != 0 {
+		from, to := captureRange(filter.Year, filter.Month)
+		query = query.Where(capturedAtExpr+" >= ? AND "+capturedAtExpr+" < ?", from, to)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var photos []model.File
+	err := query.Preload("Photo").
+		Order(capturedAtExpr + " DESC, files.id DESC").
+		Limit(filter.PerPage).
+		Offset((filter.Page - 1) * filter.PerPage).
+		Find(&photos).Error
+	return photos, total, err
+}
+
+// photos selects a user's own images that are not in the trash or
+// quarantined, joined with their metadata as pm
+func (r *photoRepositoryImpl) photos(ctx context.Context, userID uint) *gorm.DB {
+	return r.db.WithContext(ctx).Model(&model.File{}).
+		Joins("LEFT JOIN photo_metadata pm ON pm.file_id = files.id").
+		Where("files.user_id = ? AND files.workspace_id IS NULL AND files.file_type = ? AND files.scan_status <> ?",
+			userID, model.FileTypeImage, model.ScanStatusInfected)
+}
+
+// captureRange returns the span of a year, or of a month of it when month is
+// set, in the UTC wall-clock form capture times are stored in
+func captureRange(year, month int) (time.Time, time.Time) {
+	if month == 0 {
+		from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		return from, from.AddDate(1, 0, 0)
+	}
+	from := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(0, 1, 0)
+}
@@ -5,11 +5,61 @@ import (
 )
 
 type Repositories struct {
-	User UserRepository
+	User        UserRepository
+	Folder      FolderRepository
+	File        FileRepository
+	FileContent FileContentRepository
+	Share       ShareRepository
+	Permission  PermissionRepository
+	Search      SearchRepository
+	Audit       AuditRepository
+	Webhook     WebhookRepository
+	Change      ChangeRepository
+	AppPassword AppPasswordRepository
+	Vault       VaultRepository
+	Lock        LockRepository
+	Comment     CommentRepository
+	Photo       PhotoRepository
+	Album       AlbumRepository
+	Insights    InsightsRepository
+	Star        StarRepository
+	Tag         TagRepository
+	Batch       BatchRepository
+	Job         JobRepository
+	Task        TaskRepository
+	Maintenance MaintenanceRepository
+	Retention   RetentionRepository
+	Workspace   WorkspaceRepository
+	Group       GroupRepository
 }
 
 func NewRepositories(db *gorm.DB) *Repositories {
 	return &Repositories{
-		User: NewUserRepository(db),
+		User:        NewUserRepository(db),
+		Folder:      NewFolderRepository(db),
+		File:        NewFileRepository(db),
+		FileContent: NewFileContentRepository(db),
+		Share:       NewShareRepository(db),
+		Permission:  NewPermissionRepository(db),
+		Search:      NewSearchRepository(db),
+		Audit:       NewAuditRepository(db),
+		Webhook:     NewWebhookRepository(db),
+		Change:      NewChangeRepository(db),
+		AppPassword: NewAppPasswordRepository(db),
+		Vault:       NewVaultRepository(db),
+		Lock:        NewLockRepository(db),
+		Comment:     NewCommentRepository(db),
+		Photo:       NewPhotoRepository(db),
+		Album:       NewAlbumRepository(db),
+		Insights:    NewInsightsRepository(db),
+		Star:        NewStarRepository(db),
+		Tag:         NewTagRepository(db),
+		Batch:       NewBatchRepository(db),
+		Job:         NewJobRepository(db),
+		Task:        NewTaskRepository(db),
+		Maintenance: NewMaintenanceRepository(db),
+		Retention:   NewRetentionRepository(db),
+		Workspace:   NewWorkspaceRepository(db),
+		Group:       NewGroupRepository(db),
 	}
 }
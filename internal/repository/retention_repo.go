@@ -0,0 +1,266 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RetentionRepository interface {
+	CreatePolicy(ctx context.Context, policy *model.RetentionPolicy) error
+	UpdatePolicy(ctx context.Context, policy *model.RetentionPolicy) error
+	DeletePolicy(ctx context.Context, policy *model.RetentionPolicy) error
+	FindPolicyByFolder(ctx context.Context, folderID uint) (*model.RetentionPolicy, error)
+	// ListPolicies returns a page of policies with their folders, along with
+	// the total
+	ListPolicies(ctx context.Context, filter *model.RetentionPolicyFilter) ([]model.RetentionPolicy, int64, error)
+	// ListExpiringPolicies returns the policies with a maximum retention
+	ListExpiringPolicies(ctx context.Context) ([]model.RetentionPolicy, error)
+	// PoliciesOn returns the policies attached to any of the folders
+	PoliciesOn(ctx context.Context, folderIDs []uint) ([]model.RetentionPolicy, error)
+	// PoliciesUnder returns the policies attached to the live folders
+	// strictly beneath a folder
+	PoliciesUnder(ctx context.Context, folderID uint) ([]model.RetentionPolicy, error)
+
+	CreateHold(ctx context.Context, hold *model.LegalHold) error
+	FindHold(ctx context.Context, id uint) (*model.LegalHold, error)
+	// ReleaseHold marks a hold released unless it already was, reporting
+	// whether it was released
+	ReleaseHold(ctx context.Context, hold *model.LegalHold) (bool, error)
+	// ListHolds returns a page of holds, newest first, along with the total
+	ListHolds(ctx context.Context, filter *model.LegalHoldFilter) ([]model.LegalHold, int64, error)
+	// ActiveHolds returns the holds in force on any of the folders or users
+	ActiveHolds(ctx context.Context, folderIDs, userIDs []uint) ([]model.LegalHold, error)
+	// SubtreeHold returns a hold in force on a folder, on a live folder
+	// beneath it, or on the owner of anything live and outside a workspace
+	// within it, or nil
+	SubtreeHold(ctx context.Context, folderID uint) (*model.LegalHold, error)
+
+	// FolderPath returns a folder's ID followed by those of its ancestors,
+	// trashed ones included
+	FolderPath(ctx context.Context, folderID uint) ([]uint, error)
+	// NewestFileUnder returns when the newest live file in a folder or the
+	// live folders beneath it was created, or nil if there is none
+	NewestFileUnder(ctx context.Context, folderID uint) (*time.Time, error)
+	// ListFilesUnder returns up to limit live files in a folder or the live
+	// folders beneath it created before t, ordered by ID and starting after
+	// afterID
+	ListFilesUnder(ctx context.Context, folderID uint, t time.Time, afterID uint, limit int) ([]model.File, error)
+}
+
+// subtreeCTE defines subtree, a folder and the live folders beneath it.
+// Prefix it with "WITH RECURSIVE" and pass a named @folder_id argument.
+const subtreeCTE = `
+subtree AS (
+	SELECT id, user_id, workspace_id FROM folders WHERE id = @folder_id
+	UNION
+	SELECT f.id, f.user_id, f.workspace_id
+	FROM folders f
+	JOIN subtree s ON f.parent_folder_id = s.id
+	WHERE f.deleted_at IS NULL
+)`
+
+type retentionRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewRetentionRepository(db *gorm.DB) RetentionRepository {
+	return &retentionRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *retentionRepositoryImpl) CreatePolicy(ctx context.Context, policy *model.RetentionPolicy) error {
+	return r.db.WithContext(ctx).Create(policy).Error
+}
+
+func (r *retentionRepositoryImpl) UpdatePolicy(ctx context.Context, policy *model.RetentionPolicy) error {
+	return r.db.WithContext(ctx).Omit("Folder").Save(policy).Error
+}
+
+func (r *retentionRepositoryImpl) DeletePolicy(ctx context.Context, policy *model.RetentionPolicy) error {
+	return r.db.WithContext(ctx).Delete(policy).Error
+}
+
+func (r *retentionRepositoryImpl) FindPolicyByFolder(ctx context.Context, folderID uint) (*model.RetentionPolicy, error) {
+	var policy model.RetentionPolicy
+	err := r.db.WithContext(ctx).Where("folder_id = ?", folderID).First(&policy).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+func (r *retentionRepositoryImpl) ListPolicies(ctx context.Context, filter *model.RetentionPolicyFilter) ([]model.RetentionPolicy, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.RetentionPolicy{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var policies []model.RetentionPolicy
+	err := query.Preload("Folder").
+		Order("id ASC").
+		Limit(filter.PerPage).
+		Offset((filter.Page - 1) * filter.PerPage).
+		Find(&policies).Error
+	return policies, total, err
+}
+
+func (r *retentionRepositoryImpl) ListExpiringPolicies(ctx context.Context) ([]model.RetentionPolicy, error) {
+	var policies []model.RetentionPolicy
+	err := r.db.WithContext(ctx).Where("max_days > 0").Order("id ASC").Find(&policies).Error
+	return policies, err
+}
+
+func (r *retentionRepositoryImpl) PoliciesOn(ctx context.Context, folderIDs []uint) ([]model.RetentionPolicy, error) {
+	var policies []model.RetentionPolicy
+	if len(folderIDs) == 0 {
+		return policies, nil
+	}
+	err := r.db.WithContext(ctx).Where("folder_id IN ?", folderIDs).Order("id ASC").Find(&policies).Error
+	return policies, err
+}
+
+func (r *retentionRepositoryImpl) PoliciesUnder(ctx context.Context, folderID uint) ([]model.RetentionPolicy, error) {
+	var policies []model.RetentionPolicy
+	err := r.db.WithContext(ctx).Raw(`
+WITH RECURSIVE `+subtreeCTE+`
+SELECT p.*
+FROM retention_policies p
+WHERE p.folder_id IN (SELECT id FROM subtree) AND p.folder_id <> @folder_id
+ORDER BY p.id`, map[string]interface{}{"folder_id": folderID}).Scan(&policies).Error
+	return policies, err
+}
+
+func (r *retentionRepositoryImpl) CreateHold(ctx context.Context, hold *model.LegalHold) error {
+	return r.db.WithContext(ctx).Create(hold).Error
+}
+
+func (r *retentionRepositoryImpl) FindHold(ctx context.Context, id uint) (*model.LegalHold, error) {
+	var hold model.LegalHold
+	err := r.db.WithContext(ctx).First(&hold, id).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &hold, nil
+}
+
+func (r *retentionRepositoryImpl) ReleaseHold(ctx context.Context, hold *model.LegalHold) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.LegalHold{}).
+		Where("id = ? AND released_at IS NULL", hold.ID).
+		Updates(map[string]interface{}{
+			"released_at": hold.ReleasedAt,
+			"released_by": hold.ReleasedBy,
+		})
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *retentionRepositoryImpl) ListHolds(ctx context.Context, filter *model.LegalHoldFilter) ([]model.LegalHold, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.LegalHold{})
+	switch filter.Status {
+	case "active":
+		query = query.Where("released_at IS NULL")
+	case "released":
+		query = query.Where("released_at IS NOT NULL")
+	}
+	if filter.UserID != 0 {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.FolderID != 0 {
+		query = query.Where("folder_id = ?", filter.FolderID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var holds []model.LegalHold
+	err := query.Order("id DESC").
+		Limit(filter.PerPage).
+		Offset((filter.Page - 1) * filter.PerPage).
+		Find(&holds).Error
+	return holds, total, err
+}
+
+func (r *retentionRepositoryImpl) ActiveHolds(ctx context.Context, folderIDs, userIDs []uint) ([]model.LegalHold, error) {
+	var holds []model.LegalHold
+	if len(folderIDs) == 0 && len(userIDs) == 0 {
+		return holds, nil
+	}
+	err := r.db.WithContext(ctx).
+		Where("released_at IS NULL AND (folder_id IN ? OR user_id IN ?)", folderIDs, userIDs).
+		Order("id ASC").
+		Find(&holds).Error
+	return holds, err
+}
+
+func (r *retentionRepositoryImpl) SubtreeHold(ctx context.Context, folderID uint) (*model.LegalHold, error) {
+	var holds []model.LegalHold
+	err := r.db.WithContext(ctx).Raw(`
+WITH RECURSIVE `+subtreeCTE+`
+SELECT h.*
+FROM legal_holds h
+WHERE h.released_at IS NULL AND (
+	h.folder_id IN (SELECT id FROM subtree)
+	OR h.user_id IN (SELECT user_id FROM subtree WHERE workspace_id IS NULL)
+	OR h.user_id IN (
+		SELECT fi.user_id FROM files fi
+		WHERE fi.folder_id IN (SELECT id FROM subtree) AND fi.deleted_at IS NULL AND fi.workspace_id IS NULL
+	)
+)
+ORDER BY h.id
+LIMIT 1`, map[string]interface{}{"folder_id": folderID}).Scan(&holds).Error
+	if err != nil || len(holds) == 0 {
+		return nil, err
+	}
+	return &holds[0], nil
+}
+
+func (r *retentionRepositoryImpl) FolderPath(ctx context.Context, folderID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.WithContext(ctx).Raw(`
+WITH RECURSIVE ancestors AS (
+	SELECT id, parent_folder_id, 0 AS depth FROM folders WHERE id = @folder_id
+	UNION
+	SELECT f.id, f.parent_folder_id, a.depth + 1
+	FROM folders f
+	JOIN ancestors a ON f.id = a.parent_folder_id
+)
+SELECT id FROM ancestors ORDER BY depth`, map[string]interface{}{"folder_id": folderID}).Scan(&ids).Error
+	return ids, err
+}
+
+func (r *retentionRepositoryImpl) NewestFileUnder(ctx context.Context, folderID uint) (*time.Time, error) {
+	var newest *time.Time
+	err := r.db.WithContext(ctx).Raw(`
+WITH RECURSIVE `+subtreeCTE+`
+SELECT MAX(fi.created_at)
+FROM files fi
+WHERE fi.folder_id IN (SELECT id FROM subtree) AND fi.deleted_at IS NULL`,
+		map[string]interface{}{"folder_id": folderID}).Scan(&newest).Error
+	return newest, err
+}
+
+func (r *retentionRepositoryImpl) ListFilesUnder(ctx context.Context, folderID uint, t time.Time, afterID uint, limit int) ([]model.File, error) {
+	var files []model.File
+	err := r.db.WithContext(ctx).
+		Where("folder_id IN (WITH RECURSIVE "+subtreeCTE+" SELECT id FROM subtree) AND created_at < @before AND id > @after_id",
+			map[string]interface{}{"folder_id": folderID, "before": t, "after_id": afterID}).
+		Order("id ASC").
+		Limit(limit).
+		Find(&files).Error
+	return files, err
+}
@@ -0,0 +1,245 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"html"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+type SearchRepository interface {
+	Search(ctx context.Context, userID uint, req *model.SearchRequest) ([]model.SearchResult, int64, error)
+}
+
+type searchRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewSearchRepository(db *gorm.DB) SearchRepository {
+	return &searchRepositoryImpl{
+		db: db,
+	}
+}
+
+// searchRow is a search result along with the total number of matches
+type searchRow struct {
+	model.SearchResult
+	TotalCount int64
+}
+
+// Search finds files and folders visible to the user whose names match the
+// query, either through full-text search or trigram similarity, and files
+// whose indexed content matches the query
+func (r *searchRepositoryImpl) Search(ctx context.Context, userID uint, req *model.SearchRequest) ([]model.SearchResult, int64, error) {
+	args := map[string]interface{}{
+		"user_id": userID,
+		"q":       req.Query,
+		"limit":   req.PerPage,
+		"offset":  (req.Page - 1) * req.PerPage,
+	}
+
+	var sql strings.Builder
+	sql.WriteString("WITH RECURSIVE ")
+	sql.WriteString(accessCTE)
+
+	// Restrict results to a folder subtree
+	if req.FolderID != 0 {
+		args["folder_id"] = req.FolderID
+		sql.WriteString(`,
+scope_folders AS (
+	SELECT id FROM folders WHERE id = @folder_id AND deleted_at IS NULL
+	UNION
+	SELECT f.id FROM folders f JOIN scope_folders s ON f.parent_folder_id = s.id WHERE f.deleted_at IS NULL
+)`)
+	}
+
+	sql.WriteString(",\nresults AS (\n")
+	sql.WriteString(r.fileQuery(req, args))
+
+	// Folders have no type, size or tags, so any of those filters excludes them
+	if req.FileType == "" && req.MinSize == 0 && req.MaxSize == 0 && req.Tag == "" {
+		sql.WriteString("\n\tUNION ALL\n")
+		sql.WriteString(r.folderQuery(req, args))
+	}
+
+	// Snippets are only built for the page being returned since ts_headline
+	// over whole documents is expensive
+	sql.WriteString(`
+),
+page AS (
+	SELECT *, COUNT(*) OVER() AS total_count
+	FROM results
+	ORDER BY rank DESC, updated_at DESC, type, id
+	LIMIT @limit OFFSET @offset
+)
+SELECT p.*, ` + snippetExpr(req) + ` AS snippet
+FROM page p
+LEFT JOIN file_contents fc ON p.type = 'file' AND fc.file_id = p.id
+ORDER BY p.rank DESC, p.updated_at DESC, p.type, p.id`)
+
+	var rows []searchRow
+	if err := r.db.WithContext(ctx).Raw(sql.String(), args).Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	results := make([]model.SearchResult, 0, len(rows))
+	for _, row := range rows {
+		total = row.TotalCount
+		row.Highlight = markedHTML(row.Highlight)
+		row.Snippet = markedHTML(row.Snippet)
+		results = append(results, row.SearchResult)
+	}
+
+	return results, total, nil
+}
+
+// fileQuery builds the files half of the search
+func (r *searchRepositoryImpl) fileQuery(req *model.SearchRequest, args map[string]interface{}) string {
+	conditions := []string{
+		"f.deleted_at IS NULL",
+		"f.id IN (SELECT id FROM accessible_files)",
+	}
+	if req.Query != "" {
+		conditions = append(conditions, "("+nameMatchExpr("f.file_name")+" OR "+contentMatchExpr+")")
+	}
+	conditions = append(conditions, commonConditions("f", req, args)...)
+
+	if req.FolderID != 0 {
+		conditions = append(conditions, "f.folder_id IN (SELECT id FROM scope_folders)")
+	}
+	if req.FileType != "" {
+		args["file_type"] = req.FileType
+		conditions = append(conditions, "f.file_type = @file_type")
+	}
+	if req.MinSize > 0 {
+		args["min_size"] = req.MinSize
+		conditions = append(conditions, "f.file_size >= @min_size")
+	}
+	if req.MaxSize > 0 {
+		args["max_size"] = req.MaxSize
+		conditions = append(conditions, "f.file_size <= @max_size")
+	}
+	if req.Tag != "" {
+		args["tag"] = req.Tag
+		conditions = append(conditions, `EXISTS (
+		SELECT 1 FROM file_tags ft JOIN tags t ON t.id = ft.tag_id
+		WHERE ft.file_id = f.id AND t.name = @tag AND t.user_id IN (@user_id, f.user_id))`)
+	}
+
+	contentMatch, rank := "false", rankExpr("f.file_name", req)
+	if req.Query != "" {
+		contentMatch = "COALESCE(" + contentMatchExpr + ", false)"
+		rank = "GREATEST(" + rank + ", COALESCE(ts_rank(fc.content_tsv, plainto_tsquery('english', @q)), 0))::float8"
+	}
+
+	return `	SELECT 'file' AS type, f.id, f.file_name AS name, ` + highlightExpr("f.file_name", req) + ` AS highlight,
+		f.file_type, f.file_size, f.folder_id, f.user_id AS owner_id, ` + rank + ` AS rank,
+		f.created_at, f.updated_at, ` + contentMatch + ` AS content_match
+	FROM files f
+	LEFT JOIN file_contents fc ON fc.file_id = f.id AND fc.status = 'indexed'
+	WHERE ` + strings.Join(conditions, "\n\t\tAND ")
+}
+
+// folderQuery builds the folders half of the search
+func (r *searchRepositoryImpl) folderQuery(req *model.SearchRequest, args map[string]interface{}) string {
+	conditions := []string{
+		"d.deleted_at IS NULL",
+		"d.id IN (SELECT id FROM accessible_folders)",
+	}
+	if req.Query != "" {
+		conditions = append(conditions, nameMatchExpr("d.folder_name"))
+	}
+	conditions = append(conditions, commonConditions("d", req, args)...)
+
+	if req.FolderID != 0 {
+		conditions = append(conditions, "d.id IN (SELECT id FROM scope_folders)", "d.id <> @folder_id")
+	}
+
+	return `	SELECT 'folder' AS type, d.id, d.folder_name AS name, ` + highlightExpr("d.folder_name", req) + ` AS highlight,
+		'' AS file_type, 0 AS file_size, d.parent_folder_id AS folder_id, d.user_id AS owner_id, ` + rankExpr("d.folder_name", req) + ` AS rank,
+		d.created_at, d.updated_at, false AS content_match
+	FROM folders d
+	WHERE ` + strings.Join(conditions, "\n\t\tAND ")
+}
+
+// contentMatchExpr matches the indexed text of a file joined as fc
+const contentMatchExpr = "fc.content_tsv @@ plainto_tsquery('english', @q)"
+
+// nameMatchExpr matches a name through full-text search or trigram similarity
+func nameMatchExpr(column string) string {
+	return "(to_tsvector('simple', " + column + ") @@ plainto_tsquery('simple', @q)" +
+		" OR " + column + " % @q OR @q <% " + column + ")"
+}
+
+// commonConditions returns the owner and date conditions shared by files
+// and folders
+func commonConditions(alias string, req *model.SearchRequest, args map[string]interface{}) []string {
+	var conditions []string
+
+	if req.OwnerID != 0 {
+		args["owner_id"] = req.OwnerID
+		conditions = append(conditions, alias+".user_id = @owner_id AND "+alias+".workspace_id IS NULL")
+	}
+	if req.From != "" {
+		args["from"] = req.From
+		conditions = append(conditions, alias+".updated_at >= CAST(@from AS date)")
+	}
+	if req.To != "" {
+		args["to"] = req.To
+		conditions = append(conditions, alias+".updated_at < CAST(@to AS date) + 1")
+	}
+
+	return conditions
+}
+
+// rankExpr scores a name against the query, taking the better of the
+// full-text rank and the trigram word similarity
+func rankExpr(column string, req *model.SearchRequest) string {
+	if req.Query == "" {
+		return "0::float8"
+	}
+	return "GREATEST(ts_rank(to_tsvector('simple', " + column + "), plainto_tsquery('simple', @q)), word_similarity(@q, " + column + "))::float8"
+}
+
+// Matched terms are delimited with private-use characters by ts_headline
+// and turned into <mark> tags once the text around them has been escaped, so
+// names and content never reach clients as markup
+const (
+	markStart = "\uE000"
+	markEnd   = "\uE001"
+)
+
+// headlineOptions are ts_headline options delimiting matches with the marks
+func headlineOptions(options string) string {
+	return "'StartSel=' || chr(57344) || ', StopSel=' || chr(57345) || '" + options + "'"
+}
+
+// unmarked removes any mark characters already present in a column
+func unmarked(column string) string {
+	return "translate(" + column + ", chr(57344) || chr(57345), '')"
+}
+
+// highlightExpr delimits the matched terms of a name with the marks
+func highlightExpr(column string, req *model.SearchRequest) string {
+	if req.Query == "" {
+		return unmarked(column)
+	}
+	return "ts_headline('simple', " + unmarked(column) + ", plainto_tsquery('simple', @q), " + headlineOptions(", HighlightAll=true") + ")"
+}
+
+// snippetExpr shows the matching passages of a file's indexed content
+func snippetExpr(req *model.SearchRequest) string {
+	if req.Query == "" {
+		return "''"
+	}
+	return "CASE WHEN p.content_match THEN ts_headline('english', " + unmarked("fc.content") + ", plainto_tsquery('english', @q), " +
+		headlineOptions(", MaxFragments=2, MaxWords=25, MinWords=10") + ") ELSE '' END"
+}
+
+// markedHTML escapes text and turns the marks around matches into <mark> tags
+func markedHTML(text string) string {
+	return strings.NewReplacer(markStart, "<mark>", markEnd, "</mark>").Replace(html.EscapeString(text))
+}
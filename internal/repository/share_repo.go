@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type ShareRepository interface {
+	Create(ctx context.Context, share *model.Share) error
+	FindByID(ctx context.Context, id uint) (*model.Share, error)
+	// FindExisting returns the share of the same file (when FileID is set)
+	// or folder with the same user or group as share, or nil
+	FindExisting(ctx context.Context, share *model.Share) (*model.Share, error)
+	ListByFile(ctx context.Context, fileID uint) ([]model.Share, error)
+	ListByFolder(ctx context.Context, folderID uint) ([]model.Share, error)
+	// ListBySharedWith returns the shares granted to a user, directly or
+	// through the groups they belong to
+	ListBySharedWith(ctx context.Context, userID uint) ([]model.Share, error)
+	// ListByGroup returns the shares granted to a group
+	ListByGroup(ctx context.Context, groupID uint) ([]model.Share, error)
+	Update(ctx context.Context, share *model.Share) error
+	Delete(ctx context.Context, id uint) error
+}
+
+type shareRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewShareRepository(db *gorm.DB) ShareRepository {
+	return &shareRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *shareRepositoryImpl) Create(ctx context.Context, share *model.Share) error {
+	return r.db.WithContext(ctx).Create(share).Error
+}
+
+func (r *shareRepositoryImpl) FindByID(ctx context.Context, id uint) (*model.Share, error) {
+	var share model.Share
+	err := r.db.WithContext(ctx).First(&share, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &share, nil
+}
+
+func (r *shareRepositoryImpl) FindExisting(ctx context.Context, share *model.Share) (*model.Share, error) {
+	query := r.db.WithContext(ctx)
+	if share.GroupID != nil {
+		query = query.Where("group_id = ?", *share.GroupID)
+	} else {
+		query = query.Where("shared_with_id = ?", share.SharedWithID)
+	}
+	if share.FileID != nil {
+		query = query.Where("file_id = ?", *share.FileID)
+	} else {
+		query = query.Where("folder_id = ? AND file_id IS NULL", share.FolderID)
+	}
+
+	var existing model.Share
+	err := query.First(&existing).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &existing, nil
+}
+
+func (r *shareRepositoryImpl) ListByFile(ctx context.Context, fileID uint) ([]model.Share, error) {
+	var shares []model.Share
+	err := r.db.WithContext(ctx).Preload("SharedWith").Preload("Group").Where("file_id = ?", fileID).Order("id").Find(&shares).Error
+	return shares, err
+}
+
+func (r *shareRepositoryImpl) ListByFolder(ctx context.Context, folderID uint) ([]model.Share, error) {
+	var shares []model.Share
+	err := r.db.WithContext(ctx).Preload("SharedWith").Preload("Group").Where("folder_id = ? AND file_id IS NULL", folderID).Order("id").Find(&shares).Error
+	return shares, err
+}
+
+func (r *shareRepositoryImpl) ListBySharedWith(ctx context.Context, userID uint) ([]model.Share, error) {
+	var shares []model.Share
+	err := r.db.WithContext(ctx).Preload("Owner").Preload("File").Preload("Folder").Preload("Group").
+		Where("shared_with_id = ? OR group_id IN (SELECT group_id FROM group_members WHERE user_id = ?)", userID, userID).
+		Order("id DESC").Find(&shares).Error
+	return shares, err
+}
+
+func (r *shareRepositoryImpl) ListByGroup(ctx context.Context, groupID uint) ([]model.Share, error) {
+	var shares []model.Share
+	err := r.db.WithContext(ctx).Where("group_id = ?", groupID).Order("id").Find(&shares).Error
+	return shares, err
+}
+
+func (r *shareRepositoryImpl) Update(ctx context.Context, share *model.Share) error {
+	return r.db.WithContext(ctx).Save(share).Error
+}
+
+func (r *shareRepositoryImpl) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.Share{}, id).Error
+}
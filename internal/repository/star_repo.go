@@ -0,0 +1,74 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StarRepository interface {
+	// Add stars an item, reporting whether it was not starred already
+	Add(ctx context.Context, star *model.Star) (bool, error)
+	// Remove unstars an item, reporting whether it was starred
+	Remove(ctx context.Context, userID uint, itemType model.ItemType, itemID uint) (bool, error)
+	// ListFiles returns the starred files the user can still read, most
+	// recently starred first
+	ListFiles(ctx context.Context, userID uint) ([]model.File, error)
+	// ListFolders returns the starred folders the user can still read, most
+	// recently starred first
+	ListFolders(ctx context.Context, userID uint) ([]model.Folder, error)
+}
+
+type starRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewStarRepository(db *gorm.DB) StarRepository {
+	return &starRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *starRepositoryImpl) Add(ctx context.Context, star *model.Star) (bool, error) {
+	result := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Omit("User").Create(star)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *starRepositoryImpl) Remove(ctx context.Context, userID uint, itemType model.ItemType, itemID uint) (bool, error) {
+	result := r.db.WithContext(ctx).Delete(&model.Star{}, "user_id = ? AND item_type = ? AND item_id = ?", userID, itemType, itemID)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *starRepositoryImpl) ListFiles(ctx context.Context, userID uint) ([]model.File, error) {
+	var files []model.File
+	err := r.db.WithContext(ctx).Raw(`
+WITH RECURSIVE `+accessCTE+`
+SELECT f.*
+FROM stars s
+JOIN files f ON f.id = s.item_id AND f.deleted_at IS NULL
+WHERE s.user_id = @user_id AND s.item_type = @item_type
+	AND f.id IN (SELECT id FROM accessible_files)
+ORDER BY s.created_at DESC, f.id DESC`, map[string]interface{}{
+		"user_id":   userID,
+		"item_type": model.ItemTypeFile,
+	}).Scan(&files).Error
+	return files, err
+}
+
+func (r *starRepositoryImpl) ListFolders(ctx context.Context, userID uint) ([]model.Folder, error) {
+	var folders []model.Folder
+	err := r.db.WithContext(ctx).Raw(`
+WITH RECURSIVE `+accessCTE+`
+SELECT f.*
+FROM stars s
+JOIN folders f ON f.id = s.item_id AND f.deleted_at IS NULL
+WHERE s.user_id = @user_id AND s.item_type = @item_type
+	AND f.id IN (SELECT id FROM accessible_folders)
+ORDER BY s.created_at DESC, f.id DESC`, map[string]interface{}{
+		"user_id":   userID,
+		"item_type": model.ItemTypeFolder,
+	}).Scan(&folders).Error
+	return folders, err
+}
@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TagRepository interface {
+	// FindOrCreate returns the user's tag with the given name, creating it
+	// if needed
+	FindOrCreate(ctx context.Context, userID uint, name string) (*model.Tag, error)
+	// FindByName returns the user's tag with the given name, or nil
+	FindByName(ctx context.Context, userID uint, name string) (*model.Tag, error)
+	// Attach tags a file, reporting whether it was not tagged already
+	Attach(ctx context.Context, tagID, fileID uint) (bool, error)
+	// Detach untags a file, reporting whether it was tagged
+	Detach(ctx context.Context, tagID, fileID uint) (bool, error)
+}
+
+type tagRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewTagRepository(db *gorm.DB) TagRepository {
+	return &tagRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *tagRepositoryImpl) FindOrCreate(ctx context.Context, userID uint, name string) (*model.Tag, error) {
+	tag := &model.Tag{UserID: userID, Name: name}
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Omit("User", "Files").Create(tag).Error
+	if err != nil {
+		return nil, err
+	}
+	if tag.ID != 0 {
+		return tag, nil
+	}
+	// The tag already existed
+	return r.FindByName(ctx, userID, name)
+}
+
+func (r *tagRepositoryImpl) FindByName(ctx context.Context, userID uint, name string) (*model.Tag, error) {
+	var tag model.Tag
+	err := r.db.WithContext(ctx).First(&tag, "user_id = ? AND name = ?", userID, name).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (r *tagRepositoryImpl) Attach(ctx context.Context, tagID, fileID uint) (bool, error) {
+	result := r.db.WithContext(ctx).Exec(
+		"INSERT INTO file_tags (tag_id, file_id) VALUES (?, ?) ON CONFLICT DO NOTHING", tagID, fileID)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *tagRepositoryImpl) Detach(ctx context.Context, tagID, fileID uint) (bool, error) {
+	result := r.db.WithContext(ctx).Exec("DELETE FROM file_tags WHERE tag_id = ? AND file_id = ?", tagID, fileID)
+	return result.RowsAffected > 0, result.Error
+}
@@ -0,0 +1,123 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type TaskRepository interface {
+	// WithLock runs fn while holding the Postgres advisory lock key,
+	// reporting false without running it when another session holds the
+	// lock. The lock is tied to a pinned connection, so it is released even
+	// if this process dies.
+	WithLock(ctx context.Context, key int64, fn func(ctx context.Context) error) (bool, error)
+	// StartRun records a run, reporting false without recording anything
+	// when the task already ran for the same scheduled time
+	StartRun(ctx context.Context, run *model.TaskRun) (bool, error)
+	FinishRun(ctx context.Context, run *model.TaskRun) error
+	// FailRunning marks the task's runs still recorded as running as failed.
+	// It is called while holding the task's lock, when no run can be in
+	// progress.
+	FailRunning(ctx context.Context, task, message string) (int64, error)
+	// ListRuns returns a page of runs, newest first, along with the total
+	ListRuns(ctx context.Context, filter *model.TaskRunFilter) ([]model.TaskRun, int64, error)
+	// LastRuns returns the most recent run of each task, keyed by task
+	LastRuns(ctx context.Context) (map[string]*model.TaskRun, error)
+	// DeleteRunsBefore removes runs started before t
+	DeleteRunsBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+type taskRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewTaskRepository(db *gorm.DB) TaskRepository {
+	return &taskRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *taskRepositoryImpl) WithLock(ctx context.Context, key int64, fn func(ctx context.Context) error) (bool, error) {
+	acquired := false
+	err := r.db.WithContext(ctx).Connection(func(conn *gorm.DB) error {
+		if err := conn.Raw("SELECT pg_try_advisory_lock(?)", key).Scan(&acquired).Error; err != nil || !acquired {
+			return err
+		}
+		// Unlock even when shutting down, or the pooled connection would
+		// keep the lock
+		defer conn.WithContext(context.WithoutCancel(ctx)).Exec("SELECT pg_advisory_unlock(?)", key)
+		return fn(ctx)
+	})
+	return acquired, err
+}
+
+func (r *taskRepositoryImpl) StartRun(ctx context.Context, run *model.TaskRun) (bool, error) {
+	result := r.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(run)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (r *taskRepositoryImpl) FinishRun(ctx context.Context, run *model.TaskRun) error {
+	return r.db.WithContext(ctx).Save(run).Error
+}
+
+func (r *taskRepositoryImpl) FailRunning(ctx context.Context, task, message string) (int64, error) {
+	result := r.db.WithContext(ctx).
+		Model(&model.TaskRun{}).
+		Where("task = ? AND status = ?", task, model.TaskRunRunning).
+		Updates(map[string]interface{}{
+			"status":      model.TaskRunFailed,
+			"error":       message,
+			"finished_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+func (r *taskRepositoryImpl) ListRuns(ctx context.Context, filter *model.TaskRunFilter) ([]model.TaskRun, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.TaskRun{})
+	if filter.Task != "" {
+		query = query.Where("task = ?", filter.Task)
+	}
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var runs []model.TaskRun
+	err := query.Order("started_at DESC, id DESC").
+		Limit(filter.PerPage).
+		Offset((filter.Page - 1) * filter.PerPage).
+		Find(&runs).Error
+	return runs, total, err
+}
+
+func (r *taskRepositoryImpl) LastRuns(ctx context.Context) (map[string]*model.TaskRun, error) {
+	var runs []model.TaskRun
+	err := r.db.WithContext(ctx).Raw(`
+SELECT DISTINCT ON (task) *
+FROM task_runs
+ORDER BY task, started_at DESC, id DESC`).Scan(&runs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	last := make(map[string]*model.TaskRun, len(runs))
+	for i := range runs {
+		last[runs[i].Task] = &runs[i]
+	}
+	return last, nil
+}
+
+func (r *taskRepositoryImpl) DeleteRunsBefore(ctx context.Context, t time.Time) (int64, error) {
+	result := r.db.WithContext(ctx).Delete(&model.TaskRun{}, "started_at < ?", t)
+	return result.RowsAffected, result.Error
+}
@@ -16,6 +16,8 @@ type UserRepository interface {
 	Delete(ctx context.Context, id uint) error
 	GetById(ctx context.Context, id uint) (*model.User, error)
 	GetByUsername(ctx context.Context, username string) (*model.User, error)
+	FindByUsernames(ctx context.Context, usernames []string) ([]model.User, error)
+	AdjustStorageUsed(ctx context.Context, id uint, delta float64) (bool, error)
 }
 
 type userRepositoryImpl struct {
@@ -38,6 +40,16 @@ func (r *userRepositoryImpl) GetByUsername(ctx context.Context, username string)
 	}
 	return &user, nil
 }
+
+func (r *userRepositoryImpl) FindByUsernames(ctx context.Context, usernames []string) ([]model.User, error) {
+	var users []model.User
+	if len(usernames) == 0 {
+		return users, nil
+	}
+	err := r.db.WithContext(ctx).Where("username IN ?", usernames).Find(&users).Error
+	return users, err
+}
+
 func (r *userRepositoryImpl) Create(ctx context.Context, user *model.User) error {
 	return r.db.WithContext(ctx).Create(user).Error
 }
@@ -85,3 +97,18 @@ func (r *userRepositoryImpl) GetById(ctx context.Context, id uint) (*model.User,
 	}
 	return &user, nil
 }
+
+// AdjustStorageUsed adds delta to the user's storage usage. Increases are only
+// applied when they fit within the storage limit; the returned bool reports
+// whether the row was updated.
+func (r *userRepositoryImpl) AdjustStorageUsed(ctx context.Context, id uint, delta float64) (bool, error) {
+	query := r.db.WithContext(ctx).Model(&model.User{}).Where("id = ?", id)
+	if delta > 0 {
+		query = query.Where("storage_used + ? <= storage_limit", delta)
+	}
+	result := query.Update("storage_used", gorm.Expr("GREATEST(storage_used + ?, 0)", delta))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type VaultRepository interface {
+	// SetPublicKey registers or replaces a user's public key
+	SetPublicKey(ctx context.Context, key *model.UserPublicKey) error
+	FindPublicKey(ctx context.Context, userID uint) (*model.UserPublicKey, error)
+	// CreateVault creates a folder as the root of a new vault, storing the
+	// creator's wrapped key with it
+	CreateVault(ctx context.Context, folder *model.Folder, key *model.VaultKey) error
+	// SaveKey stores a member's wrapped key, replacing any previous one
+	SaveKey(ctx context.Context, key *model.VaultKey) error
+	FindKey(ctx context.Context, vaultID, userID uint) (*model.VaultKey, error)
+}
+
+type vaultRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewVaultRepository(db *gorm.DB) VaultRepository {
+	return &vaultRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *vaultRepositoryImpl) SetPublicKey(ctx context.Context, key *model.UserPublicKey) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"algorithm":  key.Algorithm,
+			"public_key": key.PublicKey,
+			"updated_at": time.Now(),
+		}),
+	}).Create(key).Error
+}
+
+func (r *vaultRepositoryImpl) FindPublicKey(ctx context.Context, userID uint) (*model.UserPublicKey, error) {
+	var key model.UserPublicKey
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *vaultRepositoryImpl) CreateVault(ctx context.Context, folder *model.Folder, key *model.VaultKey) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(folder).Error; err != nil {
+			return err
+		}
+		// A vault's root belongs to the vault it starts
+		folder.VaultID = &folder.ID
+		if err := tx.Model(folder).UpdateColumn("vault_id", folder.ID).Error; err != nil {
+			return err
+		}
+		key.VaultID = folder.ID
+		return tx.Create(key).Error
+	})
+}
+
+func (r *vaultRepositoryImpl) SaveKey(ctx context.Context, key *model.VaultKey) error {
+	return r.db.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "vault_id"}, {Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"wrapped_key": key.WrappedKey,
+			"updated_at":  time.Now(),
+		}),
+	}).Create(key).Error
+}
+
+func (r *vaultRepositoryImpl) FindKey(ctx context.Context, vaultID, userID uint) (*model.VaultKey, error) {
+	var key model.VaultKey
+	err := r.db.WithContext(ctx).Where("vault_id = ? AND user_id = ?", vaultID, userID).First(&key).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
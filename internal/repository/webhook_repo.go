@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"encoding/json"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type WebhookRepository interface {
+	Create(ctx context.Context, webhook *model.Webhook) error
+	FindByID(ctx context.Context, id uint) (*model.Webhook, error)
+	ListByUser(ctx context.Context, userID uint) ([]model.Webhook, error)
+	Update(ctx context.Context, webhook *model.Webhook) error
+	Delete(ctx context.Context, id uint) error
+	// FindSubscribers returns the active webhooks subscribed to an event
+	// type that are either global or owned by one of the audience
+	FindSubscribers(ctx context.Context, eventType model.EventType, audience []uint) ([]model.Webhook, error)
+
+	CreateDeliveries(ctx context.Context, deliveries []model.WebhookDelivery) error
+	FindDelivery(ctx context.Context, id uint) (*model.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, webhookID uint, filter *model.WebhookDeliveryFilter) ([]model.WebhookDelivery, int64, error)
+	// ListDueDeliveries returns up to limit pending deliveries whose next
+	// attempt is due, oldest first
+	ListDueDeliveries(ctx context.Context, limit int) ([]model.WebhookDelivery, error)
+	SaveDelivery(ctx context.Context, delivery *model.WebhookDelivery) error
+}
+
+type webhookRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *webhookRepositoryImpl) Create(ctx context.Context, webhook *model.Webhook) error {
+	return r.db.WithContext(ctx).Create(webhook).Error
+}
+
+func (r *webhookRepositoryImpl) FindByID(ctx context.Context, id uint) (*model.Webhook, error) {
+	var webhook model.Webhook
+	err := r.db.WithContext(ctx).First(&webhook, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+func (r *webhookRepositoryImpl) ListByUser(ctx context.Context, userID uint) ([]model.Webhook, error) {
+	var webhooks []model.Webhook
+	err := r.db.WithContext(ctx).Where("user_id = ?", userID).Order("id ASC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *webhookRepositoryImpl) Update(ctx context.Context, webhook *model.Webhook) error {
+	return r.db.WithContext(ctx).Save(webhook).Error
+}
+
+func (r *webhookRepositoryImpl) Delete(ctx context.Context, id uint) error {
+	return r.db.WithContext(ctx).Delete(&model.Webhook{}, id).Error
+}
+
+func (r *webhookRepositoryImpl) FindSubscribers(ctx context.Context, eventType model.EventType, audience []uint) ([]model.Webhook, error) {
+	events, err := json.Marshal([]model.EventType{eventType})
+	if err != nil {
+		return nil, err
+	}
+
+	query := r.db.WithContext(ctx).Where("active AND events @> ?::jsonb", string(events))
+	if len(audience) > 0 {
+		query = query.Where("global OR user_id IN ?", audience)
+	} else {
+		query = query.Where("global")
+	}
+
+	var webhooks []model.Webhook
+	err = query.Find(&webhooks).Error
+	return webhooks, err
+}
+
+func (r *webhookRepositoryImpl) CreateDeliveries(ctx context.Context, deliveries []model.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.WithContext(ctx).Create(&deliveries).Error
+}
+
+func (r *webhookRepositoryImpl) FindDelivery(ctx context.Context, id uint) (*model.WebhookDelivery, error) {
+	var delivery model.WebhookDelivery
+	err := r.db.WithContext(ctx).First(&delivery, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ListDeliveries returns a page of a webhook's deliveries, newest first,
+// along with the total count
+func (r *webhookRepositoryImpl) ListDeliveries(ctx context.Context, webhookID uint, filter *model.WebhookDeliveryFilter) ([]model.WebhookDelivery, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []model.WebhookDelivery
+	err := query.Order("id DESC").
+		Limit(filter.PerPage).
+		Offset((filter.Page - 1) * filter.PerPage).
+		Find(&deliveries).Error
+	return deliveries, total, err
+}
+
+func (r *webhookRepositoryImpl) ListDueDeliveries(ctx context.Context, limit int) ([]model.WebhookDelivery, error) {
+	var deliveries []model.WebhookDelivery
+	err := r.db.WithContext(ctx).
+		Select("id").
+		Where("status = ? AND next_attempt_at <= now()", model.DeliveryPending).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		Find(&deliveries).Error
+	return deliveries, err
+}
+
+func (r *webhookRepositoryImpl) SaveDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	return r.db.WithContext(ctx).Save(delivery).Error
+}
@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"context"
+	"drive/internal/model"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type WorkspaceRepository interface {
+	// Create creates a workspace along with its root folder and first
+	// member, setting the workspace's RootFolderID and the folder's
+	// WorkspaceID
+	Create(ctx context.Context, workspace *model.Workspace, root *model.Folder, member *model.WorkspaceMember) error
+	FindByID(ctx context.Context, id uint) (*model.Workspace, error)
+	Update(ctx context.Context, workspace *model.Workspace) error
+	// Delete removes a workspace's members and moves the workspace and its
+	// root folder, with everything beneath it, to the trash
+	Delete(ctx context.Context, workspace *model.Workspace, root *model.Folder) error
+	// List returns a page of every workspace, along with the total
+	List(ctx context.Context, filter *model.WorkspaceFilter) ([]model.Workspace, int64, error)
+	// AdjustStorageUsed adds delta to the workspace's storage usage.
+	// Increases are only applied when they fit within the storage limit;
+	// the returned bool reports whether the row was updated.
+	AdjustStorageUsed(ctx context.Context, id uint, delta float64) (bool, error)
+
+	FindMember(ctx context.Context, workspaceID, userID uint) (*model.WorkspaceMember, error)
+	// ListMembers returns a workspace's members with their users
+	ListMembers(ctx context.Context, workspaceID uint) ([]model.WorkspaceMember, error)
+	// ListMemberships returns a user's memberships with their workspaces
+	ListMemberships(ctx context.Context, userID uint) ([]model.WorkspaceMember, error)
+	// SaveMember adds a member or changes their role
+	SaveMember(ctx context.Context, member *model.WorkspaceMember) error
+	DeleteMember(ctx context.Context, member *model.WorkspaceMember) error
+	CountManagers(ctx context.Context, workspaceID uint) (int64, error)
+
+	// AdoptFile saves a personal file moved into a workspace folder,
+	// handing it and its storage charge over to the workspace. It reports
+	// false, saving nothing, when the workspace's quota cannot take it.
+	AdoptFile(ctx context.Context, file *model.File, workspaceID uint) (bool, error)
+	// AdoptFolder saves a personal folder moved into a workspace folder,
+	// handing it and everything beneath it, trashed items included, over to
+	// the workspace along with their storage charge. It reports false,
+	// saving nothing, when the workspace's quota cannot take them.
+	AdoptFolder(ctx context.Context, folder *model.Folder, workspaceID uint) (bool, error)
+}
+
+type workspaceRepositoryImpl struct {
+	db *gorm.DB
+}
+
+func NewWorkspaceRepository(db *gorm.DB) WorkspaceRepository {
+	return &workspaceRepositoryImpl{
+		db: db,
+	}
+}
+
+func (r *workspaceRepositoryImpl) Create(ctx context.Context, workspace *model.Workspace, root *model.Folder, member *model.WorkspaceMember) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(workspace).Error; err != nil {
+			return err
+		}
+		root.WorkspaceID = &workspace.ID
+		if err := tx.Create(root).Error; err != nil {
+			return err
+		}
+		workspace.RootFolderID = root.ID
+		if err := tx.Model(workspace).UpdateColumn("root_folder_id", root.ID).Error; err != nil {
+			return err
+		}
+		member.WorkspaceID = workspace.ID
+		return tx.Create(member).Error
+	})
+}
+
+func (r *workspaceRepositoryImpl) FindByID(ctx context.Context, id uint) (*model.Workspace, error) {
+	var workspace model.Workspace
+	err := r.db.WithContext(ctx).First(&workspace, id).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &workspace, nil
+}
+
+func (r *workspaceRepositoryImpl) Update(ctx context.Context, workspace *model.Workspace) error {
+	return r.db.WithContext(ctx).Save(workspace).Error
+}
+
+func (r *workspaceRepositoryImpl) Delete(ctx context.Context, workspace *model.Workspace, root *model.Folder) error {
+	return r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("workspace_id = ?", workspace.ID).Delete(&model.WorkspaceMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(root).Error; err != nil {
+			return err
+		}
+		return tx.Delete(workspace).Error
+	})
+}
+
+func (r *workspaceRepositoryImpl) List(ctx context.Context, filter *model.WorkspaceFilter) ([]model.Workspace, int64, error) {
+	query := r.db.WithContext(ctx).Model(&model.Workspace{})
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var workspaces []model.Workspace
+	err := query.Order("name ASC, id ASC").
+		Limit(filter.PerPage).
+		Offset((filter.Page - 1) * filter.PerPage).
+		Find(&workspaces).Error
+	return workspaces, total, err
+}
+
+func (r *workspaceRepositoryImpl) AdjustStorageUsed(ctx context.Context, id uint, delta float64) (bool, error) {
+	return adjustWorkspaceStorage(r.db.WithContext(ctx), id, delta)
+}
+
+// adjustWorkspaceStorage applies a storage delta within the workspace's limit
+func adjustWorkspaceStorage(db *gorm.DB, id uint, delta float64) (bool, error) {
+	query := db.Model(&model.Workspace{}).Where("id = ?", id)
+	if delta > 0 {
+		query = query.Where("storage_used + ? <= storage_limit", delta)
+	}
+	result := query.Update("storage_used", gorm.Expr("GREATEST(storage_used + ?, 0)", delta))
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *workspaceRepositoryImpl) FindMember(ctx context.Context, workspaceID, userID uint) (*model.WorkspaceMember, error) {
+	var member model.WorkspaceMember
+	err := r.db.WithContext(ctx).Where("workspace_id = ? AND user_id = ?", workspaceID, userID).First(&member).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+func (r *workspaceRepositoryImpl) ListMembers(ctx context.Context, workspaceID uint) ([]model.WorkspaceMember, error) {
+	var members []model.WorkspaceMember
+	err := r.db.WithContext(ctx).Preload("User").Where("workspace_id = ?", workspaceID).Order("id").Find(&members).Error
+	return members, err
+}
+
+func (r *workspaceRepositoryImpl) ListMemberships(ctx context.Context, userID uint) ([]model.WorkspaceMember, error) {
+	var members []model.WorkspaceMember
+	err := r.db.WithContext(ctx).
+		Joins("Workspace").
+		Where("workspace_members.user_id = ?", userID).
+		Order("\"Workspace\".name ASC, workspace_members.id ASC").
+		Find(&members).Error
+	return members, err
+}
+
+func (r *workspaceRepositoryImpl) SaveMember(ctx context.Context, member *model.WorkspaceMember) error {
+	return r.db.WithContext(ctx).Omit("Workspace", "User").Save(member).Error
+}
+
+func (r *workspaceRepositoryImpl) DeleteMember(ctx context.Context, member *model.WorkspaceMember) error {
+	return r.db.WithContext(ctx).Delete(member).Error
+}
+
+func (r *workspaceRepositoryImpl) CountManagers(ctx context.Context, workspaceID uint) (int64, error) {
+	var count int64
+	err := r.db.WithContext(ctx).Model(&model.WorkspaceMember{}).
+		Where("workspace_id = ? AND role = ?", workspaceID, model.WorkspaceManager).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *workspaceRepositoryImpl) AdoptFile(ctx context.Context, file *model.File, workspaceID uint) (bool, error) {
+	adopted := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		megabytes := float64(file.FileSize) / 1048576.0
+		ok, err := adjustWorkspaceStorage(tx, workspaceID, megabytes)
+		if err != nil || !ok {
+			return err
+		}
+		err = tx.Model(&model.User{}).Where("id = ?", file.UserID).
+			Update("storage_used", gorm.Expr("GREATEST(storage_used - ?, 0)", megabytes)).Error
+		if err != nil {
+			return err
+		}
+		file.WorkspaceID = &workspaceID
+		if err := tx.Save(file).Error; err != nil {
+			return err
+		}
+		adopted = true
+		return nil
+	})
+	if err != nil || !adopted {
+		file.WorkspaceID = nil
+	}
+	return adopted, err
+}
+
+func (r *workspaceRepositoryImpl) AdoptFolder(ctx context.Context, folder *model.Folder, workspaceID uint) (bool, error) {
+	adopted := false
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		err := tx.Raw(`
+WITH RECURSIVE subtree AS (
+	SELECT id FROM folders WHERE id = @folder_id
+	UNION
+	SELECT f.id
+	FROM folders f
+	JOIN subtree s ON f.parent_folder_id = s.id
+)
+SELECT id FROM subtree`, map[string]interface{}{"folder_id": folder.ID}).Scan(&ids).Error
+		if err != nil {
+			return err
+		}
+
+		// Every owner of a file beneath the folder is refunded what the
+		// workspace is charged for it
+		var charges []struct {
+			UserID    uint
+			Megabytes float64
+		}
+		err = tx.Raw(`
+SELECT user_id, SUM(file_size) / 1048576.0 AS megabytes
+FROM files
+WHERE folder_id IN @ids AND workspace_id IS NULL
+GROUP BY user_id`, map[string]interface{}{"ids": ids}).Scan(&charges).Error
+		if err != nil {
+			return err
+		}
+		var total float64
+		for _, charge := range charges {
+			total += charge.Megabytes
+		}
+		ok, err := adjustWorkspaceStorage(tx, workspaceID, total)
+		if err != nil || !ok {
+			return err
+		}
+		for _, charge := range charges {
+			err := tx.Model(&model.User{}).Where("id = ?", charge.UserID).
+				Update("storage_used", gorm.Expr("GREATEST(storage_used - ?, 0)", charge.Megabytes)).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		args := map[string]interface{}{"ids": ids, "workspace_id": workspaceID}
+		statements := []string{
+			`UPDATE files SET workspace_id = @workspace_id WHERE folder_id IN @ids`,
+			`UPDATE folders SET workspace_id = @workspace_id WHERE id IN @ids`,
+		}
+		for _, statement := range statements {
+			if err := tx.Exec(statement, args).Error; err != nil {
+				return err
+			}
+		}
+		folder.WorkspaceID = &workspaceID
+		if err := tx.Save(folder).Error; err != nil {
+			return err
+		}
+		adopted = true
+		return nil
+	})
+	if err != nil || !adopted {
+		folder.WorkspaceID = nil
+	}
+	return adopted, err
+}
@@ -37,13 +37,18 @@ type MetaData struct {
 
 // Standard error codes
 const (
-	ErrBadRequest     = "BAD_REQUEST"
-	ErrUnauthorized   = "UNAUTHORIZED"
-	ErrForbidden      = "FORBIDDEN"
-	ErrNotFound       = "NOT_FOUND"
-	ErrInternalServer = "INTERNAL_SERVER_ERROR"
-	ErrValidation     = "VALIDATION_ERROR"
-	ErrDuplicateEntry = "DUPLICATE_ENTRY"
+	ErrBadRequest         = "BAD_REQUEST"
+	ErrUnauthorized       = "UNAUTHORIZED"
+	ErrForbidden          = "FORBIDDEN"
+	ErrNotFound           = "NOT_FOUND"
+	ErrInternalServer     = "INTERNAL_SERVER_ERROR"
+	ErrValidation         = "VALIDATION_ERROR"
+	ErrDuplicateEntry     = "DUPLICATE_ENTRY"
+	ErrQuotaExceeded      = "QUOTA_EXCEEDED"
+	ErrPreconditionFailed = "PRECONDITION_FAILED"
+	ErrLocked             = "LOCKED"
+	ErrRetained           = "RETAINED"
+	ErrScanPending        = "SCAN_PENDING"
 )
 
 // Helper functions for common responses
@@ -0,0 +1,35 @@
+package routes
+
+import (
+	"drive/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func AdminRoutes(r chi.Router, handler *handler.Handler) {
+	r.Route("/admin", func(r chi.Router) {
+		r.Get("/audit", handler.AuditHandler.List)
+		r.Get("/audit/export", handler.AuditHandler.Export)
+		r.Get("/audit/checkpoints", handler.AuditHandler.Checkpoints)
+		r.Get("/locks", handler.LockHandler.ListAll)
+		r.Delete("/locks/{id}", handler.LockHandler.ForceUnlock)
+		r.Get("/jobs", handler.JobHandler.List)
+		r.Get("/jobs/stats", handler.JobHandler.Stats)
+		r.Get("/jobs/{id}", handler.JobHandler.Get)
+		r.Post("/jobs/{id}/retry", handler.JobHandler.Retry)
+		r.Get("/tasks", handler.TaskHandler.List)
+		r.Get("/tasks/runs", handler.TaskHandler.Runs)
+		r.Get("/integrity", handler.IntegrityHandler.Damaged)
+		r.Get("/retention", handler.RetentionHandler.ListPolicies)
+		r.Put("/folders/{id}/retention", handler.RetentionHandler.SetPolicy)
+		r.Delete("/folders/{id}/retention", handler.RetentionHandler.DeletePolicy)
+		r.Get("/legal-holds", handler.RetentionHandler.ListHolds)
+		r.Post("/legal-holds", handler.RetentionHandler.PlaceHold)
+		r.Get("/legal-holds/{id}", handler.RetentionHandler.GetHold)
+		r.Post("/legal-holds/{id}/release", handler.RetentionHandler.ReleaseHold)
+		r.Get("/workspaces", handler.WorkspaceHandler.ListAll)
+		r.Post("/workspaces", handler.WorkspaceHandler.Create)
+		r.Patch("/workspaces/{id}", handler.WorkspaceHandler.Configure)
+		r.Delete("/workspaces/{id}", handler.WorkspaceHandler.Delete)
+	})
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"drive/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func AppPasswordRoutes(r chi.Router, handler *handler.Handler) {
+	r.Route("/app-passwords", func(r chi.Router) {
+		r.Post("/", handler.AppPasswordHandler.Create)
+		r.Get("/", handler.AppPasswordHandler.List)
+		r.Delete("/{id}", handler.AppPasswordHandler.Delete)
+	})
+}
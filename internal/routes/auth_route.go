@@ -10,6 +10,7 @@ func AuthRoutes(r chi.Router, handler *handler.Handler) {
 	r.Route("/auth", func(r chi.Router) {
 		r.Post("/register", handler.UserHandler.Register)
 		r.Post("/login", handler.UserHandler.Login)
+		r.Post("/refresh", handler.UserHandler.Refresh)
 		r.Post("/oauth/login", handler.OAuthHandler.Login)
 	})
 }
@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"drive/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func BatchRoutes(r chi.Router, handler *handler.Handler) {
+	r.Route("/batch", func(r chi.Router) {
+		r.Post("/", handler.BatchHandler.Run)
+		r.Get("/{id}", handler.BatchHandler.Get)
+	})
+
+	r.Get("/starred", handler.StarHandler.List)
+}
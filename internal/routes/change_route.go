@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"drive/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func ChangeRoutes(r chi.Router, handler *handler.Handler) {
+	r.Route("/changes", func(r chi.Router) {
+		r.Get("/", handler.ChangeHandler.List)
+		r.Get("/latest", handler.ChangeHandler.Latest)
+	})
+}
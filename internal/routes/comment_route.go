@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"drive/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func CommentRoutes(r chi.Router, handler *handler.Handler) {
+	r.Route("/comments", func(r chi.Router) {
+		r.Patch("/{id}", handler.CommentHandler.Update)
+		r.Delete("/{id}", handler.CommentHandler.Delete)
+		r.Post("/{id}/resolve", handler.CommentHandler.Resolve)
+		r.Post("/{id}/reopen", handler.CommentHandler.Reopen)
+		r.Get("/{id}/history", handler.CommentHandler.History)
+	})
+}
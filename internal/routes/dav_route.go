@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"drive/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// davMethods are the WebDAV methods chi does not know about
+var davMethods = []string{"PROPFIND", "PROPPATCH", "MKCOL", "COPY", "MOVE", "LOCK", "UNLOCK"}
+
+func DAVRoutes(r chi.Router, handler *handler.Handler) {
+	for _, method := range davMethods {
+		chi.RegisterMethod(method)
+	}
+	r.Handle("/dav", handler.DAVHandler)
+	r.Handle("/dav/*", handler.DAVHandler)
+	r.Handle("/dav-workspaces/*", handler.WorkspaceDAVHandler)
+}
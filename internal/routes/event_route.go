@@ -0,0 +1,14 @@
+package routes
+
+import (
+	"drive/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func EventRoutes(r chi.Router, handler *handler.Handler) {
+	r.Route("/events", func(r chi.Router) {
+		r.Get("/", handler.EventHandler.Stream)
+		r.Get("/ws", handler.EventHandler.WebSocket)
+	})
+}
@@ -0,0 +1,24 @@
+package routes
+
+import (
+	"drive/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func FileRoutes(r chi.Router, handler *handler.Handler) {
+	r.Route("/files", func(r chi.Router) {
+		r.Post("/", handler.FileHandler.Upload)
+		r.Get("/{id}", handler.FileHandler.Get)
+		r.Get("/{id}/download", handler.FileHandler.Download)
+		r.Get("/{id}/preview", handler.PreviewHandler.Preview)
+		r.Put("/{id}/content", handler.FileHandler.ReplaceContent)
+		r.Patch("/{id}", handler.FileHandler.Update)
+		r.Delete("/{id}", handler.FileHandler.Delete)
+		r.Post("/{id}/lock", handler.LockHandler.Lock)
+		r.Delete("/{id}/lock", handler.LockHandler.Unlock)
+		r.Get("/{id}/locks", handler.LockHandler.List)
+		r.Get("/{id}/comments", handler.CommentHandler.List)
+		r.Post("/{id}/comments", handler.CommentHandler.Create)
+	})
+}
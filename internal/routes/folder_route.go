@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"drive/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func FolderRoutes(r chi.Router, handler *handler.Handler) {
+	r.Route("/folders", func(r chi.Router) {
+		r.Post("/", handler.FolderHandler.Create)
+		r.Get("/root", handler.FolderHandler.GetRoot)
+		r.Get("/{id}", handler.FolderHandler.Get)
+		r.Patch("/{id}", handler.FolderHandler.Update)
+		r.Delete("/{id}", handler.FolderHandler.Delete)
+	})
+}
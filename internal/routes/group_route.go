@@ -0,0 +1,20 @@
+package routes
+
+import (
+	"drive/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func GroupRoutes(r chi.Router, handler *handler.Handler) {
+	r.Route("/groups", func(r chi.Router) {
+		r.Get("/", handler.GroupHandler.List)
+		r.Post("/", handler.GroupHandler.Create)
+		r.Get("/{id}", handler.GroupHandler.Get)
+		r.Patch("/{id}", handler.GroupHandler.Update)
+		r.Delete("/{id}", handler.GroupHandler.Delete)
+		r.Get("/{id}/members", handler.GroupHandler.ListMembers)
+		r.Post("/{id}/members", handler.GroupHandler.AddMember)
+		r.Delete("/{id}/members/{userID}", handler.GroupHandler.RemoveMember)
+	})
+}
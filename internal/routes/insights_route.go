@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"drive/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func InsightsRoutes(r chi.Router, handler *handler.Handler) {
+	r.Route("/insights", func(r chi.Router) {
+		r.Get("/", handler.InsightsHandler.Get)
+		r.Get("/duplicates", handler.InsightsHandler.Duplicates)
+		r.Post("/duplicates/resolve", handler.InsightsHandler.ResolveDuplicates)
+	})
+}
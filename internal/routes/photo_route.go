@@ -0,0 +1,27 @@
+package routes
+
+import (
+	"drive/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func PhotoRoutes(r chi.Router, handler *handler.Handler) {
+	r.Route("/photos", func(r chi.Router) {
+		r.Get("/", handler.PhotoHandler.List)
+		r.Get("/timeline", handler.PhotoHandler.Timeline)
+		r.Get("/settings", handler.PhotoHandler.Settings)
+		r.Put("/settings", handler.PhotoHandler.UpdateSettings)
+	})
+
+	r.Route("/albums", func(r chi.Router) {
+		r.Get("/", handler.AlbumHandler.List)
+		r.Post("/", handler.AlbumHandler.Create)
+		r.Get("/{id}", handler.AlbumHandler.Get)
+		r.Patch("/{id}", handler.AlbumHandler.Update)
+		r.Delete("/{id}", handler.AlbumHandler.Delete)
+		r.Get("/{id}/photos", handler.AlbumHandler.ListPhotos)
+		r.Post("/{id}/photos", handler.AlbumHandler.AddPhotos)
+		r.Delete("/{id}/photos/{fileID}", handler.AlbumHandler.RemovePhoto)
+	})
+}
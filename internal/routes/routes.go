@@ -7,16 +7,18 @@ import (
 	chimiddleware "github.com/go-chi/chi/v5/middleware"
 
 	"drive/internal/handler"
+	"drive/internal/middleware"
 	"drive/internal/service"
 )
 
-func SetupRoutes(h *handler.Handler, authService service.AuthService) http.Handler {
+func SetupRoutes(h *handler.Handler, authService service.AuthService, appPasswords service.AppPasswordService) http.Handler {
 	r := chi.NewRouter()
 
-	r.Use(chimiddleware.Logger)
+	r.Use(middleware.Logger)
 	r.Use(chimiddleware.Recoverer)
 	r.Use(chimiddleware.RequestID)
 	r.Use(chimiddleware.RealIP)
+	r.Use(middleware.RequestMeta)
 
 	r.Route("/api", func(r chi.Router) {
 		// Health check route
@@ -29,6 +31,44 @@ func SetupRoutes(h *handler.Handler, authService service.AuthService) http.Handl
 			AuthRoutes(r, h)
 		})
 
+		// Authenticated routes
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.Auth(authService))
+			FileRoutes(r, h)
+			CommentRoutes(r, h)
+			FolderRoutes(r, h)
+			ChangeRoutes(r, h)
+			AppPasswordRoutes(r, h)
+			ShareRoutes(r, h)
+			SearchRoutes(r, h)
+			PhotoRoutes(r, h)
+			InsightsRoutes(r, h)
+			BatchRoutes(r, h)
+			WebhookRoutes(r, h)
+			VaultRoutes(r, h)
+			WorkspaceRoutes(r, h)
+			GroupRoutes(r, h)
+
+			// Administrator routes
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireAdmin)
+				AdminRoutes(r, h)
+			})
+		})
+
+		// Event streams, which browsers cannot open with an Authorization header
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.QueryToken)
+			r.Use(middleware.Auth(authService))
+			EventRoutes(r, h)
+		})
+
+	})
+
+	// WebDAV, authenticated with app passwords or bearer tokens
+	r.Group(func(r chi.Router) {
+		r.Use(middleware.DAVAuth(authService, appPasswords))
+		DAVRoutes(r, h)
 	})
 
 	return r
@@ -0,0 +1,11 @@
+package routes
+
+import (
+	"drive/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func SearchRoutes(r chi.Router, handler *handler.Handler) {
+	r.Get("/search", handler.SearchHandler.Search)
+}
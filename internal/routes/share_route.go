@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"drive/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func ShareRoutes(r chi.Router, handler *handler.Handler) {
+	r.Route("/shares", func(r chi.Router) {
+		r.Post("/", handler.ShareHandler.Create)
+		r.Get("/", handler.ShareHandler.List)
+		r.Get("/received", handler.ShareHandler.ListReceived)
+		r.Patch("/{id}", handler.ShareHandler.Update)
+		r.Delete("/{id}", handler.ShareHandler.Delete)
+	})
+}
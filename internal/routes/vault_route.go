@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"drive/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func VaultRoutes(r chi.Router, handler *handler.Handler) {
+	r.Route("/vault", func(r chi.Router) {
+		r.Put("/public-key", handler.VaultHandler.SetPublicKey)
+		r.Get("/public-key", handler.VaultHandler.GetOwnPublicKey)
+		r.Get("/public-keys", handler.VaultHandler.GetPublicKey)
+		r.Get("/keys/{folderID}", handler.VaultHandler.GetKey)
+	})
+}
@@ -0,0 +1,19 @@
+package routes
+
+import (
+	"drive/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func WebhookRoutes(r chi.Router, handler *handler.Handler) {
+	r.Route("/webhooks", func(r chi.Router) {
+		r.Post("/", handler.WebhookHandler.Create)
+		r.Get("/", handler.WebhookHandler.List)
+		r.Get("/{id}", handler.WebhookHandler.Get)
+		r.Patch("/{id}", handler.WebhookHandler.Update)
+		r.Delete("/{id}", handler.WebhookHandler.Delete)
+		r.Get("/{id}/deliveries", handler.WebhookHandler.ListDeliveries)
+		r.Post("/{id}/deliveries/{deliveryID}/redeliver", handler.WebhookHandler.Redeliver)
+	})
+}
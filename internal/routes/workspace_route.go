@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"drive/internal/handler"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func WorkspaceRoutes(r chi.Router, handler *handler.Handler) {
+	r.Route("/workspaces", func(r chi.Router) {
+		r.Get("/", handler.WorkspaceHandler.List)
+		r.Get("/{id}", handler.WorkspaceHandler.Get)
+		r.Patch("/{id}", handler.WorkspaceHandler.Update)
+		r.Get("/{id}/members", handler.WorkspaceHandler.ListMembers)
+		r.Put("/{id}/members/{userID}", handler.WorkspaceHandler.SetMember)
+		r.Delete("/{id}/members/{userID}", handler.WorkspaceHandler.RemoveMember)
+	})
+}
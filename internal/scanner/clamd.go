@@ -0,0 +1,162 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	// clamdChunkSize is the largest chunk sent per INSTREAM frame
+	clamdChunkSize = 64 << 10
+	// clamdDialTimeout bounds connecting to clamd
+	clamdDialTimeout = 10 * time.Second
+)
+
+// Clamd scans content with a ClamAV daemon using the INSTREAM command
+type Clamd struct {
+	network string
+	address string
+}
+
+// NewClamd creates a scanner for the clamd listening at address, given as
+// tcp://host:port, unix:///path/to/clamd.sock, host:port or an absolute
+// socket path
+func NewClamd(address string) (*Clamd, error) {
+	switch {
+	case strings.HasPrefix(address, "tcp://"):
+		return &Clamd{network: "tcp", address: strings.TrimPrefix(address, "tcp://")}, nil
+	case strings.HasPrefix(address, "unix://"):
+		return &Clamd{network: "unix", address: strings.TrimPrefix(address, "unix://")}, nil
+	case strings.HasPrefix(address, "/"):
+		return &Clamd{network: "unix", address: address}, nil
+	case strings.Contains(address, ":"):
+		return &Clamd{network: "tcp", address: address}, nil
+	default:
+		return nil, fmt.Errorf("invalid clamd address %q", address)
+	}
+}
+
+// Name implements Scanner
+func (c *Clamd) Name() string {
+	return "clamd"
+}
+
+// Scan implements Scanner by streaming the content to clamd
+func (c *Clamd) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	dialer := net.Dialer{Timeout: clamdDialTimeout}
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("connecting to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	// Unblock reads and writes once the context ends
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	reply := make(chan clamdReply, 1)
+	go func() {
+		reply <- readClamdReply(conn)
+	}()
+
+	if err := writeInstream(conn, r); err != nil {
+		// clamd closes the connection early when the stream exceeds its
+		// limit; its reply explains why
+		select {
+		case got := <-reply:
+			if got.err == nil {
+				return parseClamdReply(got.line)
+			}
+		case <-time.After(time.Second):
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("streaming to clamd: %w", err)
+	}
+
+	got := <-reply
+	if got.err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, fmt.Errorf("reading clamd reply: %w", got.err)
+	}
+	return parseClamdReply(got.line)
+}
+
+// writeInstream sends the INSTREAM command followed by the content in
+// length-prefixed chunks and the zero-length terminator
+func writeInstream(w io.Writer, r io.Reader) error {
+	bw := bufio.NewWriterSize(w, clamdChunkSize+4)
+	if _, err := bw.WriteString("zINSTREAM\x00"); err != nil {
+		return err
+	}
+	buf := make([]byte, clamdChunkSize)
+	size := make([]byte, 4)
+	for {
+		n, err := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := bw.Write(size); err != nil {
+				return err
+			}
+			if _, err := bw.Write(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("reading content: %w", err)
+		}
+	}
+	binary.BigEndian.PutUint32(size, 0)
+	if _, err := bw.Write(size); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+type clamdReply struct {
+	line string
+	err  error
+}
+
+// readClamdReply reads clamd's NUL-terminated reply
+func readClamdReply(r io.Reader) clamdReply {
+	line, err := bufio.NewReader(r).ReadBytes(0)
+	if err != nil && (err != io.EOF || len(line) == 0) {
+		return clamdReply{err: err}
+	}
+	return clamdReply{line: string(bytes.TrimRight(line, "\x00\n"))}
+}
+
+// parseClamdReply interprets replies such as "stream: OK",
+// "stream: Eicar-Signature FOUND" and "INSTREAM size limit exceeded. ERROR"
+func parseClamdReply(line string) (*Result, error) {
+	verdict := strings.TrimSpace(line)
+	if i := strings.Index(verdict, ": "); i >= 0 {
+		verdict = verdict[i+2:]
+	}
+	switch {
+	case verdict == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return &Result{Infected: true, Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	case strings.HasSuffix(verdict, " ERROR"):
+		return nil, fmt.Errorf("%w: %s", ErrScanFailed, strings.TrimSuffix(verdict, " ERROR"))
+	default:
+		return nil, fmt.Errorf("unexpected clamd reply %q", line)
+	}
+}
@@ -0,0 +1,130 @@
+package scanner_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"drive/internal/scanner"
+	"drive/internal/scanner/scannertest"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+func startClamd(t *testing.T, reply scannertest.Reply) (*scannertest.Clamd, *scanner.Clamd) {
+	t.Helper()
+	fake, err := scannertest.NewClamd(reply)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { fake.Close() })
+	clamd, err := scanner.NewClamd(fake.Address())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fake, clamd
+}
+
+func TestClamdScan(t *testing.T) {
+	large := bytes.Repeat([]byte("0123456789abcdef"), 20000)
+
+	tests := []struct {
+		name    string
+		reply   scannertest.Reply
+		content []byte
+		want    *scanner.Result
+	}{
+		{name: "clean", reply: scannertest.OK, content: []byte("hello"), want: &scanner.Result{}},
+		{name: "empty", reply: scannertest.OK, content: []byte{}, want: &scanner.Result{}},
+		{name: "several chunks", reply: scannertest.OK, content: large, want: &scanner.Result{}},
+		{
+			name:    "infected",
+			reply:   scannertest.Found("EICAR", "Eicar-Test-Signature"),
+			content: []byte("prefix " + eicar),
+			want:    &scanner.Result{Infected: true, Signature: "Eicar-Test-Signature"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, clamd := startClamd(t, tt.reply)
+
+			got, err := clamd.Scan(context.Background(), bytes.NewReader(tt.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if *got != *tt.want {
+				t.Errorf("result = %+v, want %+v", got, tt.want)
+			}
+			streams := fake.Streams()
+			if len(streams) != 1 || !bytes.Equal(streams[0], tt.content) {
+				t.Errorf("clamd did not receive the content intact")
+			}
+		})
+	}
+}
+
+func TestClamdScanFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		reply     scannertest.Reply
+		maxStream int64
+		timeout   time.Duration
+		down      bool
+		// failed is whether the error is a verdict failure, which is final,
+		// rather than clamd being unavailable, which is retried
+		failed bool
+		want   error
+	}{
+		{name: "clamd error", reply: scannertest.Error, failed: true},
+		{name: "size limit", reply: scannertest.OK, maxStream: 1024, failed: true},
+		{name: "timeout", reply: scannertest.Hang, timeout: 100 * time.Millisecond, want: context.DeadlineExceeded},
+		{name: "unexpected reply", reply: func([]byte) string { return "stream: who knows" }},
+		{name: "down", reply: scannertest.OK, down: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake, clamd := startClamd(t, tt.reply)
+			fake.StreamMaxLength = tt.maxStream
+			if tt.down {
+				fake.Close()
+			}
+			ctx := context.Background()
+			if tt.timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, tt.timeout)
+				defer cancel()
+			}
+
+			start := time.Now()
+			got, err := clamd.Scan(ctx, strings.NewReader(strings.Repeat("x", 256<<10)))
+			if err == nil {
+				t.Fatalf("scan succeeded with %+v", got)
+			}
+			if errors.Is(err, scanner.ErrScanFailed) != tt.failed {
+				t.Errorf("err = %v, ErrScanFailed = %v, want %v", err, !tt.failed, tt.failed)
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+			if elapsed := time.Since(start); elapsed > 5*time.Second {
+				t.Errorf("scan took %s to fail", elapsed)
+			}
+		})
+	}
+}
+
+func TestNewClamd(t *testing.T) {
+	for _, address := range []string{"tcp://localhost:3310", "unix:///run/clamav/clamd.ctl", "/run/clamd.sock", "localhost:3310"} {
+		if _, err := scanner.NewClamd(address); err != nil {
+			t.Errorf("NewClamd(%q): %v", address, err)
+		}
+	}
+	if _, err := scanner.NewClamd("clamd"); err == nil {
+		t.Error(`NewClamd("clamd") succeeded`)
+	}
+}
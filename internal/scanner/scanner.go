@@ -0,0 +1,31 @@
+// Package scanner checks uploaded content for malware.
+package scanner
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrScanFailed is returned when the scanner examined the content but could
+// not reach a verdict, e.g. because it exceeds the scanner's size limit.
+// Other errors mean the scanner could not be reached and the scan may be
+// retried.
+var ErrScanFailed = errors.New("scan failed")
+
+// Result is the verdict on scanned content
+type Result struct {
+	// Infected reports whether malware was found
+	Infected bool
+	// Signature names the malware found, if any
+	Signature string
+}
+
+// Scanner checks content for malware
+type Scanner interface {
+	// Name identifies the scanner, e.g. "clamd"
+	Name() string
+	// Scan reads the content from r and returns the verdict.
+	// Implementations should return promptly once ctx is done.
+	Scan(ctx context.Context, r io.Reader) (*Result, error)
+}
@@ -0,0 +1,157 @@
+// Package scannertest provides a fake ClamAV daemon for testing code that
+// scans content with scanner.Clamd.
+package scannertest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"sync"
+)
+
+// Reply decides clamd's reply to a stream, e.g. "stream: OK". An empty
+// reply holds the connection open without answering, like a hung daemon.
+type Reply func(content []byte) string
+
+// Replies for common verdicts
+var (
+	OK    Reply = func([]byte) string { return "stream: OK" }
+	Error Reply = func([]byte) string { return "stream: Can't allocate memory ERROR" }
+	Hang  Reply = func([]byte) string { return "" }
+)
+
+// Found returns a Reply reporting signature for content containing marker
+// and OK for anything else
+func Found(marker, signature string) Reply {
+	return func(content []byte) string {
+		if bytes.Contains(content, []byte(marker)) {
+			return "stream: " + signature + " FOUND"
+		}
+		return "stream: OK"
+	}
+}
+
+// Clamd is a fake clamd listening on a local TCP port. It answers the
+// INSTREAM command only.
+type Clamd struct {
+	// StreamMaxLength makes clamd reply with a size limit error and close
+	// the connection once a stream exceeds it, as the real daemon does. It
+	// must be set before the first scan.
+	StreamMaxLength int64
+
+	listener net.Listener
+	reply    Reply
+
+	mu      sync.Mutex
+	conns   map[net.Conn]struct{}
+	streams [][]byte
+	wg      sync.WaitGroup
+}
+
+// NewClamd starts a fake clamd answering with reply
+func NewClamd(reply Reply) (*Clamd, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	c := &Clamd{listener: listener, reply: reply, conns: make(map[net.Conn]struct{})}
+	c.wg.Add(1)
+	go c.serve()
+	return c, nil
+}
+
+// Address returns the address to pass to scanner.NewClamd
+func (c *Clamd) Address() string {
+	return "tcp://" + c.listener.Addr().String()
+}
+
+// Streams returns the content of every complete stream received
+func (c *Clamd) Streams() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([][]byte(nil), c.streams...)
+}
+
+// Close stops listening and drops open connections, after which scans fail
+// as if clamd were down
+func (c *Clamd) Close() error {
+	err := c.listener.Close()
+	c.mu.Lock()
+	for conn := range c.conns {
+		conn.Close()
+	}
+	c.mu.Unlock()
+	c.wg.Wait()
+	return err
+}
+
+func (c *Clamd) serve() {
+	defer c.wg.Done()
+	for {
+		conn, err := c.listener.Accept()
+		if err != nil {
+			return
+		}
+		c.mu.Lock()
+		c.conns[conn] = struct{}{}
+		c.mu.Unlock()
+
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.handle(conn)
+			c.mu.Lock()
+			delete(c.conns, conn)
+			c.mu.Unlock()
+			conn.Close()
+		}()
+	}
+}
+
+// handle reads one INSTREAM command and answers it
+func (c *Clamd) handle(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	command, err := r.ReadString(0)
+	if err != nil {
+		return
+	}
+	if command != "zINSTREAM\x00" {
+		conn.Write([]byte("UNKNOWN COMMAND\x00"))
+		return
+	}
+
+	var content []byte
+	size := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, size); err != nil {
+			return
+		}
+		n := binary.BigEndian.Uint32(size)
+		if n == 0 {
+			break
+		}
+		if c.StreamMaxLength > 0 && int64(len(content))+int64(n) > c.StreamMaxLength {
+			conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+			return
+		}
+		chunk := make([]byte, n)
+		if _, err := io.ReadFull(r, chunk); err != nil {
+			return
+		}
+		content = append(content, chunk...)
+	}
+
+	c.mu.Lock()
+	c.streams = append(c.streams, content)
+	c.mu.Unlock()
+
+	reply := c.reply(content)
+	if reply == "" {
+		// Hang until the client gives up or the daemon is closed
+		io.Copy(io.Discard, r)
+		return
+	}
+	conn.Write([]byte(reply + "\x00"))
+}
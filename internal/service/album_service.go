@@ -0,0 +1,210 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrAlbumNotFound      = errors.New("album not found")
+	ErrInvalidAlbumPhoto  = errors.New("only images you can read can be added to an album")
+	ErrCoverNotInAlbum    = errors.New("the cover must be one of the album's photos")
+	ErrAlbumPhotoNotFound = errors.New("photo is not in the album")
+)
+
+// AlbumService manages users' photo albums. Albums are private to their
+// owner and reference photos rather than copying them; photos the owner can
+// no longer read are hidden from the album.
+type AlbumService interface {
+	// List returns the user's albums, most recently changed first
+	List(ctx context.Context, userID uint) ([]model.Album, error)
+	// Create creates an empty album
+	Create(ctx context.Context, userID uint, req *model.CreateAlbumRequest) (*model.Album, error)
+	// Get returns one of the user's albums
+	Get(ctx context.Context, userID, albumID uint) (*model.Album, error)
+	// Update renames an album or changes its description or cover
+	Update(ctx context.Context, userID, albumID uint, req *model.UpdateAlbumRequest) (*model.Album, error)
+	// Delete removes an album, leaving its photos alone
+	Delete(ctx context.Context, userID, albumID uint) error
+	// ListPhotos returns a page of an album's photos, most recently added
+	// first
+	ListPhotos(ctx context.Context, userID, albumID uint, filter *model.AlbumPhotoFilter) ([]model.File, int64, error)
+	// AddPhotos adds images the user can read to an album. Photos already
+	// in the album are skipped.
+	AddPhotos(ctx context.Context, userID, albumID uint, req *model.AddAlbumPhotosRequest) (*model.Album, error)
+	// RemovePhoto takes a photo out of an album
+	RemovePhoto(ctx context.Context, userID, albumID, fileID uint) error
+}
+
+type albumService struct {
+	albumRepo repository.AlbumRepository
+	logger    *util.Logger
+}
+
+// NewAlbumService creates a new AlbumService instance
+func NewAlbumService(albumRepo repository.AlbumRepository, logger *util.Logger) AlbumService {
+	return &albumService{
+		albumRepo: albumRepo,
+		logger:    logger,
+	}
+}
+
+// List returns the user's albums
+func (s *albumService) List(ctx context.Context, userID uint) ([]model.Album, error) {
+	albums, err := s.albumRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing albums: %w", err)
+	}
+	return albums, nil
+}
+
+// Create creates an empty album
+func (s *albumService) Create(ctx context.Context, userID uint, req *model.CreateAlbumRequest) (*model.Album, error) {
+	album := &model.Album{
+		UserID:      userID,
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	if err := s.albumRepo.Create(ctx, album); err != nil {
+		s.logger.Error("Error creating album", util.WithUserID(userID), util.WithError(err))
+		return nil, fmt.Errorf("error creating album: %w", err)
+	}
+	return album, nil
+}
+
+// Get returns one of the user's albums
+func (s *albumService) Get(ctx context.Context, userID, albumID uint) (*model.Album, error) {
+	album, err := s.albumRepo.FindByID(ctx, albumID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding album: %w", err)
+	}
+	// Other users' albums are reported as missing rather than forbidden
+	if album == nil || album.UserID != userID {
+		return nil, ErrAlbumNotFound
+	}
+	return album, nil
+}
+
+// Update renames an album or changes its description or cover
+func (s *albumService) Update(ctx context.Context, userID, albumID uint, req *model.UpdateAlbumRequest) (*model.Album, error) {
+	album, err := s.Get(ctx, userID, albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		album.Name = *req.Name
+	}
+	if req.Description != nil {
+		album.Description = *req.Description
+	}
+	if req.CoverFileID != nil {
+		if *req.CoverFileID == 0 {
+			album.CoverFileID = nil
+		} else {
+			inAlbum, err := s.albumRepo.HasPhoto(ctx, album.ID, *req.CoverFileID)
+			if err != nil {
+				return nil, fmt.Errorf("error finding album photo: %w", err)
+			}
+			if !inAlbum {
+				return nil, ErrCoverNotInAlbum
+			}
+			album.CoverFileID = req.CoverFileID
+		}
+	}
+
+	if err := s.albumRepo.Update(ctx, album); err != nil {
+		s.logger.Error("Error updating album", util.WithUserID(userID), zap.Uint("album_id", albumID), util.WithError(err))
+		return nil, fmt.Errorf("error updating album: %w", err)
+	}
+	return album, nil
+}
+
+// Delete removes an album
+func (s *albumService) Delete(ctx context.Context, userID, albumID uint) error {
+	album, err := s.Get(ctx, userID, albumID)
+	if err != nil {
+		return err
+	}
+	if err := s.albumRepo.Delete(ctx, album); err != nil {
+		s.logger.Error("Error deleting album", util.WithUserID(userID), zap.Uint("album_id", albumID), util.WithError(err))
+		return fmt.Errorf("error deleting album: %w", err)
+	}
+	return nil
+}
+
+// ListPhotos returns a page of an album's photos
+func (s *albumService) ListPhotos(ctx context.Context, userID, albumID uint, filter *model.AlbumPhotoFilter) ([]model.File, int64, error) {
+	album, err := s.Get(ctx, userID, albumID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 {
+		filter.PerPage = defaultPhotoPerPage
+	}
+	if filter.PerPage > maxPhotoPerPage {
+		filter.PerPage = maxPhotoPerPage
+	}
+
+	photos, total, err := s.albumRepo.ListPhotos(ctx, album, filter.Page, filter.PerPage)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing album photos: %w", err)
+	}
+	return photos, total, nil
+}
+
+// AddPhotos adds images the user can read to an album. The request fails
+// as a whole if any of the files is not such an image.
+func (s *albumService) AddPhotos(ctx context.Context, userID, albumID uint, req *model.AddAlbumPhotosRequest) (*model.Album, error) {
+	album, err := s.Get(ctx, userID, albumID)
+	if err != nil {
+		return nil, err
+	}
+
+	requested := make(map[uint]bool, len(req.FileIDs))
+	for _, id := range req.FileIDs {
+		requested[id] = true
+	}
+	readable, err := s.albumRepo.FilterReadablePhotos(ctx, userID, req.FileIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error checking photos: %w", err)
+	}
+	if len(readable) != len(requested) {
+		return nil, ErrInvalidAlbumPhoto
+	}
+
+	added, err := s.albumRepo.AddPhotos(ctx, album.ID, readable)
+	if err != nil {
+		s.logger.Error("Error adding photos to album", util.WithUserID(userID), zap.Uint("album_id", albumID), util.WithError(err))
+		return nil, fmt.Errorf("error adding photos to album: %w", err)
+	}
+	s.logger.Info("Photos added to album", util.WithUserID(userID), zap.Uint("album_id", albumID), zap.Int64("added", added))
+
+	return s.Get(ctx, userID, albumID)
+}
+
+// RemovePhoto takes a photo out of an album
+func (s *albumService) RemovePhoto(ctx context.Context, userID, albumID, fileID uint) error {
+	album, err := s.Get(ctx, userID, albumID)
+	if err != nil {
+		return err
+	}
+	removed, err := s.albumRepo.RemovePhoto(ctx, album.ID, fileID)
+	if err != nil {
+		return fmt.Errorf("error removing photo from album: %w", err)
+	}
+	if !removed {
+		return ErrAlbumPhotoNotFound
+	}
+	return nil
+}
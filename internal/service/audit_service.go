@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"drive/internal/auditchain"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultAuditPerPage = 50
+	maxAuditPerPage     = 500
+	auditExportBatch    = 500
+)
+
+// AuditConfig holds the keys protecting the integrity of the audit log
+type AuditConfig struct {
+	// HMACKey authenticates every entry hash. Optional.
+	HMACKey []byte
+	// SigningKey signs periodic checkpoints. Checkpoints are disabled when nil.
+	SigningKey         ed25519.PrivateKey
+	CheckpointInterval time.Duration
+}
+
+// AuditService records and queries the security audit log
+type AuditService interface {
+	// Record appends an entry, filling in the request metadata from ctx.
+	// Failures are logged rather than returned so auditing never breaks the
+	// audited action.
+	Record(ctx context.Context, entry *model.AuditLog)
+	// List returns a page of entries matching the filter, newest first
+	List(ctx context.Context, filter *model.AuditLogFilter) ([]model.AuditLog, int64, error)
+	// Export calls fn for every entry matching the filter, oldest first
+	Export(ctx context.Context, filter *model.AuditLogFilter, fn func(*model.AuditLog) error) error
+	// Checkpoint writes a signed checkpoint of the chain head if the chain
+	// has grown since the last one. It returns nil when signing is disabled.
+	Checkpoint(ctx context.Context) (*model.AuditCheckpoint, error)
+	// Checkpoints returns every checkpoint with the key needed to verify them
+	Checkpoints(ctx context.Context) (*model.AuditCheckpointExport, error)
+	// Start seals any unchained entries and launches the periodic checkpoint writer
+	Start(ctx context.Context)
+	// Stop waits for the checkpoint writer to exit and pins the final
+	// chain head with one last checkpoint
+	Stop()
+}
+
+type auditService struct {
+	auditRepo repository.AuditRepository
+	config    AuditConfig
+	logger    *util.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewAuditService creates a new AuditService instance
+func NewAuditService(auditRepo repository.AuditRepository, config AuditConfig, logger *util.Logger) AuditService {
+	if config.CheckpointInterval <= 0 {
+		config.CheckpointInterval = time.Hour
+	}
+	return &auditService{
+		auditRepo: auditRepo,
+		config:    config,
+		logger:    logger,
+	}
+}
+
+// Record appends an entry to the audit log
+func (s *auditService) Record(ctx context.Context, entry *model.AuditLog) {
+	meta := util.RequestMetaFromContext(ctx)
+	entry.IP = meta.IP
+	entry.RequestID = meta.RequestID
+	entry.UserAgent = meta.UserAgent
+	if entry.Outcome == "" {
+		entry.Outcome = model.AuditSuccess
+	}
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+	entry.CreatedAt = auditchain.NormalizeTime(entry.CreatedAt)
+
+	// Record even if the request was cancelled after the action completed
+	if err := s.auditRepo.Append(context.WithoutCancel(ctx), entry, s.seal); err != nil {
+		s.logger.Error("Error writing audit log entry",
+			zap.String("action", string(entry.Action)),
+			zap.String("request_id", entry.RequestID),
+			util.WithError(err))
+	}
+}
+
+// List returns a page of entries matching the filter, newest first
+func (s *auditService) List(ctx context.Context, filter *model.AuditLogFilter) ([]model.AuditLog, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 {
+		filter.PerPage = defaultAuditPerPage
+	}
+	if filter.PerPage > maxAuditPerPage {
+		filter.PerPage = maxAuditPerPage
+	}
+
+	entries, total, err := s.auditRepo.List(ctx, filter)
+	if err != nil {
+		s.logger.Error("Error listing audit log", util.WithError(err))
+		return nil, 0, fmt.Errorf("error listing audit log: %w", err)
+	}
+	return entries, total, nil
+}
+
+// Export calls fn for every entry matching the filter, oldest first
+func (s *auditService) Export(ctx context.Context, filter *model.AuditLogFilter, fn func(*model.AuditLog) error) error {
+	var afterID uint
+	for {
+		entries, err := s.auditRepo.ListAfter(ctx, filter, afterID, auditExportBatch)
+		if err != nil {
+			s.logger.Error("Error exporting audit log", util.WithError(err))
+			return fmt.Errorf("error exporting audit log: %w", err)
+		}
+		for i := range entries {
+			if err := fn(&entries[i]); err != nil {
+				return err
+			}
+			afterID = entries[i].ID
+		}
+		if len(entries) < auditExportBatch {
+			return nil
+		}
+	}
+}
+
+// Checkpoint writes a signed checkpoint of the chain head
+func (s *auditService) Checkpoint(ctx context.Context) (*model.AuditCheckpoint, error) {
+	if s.config.SigningKey == nil {
+		return nil, nil
+	}
+
+	head, err := s.auditRepo.Head(ctx, s.seal)
+	if err != nil {
+		return nil, fmt.Errorf("error reading audit chain head: %w", err)
+	}
+	if head.LastEntryID == 0 {
+		return nil, nil
+	}
+
+	latest, err := s.auditRepo.LatestCheckpoint(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error reading latest audit checkpoint: %w", err)
+	}
+	if latest != nil && latest.LastEntryID == head.LastEntryID {
+		return latest, nil
+	}
+
+	checkpoint, err := auditchain.NewCheckpoint(head, s.config.SigningKey)
+	if err != nil {
+		return nil, fmt.Errorf("error signing audit checkpoint: %w", err)
+	}
+	if err := s.auditRepo.CreateCheckpoint(ctx, checkpoint); err != nil {
+		return nil, fmt.Errorf("error writing audit checkpoint: %w", err)
+	}
+
+	s.logger.Info("Audit checkpoint written",
+		zap.Uint("last_entry_id", checkpoint.LastEntryID),
+		zap.Int64("entry_count", checkpoint.EntryCount))
+	return checkpoint, nil
+}
+
+// Checkpoints returns every checkpoint with the key needed to verify them
+func (s *auditService) Checkpoints(ctx context.Context) (*model.AuditCheckpointExport, error) {
+	checkpoints, err := s.auditRepo.ListCheckpoints(ctx)
+	if err != nil {
+		s.logger.Error("Error listing audit checkpoints", util.WithError(err))
+		return nil, fmt.Errorf("error listing audit checkpoints: %w", err)
+	}
+
+	export := &model.AuditCheckpointExport{
+		Algorithm:   auditchain.SignatureAlgorithm,
+		Checkpoints: checkpoints,
+	}
+	if s.config.SigningKey != nil {
+		publicKey := s.config.SigningKey.Public().(ed25519.PublicKey)
+		export.PublicKey = auditchain.EncodePublicKey(publicKey)
+		export.KeyID = auditchain.KeyID(publicKey)
+	}
+	return export, nil
+}
+
+// Start seals any unchained entries and launches the periodic checkpoint writer
+func (s *auditService) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	if _, err := s.auditRepo.Head(ctx, s.seal); err != nil {
+		s.logger.Error("Error sealing audit log", util.WithError(err))
+	}
+
+	if s.config.SigningKey == nil {
+		s.logger.Warn("AUDIT_SIGNING_KEY not set, audit checkpoints are disabled")
+		return
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ticker := time.NewTicker(s.config.CheckpointInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := s.Checkpoint(ctx); err != nil {
+					s.logger.Error("Error writing audit checkpoint", util.WithError(err))
+				}
+			}
+		}
+	}()
+
+	s.logger.Info("Audit checkpoints started", zap.Duration("interval", s.config.CheckpointInterval))
+}
+
+// Stop waits for the checkpoint writer to exit and writes a final checkpoint
+func (s *auditService) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+
+	if _, err := s.Checkpoint(context.Background()); err != nil {
+		s.logger.Error("Error writing audit checkpoint", util.WithError(err))
+	}
+}
+
+// seal chains an entry onto the entry with hash prevHash
+func (s *auditService) seal(entry *model.AuditLog, prevHash string) error {
+	return auditchain.Seal(entry, prevHash, s.config.HMACKey)
+}
+
+// auditRef returns a pointer to an ID for the nullable audit columns
+func auditRef(id uint) *uint {
+	if id == 0 {
+		return nil
+	}
+	return &id
+}
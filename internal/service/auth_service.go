@@ -35,13 +35,17 @@ type AuthService interface {
 type authService struct {
 	userRepo repository.UserRepository
 	jwtSvc   *util.JwtService
+	audit    AuditService
+	events   EventPublisher
 	logger   *util.Logger
 }
 
-func NewAuthService(userRepo repository.UserRepository, jwtSvc *util.JwtService, logger *util.Logger) AuthService {
+func NewAuthService(userRepo repository.UserRepository, jwtSvc *util.JwtService, audit AuditService, events EventPublisher, logger *util.Logger) AuthService {
 	return &authService{
 		userRepo: userRepo,
 		jwtSvc:   jwtSvc,
+		audit:    audit,
+		events:   events,
 		logger:   logger,
 	}
 }
@@ -92,6 +96,7 @@ func (s *authService) Register(ctx context.Context, req *model.RegisterRequest)
 		logger.Error("Error creating user", util.WithError(err))
 		return nil, fmt.Errorf("error creating user: %w", err)
 	}
+	s.events.Publish(ctx, newEvent(model.EventUserRegistered, user.ID, user.ToResponse()))
 
 	token, err := s.jwtSvc.GenerateAccessToken(user.ID)
 	if err != nil {
@@ -124,11 +129,26 @@ func (s *authService) Login(ctx context.Context, req *model.LoginRequest) (*mode
 
 	if user == nil {
 		logger.Warn("User not found")
+		s.audit.Record(ctx, &model.AuditLog{
+			Action:     model.AuditLoginFailed,
+			Outcome:    model.AuditFailure,
+			ActorEmail: req.Email,
+			Metadata:   model.JSONMap{"reason": "unknown_user"},
+		})
 		return nil, ErrInvalidCredentials
 	}
 
 	if err := util.CheckPassword(user.Password, req.Password); err != nil {
 		logger.Warn("Invalid password")
+		s.audit.Record(ctx, &model.AuditLog{
+			Action:     model.AuditLoginFailed,
+			Outcome:    model.AuditFailure,
+			ActorID:    auditRef(user.ID),
+			ActorEmail: user.Email,
+			TargetType: model.AuditTargetUser,
+			TargetID:   auditRef(user.ID),
+			Metadata:   model.JSONMap{"reason": "invalid_password"},
+		})
 		return nil, ErrInvalidCredentials
 	}
 
@@ -145,6 +165,13 @@ func (s *authService) Login(ctx context.Context, req *model.LoginRequest) (*mode
 	}
 
 	logger.Info("User logged in successfully")
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditLogin,
+		ActorID:    auditRef(user.ID),
+		ActorEmail: user.Email,
+		TargetType: model.AuditTargetUser,
+		TargetID:   auditRef(user.ID),
+	})
 
 	return &model.AuthResponse{
 		User:         user.ToResponse(),
@@ -183,12 +210,14 @@ func (s *authService) RefreshTokens(ctx context.Context, refreshToken string) (*
 	userID, tokenType, err := s.jwtSvc.ValidateToken(refreshToken)
 	if err != nil {
 		s.logger.Error("Error validating refresh token", util.WithError(err))
+		s.recordRefreshFailure(ctx, 0, "invalid_token")
 		return nil, err
 	}
 
 	// Verify it's a refresh token
 	if tokenType != util.RefreshToken {
 		s.logger.Warn("Invalid token type for refresh", util.WithUserID(userID))
+		s.recordRefreshFailure(ctx, userID, "wrong_token_type")
 		return nil, ErrUnauthorized
 	}
 
@@ -196,6 +225,7 @@ func (s *authService) RefreshTokens(ctx context.Context, refreshToken string) (*
 	user, err := s.userRepo.GetById(ctx, userID)
 	if err != nil || user == nil {
 		s.logger.Error("Error getting user for refresh", util.WithUserID(userID), util.WithError(err))
+		s.recordRefreshFailure(ctx, userID, "unknown_user")
 		return nil, ErrUnauthorized
 	}
 
@@ -213,9 +243,28 @@ func (s *authService) RefreshTokens(ctx context.Context, refreshToken string) (*
 	}
 
 	s.logger.Info("Tokens refreshed successfully", util.WithUserID(userID))
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditTokenRefresh,
+		ActorID:    auditRef(user.ID),
+		ActorEmail: user.Email,
+		TargetType: model.AuditTargetUser,
+		TargetID:   auditRef(user.ID),
+	})
 
 	return &RefreshResponse{
 		AccessToken:  newAccessToken,
 		RefreshToken: newRefreshToken,
 	}, nil
 }
+
+// recordRefreshFailure audits a rejected refresh token
+func (s *authService) recordRefreshFailure(ctx context.Context, userID uint, reason string) {
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditTokenRefreshFailed,
+		Outcome:    model.AuditFailure,
+		ActorID:    auditRef(userID),
+		TargetType: model.AuditTargetUser,
+		TargetID:   auditRef(userID),
+		Metadata:   model.JSONMap{"reason": reason},
+	})
+}
@@ -0,0 +1,541 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// maxSyncBatchSize is the longest batch run during the request
+	maxSyncBatchSize = 20
+
+	batchQueueSize     = 100
+	batchWorkers       = 2
+	batchSweepInterval = time.Minute
+	batchSweepBatch    = 100
+	// batchSaveInterval is how many operations run between progress saves
+	batchSaveInterval = 25
+	// batchRetention is how long finished jobs can be polled
+	batchRetention = 7 * 24 * time.Hour
+)
+
+var (
+	ErrBatchNotFound         = errors.New("batch not found")
+	ErrInvalidBatchOperation = errors.New("invalid batch operation")
+)
+
+// errBatchInterrupted stops a batch when the server shuts down
+var errBatchInterrupted = errors.New("interrupted by a server shutdown")
+
+// batchItemErrors are the errors reported to the user as they are; any
+// other error is reported as an internal one
+var batchItemErrors = []error{
+	ErrFileNotFound, ErrFolderNotFound, ErrPermissionDenied, ErrFileQuarantined,
+	ErrQuotaExceeded, ErrFileTooLarge, ErrFileLocked, ErrRootFolder,
+	ErrInvalidFolderMove, ErrInvalidFolderCopy, ErrVaultBoundary, ErrVaultKeyRequired,
+	ErrShareNotFound, ErrShareAlreadyExists, ErrShareWithSelf, ErrUserNotFound,
+	ErrInvalidTagName, ErrInvalidBatchOperation, ErrLegalHold, ErrRetentionPeriod,
+	ErrWorkspaceBoundary, ErrGroupNotFound, ErrShareRecipient, ErrGroupShareInVault,
+}
+
+// BatchService applies operations to many files and folders at once. Each
+// operation goes through the service that owns it, so permissions, locks,
+// auditing and events apply per item exactly as for single requests.
+type BatchService interface {
+	// Run applies a batch. Short batches run immediately and are returned
+	// finished; long or async ones are queued and returned with an ID to
+	// poll, and async reports which happened.
+	Run(ctx context.Context, userID uint, req *model.BatchRequest) (job *model.BatchJob, async bool, err error)
+	// Get returns one of the user's queued or recently finished batches
+	Get(ctx context.Context, userID, jobID uint) (*model.BatchJob, error)
+	// Start launches the workers running queued batches
+	Start(ctx context.Context)
+	// Stop interrupts running batches and waits for the workers to exit
+	Stop()
+}
+
+type batchService struct {
+	batchRepo   repository.BatchRepository
+	folderRepo  repository.FolderRepository
+	files       FileService
+	folders     FolderService
+	shares      ShareService
+	stars       StarService
+	tags        TagService
+	maintenance MaintenanceService
+	logger      *util.Logger
+
+	queue    chan uint
+	mu       sync.Mutex
+	inFlight map[uint]bool
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewBatchService creates a new BatchService instance
+func NewBatchService(
+	batchRepo repository.BatchRepository,
+	folderRepo repository.FolderRepository,
+	files FileService,
+	folders FolderService,
+	shares ShareService,
+	stars StarService,
+	tags TagService,
+	maintenance MaintenanceService,
+	logger *util.Logger,
+) BatchService {
+	return &batchService{
+		batchRepo:   batchRepo,
+		folderRepo:  folderRepo,
+		files:       files,
+		folders:     folders,
+		shares:      shares,
+		stars:       stars,
+		tags:        tags,
+		maintenance: maintenance,
+		logger:      logger,
+		queue:       make(chan uint, batchQueueSize),
+		inFlight:    make(map[uint]bool),
+	}
+}
+
+// Run applies a batch now or queues it
+func (s *batchService) Run(ctx context.Context, userID uint, req *model.BatchRequest) (*model.BatchJob, bool, error) {
+	job := &model.BatchJob{
+		UserID:     userID,
+		Status:     model.BatchQueued,
+		Atomic:     req.Atomic,
+		Total:      len(req.Operations),
+		Operations: req.Operations,
+		Results:    model.BatchResultList{},
+	}
+
+	if !req.Async && len(req.Operations) <= maxSyncBatchSize {
+		now := time.Now()
+		job.Status = model.BatchRunning
+		job.CreatedAt, job.StartedAt = now, &now
+		s.execute(ctx, job, nil)
+		return job, false, nil
+	}
+
+	if err := s.batchRepo.Create(ctx, job); err != nil {
+		s.logger.Error("Error queueing batch", util.WithUserID(userID), util.WithError(err))
+		return nil, false, fmt.Errorf("error queueing batch: %w", err)
+	}
+	s.enqueue(job.ID)
+	s.logger.Info("Batch queued", util.WithUserID(userID), zap.Uint("batch_id", job.ID), zap.Int("operations", job.Total))
+	return job, true, nil
+}
+
+// Get returns one of the user's batches
+func (s *batchService) Get(ctx context.Context, userID, jobID uint) (*model.BatchJob, error) {
+	job, err := s.batchRepo.FindByID(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding batch: %w", err)
+	}
+	if job == nil || job.UserID != userID {
+		return nil, ErrBatchNotFound
+	}
+	return job, nil
+}
+
+// Start launches the workers and the periodic sweep for queued batches.
+// Batches left running by a previous process cannot be resumed safely, as
+// their operations are not idempotent, so they are marked failed.
+func (s *batchService) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	if n, err := s.batchRepo.FailRunning(ctx, errBatchInterrupted.Error()); err != nil {
+		s.logger.Error("Error failing interrupted batches", util.WithError(err))
+	} else if n > 0 {
+		s.logger.Warn("Marked interrupted batches as failed", zap.Int64("batches", n))
+	}
+
+	for i := 0; i < batchWorkers; i++ {
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.work(ctx)
+		}()
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.sweep(ctx)
+	}()
+
+	s.logger.Info("Batch runner started", zap.Int("workers", batchWorkers))
+}
+
+// Stop interrupts running batches and waits for the workers to exit
+func (s *batchService) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+	s.logger.Info("Batch runner stopped")
+}
+
+// enqueue schedules a batch. It never blocks; batches that do not fit in the
+// queue are picked up by the next sweep.
+func (s *batchService) enqueue(jobID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.inFlight[jobID] {
+		return
+	}
+	select {
+	case s.queue <- jobID:
+		s.inFlight[jobID] = true
+	default:
+		s.logger.Warn("Batch queue full, deferring batch to next sweep", zap.Uint("batch_id", jobID))
+	}
+}
+
+// work runs queued batches until the context is cancelled
+func (s *batchService) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case jobID := <-s.queue:
+			s.runJob(ctx, jobID)
+
+			s.mu.Lock()
+			delete(s.inFlight, jobID)
+			s.mu.Unlock()
+		}
+	}
+}
+
+// sweep periodically enqueues queued batches and removes old finished ones
+func (s *batchService) sweep(ctx context.Context) {
+	ticker := time.NewTicker(batchSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		ids, err := s.batchRepo.ListQueuedIDs(ctx, batchSweepBatch)
+		if err != nil && ctx.Err() == nil {
+			s.logger.Error("Error listing queued batches", util.WithError(err))
+		}
+		for _, id := range ids {
+			s.enqueue(id)
+		}
+		if _, err := s.batchRepo.DeleteFinishedBefore(ctx, time.Now().Add(-batchRetention)); err != nil && ctx.Err() == nil {
+			s.logger.Error("Error removing finished batches", util.WithError(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runJob runs a queued batch, saving its progress as it goes
+func (s *batchService) runJob(ctx context.Context, jobID uint) {
+	logger := s.logger.With(zap.Uint("batch_id", jobID))
+
+	job, err := s.batchRepo.FindByID(ctx, jobID)
+	if err != nil {
+		logger.Error("Error loading batch", util.WithError(err))
+		return
+	}
+	if job == nil {
+		return
+	}
+	claimed, err := s.batchRepo.Claim(ctx, job)
+	if err != nil {
+		logger.Error("Error claiming batch", util.WithError(err))
+		return
+	}
+	if !claimed {
+		return
+	}
+
+	// Progress is saved even while shutting down, so the batch's final
+	// state is recorded
+	saveCtx := context.WithoutCancel(ctx)
+	s.execute(ctx, job, func() {
+		if err := s.batchRepo.Update(saveCtx, job); err != nil {
+			logger.Error("Error saving batch progress", util.WithError(err))
+		}
+	})
+	if err := s.batchRepo.Update(saveCtx, job); err != nil {
+		logger.Error("Error saving batch", util.WithError(err))
+	}
+	logger.Info("Batch finished", util.WithUserID(job.UserID), zap.String("status", string(job.Status)),
+		zap.Int("processed", job.Processed), zap.Int("failed", job.Failed))
+}
+
+// execute applies a batch's operations in order, calling save periodically
+// when set. Atomic batches stop at the first failure and undo what was
+// already done, newest first. Cancelling ctx stops the batch between
+// operations.
+func (s *batchService) execute(ctx context.Context, job *model.BatchJob, save func()) {
+	undos := make([]func(context.Context) error, 0, len(job.Operations))
+	job.Results = make(model.BatchResultList, 0, len(job.Operations))
+
+	var stopErr error
+	for i, op := range job.Operations {
+		result := model.BatchItemResult{Index: i, Op: op.Op, FileID: op.FileID, FolderID: op.FolderID}
+		if ctx.Err() != nil {
+			stopErr = errBatchInterrupted
+			break
+		}
+
+		createdID, undo, err := s.apply(ctx, job.UserID, &op)
+		job.Processed++
+		if err != nil {
+			result.Status = model.BatchItemFailed
+			result.Error = s.itemError(job, i, err)
+			job.Failed++
+		} else {
+			result.Status = model.BatchItemSucceeded
+			result.CreatedID = createdID
+		}
+		job.Results = append(job.Results, result)
+		undos = append(undos, undo)
+
+		if err != nil && job.Atomic {
+			stopErr = fmt.Errorf("operation %d failed; the batch was rolled back", i)
+			break
+		}
+		if save != nil && job.Processed%batchSaveInterval == 0 {
+			save()
+		}
+	}
+
+	if stopErr != nil {
+		job.Status = model.BatchFailed
+		job.Error = stopErr.Error()
+		if job.Atomic {
+			s.rollback(context.WithoutCancel(ctx), job, undos)
+		}
+		for i := len(job.Results); i < len(job.Operations); i++ {
+			op := job.Operations[i]
+			job.Results = append(job.Results, model.BatchItemResult{
+				Index: i, Op: op.Op, FileID: op.FileID, FolderID: op.FolderID, Status: model.BatchItemSkipped,
+			})
+		}
+	} else {
+		job.Status = model.BatchCompleted
+	}
+	now := time.Now()
+	job.FinishedAt = &now
+}
+
+// itemError describes why an operation failed, hiding internal errors
+func (s *batchService) itemError(job *model.BatchJob, index int, err error) string {
+	for _, known := range batchItemErrors {
+		if errors.Is(err, known) {
+			return err.Error()
+		}
+	}
+	s.logger.Error("Error applying batch operation", util.WithUserID(job.UserID),
+		zap.Uint("batch_id", job.ID), zap.Int("index", index), util.WithError(err))
+	return "internal error"
+}
+
+// rollback undoes the succeeded operations of an atomic batch, newest first.
+// Operations that cannot be undone keep their status with the reason.
+func (s *batchService) rollback(ctx context.Context, job *model.BatchJob, undos []func(context.Context) error) {
+	for i := len(undos) - 1; i >= 0; i-- {
+		result := &job.Results[i]
+		if result.Status != model.BatchItemSucceeded {
+			continue
+		}
+		if undos[i] != nil {
+			if err := undos[i](ctx); err != nil {
+				result.Error = "could not be rolled back: " + s.itemError(job, i, err)
+				s.logger.Error("Error rolling back batch operation", util.WithUserID(job.UserID),
+					zap.Uint("batch_id", job.ID), zap.Int("index", i), util.WithError(err))
+				continue
+			}
+		}
+		result.Status = model.BatchItemRolledBack
+	}
+}
+
+// apply runs one operation, returning the ID of anything it created and a
+// function undoing it. The undo function is nil when there is nothing to
+// undo.
+func (s *batchService) apply(ctx context.Context, userID uint, op *model.BatchOperation) (uint, func(context.Context) error, error) {
+	isFile := op.FileID != 0
+
+	switch op.Op {
+	case model.BatchMove:
+		if op.DestinationID == 0 {
+			return 0, nil, fmt.Errorf("%w: destination_id is required", ErrInvalidBatchOperation)
+		}
+		if isFile {
+			return s.moveFile(ctx, userID, op.FileID, op.DestinationID)
+		}
+		return s.moveFolder(ctx, userID, op.FolderID, op.DestinationID)
+
+	case model.BatchCopy:
+		if op.DestinationID == 0 {
+			return 0, nil, fmt.Errorf("%w: destination_id is required", ErrInvalidBatchOperation)
+		}
+		if isFile {
+			file, err := s.files.Copy(ctx, userID, op.FileID, op.DestinationID)
+			if err != nil {
+				return 0, nil, err
+			}
+			return file.ID, func(ctx context.Context) error {
+				if err := s.files.Delete(ctx, userID, file.ID); err != nil {
+					return err
+				}
+				return s.maintenance.Purge(ctx, []uint{file.ID}, nil)
+			}, nil
+		}
+		folder, err := s.folders.Copy(ctx, userID, op.FolderID, op.DestinationID)
+		if err != nil {
+			return 0, nil, err
+		}
+		return folder.ID, func(ctx context.Context) error {
+			if err := s.folders.Delete(ctx, userID, folder.ID); err != nil {
+				return err
+			}
+			return s.maintenance.Purge(ctx, nil, []uint{folder.ID})
+		}, nil
+
+	case model.BatchDelete:
+		if isFile {
+			if err := s.files.Delete(ctx, userID, op.FileID); err != nil {
+				return 0, nil, err
+			}
+			return 0, func(ctx context.Context) error {
+				_, err := s.files.Restore(ctx, userID, op.FileID)
+				return err
+			}, nil
+		}
+		if err := s.folders.Delete(ctx, userID, op.FolderID); err != nil {
+			return 0, nil, err
+		}
+		return 0, func(ctx context.Context) error {
+			_, err := s.folders.Restore(ctx, userID, op.FolderID)
+			return err
+		}, nil
+
+	case model.BatchRestore:
+		if isFile {
+			if _, err := s.files.Restore(ctx, userID, op.FileID); err != nil {
+				return 0, nil, err
+			}
+			return 0, func(ctx context.Context) error { return s.files.Delete(ctx, userID, op.FileID) }, nil
+		}
+		if _, err := s.folders.Restore(ctx, userID, op.FolderID); err != nil {
+			return 0, nil, err
+		}
+		return 0, func(ctx context.Context) error { return s.folders.Delete(ctx, userID, op.FolderID) }, nil
+
+	case model.BatchStar, model.BatchUnstar:
+		itemType, itemID := model.ItemTypeFolder, op.FolderID
+		if isFile {
+			itemType, itemID = model.ItemTypeFile, op.FileID
+		}
+		star, unstar := s.stars.Star, s.stars.Unstar
+		if op.Op == model.BatchUnstar {
+			star, unstar = unstar, star
+		}
+		changed, err := star(ctx, userID, itemType, itemID)
+		if err != nil || !changed {
+			return 0, nil, err
+		}
+		return 0, func(ctx context.Context) error {
+			_, err := unstar(ctx, userID, itemType, itemID)
+			return err
+		}, nil
+
+	case model.BatchTag, model.BatchUntag:
+		if !isFile {
+			return 0, nil, fmt.Errorf("%w: only files can be tagged", ErrInvalidBatchOperation)
+		}
+		if op.Tag == "" {
+			return 0, nil, fmt.Errorf("%w: tag is required", ErrInvalidBatchOperation)
+		}
+		tag, untag := s.tags.Tag, s.tags.Untag
+		if op.Op == model.BatchUntag {
+			tag, untag = untag, tag
+		}
+		changed, err := tag(ctx, userID, op.FileID, op.Tag)
+		if err != nil || !changed {
+			return 0, nil, err
+		}
+		return 0, func(ctx context.Context) error {
+			_, err := untag(ctx, userID, op.FileID, op.Tag)
+			return err
+		}, nil
+
+	case model.BatchShare:
+		if op.Permission == "" {
+			return 0, nil, fmt.Errorf("%w: permission is required", ErrInvalidBatchOperation)
+		}
+		if op.SharedWithID == 0 && op.SharedWithEmail == "" && op.GroupID == 0 {
+			return 0, nil, fmt.Errorf("%w: shared_with_id, shared_with_email or group_id is required", ErrInvalidBatchOperation)
+		}
+		share, err := s.shares.Create(ctx, userID, &model.CreateShareRequest{
+			FileID:          op.FileID,
+			FolderID:        op.FolderID,
+			SharedWithID:    op.SharedWithID,
+			SharedWithEmail: op.SharedWithEmail,
+			GroupID:         op.GroupID,
+			Permission:      op.Permission,
+		})
+		if err != nil {
+			return 0, nil, err
+		}
+		return share.ID, func(ctx context.Context) error { return s.shares.Delete(ctx, userID, share.ID) }, nil
+	}
+
+	return 0, nil, fmt.Errorf("%w: unknown operation %q", ErrInvalidBatchOperation, op.Op)
+}
+
+// moveFile moves a file, remembering where it was so the move can be undone
+func (s *batchService) moveFile(ctx context.Context, userID, fileID, folderID uint) (uint, func(context.Context) error, error) {
+	file, err := s.files.GetFile(ctx, userID, fileID)
+	if err != nil {
+		return 0, nil, err
+	}
+	previous := file.FolderID
+	if _, err := s.files.Update(ctx, userID, fileID, &model.UpdateFileRequest{FolderID: &folderID}); err != nil {
+		return 0, nil, err
+	}
+	return 0, func(ctx context.Context) error {
+		_, err := s.files.Update(ctx, userID, fileID, &model.UpdateFileRequest{FolderID: &previous})
+		return err
+	}, nil
+}
+
+// moveFolder moves a folder, remembering where it was so the move can be
+// undone
+func (s *batchService) moveFolder(ctx context.Context, userID, folderID, parentID uint) (uint, func(context.Context) error, error) {
+	folder, err := s.folderRepo.FindByID(ctx, folderID)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error finding folder: %w", err)
+	}
+	if folder == nil {
+		return 0, nil, ErrFolderNotFound
+	}
+	// The folder service checks permissions and rejects moving the root
+	if _, err := s.folders.Update(ctx, userID, folderID, &model.UpdateFolderRequest{ParentID: &parentID}); err != nil {
+		return 0, nil, err
+	}
+	previous := *folder.ParentFolderID
+	return 0, func(ctx context.Context) error {
+		_, err := s.folders.Update(ctx, userID, folderID, &model.UpdateFolderRequest{ParentID: &previous})
+		return err
+	}, nil
+}
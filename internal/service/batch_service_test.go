@@ -0,0 +1,119 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"drive/internal/model"
+	"drive/internal/service"
+	"drive/internal/util"
+)
+
+// copyFiles copies files to new IDs and records the copies trashed
+type copyFiles struct {
+	service.FileService
+	trashed *[]uint
+}
+
+func (s copyFiles) Copy(ctx context.Context, userID, fileID, folderID uint) (*model.File, error) {
+	if fileID == 404 {
+		return nil, service.ErrFileNotFound
+	}
+	return &model.File{ID: fileID + 100, FolderID: folderID, UserID: userID}, nil
+}
+
+func (s copyFiles) Delete(ctx context.Context, userID, fileID uint) error {
+	*s.trashed = append(*s.trashed, fileID)
+	return nil
+}
+
+type copyFolders struct {
+	service.FolderService
+	trashed *[]uint
+}
+
+func (s copyFolders) Copy(ctx context.Context, userID, folderID, parentID uint) (*model.Folder, error) {
+	return &model.Folder{ID: folderID + 100, ParentFolderID: &parentID, UserID: userID}, nil
+}
+
+func (s copyFolders) Delete(ctx context.Context, userID, folderID uint) error {
+	*s.trashed = append(*s.trashed, folderID)
+	return nil
+}
+
+// purger records what is purged
+type purger struct {
+	service.MaintenanceService
+	files, folders []uint
+	err            error
+}
+
+func (m *purger) Purge(ctx context.Context, fileIDs, folderIDs []uint) error {
+	if m.err != nil {
+		return m.err
+	}
+	m.files = append(m.files, fileIDs...)
+	m.folders = append(m.folders, folderIDs...)
+	return nil
+}
+
+func TestBatchRollbackPurgesCopies(t *testing.T) {
+	tests := []struct {
+		name     string
+		purgeErr error
+		status   model.BatchItemStatus
+		purged   bool
+	}{
+		{name: "purged", status: model.BatchItemRolledBack, purged: true},
+		{name: "purge failed", purgeErr: errors.New("connection reset"), status: model.BatchItemSucceeded},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var trashedFiles, trashedFolders []uint
+			maintenance := &purger{err: tt.purgeErr}
+			batches := service.NewBatchService(nil, nil, copyFiles{trashed: &trashedFiles}, copyFolders{trashed: &trashedFolders},
+				nil, nil, nil, maintenance, &util.Logger{Logger: zap.NewNop()})
+
+			job, async, err := batches.Run(context.Background(), 1, &model.BatchRequest{
+				Atomic: true,
+				Operations: []model.BatchOperation{
+					{Op: model.BatchCopy, FileID: 1, DestinationID: 9},
+					{Op: model.BatchCopy, FolderID: 2, DestinationID: 9},
+					{Op: model.BatchCopy, FileID: 404, DestinationID: 9},
+				},
+			})
+			if err != nil || async {
+				t.Fatalf("Run = %v, async %v", err, async)
+			}
+			if job.Status != model.BatchFailed {
+				t.Errorf("batch status = %s, want %s", job.Status, model.BatchFailed)
+			}
+
+			for _, result := range job.Results[:2] {
+				if result.Status != tt.status {
+					t.Errorf("copy %d status = %s, want %s", result.Index, result.Status, tt.status)
+				}
+				if !tt.purged && !strings.HasPrefix(result.Error, "could not be rolled back") {
+					t.Errorf("copy %d error = %q, want the rollback failure", result.Index, result.Error)
+				}
+			}
+			if got := job.Results[2].Status; got != model.BatchItemFailed {
+				t.Errorf("failed copy status = %s, want %s", got, model.BatchItemFailed)
+			}
+
+			// The copies are trashed first, so a failed purge leaves them
+			// there rather than in the drive
+			if len(trashedFiles) != 1 || trashedFiles[0] != 101 || len(trashedFolders) != 1 || trashedFolders[0] != 102 {
+				t.Errorf("trashed files %v and folders %v, want [101] and [102]", trashedFiles, trashedFolders)
+			}
+			if tt.purged && (len(maintenance.files) != 1 || maintenance.files[0] != 101 || len(maintenance.folders) != 1 || maintenance.folders[0] != 102) {
+				t.Errorf("purged files %v and folders %v, want [101] and [102]", maintenance.files, maintenance.folders)
+			}
+		})
+	}
+}
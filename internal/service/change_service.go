@@ -0,0 +1,422 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultChangeLimit = 500
+	maxChangeLimit     = 1000
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ChangeService keeps a per-user journal of changes to files and folders so
+// sync clients can ask what changed since their last cursor. It is fed by
+// the event bus.
+type ChangeService interface {
+	EventPublisher
+	// List returns the user's changes after the cursor. When there are none
+	// and filter.Wait is set, it waits up to that many seconds for one.
+	List(ctx context.Context, userID uint, filter *model.ChangeFilter) (*model.ChangeSet, error)
+	// Latest returns a cursor pointing at the user's newest change, for
+	// clients that have just listed their drive in full
+	Latest(ctx context.Context, userID uint) (string, error)
+}
+
+type changeService struct {
+	changeRepo     repository.ChangeRepository
+	fileRepo       repository.FileRepository
+	folderRepo     repository.FolderRepository
+	permissionRepo repository.PermissionRepository
+	logger         *util.Logger
+
+	mu      sync.Mutex
+	waiters map[uint]map[chan struct{}]bool
+}
+
+// NewChangeService creates a new ChangeService instance
+func NewChangeService(
+	changeRepo repository.ChangeRepository,
+	fileRepo repository.FileRepository,
+	folderRepo repository.FolderRepository,
+	permissionRepo repository.PermissionRepository,
+	logger *util.Logger,
+) ChangeService {
+	return &changeService{
+		changeRepo:     changeRepo,
+		fileRepo:       fileRepo,
+		folderRepo:     folderRepo,
+		permissionRepo: permissionRepo,
+		logger:         logger,
+		waiters:        make(map[uint]map[chan struct{}]bool),
+	}
+}
+
+// parseCursor reads a cursor returned by List or Latest. An empty cursor
+// starts from the beginning of the journal.
+func parseCursor(cursor string) (uint, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(cursor, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	return uint(id), nil
+}
+
+// formatCursor encodes a change ID as a cursor
+func formatCursor(id uint) string {
+	return strconv.FormatUint(uint64(id), 10)
+}
+
+// List returns the user's changes after the cursor, long-polling when asked
+func (s *changeService) List(ctx context.Context, userID uint, filter *model.ChangeFilter) (*model.ChangeSet, error) {
+	if filter.Limit < 1 {
+		filter.Limit = defaultChangeLimit
+	}
+	if filter.Limit > maxChangeLimit {
+		filter.Limit = maxChangeLimit
+	}
+	cursor, err := parseCursor(filter.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := s.listAfter(ctx, userID, cursor, filter.Limit)
+	if err != nil || len(changes) > 0 || filter.Wait <= 0 {
+		return changeSet(changes, cursor, filter.Limit, err)
+	}
+
+	// Register before looking again so a change recorded in between still
+	// wakes us up
+	wake := s.addWaiter(userID)
+	defer s.removeWaiter(userID, wake)
+
+	if changes, err = s.listAfter(ctx, userID, cursor, filter.Limit); err != nil || len(changes) > 0 {
+		return changeSet(changes, cursor, filter.Limit, err)
+	}
+
+	timer := time.NewTimer(time.Duration(filter.Wait) * time.Second)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return changeSet(nil, cursor, filter.Limit, nil)
+	case <-timer.C:
+		return changeSet(nil, cursor, filter.Limit, nil)
+	case <-wake:
+	}
+
+	changes, err = s.listAfter(ctx, userID, cursor, filter.Limit)
+	return changeSet(changes, cursor, filter.Limit, err)
+}
+
+// Latest returns a cursor pointing at the user's newest change
+func (s *changeService) Latest(ctx context.Context, userID uint) (string, error) {
+	latest, err := s.changeRepo.Latest(ctx, userID)
+	if err != nil {
+		s.logger.Error("Error reading change journal", util.WithUserID(userID), util.WithError(err))
+		return "", fmt.Errorf("error reading change journal: %w", err)
+	}
+	return formatCursor(latest), nil
+}
+
+// listAfter reads one page of changes, fetching one extra to detect more
+func (s *changeService) listAfter(ctx context.Context, userID, cursor uint, limit int) ([]model.Change, error) {
+	changes, err := s.changeRepo.ListAfter(ctx, userID, cursor, limit+1)
+	if err != nil {
+		s.logger.Error("Error reading change journal", util.WithUserID(userID), util.WithError(err))
+		return nil, fmt.Errorf("error reading change journal: %w", err)
+	}
+	return changes, nil
+}
+
+// changeSet builds the response for a page of changes
+func changeSet(changes []model.Change, cursor uint, limit int, err error) (*model.ChangeSet, error) {
+	if err != nil {
+		return nil, err
+	}
+
+	set := &model.ChangeSet{
+		Changes: changes,
+		Cursor:  formatCursor(cursor),
+	}
+	if len(changes) > limit {
+		set.Changes = changes[:limit]
+		set.HasMore = true
+	}
+	if set.Changes == nil {
+		set.Changes = []model.Change{}
+	}
+	if n := len(set.Changes); n > 0 {
+		set.Cursor = formatCursor(set.Changes[n-1].ID)
+	}
+	return set, nil
+}
+
+// Publish records the journal entries an event implies for each user in its
+// audience and wakes their long-polling requests
+func (s *changeService) Publish(ctx context.Context, event *model.Event) {
+	// Record even if the request was cancelled after the change was made,
+	// or sync clients would never hear of it
+	ctx = context.WithoutCancel(ctx)
+
+	var changes []model.Change
+	switch data := event.Data.(type) {
+	case *model.File:
+		changes = s.fileChanges(ctx, event, data)
+	case *model.Folder:
+		changes = s.folderChanges(ctx, event, data)
+	case *model.Share:
+		changes = s.shareChanges(ctx, event, data)
+	}
+	if len(changes) == 0 {
+		return
+	}
+
+	if err := s.changeRepo.CreateBatch(ctx, changes); err != nil {
+		s.logger.Error("Error recording changes", zap.String("event_id", event.ID), zap.String("event", string(event.Type)), util.WithError(err))
+		return
+	}
+	for _, change := range changes {
+		s.notify(change.UserID)
+	}
+}
+
+// fileChanges maps a file event onto journal entries
+func (s *changeService) fileChanges(ctx context.Context, event *model.Event, file *model.File) []model.Change {
+	var kind model.ChangeType
+	switch event.Type {
+	case model.EventFileCreated, model.EventFileRestored:
+		kind = model.ChangeCreated
+	case model.EventFileUpdated:
+		kind = model.ChangeModified
+	case model.EventFileMoved:
+		kind = model.ChangeMoved
+	case model.EventFileDeleted:
+		kind = model.ChangeDeleted
+	default:
+		return nil
+	}
+
+	if kind != model.ChangeMoved {
+		return changesFor(event.Audience, fileChange(kind, file, event.OccurredAt))
+	}
+	current, err := s.permissionRepo.FileAudience(ctx, file.ID)
+	if err != nil {
+		s.logger.Error("Error resolving file audience", zap.Uint("file_id", file.ID), util.WithError(err))
+		return changesFor(event.Audience, fileChange(kind, file, event.OccurredAt))
+	}
+	kept, lost := splitAudience(event.Audience, current)
+	changes := changesFor(kept, fileChange(model.ChangeMoved, file, event.OccurredAt))
+	return append(changes, changesFor(lost, fileChange(model.ChangeDeleted, file, event.OccurredAt))...)
+}
+
+// folderChanges maps a folder event onto journal entries
+func (s *changeService) folderChanges(ctx context.Context, event *model.Event, folder *model.Folder) []model.Change {
+	var kind model.ChangeType
+	switch event.Type {
+	case model.EventFolderCreated, model.EventFolderRestored:
+		kind = model.ChangeCreated
+	case model.EventFolderUpdated:
+		kind = model.ChangeModified
+	case model.EventFolderMoved:
+		kind = model.ChangeMoved
+	case model.EventFolderDeleted:
+		kind = model.ChangeDeleted
+	default:
+		return nil
+	}
+
+	if kind != model.ChangeMoved {
+		return changesFor(event.Audience, folderChange(kind, folder, event.OccurredAt))
+	}
+	current, err := s.permissionRepo.FolderAudience(ctx, folder.ID)
+	if err != nil {
+		s.logger.Error("Error resolving folder audience", zap.Uint("folder_id", folder.ID), util.WithError(err))
+		return changesFor(event.Audience, folderChange(kind, folder, event.OccurredAt))
+	}
+	kept, lost := splitAudience(event.Audience, current)
+	changes := changesFor(kept, folderChange(model.ChangeMoved, folder, event.OccurredAt))
+	return append(changes, changesFor(lost, folderChange(model.ChangeDeleted, folder, event.OccurredAt))...)
+}
+
+// splitAudience separates the users of a move event who can still see the
+// item from those who lost access because of the move; the latter see the
+// item deleted rather than moved
+func splitAudience(audience, current []uint) (kept, lost []uint) {
+	visible := make(map[uint]bool, len(current))
+	for _, userID := range current {
+		visible[userID] = true
+	}
+	for _, userID := range audience {
+		if visible[userID] {
+			kept = append(kept, userID)
+		} else {
+			lost = append(lost, userID)
+		}
+	}
+	return kept, lost
+}
+
+// shareChanges maps a share event onto journal entries for its recipients,
+// for whom the shared item appears, changes permission or disappears. The
+// owner's view of the item is unchanged.
+func (s *changeService) shareChanges(ctx context.Context, event *model.Event, share *model.Share) []model.Change {
+	var kind model.ChangeType
+	switch event.Type {
+	case model.EventShareCreated:
+		kind = model.ChangeCreated
+	case model.EventShareUpdated:
+		kind = model.ChangeModified
+	case model.EventShareDeleted:
+		kind = model.ChangeDeleted
+	default:
+		return nil
+	}
+
+	var change model.Change
+	if share.FileID != nil {
+		file, err := s.fileRepo.FindByID(ctx, *share.FileID)
+		if err != nil || file == nil {
+			s.logger.Warn("Shared file not found for change journal", zap.Uint("share_id", share.ID), util.WithError(err))
+			return nil
+		}
+		change = fileChange(kind, file, event.OccurredAt)
+	} else {
+		folder, err := s.folderRepo.FindByID(ctx, share.FolderID)
+		if err != nil || folder == nil {
+			s.logger.Warn("Shared folder not found for change journal", zap.Uint("share_id", share.ID), util.WithError(err))
+			return nil
+		}
+		change = folderChange(kind, folder, event.OccurredAt)
+	}
+
+	// The event's audience is the owner and whoever gained or lost access:
+	// the recipient, or the members of the group the item is shared with
+	var recipients []uint
+	for _, userID := range event.Audience {
+		if userID != share.OwnerID {
+			recipients = append(recipients, userID)
+		}
+	}
+
+	// A recipient may still reach the item through another share
+	if kind == model.ChangeDeleted {
+		lost := recipients[:0]
+		for _, userID := range recipients {
+			var permission model.Permission
+			var err error
+			if share.FileID != nil {
+				permission, err = s.permissionRepo.FilePermission(ctx, userID, *share.FileID)
+			} else {
+				permission, err = s.permissionRepo.FolderPermission(ctx, userID, share.FolderID)
+			}
+			if err != nil || permission == "" {
+				lost = append(lost, userID)
+			}
+		}
+		recipients = lost
+	}
+
+	return changesFor(recipients, change)
+}
+
+// fileChange describes a file as a journal entry
+func fileChange(kind model.ChangeType, file *model.File, occurredAt time.Time) model.Change {
+	folderID := file.FolderID
+	change := model.Change{
+		Change:     kind,
+		ItemType:   model.ChangeItemFile,
+		ItemID:     file.ID,
+		ParentID:   &folderID,
+		Name:       file.FileName,
+		Size:       file.FileSize,
+		MimeType:   file.MimeType,
+		ModifiedAt: file.UpdatedAt,
+	}
+	if kind == model.ChangeDeleted {
+		change.DeletedAt = tombstoneTime(file.DeletedAt.Valid, file.DeletedAt.Time, occurredAt)
+	}
+	return change
+}
+
+// folderChange describes a folder as a journal entry
+func folderChange(kind model.ChangeType, folder *model.Folder, occurredAt time.Time) model.Change {
+	change := model.Change{
+		Change:     kind,
+		ItemType:   model.ChangeItemFolder,
+		ItemID:     folder.ID,
+		ParentID:   folder.ParentFolderID,
+		Name:       folder.FolderName,
+		ModifiedAt: folder.UpdatedAt,
+	}
+	if kind == model.ChangeDeleted {
+		change.DeletedAt = tombstoneTime(folder.DeletedAt.Valid, folder.DeletedAt.Time, occurredAt)
+	}
+	return change
+}
+
+// tombstoneTime prefers the item's own DeletedAt, falling back to the time
+// of the event for items that are still live but no longer visible
+func tombstoneTime(deleted bool, deletedAt, occurredAt time.Time) *time.Time {
+	if deleted {
+		return &deletedAt
+	}
+	return &occurredAt
+}
+
+// changesFor copies a journal entry into each user's journal
+func changesFor(userIDs []uint, change model.Change) []model.Change {
+	changes := make([]model.Change, 0, len(userIDs))
+	for _, userID := range userIDs {
+		change.UserID = userID
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// addWaiter registers a long-polling request for the user
+func (s *changeService) addWaiter(userID uint) chan struct{} {
+	wake := make(chan struct{}, 1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.waiters[userID] == nil {
+		s.waiters[userID] = make(map[chan struct{}]bool)
+	}
+	s.waiters[userID][wake] = true
+	return wake
+}
+
+// removeWaiter unregisters a long-polling request
+func (s *changeService) removeWaiter(userID uint, wake chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.waiters[userID], wake)
+	if len(s.waiters[userID]) == 0 {
+		delete(s.waiters, userID)
+	}
+}
+
+// notify wakes the user's long-polling requests
+func (s *changeService) notify(userID uint) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for wake := range s.waiters[userID] {
+		select {
+		case wake <- struct{}{}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package service_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/service"
+	"drive/internal/util"
+)
+
+// journal records changes, failing on a cancelled context as the database
+// driver does
+type journal struct {
+	repository.ChangeRepository
+
+	mu      sync.Mutex
+	changes []model.Change
+}
+
+func (r *journal) CreateBatch(ctx context.Context, changes []model.Change) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.changes = append(r.changes, changes...)
+	return nil
+}
+
+// audiences answers audience lookups from a fixed list of users
+type audiences struct {
+	repository.PermissionRepository
+	users []uint
+}
+
+func (r audiences) FileAudience(ctx context.Context, fileID uint) ([]uint, error) {
+	return r.users, ctx.Err()
+}
+
+func (r audiences) FolderAudience(ctx context.Context, folderID uint) ([]uint, error) {
+	return r.users, ctx.Err()
+}
+
+func TestChangePublishAfterCancel(t *testing.T) {
+	parentID := uint(7)
+	file := &model.File{ID: 10, FileName: "report.pdf", FileSize: 42, FolderID: parentID, UserID: 1}
+	folder := &model.Folder{ID: 20, FolderName: "Reports", ParentFolderID: &parentID, UserID: 1}
+
+	tests := []struct {
+		name  string
+		event *model.Event
+		want  map[uint]model.ChangeType
+	}{
+		{
+			name:  "file created",
+			event: &model.Event{ID: "e1", Type: model.EventFileCreated, Data: file, Audience: []uint{1, 2}},
+			want:  map[uint]model.ChangeType{1: model.ChangeCreated, 2: model.ChangeCreated},
+		},
+		{
+			// The move takes the folder out of a share with user 2
+			name:  "folder moved",
+			event: &model.Event{ID: "e2", Type: model.EventFolderMoved, Data: folder, Audience: []uint{1, 2}},
+			want:  map[uint]model.ChangeType{1: model.ChangeMoved, 2: model.ChangeDeleted},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes := &journal{}
+			changeService := service.NewChangeService(changes, nil, nil, audiences{users: []uint{1}}, &util.Logger{Logger: zap.NewNop()})
+
+			// The client went away after the change was committed
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			tt.event.OccurredAt = time.Now()
+			changeService.Publish(ctx, tt.event)
+
+			got := make(map[uint]model.ChangeType)
+			for _, change := range changes.changes {
+				got[change.UserID] = change.Change
+			}
+			if len(changes.changes) != len(tt.want) {
+				t.Fatalf("journaled %+v, want %v", changes.changes, tt.want)
+			}
+			for userID, kind := range tt.want {
+				if got[userID] != kind {
+					t.Errorf("user %d change = %q, want %q", userID, got[userID], kind)
+				}
+			}
+		})
+	}
+}
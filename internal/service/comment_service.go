@@ -0,0 +1,324 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrCommentNotFound      = errors.New("comment not found")
+	ErrInvalidCommentParent = errors.New("replies must answer a thread on the same file")
+)
+
+// CommentService manages comment threads on files. Reading comments needs
+// read access to the file and commenting needs write access.
+type CommentService interface {
+	// List returns the threads on a file the user can read
+	List(ctx context.Context, userID, fileID uint) ([]model.Comment, error)
+	// Create starts a thread or replies to one, notifying the mentioned
+	// users who have access to the file
+	Create(ctx context.Context, userID, fileID uint, req *model.CreateCommentRequest) (*model.Comment, error)
+	// Update edits the user's own comment, keeping the previous body
+	Update(ctx context.Context, userID, commentID uint, req *model.UpdateCommentRequest) (*model.Comment, error)
+	// Delete removes a comment and its replies. Authors may delete their
+	// own comments and file owners any comment.
+	Delete(ctx context.Context, userID, commentID uint) error
+	// Resolve marks the thread a comment belongs to as resolved
+	Resolve(ctx context.Context, userID, commentID uint) (*model.Comment, error)
+	// Reopen marks the thread a comment belongs to as open again
+	Reopen(ctx context.Context, userID, commentID uint) (*model.Comment, error)
+	// History returns the earlier bodies of a comment
+	History(ctx context.Context, userID, commentID uint) ([]model.CommentRevision, error)
+}
+
+type commentService struct {
+	commentRepo    repository.CommentRepository
+	userRepo       repository.UserRepository
+	permissionRepo repository.PermissionRepository
+	events         EventPublisher
+	logger         *util.Logger
+}
+
+// NewCommentService creates a new CommentService instance
+func NewCommentService(
+	commentRepo repository.CommentRepository,
+	userRepo repository.UserRepository,
+	permissionRepo repository.PermissionRepository,
+	events EventPublisher,
+	logger *util.Logger,
+) CommentService {
+	return &commentService{
+		commentRepo:    commentRepo,
+		userRepo:       userRepo,
+		permissionRepo: permissionRepo,
+		events:         events,
+		logger:         logger,
+	}
+}
+
+// List returns the threads on a file the user can read
+func (s *commentService) List(ctx context.Context, userID, fileID uint) ([]model.Comment, error) {
+	if _, err := s.requirePermission(ctx, userID, fileID, model.PermissionRead); err != nil {
+		return nil, err
+	}
+	threads, err := s.commentRepo.ListThreads(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing comments: %w", err)
+	}
+	return threads, nil
+}
+
+// Create starts a thread or replies to one
+func (s *commentService) Create(ctx context.Context, userID, fileID uint, req *model.CreateCommentRequest) (*model.Comment, error) {
+	if _, err := s.requirePermission(ctx, userID, fileID, model.PermissionWrite); err != nil {
+		return nil, err
+	}
+
+	comment := &model.Comment{
+		FileID:       fileID,
+		UserID:       userID,
+		Body:         req.Body,
+		AnchorPage:   req.AnchorPage,
+		AnchorMillis: req.AnchorMillis,
+	}
+	if req.ParentID != 0 {
+		parent, err := s.commentRepo.FindByID(ctx, req.ParentID)
+		if err != nil {
+			return nil, fmt.Errorf("error finding comment: %w", err)
+		}
+		if parent == nil || parent.FileID != fileID || parent.ParentID != nil {
+			return nil, ErrInvalidCommentParent
+		}
+		comment.ParentID = &parent.ID
+	}
+
+	audience, err := s.permissionRepo.FileAudience(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving file audience: %w", err)
+	}
+	if comment.Mentions, err = s.mentions(ctx, req.Body, audience); err != nil {
+		return nil, err
+	}
+	if err := s.commentRepo.Create(ctx, comment); err != nil {
+		s.logger.Error("Error creating comment", util.WithUserID(userID), zap.Uint("file_id", fileID), util.WithError(err))
+		return nil, fmt.Errorf("error creating comment: %w", err)
+	}
+
+	created, err := s.commentRepo.FindByID(ctx, comment.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding comment: %w", err)
+	}
+	s.events.Publish(ctx, newEvent(model.EventCommentCreated, userID, created, audience...))
+	s.notifyMentioned(ctx, userID, created, nil)
+
+	s.logger.Info("Comment created", util.WithUserID(userID), zap.Uint("file_id", fileID), zap.Uint("comment_id", created.ID))
+	return created, nil
+}
+
+// Update edits the user's own comment
+func (s *commentService) Update(ctx context.Context, userID, commentID uint, req *model.UpdateCommentRequest) (*model.Comment, error) {
+	comment, _, err := s.find(ctx, userID, commentID, model.PermissionWrite)
+	if err != nil {
+		return nil, err
+	}
+	if comment.UserID != userID {
+		return nil, ErrPermissionDenied
+	}
+	if comment.Body == req.Body {
+		return comment, nil
+	}
+
+	audience, err := s.permissionRepo.FileAudience(ctx, comment.FileID)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving file audience: %w", err)
+	}
+	mentions, err := s.mentions(ctx, req.Body, audience)
+	if err != nil {
+		return nil, err
+	}
+
+	previous := comment.Body
+	previouslyMentioned := comment.MentionedIDs()
+	now := time.Now()
+	comment.Body, comment.EditedAt, comment.Mentions = req.Body, &now, mentions
+	if err := s.commentRepo.UpdateBody(ctx, comment, previous); err != nil {
+		s.logger.Error("Error updating comment", util.WithUserID(userID), zap.Uint("comment_id", commentID), util.WithError(err))
+		return nil, fmt.Errorf("error updating comment: %w", err)
+	}
+
+	updated, err := s.commentRepo.FindByID(ctx, commentID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding comment: %w", err)
+	}
+	s.events.Publish(ctx, newEvent(model.EventCommentUpdated, userID, updated, audience...))
+	s.notifyMentioned(ctx, userID, updated, previouslyMentioned)
+	return updated, nil
+}
+
+// Delete removes a comment and its replies
+func (s *commentService) Delete(ctx context.Context, userID, commentID uint) error {
+	comment, permission, err := s.find(ctx, userID, commentID, model.PermissionWrite)
+	if err != nil {
+		return err
+	}
+	if comment.UserID != userID && permission != model.PermissionOwner {
+		return ErrPermissionDenied
+	}
+
+	if err := s.commentRepo.Delete(ctx, comment); err != nil {
+		s.logger.Error("Error deleting comment", util.WithUserID(userID), zap.Uint("comment_id", commentID), util.WithError(err))
+		return fmt.Errorf("error deleting comment: %w", err)
+	}
+
+	s.events.Publish(ctx, newEvent(model.EventCommentDeleted, userID, comment, s.fileAudience(ctx, comment.FileID)...))
+	s.logger.Info("Comment deleted", util.WithUserID(userID), zap.Uint("comment_id", commentID))
+	return nil
+}
+
+// Resolve marks the thread a comment belongs to as resolved
+func (s *commentService) Resolve(ctx context.Context, userID, commentID uint) (*model.Comment, error) {
+	return s.setResolved(ctx, userID, commentID, true)
+}
+
+// Reopen marks the thread a comment belongs to as open again
+func (s *commentService) Reopen(ctx context.Context, userID, commentID uint) (*model.Comment, error) {
+	return s.setResolved(ctx, userID, commentID, false)
+}
+
+func (s *commentService) setResolved(ctx context.Context, userID, commentID uint, resolved bool) (*model.Comment, error) {
+	comment, _, err := s.find(ctx, userID, commentID, model.PermissionWrite)
+	if err != nil {
+		return nil, err
+	}
+	thread := comment
+	if comment.ParentID != nil {
+		if thread, err = s.commentRepo.FindByID(ctx, *comment.ParentID); err != nil {
+			return nil, fmt.Errorf("error finding comment: %w", err)
+		}
+		if thread == nil {
+			return nil, ErrCommentNotFound
+		}
+	}
+	if (thread.ResolvedAt != nil) == resolved {
+		return thread, nil
+	}
+
+	if resolved {
+		now := time.Now()
+		thread.ResolvedAt, thread.ResolvedByID = &now, &userID
+	} else {
+		thread.ResolvedAt, thread.ResolvedByID = nil, nil
+	}
+	if err := s.commentRepo.SetResolved(ctx, thread); err != nil {
+		s.logger.Error("Error resolving comment thread", util.WithUserID(userID), zap.Uint("comment_id", thread.ID), util.WithError(err))
+		return nil, fmt.Errorf("error resolving comment thread: %w", err)
+	}
+
+	s.events.Publish(ctx, newEvent(model.EventCommentUpdated, userID, thread, s.fileAudience(ctx, thread.FileID)...))
+	return thread, nil
+}
+
+// History returns the earlier bodies of a comment
+func (s *commentService) History(ctx context.Context, userID, commentID uint) ([]model.CommentRevision, error) {
+	if _, _, err := s.find(ctx, userID, commentID, model.PermissionRead); err != nil {
+		return nil, err
+	}
+	revisions, err := s.commentRepo.ListRevisions(ctx, commentID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing comment revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// find returns a comment on a file the user has the required permission
+// on, along with that permission, hiding comments on files the user cannot
+// see
+func (s *commentService) find(ctx context.Context, userID, commentID uint, required model.Permission) (*model.Comment, model.Permission, error) {
+	comment, err := s.commentRepo.FindByID(ctx, commentID)
+	if err != nil {
+		return nil, "", fmt.Errorf("error finding comment: %w", err)
+	}
+	if comment == nil {
+		return nil, "", ErrCommentNotFound
+	}
+	permission, err := s.requirePermission(ctx, userID, comment.FileID, required)
+	if errors.Is(err, ErrFileNotFound) {
+		return nil, "", ErrCommentNotFound
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	return comment, permission, nil
+}
+
+// mentions resolves the usernames mentioned in a body, keeping the users
+// in the file's audience. Other names are left as plain text.
+func (s *commentService) mentions(ctx context.Context, body string, audience []uint) ([]model.CommentMention, error) {
+	users, err := s.userRepo.FindByUsernames(ctx, model.ParseMentions(body))
+	if err != nil {
+		return nil, fmt.Errorf("error finding mentioned users: %w", err)
+	}
+	allowed := make(map[uint]bool, len(audience))
+	for _, id := range audience {
+		allowed[id] = true
+	}
+
+	var mentions []model.CommentMention
+	for _, user := range users {
+		if allowed[user.ID] {
+			mentions = append(mentions, model.CommentMention{UserID: user.ID})
+		}
+	}
+	return mentions, nil
+}
+
+// notifyMentioned tells the users a comment mentions, other than its author
+// and those already told, that they were mentioned
+func (s *commentService) notifyMentioned(ctx context.Context, authorID uint, comment *model.Comment, notified []uint) {
+	skip := map[uint]bool{authorID: true}
+	for _, id := range notified {
+		skip[id] = true
+	}
+	var audience []uint
+	for _, id := range comment.MentionedIDs() {
+		if !skip[id] {
+			audience = append(audience, id)
+		}
+	}
+	if len(audience) > 0 {
+		s.events.Publish(ctx, newEvent(model.EventCommentMention, authorID, comment, audience...))
+	}
+}
+
+// fileAudience returns the users an event about a file's comments concerns
+func (s *commentService) fileAudience(ctx context.Context, fileID uint) []uint {
+	audience, err := s.permissionRepo.FileAudience(ctx, fileID)
+	if err != nil {
+		s.logger.Error("Error resolving file audience", zap.Uint("file_id", fileID), util.WithError(err))
+		return nil
+	}
+	return audience
+}
+
+// requirePermission checks the user's access to a file, hiding files the
+// user cannot see, and returns the user's permission
+func (s *commentService) requirePermission(ctx context.Context, userID, fileID uint, required model.Permission) (model.Permission, error) {
+	permission, err := s.permissionRepo.FilePermission(ctx, userID, fileID)
+	if err != nil {
+		return "", fmt.Errorf("error resolving file permission: %w", err)
+	}
+	if permission == "" {
+		return "", ErrFileNotFound
+	}
+	if !permission.Allows(required) {
+		return "", ErrPermissionDenied
+	}
+	return permission, nil
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/util"
+	"errors"
+	"sync"
+
+	"go.uber.org/zap"
+)
+
+// eventSubscriptionBuffer is the number of events a slow subscriber may fall
+// behind before it is disconnected
+const eventSubscriptionBuffer = 64
+
+var (
+	// ErrSubscriptionOverflow ends a subscription that fell too far behind
+	ErrSubscriptionOverflow = errors.New("subscriber fell too far behind")
+	// ErrEventBusClosed ends subscriptions when the server shuts down
+	ErrEventBusClosed = errors.New("event bus closed")
+)
+
+// EventBus fans events published by the services out to in-process
+// consumers. Handlers such as webhooks are called synchronously so events
+// they persist are not lost; live subscribers get a buffered stream of the
+// events that concern them.
+type EventBus interface {
+	EventPublisher
+	// Subscribe opens a live stream of the events whose audience includes userID
+	Subscribe(userID uint) *EventSubscription
+	// Close ends every subscription. Publishing continues to reach handlers.
+	Close()
+}
+
+// EventSubscription is a live stream of events for one user
+type EventSubscription struct {
+	userID uint
+	events chan *model.Event
+	bus    *eventBus
+
+	once sync.Once
+	err  error
+}
+
+// Events returns the stream of events. It is closed when the subscription
+// ends; Err then reports why.
+func (s *EventSubscription) Events() <-chan *model.Event {
+	return s.events
+}
+
+// Err returns the reason the subscription ended, or nil if it was closed by
+// the subscriber
+func (s *EventSubscription) Err() error {
+	s.bus.mu.RLock()
+	defer s.bus.mu.RUnlock()
+	return s.err
+}
+
+// Close ends the subscription
+func (s *EventSubscription) Close() {
+	s.bus.unsubscribe(s, nil)
+}
+
+type eventBus struct {
+	handlers []EventPublisher
+	logger   *util.Logger
+
+	mu            sync.RWMutex
+	subscriptions map[uint]map[*EventSubscription]bool
+	closed        bool
+}
+
+// NewEventBus creates an event bus that forwards every event to handlers
+func NewEventBus(logger *util.Logger, handlers ...EventPublisher) EventBus {
+	return &eventBus{
+		handlers:      handlers,
+		logger:        logger,
+		subscriptions: make(map[uint]map[*EventSubscription]bool),
+	}
+}
+
+// Publish forwards an event to the handlers and the subscribers in its audience
+func (b *eventBus) Publish(ctx context.Context, event *model.Event) {
+	for _, handler := range b.handlers {
+		handler.Publish(ctx, event)
+	}
+
+	var overflowed []*EventSubscription
+	b.mu.RLock()
+	for _, userID := range event.Audience {
+		for sub := range b.subscriptions[userID] {
+			select {
+			case sub.events <- event:
+			default:
+				overflowed = append(overflowed, sub)
+			}
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range overflowed {
+		b.logger.Warn("Disconnecting slow event subscriber", util.WithUserID(sub.userID), zap.String("event_id", event.ID))
+		b.unsubscribe(sub, ErrSubscriptionOverflow)
+	}
+}
+
+// Subscribe opens a live stream of the events whose audience includes userID
+func (b *eventBus) Subscribe(userID uint) *EventSubscription {
+	sub := &EventSubscription{
+		userID: userID,
+		events: make(chan *model.Event, eventSubscriptionBuffer),
+		bus:    b,
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		// Mark the subscription ended so Close does not close the stream twice
+		sub.once.Do(func() {
+			sub.err = ErrEventBusClosed
+			close(sub.events)
+		})
+		return sub
+	}
+	if b.subscriptions[userID] == nil {
+		b.subscriptions[userID] = make(map[*EventSubscription]bool)
+	}
+	b.subscriptions[userID][sub] = true
+	return sub
+}
+
+// Close ends every subscription
+func (b *eventBus) Close() {
+	b.mu.Lock()
+	b.closed = true
+	var subs []*EventSubscription
+	for _, userSubs := range b.subscriptions {
+		for sub := range userSubs {
+			subs = append(subs, sub)
+		}
+	}
+	b.mu.Unlock()
+
+	for _, sub := range subs {
+		b.unsubscribe(sub, ErrEventBusClosed)
+	}
+}
+
+// unsubscribe removes a subscription and closes its stream exactly once
+func (b *eventBus) unsubscribe(sub *EventSubscription, reason error) {
+	sub.once.Do(func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+
+		delete(b.subscriptions[sub.userID], sub)
+		if len(b.subscriptions[sub.userID]) == 0 {
+			delete(b.subscriptions, sub.userID)
+		}
+		sub.err = reason
+		close(sub.events)
+	})
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventPublisher receives the changes made by the services
+type EventPublisher interface {
+	// Publish hands an event to its subscribers. It never fails the caller;
+	// delivery problems are logged by the publisher.
+	Publish(ctx context.Context, event *model.Event)
+}
+
+// newEvent builds an event with a fresh ID
+func newEvent(eventType model.EventType, actorID uint, data interface{}, audience ...uint) *model.Event {
+	return &model.Event{
+		ID:         uuid.NewString(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		ActorID:    actorID,
+		Data:       data,
+		Audience:   audience,
+	}
+}
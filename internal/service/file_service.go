@@ -0,0 +1,874 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"drive/internal/encryption"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/storage"
+	"drive/internal/util"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// mimeSniffSize is the number of leading bytes used to detect a file's type
+const mimeSniffSize = 3072
+
+var (
+	ErrFileNotFound     = errors.New("file not found")
+	ErrFolderNotFound   = errors.New("folder not found")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrQuotaExceeded    = errors.New("storage quota exceeded")
+	ErrInvalidFileName  = errors.New("invalid file name")
+	ErrFileTooLarge     = errors.New("file exceeds the maximum upload size")
+	ErrFileModified     = errors.New("file was modified since it was last read")
+	ErrFileQuarantined  = errors.New("file is quarantined because malware was found in it")
+	ErrFileNotScanned   = errors.New("file has not been scanned for malware yet")
+	ErrIncompleteUpload = errors.New("upload ended before its declared size")
+)
+
+// FileService defines file storage operations
+type FileService interface {
+	// Upload stores a new file in a folder. A zero folderID uploads to the
+	// user's root folder. size is the exact content length, or zero when it
+	// is not known in advance; content ending short of it is rejected with
+	// ErrIncompleteUpload.
+	Upload(ctx context.Context, userID, folderID uint, fileName string, size int64, r io.Reader) (*model.File, error)
+	// GetFile returns a file the user can read
+	GetFile(ctx context.Context, userID, fileID uint) (*model.File, error)
+	// Download returns a file the user can read along with its content.
+	// Quarantined files cannot be downloaded.
+	Download(ctx context.Context, userID, fileID uint) (*model.File, io.ReadSeekCloser, error)
+	// Replace overwrites the content of a file the user can write to. When
+	// ifUpdatedAt is set the file must not have changed since that time.
+	// Replacing, updating and deleting fail with ErrFileLocked while others
+	// hold locks on the file.
+	Replace(ctx context.Context, userID, fileID uint, size int64, r io.Reader, ifUpdatedAt *time.Time) (*model.File, error)
+	// Update renames and/or moves a file
+	Update(ctx context.Context, userID, fileID uint, req *model.UpdateFileRequest) (*model.File, error)
+	// Delete moves a file the user can write to the trash
+	Delete(ctx context.Context, userID, fileID uint) error
+	// Restore takes a file out of the trash. The user needs write permission
+	// on the folder it was in, which must not be in the trash itself.
+	Restore(ctx context.Context, userID, fileID uint) (*model.File, error)
+	// Copy duplicates a file the user can read into a folder they can write
+	// to. The copy belongs to, and is charged to, the user, or the workspace
+	// of the folder.
+	Copy(ctx context.Context, userID, fileID, folderID uint) (*model.File, error)
+}
+
+type fileService struct {
+	fileRepo       repository.FileRepository
+	folderRepo     repository.FolderRepository
+	userRepo       repository.UserRepository
+	workspaceRepo  repository.WorkspaceRepository
+	permissionRepo repository.PermissionRepository
+	storage        storage.Storage
+	keys           *encryption.Keyring
+	indexer        IndexerService
+	scans          ScanService
+	locks          LockService
+	retention      RetentionService
+	audit          AuditService
+	events         EventPublisher
+	maxUploadSize  int64
+	logger         *util.Logger
+}
+
+// NewFileService creates a new FileService instance
+func NewFileService(
+	fileRepo repository.FileRepository,
+	folderRepo repository.FolderRepository,
+	userRepo repository.UserRepository,
+	workspaceRepo repository.WorkspaceRepository,
+	permissionRepo repository.PermissionRepository,
+	storage storage.Storage,
+	keys *encryption.Keyring,
+	indexer IndexerService,
+	scans ScanService,
+	locks LockService,
+	retention RetentionService,
+	audit AuditService,
+	events EventPublisher,
+	maxUploadSize int64,
+	logger *util.Logger,
+) FileService {
+	return &fileService{
+		fileRepo:       fileRepo,
+		folderRepo:     folderRepo,
+		userRepo:       userRepo,
+		workspaceRepo:  workspaceRepo,
+		permissionRepo: permissionRepo,
+		storage:        storage,
+		keys:           keys,
+		indexer:        indexer,
+		scans:          scans,
+		locks:          locks,
+		retention:      retention,
+		audit:          audit,
+		events:         events,
+		maxUploadSize:  maxUploadSize,
+		logger:         logger,
+	}
+}
+
+// Upload stores a new file in a folder
+func (s *fileService) Upload(ctx context.Context, userID, folderID uint, fileName string, size int64, r io.Reader) (*model.File, error) {
+	logger := s.logger.With(util.WithUserID(userID), zap.String("file_name", fileName))
+
+	fileName, err := cleanFileName(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if s.maxUploadSize > 0 && size > s.maxUploadSize {
+		return nil, ErrFileTooLarge
+	}
+
+	folder, err := resolveFolder(ctx, s.folderRepo, userID, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireFolderPermission(ctx, userID, folder.ID, model.PermissionWrite); err != nil {
+		return nil, err
+	}
+
+	account := storageAccount{userID: userID, workspaceID: folder.WorkspaceID}
+	blob, err := s.storeBlob(ctx, account, size, r)
+	if err != nil {
+		return nil, err
+	}
+	if folder.VaultID != nil {
+		blob.mimeType = vaultMimeType
+	}
+
+	file := &model.File{
+		FileName:    fileName,
+		FileType:    fileTypeFromMime(blob.mimeType),
+		FileSize:    blob.size,
+		MimeType:    blob.mimeType,
+		FileURL:     blob.key,
+		FolderID:    folder.ID,
+		UserID:      userID,
+		WorkspaceID: folder.WorkspaceID,
+
+		EncryptionKeyID: blob.keyID,
+		DataKey:         blob.dataKey,
+		ContentHash:     blob.hash,
+		ScanStatus:      s.initialScanStatus(folder.VaultID),
+	}
+	if err := s.fileRepo.Create(ctx, file); err != nil {
+		s.discardBlob(ctx, account, blob)
+		logger.Error("Error creating file", util.WithError(err))
+		return nil, fmt.Errorf("error creating file: %w", err)
+	}
+
+	s.indexer.Enqueue(ctx, file.ID)
+	s.scans.Enqueue(ctx, file.ID)
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditFileUpload,
+		ActorID:    auditRef(userID),
+		TargetType: model.AuditTargetFile,
+		TargetID:   auditRef(file.ID),
+		Metadata:   model.JSONMap{"file_name": file.FileName, "file_size": file.FileSize, "folder_id": file.FolderID},
+	})
+	s.events.Publish(ctx, newEvent(model.EventFileCreated, userID, file, s.fileAudience(ctx, file)...))
+
+	logger.Info("File uploaded successfully", zap.Uint("file_id", file.ID), zap.Int64("file_size", blob.size))
+	return file, nil
+}
+
+// Replace overwrites the content of a file the user can write to. The
+// storage is charged to the file's owner or workspace. With ifUpdatedAt set, the file is
+// only replaced if nobody updated it in the meantime; otherwise the upload is
+// discarded and ErrFileModified returned.
+func (s *fileService) Replace(ctx context.Context, userID, fileID uint, size int64, r io.Reader, ifUpdatedAt *time.Time) (*model.File, error) {
+	logger := s.logger.With(util.WithUserID(userID), zap.Uint("file_id", fileID))
+
+	if s.maxUploadSize > 0 && size > s.maxUploadSize {
+		return nil, ErrFileTooLarge
+	}
+	file, err := s.GetFile(ctx, userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireFilePermission(ctx, userID, file.ID, model.PermissionWrite); err != nil {
+		return nil, err
+	}
+	if ifUpdatedAt != nil && !sameInstant(file.UpdatedAt, *ifUpdatedAt) {
+		return nil, ErrFileModified
+	}
+	if err := s.locks.CheckFile(ctx, userID, file.ID); err != nil {
+		return nil, err
+	}
+	if err := s.retention.CheckFileReplace(ctx, userID, file); err != nil {
+		return nil, err
+	}
+	vaultID, err := s.folderVault(ctx, file.FolderID)
+	if err != nil {
+		return nil, err
+	}
+
+	blob, err := s.storeBlob(ctx, accountOf(file), size, r)
+	if err != nil {
+		return nil, err
+	}
+	if vaultID != nil {
+		blob.mimeType = vaultMimeType
+	}
+
+	previousKey, previousSize, previousUpdatedAt := file.FileURL, file.FileSize, file.UpdatedAt
+	file.FileURL = blob.key
+	file.FileSize = blob.size
+	file.MimeType = blob.mimeType
+	file.FileType = fileTypeFromMime(blob.mimeType)
+	file.EncryptionKeyID = blob.keyID
+	file.DataKey = blob.dataKey
+	file.ContentHash = blob.hash
+	file.ScanStatus = s.initialScanStatus(vaultID)
+	file.ScanSignature = ""
+	file.ScannedAt = nil
+	file.IntegrityStatus = model.IntegrityUnverified
+	file.VerifiedAt = nil
+	// With a precondition the row is only written if it is unchanged, as
+	// another upload may have finished while this one was streaming
+	saved := true
+	if ifUpdatedAt != nil {
+		saved, err = s.fileRepo.UpdateIfUnmodified(ctx, file, previousUpdatedAt)
+	} else {
+		err = s.fileRepo.Update(ctx, file)
+	}
+	if err != nil {
+		s.discardBlob(ctx, accountOf(file), blob)
+		logger.Error("Error updating file", util.WithError(err))
+		return nil, fmt.Errorf("error updating file: %w", err)
+	}
+	if !saved {
+		s.discardBlob(ctx, accountOf(file), blob)
+		return nil, ErrFileModified
+	}
+
+	if err := s.storage.Delete(ctx, previousKey); err != nil {
+		logger.Error("Error removing replaced blob", util.WithError(err))
+	}
+	if err := s.storage.Delete(ctx, previewKey(previousKey)); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		logger.Error("Error removing preview of replaced blob", util.WithError(err))
+	}
+	s.releaseStorage(ctx, accountOf(file), toMegabytes(previousSize))
+
+	s.indexer.Enqueue(ctx, file.ID)
+	s.scans.Enqueue(ctx, file.ID)
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditFileUpload,
+		ActorID:    auditRef(userID),
+		TargetType: model.AuditTargetFile,
+		TargetID:   auditRef(file.ID),
+		Metadata:   model.JSONMap{"file_name": file.FileName, "file_size": file.FileSize, "folder_id": file.FolderID, "replaced": true},
+	})
+	s.events.Publish(ctx, newEvent(model.EventFileUpdated, userID, file, s.fileAudience(ctx, file)...))
+
+	logger.Info("File content replaced", zap.Int64("file_size", blob.size))
+	return file, nil
+}
+
+// Update renames and/or moves a file. Renaming needs write permission;
+// moving needs write permission on the destination folder, which must be in
+// the same vault, if any, and ownership of the file unless it stays within
+// its workspace. Personal files may move into a workspace, which takes them
+// over, but nothing moves out of one.
+func (s *fileService) Update(ctx context.Context, userID, fileID uint, req *model.UpdateFileRequest) (*model.File, error) {
+	file, err := s.GetFile(ctx, userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireFilePermission(ctx, userID, file.ID, model.PermissionWrite); err != nil {
+		return nil, err
+	}
+
+	renamed := false
+	if req.Name != nil {
+		name, err := cleanFileName(*req.Name)
+		if err != nil {
+			return nil, err
+		}
+		renamed = name != file.FileName
+		file.FileName = name
+	}
+
+	var previousAudience []uint
+	var adoptingWorkspace *uint
+	moved := req.FolderID != nil && *req.FolderID != file.FolderID
+	if moved {
+		if err := s.requireFolderPermission(ctx, userID, *req.FolderID, model.PermissionWrite); err != nil {
+			return nil, err
+		}
+		from, err := s.findFolder(ctx, file.FolderID)
+		if err != nil {
+			return nil, err
+		}
+		to, err := s.findFolder(ctx, *req.FolderID)
+		if err != nil {
+			return nil, err
+		}
+		// Within a workspace, editors may rearrange what they can change
+		required := model.PermissionOwner
+		if file.WorkspaceID != nil && sameWorkspace(file.WorkspaceID, to.WorkspaceID) {
+			required = model.PermissionWrite
+		}
+		if err := s.requireFilePermission(ctx, userID, file.ID, required); err != nil {
+			return nil, err
+		}
+		if !sameVault(from.VaultID, to.VaultID) {
+			return nil, ErrVaultBoundary
+		}
+		if !sameWorkspace(file.WorkspaceID, to.WorkspaceID) {
+			if file.WorkspaceID != nil {
+				return nil, ErrWorkspaceBoundary
+			}
+			adoptingWorkspace = to.WorkspaceID
+		}
+		if err := s.retention.CheckFileMove(ctx, userID, file, *req.FolderID); err != nil {
+			return nil, err
+		}
+		previousAudience = s.fileAudience(ctx, file)
+		file.FolderID = *req.FolderID
+	}
+
+	if !renamed && !moved {
+		return file, nil
+	}
+	if err := s.locks.CheckFile(ctx, userID, file.ID); err != nil {
+		return nil, err
+	}
+	if adoptingWorkspace != nil {
+		if err := s.adopt(ctx, userID, file, *adoptingWorkspace); err != nil {
+			return nil, err
+		}
+	} else if err := s.fileRepo.Update(ctx, file); err != nil {
+		s.logger.Error("Error updating file", util.WithUserID(userID), zap.Uint("file_id", fileID), util.WithError(err))
+		return nil, fmt.Errorf("error updating file: %w", err)
+	}
+
+	if moved {
+		audience := mergeAudience(previousAudience, s.fileAudience(ctx, file))
+		s.events.Publish(ctx, newEvent(model.EventFileMoved, userID, file, audience...))
+	} else {
+		s.events.Publish(ctx, newEvent(model.EventFileUpdated, userID, file, s.fileAudience(ctx, file)...))
+	}
+	return file, nil
+}
+
+// GetFile returns a file the user can read
+func (s *fileService) GetFile(ctx context.Context, userID, fileID uint) (*model.File, error) {
+	file, err := s.fileRepo.FindByID(ctx, fileID)
+	if err != nil {
+		s.logger.Error("Error finding file", util.WithUserID(userID), util.WithError(err))
+		return nil, fmt.Errorf("error finding file: %w", err)
+	}
+	if file == nil {
+		return nil, ErrFileNotFound
+	}
+
+	permission, err := s.permissionRepo.FilePermission(ctx, userID, fileID)
+	if err != nil {
+		s.logger.Error("Error resolving file permission", util.WithUserID(userID), util.WithError(err))
+		return nil, fmt.Errorf("error resolving file permission: %w", err)
+	}
+	if !permission.Allows(model.PermissionRead) {
+		// Hide the existence of files the user cannot see
+		return nil, ErrFileNotFound
+	}
+
+	return file, nil
+}
+
+// Download returns a file the user can read along with its content
+func (s *fileService) Download(ctx context.Context, userID, fileID uint) (*model.File, io.ReadSeekCloser, error) {
+	file, err := s.GetFile(ctx, userID, fileID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := s.scans.Check(file); err != nil {
+		return nil, nil, err
+	}
+
+	blob, err := openBlob(ctx, s.storage, s.keys, file)
+	if err != nil {
+		s.logger.Error("Error opening blob", util.WithUserID(userID), zap.Uint("file_id", fileID), util.WithError(err))
+		return nil, nil, fmt.Errorf("error opening file: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditFileDownload,
+		ActorID:    auditRef(userID),
+		TargetType: model.AuditTargetFile,
+		TargetID:   auditRef(file.ID),
+		Metadata:   model.JSONMap{"file_name": file.FileName},
+	})
+
+	return file, blob, nil
+}
+
+// Delete moves a file the user can write to the trash. The blob and the
+// owner's storage usage are kept until the trash is purged.
+func (s *fileService) Delete(ctx context.Context, userID, fileID uint) error {
+	file, err := s.GetFile(ctx, userID, fileID)
+	if err != nil {
+		return err
+	}
+	if err := s.requireFilePermission(ctx, userID, fileID, model.PermissionWrite); err != nil {
+		return err
+	}
+	if err := s.locks.CheckFile(ctx, userID, file.ID); err != nil {
+		return err
+	}
+	if err := s.retention.CheckFileDelete(ctx, userID, file); err != nil {
+		return err
+	}
+
+	if err := s.fileRepo.Delete(ctx, file); err != nil {
+		s.logger.Error("Error deleting file", util.WithUserID(userID), zap.Uint("file_id", fileID), util.WithError(err))
+		return fmt.Errorf("error deleting file: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditFileDelete,
+		ActorID:    auditRef(userID),
+		TargetType: model.AuditTargetFile,
+		TargetID:   auditRef(file.ID),
+		Metadata:   model.JSONMap{"file_name": file.FileName, "owner_id": file.UserID},
+	})
+	s.events.Publish(ctx, newEvent(model.EventFileDeleted, userID, file, s.fileAudience(ctx, file)...))
+
+	s.logger.Info("File deleted", util.WithUserID(userID), zap.Uint("file_id", fileID))
+	return nil
+}
+
+// Restore takes a file out of the trash
+func (s *fileService) Restore(ctx context.Context, userID, fileID uint) (*model.File, error) {
+	file, err := s.fileRepo.FindDeleted(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding file: %w", err)
+	}
+	if file == nil {
+		return nil, ErrFileNotFound
+	}
+	if err := s.requireFolderPermission(ctx, userID, file.FolderID, model.PermissionWrite); err != nil {
+		return nil, err
+	}
+
+	if err := s.fileRepo.Restore(ctx, file); err != nil {
+		s.logger.Error("Error restoring file", util.WithUserID(userID), zap.Uint("file_id", fileID), util.WithError(err))
+		return nil, fmt.Errorf("error restoring file: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditFileRestore,
+		ActorID:    auditRef(userID),
+		TargetType: model.AuditTargetFile,
+		TargetID:   auditRef(file.ID),
+		Metadata:   model.JSONMap{"file_name": file.FileName, "owner_id": file.UserID},
+	})
+	s.events.Publish(ctx, newEvent(model.EventFileRestored, userID, file, s.fileAudience(ctx, file)...))
+
+	s.logger.Info("File restored", util.WithUserID(userID), zap.Uint("file_id", fileID))
+	return file, nil
+}
+
+// Copy duplicates a file into a folder. The content is stored again rather
+// than shared, so the copy is independent of the original. Copies cannot
+// cross a vault boundary, as vault content is encrypted for its vault.
+func (s *fileService) Copy(ctx context.Context, userID, fileID, folderID uint) (*model.File, error) {
+	logger := s.logger.With(util.WithUserID(userID), zap.Uint("file_id", fileID))
+
+	source, err := s.GetFile(ctx, userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if source.Quarantined() {
+		return nil, ErrFileQuarantined
+	}
+	folder, err := resolveFolder(ctx, s.folderRepo, userID, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireFolderPermission(ctx, userID, folder.ID, model.PermissionWrite); err != nil {
+		return nil, err
+	}
+	from, err := s.folderVault(ctx, source.FolderID)
+	if err != nil {
+		return nil, err
+	}
+	if !sameVault(from, folder.VaultID) {
+		return nil, ErrVaultBoundary
+	}
+
+	content, err := openBlob(ctx, s.storage, s.keys, source)
+	if err != nil {
+		logger.Error("Error opening file to copy", util.WithError(err))
+		return nil, fmt.Errorf("error opening file: %w", err)
+	}
+	defer content.Close()
+
+	account := storageAccount{userID: userID, workspaceID: folder.WorkspaceID}
+	blob, err := s.storeBlob(ctx, account, source.FileSize, content)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &model.File{
+		FileName:    source.FileName,
+		FileType:    source.FileType,
+		FileSize:    blob.size,
+		MimeType:    source.MimeType,
+		FileURL:     blob.key,
+		FolderID:    folder.ID,
+		UserID:      userID,
+		WorkspaceID: folder.WorkspaceID,
+
+		EncryptionKeyID: blob.keyID,
+		DataKey:         blob.dataKey,
+		ContentHash:     blob.hash,
+		ScanStatus:      s.initialScanStatus(folder.VaultID),
+	}
+	if err := s.fileRepo.Create(ctx, file); err != nil {
+		s.discardBlob(ctx, account, blob)
+		logger.Error("Error creating file copy", util.WithError(err))
+		return nil, fmt.Errorf("error creating file: %w", err)
+	}
+
+	s.indexer.Enqueue(ctx, file.ID)
+	s.scans.Enqueue(ctx, file.ID)
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditFileCopy,
+		ActorID:    auditRef(userID),
+		TargetType: model.AuditTargetFile,
+		TargetID:   auditRef(file.ID),
+		Metadata:   model.JSONMap{"source_file_id": source.ID, "file_size": file.FileSize, "folder_id": file.FolderID},
+	})
+	s.events.Publish(ctx, newEvent(model.EventFileCreated, userID, file, s.fileAudience(ctx, file)...))
+
+	logger.Info("File copied", zap.Uint("copy_id", file.ID))
+	return file, nil
+}
+
+// requireFolderPermission fails unless the user holds the required permission
+func (s *fileService) requireFolderPermission(ctx context.Context, userID, folderID uint, required model.Permission) error {
+	permission, err := s.permissionRepo.FolderPermission(ctx, userID, folderID)
+	if err != nil {
+		return fmt.Errorf("error resolving folder permission: %w", err)
+	}
+	if permission == "" {
+		return ErrFolderNotFound
+	}
+	if !permission.Allows(required) {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// requireFilePermission fails unless the user holds the required permission
+func (s *fileService) requireFilePermission(ctx context.Context, userID, fileID uint, required model.Permission) error {
+	permission, err := s.permissionRepo.FilePermission(ctx, userID, fileID)
+	if err != nil {
+		return fmt.Errorf("error resolving file permission: %w", err)
+	}
+	if permission == "" {
+		return ErrFileNotFound
+	}
+	if !permission.Allows(required) {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// fileAudience returns the users an event about a file concerns, falling
+// back to the owner if access cannot be resolved
+func (s *fileService) fileAudience(ctx context.Context, file *model.File) []uint {
+	audience, err := s.permissionRepo.FileAudience(ctx, file.ID)
+	if err != nil {
+		s.logger.Error("Error resolving file audience", zap.Uint("file_id", file.ID), util.WithError(err))
+		return []uint{file.UserID}
+	}
+	return audience
+}
+
+// storedBlob describes content written to storage and charged to a user
+type storedBlob struct {
+	key       string
+	size      int64
+	mimeType  string
+	megabytes float64
+	// hash is the hex SHA-256 of the plaintext
+	hash string
+	// keyID and dataKey are the wrapped data key of an encrypted blob
+	keyID   string
+	dataKey []byte
+}
+
+// findFolder loads a folder, failing with ErrFolderNotFound if it is gone
+func (s *fileService) findFolder(ctx context.Context, folderID uint) (*model.Folder, error) {
+	folder, err := s.folderRepo.FindByID(ctx, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding folder: %w", err)
+	}
+	if folder == nil {
+		return nil, ErrFolderNotFound
+	}
+	return folder, nil
+}
+
+// folderVault returns the ID of the vault holding a folder, or nil
+func (s *fileService) folderVault(ctx context.Context, folderID uint) (*uint, error) {
+	folder, err := s.findFolder(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+	return folder.VaultID, nil
+}
+
+// adopt saves a personal file moved into a workspace, which takes over it
+// and its storage charge
+func (s *fileService) adopt(ctx context.Context, userID uint, file *model.File, workspaceID uint) error {
+	ok, err := s.workspaceRepo.AdoptFile(ctx, file, workspaceID)
+	if err != nil {
+		s.logger.Error("Error moving file into workspace", util.WithUserID(userID), zap.Uint("file_id", file.ID), util.WithError(err))
+		return fmt.Errorf("error moving file into workspace: %w", err)
+	}
+	if !ok {
+		return ErrQuotaExceeded
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditWorkspaceTransfer,
+		ActorID:    auditRef(userID),
+		TargetType: model.AuditTargetFile,
+		TargetID:   auditRef(file.ID),
+		Metadata:   model.JSONMap{"workspace_id": workspaceID, "owner_id": file.UserID, "file_size": file.FileSize},
+	})
+	return nil
+}
+
+// storeBlob streams content into storage, charging it against the quota of
+// account. size is the declared length, which may be zero when unknown; the
+// charge is settled against the bytes actually received, and content that
+// does not match a declared length is discarded. When encryption is enabled
+// the content is encrypted under a new data key on the way in.
+func (s *fileService) storeBlob(ctx context.Context, account storageAccount, size int64, r io.Reader) (*storedBlob, error) {
+	logger := s.logger.With(util.WithUserID(account.userID))
+
+	if size < 0 {
+		size = 0
+	}
+	if s.maxUploadSize > 0 {
+		r = io.LimitReader(r, s.maxUploadSize+1)
+	}
+
+	// Sniff the content type from the first bytes of the upload
+	header := make([]byte, mimeSniffSize)
+	n, err := io.ReadFull(r, header)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("error reading upload: %w", err)
+	}
+	header = header[:n]
+
+	reserved := toMegabytes(size)
+	ok, err := s.adjustStorage(ctx, account, reserved)
+	if err != nil {
+		logger.Error("Error reserving storage", util.WithError(err))
+		return nil, fmt.Errorf("error reserving storage: %w", err)
+	}
+	if !ok {
+		return nil, ErrQuotaExceeded
+	}
+
+	blob := &storedBlob{
+		key:       fmt.Sprintf("%d/%s", account.userID, uuid.NewString()),
+		mimeType:  mimetype.Detect(header).String(),
+		megabytes: reserved,
+	}
+
+	// Count and hash the plaintext, as an encrypted blob is larger than the
+	// upload and differs for identical content
+	hasher := sha256.New()
+	content := &countingReader{r: io.TeeReader(io.MultiReader(bytes.NewReader(header), r), hasher)}
+	var body io.Reader = content
+	if s.keys != nil {
+		var dataKey []byte
+		dataKey, blob.dataKey, blob.keyID, err = s.keys.NewDataKey()
+		if err == nil {
+			body, err = encryption.Encrypt(content, dataKey)
+		}
+		if err != nil {
+			s.releaseStorage(ctx, account, reserved)
+			logger.Error("Error encrypting upload", util.WithError(err))
+			return nil, fmt.Errorf("error encrypting upload: %w", err)
+		}
+	}
+
+	if _, err := s.storage.Put(ctx, blob.key, body); err != nil {
+		s.releaseStorage(ctx, account, reserved)
+		logger.Error("Error storing upload", util.WithError(err))
+		return nil, fmt.Errorf("error storing upload: %w", err)
+	}
+	written := content.n
+	blob.size = written
+	blob.hash = hex.EncodeToString(hasher.Sum(nil))
+
+	if s.maxUploadSize > 0 && written > s.maxUploadSize {
+		s.discardBlob(ctx, account, blob)
+		return nil, ErrFileTooLarge
+	}
+	if size > 0 && written != size {
+		s.discardBlob(ctx, account, blob)
+		logger.Warn("Upload did not match its declared size", zap.Int64("declared", size), zap.Int64("received", written))
+		return nil, ErrIncompleteUpload
+	}
+
+	// Settle the reservation against the actual number of bytes received
+	if actual := toMegabytes(written); actual != reserved {
+		ok, err := s.adjustStorage(ctx, account, actual-reserved)
+		if err != nil {
+			logger.Error("Error settling storage usage", util.WithError(err))
+		} else if !ok {
+			s.discardBlob(ctx, account, blob)
+			return nil, ErrQuotaExceeded
+		} else {
+			blob.megabytes = actual
+		}
+	}
+
+	return blob, nil
+}
+
+// initialScanStatus is the scan status of newly stored content. Vault
+// content is encrypted by the client and cannot be scanned.
+func (s *fileService) initialScanStatus(vaultID *uint) model.ScanStatus {
+	if !s.scans.Enabled() || vaultID != nil {
+		return model.ScanStatusNotScanned
+	}
+	return model.ScanStatusPending
+}
+
+// discardBlob removes a stored blob that will not be used and returns its
+// storage charge
+func (s *fileService) discardBlob(ctx context.Context, account storageAccount, blob *storedBlob) {
+	if err := s.storage.Delete(ctx, blob.key); err != nil {
+		s.logger.Error("Error removing orphaned blob", util.WithUserID(account.userID), util.WithError(err))
+	}
+	s.releaseStorage(ctx, account, blob.megabytes)
+}
+
+// openBlob opens the content of a file, decrypting it if it was stored
+// encrypted
+func openBlob(ctx context.Context, store storage.Storage, keys *encryption.Keyring, file *model.File) (io.ReadSeekCloser, error) {
+	blob, err := store.Open(ctx, file.FileURL)
+	if err != nil || file.EncryptionKeyID == "" {
+		return blob, err
+	}
+
+	dataKey, err := keys.Unwrap(file.EncryptionKeyID, file.DataKey)
+	if err != nil {
+		blob.Close()
+		return nil, err
+	}
+	content, err := encryption.Decrypt(blob, dataKey)
+	if err != nil {
+		blob.Close()
+		return nil, err
+	}
+	return content, nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+// Read implements io.Reader
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// cleanFileName reduces a client supplied name to its last path segment and
+// rejects names that cannot be stored
+func cleanFileName(name string) (string, error) {
+	name = path.Base(strings.ReplaceAll(strings.TrimSpace(name), "\\", "/"))
+	if name == "" || name == "." || name == "/" || name == ".." {
+		return "", ErrInvalidFileName
+	}
+	return name, nil
+}
+
+// releaseStorage returns a reservation made for a failed upload
+func (s *fileService) releaseStorage(ctx context.Context, account storageAccount, megabytes float64) {
+	if _, err := s.adjustStorage(ctx, account, -megabytes); err != nil {
+		s.logger.Error("Error releasing storage", util.WithUserID(account.userID), util.WithError(err))
+	}
+}
+
+// storageAccount is who stored content is charged to: the workspace
+// holding it or, outside workspaces, the user who added it
+type storageAccount struct {
+	userID      uint
+	workspaceID *uint
+}
+
+// accountOf returns the account a file is charged to
+func accountOf(file *model.File) storageAccount {
+	return storageAccount{userID: file.UserID, workspaceID: file.WorkspaceID}
+}
+
+// adjustStorage adds delta to an account's storage usage, applying
+// increases only within its limit
+func (s *fileService) adjustStorage(ctx context.Context, account storageAccount, delta float64) (bool, error) {
+	if account.workspaceID != nil {
+		return s.workspaceRepo.AdjustStorageUsed(ctx, *account.workspaceID, delta)
+	}
+	return s.userRepo.AdjustStorageUsed(ctx, account.userID, delta)
+}
+
+// toMegabytes converts a byte count to the unit used by StorageUsed and StorageLimit
+func toMegabytes(size int64) float64 {
+	return float64(size) / (1024 * 1024)
+}
+
+// fileTypeFromMime maps a MIME type onto the coarse FileType categories
+func fileTypeFromMime(mimeType string) model.FileType {
+	switch {
+	case strings.HasPrefix(mimeType, "image/"):
+		return model.FileTypeImage
+	case strings.HasPrefix(mimeType, "video/"):
+		return model.FileTypeVideo
+	case strings.HasPrefix(mimeType, "audio/"):
+		return model.FileTypeAudio
+	case strings.HasPrefix(mimeType, "text/"),
+		strings.HasPrefix(mimeType, "application/pdf"),
+		strings.HasPrefix(mimeType, "application/vnd.openxmlformats-officedocument"),
+		strings.HasPrefix(mimeType, "application/vnd.oasis.opendocument"),
+		strings.HasPrefix(mimeType, "application/msword"),
+		strings.HasPrefix(mimeType, "application/rtf"):
+		return model.FileTypePDF
+	default:
+		return model.FileTypeOther
+	}
+}
+
+// sameInstant compares update times at the microsecond precision the
+// database keeps
+func sameInstant(a, b time.Time) bool {
+	return a.Truncate(time.Microsecond).Equal(b.Truncate(time.Microsecond))
+}
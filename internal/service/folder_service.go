@@ -0,0 +1,526 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInvalidFolderName = errors.New("invalid folder name")
+	ErrInvalidFolderMove = errors.New("a folder cannot be moved into itself or one of its subfolders")
+	ErrRootFolder        = errors.New("the root folder cannot be renamed, moved, copied or deleted")
+	ErrInvalidFolderCopy = errors.New("a folder cannot be copied into itself or one of its subfolders")
+)
+
+// FolderService manages the folder hierarchy
+type FolderService interface {
+	// Create makes a folder inside a parent the user can write to. A zero
+	// parent creates it in the user's root folder. Folders created inside a
+	// vault or workspace belong to it.
+	Create(ctx context.Context, userID uint, req *model.CreateFolderRequest) (*model.Folder, error)
+	// Get returns a folder the user can read along with its children. A
+	// zero folderID returns the user's root folder.
+	Get(ctx context.Context, userID, folderID uint) (*model.FolderContents, error)
+	// Update renames and/or moves a folder
+	Update(ctx context.Context, userID, folderID uint, req *model.UpdateFolderRequest) (*model.Folder, error)
+	// Delete moves a folder and everything beneath it to the trash. It fails
+	// with ErrFileLocked while others hold locks on files beneath it.
+	Delete(ctx context.Context, userID, folderID uint) error
+	// Restore takes a folder, and everything beneath it, out of the trash.
+	// The user needs write permission on its parent, which must not be in
+	// the trash itself.
+	Restore(ctx context.Context, userID, folderID uint) (*model.Folder, error)
+	// Copy duplicates a folder the user can read, with everything beneath
+	// it, into a folder they can write to. The copies belong to the user, or
+	// the workspace of the destination.
+	Copy(ctx context.Context, userID, folderID, parentID uint) (*model.Folder, error)
+}
+
+type folderService struct {
+	folderRepo     repository.FolderRepository
+	fileRepo       repository.FileRepository
+	vaultRepo      repository.VaultRepository
+	workspaceRepo  repository.WorkspaceRepository
+	permissionRepo repository.PermissionRepository
+	files          FileService
+	locks          LockService
+	retention      RetentionService
+	audit          AuditService
+	events         EventPublisher
+	logger         *util.Logger
+}
+
+// NewFolderService creates a new FolderService instance
+func NewFolderService(
+	folderRepo repository.FolderRepository,
+	fileRepo repository.FileRepository,
+	vaultRepo repository.VaultRepository,
+	workspaceRepo repository.WorkspaceRepository,
+	permissionRepo repository.PermissionRepository,
+	files FileService,
+	locks LockService,
+	retention RetentionService,
+	audit AuditService,
+	events EventPublisher,
+	logger *util.Logger,
+) FolderService {
+	return &folderService{
+		folderRepo:     folderRepo,
+		fileRepo:       fileRepo,
+		vaultRepo:      vaultRepo,
+		workspaceRepo:  workspaceRepo,
+		permissionRepo: permissionRepo,
+		files:          files,
+		locks:          locks,
+		retention:      retention,
+		audit:          audit,
+		events:         events,
+		logger:         logger,
+	}
+}
+
+// Create makes a folder inside a parent the user can write to, or the root
+// of a new vault
+func (s *folderService) Create(ctx context.Context, userID uint, req *model.CreateFolderRequest) (*model.Folder, error) {
+	name, err := cleanFolderName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	parent, err := resolveFolder(ctx, s.folderRepo, userID, req.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requirePermission(ctx, userID, parent.ID, model.PermissionWrite); err != nil {
+		return nil, err
+	}
+
+	folder := &model.Folder{
+		FolderName:     name,
+		ParentFolderID: &parent.ID,
+		UserID:         userID,
+		VaultID:        parent.VaultID,
+		WorkspaceID:    parent.WorkspaceID,
+	}
+	if req.Vault {
+		if parent.VaultID != nil {
+			return nil, ErrNestedVault
+		}
+		if len(req.WrappedKey) == 0 {
+			return nil, ErrVaultKeyRequired
+		}
+		err = s.vaultRepo.CreateVault(ctx, folder, &model.VaultKey{UserID: userID, WrappedKey: req.WrappedKey})
+	} else {
+		err = s.folderRepo.Create(ctx, folder)
+	}
+	if err != nil {
+		s.logger.Error("Error creating folder", util.WithUserID(userID), util.WithError(err))
+		return nil, fmt.Errorf("error creating folder: %w", err)
+	}
+
+	s.events.Publish(ctx, newEvent(model.EventFolderCreated, userID, folder, s.audience(ctx, folder.ID)...))
+	return folder, nil
+}
+
+// Get returns a folder the user can read along with its children
+func (s *folderService) Get(ctx context.Context, userID, folderID uint) (*model.FolderContents, error) {
+	folder, err := resolveFolder(ctx, s.folderRepo, userID, folderID)
+	if err != nil {
+		return nil, err
+	}
+
+	permission, err := s.permissionRepo.FolderPermission(ctx, userID, folder.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving folder permission: %w", err)
+	}
+	if permission == "" {
+		return nil, ErrFolderNotFound
+	}
+
+	folders, err := s.folderRepo.ListChildren(ctx, folder.ID)
+	if err != nil {
+		s.logger.Error("Error listing subfolders", zap.Uint("folder_id", folder.ID), util.WithError(err))
+		return nil, fmt.Errorf("error listing subfolders: %w", err)
+	}
+	files, err := s.fileRepo.ListByFolder(ctx, folder.ID)
+	if err != nil {
+		s.logger.Error("Error listing files", zap.Uint("folder_id", folder.ID), util.WithError(err))
+		return nil, fmt.Errorf("error listing files: %w", err)
+	}
+
+	return &model.FolderContents{
+		Folder:     folder,
+		Permission: permission,
+		Folders:    folders,
+		Files:      files,
+	}, nil
+}
+
+// Update renames and/or moves a folder. Renaming needs write permission;
+// moving needs write permission on the destination and ownership of the
+// folder unless it stays within its workspace. Folders cannot be moved into
+// or out of a vault; a vault's root moves with everything in it, but not
+// into another vault. Personal folders may move into a workspace, which
+// takes over everything in them, but nothing moves out of one. Files locked
+// by others beneath the folder, or beneath the destination of a move, keep
+// it where it is.
+func (s *folderService) Update(ctx context.Context, userID, folderID uint, req *model.UpdateFolderRequest) (*model.Folder, error) {
+	folder, err := s.findFolder(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requirePermission(ctx, userID, folder.ID, model.PermissionWrite); err != nil {
+		return nil, err
+	}
+	if folder.ParentFolderID == nil {
+		return nil, ErrRootFolder
+	}
+
+	renamed := false
+	if req.Name != nil {
+		name, err := cleanFolderName(*req.Name)
+		if err != nil {
+			return nil, err
+		}
+		renamed = name != folder.FolderName
+		folder.FolderName = name
+	}
+
+	var previousAudience []uint
+	var adoptingWorkspace *uint
+	moved := req.ParentID != nil && *req.ParentID != *folder.ParentFolderID
+	if moved {
+		if err := s.requirePermission(ctx, userID, *req.ParentID, model.PermissionWrite); err != nil {
+			return nil, err
+		}
+		destination, err := s.findFolder(ctx, *req.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		// Within a workspace, editors may rearrange what they can change
+		required := model.PermissionOwner
+		if folder.WorkspaceID != nil && sameWorkspace(folder.WorkspaceID, destination.WorkspaceID) {
+			required = model.PermissionWrite
+		}
+		if err := s.requirePermission(ctx, userID, folder.ID, required); err != nil {
+			return nil, err
+		}
+		cycle, err := s.folderRepo.IsDescendant(ctx, *req.ParentID, folder.ID)
+		if err != nil {
+			return nil, fmt.Errorf("error checking folder hierarchy: %w", err)
+		}
+		if cycle {
+			return nil, ErrInvalidFolderMove
+		}
+		vaultID := folder.VaultID
+		if isVaultRoot(folder) {
+			vaultID = nil
+		}
+		if !sameVault(vaultID, destination.VaultID) {
+			return nil, ErrVaultBoundary
+		}
+		if !sameWorkspace(folder.WorkspaceID, destination.WorkspaceID) {
+			if folder.WorkspaceID != nil {
+				return nil, ErrWorkspaceBoundary
+			}
+			adoptingWorkspace = destination.WorkspaceID
+		}
+		if err := s.retention.CheckFolderMove(ctx, userID, folder, destination.ID); err != nil {
+			return nil, err
+		}
+
+		previousAudience = s.audience(ctx, folder.ID)
+		parentID := *req.ParentID
+		folder.ParentFolderID = &parentID
+	}
+
+	if !renamed && !moved {
+		return folder, nil
+	}
+	if err := s.locks.CheckFolder(ctx, userID, folder.ID); err != nil {
+		return nil, err
+	}
+	if moved {
+		if err := s.locks.CheckFolder(ctx, userID, *folder.ParentFolderID); err != nil {
+			return nil, err
+		}
+	}
+	if adoptingWorkspace != nil {
+		if err := s.adopt(ctx, userID, folder, *adoptingWorkspace); err != nil {
+			return nil, err
+		}
+	} else if err := s.folderRepo.Update(ctx, folder); err != nil {
+		s.logger.Error("Error updating folder", util.WithUserID(userID), zap.Uint("folder_id", folderID), util.WithError(err))
+		return nil, fmt.Errorf("error updating folder: %w", err)
+	}
+
+	if moved {
+		audience := mergeAudience(previousAudience, s.audience(ctx, folder.ID))
+		s.events.Publish(ctx, newEvent(model.EventFolderMoved, userID, folder, audience...))
+	} else {
+		s.events.Publish(ctx, newEvent(model.EventFolderUpdated, userID, folder, s.audience(ctx, folder.ID)...))
+	}
+	return folder, nil
+}
+
+// Delete moves a folder to the trash. Its contents become unreachable with
+// it and are restored along with it.
+func (s *folderService) Delete(ctx context.Context, userID, folderID uint) error {
+	folder, err := s.findFolder(ctx, folderID)
+	if err != nil {
+		return err
+	}
+	if err := s.requirePermission(ctx, userID, folder.ID, model.PermissionWrite); err != nil {
+		return err
+	}
+	if folder.ParentFolderID == nil {
+		return ErrRootFolder
+	}
+	if err := s.locks.CheckFolder(ctx, userID, folder.ID); err != nil {
+		return err
+	}
+	if err := s.retention.CheckFolderDelete(ctx, userID, folder); err != nil {
+		return err
+	}
+
+	audience := s.audience(ctx, folder.ID)
+	if err := s.folderRepo.Delete(ctx, folder); err != nil {
+		s.logger.Error("Error deleting folder", util.WithUserID(userID), zap.Uint("folder_id", folderID), util.WithError(err))
+		return fmt.Errorf("error deleting folder: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditFolderDelete,
+		ActorID:    auditRef(userID),
+		TargetType: model.AuditTargetFolder,
+		TargetID:   auditRef(folder.ID),
+		Metadata:   model.JSONMap{"folder_name": folder.FolderName, "owner_id": folder.UserID},
+	})
+	s.events.Publish(ctx, newEvent(model.EventFolderDeleted, userID, folder, audience...))
+	s.logger.Info("Folder deleted", util.WithUserID(userID), zap.Uint("folder_id", folderID))
+	return nil
+}
+
+// Restore takes a folder out of the trash
+func (s *folderService) Restore(ctx context.Context, userID, folderID uint) (*model.Folder, error) {
+	folder, err := s.folderRepo.FindDeleted(ctx, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding folder: %w", err)
+	}
+	if folder == nil || folder.ParentFolderID == nil {
+		return nil, ErrFolderNotFound
+	}
+	if err := s.requirePermission(ctx, userID, *folder.ParentFolderID, model.PermissionWrite); err != nil {
+		return nil, err
+	}
+
+	if err := s.folderRepo.Restore(ctx, folder); err != nil {
+		s.logger.Error("Error restoring folder", util.WithUserID(userID), zap.Uint("folder_id", folderID), util.WithError(err))
+		return nil, fmt.Errorf("error restoring folder: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditFolderRestore,
+		ActorID:    auditRef(userID),
+		TargetType: model.AuditTargetFolder,
+		TargetID:   auditRef(folder.ID),
+		Metadata:   model.JSONMap{"folder_name": folder.FolderName, "owner_id": folder.UserID},
+	})
+	s.events.Publish(ctx, newEvent(model.EventFolderRestored, userID, folder, s.audience(ctx, folder.ID)...))
+	s.logger.Info("Folder restored", util.WithUserID(userID), zap.Uint("folder_id", folderID))
+	return folder, nil
+}
+
+// Copy duplicates a folder and everything beneath it. Quarantined files are
+// left out. A failure part way leaves what was already copied in place.
+// Vault roots cannot be copied, as the copy would have no key, and copies
+// cannot cross a vault boundary.
+func (s *folderService) Copy(ctx context.Context, userID, folderID, parentID uint) (*model.Folder, error) {
+	source, err := s.findFolder(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requirePermission(ctx, userID, source.ID, model.PermissionRead); err != nil {
+		return nil, err
+	}
+	if source.ParentFolderID == nil {
+		return nil, ErrRootFolder
+	}
+
+	parent, err := resolveFolder(ctx, s.folderRepo, userID, parentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requirePermission(ctx, userID, parent.ID, model.PermissionWrite); err != nil {
+		return nil, err
+	}
+	cycle, err := s.folderRepo.IsDescendant(ctx, parent.ID, source.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error checking folder hierarchy: %w", err)
+	}
+	if cycle {
+		return nil, ErrInvalidFolderCopy
+	}
+	if isVaultRoot(source) || !sameVault(source.VaultID, parent.VaultID) {
+		return nil, ErrVaultBoundary
+	}
+
+	return s.copyTree(ctx, userID, source, parent)
+}
+
+// copyTree copies source into parent, recursing into its subfolders
+func (s *folderService) copyTree(ctx context.Context, userID uint, source, parent *model.Folder) (*model.Folder, error) {
+	folder := &model.Folder{
+		FolderName:     source.FolderName,
+		ParentFolderID: &parent.ID,
+		UserID:         userID,
+		VaultID:        parent.VaultID,
+		WorkspaceID:    parent.WorkspaceID,
+	}
+	if err := s.folderRepo.Create(ctx, folder); err != nil {
+		s.logger.Error("Error creating folder copy", util.WithUserID(userID), zap.Uint("folder_id", source.ID), util.WithError(err))
+		return nil, fmt.Errorf("error creating folder: %w", err)
+	}
+	s.events.Publish(ctx, newEvent(model.EventFolderCreated, userID, folder, s.audience(ctx, folder.ID)...))
+
+	files, err := s.fileRepo.ListByFolder(ctx, source.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing files: %w", err)
+	}
+	for _, file := range files {
+		if file.Quarantined() {
+			continue
+		}
+		if _, err := s.files.Copy(ctx, userID, file.ID, folder.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	children, err := s.folderRepo.ListChildren(ctx, source.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing subfolders: %w", err)
+	}
+	for i := range children {
+		if _, err := s.copyTree(ctx, userID, &children[i], folder); err != nil {
+			return nil, err
+		}
+	}
+	return folder, nil
+}
+
+// findFolder loads a folder by ID
+func (s *folderService) findFolder(ctx context.Context, folderID uint) (*model.Folder, error) {
+	folder, err := s.folderRepo.FindByID(ctx, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding folder: %w", err)
+	}
+	if folder == nil {
+		return nil, ErrFolderNotFound
+	}
+	return folder, nil
+}
+
+// adopt saves a personal folder moved into a workspace, which takes over
+// everything beneath it and its storage charge
+func (s *folderService) adopt(ctx context.Context, userID uint, folder *model.Folder, workspaceID uint) error {
+	ok, err := s.workspaceRepo.AdoptFolder(ctx, folder, workspaceID)
+	if err != nil {
+		s.logger.Error("Error moving folder into workspace", util.WithUserID(userID), zap.Uint("folder_id", folder.ID), util.WithError(err))
+		return fmt.Errorf("error moving folder into workspace: %w", err)
+	}
+	if !ok {
+		return ErrQuotaExceeded
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditWorkspaceTransfer,
+		ActorID:    auditRef(userID),
+		TargetType: model.AuditTargetFolder,
+		TargetID:   auditRef(folder.ID),
+		Metadata:   model.JSONMap{"workspace_id": workspaceID, "owner_id": folder.UserID},
+	})
+	return nil
+}
+
+// requirePermission fails unless the user holds the required permission
+func (s *folderService) requirePermission(ctx context.Context, userID, folderID uint, required model.Permission) error {
+	permission, err := s.permissionRepo.FolderPermission(ctx, userID, folderID)
+	if err != nil {
+		return fmt.Errorf("error resolving folder permission: %w", err)
+	}
+	if permission == "" {
+		return ErrFolderNotFound
+	}
+	if !permission.Allows(required) {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// audience returns the users an event about a folder concerns
+func (s *folderService) audience(ctx context.Context, folderID uint) []uint {
+	audience, err := s.permissionRepo.FolderAudience(ctx, folderID)
+	if err != nil {
+		s.logger.Error("Error resolving folder audience", zap.Uint("folder_id", folderID), util.WithError(err))
+	}
+	return audience
+}
+
+// resolveFolder loads the target folder, falling back to the user's root
+// folder (created on first use) when folderID is zero
+func resolveFolder(ctx context.Context, folderRepo repository.FolderRepository, userID, folderID uint) (*model.Folder, error) {
+	if folderID != 0 {
+		folder, err := folderRepo.FindByID(ctx, folderID)
+		if err != nil {
+			return nil, fmt.Errorf("error finding folder: %w", err)
+		}
+		if folder == nil {
+			return nil, ErrFolderNotFound
+		}
+		return folder, nil
+	}
+
+	folder, err := folderRepo.FindRoot(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding root folder: %w", err)
+	}
+	if folder != nil {
+		return folder, nil
+	}
+
+	folder = &model.Folder{FolderName: "/", UserID: userID}
+	if err := folderRepo.Create(ctx, folder); err != nil {
+		return nil, fmt.Errorf("error creating root folder: %w", err)
+	}
+	return folder, nil
+}
+
+// cleanFolderName trims a folder name and rejects names that cannot be
+// used as a path segment
+func cleanFolderName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, "/\\\x00") {
+		return "", ErrInvalidFolderName
+	}
+	return name, nil
+}
+
+// mergeAudience combines two audiences without duplicates
+func mergeAudience(a, b []uint) []uint {
+	seen := make(map[uint]bool, len(a)+len(b))
+	merged := make([]uint, 0, len(a)+len(b))
+	for _, id := range append(a, b...) {
+		if !seen[id] {
+			seen[id] = true
+			merged = append(merged, id)
+		}
+	}
+	return merged
+}
@@ -0,0 +1,372 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrGroupNotFound       = errors.New("group not found")
+	ErrGroupMemberNotFound = errors.New("group member not found")
+	ErrAlreadyGroupMember  = errors.New("user is already a member of this group")
+	ErrInvalidGroupName    = errors.New("invalid group name")
+)
+
+// GroupService manages groups of users that items can be shared with at
+// once. Membership is resolved whenever access is checked, so joining a
+// group grants access to what is shared with it immediately and leaving
+// revokes it.
+type GroupService interface {
+	// Create makes a group managed by the user or, when requested by an
+	// administrator, an admin-managed group
+	Create(ctx context.Context, user *model.User, req *model.CreateGroupRequest) (*model.Group, error)
+	// List returns the groups the user owns or belongs to and every
+	// admin-managed group
+	List(ctx context.Context, userID uint) ([]model.Group, error)
+	// Get returns a group the user can see
+	Get(ctx context.Context, userID, groupID uint) (*model.Group, error)
+	// Update changes a group the user manages
+	Update(ctx context.Context, user *model.User, groupID uint, req *model.UpdateGroupRequest) (*model.Group, error)
+	// Delete deletes a group the user manages, revoking every share made
+	// with it
+	Delete(ctx context.Context, user *model.User, groupID uint) error
+	// ListMembers returns the members of a group the user can see
+	ListMembers(ctx context.Context, userID, groupID uint) ([]model.GroupMember, error)
+	// AddMember adds a user to a group the user manages
+	AddMember(ctx context.Context, user *model.User, groupID uint, req *model.AddGroupMemberRequest) (*model.GroupMember, error)
+	// RemoveMember takes a user out of a group the user manages. Members
+	// can also leave groups that are not admin-managed.
+	RemoveMember(ctx context.Context, user *model.User, groupID, memberID uint) error
+}
+
+type groupService struct {
+	groupRepo repository.GroupRepository
+	userRepo  repository.UserRepository
+	shareRepo repository.ShareRepository
+	audit     AuditService
+	events    EventPublisher
+	logger    *util.Logger
+}
+
+// NewGroupService creates a new GroupService instance
+func NewGroupService(
+	groupRepo repository.GroupRepository,
+	userRepo repository.UserRepository,
+	shareRepo repository.ShareRepository,
+	audit AuditService,
+	events EventPublisher,
+	logger *util.Logger,
+) GroupService {
+	return &groupService{
+		groupRepo: groupRepo,
+		userRepo:  userRepo,
+		shareRepo: shareRepo,
+		audit:     audit,
+		events:    events,
+		logger:    logger,
+	}
+}
+
+// Create makes a group owned by the user
+func (s *groupService) Create(ctx context.Context, user *model.User, req *model.CreateGroupRequest) (*model.Group, error) {
+	if req.AdminManaged && !user.IsAdmin {
+		return nil, ErrPermissionDenied
+	}
+	name, err := cleanGroupName(req.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	group := &model.Group{
+		Name:         name,
+		Description:  req.Description,
+		OwnerID:      user.ID,
+		AdminManaged: req.AdminManaged,
+	}
+	if err := s.groupRepo.Create(ctx, group); err != nil {
+		s.logger.Error("Error creating group", util.WithUserID(user.ID), util.WithError(err))
+		return nil, fmt.Errorf("error creating group: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditGroupCreate,
+		ActorID:    auditRef(user.ID),
+		TargetType: model.AuditTargetGroup,
+		TargetID:   auditRef(group.ID),
+		Metadata:   model.JSONMap{"name": group.Name, "admin_managed": group.AdminManaged},
+	})
+	s.logger.Info("Group created", util.WithUserID(user.ID), zap.Uint("group_id", group.ID))
+	return group, nil
+}
+
+// List returns the groups the user can see
+func (s *groupService) List(ctx context.Context, userID uint) ([]model.Group, error) {
+	groups, err := s.groupRepo.ListVisible(ctx, userID)
+	if err != nil {
+		s.logger.Error("Error listing groups", util.WithUserID(userID), util.WithError(err))
+		return nil, fmt.Errorf("error listing groups: %w", err)
+	}
+	return groups, nil
+}
+
+// Get returns a group the user can see
+func (s *groupService) Get(ctx context.Context, userID, groupID uint) (*model.Group, error) {
+	return findVisibleGroup(ctx, s.groupRepo, userID, groupID)
+}
+
+// Update changes a group the user manages
+func (s *groupService) Update(ctx context.Context, user *model.User, groupID uint, req *model.UpdateGroupRequest) (*model.Group, error) {
+	group, err := s.findManaged(ctx, user, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := model.JSONMap{}
+	if req.Name != nil {
+		name, err := cleanGroupName(*req.Name)
+		if err != nil {
+			return nil, err
+		}
+		if name != group.Name {
+			changes["name"] = name
+			group.Name = name
+		}
+	}
+	if req.Description != nil && *req.Description != group.Description {
+		changes["description"] = *req.Description
+		group.Description = *req.Description
+	}
+	if len(changes) == 0 {
+		return group, nil
+	}
+
+	if err := s.groupRepo.Update(ctx, group); err != nil {
+		s.logger.Error("Error updating group", util.WithUserID(user.ID), zap.Uint("group_id", groupID), util.WithError(err))
+		return nil, fmt.Errorf("error updating group: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditGroupUpdate,
+		ActorID:    auditRef(user.ID),
+		TargetType: model.AuditTargetGroup,
+		TargetID:   auditRef(group.ID),
+		Metadata:   changes,
+	})
+	return group, nil
+}
+
+// Delete deletes a group along with the shares made with it
+func (s *groupService) Delete(ctx context.Context, user *model.User, groupID uint) error {
+	group, err := s.findManaged(ctx, user, groupID)
+	if err != nil {
+		return err
+	}
+
+	// Gather who loses access before the memberships are gone
+	shares, err := s.shareRepo.ListByGroup(ctx, group.ID)
+	if err != nil {
+		return fmt.Errorf("error listing group shares: %w", err)
+	}
+	members, err := s.groupRepo.ListMembers(ctx, group.ID)
+	if err != nil {
+		return fmt.Errorf("error listing group members: %w", err)
+	}
+
+	if err := s.groupRepo.Delete(ctx, group); err != nil {
+		s.logger.Error("Error deleting group", util.WithUserID(user.ID), zap.Uint("group_id", groupID), util.WithError(err))
+		return fmt.Errorf("error deleting group: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditGroupDelete,
+		ActorID:    auditRef(user.ID),
+		TargetType: model.AuditTargetGroup,
+		TargetID:   auditRef(group.ID),
+		Metadata:   model.JSONMap{"name": group.Name, "members": len(members), "shares_revoked": len(shares)},
+	})
+	for i := range shares {
+		audience := []uint{shares[i].OwnerID}
+		for _, member := range members {
+			audience = append(audience, member.UserID)
+		}
+		s.events.Publish(ctx, newEvent(model.EventShareDeleted, user.ID, &shares[i], audience...))
+	}
+	s.logger.Info("Group deleted", util.WithUserID(user.ID), zap.Uint("group_id", group.ID))
+	return nil
+}
+
+// ListMembers returns the members of a group the user can see
+func (s *groupService) ListMembers(ctx context.Context, userID, groupID uint) ([]model.GroupMember, error) {
+	group, err := findVisibleGroup(ctx, s.groupRepo, userID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	members, err := s.groupRepo.ListMembers(ctx, group.ID)
+	if err != nil {
+		s.logger.Error("Error listing group members", zap.Uint("group_id", groupID), util.WithError(err))
+		return nil, fmt.Errorf("error listing group members: %w", err)
+	}
+	return members, nil
+}
+
+// AddMember adds a user to a group, giving them access to what is shared
+// with it
+func (s *groupService) AddMember(ctx context.Context, user *model.User, groupID uint, req *model.AddGroupMemberRequest) (*model.GroupMember, error) {
+	group, err := s.findManaged(ctx, user, groupID)
+	if err != nil {
+		return nil, err
+	}
+	added, err := s.findUser(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.groupRepo.FindMember(ctx, group.ID, added.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding group member: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrAlreadyGroupMember
+	}
+
+	member := &model.GroupMember{GroupID: group.ID, UserID: added.ID, AddedBy: user.ID}
+	if err := s.groupRepo.AddMember(ctx, member); err != nil {
+		s.logger.Error("Error adding group member", util.WithUserID(user.ID), zap.Uint("group_id", groupID), util.WithError(err))
+		return nil, fmt.Errorf("error adding group member: %w", err)
+	}
+	member.User = added
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditGroupMemberAdd,
+		ActorID:    auditRef(user.ID),
+		TargetType: model.AuditTargetGroup,
+		TargetID:   auditRef(group.ID),
+		Metadata:   model.JSONMap{"member_id": added.ID},
+	})
+	s.publishShares(ctx, user.ID, group.ID, added.ID, model.EventShareCreated)
+	return member, nil
+}
+
+// RemoveMember takes a user out of a group, revoking their access to what
+// is shared with it
+func (s *groupService) RemoveMember(ctx context.Context, user *model.User, groupID, memberID uint) error {
+	var group *model.Group
+	var err error
+	if memberID == user.ID {
+		group, err = findVisibleGroup(ctx, s.groupRepo, user.ID, groupID)
+		if err == nil && group.AdminManaged && !user.IsAdmin {
+			err = ErrPermissionDenied
+		}
+	} else {
+		group, err = s.findManaged(ctx, user, groupID)
+	}
+	if err != nil {
+		return err
+	}
+
+	member, err := s.groupRepo.FindMember(ctx, group.ID, memberID)
+	if err != nil {
+		return fmt.Errorf("error finding group member: %w", err)
+	}
+	if member == nil {
+		return ErrGroupMemberNotFound
+	}
+	if err := s.groupRepo.DeleteMember(ctx, member); err != nil {
+		s.logger.Error("Error removing group member", util.WithUserID(user.ID), zap.Uint("group_id", groupID), util.WithError(err))
+		return fmt.Errorf("error removing group member: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditGroupMemberRemove,
+		ActorID:    auditRef(user.ID),
+		TargetType: model.AuditTargetGroup,
+		TargetID:   auditRef(group.ID),
+		Metadata:   model.JSONMap{"member_id": memberID},
+	})
+	s.publishShares(ctx, user.ID, group.ID, memberID, model.EventShareDeleted)
+	return nil
+}
+
+// publishShares tells a user who joined or left a group about each share
+// made with it, which they gained or lost along with the membership
+func (s *groupService) publishShares(ctx context.Context, actorID, groupID, userID uint, eventType model.EventType) {
+	shares, err := s.shareRepo.ListByGroup(ctx, groupID)
+	if err != nil {
+		s.logger.Error("Error listing group shares", zap.Uint("group_id", groupID), util.WithError(err))
+		return
+	}
+	for i := range shares {
+		s.events.Publish(ctx, newEvent(eventType, actorID, &shares[i], userID))
+	}
+}
+
+// findManaged returns a group the user can see, failing with
+// ErrPermissionDenied unless they also manage it: administrators manage
+// admin-managed groups, owners the others
+func (s *groupService) findManaged(ctx context.Context, user *model.User, groupID uint) (*model.Group, error) {
+	group, err := findVisibleGroup(ctx, s.groupRepo, user.ID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group.AdminManaged && !user.IsAdmin || !group.AdminManaged && group.OwnerID != user.ID {
+		return nil, ErrPermissionDenied
+	}
+	return group, nil
+}
+
+// findUser resolves the user a membership request names
+func (s *groupService) findUser(ctx context.Context, req *model.AddGroupMemberRequest) (*model.User, error) {
+	var user *model.User
+	var err error
+	if req.UserID != 0 {
+		user, err = s.userRepo.FindByID(ctx, req.UserID)
+	} else {
+		user, err = s.userRepo.FindByEmail(ctx, req.Email)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error finding user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// findVisibleGroup loads a group the user owns or belongs to, or any
+// admin-managed group. Other groups are reported as not found.
+func findVisibleGroup(ctx context.Context, groupRepo repository.GroupRepository, userID, groupID uint) (*model.Group, error) {
+	group, err := groupRepo.FindByID(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding group: %w", err)
+	}
+	if group == nil {
+		return nil, ErrGroupNotFound
+	}
+	if group.AdminManaged || group.OwnerID == userID {
+		return group, nil
+	}
+	member, err := groupRepo.FindMember(ctx, group.ID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding group member: %w", err)
+	}
+	if member == nil {
+		return nil, ErrGroupNotFound
+	}
+	return group, nil
+}
+
+// cleanGroupName trims a group name and rejects blank ones
+func cleanGroupName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", ErrInvalidGroupName
+	}
+	return name, nil
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"drive/internal/encryption"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/storage"
+	"drive/internal/util"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	hashSweepInterval = time.Minute
+	hashSweepBatch    = 100
+)
+
+// HashService computes the content hash of files stored before hashing was
+// introduced. New content is hashed as it is uploaded.
+type HashService interface {
+	// Start launches the background backfill
+	Start(ctx context.Context)
+	// Stop waits for the backfill to finish its current file
+	Stop()
+}
+
+type hashService struct {
+	fileRepo repository.FileRepository
+	storage  storage.Storage
+	keys     *encryption.Keyring
+	logger   *util.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewHashService creates a new HashService instance
+func NewHashService(fileRepo repository.FileRepository, storage storage.Storage, keys *encryption.Keyring, logger *util.Logger) HashService {
+	return &hashService{
+		fileRepo: fileRepo,
+		storage:  storage,
+		keys:     keys,
+		logger:   logger,
+	}
+}
+
+// Start launches the background backfill
+func (s *hashService) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.sweep(ctx)
+	}()
+
+	s.logger.Info("Content hash backfill started")
+}
+
+// Stop waits for the backfill to finish its current file
+func (s *hashService) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+	s.logger.Info("Content hash backfill stopped")
+}
+
+// sweep hashes unhashed files batch by batch. Files that cannot be hashed,
+// such as those whose blob is missing, are retried on the next pass.
+func (s *hashService) sweep(ctx context.Context) {
+	ticker := time.NewTicker(hashSweepInterval)
+	defer ticker.Stop()
+
+	var afterID uint
+	for {
+		files, err := s.fileRepo.ListUnhashed(ctx, afterID, hashSweepBatch)
+		if err != nil && ctx.Err() == nil {
+			s.logger.Error("Error listing unhashed files", util.WithError(err))
+		}
+		for i := range files {
+			if ctx.Err() != nil {
+				return
+			}
+			file := &files[i]
+			afterID = file.ID
+
+			hash, err := s.hash(ctx, file)
+			if err != nil {
+				if ctx.Err() == nil {
+					s.logger.Warn("Error hashing file", zap.Uint("file_id", file.ID), util.WithError(err))
+				}
+				continue
+			}
+			file.ContentHash = hash
+			if _, err := s.fileRepo.UpdateContentHash(ctx, file); err != nil && ctx.Err() == nil {
+				s.logger.Error("Error saving content hash", zap.Uint("file_id", file.ID), util.WithError(err))
+			}
+		}
+
+		// Keep going while there are full batches, then start over later
+		if len(files) == hashSweepBatch {
+			continue
+		}
+		afterID = 0
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// hash computes the hex SHA-256 of a file's plaintext content
+func (s *hashService) hash(ctx context.Context, file *model.File) (string, error) {
+	blob, err := openBlob(ctx, s.storage, s.keys, file)
+	if err != nil {
+		return "", fmt.Errorf("error opening blob: %w", err)
+	}
+	defer blob.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, blob); err != nil {
+		return "", fmt.Errorf("error reading blob: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
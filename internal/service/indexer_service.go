@@ -0,0 +1,302 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"drive/internal/encryption"
+	"drive/internal/exif"
+	"drive/internal/extractor"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/storage"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// indexerRequeueBatch bounds the files queued by one Requeue
+const indexerRequeueBatch = 500
+
+// errTextLimitReached stops an extractor once enough text has been collected
+var errTextLimitReached = errors.New("text limit reached")
+
+// IndexerConfig holds the limits applied to content extraction
+type IndexerConfig struct {
+	Timeout     time.Duration
+	MaxFileSize int64
+	MaxTextSize int64
+	// StripPhotoLocation drops the GPS coordinates read from every photo.
+	// Those of users who strip their own photo locations are dropped when
+	// the metadata is saved.
+	StripPhotoLocation bool
+}
+
+// IndexerService extracts text from uploaded files in the background so
+// documents can be found by their content. It also reads the EXIF metadata
+// of photos. Files are indexed by JobIndexFile jobs.
+type IndexerService interface {
+	// Enqueue queues a job to index a file. Files whose job could not be
+	// queued stay pending until Requeue picks them up.
+	Enqueue(ctx context.Context, fileID uint)
+	// Index extracts and stores the text of a file, and the metadata of
+	// photos. It is the handler of JobIndexFile.
+	Index(ctx context.Context, fileID uint) error
+	// Requeue queues jobs for files still waiting to be indexed and returns
+	// how many it queued
+	Requeue(ctx context.Context) (int, error)
+}
+
+type indexerService struct {
+	fileRepo    repository.FileRepository
+	contentRepo repository.FileContentRepository
+	photoRepo   repository.PhotoRepository
+	storage     storage.Storage
+	keys        *encryption.Keyring
+	extractors  *extractor.Registry
+	jobs        JobService
+	config      IndexerConfig
+	logger      *util.Logger
+}
+
+// NewIndexerService creates a new IndexerService instance
+func NewIndexerService(
+	fileRepo repository.FileRepository,
+	contentRepo repository.FileContentRepository,
+	photoRepo repository.PhotoRepository,
+	storage storage.Storage,
+	keys *encryption.Keyring,
+	extractors *extractor.Registry,
+	jobs JobService,
+	config IndexerConfig,
+	logger *util.Logger,
+) IndexerService {
+	return &indexerService{
+		fileRepo:    fileRepo,
+		contentRepo: contentRepo,
+		photoRepo:   photoRepo,
+		storage:     storage,
+		keys:        keys,
+		extractors:  extractors,
+		jobs:        jobs,
+		config:      config,
+		logger:      logger,
+	}
+}
+
+// Enqueue queues a job to index a file
+func (s *indexerService) Enqueue(ctx context.Context, fileID uint) {
+	// Queue even if the request was cancelled after the file was stored
+	ctx = context.WithoutCancel(ctx)
+	opts := &JobOptions{UniqueKey: fmt.Sprintf("%s:%d", model.JobIndexFile, fileID)}
+	if _, err := s.jobs.Enqueue(ctx, model.JobIndexFile, model.FileJob{FileID: fileID}, opts); err != nil {
+		s.logger.Warn("Error queueing file for indexing, deferring it to the next requeue", zap.Uint("file_id", fileID), util.WithError(err))
+	}
+}
+
+// Requeue queues jobs for files still waiting to be indexed, covering files
+// whose job could not be queued and those stored before the job queue
+func (s *indexerService) Requeue(ctx context.Context) (int, error) {
+	ids, err := s.contentRepo.ListPendingFileIDs(ctx, indexerRequeueBatch)
+	if err != nil {
+		return 0, fmt.Errorf("error listing files pending indexing: %w", err)
+	}
+	for _, id := range ids {
+		s.Enqueue(ctx, id)
+	}
+	return len(ids), nil
+}
+
+// Index extracts and stores the text of a single file, and the metadata of
+// photos. Errors are returned only when indexing is worth another attempt;
+// files that cannot be read are recorded as failed.
+func (s *indexerService) Index(ctx context.Context, fileID uint) error {
+	logger := s.logger.With(zap.Uint("file_id", fileID))
+
+	file, err := s.fileRepo.FindByID(ctx, fileID)
+	if err != nil {
+		return fmt.Errorf("error loading file for indexing: %w", err)
+	}
+	if file == nil {
+		return nil
+	}
+
+	content := &model.FileContent{FileID: file.ID, IndexedAt: time.Now()}
+
+	ext, err := s.extractors.Find(file.MimeType, file.FileName)
+	switch {
+	case err != nil:
+		content.Status = model.IndexStatusSkipped
+		content.Error = err.Error()
+	case s.config.MaxFileSize > 0 && file.FileSize > s.config.MaxFileSize:
+		content.Status = model.IndexStatusSkipped
+		content.Error = fmt.Sprintf("file exceeds the %d byte indexing limit", s.config.MaxFileSize)
+	default:
+		content.Extractor = ext.Name()
+		text, truncated, err := s.extract(ctx, ext, file)
+		if err != nil {
+			if ctx.Err() != nil {
+				// Shutting down; the job runs again on the next start
+				return ctx.Err()
+			}
+			logger.Warn("Error extracting file content", zap.String("extractor", ext.Name()), util.WithError(err))
+			content.Status = model.IndexStatusFailed
+			content.Error = err.Error()
+		} else {
+			content.Status = model.IndexStatusIndexed
+			content.Content = text
+			content.Truncated = truncated
+		}
+	}
+
+	if file.FileType == model.FileTypeImage {
+		if err := s.indexPhoto(ctx, file); err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			logger.Warn("Error reading photo metadata", util.WithError(err))
+		}
+	} else if err := s.photoRepo.DeleteMetadata(ctx, file.ID); err != nil {
+		// The file may have been an image before its content was replaced
+		return fmt.Errorf("error removing photo metadata: %w", err)
+	}
+
+	if err := s.contentRepo.Save(ctx, content); err != nil {
+		return fmt.Errorf("error saving file content: %w", err)
+	}
+
+	logger.Debug("File indexed", zap.String("status", string(content.Status)))
+	return nil
+}
+
+// indexPhoto reads and stores the EXIF metadata of an image. Images whose
+// metadata cannot be read are stored without it, so they still appear in
+// the timeline by their upload time.
+func (s *indexerService) indexPhoto(ctx context.Context, file *model.File) error {
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+
+	blob, err := openBlob(ctx, s.storage, s.keys, file)
+	if err != nil {
+		return fmt.Errorf("error opening blob: %w", err)
+	}
+	defer blob.Close()
+
+	meta, decodeErr := exif.Decode(blob)
+	if decodeErr != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		meta = &exif.Metadata{}
+	}
+
+	metadata := &model.PhotoMetadata{
+		FileID:      file.ID,
+		TakenAt:     meta.TakenAt,
+		TimeOffset:  meta.TimeOffset,
+		CameraMake:  meta.CameraMake,
+		CameraModel: meta.CameraModel,
+		LensModel:   meta.LensModel,
+		Orientation: meta.Orientation,
+		Width:       meta.Width,
+		Height:      meta.Height,
+	}
+	if !s.config.StripPhotoLocation {
+		metadata.Latitude, metadata.Longitude = meta.Latitude, meta.Longitude
+	}
+	if err := s.photoRepo.SaveMetadata(ctx, metadata); err != nil {
+		return fmt.Errorf("error saving photo metadata: %w", err)
+	}
+	if decodeErr != nil && !errors.Is(decodeErr, exif.ErrUnsupported) {
+		return decodeErr
+	}
+	return nil
+}
+
+// extract runs an extractor with the configured time and size limits
+func (s *indexerService) extract(ctx context.Context, ext extractor.Extractor, file *model.File) (string, bool, error) {
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+
+	blob, err := openBlob(ctx, s.storage, s.keys, file)
+	if err != nil {
+		return "", false, fmt.Errorf("error opening blob: %w", err)
+	}
+
+	// Reads fail once ctx is done, so an extractor that ignores the deadline
+	// still stops at its next read; the goroutine owns the blob until then
+	var r io.Reader = &contextReader{ctx: ctx, r: blob}
+	if s.config.MaxFileSize > 0 {
+		r = io.LimitReader(r, s.config.MaxFileSize+1)
+	}
+	out := &limitedBuffer{limit: s.config.MaxTextSize}
+	done := make(chan error, 1)
+	go func() {
+		defer blob.Close()
+		done <- ext.Extract(ctx, r, out)
+	}()
+
+	// Extractors are expected to honour ctx, but never wait past the deadline
+	select {
+	case err = <-done:
+	case <-ctx.Done():
+		return "", false, fmt.Errorf("extraction timed out: %w", ctx.Err())
+	}
+	if err != nil && !errors.Is(err, errTextLimitReached) {
+		return "", false, err
+	}
+
+	return sanitizeText(out.String()), out.truncated, nil
+}
+
+// limitedBuffer collects text up to a fixed size
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int64
+	truncated bool
+}
+
+// Write implements io.Writer, failing with errTextLimitReached once full
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.limit <= 0 {
+		return b.Buffer.Write(p)
+	}
+	remaining := b.limit - int64(b.Len())
+	if int64(len(p)) > remaining {
+		b.Buffer.Write(p[:remaining])
+		b.truncated = true
+		return int(remaining), errTextLimitReached
+	}
+	return b.Buffer.Write(p)
+}
+
+// contextReader stops reading once the context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+// Read implements io.Reader
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.r.Read(p)
+}
+
+// sanitizeText makes extracted text safe to store in a Postgres text column
+func sanitizeText(text string) string {
+	// Truncation may split a multi-byte rune and Postgres rejects NUL bytes
+	text = strings.ToValidUTF8(text, "")
+	return strings.ReplaceAll(text, "\x00", "")
+}
@@ -0,0 +1,198 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// insightsListSize is how many folders and files the insights list
+	insightsListSize = 20
+
+	defaultDuplicatePerPage = 20
+	maxDuplicatePerPage     = 100
+)
+
+var (
+	ErrInvalidDuplicate   = errors.New("files to keep must be your own files outside the trash with duplicates")
+	ErrConflictingKeepers = errors.New("only one copy of each file can be kept")
+)
+
+// InsightsService shows users what their storage is used by and helps them
+// clean up duplicate files
+type InsightsService interface {
+	// Insights breaks a user's storage down by file type, folder and age and
+	// lists their largest files
+	Insights(ctx context.Context, userID uint) (*model.StorageInsights, error)
+	// Duplicates returns a page of the user's files that have identical
+	// content, grouped, with the groups wasting the most space first
+	Duplicates(ctx context.Context, userID uint, filter *model.DuplicateFilter) ([]model.DuplicateGroup, int64, error)
+	// ResolveDuplicates keeps the given files and moves the other copies of
+	// each to the trash. Copies that cannot be trashed, such as locked ones,
+	// are reported without failing the request.
+	ResolveDuplicates(ctx context.Context, userID uint, req *model.ResolveDuplicatesRequest) (*model.ResolveDuplicatesResult, error)
+}
+
+type insightsService struct {
+	insightsRepo repository.InsightsRepository
+	fileRepo     repository.FileRepository
+	userRepo     repository.UserRepository
+	files        FileService
+	logger       *util.Logger
+}
+
+// NewInsightsService creates a new InsightsService instance
+func NewInsightsService(
+	insightsRepo repository.InsightsRepository,
+	fileRepo repository.FileRepository,
+	userRepo repository.UserRepository,
+	files FileService,
+	logger *util.Logger,
+) InsightsService {
+	return &insightsService{
+		insightsRepo: insightsRepo,
+		fileRepo:     fileRepo,
+		userRepo:     userRepo,
+		files:        files,
+		logger:       logger,
+	}
+}
+
+// Insights breaks a user's storage down
+func (s *insightsService) Insights(ctx context.Context, userID uint) (*model.StorageInsights, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	insights := &model.StorageInsights{
+		StorageUsed:  user.StorageUsed,
+		StorageLimit: user.StorageLimit,
+	}
+	usage, err := s.insightsRepo.Usage(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error totalling storage usage: %w", err)
+	}
+	insights.StorageUsage = *usage
+	duplicates, err := s.insightsRepo.DuplicateUsage(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error totalling duplicates: %w", err)
+	}
+	insights.Duplicates = *duplicates
+	if insights.ByType, err = s.insightsRepo.UsageByType(ctx, userID); err != nil {
+		return nil, fmt.Errorf("error totalling usage by type: %w", err)
+	}
+	if insights.ByFolder, err = s.insightsRepo.UsageByFolder(ctx, userID, insightsListSize); err != nil {
+		return nil, fmt.Errorf("error totalling usage by folder: %w", err)
+	}
+	if insights.ByAge, err = s.insightsRepo.UsageByAge(ctx, userID); err != nil {
+		return nil, fmt.Errorf("error totalling usage by age: %w", err)
+	}
+	if insights.LargestFiles, err = s.insightsRepo.LargestFiles(ctx, userID, insightsListSize); err != nil {
+		return nil, fmt.Errorf("error listing largest files: %w", err)
+	}
+	return insights, nil
+}
+
+// Duplicates returns a page of the user's duplicate groups
+func (s *insightsService) Duplicates(ctx context.Context, userID uint, filter *model.DuplicateFilter) ([]model.DuplicateGroup, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 {
+		filter.PerPage = defaultDuplicatePerPage
+	}
+	if filter.PerPage > maxDuplicatePerPage {
+		filter.PerPage = maxDuplicatePerPage
+	}
+
+	groups, total, err := s.insightsRepo.ListDuplicates(ctx, userID, filter.Page, filter.PerPage)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing duplicates: %w", err)
+	}
+	return groups, total, nil
+}
+
+// ResolveDuplicates keeps the given files and trashes their other copies.
+// The files to keep are all checked before anything is trashed.
+func (s *insightsService) ResolveDuplicates(ctx context.Context, userID uint, req *model.ResolveDuplicatesRequest) (*model.ResolveDuplicatesResult, error) {
+	keep := make(map[uint]bool, len(req.KeepFileIDs))
+	hashes := make(map[string]bool, len(req.KeepFileIDs))
+	var copies []model.File
+	for _, id := range req.KeepFileIDs {
+		if keep[id] {
+			continue
+		}
+		keep[id] = true
+
+		file, err := s.fileRepo.FindByID(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("error finding file: %w", err)
+		}
+		if file == nil || file.UserID != userID || file.WorkspaceID != nil || file.ContentHash == "" || file.FileSize == 0 {
+			return nil, ErrInvalidDuplicate
+		}
+		if hashes[file.ContentHash] {
+			return nil, ErrConflictingKeepers
+		}
+		hashes[file.ContentHash] = true
+
+		trashed, err := s.insightsRepo.InTrash(ctx, file)
+		if err != nil {
+			return nil, fmt.Errorf("error checking file: %w", err)
+		}
+		if trashed {
+			return nil, ErrInvalidDuplicate
+		}
+		others, err := s.insightsRepo.ListCopies(ctx, file)
+		if err != nil {
+			return nil, fmt.Errorf("error listing copies: %w", err)
+		}
+		if len(others) == 0 {
+			return nil, ErrInvalidDuplicate
+		}
+		copies = append(copies, others...)
+	}
+
+	// Trash through the file service so locks are honoured and the deletions
+	// are audited and announced like any other
+	result := &model.ResolveDuplicatesResult{
+		TrashedFileIDs: []uint{},
+		Failed:         []model.DuplicateFailure{},
+	}
+	for _, file := range copies {
+		if err := s.files.Delete(ctx, userID, file.ID); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			result.Failed = append(result.Failed, model.DuplicateFailure{FileID: file.ID, Error: s.copyError(userID, file.ID, err)})
+			continue
+		}
+		result.TrashedFileIDs = append(result.TrashedFileIDs, file.ID)
+	}
+
+	s.logger.Info("Duplicates resolved", util.WithUserID(userID),
+		zap.Int("trashed", len(result.TrashedFileIDs)), zap.Int("failed", len(result.Failed)))
+	return result, nil
+}
+
+// copyError describes why a copy could not be trashed, hiding internal
+// errors as batches do
+func (s *insightsService) copyError(userID, fileID uint, err error) string {
+	for _, known := range batchItemErrors {
+		if errors.Is(err, known) {
+			return err.Error()
+		}
+	}
+	s.logger.Error("Error trashing duplicate", util.WithUserID(userID), zap.Uint("file_id", fileID), util.WithError(err))
+	return "internal error"
+}
@@ -0,0 +1,75 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/service"
+	"drive/internal/util"
+)
+
+// keptFile is the user's file 1, with copies 2, 3 and 4
+type keptFile struct {
+	repository.FileRepository
+}
+
+func (r keptFile) FindByID(ctx context.Context, id uint) (*model.File, error) {
+	return &model.File{ID: id, UserID: 1, ContentHash: "abc", FileSize: 10}, nil
+}
+
+type fileCopies struct {
+	repository.InsightsRepository
+}
+
+func (r fileCopies) InTrash(ctx context.Context, file *model.File) (bool, error) {
+	return false, nil
+}
+
+func (r fileCopies) ListCopies(ctx context.Context, file *model.File) ([]model.File, error) {
+	return []model.File{{ID: 2}, {ID: 3}, {ID: 4}}, nil
+}
+
+// failingDeletes trashes copy 2, finds copy 3 locked and fails on copy 4
+// with a database error
+type failingDeletes struct {
+	service.FileService
+}
+
+func (s failingDeletes) Delete(ctx context.Context, userID, fileID uint) error {
+	switch fileID {
+	case 3:
+		return service.ErrFileLocked
+	case 4:
+		return errors.New(`error deleting file: pq: relation "files" does not exist`)
+	}
+	return nil
+}
+
+func TestResolveDuplicatesHidesInternalErrors(t *testing.T) {
+	insights := service.NewInsightsService(fileCopies{}, keptFile{}, nil, failingDeletes{}, &util.Logger{Logger: zap.NewNop()})
+
+	result, err := insights.ResolveDuplicates(context.Background(), 1, &model.ResolveDuplicatesRequest{KeepFileIDs: []uint{1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.TrashedFileIDs) != 1 || result.TrashedFileIDs[0] != 2 {
+		t.Errorf("trashed %v, want [2]", result.TrashedFileIDs)
+	}
+	want := []model.DuplicateFailure{
+		{FileID: 3, Error: service.ErrFileLocked.Error()},
+		{FileID: 4, Error: "internal error"},
+	}
+	if len(result.Failed) != len(want) {
+		t.Fatalf("failed = %+v, want %+v", result.Failed, want)
+	}
+	for i := range want {
+		if result.Failed[i] != want[i] {
+			t.Errorf("failed[%d] = %+v, want %+v", i, result.Failed[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"drive/internal/encryption"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/storage"
+	"drive/internal/util"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// garbageBatchSize is how many listed blobs are looked up per query
+	garbageBatchSize = 500
+	scrubBatchSize   = 100
+
+	defaultDamagedPerPage = 20
+)
+
+// GarbageOptions controls a pass of the orphaned blob collector
+type GarbageOptions struct {
+	// GracePeriod is how old an unreferenced blob must be before it is
+	// removed. An upload stores its blob before saving its file, so younger
+	// blobs may still be about to be referenced.
+	GracePeriod time.Duration
+	// DryRun counts orphaned blobs without removing them
+	DryRun bool
+}
+
+// ScrubOptions controls a pass of the integrity scrubber
+type ScrubOptions struct {
+	// Interval skips files verified more recently than this; zero checks
+	// every file
+	Interval time.Duration
+	// MaxBytes stops the pass once this much content has been read; zero
+	// means no limit
+	MaxBytes int64
+}
+
+// IntegrityService finds stored blobs that no file references and checks
+// that stored content still matches the hash recorded when it was uploaded
+type IntegrityService interface {
+	// CollectGarbage removes the blobs, and cached renderings, that no file
+	// references, trashed files included
+	CollectGarbage(ctx context.Context, opts GarbageOptions) (*model.GarbageReport, error)
+	// Scrub re-hashes the content of files due for verification and records
+	// whether each is intact, corrupt or missing. Files whose hash has not
+	// been computed yet are left to the hash backfill.
+	Scrub(ctx context.Context, opts ScrubOptions) (*model.ScrubReport, error)
+	// Damaged returns a page of the files found corrupt or missing
+	Damaged(ctx context.Context, filter *model.IntegrityFilter) ([]model.File, int64, error)
+}
+
+type integrityService struct {
+	fileRepo        repository.FileRepository
+	maintenanceRepo repository.MaintenanceRepository
+	storage         storage.Storage
+	keys            *encryption.Keyring
+	logger          *util.Logger
+}
+
+// NewIntegrityService creates a new IntegrityService instance
+func NewIntegrityService(
+	fileRepo repository.FileRepository,
+	maintenanceRepo repository.MaintenanceRepository,
+	storage storage.Storage,
+	keys *encryption.Keyring,
+	logger *util.Logger,
+) IntegrityService {
+	return &integrityService{
+		fileRepo:        fileRepo,
+		maintenanceRepo: maintenanceRepo,
+		storage:         storage,
+		keys:            keys,
+		logger:          logger,
+	}
+}
+
+// CollectGarbage lists the storage backend and removes unreferenced blobs
+// older than the grace period, looking them up in batches
+func (s *integrityService) CollectGarbage(ctx context.Context, opts GarbageOptions) (*model.GarbageReport, error) {
+	report := &model.GarbageReport{}
+	cutoff := time.Now().Add(-opts.GracePeriod)
+
+	batch := make([]storage.BlobInfo, 0, garbageBatchSize)
+	flush := func() error {
+		orphans, err := s.orphans(ctx, batch)
+		batch = batch[:0]
+		if err != nil {
+			return err
+		}
+		for _, blob := range orphans {
+			report.Orphaned++
+			report.OrphanedBytes += blob.Size
+			if opts.DryRun {
+				continue
+			}
+			if err := s.storage.Delete(ctx, blob.Key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+				s.logger.Error("Error removing orphaned blob", zap.String("key", blob.Key), util.WithError(err))
+				continue
+			}
+			report.Removed++
+		}
+		return nil
+	}
+
+	err := s.storage.List(ctx, func(blob storage.BlobInfo) error {
+		report.Scanned++
+		if !blob.ModTime.Before(cutoff) {
+			return nil
+		}
+		batch = append(batch, blob)
+		if len(batch) < garbageBatchSize {
+			return nil
+		}
+		return flush()
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		return report, fmt.Errorf("error collecting orphaned blobs: %w", err)
+	}
+
+	if report.Orphaned > 0 {
+		s.logger.Info("Orphaned blobs collected",
+			zap.Int("orphaned", report.Orphaned),
+			zap.Int("removed", report.Removed),
+			zap.Int64("bytes", report.OrphanedBytes),
+			zap.Bool("dry_run", opts.DryRun))
+	}
+	return report, nil
+}
+
+// orphans returns the blobs no file references. A cached rendering is kept
+// while the blob it was made from is referenced and its version is current.
+func (s *integrityService) orphans(ctx context.Context, blobs []storage.BlobInfo) ([]storage.BlobInfo, error) {
+	if len(blobs) == 0 {
+		return nil, nil
+	}
+
+	sources := make([]string, len(blobs))
+	for i, blob := range blobs {
+		sources[i] = blob.Key
+		if strings.HasPrefix(blob.Key, previewRoot) {
+			sources[i], _ = previewSource(blob.Key)
+		}
+	}
+	referenced, err := s.maintenanceRepo.ReferencedBlobs(ctx, sources)
+	if err != nil {
+		return nil, fmt.Errorf("error finding referenced blobs: %w", err)
+	}
+
+	var orphans []storage.BlobInfo
+	for i, blob := range blobs {
+		if sources[i] == "" || !referenced[sources[i]] {
+			orphans = append(orphans, blob)
+		}
+	}
+	return orphans, nil
+}
+
+// Scrub walks the files due for verification in ID order
+func (s *integrityService) Scrub(ctx context.Context, opts ScrubOptions) (*model.ScrubReport, error) {
+	report := &model.ScrubReport{}
+	verifiedBefore := time.Now().Add(-opts.Interval)
+
+	var afterID uint
+	for {
+		files, err := s.fileRepo.ListUnverified(ctx, verifiedBefore, afterID, scrubBatchSize)
+		if err != nil {
+			return report, fmt.Errorf("error listing files to scrub: %w", err)
+		}
+		for i := range files {
+			if opts.MaxBytes > 0 && report.Bytes >= opts.MaxBytes {
+				return report, nil
+			}
+			file := &files[i]
+			afterID = file.ID
+
+			status, err := s.verify(ctx, file)
+			if err != nil {
+				if ctx.Err() != nil {
+					return report, ctx.Err()
+				}
+				report.Failed++
+				s.logger.Warn("Error scrubbing file", zap.Uint("file_id", file.ID), util.WithError(err))
+				continue
+			}
+
+			now := time.Now()
+			file.IntegrityStatus = status
+			file.VerifiedAt = &now
+			// A file replaced meanwhile is checked again on the next pass
+			saved, err := s.fileRepo.UpdateIntegrity(ctx, file)
+			if err != nil {
+				return report, fmt.Errorf("error saving integrity status: %w", err)
+			}
+			if !saved {
+				continue
+			}
+
+			report.Checked++
+			report.Bytes += file.FileSize
+			switch status {
+			case model.IntegrityCorrupt:
+				report.Corrupt++
+			case model.IntegrityMissing:
+				report.Missing++
+			}
+			if status != model.IntegrityOK {
+				s.logger.Error("File failed integrity check",
+					zap.Uint("file_id", file.ID),
+					zap.String("key", file.FileURL),
+					zap.String("status", string(status)))
+			}
+		}
+		if len(files) < scrubBatchSize {
+			return report, nil
+		}
+	}
+}
+
+// verify re-hashes a file's content. Errors are returned only when the
+// content could not be checked, not when it is damaged.
+func (s *integrityService) verify(ctx context.Context, file *model.File) (model.IntegrityStatus, error) {
+	blob, err := openBlob(ctx, s.storage, s.keys, file)
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return model.IntegrityMissing, nil
+	case errors.Is(err, encryption.ErrCorrupt), errors.Is(err, encryption.ErrUnwrapFailed):
+		return model.IntegrityCorrupt, nil
+	case err != nil:
+		return "", fmt.Errorf("error opening blob: %w", err)
+	}
+	defer blob.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, blob); err != nil {
+		if errors.Is(err, encryption.ErrCorrupt) {
+			return model.IntegrityCorrupt, nil
+		}
+		return "", fmt.Errorf("error reading blob: %w", err)
+	}
+	if hex.EncodeToString(hasher.Sum(nil)) != file.ContentHash {
+		return model.IntegrityCorrupt, nil
+	}
+	return model.IntegrityOK, nil
+}
+
+// Damaged returns a page of damaged files
+func (s *integrityService) Damaged(ctx context.Context, filter *model.IntegrityFilter) ([]model.File, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 {
+		filter.PerPage = defaultDamagedPerPage
+	}
+
+	files, total, err := s.fileRepo.ListDamaged(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing damaged files: %w", err)
+	}
+	return files, total, nil
+}
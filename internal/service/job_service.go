@@ -0,0 +1,469 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	jobBaseBackoff     = 10 * time.Second
+	jobMaxBackoff      = time.Hour
+	jobRecoverInterval = time.Minute
+	// jobRetention is how long succeeded jobs are kept for inspection
+	jobRetention = 7 * 24 * time.Hour
+
+	defaultJobPerPage = 20
+)
+
+var (
+	ErrJobNotFound     = errors.New("job not found")
+	ErrJobNotRetryable = errors.New("only dead jobs and jobs waiting to be retried can be retried")
+	ErrJobDuplicate    = errors.New("another job with the same unique key is queued or running")
+	// ErrJobPermanent marks a handler error as not worth retrying; the job
+	// goes straight to the dead letter state
+	ErrJobPermanent = errors.New("permanent job failure")
+)
+
+// errJobInterrupted is recorded on jobs stopped by a server shutdown
+var errJobInterrupted = errors.New("interrupted by a server shutdown")
+
+// JobHandlerFunc runs one attempt at a job. Jobs are delivered at least
+// once, so handlers must tolerate running again after a crash.
+type JobHandlerFunc func(ctx context.Context, job *model.Job) error
+
+// JobOptions adjusts how a job is queued
+type JobOptions struct {
+	// RunAt delays the job; zero runs it as soon as a worker is free
+	RunAt time.Time
+	// UniqueKey skips queueing the job while another job with the same key
+	// is queued or running
+	UniqueKey string
+	// MaxAttempts overrides the configured number of attempts
+	MaxAttempts int
+}
+
+// JobConfig holds the job queue settings
+type JobConfig struct {
+	Workers      int
+	PollInterval time.Duration
+	// Timeout bounds a single attempt
+	Timeout     time.Duration
+	MaxAttempts int
+}
+
+// JobService is a Postgres backed queue of background jobs. Workers on every
+// server instance claim due jobs with SKIP LOCKED, retry failures with
+// exponential backoff and move jobs that keep failing to a dead letter state
+// where administrators can inspect and retry them.
+type JobService interface {
+	// Register sets the handler for a job type. Handlers are registered
+	// before Start; jobs of types without a handler stay queued.
+	Register(jobType string, handler JobHandlerFunc)
+	// Enqueue queues a job with a JSON encoded payload. When a job with the
+	// same unique key is already queued or running, that job is returned
+	// instead.
+	Enqueue(ctx context.Context, jobType string, payload interface{}, opts *JobOptions) (*model.Job, error)
+	// List returns a page of jobs, newest first
+	List(ctx context.Context, filter *model.JobFilter) ([]model.Job, int64, error)
+	Get(ctx context.Context, jobID uint) (*model.Job, error)
+	// Stats counts jobs by status
+	Stats(ctx context.Context) (*model.JobStats, error)
+	// Retry runs a dead job, or one waiting to be retried, again now with a
+	// fresh set of attempts
+	Retry(ctx context.Context, jobID uint) (*model.Job, error)
+	// Start launches the workers
+	Start(ctx context.Context)
+	// Stop interrupts running jobs, requeues them and waits for the workers
+	// to exit
+	Stop()
+}
+
+// RegisterJob registers a handler that receives its job's payload decoded
+// into T. Payloads that cannot be decoded fail permanently.
+func RegisterJob[T any](jobs JobService, jobType string, handle func(ctx context.Context, payload T) error) {
+	jobs.Register(jobType, func(ctx context.Context, job *model.Job) error {
+		var payload T
+		if err := json.Unmarshal([]byte(job.Payload), &payload); err != nil {
+			return fmt.Errorf("%w: invalid payload: %v", ErrJobPermanent, err)
+		}
+		return handle(ctx, payload)
+	})
+}
+
+type jobService struct {
+	jobRepo repository.JobRepository
+	config  JobConfig
+	logger  *util.Logger
+
+	mu       sync.RWMutex
+	handlers map[string]JobHandlerFunc
+	wake     chan struct{}
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewJobService creates a new JobService instance
+func NewJobService(jobRepo repository.JobRepository, config JobConfig, logger *util.Logger) JobService {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	return &jobService{
+		jobRepo:  jobRepo,
+		config:   config,
+		logger:   logger,
+		handlers: make(map[string]JobHandlerFunc),
+		wake:     make(chan struct{}, 1),
+	}
+}
+
+// Register sets the handler for a job type
+func (s *jobService) Register(jobType string, handler JobHandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[jobType] = handler
+}
+
+// Enqueue queues a job
+func (s *jobService) Enqueue(ctx context.Context, jobType string, payload interface{}, opts *JobOptions) (*model.Job, error) {
+	if opts == nil {
+		opts = &JobOptions{}
+	}
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding job payload: %w", err)
+	}
+
+	job := &model.Job{
+		Type:        jobType,
+		Payload:     string(data),
+		Status:      model.JobQueued,
+		MaxAttempts: s.config.MaxAttempts,
+		RunAt:       opts.RunAt,
+	}
+	if opts.MaxAttempts > 0 {
+		job.MaxAttempts = opts.MaxAttempts
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = time.Now()
+	}
+	if opts.UniqueKey != "" {
+		job.UniqueKey = &opts.UniqueKey
+	}
+
+	// A conflicting job may finish between the insert and the lookup, in
+	// which case the insert is worth one more try
+	for i := 0; i < 2; i++ {
+		created, err := s.jobRepo.Enqueue(ctx, job)
+		if err != nil {
+			s.logger.Error("Error queueing job", zap.String("job_type", jobType), util.WithError(err))
+			return nil, fmt.Errorf("error queueing job: %w", err)
+		}
+		if created {
+			if !job.RunAt.After(time.Now()) {
+				s.notify()
+			}
+			return job, nil
+		}
+
+		existing, err := s.jobRepo.FindActiveByUniqueKey(ctx, opts.UniqueKey)
+		if err != nil {
+			return nil, fmt.Errorf("error finding job: %w", err)
+		}
+		if existing != nil {
+			return existing, nil
+		}
+	}
+	return nil, ErrJobDuplicate
+}
+
+// List returns a page of jobs
+func (s *jobService) List(ctx context.Context, filter *model.JobFilter) ([]model.Job, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 {
+		filter.PerPage = defaultJobPerPage
+	}
+
+	jobs, total, err := s.jobRepo.List(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing jobs: %w", err)
+	}
+	return jobs, total, nil
+}
+
+// Get returns a job
+func (s *jobService) Get(ctx context.Context, jobID uint) (*model.Job, error) {
+	job, err := s.jobRepo.FindByID(ctx, jobID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding job: %w", err)
+	}
+	if job == nil {
+		return nil, ErrJobNotFound
+	}
+	return job, nil
+}
+
+// Stats counts jobs by status
+func (s *jobService) Stats(ctx context.Context) (*model.JobStats, error) {
+	stats, err := s.jobRepo.Stats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error counting jobs: %w", err)
+	}
+	return stats, nil
+}
+
+// Retry requeues a job to run now
+func (s *jobService) Retry(ctx context.Context, jobID uint) (*model.Job, error) {
+	job, err := s.Get(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != model.JobDead && job.Status != model.JobQueued {
+		return nil, ErrJobNotRetryable
+	}
+
+	retried, err := s.jobRepo.Retry(ctx, jobID)
+	if err != nil {
+		s.logger.Error("Error retrying job", zap.Uint("job_id", jobID), util.WithError(err))
+		return nil, fmt.Errorf("error retrying job: %w", err)
+	}
+	if !retried {
+		// Either a worker claimed it in the meantime or another job holds
+		// its unique key
+		if current, err := s.Get(ctx, jobID); err == nil && current.Status != job.Status {
+			return nil, ErrJobNotRetryable
+		}
+		return nil, ErrJobDuplicate
+	}
+
+	s.logger.Info("Job retried", zap.Uint("job_id", jobID), zap.String("job_type", job.Type))
+	s.notify()
+	return s.Get(ctx, jobID)
+}
+
+// Start launches the workers and the sweep recovering abandoned jobs
+func (s *jobService) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	s.mu.RLock()
+	types := make([]string, 0, len(s.handlers))
+	for jobType := range s.handlers {
+		types = append(types, jobType)
+	}
+	s.mu.RUnlock()
+	sort.Strings(types)
+
+	if len(types) > 0 {
+		for i := 0; i < s.config.Workers; i++ {
+			s.wg.Add(1)
+			go func() {
+				defer s.wg.Done()
+				s.work(ctx, types)
+			}()
+		}
+	}
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.sweep(ctx)
+	}()
+
+	s.logger.Info("Job workers started", zap.Int("workers", s.config.Workers), zap.Strings("job_types", types))
+}
+
+// Stop interrupts running jobs and waits for the workers to exit
+func (s *jobService) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+	s.logger.Info("Job workers stopped")
+}
+
+// notify wakes a waiting worker without blocking
+func (s *jobService) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// work claims and runs due jobs one at a time until the context is
+// cancelled
+func (s *jobService) work(ctx context.Context, types []string) {
+	ticker := time.NewTicker(s.config.PollInterval)
+	defer ticker.Stop()
+
+	// The lease outlasts the attempt timeout so a running job is only
+	// presumed abandoned once its worker is gone
+	lease := s.config.Timeout + time.Minute
+
+	for {
+		jobs, err := s.jobRepo.Claim(ctx, types, 1, lease)
+		if err != nil && ctx.Err() == nil {
+			s.logger.Error("Error claiming jobs", util.WithError(err))
+		}
+		if len(jobs) > 0 {
+			s.run(ctx, &jobs[0])
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// run makes one attempt at a claimed job and records the outcome
+func (s *jobService) run(ctx context.Context, job *model.Job) {
+	logger := s.logger.With(zap.Uint("job_id", job.ID), zap.String("job_type", job.Type), zap.Int("attempt", job.Attempts))
+
+	s.mu.RLock()
+	handler := s.handlers[job.Type]
+	s.mu.RUnlock()
+
+	runCtx, cancel := context.WithTimeout(context.WithValue(ctx, runningJobKey{}, job), s.config.Timeout)
+	err := s.call(runCtx, handler, job)
+	cancel()
+
+	lockedUntil := *job.LockedUntil
+	now := time.Now()
+	job.LockedUntil = nil
+	job.LastError = ""
+	switch {
+	case err == nil:
+		job.Status = model.JobSucceeded
+		job.FinishedAt = &now
+	case ctx.Err() != nil:
+		// Shutting down; the attempt does not count and the job runs again
+		// on the next start
+		job.Status = model.JobQueued
+		job.Attempts--
+		job.RunAt = now
+		job.LastError = errJobInterrupted.Error()
+	case errors.Is(err, ErrJobPermanent) || job.Attempts >= job.MaxAttempts:
+		job.Status = model.JobDead
+		job.FinishedAt = &now
+		job.LastError = err.Error()
+		logger.Error("Job moved to the dead letter state", util.WithError(err))
+	default:
+		job.Status = model.JobQueued
+		job.RunAt = now.Add(jobBackoff(job.Attempts))
+		job.LastError = err.Error()
+		logger.Warn("Job failed, will retry", zap.Time("run_at", job.RunAt), util.WithError(err))
+	}
+
+	saved, saveErr := s.jobRepo.Finish(context.WithoutCancel(ctx), job, lockedUntil)
+	switch {
+	case saveErr != nil:
+		logger.Error("Error saving job", util.WithError(saveErr))
+	case !saved:
+		logger.Warn("Job lease expired before it finished")
+	}
+}
+
+// call runs a handler, turning panics into errors so one bad job cannot take
+// the worker down
+func (s *jobService) call(ctx context.Context, handler JobHandlerFunc, job *model.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: handler panicked: %v", ErrJobPermanent, r)
+		}
+	}()
+	if handler == nil {
+		return fmt.Errorf("%w: no handler for job type %q", ErrJobPermanent, job.Type)
+	}
+	return handler(ctx, job)
+}
+
+// sweep periodically requeues jobs abandoned by workers that died and
+// removes old succeeded jobs
+func (s *jobService) sweep(ctx context.Context) {
+	ticker := time.NewTicker(jobRecoverInterval)
+	defer ticker.Stop()
+
+	for {
+		if n, err := s.jobRepo.RecoverExpired(ctx); err != nil && ctx.Err() == nil {
+			s.logger.Error("Error recovering abandoned jobs", util.WithError(err))
+		} else if n > 0 {
+			s.logger.Warn("Recovered abandoned jobs", zap.Int64("jobs", n))
+			s.notify()
+		}
+		if _, err := s.jobRepo.DeleteSucceededBefore(ctx, time.Now().Add(-jobRetention)); err != nil && ctx.Err() == nil {
+			s.logger.Error("Error removing succeeded jobs", util.WithError(err))
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// requeueTask queues jobs for the files still waiting to be indexed or
+// scanned and the webhook deliveries still due, catching work whose job
+// could not be queued or gave up while a dependency was down
+func requeueTask(indexer IndexerService, scans ScanService, webhooks WebhookService) TaskFunc {
+	return func(ctx context.Context) (string, error) {
+		indexed, err := indexer.Requeue(ctx)
+		if err != nil {
+			return "", err
+		}
+		scanned, err := scans.Requeue(ctx)
+		if err != nil {
+			return "", err
+		}
+		delivered, err := webhooks.Requeue(ctx)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("queued %d files for indexing, %d for scanning and %d webhook deliveries", indexed, scanned, delivered), nil
+	}
+}
+
+type runningJobKey struct{}
+
+// runningJob returns the job a handler was called for, or nil outside a job
+func runningJob(ctx context.Context) *model.Job {
+	job, _ := ctx.Value(runningJobKey{}).(*model.Job)
+	return job
+}
+
+// lastAttempt reports whether a failure of the running job would be its
+// last. Work done outside a job gets a single attempt.
+func lastAttempt(ctx context.Context) bool {
+	job := runningJob(ctx)
+	return job == nil || job.Attempts >= job.MaxAttempts
+}
+
+// jobBackoff returns the delay before the next attempt, doubling from
+// jobBaseBackoff up to jobMaxBackoff
+func jobBackoff(attempts int) time.Duration {
+	delay := jobBaseBackoff
+	for i := 1; i < attempts && delay < jobMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > jobMaxBackoff {
+		delay = jobMaxBackoff
+	}
+	return delay
+}
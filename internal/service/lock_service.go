@@ -0,0 +1,277 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultLockDuration is how long a lock lasts when no duration is given
+	DefaultLockDuration = time.Hour
+	// MaxLockDuration caps lock durations, including infinite WebDAV locks
+	MaxLockDuration = 7 * 24 * time.Hour
+)
+
+var (
+	ErrFileLocked   = errors.New("file is locked by another user")
+	ErrLockNotFound = errors.New("lock not found")
+)
+
+// LockService manages file check-outs. The file and folder services consult
+// it before changing files, and WebDAV LOCK requests on files go through it.
+type LockService interface {
+	// Lock checks out a file the user can write to, replacing the user's
+	// previous lock on it
+	Lock(ctx context.Context, userID, fileID uint, req *model.LockFileRequest) (*model.FileLock, error)
+	// List returns the active locks on a file the user can read
+	List(ctx context.Context, userID, fileID uint) ([]model.FileLock, error)
+	// Unlock releases the user's lock on a file
+	Unlock(ctx context.Context, userID, fileID uint) error
+	// ListAll returns all active locks, optionally limited to a file and/or
+	// holder, for administrators
+	ListAll(ctx context.Context, fileID, userID uint) ([]model.FileLock, error)
+	// ForceUnlock releases any lock on behalf of an administrator
+	ForceUnlock(ctx context.Context, adminID, lockID uint) error
+	// CheckFile returns ErrFileLocked if the file's locks keep the user from
+	// changing it
+	CheckFile(ctx context.Context, userID, fileID uint) error
+	// CheckFolder returns ErrFileLocked if any file in the folder or beneath
+	// it is locked by others
+	CheckFolder(ctx context.Context, userID, folderID uint) error
+	// LockByToken returns the active lock with a WebDAV token, or nil
+	LockByToken(ctx context.Context, token string) (*model.FileLock, error)
+	// Refresh extends the user's lock with a WebDAV token
+	Refresh(ctx context.Context, userID uint, token string, duration time.Duration) (*model.FileLock, error)
+	// Release removes the user's lock with a WebDAV token
+	Release(ctx context.Context, userID uint, token string) error
+}
+
+type lockService struct {
+	lockRepo       repository.LockRepository
+	permissionRepo repository.PermissionRepository
+	audit          AuditService
+	logger         *util.Logger
+}
+
+// NewLockService creates a new LockService instance
+func NewLockService(
+	lockRepo repository.LockRepository,
+	permissionRepo repository.PermissionRepository,
+	audit AuditService,
+	logger *util.Logger,
+) LockService {
+	return &lockService{
+		lockRepo:       lockRepo,
+		permissionRepo: permissionRepo,
+		audit:          audit,
+		logger:         logger,
+	}
+}
+
+// Lock checks out a file the user can write to
+func (s *lockService) Lock(ctx context.Context, userID, fileID uint, req *model.LockFileRequest) (*model.FileLock, error) {
+	if err := s.requirePermission(ctx, userID, fileID, model.PermissionWrite); err != nil {
+		return nil, err
+	}
+
+	scope := req.Scope
+	if scope == "" {
+		scope = model.LockExclusive
+	}
+	lock := &model.FileLock{
+		FileID:    fileID,
+		UserID:    userID,
+		Scope:     scope,
+		Token:     "opaquelocktoken:" + uuid.NewString(),
+		Note:      req.Note,
+		ExpiresAt: time.Now().Add(lockDuration(time.Duration(req.DurationSeconds) * time.Second)),
+	}
+	acquired, err := s.lockRepo.Acquire(ctx, lock)
+	if err != nil {
+		s.logger.Error("Error acquiring lock", util.WithUserID(userID), zap.Uint("file_id", fileID), util.WithError(err))
+		return nil, fmt.Errorf("error acquiring lock: %w", err)
+	}
+	if !acquired {
+		return nil, ErrFileLocked
+	}
+
+	s.logger.Info("File locked", util.WithUserID(userID), zap.Uint("file_id", fileID), zap.String("scope", string(scope)))
+	return lock, nil
+}
+
+// List returns the active locks on a file the user can read
+func (s *lockService) List(ctx context.Context, userID, fileID uint) ([]model.FileLock, error) {
+	if err := s.requirePermission(ctx, userID, fileID, model.PermissionRead); err != nil {
+		return nil, err
+	}
+	locks, err := s.lockRepo.ListActive(ctx, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing locks: %w", err)
+	}
+	return locks, nil
+}
+
+// Unlock releases the user's lock on a file
+func (s *lockService) Unlock(ctx context.Context, userID, fileID uint) error {
+	if err := s.requirePermission(ctx, userID, fileID, model.PermissionRead); err != nil {
+		return err
+	}
+	locks, err := s.lockRepo.ListActive(ctx, fileID)
+	if err != nil {
+		return fmt.Errorf("error listing locks: %w", err)
+	}
+	for i := range locks {
+		if locks[i].UserID == userID {
+			return s.delete(ctx, &locks[i])
+		}
+	}
+	return ErrLockNotFound
+}
+
+// ListAll returns all active locks for administrators
+func (s *lockService) ListAll(ctx context.Context, fileID, userID uint) ([]model.FileLock, error) {
+	locks, err := s.lockRepo.ListAllActive(ctx, fileID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing locks: %w", err)
+	}
+	return locks, nil
+}
+
+// ForceUnlock releases any lock on behalf of an administrator
+func (s *lockService) ForceUnlock(ctx context.Context, adminID, lockID uint) error {
+	lock, err := s.lockRepo.FindByID(ctx, lockID)
+	if err != nil {
+		return fmt.Errorf("error finding lock: %w", err)
+	}
+	if lock == nil || !lock.Active(time.Now()) {
+		return ErrLockNotFound
+	}
+	if err := s.delete(ctx, lock); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditFileForceUnlock,
+		ActorID:    auditRef(adminID),
+		TargetType: model.AuditTargetFile,
+		TargetID:   auditRef(lock.FileID),
+		Metadata:   model.JSONMap{"lock_id": lock.ID, "holder_id": lock.UserID, "scope": lock.Scope},
+	})
+	s.logger.Info("Lock forcibly released", util.WithUserID(adminID), zap.Uint("lock_id", lock.ID), zap.Uint("file_id", lock.FileID))
+	return nil
+}
+
+// CheckFile returns ErrFileLocked if the file's locks keep the user from
+// changing it
+func (s *lockService) CheckFile(ctx context.Context, userID, fileID uint) error {
+	locks, err := s.lockRepo.ListActive(ctx, fileID)
+	if err != nil {
+		return fmt.Errorf("error listing locks: %w", err)
+	}
+	if model.LocksBlock(locks, userID) {
+		return ErrFileLocked
+	}
+	return nil
+}
+
+// CheckFolder returns ErrFileLocked if any file in the folder or beneath it
+// is locked by others
+func (s *lockService) CheckFolder(ctx context.Context, userID, folderID uint) error {
+	blocked, err := s.lockRepo.BlockedUnderFolder(ctx, folderID, userID)
+	if err != nil {
+		return fmt.Errorf("error checking folder locks: %w", err)
+	}
+	if blocked {
+		return ErrFileLocked
+	}
+	return nil
+}
+
+// LockByToken returns the active lock with a WebDAV token, or nil
+func (s *lockService) LockByToken(ctx context.Context, token string) (*model.FileLock, error) {
+	lock, err := s.lockRepo.FindByToken(ctx, token)
+	if err != nil {
+		return nil, fmt.Errorf("error finding lock: %w", err)
+	}
+	if lock == nil || !lock.Active(time.Now()) {
+		return nil, nil
+	}
+	return lock, nil
+}
+
+// Refresh extends the user's lock with a WebDAV token
+func (s *lockService) Refresh(ctx context.Context, userID uint, token string, duration time.Duration) (*model.FileLock, error) {
+	lock, err := s.LockByToken(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+	if lock == nil || lock.UserID != userID {
+		return nil, ErrLockNotFound
+	}
+
+	lock.ExpiresAt = time.Now().Add(lockDuration(duration))
+	if err := s.lockRepo.Refresh(ctx, lock); err != nil {
+		return nil, fmt.Errorf("error refreshing lock: %w", err)
+	}
+	return lock, nil
+}
+
+// Release removes the user's lock with a WebDAV token
+func (s *lockService) Release(ctx context.Context, userID uint, token string) error {
+	lock, err := s.LockByToken(ctx, token)
+	if err != nil {
+		return err
+	}
+	if lock == nil {
+		return ErrLockNotFound
+	}
+	if lock.UserID != userID {
+		return ErrPermissionDenied
+	}
+	return s.delete(ctx, lock)
+}
+
+func (s *lockService) delete(ctx context.Context, lock *model.FileLock) error {
+	if err := s.lockRepo.Delete(ctx, lock); err != nil {
+		s.logger.Error("Error releasing lock", zap.Uint("lock_id", lock.ID), util.WithError(err))
+		return fmt.Errorf("error releasing lock: %w", err)
+	}
+	return nil
+}
+
+// requirePermission checks the user's access to a file, hiding files the
+// user cannot see
+func (s *lockService) requirePermission(ctx context.Context, userID, fileID uint, required model.Permission) error {
+	permission, err := s.permissionRepo.FilePermission(ctx, userID, fileID)
+	if err != nil {
+		return fmt.Errorf("error resolving file permission: %w", err)
+	}
+	if permission == "" {
+		return ErrFileNotFound
+	}
+	if !permission.Allows(required) {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// lockDuration applies the default and the cap to a requested duration.
+// Zero means the default and negative means infinite.
+func lockDuration(d time.Duration) time.Duration {
+	switch {
+	case d == 0:
+		return DefaultLockDuration
+	case d < 0, d > MaxLockDuration:
+		return MaxLockDuration
+	default:
+		return d
+	}
+}
@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/storage"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// purgeBatchSize is how many files are purged per transaction
+const purgeBatchSize = 100
+
+// MaintenanceConfig holds the maintenance task settings
+type MaintenanceConfig struct {
+	// TrashRetention is how long items stay in the trash before they are
+	// deleted permanently
+	TrashRetention time.Duration
+	// StaleUploadAge is how long an unfinished upload is left alone before
+	// its partial content is removed
+	StaleUploadAge time.Duration
+}
+
+// MaintenanceService holds the housekeeping tasks run by the scheduler. Each
+// returns a short summary of what it did for the run history.
+type MaintenanceService interface {
+	// PurgeTrash permanently deletes files and folders that have been in the
+	// trash longer than the retention period, along with their blobs
+	PurgeTrash(ctx context.Context) (string, error)
+	// Purge permanently deletes the files of fileIDs and the folders of
+	// folderIDs, with everything beneath them, that are in the trash, along
+	// with their blobs. Files kept by a legal hold or a retention minimum
+	// fail it with ErrLegalHold and nothing is purged.
+	Purge(ctx context.Context, fileIDs, folderIDs []uint) error
+	// RemoveExpiredLocks deletes file locks that have expired
+	RemoveExpiredLocks(ctx context.Context) (string, error)
+	// ReconcileQuotas recomputes every user's and workspace's storage usage
+	// from their files, correcting drift left by failed uploads and crashes
+	ReconcileQuotas(ctx context.Context) (string, error)
+	// RemoveStaleUploads removes the partial content of interrupted uploads
+	RemoveStaleUploads(ctx context.Context) (string, error)
+	// DeleteBlob removes a blob that is no longer referenced. It is the
+	// handler of JobDeleteBlob, which PurgeTrash queues for every blob.
+	DeleteBlob(ctx context.Context, key string) error
+}
+
+type maintenanceService struct {
+	maintenanceRepo repository.MaintenanceRepository
+	lockRepo        repository.LockRepository
+	userRepo        repository.UserRepository
+	workspaceRepo   repository.WorkspaceRepository
+	retention       RetentionService
+	storage         storage.Storage
+	audit           AuditService
+	jobs            JobService
+	config          MaintenanceConfig
+	logger          *util.Logger
+}
+
+// NewMaintenanceService creates a new MaintenanceService instance
+func NewMaintenanceService(
+	maintenanceRepo repository.MaintenanceRepository,
+	lockRepo repository.LockRepository,
+	userRepo repository.UserRepository,
+	workspaceRepo repository.WorkspaceRepository,
+	retention RetentionService,
+	storage storage.Storage,
+	audit AuditService,
+	jobs JobService,
+	config MaintenanceConfig,
+	logger *util.Logger,
+) MaintenanceService {
+	return &maintenanceService{
+		maintenanceRepo: maintenanceRepo,
+		lockRepo:        lockRepo,
+		userRepo:        userRepo,
+		workspaceRepo:   workspaceRepo,
+		retention:       retention,
+		storage:         storage,
+		audit:           audit,
+		jobs:            jobs,
+		config:          config,
+		logger:          logger,
+	}
+}
+
+// PurgeTrash permanently deletes expired trash. Rows are deleted first and
+// their blobs removed by JobDeleteBlob jobs afterwards, so a failure can
+// leave an orphaned blob but never a file without content. Files kept by a legal hold or a retention minimum stay in the
+// trash until they are released.
+func (s *maintenanceService) PurgeTrash(ctx context.Context) (string, error) {
+	cutoff := time.Now().Add(-s.config.TrashRetention)
+
+	var files, kept int
+	var bytes int64
+	var afterID uint
+	for {
+		listed, err := s.maintenanceRepo.ListExpiredFiles(ctx, cutoff, afterID, purgeBatchSize)
+		if err != nil {
+			return "", fmt.Errorf("error listing expired files: %w", err)
+		}
+		if len(listed) == 0 {
+			break
+		}
+		afterID = listed[len(listed)-1].ID
+
+		var expired []model.File
+		for i := range listed {
+			purgeable, err := s.retention.Purgeable(ctx, &listed[i])
+			if err != nil {
+				return "", err
+			}
+			if !purgeable {
+				kept++
+				continue
+			}
+			expired = append(expired, listed[i])
+		}
+		if len(expired) == 0 {
+			if len(listed) < purgeBatchSize {
+				break
+			}
+			continue
+		}
+
+		purged, err := s.purgeFiles(ctx, expired)
+		if err != nil {
+			return "", err
+		}
+		files += len(expired)
+		bytes += purged
+
+		if len(listed) < purgeBatchSize {
+			break
+		}
+	}
+
+	folders, err := s.maintenanceRepo.PurgeExpiredFolders(ctx, cutoff)
+	if err != nil {
+		return "", fmt.Errorf("error purging folders: %w", err)
+	}
+
+	if files > 0 || folders > 0 {
+		s.logger.Info("Trash purged", zap.Int("files", files), zap.Int64("folders", folders), zap.Int64("bytes", bytes), zap.Int("kept", kept))
+	}
+	return fmt.Sprintf("purged %d files (%d bytes) and %d folders, kept %d under holds or minimums", files, bytes, folders, kept), nil
+}
+
+// Purge permanently deletes trashed files and folders now. It discards the
+// copies a rolled back batch made, which never belonged in the trash.
+func (s *maintenanceService) Purge(ctx context.Context, fileIDs, folderIDs []uint) error {
+	files, err := s.maintenanceRepo.ListTrashedFiles(ctx, fileIDs, folderIDs)
+	if err != nil {
+		return fmt.Errorf("error listing trashed files: %w", err)
+	}
+	for i := range files {
+		purgeable, err := s.retention.Purgeable(ctx, &files[i])
+		if err != nil {
+			return err
+		}
+		if !purgeable {
+			return ErrLegalHold
+		}
+	}
+	for start := 0; start < len(files); start += purgeBatchSize {
+		if _, err := s.purgeFiles(ctx, files[start:min(start+purgeBatchSize, len(files))]); err != nil {
+			return err
+		}
+	}
+	if _, err := s.maintenanceRepo.PurgeFolders(ctx, folderIDs); err != nil {
+		return fmt.Errorf("error purging folders: %w", err)
+	}
+	return nil
+}
+
+// purgeFiles permanently deletes trashed files, queues the removal of their
+// blobs and returns their storage to the owners, returning the bytes freed
+func (s *maintenanceService) purgeFiles(ctx context.Context, files []model.File) (int64, error) {
+	ids := make([]uint, len(files))
+	for i := range files {
+		ids[i] = files[i].ID
+	}
+	if err := s.maintenanceRepo.PurgeFiles(ctx, ids); err != nil {
+		return 0, fmt.Errorf("error purging files: %w", err)
+	}
+
+	var bytes int64
+	released := make(map[uint]int64)
+	releasedByWorkspace := make(map[uint]int64)
+	for i := range files {
+		file := &files[i]
+		s.queueBlobDeletion(ctx, file.FileURL)
+		s.queueBlobDeletion(ctx, previewKey(file.FileURL))
+		if file.WorkspaceID != nil {
+			releasedByWorkspace[*file.WorkspaceID] += file.FileSize
+		} else {
+			released[file.UserID] += file.FileSize
+		}
+		s.audit.Record(ctx, &model.AuditLog{
+			Action:     model.AuditFilePurge,
+			TargetType: model.AuditTargetFile,
+			TargetID:   auditRef(file.ID),
+			Metadata:   model.JSONMap{"file_name": file.FileName, "owner_id": file.UserID, "file_size": file.FileSize},
+		})
+		bytes += file.FileSize
+	}
+	for userID, size := range released {
+		if _, err := s.userRepo.AdjustStorageUsed(ctx, userID, -toMegabytes(size)); err != nil {
+			s.logger.Error("Error releasing purged storage", util.WithUserID(userID), util.WithError(err))
+		}
+	}
+	for workspaceID, size := range releasedByWorkspace {
+		if _, err := s.workspaceRepo.AdjustStorageUsed(ctx, workspaceID, -toMegabytes(size)); err != nil {
+			s.logger.Error("Error releasing purged storage", zap.Uint("workspace_id", workspaceID), util.WithError(err))
+		}
+	}
+	return bytes, nil
+}
+
+// queueBlobDeletion queues the removal of a blob that is no longer
+// referenced. Blobs whose job cannot be queued are left to blob_gc.
+func (s *maintenanceService) queueBlobDeletion(ctx context.Context, key string) {
+	opts := &JobOptions{UniqueKey: model.JobDeleteBlob + ":" + key}
+	if _, err := s.jobs.Enqueue(ctx, model.JobDeleteBlob, model.BlobJob{Key: key}, opts); err != nil {
+		s.logger.Error("Error queueing purged blob for removal", zap.String("key", key), util.WithError(err))
+	}
+}
+
+// DeleteBlob removes a blob; blobs that are already gone are not an error
+func (s *maintenanceService) DeleteBlob(ctx context.Context, key string) error {
+	if err := s.storage.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("error removing blob: %w", err)
+	}
+	return nil
+}
+
+// RemoveExpiredLocks deletes expired file locks
+func (s *maintenanceService) RemoveExpiredLocks(ctx context.Context) (string, error) {
+	n, err := s.lockRepo.DeleteExpired(ctx, time.Now())
+	if err != nil {
+		return "", fmt.Errorf("error removing expired locks: %w", err)
+	}
+	return fmt.Sprintf("removed %d expired locks", n), nil
+}
+
+// ReconcileQuotas recomputes storage usage. Uploads in flight while it runs
+// hold a reservation that is not counted, so their owner's usage is briefly
+// off until the next run.
+func (s *maintenanceService) ReconcileQuotas(ctx context.Context) (string, error) {
+	n, err := s.maintenanceRepo.ReconcileStorageUsed(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error reconciling storage usage: %w", err)
+	}
+	w, err := s.maintenanceRepo.ReconcileWorkspaceStorageUsed(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error reconciling workspace storage usage: %w", err)
+	}
+	if n > 0 || w > 0 {
+		s.logger.Warn("Corrected storage usage", zap.Int64("users", n), zap.Int64("workspaces", w))
+	}
+	return fmt.Sprintf("corrected storage usage of %d users and %d workspaces", n, w), nil
+}
+
+// RemoveStaleUploads removes the partial content of interrupted uploads
+func (s *maintenanceService) RemoveStaleUploads(ctx context.Context) (string, error) {
+	n, err := s.storage.RemoveStaleUploads(ctx, time.Now().Add(-s.config.StaleUploadAge))
+	if err != nil {
+		return "", fmt.Errorf("error removing stale uploads: %w", err)
+	}
+	return fmt.Sprintf("removed %d stale uploads", n), nil
+}
@@ -49,6 +49,8 @@ type oauthService struct {
 	facebookConfig *FacebookOAuthConfig
 	logger         *util.Logger
 	authService    AuthService
+	audit          AuditService
+	events         EventPublisher
 }
 
 // GoogleOAuthConfig holds configuration for Google OAuth
@@ -71,6 +73,8 @@ func NewOAuthService(
 	facebookConfig *FacebookOAuthConfig,
 	logger *util.Logger,
 	authService AuthService,
+	audit AuditService,
+	events EventPublisher,
 ) OAuthService {
 	return &oauthService{
 		userRepo:       userRepo,
@@ -79,6 +83,8 @@ func NewOAuthService(
 		facebookConfig: facebookConfig,
 		logger:         logger,
 		authService:    authService,
+		audit:          audit,
+		events:         events,
 	}
 }
 
@@ -94,12 +100,22 @@ func (s *oauthService) Login(ctx context.Context, providerName string, token str
 		s.logger.Error("Error fetching user info from OAuth provider",
 			zap.String("provider", providerName),
 			util.WithError(err))
+		s.audit.Record(ctx, &model.AuditLog{
+			Action:   model.AuditOAuthLoginFailed,
+			Outcome:  model.AuditFailure,
+			Metadata: model.JSONMap{"provider": providerName, "reason": "provider_rejected_token"},
+		})
 		return nil, err
 	}
 
 	if userInfo.Email == "" {
 		s.logger.Error("OAuth provider did not return an email",
 			zap.String("provider", providerName))
+		s.audit.Record(ctx, &model.AuditLog{
+			Action:   model.AuditOAuthLoginFailed,
+			Outcome:  model.AuditFailure,
+			Metadata: model.JSONMap{"provider": providerName, "reason": "missing_email"},
+		})
 		return nil, errors.New("oauth provider did not return an email")
 	}
 
@@ -145,6 +161,7 @@ func (s *oauthService) Login(ctx context.Context, providerName string, token str
 				util.WithError(err))
 			return nil, err
 		}
+		s.events.Publish(ctx, newEvent(model.EventUserRegistered, user.ID, user.ToResponse()))
 	} else if user.Provider == model.LocalAuth {
 		// Update existing user with OAuth info if they were using local auth
 		user.Provider = authProvider
@@ -178,6 +195,14 @@ func (s *oauthService) Login(ctx context.Context, providerName string, token str
 	s.logger.Info("User logged in with OAuth successfully",
 		util.WithUserID(user.ID),
 		zap.String("provider", providerName))
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditOAuthLogin,
+		ActorID:    auditRef(user.ID),
+		ActorEmail: user.Email,
+		TargetType: model.AuditTargetUser,
+		TargetID:   auditRef(user.ID),
+		Metadata:   model.JSONMap{"provider": providerName},
+	})
 
 	return &model.AuthResponse{
 		User:         user.ToResponse(),
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"fmt"
+)
+
+const (
+	defaultPhotoPerPage = 100
+	maxPhotoPerPage     = 500
+)
+
+// PhotoService lists a user's photos by when they were taken. Photos are
+// the user's own image files; their metadata is read by the indexer.
+type PhotoService interface {
+	// Timeline counts the user's photos by month, or by year when
+	// req.Granularity is "year", newest first
+	Timeline(ctx context.Context, userID uint, req *model.TimelineRequest) ([]model.TimelineBucket, error)
+	// List returns a page of the user's photos, newest first, optionally
+	// only those taken in a given year or month
+	List(ctx context.Context, userID uint, filter *model.PhotoFilter) ([]model.File, int64, error)
+	// Settings returns the user's photo privacy settings
+	Settings(ctx context.Context, userID uint) (*model.PhotoSettings, error)
+	// UpdateSettings changes the user's photo privacy settings. Turning on
+	// location stripping clears the coordinates of the user's photos.
+	UpdateSettings(ctx context.Context, userID uint, req *model.UpdatePhotoSettingsRequest) (*model.PhotoSettings, error)
+}
+
+// PhotoConfig holds the server-wide photo settings
+type PhotoConfig struct {
+	// StripLocation strips the coordinates of every user's photos,
+	// overriding their own setting
+	StripLocation bool
+}
+
+type photoService struct {
+	photoRepo repository.PhotoRepository
+	userRepo  repository.UserRepository
+	config    PhotoConfig
+	logger    *util.Logger
+}
+
+// NewPhotoService creates a new PhotoService instance
+func NewPhotoService(photoRepo repository.PhotoRepository, userRepo repository.UserRepository, config PhotoConfig, logger *util.Logger) PhotoService {
+	return &photoService{
+		photoRepo: photoRepo,
+		userRepo:  userRepo,
+		config:    config,
+		logger:    logger,
+	}
+}
+
+// Timeline counts the user's photos by month or year
+func (s *photoService) Timeline(ctx context.Context, userID uint, req *model.TimelineRequest) ([]model.TimelineBucket, error) {
+	buckets, err := s.photoRepo.Timeline(ctx, userID, req.Granularity != "year")
+	if err != nil {
+		s.logger.Error("Error building photo timeline", util.WithUserID(userID), util.WithError(err))
+		return nil, fmt.Errorf("error building photo timeline: %w", err)
+	}
+	return buckets, nil
+}
+
+// List returns a page of the user's photos
+func (s *photoService) List(ctx context.Context, userID uint, filter *model.PhotoFilter) ([]model.File, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 {
+		filter.PerPage = defaultPhotoPerPage
+	}
+	if filter.PerPage > maxPhotoPerPage {
+		filter.PerPage = maxPhotoPerPage
+	}
+
+	photos, total, err := s.photoRepo.List(ctx, userID, filter)
+	if err != nil {
+		s.logger.Error("Error listing photos", util.WithUserID(userID), util.WithError(err))
+		return nil, 0, fmt.Errorf("error listing photos: %w", err)
+	}
+	return photos, total, nil
+}
+
+// Settings returns the user's photo privacy settings
+func (s *photoService) Settings(ctx context.Context, userID uint) (*model.PhotoSettings, error) {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return &model.PhotoSettings{
+		StripLocation:         user.StripPhotoLocation,
+		StripLocationEnforced: s.config.StripLocation,
+	}, nil
+}
+
+// UpdateSettings changes the user's photo privacy settings
+func (s *photoService) UpdateSettings(ctx context.Context, userID uint, req *model.UpdatePhotoSettingsRequest) (*model.PhotoSettings, error) {
+	if err := s.photoRepo.SetStripLocation(ctx, userID, *req.StripLocation); err != nil {
+		s.logger.Error("Error saving photo settings", util.WithUserID(userID), util.WithError(err))
+		return nil, fmt.Errorf("error saving photo settings: %w", err)
+	}
+	return s.Settings(ctx, userID)
+}
@@ -0,0 +1,255 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"drive/internal/encryption"
+	"drive/internal/model"
+	"drive/internal/preview"
+	"drive/internal/repository"
+	"drive/internal/storage"
+	"drive/internal/util"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultPreviewPerPage = 50
+	maxPreviewPerPage     = 500
+)
+
+var (
+	ErrPreviewUnsupported = errors.New("no preview is available for this file type")
+	ErrPreviewTooLarge    = errors.New("file is too large to preview")
+	ErrPreviewFailed      = errors.New("file could not be previewed")
+)
+
+// PreviewConfig holds the settings of the preview service
+type PreviewConfig struct {
+	// MaxFileSize is the largest file in bytes that will be previewed
+	MaxFileSize int64
+	// Timeout bounds the time spent rendering a single file
+	Timeout time.Duration
+}
+
+// PreviewService renders files for viewing without downloading them.
+// Renderings are cached in storage next to the blobs they were made from.
+type PreviewService interface {
+	// Preview returns the rendering of a file the user can read. Tables
+	// are returned a page at a time.
+	Preview(ctx context.Context, userID, fileID uint, req *model.PreviewRequest) (*preview.Preview, error)
+}
+
+type previewService struct {
+	files      FileService
+	scans      ScanService
+	folderRepo repository.FolderRepository
+	storage    storage.Storage
+	keys       *encryption.Keyring
+	registry   *preview.Registry
+	config     PreviewConfig
+	logger     *util.Logger
+}
+
+// NewPreviewService creates a new PreviewService instance
+func NewPreviewService(
+	files FileService,
+	scans ScanService,
+	folderRepo repository.FolderRepository,
+	store storage.Storage,
+	keys *encryption.Keyring,
+	registry *preview.Registry,
+	config PreviewConfig,
+	logger *util.Logger,
+) PreviewService {
+	return &previewService{
+		files:      files,
+		scans:      scans,
+		folderRepo: folderRepo,
+		storage:    store,
+		keys:       keys,
+		registry:   registry,
+		config:     config,
+		logger:     logger,
+	}
+}
+
+// Preview returns the rendering of a file the user can read, rendering it
+// on first use
+func (s *previewService) Preview(ctx context.Context, userID, fileID uint, req *model.PreviewRequest) (*preview.Preview, error) {
+	file, err := s.files.GetFile(ctx, userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.scans.Check(file); err != nil {
+		return nil, err
+	}
+	folder, err := s.folderRepo.FindByID(ctx, file.FolderID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding folder: %w", err)
+	}
+	if folder != nil && folder.VaultID != nil {
+		// Vault content is encrypted by the client
+		return nil, ErrPreviewUnsupported
+	}
+	renderer, err := s.registry.Find(file.MimeType, file.FileName)
+	if err != nil {
+		return nil, ErrPreviewUnsupported
+	}
+	if s.config.MaxFileSize > 0 && file.FileSize > s.config.MaxFileSize {
+		return nil, ErrPreviewTooLarge
+	}
+
+	logger := s.logger.With(util.WithUserID(userID), zap.Uint("file_id", fileID))
+	p, err := s.cached(ctx, file)
+	if err != nil {
+		logger.Warn("Error reading cached preview", util.WithError(err))
+	}
+	if p == nil {
+		if p, err = s.render(ctx, file, renderer); err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			logger.Warn("Error rendering preview", zap.String("renderer", renderer.Name()), util.WithError(err))
+			return nil, fmt.Errorf("%w: %v", ErrPreviewFailed, err)
+		}
+		if err := s.store(ctx, file, p); err != nil {
+			logger.Warn("Error caching preview", util.WithError(err))
+		}
+	}
+
+	if p.Kind == preview.KindTable {
+		paginate(p, req)
+	}
+	return p, nil
+}
+
+// render opens a file's blob and renders it within the configured timeout
+func (s *previewService) render(ctx context.Context, file *model.File, renderer preview.Renderer) (*preview.Preview, error) {
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+
+	blob, err := openBlob(ctx, s.storage, s.keys, file)
+	if err != nil {
+		return nil, err
+	}
+	defer blob.Close()
+	return renderer.Render(ctx, file.FileName, blob, file.FileSize)
+}
+
+// cached returns the stored rendering of a file's blob, or nil if there is
+// none. Renderings of encrypted blobs are encrypted with a key derived from
+// the blob's data key.
+func (s *previewService) cached(ctx context.Context, file *model.File) (*preview.Preview, error) {
+	blob, err := s.storage.Open(ctx, previewKey(file.FileURL))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer blob.Close()
+
+	var r io.Reader = blob
+	if file.EncryptionKeyID != "" {
+		key, err := s.renderingKey(file)
+		if err != nil {
+			return nil, err
+		}
+		if r, err = encryption.Decrypt(blob, key); err != nil {
+			return nil, err
+		}
+	}
+
+	var p preview.Preview
+	if err := json.NewDecoder(r).Decode(&p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// store caches the rendering of a file's blob
+func (s *previewService) store(ctx context.Context, file *model.File, p *preview.Preview) error {
+	data, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	var r io.Reader = bytes.NewReader(data)
+	if file.EncryptionKeyID != "" {
+		key, err := s.renderingKey(file)
+		if err != nil {
+			return err
+		}
+		if r, err = encryption.Encrypt(r, key); err != nil {
+			return err
+		}
+	}
+	_, err = s.storage.Put(ctx, previewKey(file.FileURL), r)
+	return err
+}
+
+// renderingKey returns the key renderings of an encrypted blob are encrypted
+// with. Reusing the data key would repeat the blob's own nonces.
+func (s *previewService) renderingKey(file *model.File) ([]byte, error) {
+	dataKey, err := s.keys.Unwrap(file.EncryptionKeyID, file.DataKey)
+	if err != nil {
+		return nil, fmt.Errorf("error unwrapping data key: %w", err)
+	}
+	return encryption.DeriveKey(dataKey, "drive preview v1")
+}
+
+// paginate cuts a table preview down to the requested page of rows
+func paginate(p *preview.Preview, req *model.PreviewRequest) {
+	page, perPage := req.Page, req.PerPage
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = defaultPreviewPerPage
+	}
+	if perPage > maxPreviewPerPage {
+		perPage = maxPreviewPerPage
+	}
+
+	start := len(p.Rows)
+	if page-1 <= len(p.Rows)/perPage {
+		start = min((page-1)*perPage, len(p.Rows))
+	}
+	end := min(start+perPage, len(p.Rows))
+	p.TotalRows = len(p.Rows)
+	p.Rows = p.Rows[start:end]
+	p.Page, p.PerPage = page, perPage
+}
+
+const (
+	// previewRoot holds the cached renderings of every version
+	previewRoot = "previews/"
+	// previewPrefix holds the renderings of the current version
+	previewPrefix = previewRoot + "v2/"
+	previewSuffix = ".json"
+)
+
+// previewKey returns where the rendering of a blob is cached. The version
+// segment changes whenever renderings would come out differently.
+func previewKey(blobKey string) string {
+	return previewPrefix + blobKey + previewSuffix
+}
+
+// previewSource returns the blob a cached rendering was made from, or false
+// for renderings of an earlier version
+func previewSource(key string) (string, bool) {
+	if !strings.HasPrefix(key, previewPrefix) || !strings.HasSuffix(key, previewSuffix) {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(key, previewPrefix), previewSuffix), true
+}
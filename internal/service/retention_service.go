@@ -0,0 +1,628 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// retentionBatchSize is how many files the enforcement pass loads at once
+	retentionBatchSize = 100
+
+	defaultRetentionPerPage = 20
+)
+
+var (
+	ErrLegalHold         = errors.New("item is under legal hold")
+	ErrRetentionPeriod   = errors.New("item is within its minimum retention period")
+	ErrInvalidRetention  = errors.New("retention policy needs a minimum or a maximum, and a maximum no shorter than its minimum")
+	ErrPolicyNotFound    = errors.New("retention policy not found")
+	ErrLegalHoldNotFound = errors.New("legal hold not found")
+	ErrLegalHoldReleased = errors.New("legal hold is already released")
+	ErrInvalidLegalHold  = errors.New("legal hold needs exactly one of user_id or folder_id")
+)
+
+// RetentionService manages retention policies and legal holds. The file
+// and folder services consult it before deleting, overwriting or moving
+// items, and every refusal is audited.
+type RetentionService interface {
+	// SetPolicy creates or replaces the retention policy of a folder
+	SetPolicy(ctx context.Context, adminID, folderID uint, req *model.SetRetentionPolicyRequest) (*model.RetentionPolicy, error)
+	// DeletePolicy removes the retention policy of a folder
+	DeletePolicy(ctx context.Context, adminID, folderID uint) error
+	// ListPolicies returns a page of policies with their folders
+	ListPolicies(ctx context.Context, filter *model.RetentionPolicyFilter) ([]model.RetentionPolicy, int64, error)
+	// PlaceHold puts a user or a folder under legal hold
+	PlaceHold(ctx context.Context, adminID uint, req *model.CreateLegalHoldRequest) (*model.LegalHold, error)
+	// ReleaseHold lifts a legal hold
+	ReleaseHold(ctx context.Context, adminID, holdID uint) (*model.LegalHold, error)
+	GetHold(ctx context.Context, holdID uint) (*model.LegalHold, error)
+	// ListHolds returns a page of legal holds, newest first
+	ListHolds(ctx context.Context, filter *model.LegalHoldFilter) ([]model.LegalHold, int64, error)
+
+	// CheckFileDelete returns ErrLegalHold or ErrRetentionPeriod if the file
+	// must not be moved to the trash
+	CheckFileDelete(ctx context.Context, userID uint, file *model.File) error
+	// CheckFileReplace returns ErrLegalHold if the file's content must not
+	// be overwritten, as that discards the previous content
+	CheckFileReplace(ctx context.Context, userID uint, file *model.File) error
+	// CheckFileMove returns ErrLegalHold or ErrRetentionPeriod if moving the
+	// file to the folder would take it out of a hold or policy it is under
+	CheckFileMove(ctx context.Context, userID uint, file *model.File, folderID uint) error
+	// CheckFolderDelete returns ErrLegalHold or ErrRetentionPeriod if the
+	// folder, or anything beneath it, must not be moved to the trash
+	CheckFolderDelete(ctx context.Context, userID uint, folder *model.Folder) error
+	// CheckFolderMove returns ErrLegalHold or ErrRetentionPeriod if moving
+	// the folder under parentID would take its contents out of a hold or
+	// policy they are under
+	CheckFolderMove(ctx context.Context, userID uint, folder *model.Folder, parentID uint) error
+	// Purgeable reports whether a trashed file may be deleted permanently
+	Purgeable(ctx context.Context, file *model.File) (bool, error)
+	// Enforce moves files past their maximum retention to the trash, unless
+	// a hold or a longer minimum keeps them, and summarises what it did
+	Enforce(ctx context.Context) (string, error)
+}
+
+type retentionService struct {
+	retentionRepo  repository.RetentionRepository
+	fileRepo       repository.FileRepository
+	folderRepo     repository.FolderRepository
+	userRepo       repository.UserRepository
+	permissionRepo repository.PermissionRepository
+	audit          AuditService
+	events         EventPublisher
+	logger         *util.Logger
+}
+
+// NewRetentionService creates a new RetentionService instance
+func NewRetentionService(
+	retentionRepo repository.RetentionRepository,
+	fileRepo repository.FileRepository,
+	folderRepo repository.FolderRepository,
+	userRepo repository.UserRepository,
+	permissionRepo repository.PermissionRepository,
+	audit AuditService,
+	events EventPublisher,
+	logger *util.Logger,
+) RetentionService {
+	return &retentionService{
+		retentionRepo:  retentionRepo,
+		fileRepo:       fileRepo,
+		folderRepo:     folderRepo,
+		userRepo:       userRepo,
+		permissionRepo: permissionRepo,
+		audit:          audit,
+		events:         events,
+		logger:         logger,
+	}
+}
+
+// SetPolicy creates or replaces the retention policy of a folder
+func (s *retentionService) SetPolicy(ctx context.Context, adminID, folderID uint, req *model.SetRetentionPolicyRequest) (*model.RetentionPolicy, error) {
+	if req.MinDays == 0 && req.MaxDays == 0 || req.MaxDays > 0 && req.MaxDays < req.MinDays {
+		return nil, ErrInvalidRetention
+	}
+	folder, err := s.folderRepo.FindByID(ctx, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding folder: %w", err)
+	}
+	if folder == nil {
+		return nil, ErrFolderNotFound
+	}
+
+	policy, err := s.retentionRepo.FindPolicyByFolder(ctx, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding retention policy: %w", err)
+	}
+	metadata := model.JSONMap{"min_days": req.MinDays, "max_days": req.MaxDays}
+	if policy == nil {
+		policy = &model.RetentionPolicy{FolderID: folderID, CreatedBy: adminID}
+	} else {
+		metadata["previous_min_days"] = policy.MinDays
+		metadata["previous_max_days"] = policy.MaxDays
+	}
+	policy.MinDays = req.MinDays
+	policy.MaxDays = req.MaxDays
+	policy.Description = req.Description
+
+	if policy.ID == 0 {
+		err = s.retentionRepo.CreatePolicy(ctx, policy)
+	} else {
+		err = s.retentionRepo.UpdatePolicy(ctx, policy)
+	}
+	if err != nil {
+		s.logger.Error("Error saving retention policy", util.WithUserID(adminID), zap.Uint("folder_id", folderID), util.WithError(err))
+		return nil, fmt.Errorf("error saving retention policy: %w", err)
+	}
+
+	metadata["policy_id"] = policy.ID
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditRetentionPolicySet,
+		ActorID:    auditRef(adminID),
+		TargetType: model.AuditTargetFolder,
+		TargetID:   auditRef(folderID),
+		Metadata:   metadata,
+	})
+	s.logger.Info("Retention policy set", util.WithUserID(adminID), zap.Uint("folder_id", folderID),
+		zap.Int("min_days", policy.MinDays), zap.Int("max_days", policy.MaxDays))
+	return policy, nil
+}
+
+// DeletePolicy removes the retention policy of a folder
+func (s *retentionService) DeletePolicy(ctx context.Context, adminID, folderID uint) error {
+	policy, err := s.retentionRepo.FindPolicyByFolder(ctx, folderID)
+	if err != nil {
+		return fmt.Errorf("error finding retention policy: %w", err)
+	}
+	if policy == nil {
+		return ErrPolicyNotFound
+	}
+	if err := s.retentionRepo.DeletePolicy(ctx, policy); err != nil {
+		s.logger.Error("Error deleting retention policy", util.WithUserID(adminID), zap.Uint("folder_id", folderID), util.WithError(err))
+		return fmt.Errorf("error deleting retention policy: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditRetentionPolicyDelete,
+		ActorID:    auditRef(adminID),
+		TargetType: model.AuditTargetFolder,
+		TargetID:   auditRef(folderID),
+		Metadata:   model.JSONMap{"policy_id": policy.ID, "min_days": policy.MinDays, "max_days": policy.MaxDays},
+	})
+	s.logger.Info("Retention policy deleted", util.WithUserID(adminID), zap.Uint("folder_id", folderID))
+	return nil
+}
+
+// ListPolicies returns a page of policies
+func (s *retentionService) ListPolicies(ctx context.Context, filter *model.RetentionPolicyFilter) ([]model.RetentionPolicy, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 {
+		filter.PerPage = defaultRetentionPerPage
+	}
+
+	policies, total, err := s.retentionRepo.ListPolicies(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing retention policies: %w", err)
+	}
+	return policies, total, nil
+}
+
+// PlaceHold puts a user or a folder under legal hold. Folders already in
+// the trash can be held to keep them from being purged.
+func (s *retentionService) PlaceHold(ctx context.Context, adminID uint, req *model.CreateLegalHoldRequest) (*model.LegalHold, error) {
+	if (req.UserID == nil) == (req.FolderID == nil) {
+		return nil, ErrInvalidLegalHold
+	}
+
+	target, targetID := model.AuditTargetUser, req.UserID
+	if req.UserID != nil {
+		user, err := s.userRepo.FindByID(ctx, *req.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("error finding user: %w", err)
+		}
+		if user == nil {
+			return nil, ErrUserNotFound
+		}
+	} else {
+		target, targetID = model.AuditTargetFolder, req.FolderID
+		folder, err := s.folderRepo.FindByID(ctx, *req.FolderID)
+		if err == nil && folder == nil {
+			folder, err = s.folderRepo.FindDeleted(ctx, *req.FolderID)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error finding folder: %w", err)
+		}
+		if folder == nil {
+			return nil, ErrFolderNotFound
+		}
+	}
+
+	hold := &model.LegalHold{
+		UserID:    req.UserID,
+		FolderID:  req.FolderID,
+		Reason:    req.Reason,
+		CreatedBy: adminID,
+	}
+	if err := s.retentionRepo.CreateHold(ctx, hold); err != nil {
+		s.logger.Error("Error placing legal hold", util.WithUserID(adminID), util.WithError(err))
+		return nil, fmt.Errorf("error placing legal hold: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditLegalHoldPlace,
+		ActorID:    auditRef(adminID),
+		TargetType: model.AuditTargetLegalHold,
+		TargetID:   auditRef(hold.ID),
+		Metadata:   model.JSONMap{"held_type": target, "held_id": *targetID, "reason": hold.Reason},
+	})
+	s.logger.Info("Legal hold placed", util.WithUserID(adminID), zap.Uint("hold_id", hold.ID))
+	return hold, nil
+}
+
+// ReleaseHold lifts a legal hold
+func (s *retentionService) ReleaseHold(ctx context.Context, adminID, holdID uint) (*model.LegalHold, error) {
+	hold, err := s.GetHold(ctx, holdID)
+	if err != nil {
+		return nil, err
+	}
+	if !hold.Active() {
+		return nil, ErrLegalHoldReleased
+	}
+
+	now := time.Now()
+	hold.ReleasedAt = &now
+	hold.ReleasedBy = &adminID
+	released, err := s.retentionRepo.ReleaseHold(ctx, hold)
+	if err != nil {
+		s.logger.Error("Error releasing legal hold", util.WithUserID(adminID), zap.Uint("hold_id", holdID), util.WithError(err))
+		return nil, fmt.Errorf("error releasing legal hold: %w", err)
+	}
+	if !released {
+		return nil, ErrLegalHoldReleased
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditLegalHoldRelease,
+		ActorID:    auditRef(adminID),
+		TargetType: model.AuditTargetLegalHold,
+		TargetID:   auditRef(hold.ID),
+		Metadata:   model.JSONMap{"user_id": hold.UserID, "folder_id": hold.FolderID},
+	})
+	s.logger.Info("Legal hold released", util.WithUserID(adminID), zap.Uint("hold_id", hold.ID))
+	return hold, nil
+}
+
+// GetHold returns a legal hold
+func (s *retentionService) GetHold(ctx context.Context, holdID uint) (*model.LegalHold, error) {
+	hold, err := s.retentionRepo.FindHold(ctx, holdID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding legal hold: %w", err)
+	}
+	if hold == nil {
+		return nil, ErrLegalHoldNotFound
+	}
+	return hold, nil
+}
+
+// ListHolds returns a page of legal holds
+func (s *retentionService) ListHolds(ctx context.Context, filter *model.LegalHoldFilter) ([]model.LegalHold, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 {
+		filter.PerPage = defaultRetentionPerPage
+	}
+
+	holds, total, err := s.retentionRepo.ListHolds(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing legal holds: %w", err)
+	}
+	return holds, total, nil
+}
+
+// retentionBlock explains why retention keeps an item where it is
+type retentionBlock struct {
+	hold   *model.LegalHold
+	policy *model.RetentionPolicy
+	until  time.Time
+}
+
+// err returns the error reported for the block
+func (b *retentionBlock) err() error {
+	if b.hold != nil {
+		return ErrLegalHold
+	}
+	return fmt.Errorf("%w, which ends %s", ErrRetentionPeriod, b.until.UTC().Format(time.DateOnly))
+}
+
+// CheckFileDelete checks the holds on the file's owner and folders and the
+// minimums of the policies above it
+func (s *retentionService) CheckFileDelete(ctx context.Context, userID uint, file *model.File) error {
+	path, err := s.retentionRepo.FolderPath(ctx, file.FolderID)
+	if err != nil {
+		return fmt.Errorf("error finding folder path: %w", err)
+	}
+	block, err := s.check(ctx, path, fileOwners(file), &file.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return s.refuse(ctx, userID, "delete", model.AuditTargetFile, file.ID, block)
+}
+
+// CheckFileReplace checks the holds on the file's owner and folders
+func (s *retentionService) CheckFileReplace(ctx context.Context, userID uint, file *model.File) error {
+	path, err := s.retentionRepo.FolderPath(ctx, file.FolderID)
+	if err != nil {
+		return fmt.Errorf("error finding folder path: %w", err)
+	}
+	block, err := s.check(ctx, path, fileOwners(file), nil)
+	if err != nil {
+		return err
+	}
+	return s.refuse(ctx, userID, "replace", model.AuditTargetFile, file.ID, block)
+}
+
+// CheckFileMove checks the holds and policies on the folders the file
+// would leave. Holds on its owner stay with it.
+func (s *retentionService) CheckFileMove(ctx context.Context, userID uint, file *model.File, folderID uint) error {
+	left, err := s.leftFolders(ctx, file.FolderID, folderID)
+	if err != nil {
+		return err
+	}
+	block, err := s.check(ctx, left, nil, &file.CreatedAt)
+	if err != nil {
+		return err
+	}
+	return s.refuse(ctx, userID, "move", model.AuditTargetFile, file.ID, block)
+}
+
+// CheckFolderDelete checks the holds on the folder, its ancestors and
+// everything beneath it, and the minimums of the policies above and
+// beneath it against the newest files they cover
+func (s *retentionService) CheckFolderDelete(ctx context.Context, userID uint, folder *model.Folder) error {
+	block, err := s.folderBlock(ctx, folder)
+	if err != nil {
+		return err
+	}
+	return s.refuse(ctx, userID, "delete", model.AuditTargetFolder, folder.ID, block)
+}
+
+// folderBlock finds what keeps a folder from being deleted
+func (s *retentionService) folderBlock(ctx context.Context, folder *model.Folder) (*retentionBlock, error) {
+	hold, err := s.retentionRepo.SubtreeHold(ctx, folder.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error checking legal holds: %w", err)
+	}
+	if hold != nil {
+		return &retentionBlock{hold: hold}, nil
+	}
+
+	path, err := s.retentionRepo.FolderPath(ctx, folder.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding folder path: %w", err)
+	}
+	newest, err := s.retentionRepo.NewestFileUnder(ctx, folder.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding newest file: %w", err)
+	}
+	block, err := s.check(ctx, path, nil, newest)
+	if err != nil || block != nil {
+		return block, err
+	}
+
+	policies, err := s.retentionRepo.PoliciesUnder(ctx, folder.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding retention policies: %w", err)
+	}
+	for i := range policies {
+		if policies[i].MinDays == 0 {
+			continue
+		}
+		newest, err := s.retentionRepo.NewestFileUnder(ctx, policies[i].FolderID)
+		if err != nil {
+			return nil, fmt.Errorf("error finding newest file: %w", err)
+		}
+		if block := retainedBy(&policies[i], newest); block != nil {
+			return block, nil
+		}
+	}
+	return nil, nil
+}
+
+// CheckFolderMove checks the holds and policies on the folders the folder
+// would leave against the newest file beneath it
+func (s *retentionService) CheckFolderMove(ctx context.Context, userID uint, folder *model.Folder, parentID uint) error {
+	if folder.ParentFolderID == nil {
+		return nil
+	}
+	left, err := s.leftFolders(ctx, *folder.ParentFolderID, parentID)
+	if err != nil {
+		return err
+	}
+	if len(left) == 0 {
+		return nil
+	}
+	newest, err := s.retentionRepo.NewestFileUnder(ctx, folder.ID)
+	if err != nil {
+		return fmt.Errorf("error finding newest file: %w", err)
+	}
+	block, err := s.check(ctx, left, nil, newest)
+	if err != nil {
+		return err
+	}
+	return s.refuse(ctx, userID, "move", model.AuditTargetFolder, folder.ID, block)
+}
+
+// Purgeable checks a trashed file like a deletion, without auditing
+func (s *retentionService) Purgeable(ctx context.Context, file *model.File) (bool, error) {
+	path, err := s.retentionRepo.FolderPath(ctx, file.FolderID)
+	if err != nil {
+		return false, fmt.Errorf("error finding folder path: %w", err)
+	}
+	block, err := s.check(ctx, path, fileOwners(file), &file.CreatedAt)
+	return block == nil && err == nil, err
+}
+
+// leftFolders returns the folders of from's path that are not on to's
+func (s *retentionService) leftFolders(ctx context.Context, from, to uint) ([]uint, error) {
+	source, err := s.retentionRepo.FolderPath(ctx, from)
+	if err != nil {
+		return nil, fmt.Errorf("error finding folder path: %w", err)
+	}
+	destination, err := s.retentionRepo.FolderPath(ctx, to)
+	if err != nil {
+		return nil, fmt.Errorf("error finding folder path: %w", err)
+	}
+	kept := make(map[uint]bool, len(destination))
+	for _, id := range destination {
+		kept[id] = true
+	}
+	var left []uint
+	for _, id := range source {
+		if !kept[id] {
+			left = append(left, id)
+		}
+	}
+	return left, nil
+}
+
+// check looks for a hold on the folders or owners, then for a policy on the
+// folders whose minimum retains content created at createdAt. A nil
+// createdAt only checks holds.
+func (s *retentionService) check(ctx context.Context, folderIDs, ownerIDs []uint, createdAt *time.Time) (*retentionBlock, error) {
+	holds, err := s.retentionRepo.ActiveHolds(ctx, folderIDs, ownerIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error checking legal holds: %w", err)
+	}
+	if len(holds) > 0 {
+		return &retentionBlock{hold: &holds[0]}, nil
+	}
+	if createdAt == nil {
+		return nil, nil
+	}
+
+	policies, err := s.retentionRepo.PoliciesOn(ctx, folderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("error finding retention policies: %w", err)
+	}
+	var longest *retentionBlock
+	for i := range policies {
+		if block := retainedBy(&policies[i], createdAt); block != nil && (longest == nil || block.until.After(longest.until)) {
+			longest = block
+		}
+	}
+	return longest, nil
+}
+
+// fileOwners returns the user a file belongs to, or none for a file in a
+// workspace, which holds on its members do not cover
+func fileOwners(file *model.File) []uint {
+	if file.WorkspaceID != nil {
+		return nil
+	}
+	return []uint{file.UserID}
+}
+
+// retainedBy returns a block if the policy's minimum still retains content
+// created at createdAt
+func retainedBy(policy *model.RetentionPolicy, createdAt *time.Time) *retentionBlock {
+	if policy.MinDays == 0 || createdAt == nil {
+		return nil
+	}
+	until := createdAt.AddDate(0, 0, policy.MinDays)
+	if !until.After(time.Now()) {
+		return nil
+	}
+	return &retentionBlock{policy: policy, until: until}
+}
+
+// refuse audits a blocked operation and returns its error
+func (s *retentionService) refuse(ctx context.Context, userID uint, operation string, target model.AuditTarget, targetID uint, block *retentionBlock) error {
+	if block == nil {
+		return nil
+	}
+
+	metadata := model.JSONMap{"operation": operation}
+	if block.hold != nil {
+		metadata["hold_id"] = block.hold.ID
+	} else {
+		metadata["policy_id"] = block.policy.ID
+		metadata["retained_until"] = block.until.UTC().Format(time.RFC3339)
+	}
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditRetentionBlock,
+		Outcome:    model.AuditFailure,
+		ActorID:    auditRef(userID),
+		TargetType: target,
+		TargetID:   auditRef(targetID),
+		Metadata:   metadata,
+	})
+	return block.err()
+}
+
+// Enforce walks the files past each policy's maximum. A file under several
+// policies is trashed by whichever maximum it passes first.
+func (s *retentionService) Enforce(ctx context.Context) (string, error) {
+	policies, err := s.retentionRepo.ListExpiringPolicies(ctx)
+	if err != nil {
+		return "", fmt.Errorf("error listing retention policies: %w", err)
+	}
+
+	trashed, kept := 0, 0
+	for i := range policies {
+		policy := &policies[i]
+		cutoff := time.Now().AddDate(0, 0, -policy.MaxDays)
+
+		var afterID uint
+		for {
+			files, err := s.retentionRepo.ListFilesUnder(ctx, policy.FolderID, cutoff, afterID, retentionBatchSize)
+			if err != nil {
+				return "", fmt.Errorf("error listing expired files: %w", err)
+			}
+			for j := range files {
+				file := &files[j]
+				afterID = file.ID
+
+				// Holds and longer minimums, from this or other policies,
+				// keep the file
+				purgeable, err := s.Purgeable(ctx, file)
+				if err != nil {
+					return "", err
+				}
+				if !purgeable {
+					kept++
+					continue
+				}
+				if err := s.expire(ctx, policy, file); err != nil {
+					return "", err
+				}
+				trashed++
+			}
+			if len(files) < retentionBatchSize {
+				break
+			}
+		}
+	}
+
+	if trashed > 0 {
+		s.logger.Info("Expired files moved to the trash", zap.Int("files", trashed), zap.Int("kept", kept))
+	}
+	return fmt.Sprintf("moved %d expired files to the trash, kept %d under holds or minimums", trashed, kept), nil
+}
+
+// expire moves a file past its maximum retention to the trash
+func (s *retentionService) expire(ctx context.Context, policy *model.RetentionPolicy, file *model.File) error {
+	audience := s.audience(ctx, file)
+	if err := s.fileRepo.Delete(ctx, file); err != nil {
+		s.logger.Error("Error trashing expired file", zap.Uint("file_id", file.ID), util.WithError(err))
+		return fmt.Errorf("error trashing expired file: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditRetentionExpire,
+		TargetType: model.AuditTargetFile,
+		TargetID:   auditRef(file.ID),
+		Metadata:   model.JSONMap{"file_name": file.FileName, "owner_id": file.UserID, "policy_id": policy.ID, "max_days": policy.MaxDays},
+	})
+	s.events.Publish(ctx, newEvent(model.EventFileDeleted, 0, file, audience...))
+	return nil
+}
+
+// audience returns the users who can see a file
+func (s *retentionService) audience(ctx context.Context, file *model.File) []uint {
+	audience, err := s.permissionRepo.FileAudience(ctx, file.ID)
+	if err != nil {
+		s.logger.Error("Error resolving file audience", zap.Uint("file_id", file.ID), util.WithError(err))
+		return fileOwners(file)
+	}
+	return audience
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"drive/internal/encryption"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/scanner"
+	"drive/internal/storage"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// scanRequeueBatch bounds the files queued by one Requeue
+const scanRequeueBatch = 500
+
+// ScanConfig holds the limits applied to malware scanning
+type ScanConfig struct {
+	Timeout time.Duration
+	// MaxFileSize is the largest file in bytes that is scanned; larger files
+	// are marked skipped
+	MaxFileSize int64
+	// FailClosed withholds files still pending a scan, including while the
+	// scanner is unreachable, instead of serving them
+	FailClosed bool
+}
+
+// ScanService scans uploaded files for malware in the background and
+// quarantines infected ones. Files are scanned by JobScanFile jobs.
+type ScanService interface {
+	// Enabled reports whether a scanner is configured. New files are only
+	// marked pending when it is.
+	Enabled() bool
+	// Enqueue queues a job to scan a file. Files whose job could not be
+	// queued stay pending until Requeue picks them up.
+	Enqueue(ctx context.Context, fileID uint)
+	// Scan checks a file and records the verdict. It is the handler of
+	// JobScanFile.
+	Scan(ctx context.Context, fileID uint) error
+	// Requeue queues jobs for files still waiting to be scanned and returns
+	// how many it queued
+	Requeue(ctx context.Context) (int, error)
+	// Check reports whether a file's content may be served: it returns
+	// ErrFileQuarantined for infected files and, when failing closed,
+	// ErrFileNotScanned for files still pending a scan
+	Check(file *model.File) error
+}
+
+type scanService struct {
+	fileRepo repository.FileRepository
+	storage  storage.Storage
+	keys     *encryption.Keyring
+	scanner  scanner.Scanner
+	audit    AuditService
+	events   EventPublisher
+	jobs     JobService
+	config   ScanConfig
+	logger   *util.Logger
+}
+
+// NewScanService creates a new ScanService instance. A nil scanner disables
+// scanning.
+func NewScanService(
+	fileRepo repository.FileRepository,
+	storage storage.Storage,
+	keys *encryption.Keyring,
+	scanner scanner.Scanner,
+	audit AuditService,
+	events EventPublisher,
+	jobs JobService,
+	config ScanConfig,
+	logger *util.Logger,
+) ScanService {
+	return &scanService{
+		fileRepo: fileRepo,
+		storage:  storage,
+		keys:     keys,
+		scanner:  scanner,
+		audit:    audit,
+		events:   events,
+		jobs:     jobs,
+		config:   config,
+		logger:   logger,
+	}
+}
+
+// Enabled reports whether a scanner is configured
+func (s *scanService) Enabled() bool {
+	return s.scanner != nil
+}
+
+// Check reports whether a file's content may be served
+func (s *scanService) Check(file *model.File) error {
+	if file.Quarantined() {
+		return ErrFileQuarantined
+	}
+	if s.config.FailClosed && file.ScanStatus == model.ScanStatusPending {
+		return ErrFileNotScanned
+	}
+	return nil
+}
+
+// Enqueue queues a job to scan a file
+func (s *scanService) Enqueue(ctx context.Context, fileID uint) {
+	if s.scanner == nil {
+		return
+	}
+	// Queue even if the request was cancelled after the file was stored
+	ctx = context.WithoutCancel(ctx)
+	opts := &JobOptions{UniqueKey: fmt.Sprintf("%s:%d", model.JobScanFile, fileID)}
+	if _, err := s.jobs.Enqueue(ctx, model.JobScanFile, model.FileJob{FileID: fileID}, opts); err != nil {
+		s.logger.Warn("Error queueing file for scanning, deferring it to the next requeue", zap.Uint("file_id", fileID), util.WithError(err))
+	}
+}
+
+// Requeue queues jobs for files still waiting to be scanned, covering
+// files whose job could not be queued or ran out of attempts while the
+// scanner was unreachable
+func (s *scanService) Requeue(ctx context.Context) (int, error) {
+	if s.scanner == nil {
+		return 0, nil
+	}
+	ids, err := s.fileRepo.ListPendingScanIDs(ctx, scanRequeueBatch)
+	if err != nil {
+		return 0, fmt.Errorf("error listing files pending scan: %w", err)
+	}
+	for _, id := range ids {
+		s.Enqueue(ctx, id)
+	}
+	return len(ids), nil
+}
+
+// Scan checks a single file and records the verdict. An unreachable
+// scanner is returned as an error, so the job is retried; meanwhile the
+// file stays pending, which Check serves or withholds depending on
+// FailClosed.
+func (s *scanService) Scan(ctx context.Context, fileID uint) error {
+	if s.scanner == nil {
+		return nil
+	}
+	logger := s.logger.With(zap.Uint("file_id", fileID))
+
+	file, err := s.fileRepo.FindByID(ctx, fileID)
+	if err != nil {
+		return fmt.Errorf("error loading file for scanning: %w", err)
+	}
+	if file == nil || file.ScanStatus != model.ScanStatusPending {
+		return nil
+	}
+
+	var result *scanner.Result
+	if s.config.MaxFileSize > 0 && file.FileSize > s.config.MaxFileSize {
+		file.ScanStatus = model.ScanStatusSkipped
+	} else {
+		result, err = s.run(ctx, file)
+		switch {
+		case err == nil && result.Infected:
+			file.ScanStatus = model.ScanStatusInfected
+			file.ScanSignature = result.Signature
+		case err == nil:
+			file.ScanStatus = model.ScanStatusClean
+		case errors.Is(err, scanner.ErrScanFailed):
+			logger.Warn("Scanner could not check file", util.WithError(err))
+			file.ScanStatus = model.ScanStatusFailed
+		default:
+			// Unreachable scanner or shutting down; the file stays pending
+			return fmt.Errorf("error scanning file: %w", err)
+		}
+	}
+
+	now := time.Now()
+	file.ScannedAt = &now
+	saved, err := s.fileRepo.UpdateScanResult(ctx, file)
+	if err != nil {
+		return fmt.Errorf("error saving scan result: %w", err)
+	}
+	if !saved {
+		// The content was replaced meanwhile and awaits its own scan
+		return nil
+	}
+
+	if file.Quarantined() {
+		s.quarantined(ctx, file)
+	}
+	logger.Debug("File scanned", zap.String("status", string(file.ScanStatus)))
+	return nil
+}
+
+// run streams a file's content through the scanner
+func (s *scanService) run(ctx context.Context, file *model.File) (*scanner.Result, error) {
+	if s.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.config.Timeout)
+		defer cancel()
+	}
+
+	blob, err := openBlob(ctx, s.storage, s.keys, file)
+	if err != nil {
+		return nil, fmt.Errorf("error opening blob: %w", err)
+	}
+	defer blob.Close()
+
+	return s.scanner.Scan(ctx, blob)
+}
+
+// quarantined records an infected file and tells its owner
+func (s *scanService) quarantined(ctx context.Context, file *model.File) {
+	s.logger.Warn("Infected file quarantined",
+		zap.Uint("file_id", file.ID),
+		util.WithUserID(file.UserID),
+		zap.String("signature", file.ScanSignature),
+	)
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditFileQuarantine,
+		TargetType: model.AuditTargetFile,
+		TargetID:   auditRef(file.ID),
+		Metadata: model.JSONMap{
+			"file_name": file.FileName,
+			"owner_id":  file.UserID,
+			"signature": file.ScanSignature,
+			"scanner":   s.scanner.Name(),
+		},
+	})
+	s.events.Publish(ctx, newEvent(model.EventFileQuarantined, 0, file, file.UserID))
+}
@@ -0,0 +1,258 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/scanner"
+	"drive/internal/scanner/scannertest"
+	"drive/internal/service"
+	"drive/internal/storage"
+	"drive/internal/util"
+)
+
+const (
+	eicar   = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+	ownerID = 3
+)
+
+// scanFiles holds the files being scanned and records saved verdicts
+type scanFiles struct {
+	repository.FileRepository
+
+	mu    sync.Mutex
+	files map[uint]*model.File
+	saved int
+}
+
+func (r *scanFiles) FindByID(ctx context.Context, id uint) (*model.File, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	file, ok := r.files[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *file
+	return &copied, nil
+}
+
+func (r *scanFiles) UpdateScanResult(ctx context.Context, file *model.File) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	copied := *file
+	r.files[file.ID] = &copied
+	r.saved++
+	return true, nil
+}
+
+type recordingAudit struct {
+	service.AuditService
+
+	mu      sync.Mutex
+	entries []*model.AuditLog
+}
+
+func (a *recordingAudit) Record(ctx context.Context, entry *model.AuditLog) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.entries = append(a.entries, entry)
+}
+
+type recordingEvents struct {
+	mu     sync.Mutex
+	events []*model.Event
+}
+
+func (e *recordingEvents) Publish(ctx context.Context, event *model.Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, event)
+}
+
+type scanFixture struct {
+	scans  service.ScanService
+	files  *scanFiles
+	audit  *recordingAudit
+	events *recordingEvents
+}
+
+// newScanFixture stores content as file 1, pending a scan by the clamd at
+// address
+func newScanFixture(t *testing.T, address, content string, config service.ScanConfig) *scanFixture {
+	t.Helper()
+	store, err := storage.NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Put(context.Background(), "blob", strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	clamd, err := scanner.NewClamd(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f := &scanFixture{
+		files: &scanFiles{files: map[uint]*model.File{
+			1: {ID: 1, FileName: "upload.exe", FileSize: int64(len(content)), FileURL: "blob", UserID: ownerID, ScanStatus: model.ScanStatusPending},
+		}},
+		audit:  &recordingAudit{},
+		events: &recordingEvents{},
+	}
+	f.scans = service.NewScanService(f.files, store, nil, clamd, f.audit, f.events, nil, config, &util.Logger{Logger: zap.NewNop()})
+	return f
+}
+
+func (f *scanFixture) file(t *testing.T) *model.File {
+	t.Helper()
+	file, _ := f.files.FindByID(context.Background(), 1)
+	return file
+}
+
+func TestScanVerdicts(t *testing.T) {
+	tests := []struct {
+		name      string
+		reply     scannertest.Reply
+		content   string
+		status    model.ScanStatus
+		signature string
+		check     error
+	}{
+		{name: "clean", reply: scannertest.OK, content: "hello", status: model.ScanStatusClean},
+		{name: "infected", reply: scannertest.Found("EICAR", "Eicar-Test-Signature"), content: eicar, status: model.ScanStatusInfected, signature: "Eicar-Test-Signature", check: service.ErrFileQuarantined},
+		{name: "no verdict", reply: scannertest.Error, content: "hello", status: model.ScanStatusFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clamd, err := scannertest.NewClamd(tt.reply)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer clamd.Close()
+			f := newScanFixture(t, clamd.Address(), tt.content, service.ScanConfig{Timeout: time.Minute, FailClosed: true})
+
+			if err := f.scans.Scan(context.Background(), 1); err != nil {
+				t.Fatal(err)
+			}
+			file := f.file(t)
+			if file.ScanStatus != tt.status || file.ScanSignature != tt.signature {
+				t.Errorf("scan status = %s %q, want %s %q", file.ScanStatus, file.ScanSignature, tt.status, tt.signature)
+			}
+			if file.ScannedAt == nil {
+				t.Error("scan time not recorded")
+			}
+			if err := f.scans.Check(file); !errors.Is(err, tt.check) {
+				t.Errorf("Check = %v, want %v", err, tt.check)
+			}
+		})
+	}
+}
+
+func TestScanQuarantinesInfectedUpload(t *testing.T) {
+	clamd, err := scannertest.NewClamd(scannertest.Found("EICAR", "Eicar-Test-Signature"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clamd.Close()
+	f := newScanFixture(t, clamd.Address(), "zip header "+eicar, service.ScanConfig{})
+
+	if err := f.scans.Scan(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+
+	file := f.file(t)
+	if !file.Quarantined() {
+		t.Fatalf("file is %s, want it quarantined", file.ScanStatus)
+	}
+	if err := f.scans.Check(file); !errors.Is(err, service.ErrFileQuarantined) {
+		t.Errorf("Check = %v, want %v", err, service.ErrFileQuarantined)
+	}
+	if len(f.audit.entries) != 1 || f.audit.entries[0].Action != model.AuditFileQuarantine {
+		t.Errorf("audit entries = %+v, want one %s", f.audit.entries, model.AuditFileQuarantine)
+	} else if got := f.audit.entries[0].Metadata["signature"]; got != "Eicar-Test-Signature" {
+		t.Errorf("audited signature = %v", got)
+	}
+	if len(f.events.events) != 1 || f.events.events[0].Type != model.EventFileQuarantined {
+		t.Fatalf("events = %+v, want one %s", f.events.events, model.EventFileQuarantined)
+	}
+	if audience := f.events.events[0].Audience; len(audience) != 1 || audience[0] != ownerID {
+		t.Errorf("event audience = %v, want the owner", audience)
+	}
+
+	// A finished scan is not repeated
+	if err := f.scans.Scan(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(clamd.Streams()); got != 1 {
+		t.Errorf("clamd scanned the file %d times", got)
+	}
+}
+
+func TestScanUnavailable(t *testing.T) {
+	tests := []struct {
+		name       string
+		reply      scannertest.Reply
+		down       bool
+		failClosed bool
+		check      error
+	}{
+		{name: "down, failing open", reply: scannertest.OK, down: true},
+		{name: "down, failing closed", reply: scannertest.OK, down: true, failClosed: true, check: service.ErrFileNotScanned},
+		{name: "hung, failing open", reply: scannertest.Hang},
+		{name: "hung, failing closed", reply: scannertest.Hang, failClosed: true, check: service.ErrFileNotScanned},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clamd, err := scannertest.NewClamd(tt.reply)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer clamd.Close()
+			if tt.down {
+				clamd.Close()
+			}
+			f := newScanFixture(t, clamd.Address(), eicar, service.ScanConfig{Timeout: 100 * time.Millisecond, FailClosed: tt.failClosed})
+
+			// The error makes the job queue retry the scan later
+			if err := f.scans.Scan(context.Background(), 1); err == nil {
+				t.Fatal("scan succeeded without clamd")
+			}
+			file := f.file(t)
+			if file.ScanStatus != model.ScanStatusPending || f.files.saved != 0 {
+				t.Errorf("scan status = %s after %d saves, want it left pending", file.ScanStatus, f.files.saved)
+			}
+			if err := f.scans.Check(file); !errors.Is(err, tt.check) {
+				t.Errorf("Check = %v, want %v", err, tt.check)
+			}
+		})
+	}
+}
+
+func TestScanFailClosedServesAfterVerdict(t *testing.T) {
+	clamd, err := scannertest.NewClamd(scannertest.OK)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer clamd.Close()
+	f := newScanFixture(t, clamd.Address(), "hello", service.ScanConfig{FailClosed: true})
+
+	if err := f.scans.Check(f.file(t)); !errors.Is(err, service.ErrFileNotScanned) {
+		t.Fatalf("Check before the scan = %v, want %v", err, service.ErrFileNotScanned)
+	}
+	if err := f.scans.Scan(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.scans.Check(f.file(t)); err != nil {
+		t.Errorf("Check after a clean scan = %v", err)
+	}
+}
@@ -0,0 +1,285 @@
+package service
+
+import (
+	"context"
+	"drive/internal/cron"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// taskRunRetention is how long the run history is kept
+	taskRunRetention = 90 * 24 * time.Hour
+
+	defaultTaskRunPerPage = 20
+)
+
+var ErrTaskExists = errors.New("a task with this name is already registered")
+
+// errTaskInterrupted is recorded on runs cut short by a shutdown or crash
+var errTaskInterrupted = errors.New("interrupted before it finished")
+
+// TaskFunc runs a scheduled task and summarises what it did
+type TaskFunc func(ctx context.Context) (string, error)
+
+// reportTask adapts a task that returns a report into a TaskFunc
+func reportTask[T fmt.Stringer](run func(ctx context.Context) (T, error)) TaskFunc {
+	return func(ctx context.Context) (string, error) {
+		report, err := run(ctx)
+		if err != nil {
+			return "", err
+		}
+		return report.String(), nil
+	}
+}
+
+// SchedulerService runs recurring tasks on cron schedules. Every server
+// instance keeps the schedule, but a Postgres advisory lock per task and a
+// unique run per scheduled time make sure each run happens on one instance
+// only. Schedules are evaluated in UTC and missed runs are not caught up.
+type SchedulerService interface {
+	// Register adds a task with a cron expression. Tasks are registered
+	// before Start.
+	Register(name, schedule string, task TaskFunc) error
+	// Tasks lists the registered tasks with their next and most recent runs
+	Tasks(ctx context.Context) ([]model.ScheduledTask, error)
+	// Runs returns a page of the run history, newest first
+	Runs(ctx context.Context, filter *model.TaskRunFilter) ([]model.TaskRun, int64, error)
+	// Start launches the scheduler
+	Start(ctx context.Context)
+	// Stop interrupts running tasks and waits for them to exit
+	Stop()
+}
+
+// scheduledTask is a registered task
+type scheduledTask struct {
+	name     string
+	schedule *cron.Schedule
+	run      TaskFunc
+	lockKey  int64
+}
+
+type schedulerService struct {
+	taskRepo repository.TaskRepository
+	instance string
+	logger   *util.Logger
+
+	tasks  []*scheduledTask
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewSchedulerService creates a new SchedulerService instance
+func NewSchedulerService(taskRepo repository.TaskRepository, logger *util.Logger) SchedulerService {
+	instance, err := os.Hostname()
+	if err != nil {
+		instance = "unknown"
+	}
+	return &schedulerService{
+		taskRepo: taskRepo,
+		instance: fmt.Sprintf("%s:%d", instance, os.Getpid()),
+		logger:   logger,
+	}
+}
+
+// Register adds a task
+func (s *schedulerService) Register(name, schedule string, task TaskFunc) error {
+	for _, t := range s.tasks {
+		if t.name == name {
+			return fmt.Errorf("%w: %s", ErrTaskExists, name)
+		}
+	}
+	parsed, err := cron.Parse(schedule)
+	if err != nil {
+		return fmt.Errorf("task %s: %w", name, err)
+	}
+	s.tasks = append(s.tasks, &scheduledTask{
+		name:     name,
+		schedule: parsed,
+		run:      task,
+		lockKey:  taskLockKey(name),
+	})
+	return nil
+}
+
+// Tasks lists the registered tasks
+func (s *schedulerService) Tasks(ctx context.Context) ([]model.ScheduledTask, error) {
+	last, err := s.taskRepo.LastRuns(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error finding last runs: %w", err)
+	}
+
+	now := time.Now().UTC()
+	tasks := make([]model.ScheduledTask, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		tasks = append(tasks, model.ScheduledTask{
+			Name:      t.name,
+			Schedule:  t.schedule.String(),
+			NextRunAt: t.schedule.Next(now),
+			LastRun:   last[t.name],
+		})
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Name < tasks[j].Name })
+	return tasks, nil
+}
+
+// Runs returns a page of the run history
+func (s *schedulerService) Runs(ctx context.Context, filter *model.TaskRunFilter) ([]model.TaskRun, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 {
+		filter.PerPage = defaultTaskRunPerPage
+	}
+
+	runs, total, err := s.taskRepo.ListRuns(ctx, filter)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error listing task runs: %w", err)
+	}
+	return runs, total, nil
+}
+
+// Start launches one loop per task
+func (s *schedulerService) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+
+	names := make([]string, len(s.tasks))
+	for i, t := range s.tasks {
+		names[i] = t.name
+		s.wg.Add(1)
+		go func(t *scheduledTask) {
+			defer s.wg.Done()
+			s.loop(ctx, t)
+		}(t)
+	}
+
+	s.logger.Info("Scheduler started", zap.String("instance", s.instance), zap.Strings("tasks", names))
+}
+
+// Stop interrupts running tasks and waits for them to exit
+func (s *schedulerService) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+	s.logger.Info("Scheduler stopped")
+}
+
+// loop waits for each scheduled time of a task and runs it. A run that
+// overlaps the next scheduled time delays it rather than running twice.
+func (s *schedulerService) loop(ctx context.Context, t *scheduledTask) {
+	for {
+		next := t.schedule.Next(time.Now().UTC())
+		if next.IsZero() {
+			s.logger.Warn("Task schedule never fires", zap.String("task", t.name), zap.String("schedule", t.schedule.String()))
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		s.runScheduled(ctx, t, next)
+	}
+}
+
+// runScheduled runs a task for one scheduled time unless another instance
+// is running it or already has
+func (s *schedulerService) runScheduled(ctx context.Context, t *scheduledTask, scheduledAt time.Time) {
+	logger := s.logger.With(zap.String("task", t.name), zap.Time("scheduled_at", scheduledAt))
+
+	acquired, err := s.taskRepo.WithLock(ctx, t.lockKey, func(ctx context.Context) error {
+		// Holding the lock, any run still marked running was cut short
+		if n, err := s.taskRepo.FailRunning(ctx, t.name, errTaskInterrupted.Error()); err != nil {
+			return fmt.Errorf("error failing interrupted runs: %w", err)
+		} else if n > 0 {
+			logger.Warn("Marked interrupted task runs as failed", zap.Int64("runs", n))
+		}
+
+		run := &model.TaskRun{
+			Task:        t.name,
+			ScheduledAt: scheduledAt,
+			Status:      model.TaskRunRunning,
+			Instance:    s.instance,
+			StartedAt:   time.Now(),
+		}
+		started, err := s.taskRepo.StartRun(ctx, run)
+		if err != nil {
+			return fmt.Errorf("error recording task run: %w", err)
+		}
+		if !started {
+			logger.Debug("Task already ran on another instance")
+			return nil
+		}
+
+		s.execute(ctx, t, run)
+
+		if _, err := s.taskRepo.DeleteRunsBefore(ctx, time.Now().Add(-taskRunRetention)); err != nil && ctx.Err() == nil {
+			logger.Error("Error removing old task runs", util.WithError(err))
+		}
+		return nil
+	})
+	switch {
+	case err != nil && ctx.Err() == nil:
+		logger.Error("Error running task", util.WithError(err))
+	case err == nil && !acquired:
+		logger.Debug("Task is running on another instance")
+	}
+}
+
+// execute runs a task and records the outcome. Panics are recorded as
+// failures so one broken task cannot take the scheduler down.
+func (s *schedulerService) execute(ctx context.Context, t *scheduledTask, run *model.TaskRun) {
+	logger := s.logger.With(zap.String("task", t.name))
+
+	result, err := func() (result string, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("task panicked: %v", r)
+			}
+		}()
+		return t.run(ctx)
+	}()
+
+	now := time.Now()
+	run.FinishedAt = &now
+	run.DurationMS = now.Sub(run.StartedAt).Milliseconds()
+	run.Result = result
+	switch {
+	case err == nil:
+		run.Status = model.TaskRunSucceeded
+		logger.Info("Task finished", zap.String("result", result), zap.Int64("duration_ms", run.DurationMS))
+	case ctx.Err() != nil:
+		run.Status = model.TaskRunFailed
+		run.Error = errTaskInterrupted.Error()
+	default:
+		run.Status = model.TaskRunFailed
+		run.Error = err.Error()
+		logger.Error("Task failed", zap.Int64("duration_ms", run.DurationMS), util.WithError(err))
+	}
+
+	if err := s.taskRepo.FinishRun(context.WithoutCancel(ctx), run); err != nil {
+		logger.Error("Error saving task run", util.WithError(err))
+	}
+}
+
+// taskLockKey derives a task's advisory lock key from its name
+func taskLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("drive:task:" + name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	defaultSearchPerPage = 20
+	maxSearchPerPage     = 100
+)
+
+var (
+	// ErrInvalidSearchFilter indicates that the search filters are inconsistent
+	ErrInvalidSearchFilter = errors.New("invalid search filter")
+)
+
+// SearchService defines search operations across the drive
+type SearchService interface {
+	// Search returns files and folders visible to the user matching the request
+	Search(ctx context.Context, userID uint, req *model.SearchRequest) ([]model.SearchResult, int64, error)
+}
+
+type searchService struct {
+	searchRepo repository.SearchRepository
+	logger     *util.Logger
+}
+
+// NewSearchService creates a new SearchService instance
+func NewSearchService(searchRepo repository.SearchRepository, logger *util.Logger) SearchService {
+	return &searchService{
+		searchRepo: searchRepo,
+		logger:     logger,
+	}
+}
+
+// Search returns files and folders visible to the user matching the request
+func (s *searchService) Search(ctx context.Context, userID uint, req *model.SearchRequest) ([]model.SearchResult, int64, error) {
+	if err := normalizeSearchRequest(req); err != nil {
+		return nil, 0, err
+	}
+
+	results, total, err := s.searchRepo.Search(ctx, userID, req)
+	if err != nil {
+		s.logger.Error("Error searching drive", util.WithUserID(userID), util.WithError(err))
+		return nil, 0, fmt.Errorf("error searching drive: %w", err)
+	}
+
+	return results, total, nil
+}
+
+// normalizeSearchRequest applies pagination defaults and checks that ranges
+// are well formed
+func normalizeSearchRequest(req *model.SearchRequest) error {
+	if req.Page < 1 {
+		req.Page = 1
+	}
+	if req.PerPage < 1 {
+		req.PerPage = defaultSearchPerPage
+	}
+	if req.PerPage > maxSearchPerPage {
+		req.PerPage = maxSearchPerPage
+	}
+
+	if req.MaxSize > 0 && req.MinSize > req.MaxSize {
+		return fmt.Errorf("%w: min_size is greater than max_size", ErrInvalidSearchFilter)
+	}
+
+	var from, to time.Time
+	var err error
+	if req.From != "" {
+		if from, err = time.Parse(time.DateOnly, req.From); err != nil {
+			return fmt.Errorf("%w: invalid from date", ErrInvalidSearchFilter)
+		}
+	}
+	if req.To != "" {
+		if to, err = time.Parse(time.DateOnly, req.To); err != nil {
+			return fmt.Errorf("%w: invalid to date", ErrInvalidSearchFilter)
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && from.After(to) {
+		return fmt.Errorf("%w: from date is after to date", ErrInvalidSearchFilter)
+	}
+
+	return nil
+}
@@ -1,18 +1,103 @@
 package service
 
 import (
+	"context"
+	"drive/internal/auditchain"
 	"drive/internal/config"
+	"drive/internal/encryption"
+	"drive/internal/extractor"
+	"drive/internal/model"
+	"drive/internal/preview"
 	"drive/internal/repository"
+	"drive/internal/scanner"
+	"drive/internal/storage"
 	"drive/internal/util"
 )
 
 type Services struct {
-	Auth  AuthService
-	OAuth OAuthService
+	Auth        AuthService
+	OAuth       OAuthService
+	File        FileService
+	Folder      FolderService
+	Share       ShareService
+	Indexer     IndexerService
+	Scan        ScanService
+	Preview     PreviewService
+	Search      SearchService
+	Audit       AuditService
+	Webhook     WebhookService
+	Events      EventBus
+	Changes     ChangeService
+	AppPassword AppPasswordService
+	Vault       VaultService
+	Lock        LockService
+	Comment     CommentService
+	Photo       PhotoService
+	Album       AlbumService
+	Insights    InsightsService
+	Hash        HashService
+	Star        StarService
+	Batch       BatchService
+	Jobs        JobService
+	Scheduler   SchedulerService
+	Integrity   IntegrityService
+	Retention   RetentionService
+	Workspace   WorkspaceService
+	Group       GroupService
 }
 
-func NewServices(repos repository.Repositories, jwtSvc *util.JwtService, logger *util.Logger, cfg *config.Config) *Services {
-	authService := NewAuthService(repos.User, jwtSvc, logger)
+func NewServices(repos repository.Repositories, store storage.Storage, jwtSvc *util.JwtService, logger *util.Logger, cfg *config.Config) (*Services, error) {
+	signingKey, err := auditchain.ParseSigningKey(cfg.Audit.SigningKey)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := encryption.ParseKeyring(cfg.Storage.EncryptionKeys, cfg.Storage.EncryptionKeyID)
+	if err != nil {
+		return nil, err
+	}
+	auditService := NewAuditService(repos.Audit, AuditConfig{
+		HMACKey:            []byte(cfg.Audit.HMACKey),
+		SigningKey:         signingKey,
+		CheckpointInterval: cfg.Audit.CheckpointInterval,
+	}, logger)
+	jobService := NewJobService(repos.Job, JobConfig{
+		Workers:      cfg.Jobs.Workers,
+		PollInterval: cfg.Jobs.PollInterval,
+		Timeout:      cfg.Jobs.Timeout,
+		MaxAttempts:  cfg.Jobs.MaxAttempts,
+	}, logger)
+	webhookService := NewWebhookService(repos.Webhook, jobService, WebhookConfig{
+		Timeout:              cfg.Webhook.Timeout,
+		MaxAttempts:          cfg.Webhook.MaxAttempts,
+		AllowPrivateNetworks: cfg.Webhook.AllowPrivateNetworks,
+	}, logger)
+	changeService := NewChangeService(repos.Change, repos.File, repos.Folder, repos.Permission, logger)
+	eventBus := NewEventBus(logger, changeService, webhookService)
+	authService := NewAuthService(repos.User, jwtSvc, auditService, eventBus, logger)
+
+	indexerService := NewIndexerService(repos.File, repos.FileContent, repos.Photo, store, keys, extractor.NewDefaultRegistry(), jobService, IndexerConfig{
+		Timeout:            cfg.Indexer.Timeout,
+		MaxFileSize:        cfg.Indexer.MaxFileSize,
+		MaxTextSize:        cfg.Indexer.MaxTextSize,
+		StripPhotoLocation: cfg.Photos.StripLocation,
+	}, logger)
+
+	var fileScanner scanner.Scanner
+	if cfg.Scanner.ClamdAddress != "" {
+		clamd, err := scanner.NewClamd(cfg.Scanner.ClamdAddress)
+		if err != nil {
+			return nil, err
+		}
+		fileScanner = clamd
+	}
+	scanService := NewScanService(repos.File, store, keys, fileScanner, auditService, eventBus, jobService, ScanConfig{
+		Timeout:     cfg.Scanner.Timeout,
+		MaxFileSize: cfg.Scanner.MaxFileSize,
+		FailClosed:  cfg.Scanner.FailClosed,
+	}, logger)
+
+	lockService := NewLockService(repos.Lock, repos.Permission, auditService, logger)
+	retentionService := NewRetentionService(repos.Retention, repos.File, repos.Folder, repos.User, repos.Permission, auditService, eventBus, logger)
 
 	// Create OAuth configs
 	googleConfig := &GoogleOAuthConfig{
@@ -25,8 +110,93 @@ func NewServices(repos repository.Repositories, jwtSvc *util.JwtService, logger
 		AppSecret: cfg.OAuth.FacebookAppSecret,
 	}
 
-	return &Services{
-		Auth:  authService,
-		OAuth: NewOAuthService(repos.User, jwtSvc, googleConfig, facebookConfig, logger, authService),
+	fileService := NewFileService(repos.File, repos.Folder, repos.User, repos.Workspace, repos.Permission, store, keys, indexerService, scanService, lockService, retentionService, auditService, eventBus, cfg.Storage.MaxUploadSize, logger)
+
+	previewService := NewPreviewService(fileService, scanService, repos.Folder, store, keys, preview.NewDefaultRegistry(), PreviewConfig{
+		MaxFileSize: cfg.Preview.MaxFileSize,
+		Timeout:     cfg.Preview.Timeout,
+	}, logger)
+
+	folderService := NewFolderService(repos.Folder, repos.File, repos.Vault, repos.Workspace, repos.Permission, fileService, lockService, retentionService, auditService, eventBus, logger)
+	shareService := NewShareService(repos.Share, repos.File, repos.Folder, repos.User, repos.Group, repos.Vault, repos.Permission, auditService, eventBus, logger)
+	starService := NewStarService(repos.Star, repos.Permission, logger)
+	tagService := NewTagService(repos.Tag, repos.Permission, logger)
+	maintenanceService := NewMaintenanceService(repos.Maintenance, repos.Lock, repos.User, repos.Workspace, retentionService, store, auditService, jobService, MaintenanceConfig{
+		TrashRetention: cfg.Maintenance.TrashRetention,
+		StaleUploadAge: cfg.Maintenance.StaleUploadAge,
+	}, logger)
+	batchService := NewBatchService(repos.Batch, repos.Folder, fileService, folderService, shareService, starService, tagService, maintenanceService, logger)
+
+	integrityService := NewIntegrityService(repos.File, repos.Maintenance, store, keys, logger)
+	schedulerService := NewSchedulerService(repos.Task, logger)
+	maintenanceTasks := []struct {
+		name, schedule string
+		run            TaskFunc
+	}{
+		{"trash_purge", cfg.Maintenance.TrashPurgeSchedule, maintenanceService.PurgeTrash},
+		{"expired_locks", cfg.Maintenance.ExpiredLocksSchedule, maintenanceService.RemoveExpiredLocks},
+		{"quota_reconcile", cfg.Maintenance.QuotaReconcileSchedule, maintenanceService.ReconcileQuotas},
+		{"stale_uploads", cfg.Maintenance.StaleUploadsSchedule, maintenanceService.RemoveStaleUploads},
+		{"blob_gc", cfg.Maintenance.BlobGCSchedule, reportTask(func(ctx context.Context) (*model.GarbageReport, error) {
+			return integrityService.CollectGarbage(ctx, GarbageOptions{GracePeriod: cfg.Maintenance.BlobGCGrace})
+		})},
+		{"integrity_scrub", cfg.Maintenance.ScrubSchedule, reportTask(func(ctx context.Context) (*model.ScrubReport, error) {
+			return integrityService.Scrub(ctx, ScrubOptions{Interval: cfg.Maintenance.ScrubInterval, MaxBytes: cfg.Maintenance.ScrubMaxBytes})
+		})},
+		{"retention_enforce", cfg.Maintenance.RetentionSchedule, retentionService.Enforce},
+		{"requeue_pending", cfg.Maintenance.RequeueSchedule, requeueTask(indexerService, scanService, webhookService)},
+	}
+	for _, task := range maintenanceTasks {
+		if task.schedule == "" {
+			continue
+		}
+		if err := schedulerService.Register(task.name, task.schedule, task.run); err != nil {
+			return nil, err
+		}
 	}
+
+	RegisterJob(jobService, model.JobIndexFile, func(ctx context.Context, job model.FileJob) error {
+		return indexerService.Index(ctx, job.FileID)
+	})
+	RegisterJob(jobService, model.JobScanFile, func(ctx context.Context, job model.FileJob) error {
+		return scanService.Scan(ctx, job.FileID)
+	})
+	RegisterJob(jobService, model.JobDeliverWebhook, func(ctx context.Context, job model.DeliveryJob) error {
+		return webhookService.Deliver(ctx, job.DeliveryID)
+	})
+	RegisterJob(jobService, model.JobDeleteBlob, func(ctx context.Context, job model.BlobJob) error {
+		return maintenanceService.DeleteBlob(ctx, job.Key)
+	})
+
+	return &Services{
+		Auth:        authService,
+		OAuth:       NewOAuthService(repos.User, jwtSvc, googleConfig, facebookConfig, logger, authService, auditService, eventBus),
+		File:        fileService,
+		Folder:      folderService,
+		Share:       shareService,
+		Indexer:     indexerService,
+		Scan:        scanService,
+		Preview:     previewService,
+		Search:      NewSearchService(repos.Search, logger),
+		Audit:       auditService,
+		Webhook:     webhookService,
+		Events:      eventBus,
+		Changes:     changeService,
+		AppPassword: NewAppPasswordService(repos.AppPassword, repos.User, auditService, logger),
+		Vault:       NewVaultService(repos.Vault, repos.Folder, repos.User, repos.Permission, logger),
+		Lock:        lockService,
+		Comment:     NewCommentService(repos.Comment, repos.User, repos.Permission, eventBus, logger),
+		Photo:       NewPhotoService(repos.Photo, repos.User, PhotoConfig{StripLocation: cfg.Photos.StripLocation}, logger),
+		Album:       NewAlbumService(repos.Album, logger),
+		Insights:    NewInsightsService(repos.Insights, repos.File, repos.User, fileService, logger),
+		Hash:        NewHashService(repos.File, store, keys, logger),
+		Star:        starService,
+		Batch:       batchService,
+		Jobs:        jobService,
+		Scheduler:   schedulerService,
+		Integrity:   integrityService,
+		Retention:   retentionService,
+		Workspace:   NewWorkspaceService(repos.Workspace, repos.Folder, repos.User, repos.Permission, lockService, retentionService, auditService, eventBus, logger),
+		Group:       NewGroupService(repos.Group, repos.User, repos.Share, auditService, eventBus, logger),
+	}, nil
 }
@@ -0,0 +1,392 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrShareNotFound      = errors.New("share not found")
+	ErrShareAlreadyExists = errors.New("item is already shared with this user or group")
+	ErrShareWithSelf      = errors.New("cannot share an item with yourself")
+	ErrShareRecipient     = errors.New("an item is shared with either a user or a group")
+	ErrGroupShareInVault  = errors.New("items in a vault cannot be shared with a group")
+)
+
+// ShareService manages sharing files and folders with other users and groups
+type ShareService interface {
+	// Create shares a file or folder the user owns with another user or a
+	// group they can see
+	Create(ctx context.Context, userID uint, req *model.CreateShareRequest) (*model.Share, error)
+	// ListForItem lists the shares of a file or folder the user owns
+	ListForItem(ctx context.Context, userID, fileID, folderID uint) ([]model.Share, error)
+	// ListReceived lists the shares granted to the user, directly or
+	// through their groups
+	ListReceived(ctx context.Context, userID uint) ([]model.Share, error)
+	// UpdatePermission changes the permission granted by a share
+	UpdatePermission(ctx context.Context, userID, shareID uint, permission model.Permission) (*model.Share, error)
+	// Delete revokes a share. Both the owner and the recipient may do so;
+	// only the owner can revoke a share made with a group.
+	Delete(ctx context.Context, userID, shareID uint) error
+}
+
+type shareService struct {
+	shareRepo      repository.ShareRepository
+	fileRepo       repository.FileRepository
+	folderRepo     repository.FolderRepository
+	userRepo       repository.UserRepository
+	groupRepo      repository.GroupRepository
+	vaultRepo      repository.VaultRepository
+	permissionRepo repository.PermissionRepository
+	audit          AuditService
+	events         EventPublisher
+	logger         *util.Logger
+}
+
+// NewShareService creates a new ShareService instance
+func NewShareService(
+	shareRepo repository.ShareRepository,
+	fileRepo repository.FileRepository,
+	folderRepo repository.FolderRepository,
+	userRepo repository.UserRepository,
+	groupRepo repository.GroupRepository,
+	vaultRepo repository.VaultRepository,
+	permissionRepo repository.PermissionRepository,
+	audit AuditService,
+	events EventPublisher,
+	logger *util.Logger,
+) ShareService {
+	return &shareService{
+		shareRepo:      shareRepo,
+		fileRepo:       fileRepo,
+		folderRepo:     folderRepo,
+		userRepo:       userRepo,
+		groupRepo:      groupRepo,
+		vaultRepo:      vaultRepo,
+		permissionRepo: permissionRepo,
+		audit:          audit,
+		events:         events,
+		logger:         logger,
+	}
+}
+
+// Create shares a file or folder the user owns with another user or a
+// group. Items in a vault are only shared with users, along with the vault
+// key wrapped for the recipient.
+func (s *shareService) Create(ctx context.Context, userID uint, req *model.CreateShareRequest) (*model.Share, error) {
+	logger := s.logger.With(util.WithUserID(userID))
+
+	share := &model.Share{
+		OwnerID:    userID,
+		Permission: req.Permission,
+	}
+	var recipient *model.User
+	if req.GroupID != 0 {
+		if req.SharedWithID != 0 || req.SharedWithEmail != "" {
+			return nil, ErrShareRecipient
+		}
+		group, err := findVisibleGroup(ctx, s.groupRepo, userID, req.GroupID)
+		if err != nil {
+			return nil, err
+		}
+		share.GroupID = &group.ID
+	} else {
+		var err error
+		recipient, err = s.findRecipient(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if recipient.ID == userID {
+			return nil, ErrShareWithSelf
+		}
+		share.SharedWithID = &recipient.ID
+	}
+	targetType, targetID := model.AuditTargetFolder, req.FolderID
+	folderID := req.FolderID
+
+	if req.FileID != 0 {
+		file, err := s.fileRepo.FindByID(ctx, req.FileID)
+		if err != nil {
+			return nil, fmt.Errorf("error finding file: %w", err)
+		}
+		if file == nil {
+			return nil, ErrFileNotFound
+		}
+		if err := s.requireOwner(ctx, userID, file.ID, 0); err != nil {
+			return nil, err
+		}
+		if file.Quarantined() {
+			return nil, ErrFileQuarantined
+		}
+		share.FileID = &file.ID
+		folderID = file.FolderID
+		targetType, targetID = model.AuditTargetFile, file.ID
+	}
+
+	folder, err := s.folderRepo.FindByID(ctx, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding folder: %w", err)
+	}
+	if folder == nil {
+		return nil, ErrFolderNotFound
+	}
+	if req.FileID == 0 {
+		if err := s.requireOwner(ctx, userID, 0, folder.ID); err != nil {
+			return nil, err
+		}
+	}
+	share.FolderID = folder.ID
+
+	if folder.VaultID == nil && len(req.WrappedKey) > 0 {
+		return nil, ErrNotInVault
+	}
+	if folder.VaultID != nil && recipient == nil {
+		return nil, ErrGroupShareInVault
+	}
+	if folder.VaultID != nil && len(req.WrappedKey) == 0 {
+		return nil, ErrVaultKeyRequired
+	}
+
+	existing, err := s.shareRepo.FindExisting(ctx, share)
+	if err != nil {
+		return nil, fmt.Errorf("error checking existing share: %w", err)
+	}
+	if existing != nil {
+		return nil, ErrShareAlreadyExists
+	}
+
+	if folder.VaultID != nil {
+		// The recipient needs the vault key to read anything in the share
+		key := &model.VaultKey{VaultID: *folder.VaultID, UserID: recipient.ID, WrappedKey: req.WrappedKey}
+		if err := s.vaultRepo.SaveKey(ctx, key); err != nil {
+			logger.Error("Error saving vault key", util.WithError(err))
+			return nil, fmt.Errorf("error saving vault key: %w", err)
+		}
+	}
+	if err := s.shareRepo.Create(ctx, share); err != nil {
+		logger.Error("Error creating share", util.WithError(err))
+		return nil, fmt.Errorf("error creating share: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditShareCreate,
+		ActorID:    auditRef(userID),
+		TargetType: targetType,
+		TargetID:   auditRef(targetID),
+		Metadata: withRecipient(share, model.JSONMap{
+			"share_id":   share.ID,
+			"permission": share.Permission,
+		}),
+	})
+	s.events.Publish(ctx, newEvent(model.EventShareCreated, userID, share, s.audience(ctx, share)...))
+
+	logger.Info("Share created", zap.Uint("share_id", share.ID), zap.Uintp("shared_with_id", share.SharedWithID), zap.Uintp("group_id", share.GroupID))
+	return share, nil
+}
+
+// ListForItem lists the shares of a file or folder the user owns
+func (s *shareService) ListForItem(ctx context.Context, userID, fileID, folderID uint) ([]model.Share, error) {
+	if err := s.requireOwner(ctx, userID, fileID, folderID); err != nil {
+		return nil, err
+	}
+
+	var shares []model.Share
+	var err error
+	if fileID != 0 {
+		shares, err = s.shareRepo.ListByFile(ctx, fileID)
+	} else {
+		shares, err = s.shareRepo.ListByFolder(ctx, folderID)
+	}
+	if err != nil {
+		s.logger.Error("Error listing shares", util.WithUserID(userID), util.WithError(err))
+		return nil, fmt.Errorf("error listing shares: %w", err)
+	}
+	return shares, nil
+}
+
+// ListReceived lists the shares granted to the user
+func (s *shareService) ListReceived(ctx context.Context, userID uint) ([]model.Share, error) {
+	shares, err := s.shareRepo.ListBySharedWith(ctx, userID)
+	if err != nil {
+		s.logger.Error("Error listing received shares", util.WithUserID(userID), util.WithError(err))
+		return nil, fmt.Errorf("error listing received shares: %w", err)
+	}
+	return shares, nil
+}
+
+// UpdatePermission changes the permission granted by a share
+func (s *shareService) UpdatePermission(ctx context.Context, userID, shareID uint, permission model.Permission) (*model.Share, error) {
+	share, err := s.findShare(ctx, shareID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.requireOwner(ctx, userID, shareFileID(share), share.FolderID); err != nil {
+		return nil, err
+	}
+
+	previous := share.Permission
+	if previous == permission {
+		return share, nil
+	}
+	share.Permission = permission
+	if err := s.shareRepo.Update(ctx, share); err != nil {
+		s.logger.Error("Error updating share", util.WithUserID(userID), zap.Uint("share_id", shareID), util.WithError(err))
+		return nil, fmt.Errorf("error updating share: %w", err)
+	}
+
+	targetType, targetID := shareTarget(share)
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditSharePermission,
+		ActorID:    auditRef(userID),
+		TargetType: targetType,
+		TargetID:   auditRef(targetID),
+		Metadata: withRecipient(share, model.JSONMap{
+			"share_id":            share.ID,
+			"previous_permission": previous,
+			"permission":          permission,
+		}),
+	})
+	s.events.Publish(ctx, newEvent(model.EventShareUpdated, userID, share, s.audience(ctx, share)...))
+
+	return share, nil
+}
+
+// Delete revokes a share. Both the owner and the recipient may do so.
+// Members cannot revoke a share made with their group for everyone.
+func (s *shareService) Delete(ctx context.Context, userID, shareID uint) error {
+	share, err := s.findShare(ctx, shareID)
+	if err != nil {
+		return err
+	}
+	if share.SharedWithID == nil || *share.SharedWithID != userID {
+		if err := s.requireOwner(ctx, userID, shareFileID(share), share.FolderID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.shareRepo.Delete(ctx, share.ID); err != nil {
+		s.logger.Error("Error deleting share", util.WithUserID(userID), zap.Uint("share_id", shareID), util.WithError(err))
+		return fmt.Errorf("error deleting share: %w", err)
+	}
+
+	targetType, targetID := shareTarget(share)
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditShareDelete,
+		ActorID:    auditRef(userID),
+		TargetType: targetType,
+		TargetID:   auditRef(targetID),
+		Metadata: withRecipient(share, model.JSONMap{
+			"share_id":   share.ID,
+			"permission": share.Permission,
+		}),
+	})
+	s.events.Publish(ctx, newEvent(model.EventShareDeleted, userID, share, s.audience(ctx, share)...))
+
+	return nil
+}
+
+// findRecipient resolves the user a share is granted to
+func (s *shareService) findRecipient(ctx context.Context, req *model.CreateShareRequest) (*model.User, error) {
+	var user *model.User
+	var err error
+	if req.SharedWithID != 0 {
+		user, err = s.userRepo.FindByID(ctx, req.SharedWithID)
+	} else {
+		user, err = s.userRepo.FindByEmail(ctx, req.SharedWithEmail)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error finding user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
+
+// audience returns the users an event about a share concerns: its owner
+// and its recipient, or the members of its group
+func (s *shareService) audience(ctx context.Context, share *model.Share) []uint {
+	if share.SharedWithID != nil {
+		return []uint{share.OwnerID, *share.SharedWithID}
+	}
+	audience := []uint{share.OwnerID}
+	if share.GroupID == nil {
+		return audience
+	}
+	members, err := s.groupRepo.ListMembers(ctx, *share.GroupID)
+	if err != nil {
+		s.logger.Error("Error listing group members", zap.Uint("share_id", share.ID), util.WithError(err))
+	}
+	for _, member := range members {
+		audience = append(audience, member.UserID)
+	}
+	return audience
+}
+
+// findShare loads a share by ID
+func (s *shareService) findShare(ctx context.Context, shareID uint) (*model.Share, error) {
+	share, err := s.shareRepo.FindByID(ctx, shareID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding share: %w", err)
+	}
+	if share == nil {
+		return nil, ErrShareNotFound
+	}
+	return share, nil
+}
+
+// requireOwner fails unless the user owns the file (when fileID is set) or folder
+func (s *shareService) requireOwner(ctx context.Context, userID, fileID, folderID uint) error {
+	var permission model.Permission
+	var err error
+	if fileID != 0 {
+		permission, err = s.permissionRepo.FilePermission(ctx, userID, fileID)
+	} else {
+		permission, err = s.permissionRepo.FolderPermission(ctx, userID, folderID)
+	}
+	if err != nil {
+		return fmt.Errorf("error resolving permission: %w", err)
+	}
+
+	switch {
+	case permission == "" && fileID != 0:
+		return ErrFileNotFound
+	case permission == "":
+		return ErrFolderNotFound
+	case !permission.Allows(model.PermissionOwner):
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// shareFileID returns the shared file ID, or zero for folder shares
+func shareFileID(share *model.Share) uint {
+	if share.FileID == nil {
+		return 0
+	}
+	return *share.FileID
+}
+
+// withRecipient adds who a share is granted to to its audit metadata
+func withRecipient(share *model.Share, metadata model.JSONMap) model.JSONMap {
+	if share.GroupID != nil {
+		metadata["group_id"] = *share.GroupID
+	} else if share.SharedWithID != nil {
+		metadata["shared_with_id"] = *share.SharedWithID
+	}
+	return metadata
+}
+
+// shareTarget returns the audit target of a share
+func shareTarget(share *model.Share) (model.AuditTarget, uint) {
+	if fileID := shareFileID(share); fileID != 0 {
+		return model.AuditTargetFile, fileID
+	}
+	return model.AuditTargetFolder, share.FolderID
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"fmt"
+)
+
+// StarService manages the files and folders users mark as favourites
+type StarService interface {
+	// Star marks an item the user can read, reporting whether it was not
+	// starred already
+	Star(ctx context.Context, userID uint, itemType model.ItemType, itemID uint) (bool, error)
+	// Unstar removes the user's star from an item, reporting whether it was
+	// starred
+	Unstar(ctx context.Context, userID uint, itemType model.ItemType, itemID uint) (bool, error)
+	// List returns the starred items the user can still read
+	List(ctx context.Context, userID uint) (*model.StarredItems, error)
+}
+
+type starService struct {
+	starRepo       repository.StarRepository
+	permissionRepo repository.PermissionRepository
+	logger         *util.Logger
+}
+
+// NewStarService creates a new StarService instance
+func NewStarService(starRepo repository.StarRepository, permissionRepo repository.PermissionRepository, logger *util.Logger) StarService {
+	return &starService{
+		starRepo:       starRepo,
+		permissionRepo: permissionRepo,
+		logger:         logger,
+	}
+}
+
+// Star marks an item the user can read
+func (s *starService) Star(ctx context.Context, userID uint, itemType model.ItemType, itemID uint) (bool, error) {
+	var permission model.Permission
+	var err error
+	switch itemType {
+	case model.ItemTypeFile:
+		permission, err = s.permissionRepo.FilePermission(ctx, userID, itemID)
+		if err == nil && permission == "" {
+			return false, ErrFileNotFound
+		}
+	case model.ItemTypeFolder:
+		permission, err = s.permissionRepo.FolderPermission(ctx, userID, itemID)
+		if err == nil && permission == "" {
+			return false, ErrFolderNotFound
+		}
+	default:
+		return false, fmt.Errorf("unknown item type %q", itemType)
+	}
+	if err != nil {
+		return false, fmt.Errorf("error resolving permission: %w", err)
+	}
+
+	added, err := s.starRepo.Add(ctx, &model.Star{UserID: userID, ItemType: itemType, ItemID: itemID})
+	if err != nil {
+		return false, fmt.Errorf("error starring item: %w", err)
+	}
+	return added, nil
+}
+
+// Unstar removes the user's star from an item
+func (s *starService) Unstar(ctx context.Context, userID uint, itemType model.ItemType, itemID uint) (bool, error) {
+	removed, err := s.starRepo.Remove(ctx, userID, itemType, itemID)
+	if err != nil {
+		return false, fmt.Errorf("error unstarring item: %w", err)
+	}
+	return removed, nil
+}
+
+// List returns the starred items the user can still read
+func (s *starService) List(ctx context.Context, userID uint) (*model.StarredItems, error) {
+	files, err := s.starRepo.ListFiles(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing starred files: %w", err)
+	}
+	folders, err := s.starRepo.ListFolders(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("error listing starred folders: %w", err)
+	}
+	return &model.StarredItems{Files: files, Folders: folders}, nil
+}
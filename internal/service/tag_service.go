@@ -0,0 +1,95 @@
+package service
+
+import (
+	"context"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// maxTagNameLength is the longest tag name accepted
+const maxTagNameLength = 255
+
+var ErrInvalidTagName = errors.New("tag names must be between 1 and 255 characters")
+
+// TagService attaches users' labels to files. Tags belong to the user who
+// applies them, so anyone who can read a file can tag it for themselves.
+type TagService interface {
+	// Tag labels a file the user can read, creating the tag if needed and
+	// reporting whether the file was not tagged with it already
+	Tag(ctx context.Context, userID, fileID uint, name string) (bool, error)
+	// Untag removes one of the user's tags from a file, reporting whether
+	// the file was tagged with it
+	Untag(ctx context.Context, userID, fileID uint, name string) (bool, error)
+}
+
+type tagService struct {
+	tagRepo        repository.TagRepository
+	permissionRepo repository.PermissionRepository
+	logger         *util.Logger
+}
+
+// NewTagService creates a new TagService instance
+func NewTagService(tagRepo repository.TagRepository, permissionRepo repository.PermissionRepository, logger *util.Logger) TagService {
+	return &tagService{
+		tagRepo:        tagRepo,
+		permissionRepo: permissionRepo,
+		logger:         logger,
+	}
+}
+
+// Tag labels a file the user can read
+func (s *tagService) Tag(ctx context.Context, userID, fileID uint, name string) (bool, error) {
+	name, err := cleanTagName(name)
+	if err != nil {
+		return false, err
+	}
+	permission, err := s.permissionRepo.FilePermission(ctx, userID, fileID)
+	if err != nil {
+		return false, fmt.Errorf("error resolving file permission: %w", err)
+	}
+	if permission == "" {
+		return false, ErrFileNotFound
+	}
+
+	tag, err := s.tagRepo.FindOrCreate(ctx, userID, name)
+	if err != nil {
+		return false, fmt.Errorf("error creating tag: %w", err)
+	}
+	added, err := s.tagRepo.Attach(ctx, tag.ID, fileID)
+	if err != nil {
+		return false, fmt.Errorf("error tagging file: %w", err)
+	}
+	return added, nil
+}
+
+// Untag removes one of the user's tags from a file
+func (s *tagService) Untag(ctx context.Context, userID, fileID uint, name string) (bool, error) {
+	name, err := cleanTagName(name)
+	if err != nil {
+		return false, err
+	}
+	tag, err := s.tagRepo.FindByName(ctx, userID, name)
+	if err != nil {
+		return false, fmt.Errorf("error finding tag: %w", err)
+	}
+	if tag == nil {
+		return false, nil
+	}
+	removed, err := s.tagRepo.Detach(ctx, tag.ID, fileID)
+	if err != nil {
+		return false, fmt.Errorf("error untagging file: %w", err)
+	}
+	return removed, nil
+}
+
+// cleanTagName trims a tag name and checks its length
+func cleanTagName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || len(name) > maxTagNameLength {
+		return "", ErrInvalidTagName
+	}
+	return name, nil
+}
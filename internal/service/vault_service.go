@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+// vaultMimeType is recorded for files in a vault, whose content the server
+// cannot inspect
+const vaultMimeType = "application/octet-stream"
+
+var (
+	ErrPublicKeyNotFound = errors.New("public key not found")
+	ErrVaultKeyNotFound  = errors.New("vault key not found")
+	ErrVaultKeyRequired  = errors.New("a wrapped vault key is required")
+	ErrNotInVault        = errors.New("item is not in a vault")
+	ErrNestedVault       = errors.New("a vault cannot be created inside another vault")
+	ErrVaultBoundary     = errors.New("items cannot be moved or copied into or out of a vault")
+)
+
+// VaultService manages the keys of end-to-end encrypted vault folders.
+//
+// The server never sees a vault's key or its plaintext: clients encrypt
+// names and content with the vault key, and the key itself is only stored
+// wrapped for each member's public key.
+type VaultService interface {
+	// SetPublicKey registers or replaces the user's public key. Vault keys
+	// wrapped for a replaced key can no longer be opened with the new one.
+	SetPublicKey(ctx context.Context, userID uint, req *model.SetPublicKeyRequest) (*model.UserPublicKey, error)
+	// PublicKey returns the public key of the user identified by ID or, when
+	// the ID is zero, by email, so items can be shared with them
+	PublicKey(ctx context.Context, userID uint, email string) (*model.UserPublicKey, error)
+	// Key returns the user's wrapped key for the vault holding a folder
+	// they can read
+	Key(ctx context.Context, userID, folderID uint) (*model.VaultKey, error)
+}
+
+type vaultService struct {
+	vaultRepo      repository.VaultRepository
+	folderRepo     repository.FolderRepository
+	userRepo       repository.UserRepository
+	permissionRepo repository.PermissionRepository
+	logger         *util.Logger
+}
+
+// NewVaultService creates a new VaultService instance
+func NewVaultService(
+	vaultRepo repository.VaultRepository,
+	folderRepo repository.FolderRepository,
+	userRepo repository.UserRepository,
+	permissionRepo repository.PermissionRepository,
+	logger *util.Logger,
+) VaultService {
+	return &vaultService{
+		vaultRepo:      vaultRepo,
+		folderRepo:     folderRepo,
+		userRepo:       userRepo,
+		permissionRepo: permissionRepo,
+		logger:         logger,
+	}
+}
+
+// SetPublicKey registers or replaces the user's public key
+func (s *vaultService) SetPublicKey(ctx context.Context, userID uint, req *model.SetPublicKeyRequest) (*model.UserPublicKey, error) {
+	key := &model.UserPublicKey{
+		UserID:    userID,
+		Algorithm: req.Algorithm,
+		PublicKey: req.PublicKey,
+	}
+	if err := s.vaultRepo.SetPublicKey(ctx, key); err != nil {
+		s.logger.Error("Error saving public key", util.WithUserID(userID), util.WithError(err))
+		return nil, fmt.Errorf("error saving public key: %w", err)
+	}
+	return s.vaultRepo.FindPublicKey(ctx, userID)
+}
+
+// PublicKey returns the public key of the user identified by ID or email
+func (s *vaultService) PublicKey(ctx context.Context, userID uint, email string) (*model.UserPublicKey, error) {
+	if userID == 0 {
+		user, err := s.userRepo.FindByEmail(ctx, email)
+		if err != nil {
+			return nil, fmt.Errorf("error finding user: %w", err)
+		}
+		if user == nil {
+			return nil, ErrUserNotFound
+		}
+		userID = user.ID
+	}
+
+	key, err := s.vaultRepo.FindPublicKey(ctx, userID)
+	if err != nil {
+		s.logger.Error("Error finding public key", util.WithUserID(userID), util.WithError(err))
+		return nil, fmt.Errorf("error finding public key: %w", err)
+	}
+	if key == nil {
+		return nil, ErrPublicKeyNotFound
+	}
+	return key, nil
+}
+
+// Key returns the user's wrapped key for the vault holding a folder
+func (s *vaultService) Key(ctx context.Context, userID, folderID uint) (*model.VaultKey, error) {
+	folder, err := s.folderRepo.FindByID(ctx, folderID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding folder: %w", err)
+	}
+	if folder == nil {
+		return nil, ErrFolderNotFound
+	}
+	permission, err := s.permissionRepo.FolderPermission(ctx, userID, folder.ID)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving folder permission: %w", err)
+	}
+	if permission == "" {
+		return nil, ErrFolderNotFound
+	}
+	if folder.VaultID == nil {
+		return nil, ErrNotInVault
+	}
+
+	key, err := s.vaultRepo.FindKey(ctx, *folder.VaultID, userID)
+	if err != nil {
+		s.logger.Error("Error finding vault key", util.WithUserID(userID), zap.Uint("vault_id", *folder.VaultID), util.WithError(err))
+		return nil, fmt.Errorf("error finding vault key: %w", err)
+	}
+	if key == nil {
+		return nil, ErrVaultKeyNotFound
+	}
+	return key, nil
+}
+
+// sameVault reports whether two vault IDs name the same vault, or both no
+// vault at all
+func sameVault(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
+// isVaultRoot reports whether a folder is the root of a vault
+func isVaultRoot(folder *model.Folder) bool {
+	return folder.VaultID != nil && *folder.VaultID == folder.ID
+}
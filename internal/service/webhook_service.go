@@ -0,0 +1,515 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	webhookResponseLimit = 4096
+	webhookSecretBytes   = 32
+	// webhookRequeueBatch bounds the deliveries queued by one Requeue
+	webhookRequeueBatch = 500
+
+	defaultDeliveryPerPage = 20
+	maxDeliveryPerPage     = 100
+)
+
+var (
+	ErrWebhookNotFound      = errors.New("webhook not found")
+	ErrDeliveryNotFound     = errors.New("webhook delivery not found")
+	ErrWebhookURLNotAllowed = errors.New("webhook URL is not allowed")
+)
+
+// WebhookConfig holds the limits applied to webhook delivery
+type WebhookConfig struct {
+	Timeout              time.Duration
+	MaxAttempts          int
+	AllowPrivateNetworks bool
+}
+
+// WebhookService manages webhooks and delivers events to them. Deliveries
+// are written to the database when an event is published and sent by
+// JobDeliverWebhook jobs, so pending deliveries survive restarts.
+type WebhookService interface {
+	EventPublisher
+	// Create registers a webhook. Only administrators may create global webhooks.
+	Create(ctx context.Context, user *model.User, req *model.CreateWebhookRequest) (*model.CreateWebhookResponse, error)
+	// List returns the webhooks the user registered
+	List(ctx context.Context, userID uint) ([]model.Webhook, error)
+	// Get returns a webhook the user manages
+	Get(ctx context.Context, user *model.User, webhookID uint) (*model.Webhook, error)
+	// Update changes a webhook the user manages
+	Update(ctx context.Context, user *model.User, webhookID uint, req *model.UpdateWebhookRequest) (*model.Webhook, error)
+	// Delete removes a webhook; its pending deliveries are abandoned
+	Delete(ctx context.Context, user *model.User, webhookID uint) error
+	// ListDeliveries returns a page of a webhook's delivery log, newest first
+	ListDeliveries(ctx context.Context, user *model.User, webhookID uint, filter *model.WebhookDeliveryFilter) ([]model.WebhookDelivery, int64, error)
+	// Redeliver queues a new delivery of a previously sent event
+	Redeliver(ctx context.Context, user *model.User, webhookID, deliveryID uint) (*model.WebhookDelivery, error)
+	// Deliver makes one attempt at a delivery and records the outcome. It
+	// is the handler of JobDeliverWebhook, failing while the delivery has
+	// not succeeded so the job is retried.
+	Deliver(ctx context.Context, deliveryID uint) error
+	// Requeue queues jobs for due deliveries that have none, and returns
+	// how many it queued
+	Requeue(ctx context.Context) (int, error)
+}
+
+type webhookService struct {
+	webhookRepo repository.WebhookRepository
+	config      WebhookConfig
+	jobs        JobService
+	client      *http.Client
+	logger      *util.Logger
+}
+
+// NewWebhookService creates a new WebhookService instance
+func NewWebhookService(webhookRepo repository.WebhookRepository, jobs JobService, config WebhookConfig, logger *util.Logger) WebhookService {
+	if config.Timeout <= 0 {
+		config.Timeout = 10 * time.Second
+	}
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	return &webhookService{
+		webhookRepo: webhookRepo,
+		config:      config,
+		jobs:        jobs,
+		client:      newWebhookClient(config),
+		logger:      logger,
+	}
+}
+
+// Publish queues a delivery of the event to every subscribed webhook
+func (s *webhookService) Publish(ctx context.Context, event *model.Event) {
+	// Queue even if the request was cancelled after the change was made
+	ctx = context.WithoutCancel(ctx)
+	logger := s.logger.With(zap.String("event_id", event.ID), zap.String("event_type", string(event.Type)))
+
+	webhooks, err := s.webhookRepo.FindSubscribers(ctx, event.Type, event.Audience)
+	if err != nil {
+		logger.Error("Error finding webhook subscribers", util.WithError(err))
+		return
+	}
+	if len(webhooks) == 0 {
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		logger.Error("Error encoding webhook payload", util.WithError(err))
+		return
+	}
+
+	now := time.Now()
+	deliveries := make([]model.WebhookDelivery, len(webhooks))
+	for i, webhook := range webhooks {
+		deliveries[i] = model.WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       event.ID,
+			EventType:     event.Type,
+			Payload:       string(payload),
+			Status:        model.DeliveryPending,
+			NextAttemptAt: &now,
+		}
+	}
+	if err := s.webhookRepo.CreateDeliveries(ctx, deliveries); err != nil {
+		logger.Error("Error queueing webhook deliveries", util.WithError(err))
+		return
+	}
+
+	for i := range deliveries {
+		s.enqueue(ctx, &deliveries[i])
+	}
+}
+
+// Create registers a webhook
+func (s *webhookService) Create(ctx context.Context, user *model.User, req *model.CreateWebhookRequest) (*model.CreateWebhookResponse, error) {
+	if req.Global && !user.IsAdmin {
+		return nil, ErrPermissionDenied
+	}
+	if err := s.validateURL(req.URL); err != nil {
+		return nil, err
+	}
+
+	secret, err := newWebhookSecret()
+	if err != nil {
+		return nil, fmt.Errorf("error generating webhook secret: %w", err)
+	}
+
+	webhook := &model.Webhook{
+		UserID:      user.ID,
+		URL:         req.URL,
+		Description: req.Description,
+		Secret:      secret,
+		Events:      eventList(req.Events),
+		Global:      req.Global,
+		Active:      true,
+	}
+	if err := s.webhookRepo.Create(ctx, webhook); err != nil {
+		s.logger.Error("Error creating webhook", util.WithUserID(user.ID), util.WithError(err))
+		return nil, fmt.Errorf("error creating webhook: %w", err)
+	}
+
+	s.logger.Info("Webhook created", util.WithUserID(user.ID), zap.Uint("webhook_id", webhook.ID))
+	return &model.CreateWebhookResponse{Webhook: webhook, Secret: secret}, nil
+}
+
+// List returns the webhooks the user registered
+func (s *webhookService) List(ctx context.Context, userID uint) ([]model.Webhook, error) {
+	webhooks, err := s.webhookRepo.ListByUser(ctx, userID)
+	if err != nil {
+		s.logger.Error("Error listing webhooks", util.WithUserID(userID), util.WithError(err))
+		return nil, fmt.Errorf("error listing webhooks: %w", err)
+	}
+	return webhooks, nil
+}
+
+// Get returns a webhook the user manages
+func (s *webhookService) Get(ctx context.Context, user *model.User, webhookID uint) (*model.Webhook, error) {
+	webhook, err := s.webhookRepo.FindByID(ctx, webhookID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding webhook: %w", err)
+	}
+	// Administrators manage every global webhook; everyone else only their own
+	if webhook == nil || (webhook.UserID != user.ID && !(webhook.Global && user.IsAdmin)) {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// Update changes a webhook the user manages
+func (s *webhookService) Update(ctx context.Context, user *model.User, webhookID uint, req *model.UpdateWebhookRequest) (*model.Webhook, error) {
+	webhook, err := s.Get(ctx, user, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.URL != nil {
+		if err := s.validateURL(*req.URL); err != nil {
+			return nil, err
+		}
+		webhook.URL = *req.URL
+	}
+	if req.Description != nil {
+		webhook.Description = *req.Description
+	}
+	if req.Events != nil {
+		webhook.Events = eventList(req.Events)
+	}
+	if req.Active != nil {
+		webhook.Active = *req.Active
+	}
+
+	if err := s.webhookRepo.Update(ctx, webhook); err != nil {
+		s.logger.Error("Error updating webhook", util.WithUserID(user.ID), zap.Uint("webhook_id", webhookID), util.WithError(err))
+		return nil, fmt.Errorf("error updating webhook: %w", err)
+	}
+	return webhook, nil
+}
+
+// Delete removes a webhook
+func (s *webhookService) Delete(ctx context.Context, user *model.User, webhookID uint) error {
+	webhook, err := s.Get(ctx, user, webhookID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.webhookRepo.Delete(ctx, webhook.ID); err != nil {
+		s.logger.Error("Error deleting webhook", util.WithUserID(user.ID), zap.Uint("webhook_id", webhookID), util.WithError(err))
+		return fmt.Errorf("error deleting webhook: %w", err)
+	}
+
+	s.logger.Info("Webhook deleted", util.WithUserID(user.ID), zap.Uint("webhook_id", webhookID))
+	return nil
+}
+
+// ListDeliveries returns a page of a webhook's delivery log
+func (s *webhookService) ListDeliveries(ctx context.Context, user *model.User, webhookID uint, filter *model.WebhookDeliveryFilter) ([]model.WebhookDelivery, int64, error) {
+	if _, err := s.Get(ctx, user, webhookID); err != nil {
+		return nil, 0, err
+	}
+
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 {
+		filter.PerPage = defaultDeliveryPerPage
+	}
+	if filter.PerPage > maxDeliveryPerPage {
+		filter.PerPage = maxDeliveryPerPage
+	}
+
+	deliveries, total, err := s.webhookRepo.ListDeliveries(ctx, webhookID, filter)
+	if err != nil {
+		s.logger.Error("Error listing webhook deliveries", zap.Uint("webhook_id", webhookID), util.WithError(err))
+		return nil, 0, fmt.Errorf("error listing webhook deliveries: %w", err)
+	}
+	return deliveries, total, nil
+}
+
+// Redeliver queues a new delivery of a previously sent event
+func (s *webhookService) Redeliver(ctx context.Context, user *model.User, webhookID, deliveryID uint) (*model.WebhookDelivery, error) {
+	webhook, err := s.Get(ctx, user, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := s.webhookRepo.FindDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding webhook delivery: %w", err)
+	}
+	if original == nil || original.WebhookID != webhook.ID {
+		return nil, ErrDeliveryNotFound
+	}
+
+	now := time.Now()
+	deliveries := []model.WebhookDelivery{{
+		WebhookID:     webhook.ID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        model.DeliveryPending,
+		NextAttemptAt: &now,
+		RedeliveryOf:  &original.ID,
+	}}
+	if err := s.webhookRepo.CreateDeliveries(ctx, deliveries); err != nil {
+		s.logger.Error("Error queueing redelivery", zap.Uint("delivery_id", deliveryID), util.WithError(err))
+		return nil, fmt.Errorf("error queueing redelivery: %w", err)
+	}
+
+	s.enqueue(ctx, &deliveries[0])
+	return &deliveries[0], nil
+}
+
+// enqueue queues the job sending a delivery. A delivery whose job cannot
+// be queued stays pending until Requeue picks it up.
+func (s *webhookService) enqueue(ctx context.Context, delivery *model.WebhookDelivery) {
+	opts := &JobOptions{
+		UniqueKey:   fmt.Sprintf("%s:%d", model.JobDeliverWebhook, delivery.ID),
+		MaxAttempts: s.config.MaxAttempts,
+	}
+	if _, err := s.jobs.Enqueue(ctx, model.JobDeliverWebhook, model.DeliveryJob{DeliveryID: delivery.ID}, opts); err != nil {
+		s.logger.Warn("Error queueing webhook delivery, deferring it to the next requeue", zap.Uint("delivery_id", delivery.ID), util.WithError(err))
+	}
+}
+
+// Requeue queues jobs for due deliveries, covering deliveries whose job
+// could not be queued and those left by the former dispatcher. Deliveries
+// that already have a job are skipped by its unique key.
+func (s *webhookService) Requeue(ctx context.Context) (int, error) {
+	deliveries, err := s.webhookRepo.ListDueDeliveries(ctx, webhookRequeueBatch)
+	if err != nil {
+		return 0, fmt.Errorf("error listing due webhook deliveries: %w", err)
+	}
+	for i := range deliveries {
+		s.enqueue(ctx, &deliveries[i])
+	}
+	return len(deliveries), nil
+}
+
+// Deliver makes one attempt at a delivery and records the outcome
+func (s *webhookService) Deliver(ctx context.Context, deliveryID uint) error {
+	delivery, err := s.webhookRepo.FindDelivery(ctx, deliveryID)
+	if err != nil {
+		return fmt.Errorf("error loading webhook delivery: %w", err)
+	}
+	if delivery == nil || delivery.Status == model.DeliverySucceeded {
+		return nil
+	}
+	logger := s.logger.With(zap.Uint("delivery_id", delivery.ID), zap.Uint("webhook_id", delivery.WebhookID))
+
+	webhook, err := s.webhookRepo.FindByID(ctx, delivery.WebhookID)
+	if err != nil {
+		return fmt.Errorf("error loading webhook: %w", err)
+	}
+	if webhook == nil || !webhook.Active {
+		delivery.Status = model.DeliveryFailed
+		delivery.NextAttemptAt = nil
+		delivery.Error = "webhook was deleted or disabled"
+		return s.saveDelivery(ctx, delivery)
+	}
+
+	now := time.Now()
+	status, body, sendErr := s.send(ctx, webhook, delivery)
+	if ctx.Err() != nil {
+		// Shutting down; the job runs again on the next start
+		return ctx.Err()
+	}
+
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.DurationMS = time.Since(now).Milliseconds()
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = body
+	delivery.Error = ""
+	if sendErr != nil {
+		delivery.Error = sendErr.Error()
+	}
+
+	if sendErr == nil && status >= 200 && status < 300 {
+		delivery.Status = model.DeliverySucceeded
+		delivery.NextAttemptAt = nil
+		return s.saveDelivery(ctx, delivery)
+	}
+	if sendErr == nil {
+		sendErr = fmt.Errorf("webhook responded with status %d", status)
+	}
+
+	if lastAttempt(ctx) {
+		delivery.Status = model.DeliveryFailed
+		delivery.NextAttemptAt = nil
+		logger.Warn("Webhook delivery failed permanently", zap.Int("attempts", delivery.Attempts))
+	} else {
+		next := now.Add(jobBackoff(runningJob(ctx).Attempts))
+		delivery.Status = model.DeliveryPending
+		delivery.NextAttemptAt = &next
+	}
+	if err := s.saveDelivery(ctx, delivery); err != nil {
+		return err
+	}
+	return sendErr
+}
+
+// saveDelivery records the outcome of an attempt
+func (s *webhookService) saveDelivery(ctx context.Context, delivery *model.WebhookDelivery) error {
+	if err := s.webhookRepo.SaveDelivery(context.WithoutCancel(ctx), delivery); err != nil {
+		return fmt.Errorf("error saving webhook delivery: %w", err)
+	}
+	return nil
+}
+
+// send POSTs the payload to the webhook and returns the response status and
+// the start of the response body
+func (s *webhookService) send(ctx context.Context, webhook *model.Webhook, delivery *model.WebhookDelivery) (int, string, error) {
+	body := []byte(delivery.Payload)
+	timestamp := time.Now().Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "drive-webhooks/1.0")
+	req.Header.Set("X-Drive-Event", string(delivery.EventType))
+	req.Header.Set("X-Drive-Event-ID", delivery.EventID)
+	req.Header.Set("X-Drive-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Drive-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Drive-Signature", signWebhookPayload(webhook.Secret, timestamp, body))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseLimit))
+	// Drain a little more so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	return resp.StatusCode, sanitizeText(string(snippet)), nil
+}
+
+// validateURL rejects URLs that cannot or must not receive deliveries
+func (s *webhookService) validateURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrWebhookURLNotAllowed
+	}
+	if ip := net.ParseIP(u.Hostname()); ip != nil && !s.config.AllowPrivateNetworks && !isPublicIP(ip) {
+		return ErrWebhookURLNotAllowed
+	}
+	return nil
+}
+
+// newWebhookClient creates the HTTP client used for deliveries. Redirects
+// are not followed and, unless private networks are allowed, connections to
+// non-public addresses are refused at dial time so DNS cannot be used to
+// reach internal services.
+func newWebhookClient(config WebhookConfig) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: config.Timeout,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			if config.AllowPrivateNetworks {
+				return nil
+			}
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !isPublicIP(ip) {
+				return ErrWebhookURLNotAllowed
+			}
+			return nil
+		},
+	}
+
+	return &http.Client{
+		Timeout: config.Timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: config.Timeout,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     90 * time.Second,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// isPublicIP reports whether ip is a globally routable unicast address
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast())
+}
+
+// signWebhookPayload returns the X-Drive-Signature header value: an
+// HMAC-SHA256 of "<timestamp>.<body>" keyed with the webhook secret
+func signWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// newWebhookSecret generates a random signing secret
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, webhookSecretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// eventList converts requested event types to the stored form
+func eventList(events []model.EventType) model.StringList {
+	list := make(model.StringList, 0, len(events))
+	for _, event := range events {
+		if !list.Contains(string(event)) {
+			list = append(list, string(event))
+		}
+	}
+	return list
+}
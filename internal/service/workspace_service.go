@@ -0,0 +1,441 @@
+package service
+
+import (
+	"context"
+	"drive/internal/model"
+	"drive/internal/repository"
+	"drive/internal/util"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+const defaultWorkspacePerPage = 20
+
+var (
+	ErrWorkspaceNotFound       = errors.New("workspace not found")
+	ErrWorkspaceMemberNotFound = errors.New("workspace member not found")
+	ErrWorkspaceBoundary       = errors.New("items cannot be moved into another workspace or out of one")
+	ErrLastManager             = errors.New("a workspace must keep at least one manager")
+	ErrInvalidWorkspaceName    = errors.New("invalid workspace name")
+)
+
+// WorkspaceService manages team workspaces and their members. Content in a
+// workspace is reached through the folder and file endpoints, starting at
+// its root folder; members hold the permission their role grants on all of
+// it.
+type WorkspaceService interface {
+	// Create makes a workspace with its root folder and first manager
+	Create(ctx context.Context, adminID uint, req *model.CreateWorkspaceRequest) (*model.Workspace, error)
+	// List returns a page of every workspace, along with the total
+	List(ctx context.Context, filter *model.WorkspaceFilter) ([]model.Workspace, int64, error)
+	// Configure renames a workspace or changes its quota on behalf of an
+	// administrator
+	Configure(ctx context.Context, adminID, workspaceID uint, req *model.UpdateWorkspaceRequest) (*model.Workspace, error)
+	// Delete removes a workspace's members and moves the workspace, with
+	// all of its content, to the trash on behalf of an administrator. The
+	// content is purged with the rest of the trash, releasing its blobs. It
+	// fails like deleting the root folder would while files are locked or
+	// held.
+	Delete(ctx context.Context, adminID, workspaceID uint) error
+
+	// ListMine returns the user's memberships with their workspaces
+	ListMine(ctx context.Context, userID uint) ([]model.WorkspaceMember, error)
+	// Get returns a workspace the user is a member of
+	Get(ctx context.Context, userID, workspaceID uint) (*model.Workspace, error)
+	// Update renames a workspace the user manages. Only administrators can
+	// change its quota.
+	Update(ctx context.Context, userID, workspaceID uint, req *model.UpdateWorkspaceRequest) (*model.Workspace, error)
+	// ListMembers returns the members of a workspace the user belongs to
+	ListMembers(ctx context.Context, userID, workspaceID uint) ([]model.WorkspaceMember, error)
+	// SetMember adds a user to a workspace the user manages or changes
+	// their role. It fails with ErrLastManager when demoting the only
+	// manager.
+	SetMember(ctx context.Context, userID, workspaceID, memberID uint, req *model.SetWorkspaceMemberRequest) (*model.WorkspaceMember, error)
+	// RemoveMember takes a user out of a workspace. Managers can remove
+	// anyone and members can leave, but the last manager cannot.
+	RemoveMember(ctx context.Context, userID, workspaceID, memberID uint) error
+}
+
+type workspaceService struct {
+	workspaceRepo  repository.WorkspaceRepository
+	folderRepo     repository.FolderRepository
+	userRepo       repository.UserRepository
+	permissionRepo repository.PermissionRepository
+	locks          LockService
+	retention      RetentionService
+	audit          AuditService
+	events         EventPublisher
+	logger         *util.Logger
+}
+
+// NewWorkspaceService creates a new WorkspaceService instance
+func NewWorkspaceService(
+	workspaceRepo repository.WorkspaceRepository,
+	folderRepo repository.FolderRepository,
+	userRepo repository.UserRepository,
+	permissionRepo repository.PermissionRepository,
+	locks LockService,
+	retention RetentionService,
+	audit AuditService,
+	events EventPublisher,
+	logger *util.Logger,
+) WorkspaceService {
+	return &workspaceService{
+		workspaceRepo:  workspaceRepo,
+		folderRepo:     folderRepo,
+		userRepo:       userRepo,
+		permissionRepo: permissionRepo,
+		locks:          locks,
+		retention:      retention,
+		audit:          audit,
+		events:         events,
+		logger:         logger,
+	}
+}
+
+// Create makes a workspace and makes the requested user its manager
+func (s *workspaceService) Create(ctx context.Context, adminID uint, req *model.CreateWorkspaceRequest) (*model.Workspace, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrInvalidWorkspaceName
+	}
+	if err := s.requireUser(ctx, req.ManagerID); err != nil {
+		return nil, err
+	}
+
+	workspace := &model.Workspace{
+		Name:         name,
+		StorageLimit: req.StorageLimit,
+		CreatedBy:    adminID,
+	}
+	root := &model.Folder{FolderName: "/", UserID: adminID}
+	member := &model.WorkspaceMember{
+		UserID:  req.ManagerID,
+		Role:    model.WorkspaceManager,
+		AddedBy: adminID,
+	}
+	if err := s.workspaceRepo.Create(ctx, workspace, root, member); err != nil {
+		s.logger.Error("Error creating workspace", util.WithUserID(adminID), util.WithError(err))
+		return nil, fmt.Errorf("error creating workspace: %w", err)
+	}
+	// Reload to pick up the default limit
+	workspace, err := s.findWorkspace(ctx, workspace.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditWorkspaceCreate,
+		ActorID:    auditRef(adminID),
+		TargetType: model.AuditTargetWorkspace,
+		TargetID:   auditRef(workspace.ID),
+		Metadata:   model.JSONMap{"name": workspace.Name, "manager_id": req.ManagerID, "storage_limit": workspace.StorageLimit},
+	})
+	s.logger.Info("Workspace created", util.WithUserID(adminID), zap.Uint("workspace_id", workspace.ID))
+	return workspace, nil
+}
+
+// List returns a page of every workspace
+func (s *workspaceService) List(ctx context.Context, filter *model.WorkspaceFilter) ([]model.Workspace, int64, error) {
+	if filter.Page < 1 {
+		filter.Page = 1
+	}
+	if filter.PerPage < 1 {
+		filter.PerPage = defaultWorkspacePerPage
+	}
+
+	workspaces, total, err := s.workspaceRepo.List(ctx, filter)
+	if err != nil {
+		s.logger.Error("Error listing workspaces", util.WithError(err))
+		return nil, 0, fmt.Errorf("error listing workspaces: %w", err)
+	}
+	return workspaces, total, nil
+}
+
+// Configure applies an administrator's changes to a workspace
+func (s *workspaceService) Configure(ctx context.Context, adminID, workspaceID uint, req *model.UpdateWorkspaceRequest) (*model.Workspace, error) {
+	workspace, err := s.findWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	return s.update(ctx, adminID, workspace, req)
+}
+
+// Delete trashes a workspace's content and removes the workspace
+func (s *workspaceService) Delete(ctx context.Context, adminID, workspaceID uint) error {
+	workspace, err := s.findWorkspace(ctx, workspaceID)
+	if err != nil {
+		return err
+	}
+	root, err := s.folderRepo.FindByID(ctx, workspace.RootFolderID)
+	if err != nil {
+		return fmt.Errorf("error finding workspace root folder: %w", err)
+	}
+	if root == nil {
+		return ErrFolderNotFound
+	}
+	if err := s.locks.CheckFolder(ctx, adminID, root.ID); err != nil {
+		return err
+	}
+	if err := s.retention.CheckFolderDelete(ctx, adminID, root); err != nil {
+		return err
+	}
+
+	// The members lose access along with their membership
+	audience, err := s.permissionRepo.FolderAudience(ctx, root.ID)
+	if err != nil {
+		s.logger.Error("Error resolving folder audience", zap.Uint("folder_id", root.ID), util.WithError(err))
+	}
+	if err := s.workspaceRepo.Delete(ctx, workspace, root); err != nil {
+		s.logger.Error("Error deleting workspace", util.WithUserID(adminID), zap.Uint("workspace_id", workspaceID), util.WithError(err))
+		return fmt.Errorf("error deleting workspace: %w", err)
+	}
+
+	s.events.Publish(ctx, newEvent(model.EventFolderDeleted, adminID, root, audience...))
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditWorkspaceDelete,
+		ActorID:    auditRef(adminID),
+		TargetType: model.AuditTargetWorkspace,
+		TargetID:   auditRef(workspace.ID),
+		Metadata:   model.JSONMap{"name": workspace.Name, "root_folder_id": root.ID, "storage_used": workspace.StorageUsed},
+	})
+	s.logger.Info("Workspace deleted", util.WithUserID(adminID), zap.Uint("workspace_id", workspaceID))
+	return nil
+}
+
+// ListMine returns the workspaces the user belongs to
+func (s *workspaceService) ListMine(ctx context.Context, userID uint) ([]model.WorkspaceMember, error) {
+	memberships, err := s.workspaceRepo.ListMemberships(ctx, userID)
+	if err != nil {
+		s.logger.Error("Error listing workspace memberships", util.WithUserID(userID), util.WithError(err))
+		return nil, fmt.Errorf("error listing workspaces: %w", err)
+	}
+	return memberships, nil
+}
+
+// Get returns a workspace the user belongs to
+func (s *workspaceService) Get(ctx context.Context, userID, workspaceID uint) (*model.Workspace, error) {
+	workspace, _, err := s.membership(ctx, userID, workspaceID)
+	return workspace, err
+}
+
+// Update renames a workspace the user manages
+func (s *workspaceService) Update(ctx context.Context, userID, workspaceID uint, req *model.UpdateWorkspaceRequest) (*model.Workspace, error) {
+	workspace, err := s.requireManager(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if req.StorageLimit != nil {
+		return nil, ErrPermissionDenied
+	}
+	return s.update(ctx, userID, workspace, req)
+}
+
+// ListMembers returns the members of a workspace the user belongs to
+func (s *workspaceService) ListMembers(ctx context.Context, userID, workspaceID uint) ([]model.WorkspaceMember, error) {
+	if _, _, err := s.membership(ctx, userID, workspaceID); err != nil {
+		return nil, err
+	}
+	members, err := s.workspaceRepo.ListMembers(ctx, workspaceID)
+	if err != nil {
+		s.logger.Error("Error listing workspace members", zap.Uint("workspace_id", workspaceID), util.WithError(err))
+		return nil, fmt.Errorf("error listing workspace members: %w", err)
+	}
+	return members, nil
+}
+
+// SetMember adds a member or changes their role
+func (s *workspaceService) SetMember(ctx context.Context, userID, workspaceID, memberID uint, req *model.SetWorkspaceMemberRequest) (*model.WorkspaceMember, error) {
+	if _, err := s.requireManager(ctx, userID, workspaceID); err != nil {
+		return nil, err
+	}
+	if err := s.requireUser(ctx, memberID); err != nil {
+		return nil, err
+	}
+
+	member, err := s.workspaceRepo.FindMember(ctx, workspaceID, memberID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding workspace member: %w", err)
+	}
+	previous := model.WorkspaceRole("")
+	if member == nil {
+		member = &model.WorkspaceMember{WorkspaceID: workspaceID, UserID: memberID, AddedBy: userID}
+	} else {
+		previous = member.Role
+		if previous == req.Role {
+			return member, nil
+		}
+		if previous == model.WorkspaceManager {
+			if err := s.keepManager(ctx, workspaceID); err != nil {
+				return nil, err
+			}
+		}
+	}
+	member.Role = req.Role
+
+	if err := s.workspaceRepo.SaveMember(ctx, member); err != nil {
+		s.logger.Error("Error saving workspace member", util.WithUserID(userID), zap.Uint("workspace_id", workspaceID), util.WithError(err))
+		return nil, fmt.Errorf("error saving workspace member: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditWorkspaceMemberSet,
+		ActorID:    auditRef(userID),
+		TargetType: model.AuditTargetWorkspace,
+		TargetID:   auditRef(workspaceID),
+		Metadata:   model.JSONMap{"member_id": memberID, "role": member.Role, "previous_role": previous},
+	})
+	return member, nil
+}
+
+// RemoveMember takes a user out of a workspace
+func (s *workspaceService) RemoveMember(ctx context.Context, userID, workspaceID, memberID uint) error {
+	if memberID == userID {
+		if _, _, err := s.membership(ctx, userID, workspaceID); err != nil {
+			return err
+		}
+	} else if _, err := s.requireManager(ctx, userID, workspaceID); err != nil {
+		return err
+	}
+
+	member, err := s.workspaceRepo.FindMember(ctx, workspaceID, memberID)
+	if err != nil {
+		return fmt.Errorf("error finding workspace member: %w", err)
+	}
+	if member == nil {
+		return ErrWorkspaceMemberNotFound
+	}
+	if member.Role == model.WorkspaceManager {
+		if err := s.keepManager(ctx, workspaceID); err != nil {
+			return err
+		}
+	}
+
+	if err := s.workspaceRepo.DeleteMember(ctx, member); err != nil {
+		s.logger.Error("Error removing workspace member", util.WithUserID(userID), zap.Uint("workspace_id", workspaceID), util.WithError(err))
+		return fmt.Errorf("error removing workspace member: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditWorkspaceMemberRemove,
+		ActorID:    auditRef(userID),
+		TargetType: model.AuditTargetWorkspace,
+		TargetID:   auditRef(workspaceID),
+		Metadata:   model.JSONMap{"member_id": memberID, "role": member.Role},
+	})
+	return nil
+}
+
+// update applies a rename and quota change and records them
+func (s *workspaceService) update(ctx context.Context, actorID uint, workspace *model.Workspace, req *model.UpdateWorkspaceRequest) (*model.Workspace, error) {
+	changes := model.JSONMap{}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" {
+			return nil, ErrInvalidWorkspaceName
+		}
+		if name != workspace.Name {
+			changes["name"] = name
+			workspace.Name = name
+		}
+	}
+	if req.StorageLimit != nil && *req.StorageLimit != workspace.StorageLimit {
+		changes["storage_limit"] = *req.StorageLimit
+		workspace.StorageLimit = *req.StorageLimit
+	}
+	if len(changes) == 0 {
+		return workspace, nil
+	}
+
+	if err := s.workspaceRepo.Update(ctx, workspace); err != nil {
+		s.logger.Error("Error updating workspace", util.WithUserID(actorID), zap.Uint("workspace_id", workspace.ID), util.WithError(err))
+		return nil, fmt.Errorf("error updating workspace: %w", err)
+	}
+
+	s.audit.Record(ctx, &model.AuditLog{
+		Action:     model.AuditWorkspaceUpdate,
+		ActorID:    auditRef(actorID),
+		TargetType: model.AuditTargetWorkspace,
+		TargetID:   auditRef(workspace.ID),
+		Metadata:   changes,
+	})
+	return workspace, nil
+}
+
+// findWorkspace loads a workspace by ID
+func (s *workspaceService) findWorkspace(ctx context.Context, workspaceID uint) (*model.Workspace, error) {
+	workspace, err := s.workspaceRepo.FindByID(ctx, workspaceID)
+	if err != nil {
+		return nil, fmt.Errorf("error finding workspace: %w", err)
+	}
+	if workspace == nil {
+		return nil, ErrWorkspaceNotFound
+	}
+	return workspace, nil
+}
+
+// membership returns a workspace with the user's membership of it. Users
+// outside a workspace are told it does not exist.
+func (s *workspaceService) membership(ctx context.Context, userID, workspaceID uint) (*model.Workspace, *model.WorkspaceMember, error) {
+	workspace, err := s.findWorkspace(ctx, workspaceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	member, err := s.workspaceRepo.FindMember(ctx, workspaceID, userID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error finding workspace member: %w", err)
+	}
+	if member == nil {
+		return nil, nil, ErrWorkspaceNotFound
+	}
+	return workspace, member, nil
+}
+
+// requireManager fails unless the user manages the workspace
+func (s *workspaceService) requireManager(ctx context.Context, userID, workspaceID uint) (*model.Workspace, error) {
+	workspace, member, err := s.membership(ctx, userID, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role != model.WorkspaceManager {
+		return nil, ErrPermissionDenied
+	}
+	return workspace, nil
+}
+
+// keepManager fails when a workspace has no manager besides the one about
+// to be demoted or removed
+func (s *workspaceService) keepManager(ctx context.Context, workspaceID uint) error {
+	managers, err := s.workspaceRepo.CountManagers(ctx, workspaceID)
+	if err != nil {
+		return fmt.Errorf("error counting workspace managers: %w", err)
+	}
+	if managers <= 1 {
+		return ErrLastManager
+	}
+	return nil
+}
+
+// requireUser fails with ErrUserNotFound unless the user exists
+func (s *workspaceService) requireUser(ctx context.Context, userID uint) error {
+	user, err := s.userRepo.FindByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("error finding user: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// sameWorkspace reports whether two items are in the same workspace, or
+// both outside any
+func sameWorkspace(a, b *uint) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned when a blob does not exist
+	ErrNotFound = errors.New("blob not found")
+	// ErrInvalidKey is returned when a key would escape the storage root
+	ErrInvalidKey = errors.New("invalid blob key")
+)
+
+// BlobInfo describes a stored blob
+type BlobInfo struct {
+	Key  string
+	Size int64
+	// ModTime is when the blob was last written
+	ModTime time.Time
+}
+
+// Storage defines the operations required from a blob storage backend.
+// Keys are slash-separated paths relative to the backend root.
+type Storage interface {
+	// Put stores the content of r under key and returns the number of bytes written
+	Put(ctx context.Context, key string, r io.Reader) (int64, error)
+	// Open returns a seekable reader over the blob stored under key
+	Open(ctx context.Context, key string) (io.ReadSeekCloser, error)
+	// Delete removes the blob stored under key
+	Delete(ctx context.Context, key string) error
+	// List calls fn for every stored blob, in no particular order, stopping
+	// at the first error fn returns. Uploads still being written are not
+	// listed.
+	List(ctx context.Context, fn func(BlobInfo) error) error
+	// RemoveStaleUploads removes the partial content of uploads that were
+	// interrupted before they completed, such as by a crash, and were last
+	// written before the given time. It returns how many were removed.
+	RemoveStaleUploads(ctx context.Context, before time.Time) (int, error)
+}
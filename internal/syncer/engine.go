@@ -0,0 +1,320 @@
+// Package syncer keeps a local directory and a drive folder in two-way sync.
+//
+// Each sync cycle first pulls the remote change feed and applies it to the
+// directory, then scans the directory and pushes what changed locally. A
+// state database records every item as it was when both sides last agreed;
+// comparing against it tells a local edit from a remote one. When both sides
+// changed, neither is overwritten: the local version is kept as a
+// "conflicted copy" next to the remote one. Cycles are triggered by file
+// system notifications (or a polling fallback), by the remote change feed
+// and by a periodic full scan.
+//
+// The engine only talks to the server through pkg/client, so it can be run
+// against an in-process server for end-to-end tests.
+package syncer
+
+import (
+	"context"
+	"drive/internal/util"
+	"drive/pkg/client"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// stateDirName is the directory inside the sync root holding the state
+	// database and partial downloads. It is never synced.
+	stateDirName = ".drive-sync"
+
+	defaultWatchPollInterval = 5 * time.Minute
+	defaultPollInterval      = 10 * time.Second
+	defaultDebounce          = time.Second
+
+	// remoteWait is how long a change feed request waits for news
+	remoteWait = time.Minute
+	// maxRemoteBackoff caps the delay between failed change feed requests
+	maxRemoteBackoff = time.Minute
+	// changePageSize is the number of changes pulled per request
+	changePageSize = 500
+)
+
+// ErrStateMismatch is returned when the state directory was created for a
+// different server or remote folder
+var ErrStateMismatch = errors.New("sync state belongs to a different server or remote folder")
+
+// Options configures an Engine
+type Options struct {
+	// LocalDir is the directory kept in sync
+	LocalDir string
+	// RemotePath is the drive folder it mirrors, created if missing.
+	// Defaults to the root folder.
+	RemotePath string
+	// StateDir holds the state database. Defaults to .drive-sync inside
+	// LocalDir.
+	StateDir string
+	// PollInterval is the time between full scans. Defaults to 5 minutes
+	// when file system notifications are available and 10 seconds when not.
+	PollInterval time.Duration
+	// Debounce is how long the directory must be quiet after a notification
+	// before a sync starts
+	Debounce time.Duration
+	// DisableWatcher forces polling even where notifications are available
+	DisableWatcher bool
+	// Hostname goes into the names of conflicted copies. Defaults to the
+	// machine's host name.
+	Hostname string
+}
+
+// Engine syncs one directory with one drive folder
+type Engine struct {
+	client *client.Client
+	opts   Options
+	logger *util.Logger
+
+	root     string
+	stateDir string
+	lock     io.Closer
+	watcher  watcher
+
+	// mu guards state.Cursor, which the change feed poller reads
+	mu    sync.Mutex
+	state *state
+	// rescan asks for another push after conflicted copies were created
+	rescan bool
+}
+
+// New prepares an engine, taking the lock on its state directory
+func New(c *client.Client, opts Options, logger *util.Logger) (*Engine, error) {
+	root, err := filepath.Abs(opts.LocalDir)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%s is not a directory", root)
+	}
+
+	if opts.RemotePath == "" {
+		opts.RemotePath = "/"
+	}
+	if opts.StateDir == "" {
+		opts.StateDir = filepath.Join(root, stateDirName)
+	}
+	if opts.Debounce <= 0 {
+		opts.Debounce = defaultDebounce
+	}
+	if opts.Hostname == "" {
+		opts.Hostname, _ = os.Hostname()
+	}
+	stateDir, err := filepath.Abs(opts.StateDir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Join(stateDir, "tmp"), 0o700); err != nil {
+		return nil, fmt.Errorf("creating state directory: %w", err)
+	}
+
+	lock, err := lockDir(stateDir)
+	if err != nil {
+		return nil, err
+	}
+	st, err := loadState(filepath.Join(stateDir, "state.json"))
+	if err != nil {
+		lock.Close()
+		return nil, err
+	}
+	if st.Server == "" {
+		st.Server, st.RemotePath = c.BaseURL(), opts.RemotePath
+	} else if st.Server != c.BaseURL() || st.RemotePath != opts.RemotePath {
+		lock.Close()
+		return nil, ErrStateMismatch
+	}
+
+	e := &Engine{
+		client:   c,
+		opts:     opts,
+		logger:   logger.With(zap.String("dir", root)),
+		root:     root,
+		stateDir: stateDir,
+		lock:     lock,
+		state:    st,
+	}
+	if !opts.DisableWatcher {
+		if e.watcher, err = newWatcher(); err != nil {
+			e.logger.Warn("File system notifications unavailable, polling instead", util.WithError(err))
+		}
+	}
+	if e.opts.PollInterval <= 0 {
+		e.opts.PollInterval = defaultPollInterval
+		if e.watcher != nil {
+			e.opts.PollInterval = defaultWatchPollInterval
+		}
+	}
+	return e, nil
+}
+
+// Close stops watching the directory and releases the state directory
+func (e *Engine) Close() error {
+	if e.watcher != nil {
+		e.watcher.Close()
+	}
+	return e.lock.Close()
+}
+
+// Run syncs until ctx is cancelled. Failed cycles are logged and retried on
+// the next trigger.
+func (e *Engine) Run(ctx context.Context) {
+	e.syncAndLog(ctx)
+
+	remote := make(chan chan struct{})
+	go e.pollRemote(ctx, remote)
+
+	watching := e.watcher != nil
+	ticker := time.NewTicker(e.opts.PollInterval)
+	defer ticker.Stop()
+	debounce := time.NewTimer(0)
+	if !debounce.Stop() {
+		<-debounce.C
+	}
+
+	for {
+		var local <-chan struct{}
+		if e.watcher != nil {
+			local = e.watcher.Events()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-local:
+			debounce.Reset(e.opts.Debounce)
+		case <-debounce.C:
+			e.syncAndLog(ctx)
+		case <-ticker.C:
+			e.syncAndLog(ctx)
+		case done := <-remote:
+			e.syncAndLog(ctx)
+			close(done)
+		}
+
+		if watching && e.watcher == nil {
+			// The watcher failed; poll as if it had never been there
+			watching = false
+			ticker.Reset(min(e.opts.PollInterval, defaultPollInterval))
+		}
+	}
+}
+
+func (e *Engine) syncAndLog(ctx context.Context) {
+	if err := e.SyncOnce(ctx); err != nil && ctx.Err() == nil {
+		e.logger.Error("Sync failed", util.WithError(err))
+	}
+}
+
+// SyncOnce runs one sync cycle: pull remote changes, then push local ones
+func (e *Engine) SyncOnce(ctx context.Context) error {
+	if e.cursor() == "" {
+		if err := e.initialSync(ctx); err != nil {
+			return err
+		}
+	}
+	if err := e.pull(ctx); err != nil {
+		return err
+	}
+	for {
+		e.rescan = false
+		if err := e.push(ctx); err != nil {
+			return err
+		}
+		if err := e.state.save(); err != nil {
+			return err
+		}
+		if !e.rescan {
+			return nil
+		}
+	}
+}
+
+// pollRemote long-polls the change feed and asks Run for a cycle whenever
+// changes arrive, waiting for it to finish before polling again
+func (e *Engine) pollRemote(ctx context.Context, remote chan<- chan struct{}) {
+	backoff := time.Second
+	for ctx.Err() == nil {
+		cursor := e.cursor()
+		if cursor == "" {
+			// The initial sync has not completed; the ticker retries it
+			if !sleep(ctx, defaultPollInterval) {
+				return
+			}
+			continue
+		}
+
+		changes, err := e.client.Changes(ctx, cursor, 1, remoteWait)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			e.logger.Warn("Error polling change feed", util.WithError(err))
+			if !sleep(ctx, backoff) {
+				return
+			}
+			backoff = min(backoff*2, maxRemoteBackoff)
+			continue
+		}
+		backoff = time.Second
+		if len(changes.Changes) == 0 {
+			continue
+		}
+
+		done := make(chan struct{})
+		select {
+		case remote <- done:
+		case <-ctx.Done():
+			return
+		}
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (e *Engine) cursor() string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.state.Cursor
+}
+
+func (e *Engine) setCursor(cursor string) {
+	e.mu.Lock()
+	e.state.Cursor = cursor
+	e.mu.Unlock()
+}
+
+// localPath turns a state path into a file system path
+func (e *Engine) localPath(p string) string {
+	return filepath.Join(e.root, filepath.FromSlash(p))
+}
+
+// sleep waits for d, reporting false if ctx ended first
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
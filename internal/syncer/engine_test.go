@@ -0,0 +1,607 @@
+package syncer_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+
+	"drive/internal/handler"
+	"drive/internal/middleware"
+	"drive/internal/model"
+	"drive/internal/routes"
+	"drive/internal/service"
+	"drive/internal/syncer"
+	"drive/internal/util"
+	"drive/pkg/client"
+)
+
+const (
+	token  = "sync-token"
+	userID = 1
+	rootID = 1
+)
+
+// fakeDrive is an in-memory drive for a single user, served through the
+// real routes and handlers. Every change is journaled like the real
+// services do.
+type fakeDrive struct {
+	mu      sync.Mutex
+	now     time.Time
+	nextID  uint
+	folders map[uint]*model.Folder
+	files   map[uint]*model.File
+	content map[uint][]byte
+	changes []model.Change
+	// lag hides new changes from the feed, as if they were journaled just
+	// after the engine read it
+	lag bool
+}
+
+func newFakeDrive() *fakeDrive {
+	return &fakeDrive{
+		now:     time.Date(2026, 3, 1, 9, 0, 0, 123456000, time.UTC),
+		nextID:  rootID + 1,
+		folders: map[uint]*model.Folder{rootID: {ID: rootID, FolderName: "/", UserID: userID}},
+		files:   make(map[uint]*model.File),
+		content: make(map[uint][]byte),
+	}
+}
+
+// tick moves the clock forward so every write gets a distinct time
+func (d *fakeDrive) tick() time.Time {
+	d.now = d.now.Add(time.Second)
+	return d.now
+}
+
+func (d *fakeDrive) id() uint {
+	d.nextID++
+	return d.nextID - 1
+}
+
+func (d *fakeDrive) journal(change model.ChangeType, itemType model.ChangeItemType, id, parentID uint, name string, size int64, modified time.Time) {
+	d.changes = append(d.changes, model.Change{
+		ID:         uint(len(d.changes) + 1),
+		UserID:     userID,
+		Change:     change,
+		ItemType:   itemType,
+		ItemID:     id,
+		ParentID:   &parentID,
+		Name:       name,
+		Size:       size,
+		ModifiedAt: modified,
+	})
+}
+
+// put stores a file directly, as another client would
+func (d *fakeDrive) put(folderID uint, name, content string) *model.File {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.create(folderID, name, []byte(content))
+}
+
+func (d *fakeDrive) create(folderID uint, name string, content []byte) *model.File {
+	now := d.tick()
+	file := &model.File{ID: d.id(), FileName: name, FileSize: int64(len(content)), FolderID: folderID, UserID: userID, CreatedAt: now, UpdatedAt: now}
+	d.files[file.ID] = file
+	d.content[file.ID] = content
+	d.journal(model.ChangeCreated, model.ChangeItemFile, file.ID, folderID, name, file.FileSize, now)
+	return file
+}
+
+// edit replaces a file's content directly, as another client would
+func (d *fakeDrive) edit(id uint, content string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.replace(d.files[id], []byte(content))
+}
+
+func (d *fakeDrive) replace(file *model.File, content []byte) {
+	file.UpdatedAt = d.tick()
+	file.FileSize = int64(len(content))
+	d.content[file.ID] = content
+	d.journal(model.ChangeModified, model.ChangeItemFile, file.ID, file.FolderID, file.FileName, file.FileSize, file.UpdatedAt)
+}
+
+// remove deletes a file directly, as another client would
+func (d *fakeDrive) remove(id uint) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deleteFile(d.files[id])
+}
+
+func (d *fakeDrive) deleteFile(file *model.File) {
+	delete(d.files, file.ID)
+	delete(d.content, file.ID)
+	d.journal(model.ChangeDeleted, model.ChangeItemFile, file.ID, file.FolderID, file.FileName, file.FileSize, d.tick())
+}
+
+// lookup finds a file by its path below the root folder
+func (d *fakeDrive) lookup(p string) (*model.File, string, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	folderID := uint(rootID)
+	parts := strings.Split(p, "/")
+	for _, name := range parts[:len(parts)-1] {
+		found := false
+		for _, folder := range d.folders {
+			if folder.ParentFolderID != nil && *folder.ParentFolderID == folderID && folder.FolderName == name {
+				folderID, found = folder.ID, true
+				break
+			}
+		}
+		if !found {
+			return nil, "", false
+		}
+	}
+	for _, file := range d.files {
+		if file.FolderID == folderID && file.FileName == parts[len(parts)-1] {
+			return file, string(d.content[file.ID]), true
+		}
+	}
+	return nil, "", false
+}
+
+// names lists the files in a folder
+func (d *fakeDrive) names(folderID uint) []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var names []string
+	for _, file := range d.files {
+		if file.FolderID == folderID {
+			names = append(names, file.FileName)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+type driveFolders struct {
+	service.FolderService
+	*fakeDrive
+}
+
+func (d driveFolders) Create(ctx context.Context, userID uint, req *model.CreateFolderRequest) (*model.Folder, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	parentID := req.ParentID
+	if parentID == 0 {
+		parentID = rootID
+	}
+	if d.folders[parentID] == nil {
+		return nil, service.ErrFolderNotFound
+	}
+	now := d.tick()
+	folder := &model.Folder{ID: d.id(), FolderName: req.Name, ParentFolderID: &parentID, UserID: userID, CreatedAt: now, UpdatedAt: now}
+	d.folders[folder.ID] = folder
+	d.journal(model.ChangeCreated, model.ChangeItemFolder, folder.ID, parentID, folder.FolderName, 0, now)
+	copied := *folder
+	return &copied, nil
+}
+
+func (d driveFolders) Get(ctx context.Context, userID, folderID uint) (*model.FolderContents, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if folderID == 0 {
+		folderID = rootID
+	}
+	folder := d.folders[folderID]
+	if folder == nil {
+		return nil, service.ErrFolderNotFound
+	}
+	contents := &model.FolderContents{Folder: folder, Permission: model.PermissionOwner, Folders: []model.Folder{}, Files: []model.File{}}
+	for _, child := range d.folders {
+		if child.ParentFolderID != nil && *child.ParentFolderID == folderID {
+			contents.Folders = append(contents.Folders, *child)
+		}
+	}
+	for _, file := range d.files {
+		if file.FolderID == folderID {
+			contents.Files = append(contents.Files, *file)
+		}
+	}
+	sort.Slice(contents.Folders, func(i, j int) bool { return contents.Folders[i].ID < contents.Folders[j].ID })
+	sort.Slice(contents.Files, func(i, j int) bool { return contents.Files[i].ID < contents.Files[j].ID })
+	return contents, nil
+}
+
+func (d driveFolders) Delete(ctx context.Context, userID, folderID uint) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	folder := d.folders[folderID]
+	if folder == nil || folder.ParentFolderID == nil {
+		return service.ErrFolderNotFound
+	}
+	d.deleteFolder(folderID)
+	d.journal(model.ChangeDeleted, model.ChangeItemFolder, folder.ID, *folder.ParentFolderID, folder.FolderName, 0, d.tick())
+	return nil
+}
+
+func (d driveFolders) deleteFolder(folderID uint) {
+	for id, file := range d.files {
+		if file.FolderID == folderID {
+			delete(d.files, id)
+			delete(d.content, id)
+		}
+	}
+	for id, child := range d.folders {
+		if child.ParentFolderID != nil && *child.ParentFolderID == folderID {
+			d.deleteFolder(id)
+		}
+	}
+	delete(d.folders, folderID)
+}
+
+type driveFiles struct {
+	service.FileService
+	*fakeDrive
+}
+
+func (d driveFiles) Upload(ctx context.Context, userID, folderID uint, fileName string, size int64, r io.Reader) (*model.File, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if folderID == 0 {
+		folderID = rootID
+	}
+	if d.folders[folderID] == nil {
+		return nil, service.ErrFolderNotFound
+	}
+	file := *d.create(folderID, fileName, content)
+	return &file, nil
+}
+
+func (d driveFiles) GetFile(ctx context.Context, userID, fileID uint) (*model.File, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	file := d.files[fileID]
+	if file == nil {
+		return nil, service.ErrFileNotFound
+	}
+	copied := *file
+	return &copied, nil
+}
+
+func (d driveFiles) Download(ctx context.Context, userID, fileID uint) (*model.File, io.ReadSeekCloser, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	file := d.files[fileID]
+	if file == nil {
+		return nil, nil, service.ErrFileNotFound
+	}
+	copied := *file
+	return &copied, readSeekNopCloser{bytes.NewReader(d.content[fileID])}, nil
+}
+
+func (d driveFiles) Replace(ctx context.Context, userID, fileID uint, size int64, r io.Reader, ifUpdatedAt *time.Time) (*model.File, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	file := d.files[fileID]
+	if file == nil {
+		return nil, service.ErrFileNotFound
+	}
+	if ifUpdatedAt != nil && !ifUpdatedAt.Truncate(time.Microsecond).Equal(file.UpdatedAt.Truncate(time.Microsecond)) {
+		return nil, service.ErrFileModified
+	}
+	d.replace(file, content)
+	copied := *file
+	return &copied, nil
+}
+
+func (d driveFiles) Update(ctx context.Context, userID, fileID uint, req *model.UpdateFileRequest) (*model.File, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	file := d.files[fileID]
+	if file == nil {
+		return nil, service.ErrFileNotFound
+	}
+	if req.Name != nil {
+		file.FileName = *req.Name
+	}
+	if req.FolderID != nil {
+		if d.folders[*req.FolderID] == nil {
+			return nil, service.ErrFolderNotFound
+		}
+		file.FolderID = *req.FolderID
+	}
+	file.UpdatedAt = d.tick()
+	d.journal(model.ChangeMoved, model.ChangeItemFile, file.ID, file.FolderID, file.FileName, file.FileSize, file.UpdatedAt)
+	copied := *file
+	return &copied, nil
+}
+
+func (d driveFiles) Delete(ctx context.Context, userID, fileID uint) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	file := d.files[fileID]
+	if file == nil {
+		return service.ErrFileNotFound
+	}
+	d.deleteFile(file)
+	return nil
+}
+
+type driveChanges struct {
+	service.ChangeService
+	*fakeDrive
+}
+
+func (d driveChanges) List(ctx context.Context, userID uint, filter *model.ChangeFilter) (*model.ChangeSet, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	after := 0
+	if filter.Cursor != "" {
+		var err error
+		if after, err = strconv.Atoi(filter.Cursor); err != nil || after > len(d.changes) {
+			return nil, service.ErrInvalidCursor
+		}
+	}
+	set := &model.ChangeSet{Changes: []model.Change{}, Cursor: strconv.Itoa(after)}
+	if d.lag {
+		return set, nil
+	}
+	end := len(d.changes)
+	if filter.Limit > 0 && after+filter.Limit < end {
+		end, set.HasMore = after+filter.Limit, true
+	}
+	set.Changes = append(set.Changes, d.changes[after:end]...)
+	set.Cursor = strconv.Itoa(end)
+	return set, nil
+}
+
+func (d driveChanges) Latest(ctx context.Context, userID uint) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return strconv.Itoa(len(d.changes)), nil
+}
+
+type readSeekNopCloser struct {
+	io.ReadSeeker
+}
+
+func (readSeekNopCloser) Close() error { return nil }
+
+type stubAuth struct {
+	service.AuthService
+}
+
+func (stubAuth) GetUserByToken(ctx context.Context, t string) (*model.User, error) {
+	if t != token {
+		return nil, errors.New("invalid token")
+	}
+	return &model.User{ID: userID}, nil
+}
+
+// newServer serves the drive through the routes the sync engine uses
+func newServer(t *testing.T, d *fakeDrive) *httptest.Server {
+	t.Helper()
+	h := &handler.Handler{
+		FileHandler:   handler.NewFileHandler(driveFiles{fakeDrive: d}),
+		FolderHandler: handler.NewFolderHandler(driveFolders{fakeDrive: d}),
+		ChangeHandler: handler.NewChangeHandler(driveChanges{fakeDrive: d}),
+	}
+	r := chi.NewRouter()
+	r.Route("/api", func(r chi.Router) {
+		r.Use(middleware.Auth(stubAuth{}))
+		routes.FileRoutes(r, h)
+		routes.FolderRoutes(r, h)
+		routes.ChangeRoutes(r, h)
+	})
+	server := httptest.NewServer(r)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// newEngine syncs a fresh temporary directory with the drive's root folder
+func newEngine(t *testing.T, server *httptest.Server) (*syncer.Engine, string) {
+	t.Helper()
+	dir := t.TempDir()
+	c := client.New(server.URL, client.WithTokens(client.Tokens{AccessToken: token}))
+	engine, err := syncer.New(c, syncer.Options{
+		LocalDir:       dir,
+		StateDir:       t.TempDir(),
+		DisableWatcher: true,
+		Hostname:       "testhost",
+	}, &util.Logger{Logger: zap.NewNop()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { engine.Close() })
+	return engine, dir
+}
+
+func syncOnce(t *testing.T, engine *syncer.Engine) {
+	t.Helper()
+	if err := engine.SyncOnce(context.Background()); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertLocal(t *testing.T, name, want string) {
+	t.Helper()
+	got, err := os.ReadFile(name)
+	if err != nil {
+		t.Errorf("reading %s: %v", name, err)
+		return
+	}
+	if string(got) != want {
+		t.Errorf("%s = %q, want %q", name, got, want)
+	}
+}
+
+func assertRemote(t *testing.T, d *fakeDrive, p, want string) {
+	t.Helper()
+	_, got, ok := d.lookup(p)
+	if !ok {
+		t.Errorf("%s is missing remotely", p)
+		return
+	}
+	if got != want {
+		t.Errorf("remote %s = %q, want %q", p, got, want)
+	}
+}
+
+// conflictedCopies returns the conflicted copies of name.ext in dir
+func conflictedCopies(t *testing.T, dir, name, ext string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, name+" (conflicted copy * testhost)"+ext))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestSyncInitial(t *testing.T) {
+	d := newFakeDrive()
+	d.put(rootID, "remote.txt", "from the server")
+	server := newServer(t, d)
+	engine, dir := newEngine(t, server)
+
+	writeFile(t, filepath.Join(dir, "local.txt"), "from the workstation")
+	writeFile(t, filepath.Join(dir, "docs", "report.md"), "# Report")
+
+	syncOnce(t, engine)
+
+	assertLocal(t, filepath.Join(dir, "remote.txt"), "from the server")
+	assertRemote(t, d, "local.txt", "from the workstation")
+	assertRemote(t, d, "docs/report.md", "# Report")
+	if _, err := os.Stat(filepath.Join(dir, ".drive-sync")); !os.IsNotExist(err) {
+		t.Errorf("state directory created in the synced directory: %v", err)
+	}
+
+	// A second cycle with nothing new changes nothing
+	journaled := len(d.changes)
+	syncOnce(t, engine)
+	if len(d.changes) != journaled {
+		t.Errorf("idle sync made %d remote changes", len(d.changes)-journaled)
+	}
+}
+
+func TestSyncPullsRemoteChanges(t *testing.T) {
+	d := newFakeDrive()
+	edited := d.put(rootID, "edited.txt", "v1")
+	deleted := d.put(rootID, "deleted.txt", "bye")
+	server := newServer(t, d)
+	engine, dir := newEngine(t, server)
+	syncOnce(t, engine)
+
+	d.edit(edited.ID, "v2")
+	d.remove(deleted.ID)
+	d.put(rootID, "new.txt", "hi")
+	syncOnce(t, engine)
+
+	assertLocal(t, filepath.Join(dir, "edited.txt"), "v2")
+	assertLocal(t, filepath.Join(dir, "new.txt"), "hi")
+	if _, err := os.Stat(filepath.Join(dir, "deleted.txt")); !os.IsNotExist(err) {
+		t.Errorf("file deleted on the server is still there: %v", err)
+	}
+}
+
+func TestSyncPushesLocalChanges(t *testing.T) {
+	d := newFakeDrive()
+	d.put(rootID, "edited.txt", "v1")
+	d.put(rootID, "deleted.txt", "bye")
+	d.put(rootID, "old name.txt", "same content")
+	server := newServer(t, d)
+	engine, dir := newEngine(t, server)
+	syncOnce(t, engine)
+	renamed, _, _ := d.lookup("old name.txt")
+
+	writeFile(t, filepath.Join(dir, "edited.txt"), "v2 is longer")
+	if err := os.Remove(filepath.Join(dir, "deleted.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Rename(filepath.Join(dir, "old name.txt"), filepath.Join(dir, "new name.txt")); err != nil {
+		t.Fatal(err)
+	}
+	syncOnce(t, engine)
+
+	assertRemote(t, d, "edited.txt", "v2 is longer")
+	if _, _, ok := d.lookup("deleted.txt"); ok {
+		t.Error("file deleted locally is still on the server")
+	}
+	if file, _, ok := d.lookup("new name.txt"); !ok || file.ID != renamed.ID {
+		t.Errorf("rename was not pushed as a rename: %+v", file)
+	}
+	if got, want := d.names(rootID), []string{"edited.txt", "new name.txt"}; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("remote files = %q, want %q", got, want)
+	}
+}
+
+func TestSyncConflicts(t *testing.T) {
+	tests := []struct {
+		name string
+		// lag hides the remote edit from the change feed until after the
+		// push, so the conflict is found by the upload's precondition
+		// rather than by the pull
+		lag bool
+	}{
+		{name: "found by pull"},
+		{name: "found by upload", lag: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := newFakeDrive()
+			notes := d.put(rootID, "notes.txt", "original")
+			server := newServer(t, d)
+			engine, dir := newEngine(t, server)
+			syncOnce(t, engine)
+
+			d.mu.Lock()
+			d.lag = tt.lag
+			d.mu.Unlock()
+			d.edit(notes.ID, "remote edit")
+			writeFile(t, filepath.Join(dir, "notes.txt"), "local edit!")
+			syncOnce(t, engine)
+
+			d.mu.Lock()
+			d.lag = false
+			d.mu.Unlock()
+			syncOnce(t, engine)
+
+			// Neither side's edit is lost: the remote version keeps the
+			// name and the local one becomes a conflicted copy on both sides
+			assertLocal(t, filepath.Join(dir, "notes.txt"), "remote edit")
+			assertRemote(t, d, "notes.txt", "remote edit")
+			copies := conflictedCopies(t, dir, "notes", ".txt")
+			if len(copies) != 1 {
+				t.Fatalf("conflicted copies = %q, want one", copies)
+			}
+			assertLocal(t, copies[0], "local edit!")
+			assertRemote(t, d, filepath.Base(copies[0]), "local edit!")
+			if got := len(d.names(rootID)); got != 2 {
+				t.Errorf("remote has %d files, want 2", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,449 @@
+package syncer
+
+import (
+	"context"
+	"crypto/sha256"
+	"drive/internal/util"
+	"drive/pkg/client"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// localItem is a file or directory found by a scan
+type localItem struct {
+	dir     bool
+	size    int64
+	modTime int64
+}
+
+// scan walks the directory, skipping the state directory, symbolic links
+// and anything else that is not a regular file or directory
+func (e *Engine) scan() (map[string]localItem, error) {
+	items := make(map[string]localItem)
+	err := filepath.WalkDir(e.root, func(local string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if local == e.root {
+				return err
+			}
+			// Vanished or unreadable mid-walk; the next scan will see it
+			e.logger.Debug("Skipping unreadable path", util.WithPath(local), util.WithError(err))
+			return nil
+		}
+		if local == e.root {
+			return nil
+		}
+		if local == e.stateDir {
+			return filepath.SkipDir
+		}
+		rel, err := filepath.Rel(e.root, local)
+		if err != nil {
+			return err
+		}
+		p := filepath.ToSlash(rel)
+		if strings.Contains(d.Name(), "\\") {
+			// The server treats backslashes as separators
+			e.logger.Warn("Skipping file name the server cannot store", util.WithPath(p))
+			return skip(d)
+		}
+
+		switch {
+		case d.IsDir():
+			items[p] = localItem{dir: true}
+		case d.Type().IsRegular():
+			info, err := d.Info()
+			if err != nil {
+				return nil
+			}
+			items[p] = localItem{size: info.Size(), modTime: info.ModTime().UnixNano()}
+		default:
+			e.logger.Debug("Skipping special file", util.WithPath(p))
+		}
+		return nil
+	})
+	return items, err
+}
+
+// push uploads what changed in the directory since the last sync: new and
+// modified files, new directories, renames and deletions
+func (e *Engine) push(ctx context.Context) error {
+	items, err := e.scan()
+	if err != nil {
+		return fmt.Errorf("scanning %s: %w", e.root, err)
+	}
+	e.watch(items)
+
+	// Synced items that are gone, or were replaced by something of the
+	// other kind, are deleted remotely unless they turn out to be renames
+	missing := make(map[string]*entry)
+	for _, known := range e.state.sorted() {
+		if item, ok := items[known.Path]; !ok || item.dir != known.Dir {
+			missing[known.Path] = known
+		}
+	}
+
+	paths := make([]string, 0, len(items))
+	for p := range items {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		item := items[p]
+		known := e.state.get(p)
+		if known != nil && missing[p] == nil {
+			if !item.dir {
+				if err := e.pushModified(ctx, known, item); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		parentPath, name := splitPath(p)
+		parentID, ok := e.state.folderID(parentPath)
+		if !ok {
+			// The parent could not be created; try again next cycle
+			continue
+		}
+		if item.dir {
+			if err := e.pushDir(ctx, p, parentID, name); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := e.pushNew(ctx, p, item, parentID, name, missing); err != nil {
+			return err
+		}
+	}
+
+	return e.pushDeletions(ctx, missing)
+}
+
+// pushDir creates the remote folder for a new directory
+func (e *Engine) pushDir(ctx context.Context, p string, parentID uint, name string) error {
+	if old := e.state.get(p); old != nil {
+		// A file was replaced by a directory of the same name
+		e.state.remove(p)
+		if err := e.client.DeleteFile(ctx, old.ID); err != nil && !client.IsNotFound(err) {
+			return fmt.Errorf("deleting %s: %w", p, err)
+		}
+	}
+	folder, err := e.client.CreateFolder(ctx, parentID, name)
+	if err != nil {
+		return fmt.Errorf("creating folder %s: %w", p, err)
+	}
+	e.state.put(&entry{
+		Path:     p,
+		Dir:      true,
+		ID:       folder.ID,
+		ParentID: parentID,
+		Name:     folder.FolderName,
+		Modified: folder.UpdatedAt,
+	})
+	e.logger.Info("Created folder", util.WithPath(p))
+	return nil
+}
+
+// pushNew uploads a new file, or renames the remote file when the content
+// matches a file that disappeared locally
+func (e *Engine) pushNew(ctx context.Context, p string, item localItem, parentID uint, name string, missing map[string]*entry) error {
+	hash, err := hashFile(e.localPath(p))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if old := e.state.get(p); old != nil && old.Dir {
+		// A directory was replaced by a file; remove the folder first so
+		// the names do not clash
+		if err := e.deleteRemote(ctx, old); err != nil {
+			return err
+		}
+		delete(missing, p)
+	}
+
+	if renamed := findRenamed(missing, item, hash); renamed != nil {
+		req := &client.UpdateFileRequest{}
+		if renamed.Name != name {
+			req.Name = &name
+		}
+		if renamed.ParentID != parentID {
+			req.FolderID = &parentID
+		}
+		file, err := e.client.UpdateFile(ctx, renamed.ID, req)
+		switch {
+		case err == nil:
+			delete(missing, renamed.Path)
+			e.logger.Info("Moved", util.WithPath(renamed.Path), zap.String("to", p))
+			e.state.move(renamed.Path, p)
+			renamed.ParentID, renamed.Name, renamed.Modified = parentID, file.FileName, file.UpdatedAt
+			renamed.ModTime = item.modTime
+			return nil
+		case client.IsNotFound(err):
+			// Gone remotely as well; upload it as a new file instead
+			delete(missing, renamed.Path)
+			e.state.remove(renamed.Path)
+		default:
+			return fmt.Errorf("moving %s to %s: %w", renamed.Path, p, err)
+		}
+	}
+
+	return e.upload(ctx, p, item, parentID, name, hash)
+}
+
+// upload sends a local file as a new remote file
+func (e *Engine) upload(ctx context.Context, p string, item localItem, parentID uint, name, hash string) error {
+	f, err := os.Open(e.localPath(p))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	file, err := e.client.Upload(ctx, parentID, name, item.size, f)
+	if err != nil {
+		return fmt.Errorf("uploading %s: %w", p, err)
+	}
+	e.state.put(&entry{
+		Path:     p,
+		ID:       file.ID,
+		ParentID: parentID,
+		Name:     file.FileName,
+		Modified: file.UpdatedAt,
+		Size:     item.size,
+		ModTime:  item.modTime,
+		Hash:     hash,
+	})
+	e.logger.Info("Uploaded", util.WithPath(p))
+	return nil
+}
+
+// pushModified replaces the remote content of a synced file that changed
+// locally. If the remote file changed too, the local version is kept as a
+// conflicted copy and the remote version downloaded in its place.
+func (e *Engine) pushModified(ctx context.Context, known *entry, item localItem) error {
+	if known.Size == item.size && known.ModTime == item.modTime {
+		return nil
+	}
+	hash, err := hashFile(e.localPath(known.Path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if hash == known.Hash {
+		// Touched but not changed
+		known.Size, known.ModTime = item.size, item.modTime
+		return nil
+	}
+
+	f, err := os.Open(e.localPath(known.Path))
+	if err != nil {
+		return err
+	}
+	file, err := e.client.ReplaceContent(ctx, known.ID, known.etag(), item.size, f)
+	f.Close()
+	switch {
+	case err == nil:
+		known.Size, known.ModTime, known.Hash, known.Modified = item.size, item.modTime, hash, file.UpdatedAt
+		e.logger.Info("Uploaded changes", util.WithPath(known.Path))
+		return nil
+
+	case client.IsModified(err):
+		// Edited on both sides since the last sync
+		remote, err := e.client.File(ctx, known.ID)
+		if err != nil {
+			return fmt.Errorf("reading %s: %w", known.Path, err)
+		}
+		if err := e.keepConflicted(known.Path); err != nil {
+			return err
+		}
+		return e.fetch(ctx, known.Path, remoteFile{
+			ID:       remote.ID,
+			ParentID: remote.FolderID,
+			Name:     remote.FileName,
+			Size:     remote.FileSize,
+			Modified: remote.UpdatedAt,
+		})
+
+	case client.IsNotFound(err):
+		// Deleted remotely while edited here: keep the edits as a new file
+		p, parentID, name := known.Path, known.ParentID, known.Name
+		e.state.remove(p)
+		return e.upload(ctx, p, item, parentID, name, hash)
+
+	case client.IsLocked(err):
+		// Checked out by someone else: try again on a later pass
+		e.logger.Warn("Skipping locked file", util.WithPath(known.Path))
+		return nil
+
+	default:
+		return fmt.Errorf("uploading changes to %s: %w", known.Path, err)
+	}
+}
+
+// pushDeletions deletes remotely what was deleted locally. Only the topmost
+// missing item of a deleted tree is deleted; the server trashes the rest.
+func (e *Engine) pushDeletions(ctx context.Context, missing map[string]*entry) error {
+	paths := make([]string, 0, len(missing))
+	for p := range missing {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		known := missing[p]
+		if e.state.get(p) != known {
+			// Already handled along with a missing parent
+			continue
+		}
+		if err := e.deleteRemote(ctx, known); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteRemote moves a synced item to the trash and forgets it along with
+// everything beneath it
+func (e *Engine) deleteRemote(ctx context.Context, known *entry) error {
+	var err error
+	if known.Dir {
+		err = e.client.DeleteFolder(ctx, known.ID)
+	} else {
+		err = e.client.DeleteFile(ctx, known.ID)
+	}
+	if err != nil && !client.IsNotFound(err) {
+		return fmt.Errorf("deleting %s: %w", known.Path, err)
+	}
+	e.state.remove(known.Path)
+	e.logger.Info("Deleted", util.WithPath(known.Path))
+	return nil
+}
+
+// keepConflicted moves the local item at p aside under a conflicted copy
+// name, to be uploaded as a new item
+func (e *Engine) keepConflicted(p string) error {
+	copyPath := conflictedCopyPath(p, e.opts.Hostname, time.Now())
+	for n := 2; ; n++ {
+		if _, err := os.Lstat(e.localPath(copyPath)); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		copyPath = conflictedCopyPath(p, fmt.Sprintf("%s %d", e.opts.Hostname, n), time.Now())
+	}
+	if err := os.Rename(e.localPath(p), e.localPath(copyPath)); err != nil {
+		return fmt.Errorf("keeping conflicted copy of %s: %w", p, err)
+	}
+	e.logger.Warn("Conflict: kept local version as a copy", util.WithPath(p), zap.String("copy", copyPath))
+	e.rescan = true
+	return nil
+}
+
+// conflictedCopyPath names the copy kept when a file changed on both sides,
+// e.g. "report (conflicted copy 2024-05-01 153000 laptop).pdf"
+func conflictedCopyPath(p, hostname string, at time.Time) string {
+	ext := path.Ext(p)
+	if strings.Contains(ext, "/") || ext == p {
+		ext = ""
+	}
+	label := "conflicted copy " + at.Format("2006-01-02 150405")
+	if hostname != "" {
+		label += " " + hostname
+	}
+	return strings.TrimSuffix(p, ext) + " (" + label + ")" + ext
+}
+
+// unchangedSinceSync reports whether the local file at p still looks the way
+// it did when it was last synced
+func (e *Engine) unchangedSinceSync(p string, info os.FileInfo) bool {
+	known := e.state.get(p)
+	if known == nil || known.Dir || !info.Mode().IsRegular() {
+		return false
+	}
+	if known.Size == info.Size() && known.ModTime == info.ModTime().UnixNano() {
+		return true
+	}
+	hash, err := hashFile(e.localPath(p))
+	return err == nil && hash == known.Hash
+}
+
+// watch asks the watcher to cover every directory found by a scan. If that
+// fails, e.g. because the watch limit was reached, the engine falls back to
+// polling.
+func (e *Engine) watch(items map[string]localItem) {
+	if e.watcher == nil {
+		return
+	}
+	dirs := []string{e.root}
+	for p, item := range items {
+		if item.dir {
+			dirs = append(dirs, e.localPath(p))
+		}
+	}
+	if err := e.watcher.Watch(dirs); err != nil {
+		e.logger.Warn("File system notifications failed, polling instead", util.WithError(err))
+		e.watcher.Close()
+		e.watcher = nil
+	}
+}
+
+// findRenamed returns a missing file with the given content, if any
+func findRenamed(missing map[string]*entry, item localItem, hash string) *entry {
+	var found *entry
+	for _, known := range missing {
+		if known.Dir || known.Size != item.size || known.Hash != hash {
+			continue
+		}
+		if found == nil || known.Path < found.Path {
+			found = known
+		}
+	}
+	return found
+}
+
+// hashFile returns the hex SHA-256 of a file's content
+func hashFile(name string) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// splitPath splits a state path into its parent path and name
+func splitPath(p string) (string, string) {
+	dir, name := path.Split(p)
+	return strings.TrimSuffix(dir, "/"), name
+}
+
+// skip stops a walk from descending into a directory
+func skip(d fs.DirEntry) error {
+	if d.IsDir() {
+		return filepath.SkipDir
+	}
+	return nil
+}
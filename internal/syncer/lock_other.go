@@ -0,0 +1,15 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package syncer
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// lockDir opens the lock file without locking it; advisory locks are not
+// available here, so running two engines on one directory is not prevented
+func lockDir(dir string) (io.Closer, error) {
+	return os.OpenFile(filepath.Join(dir, "lock"), os.O_CREATE|os.O_RDWR, 0o600)
+}
@@ -0,0 +1,363 @@
+package syncer
+
+import (
+	"context"
+	"crypto/sha256"
+	"drive/internal/util"
+	"drive/pkg/client"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// remoteFile is the version of a remote file to bring down
+type remoteFile struct {
+	ID       uint
+	ParentID uint
+	Name     string
+	Size     int64
+	Modified time.Time
+}
+
+// initialSync mirrors the whole remote folder into the directory the first
+// time the engine runs. Local files that differ from their remote namesakes
+// become conflicted copies; local-only items are uploaded by the push that
+// follows.
+func (e *Engine) initialSync(ctx context.Context) error {
+	// Take the cursor before walking so nothing that changes during the
+	// walk is missed; replaying those changes afterwards is harmless
+	cursor, err := e.client.LatestCursor(ctx)
+	if err != nil {
+		return fmt.Errorf("reading change feed position: %w", err)
+	}
+	root, err := e.client.MkdirAll(ctx, e.opts.RemotePath)
+	if err != nil {
+		return fmt.Errorf("resolving remote folder %s: %w", e.opts.RemotePath, err)
+	}
+	e.state.RootID = root.ID
+	e.logger.Info("Starting initial sync", zap.String("remote", e.opts.RemotePath))
+
+	queue := []uint{root.ID}
+	for len(queue) > 0 {
+		folderID := queue[0]
+		queue = queue[1:]
+		contents, err := e.client.Folder(ctx, folderID)
+		if err != nil {
+			return fmt.Errorf("listing remote folder %d: %w", folderID, err)
+		}
+		parentPath, _ := e.state.folderPath(folderID)
+
+		for _, folder := range contents.Folders {
+			if folder.VaultID != nil {
+				// Vault content is end-to-end encrypted and not synced
+				continue
+			}
+			if err := e.pullFolder(parentPath, &folder); err != nil {
+				return err
+			}
+			queue = append(queue, folder.ID)
+		}
+		for _, file := range contents.Files {
+			rf := remoteFile{ID: file.ID, ParentID: file.FolderID, Name: file.FileName, Size: file.FileSize, Modified: file.UpdatedAt}
+			if err := e.fetch(ctx, e.placement(parentPath, rf.Name, false, rf.ID), rf); err != nil {
+				return err
+			}
+		}
+	}
+
+	e.setCursor(cursor)
+	return e.state.save()
+}
+
+// pullFolder creates the local directory for a remote folder
+func (e *Engine) pullFolder(parentPath string, folder *client.Folder) error {
+	p := e.placement(parentPath, folder.FolderName, true, folder.ID)
+	if err := e.makeDir(p); err != nil {
+		return err
+	}
+	e.state.put(&entry{
+		Path:     p,
+		Dir:      true,
+		ID:       folder.ID,
+		ParentID: *folder.ParentFolderID,
+		Name:     folder.FolderName,
+		Modified: folder.UpdatedAt,
+	})
+	return nil
+}
+
+// pull applies the change feed to the directory, saving the cursor after
+// each page
+func (e *Engine) pull(ctx context.Context) error {
+	for {
+		changes, err := e.client.Changes(ctx, e.cursor(), changePageSize, 0)
+		if err != nil {
+			return fmt.Errorf("reading change feed: %w", err)
+		}
+		for i := range changes.Changes {
+			if err := e.apply(ctx, &changes.Changes[i]); err != nil {
+				return err
+			}
+		}
+		e.setCursor(changes.Cursor)
+		if err := e.state.save(); err != nil {
+			return err
+		}
+		if !changes.HasMore {
+			return nil
+		}
+	}
+}
+
+// apply brings one remote change into the directory
+func (e *Engine) apply(ctx context.Context, c *client.Change) error {
+	dir := c.ItemType == "folder"
+	known := e.state.lookup(dir, c.ItemID)
+
+	parentPath, inScope := "", false
+	if c.ParentID != nil {
+		parentPath, inScope = e.state.folderPath(*c.ParentID)
+	}
+	if c.Change == "deleted" || !inScope {
+		// Deleted, or moved somewhere outside the synced folder
+		if known != nil {
+			return e.removeLocal(known)
+		}
+		return nil
+	}
+
+	if known != nil && (known.Name != c.Name || known.ParentID != *c.ParentID) {
+		if err := e.moveLocal(known, e.placement(parentPath, c.Name, dir, c.ItemID)); err != nil {
+			return err
+		}
+		known.Name, known.ParentID, known.Modified = c.Name, *c.ParentID, c.ModifiedAt
+		// A rename or move never changes the content
+		return nil
+	}
+
+	if dir {
+		if known != nil {
+			known.Modified = c.ModifiedAt
+			return nil
+		}
+		// The change feed does not say whether a new folder is a vault
+		contents, err := e.client.Folder(ctx, c.ItemID)
+		if client.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("reading remote folder %d: %w", c.ItemID, err)
+		}
+		if contents.Folder.VaultID != nil {
+			return nil
+		}
+		return e.pullFolder(parentPath, &client.Folder{
+			ID:             c.ItemID,
+			FolderName:     c.Name,
+			ParentFolderID: c.ParentID,
+			UpdatedAt:      c.ModifiedAt,
+		})
+	}
+
+	if known != nil && known.Size == c.Size && known.Modified.Truncate(time.Microsecond).Equal(c.ModifiedAt.Truncate(time.Microsecond)) {
+		// Already have this version, typically our own upload coming back
+		return nil
+	}
+	p := e.placement(parentPath, c.Name, false, c.ItemID)
+	return e.fetch(ctx, p, remoteFile{ID: c.ItemID, ParentID: *c.ParentID, Name: c.Name, Size: c.Size, Modified: c.ModifiedAt})
+}
+
+// fetch downloads a remote file to path p. A local file in the way that was
+// changed since the last sync, or was never synced and differs, is kept as
+// a conflicted copy.
+func (e *Engine) fetch(ctx context.Context, p string, rf remoteFile) error {
+	tmp, hash, err := e.download(ctx, rf.ID)
+	if client.IsNotFound(err) {
+		// Gone already; its deletion is further down the change feed
+		return nil
+	}
+	if client.IsForbidden(err) {
+		// Quarantined by the malware scanner
+		e.logger.Warn("Skipping quarantined file", util.WithPath(p))
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("downloading %s: %w", p, err)
+	}
+	defer os.Remove(tmp)
+
+	local := e.localPath(p)
+	info, err := os.Lstat(local)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	case info.IsDir():
+		if err := e.keepConflicted(p); err != nil {
+			return err
+		}
+	default:
+		if !e.unchangedSinceSync(p, info) {
+			localHash, err := hashFile(local)
+			if err != nil {
+				return err
+			}
+			if localHash != hash {
+				if err := e.keepConflicted(p); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if err := os.MkdirAll(filepath.Dir(local), 0o755); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, local); err != nil {
+		return err
+	}
+	if !rf.Modified.IsZero() {
+		os.Chtimes(local, time.Now(), rf.Modified)
+	}
+	info, err = os.Stat(local)
+	if err != nil {
+		return err
+	}
+
+	e.state.put(&entry{
+		Path:     p,
+		ID:       rf.ID,
+		ParentID: rf.ParentID,
+		Name:     rf.Name,
+		Modified: rf.Modified,
+		Size:     info.Size(),
+		ModTime:  info.ModTime().UnixNano(),
+		Hash:     hash,
+	})
+	e.logger.Info("Downloaded", util.WithPath(p))
+	return nil
+}
+
+// download saves a remote file's content to a temporary file in the state
+// directory, returning its path and hash
+func (e *Engine) download(ctx context.Context, id uint) (string, string, error) {
+	body, _, err := e.client.Download(ctx, id, 0)
+	if err != nil {
+		return "", "", err
+	}
+	defer body.Close()
+
+	tmp, err := os.CreateTemp(filepath.Join(e.stateDir, "tmp"), "download-*")
+	if err != nil {
+		return "", "", err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, h), body); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return "", "", err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return "", "", err
+	}
+	return tmp.Name(), hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// removeLocal deletes an item the server no longer has. Files edited since
+// the last sync are left in place and forgotten, so the next push uploads
+// them again rather than losing the edits.
+func (e *Engine) removeLocal(known *entry) error {
+	subtree := e.state.subtree(known.Path)
+	for i := len(subtree) - 1; i >= 0; i-- {
+		item := subtree[i]
+		local := e.localPath(item.Path)
+		if item.Dir {
+			// Only succeeds once everything synced inside is gone
+			os.Remove(local)
+			continue
+		}
+		info, err := os.Lstat(local)
+		if err != nil {
+			continue
+		}
+		if e.unchangedSinceSync(item.Path, info) {
+			if err := os.Remove(local); err != nil && !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		} else {
+			e.logger.Info("Keeping locally modified file deleted on the server", util.WithPath(item.Path))
+		}
+	}
+	e.state.remove(known.Path)
+	e.logger.Info("Removed", util.WithPath(known.Path))
+	return nil
+}
+
+// moveLocal renames an item to follow a remote rename or move
+func (e *Engine) moveLocal(known *entry, p string) error {
+	if p == known.Path {
+		return nil
+	}
+	if _, err := os.Lstat(e.localPath(p)); err == nil {
+		if err := e.keepConflicted(p); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(e.localPath(p)), 0o755); err != nil {
+		return err
+	}
+	err := os.Rename(e.localPath(known.Path), e.localPath(p))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	e.logger.Info("Moved", util.WithPath(known.Path), zap.String("to", p))
+	e.state.move(known.Path, p)
+	return nil
+}
+
+// placement picks the local path for a remote item. Folders may hold
+// several items with the same name, which a directory cannot, so later ones
+// get a numbered suffix.
+func (e *Engine) placement(parentPath, name string, dir bool, id uint) string {
+	base := path.Join(parentPath, name)
+	candidate := base
+	for n := 2; ; n++ {
+		existing := e.state.get(candidate)
+		if existing == nil || (existing.Dir == dir && existing.ID == id) {
+			return candidate
+		}
+		if dir {
+			candidate = base + " (" + strconv.Itoa(n) + ")"
+		} else {
+			ext := path.Ext(base)
+			candidate = strings.TrimSuffix(base, ext) + " (" + strconv.Itoa(n) + ")" + ext
+		}
+	}
+}
+
+// makeDir creates a directory for a remote folder, moving a file in the way
+// aside as a conflicted copy
+func (e *Engine) makeDir(p string) error {
+	local := e.localPath(p)
+	info, err := os.Lstat(local)
+	if err == nil && info.IsDir() {
+		return nil
+	}
+	if err == nil {
+		if err := e.keepConflicted(p); err != nil {
+			return err
+		}
+	}
+	return os.MkdirAll(local, 0o755)
+}
@@ -0,0 +1,212 @@
+package syncer
+
+import (
+	"drive/pkg/client"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// stateVersion is bumped when the state file format changes incompatibly
+const stateVersion = 1
+
+// entry records a file or folder as it was when it was last in sync on both
+// sides
+type entry struct {
+	Path string `json:"-"`
+	Dir  bool   `json:"dir,omitempty"`
+
+	// Remote side: the item, where it lives and its version
+	ID       uint      `json:"id"`
+	ParentID uint      `json:"parent_id"`
+	Name     string    `json:"name"`
+	Modified time.Time `json:"modified"`
+
+	// Local side, for files: what the content looked like
+	Size    int64  `json:"size,omitempty"`
+	ModTime int64  `json:"mod_time,omitempty"`
+	Hash    string `json:"hash,omitempty"`
+}
+
+// etag returns the version of the remote file the entry was synced from
+func (e *entry) etag() string {
+	file := client.File{ID: e.ID, FileSize: e.Size, UpdatedAt: e.Modified}
+	return file.ETag()
+}
+
+// itemKey identifies a remote item; files and folders have separate IDs
+type itemKey struct {
+	dir bool
+	id  uint
+}
+
+// state is the local state database: the change feed position and every
+// synced item, keyed by its slash-separated path below the sync root
+type state struct {
+	Version    int               `json:"version"`
+	Server     string            `json:"server"`
+	RemotePath string            `json:"remote_path"`
+	RootID     uint              `json:"root_id"`
+	Cursor     string            `json:"cursor"`
+	Entries    map[string]*entry `json:"entries"`
+
+	path string
+	byID map[itemKey]*entry
+}
+
+// loadState reads the state file, returning an empty state if there is none
+func loadState(path string) (*state, error) {
+	s := &state{
+		Version: stateVersion,
+		Entries: make(map[string]*entry),
+		path:    path,
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		s.reindex()
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading sync state: %w", err)
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("parsing sync state %s: %w", path, err)
+	}
+	if s.Version != stateVersion {
+		return nil, fmt.Errorf("sync state %s has unsupported version %d", path, s.Version)
+	}
+	if s.Entries == nil {
+		s.Entries = make(map[string]*entry)
+	}
+	s.reindex()
+	return s, nil
+}
+
+// save writes the state atomically
+func (s *state) save() error {
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".state-*.json")
+	if err != nil {
+		return fmt.Errorf("writing sync state: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing sync state: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing sync state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing sync state: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("writing sync state: %w", err)
+	}
+	return nil
+}
+
+func (s *state) reindex() {
+	s.byID = make(map[itemKey]*entry, len(s.Entries))
+	for p, e := range s.Entries {
+		e.Path = p
+		s.byID[itemKey{e.Dir, e.ID}] = e
+	}
+}
+
+// get returns the entry at a path
+func (s *state) get(p string) *entry {
+	return s.Entries[p]
+}
+
+// lookup returns the entry for a remote item
+func (s *state) lookup(dir bool, id uint) *entry {
+	return s.byID[itemKey{dir, id}]
+}
+
+// folderPath returns the local path of a remote folder, the sync root being
+// the empty path
+func (s *state) folderPath(id uint) (string, bool) {
+	if id == s.RootID {
+		return "", true
+	}
+	if e := s.lookup(true, id); e != nil {
+		return e.Path, true
+	}
+	return "", false
+}
+
+// folderID returns the remote folder synced to a local directory path
+func (s *state) folderID(p string) (uint, bool) {
+	if p == "" {
+		return s.RootID, true
+	}
+	if e := s.get(p); e != nil && e.Dir {
+		return e.ID, true
+	}
+	return 0, false
+}
+
+// put records an entry, replacing whatever was at its path
+func (s *state) put(e *entry) {
+	if old := s.Entries[e.Path]; old != nil {
+		delete(s.byID, itemKey{old.Dir, old.ID})
+	}
+	if old := s.byID[itemKey{e.Dir, e.ID}]; old != nil && old.Path != e.Path {
+		delete(s.Entries, old.Path)
+	}
+	s.Entries[e.Path] = e
+	s.byID[itemKey{e.Dir, e.ID}] = e
+}
+
+// remove forgets the entry at a path along with everything beneath it
+func (s *state) remove(p string) {
+	for _, e := range s.subtree(p) {
+		delete(s.Entries, e.Path)
+		delete(s.byID, itemKey{e.Dir, e.ID})
+	}
+}
+
+// move re-keys the entry at oldPath and everything beneath it to newPath
+func (s *state) move(oldPath, newPath string) {
+	moved := s.subtree(oldPath)
+	for _, e := range moved {
+		delete(s.Entries, e.Path)
+	}
+	for _, e := range moved {
+		e.Path = newPath + strings.TrimPrefix(e.Path, oldPath)
+		s.Entries[e.Path] = e
+	}
+}
+
+// subtree returns the entry at p and its descendants, parents first
+func (s *state) subtree(p string) []*entry {
+	var found []*entry
+	prefix := p + "/"
+	for key, e := range s.Entries {
+		if key == p || strings.HasPrefix(key, prefix) {
+			found = append(found, e)
+		}
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i].Path < found[j].Path })
+	return found
+}
+
+// sorted returns every entry, parents before their children
+func (s *state) sorted() []*entry {
+	all := make([]*entry, 0, len(s.Entries))
+	for _, e := range s.Entries {
+		all = append(all, e)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Path < all[j].Path })
+	return all
+}
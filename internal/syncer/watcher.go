@@ -0,0 +1,13 @@
+package syncer
+
+// watcher reports that something changed in a set of directories. It does
+// not say what: every notification triggers a scan, which finds out.
+type watcher interface {
+	// Events receives a value, coalesced, whenever a watched directory
+	// changes
+	Events() <-chan struct{}
+	// Watch sets the directories to watch, typically every directory found
+	// by the last scan
+	Watch(dirs []string) error
+	Close() error
+}
@@ -0,0 +1,88 @@
+package syncer
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// inotifyMask covers every change that can affect the content of a directory
+const inotifyMask = unix.IN_CREATE | unix.IN_DELETE | unix.IN_MODIFY | unix.IN_CLOSE_WRITE |
+	unix.IN_MOVED_FROM | unix.IN_MOVED_TO | unix.IN_ATTRIB | unix.IN_DELETE_SELF | unix.IN_MOVE_SELF
+
+// inotifyWatcher watches directories with inotify. Events are not decoded,
+// only counted: any of them means a scan is due.
+type inotifyWatcher struct {
+	fd     int
+	file   *os.File
+	events chan struct{}
+
+	mu      sync.Mutex
+	watched map[string]bool
+}
+
+func newWatcher() (watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify: %w", err)
+	}
+	w := &inotifyWatcher{
+		fd: fd,
+		// A non-blocking descriptor goes through the runtime poller, so
+		// closing the file interrupts a pending read
+		file:    os.NewFile(uintptr(fd), "inotify"),
+		events:  make(chan struct{}, 1),
+		watched: make(map[string]bool),
+	}
+	go w.read()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Events() <-chan struct{} {
+	return w.events
+}
+
+// Watch adds watches for new directories. Watches on deleted directories
+// are dropped by the kernel; a renamed directory keeps its watch.
+func (w *inotifyWatcher) Watch(dirs []string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	current := make(map[string]bool, len(dirs))
+	for _, dir := range dirs {
+		current[dir] = true
+		if w.watched[dir] {
+			continue
+		}
+		if _, err := unix.InotifyAddWatch(w.fd, dir, inotifyMask); err != nil {
+			if err == unix.ENOENT {
+				continue
+			}
+			return fmt.Errorf("watching %s: %w", dir, err)
+		}
+	}
+	w.watched = current
+	return nil
+}
+
+func (w *inotifyWatcher) Close() error {
+	return w.file.Close()
+}
+
+func (w *inotifyWatcher) read() {
+	buf := make([]byte, 64*1024)
+	for {
+		n, err := w.file.Read(buf)
+		if err != nil {
+			return
+		}
+		if n > 0 {
+			select {
+			case w.events <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package syncer
+
+import "errors"
+
+func newWatcher() (watcher, error) {
+	return nil, errors.New("file system notifications are not supported on this platform")
+}
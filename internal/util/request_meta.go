@@ -0,0 +1,23 @@
+package util
+
+import "context"
+
+type requestMetaKey struct{}
+
+// RequestMeta describes the client behind a request for auditing purposes
+type RequestMeta struct {
+	IP        string
+	RequestID string
+	UserAgent string
+}
+
+// WithRequestMeta returns a copy of ctx carrying the request metadata
+func WithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+// RequestMetaFromContext returns the request metadata stored in ctx, if any
+func RequestMetaFromContext(ctx context.Context) RequestMeta {
+	meta, _ := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return meta
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// Login signs in with an email and password and stores the tokens
+func (c *Client) Login(ctx context.Context, email, password string) (*User, error) {
+	var resp struct {
+		User *User `json:"user"`
+		Tokens
+	}
+	_, err := c.do(ctx, &request{
+		method: http.MethodPost,
+		path:   "/api/auth/login",
+		body:   map[string]string{"email": email, "password": password},
+		noAuth: true,
+	}, &resp)
+	if err != nil {
+		return nil, err
+	}
+
+	c.setTokens(resp.Tokens)
+	return resp.User, nil
+}
+
+// Refresh exchanges a refresh token for new tokens and stores them
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (Tokens, error) {
+	var tokens Tokens
+	_, err := c.do(ctx, &request{
+		method: http.MethodPost,
+		path:   "/api/auth/refresh",
+		body:   map[string]string{"refresh_token": refreshToken},
+		noAuth: true,
+	}, &tokens)
+	if err != nil {
+		return Tokens{}, err
+	}
+
+	c.setTokens(tokens)
+	return tokens, nil
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Changes returns the changes after cursor, an empty cursor reading from the
+// start of the journal. With wait set and nothing new, the server holds the
+// request open for up to that long (at most a minute) for a change to arrive.
+func (c *Client) Changes(ctx context.Context, cursor string, limit int, wait time.Duration) (*ChangeSet, error) {
+	q := url.Values{}
+	if cursor != "" {
+		q.Set("cursor", cursor)
+	}
+	if limit > 0 {
+		q.Set("limit", strconv.Itoa(limit))
+	}
+	if wait > 0 {
+		q.Set("wait", strconv.Itoa(int(wait/time.Second)))
+	}
+
+	var changes ChangeSet
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: "/api/changes", query: q}, &changes); err != nil {
+		return nil, err
+	}
+	return &changes, nil
+}
+
+// LatestCursor returns a cursor positioned after the newest change, for
+// callers that only want changes from now on
+func (c *Client) LatestCursor(ctx context.Context) (string, error) {
+	var resp struct {
+		Cursor string `json:"cursor"`
+	}
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: "/api/changes/latest"}, &resp); err != nil {
+		return "", err
+	}
+	return resp.Cursor, nil
+}
@@ -0,0 +1,354 @@
+// Package client is a Go client for the drive REST API. It handles the
+// response envelope, refreshes expired access tokens and streams uploads and
+// downloads, so command-line tools and sync agents can share one
+// implementation.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNotAuthenticated is returned when a request needs a token and the
+// client has none
+var ErrNotAuthenticated = errors.New("not logged in")
+
+// tokenRefreshLeeway is how long before it expires an access token is
+// refreshed
+const tokenRefreshLeeway = 30 * time.Second
+
+// APIError is an error response returned by the server
+type APIError struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Details    []string
+	Fields     map[string]string
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if len(e.Details) > 0 {
+		msg += ": " + strings.Join(e.Details, "; ")
+	}
+	for field, problem := range e.Fields {
+		msg += fmt.Sprintf(" (%s: %s)", field, problem)
+	}
+	return msg
+}
+
+// IsNotFound reports whether err is a 404 from the server or a path that
+// did not resolve
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusNotFound
+	}
+	return errors.Is(err, ErrPathNotFound)
+}
+
+// IsForbidden reports whether err is a 403 from the server, which downloads
+// return for quarantined files
+func IsForbidden(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusForbidden
+}
+
+// IsModified reports whether err is a failed precondition: the item changed
+// since the version the request was based on
+func IsModified(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusPreconditionFailed
+}
+
+// IsLocked reports whether err is a 423 from the server: the file is locked
+// by another user
+func IsLocked(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusLocked
+}
+
+// Client talks to a drive server. It is safe for concurrent use.
+type Client struct {
+	baseURL        string
+	httpClient     *http.Client
+	onTokenRefresh func(Tokens)
+
+	mu         sync.Mutex
+	tokens     Tokens
+	refreshing chan struct{}
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used for requests
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithTokens sets the tokens the client starts with
+func WithTokens(tokens Tokens) Option {
+	return func(c *Client) {
+		c.tokens = tokens
+	}
+}
+
+// OnTokenRefresh registers a callback run whenever the client obtains new
+// tokens, by logging in or by refreshing an expired access token, so callers
+// can persist them
+func OnTokenRefresh(fn func(Tokens)) Option {
+	return func(c *Client) {
+		c.onTokenRefresh = fn
+	}
+}
+
+// New creates a client for the server at baseURL, e.g. http://localhost:8080
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// BaseURL returns the server address the client talks to
+func (c *Client) BaseURL() string {
+	return c.baseURL
+}
+
+// Tokens returns the client's current tokens
+func (c *Client) Tokens() Tokens {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tokens
+}
+
+// SetTokens replaces the client's tokens
+func (c *Client) SetTokens(tokens Tokens) {
+	c.mu.Lock()
+	c.tokens = tokens
+	c.mu.Unlock()
+}
+
+// envelope is the body of every JSON response
+type envelope struct {
+	Success bool            `json:"success"`
+	Data    json.RawMessage `json:"data"`
+	Error   *struct {
+		Code    string            `json:"code"`
+		Message string            `json:"message"`
+		Details []string          `json:"details"`
+		Fields  map[string]string `json:"fields"`
+	} `json:"error"`
+	Meta *Page `json:"meta"`
+}
+
+// request describes one API call. Body is either a value encoded as JSON or,
+// when newBody is set, a stream; replayable marks streams newBody can
+// recreate for a retry.
+type request struct {
+	method        string
+	path          string
+	query         url.Values
+	body          interface{}
+	newBody       func() (io.Reader, error)
+	replayable    bool
+	contentType   string
+	contentLength int64
+	header        http.Header
+	noAuth        bool
+}
+
+// do sends a request and decodes the data of the response into out. The
+// page metadata, if any, is returned.
+func (c *Client) do(ctx context.Context, req *request, out interface{}) (*Page, error) {
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return decode(resp, out)
+}
+
+// send sends a request, refreshing the access token and retrying once if the
+// server rejects it. The caller closes the response body.
+func (c *Client) send(ctx context.Context, req *request) (*http.Response, error) {
+	tokens := c.Tokens()
+	token := tokens.AccessToken
+	if !req.noAuth && tokens.RefreshToken != "" && (token == "" || expiresSoon(token)) {
+		// Refresh up front: a streamed body cannot be sent a second time
+		if err := c.refreshAfter(ctx, token); err != nil {
+			return nil, err
+		}
+		token = c.Tokens().AccessToken
+	}
+
+	resp, err := c.sendWithToken(ctx, req, token)
+	if err != nil || req.noAuth || resp.StatusCode != http.StatusUnauthorized {
+		return resp, err
+	}
+	if c.Tokens().RefreshToken == "" || (req.newBody != nil && !req.replayable) {
+		return resp, nil
+	}
+	if err := c.refreshAfter(ctx, token); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	return c.sendWithToken(ctx, req, c.Tokens().AccessToken)
+}
+
+func (c *Client) sendWithToken(ctx context.Context, req *request, token string) (*http.Response, error) {
+	var body io.Reader
+	contentType := req.contentType
+	switch {
+	case req.newBody != nil:
+		r, err := req.newBody()
+		if err != nil {
+			return nil, err
+		}
+		body = r
+	case req.body != nil:
+		data, err := json.Marshal(req.body)
+		if err != nil {
+			return nil, fmt.Errorf("error encoding request: %w", err)
+		}
+		body = bytes.NewReader(data)
+		contentType = "application/json"
+	}
+
+	u := c.baseURL + req.path
+	if len(req.query) > 0 {
+		u += "?" + req.query.Encode()
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, req.method, u, body)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range req.header {
+		httpReq.Header[key] = values
+	}
+	if contentType != "" {
+		httpReq.Header.Set("Content-Type", contentType)
+	}
+	if req.contentLength > 0 {
+		httpReq.ContentLength = req.contentLength
+	}
+	httpReq.Header.Set("Accept", "application/json")
+	if !req.noAuth {
+		if token == "" {
+			return nil, ErrNotAuthenticated
+		}
+		httpReq.Header.Set("Authorization", "Bearer "+token)
+	}
+	return c.httpClient.Do(httpReq)
+}
+
+// refreshAfter exchanges the refresh token for new tokens unless another
+// request already replaced the rejected access token
+func (c *Client) refreshAfter(ctx context.Context, rejected string) error {
+	c.mu.Lock()
+	if c.tokens.AccessToken != rejected {
+		c.mu.Unlock()
+		return nil
+	}
+	if wait := c.refreshing; wait != nil {
+		c.mu.Unlock()
+		select {
+		case <-wait:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	done := make(chan struct{})
+	c.refreshing = done
+	refreshToken := c.tokens.RefreshToken
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		c.refreshing = nil
+		c.mu.Unlock()
+		close(done)
+	}()
+	_, err := c.Refresh(ctx, refreshToken)
+	return err
+}
+
+// expiresSoon reports whether a JWT access token expires within
+// tokenRefreshLeeway. The token is only decoded, not verified; tokens that
+// cannot be decoded are left for the server to judge.
+func expiresSoon(token string) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	var claims struct {
+		ExpiresAt int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.ExpiresAt == 0 {
+		return false
+	}
+	return time.Until(time.Unix(claims.ExpiresAt, 0)) < tokenRefreshLeeway
+}
+
+// setTokens stores new tokens and notifies the refresh callback
+func (c *Client) setTokens(tokens Tokens) {
+	c.SetTokens(tokens)
+	if c.onTokenRefresh != nil {
+		c.onTokenRefresh(tokens)
+	}
+}
+
+// decode reads a response envelope, turning error responses into *APIError
+func decode(resp *http.Response, out interface{}) (*Page, error) {
+	var env envelope
+	if err := json.NewDecoder(resp.Body).Decode(&env); err != nil && !errors.Is(err, io.EOF) {
+		if resp.StatusCode >= 400 {
+			return nil, &APIError{StatusCode: resp.StatusCode}
+		}
+		return nil, fmt.Errorf("error decoding response: %w", err)
+	}
+
+	if resp.StatusCode >= 400 {
+		apiErr := &APIError{StatusCode: resp.StatusCode}
+		if env.Error != nil {
+			apiErr.Code = env.Error.Code
+			apiErr.Message = env.Error.Message
+			apiErr.Details = env.Error.Details
+			apiErr.Fields = env.Error.Fields
+		}
+		return nil, apiErr
+	}
+
+	if out != nil && len(env.Data) > 0 {
+		if err := json.Unmarshal(env.Data, out); err != nil {
+			return nil, fmt.Errorf("error decoding response: %w", err)
+		}
+	}
+	return env.Meta, nil
+}
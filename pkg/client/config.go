@@ -0,0 +1,109 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Config is the server address, tokens and vault identity shared by the
+// command-line tools, written by drivectl login
+type Config struct {
+	Server       string `json:"server"`
+	Email        string `json:"email,omitempty"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// VaultKey is the private key opening the user's vaults, written by
+	// drivectl vault init
+	VaultKey string `json:"vault_key,omitempty"`
+
+	path string
+}
+
+// DefaultConfigPath returns the config file under the user's config directory
+func DefaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ".drive.json"
+	}
+	return filepath.Join(dir, "drive", "config.json")
+}
+
+// LoadConfig reads a config file; a missing file yields an empty config that
+// Save will create
+func LoadConfig(path string) (*Config, error) {
+	cfg := &Config{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading config: %w", err)
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("parsing config %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Save writes the config readable only by the user, replacing the old file
+// atomically so an interrupted write cannot lose the tokens
+func (c *Config) Save() error {
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o700); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), ".config-*.json")
+	if err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing config: %w", err)
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing config: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("writing config: %w", err)
+	}
+	return nil
+}
+
+// Tokens returns the stored tokens
+func (c *Config) Tokens() Tokens {
+	return Tokens{AccessToken: c.AccessToken, RefreshToken: c.RefreshToken}
+}
+
+// SetTokens stores new tokens
+func (c *Config) SetTokens(tokens Tokens) {
+	c.AccessToken = tokens.AccessToken
+	c.RefreshToken = tokens.RefreshToken
+}
+
+// NewClient creates a client for the configured server that saves refreshed
+// tokens back to the config file. Save errors are passed to onSaveError,
+// which may be nil.
+func (c *Config) NewClient(onSaveError func(error), opts ...Option) *Client {
+	opts = append([]Option{
+		WithTokens(c.Tokens()),
+		OnTokenRefresh(func(tokens Tokens) {
+			c.SetTokens(tokens)
+			if err := c.Save(); err != nil && onSaveError != nil {
+				onSaveError(err)
+			}
+		}),
+	}, opts...)
+	return New(c.Server, opts...)
+}
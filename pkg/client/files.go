@@ -0,0 +1,174 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+)
+
+// UpdateFileRequest renames and/or moves a file; nil fields are left alone
+type UpdateFileRequest struct {
+	Name     *string `json:"name,omitempty"`
+	FolderID *uint   `json:"folder_id,omitempty"`
+}
+
+// File returns a file's metadata
+func (c *Client) File(ctx context.Context, id uint) (*File, error) {
+	var file File
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: fileURL(id)}, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// Upload streams r to the server as a new file named name in folderID (zero
+// for the root folder). size is the number of bytes r yields, or -1 if
+// unknown; a known size lets the server check the quota before the upload
+// starts.
+func (c *Client) Upload(ctx context.Context, folderID uint, name string, size int64, r io.Reader) (*File, error) {
+	// Build the multipart framing up front so the body length is known
+	var head, tail bytes.Buffer
+	mw := multipart.NewWriter(&head)
+	if folderID != 0 {
+		if err := mw.WriteField("folder_id", strconv.FormatUint(uint64(folderID), 10)); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := mw.CreateFormFile("file", name); err != nil {
+		return nil, err
+	}
+	contentType := mw.FormDataContentType()
+	closer := multipart.NewWriter(&tail)
+	if err := closer.SetBoundary(mw.Boundary()); err != nil {
+		return nil, err
+	}
+	if err := closer.Close(); err != nil {
+		return nil, err
+	}
+
+	req := &request{
+		method:      http.MethodPost,
+		path:        "/api/files",
+		contentType: contentType,
+		newBody: func() (io.Reader, error) {
+			return io.MultiReader(bytes.NewReader(head.Bytes()), r, bytes.NewReader(tail.Bytes())), nil
+		},
+	}
+	if size >= 0 {
+		req.contentLength = int64(head.Len()) + size + int64(tail.Len())
+	}
+
+	var file File
+	if _, err := c.do(ctx, req, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// ReplaceContent overwrites a file's content with size bytes read from r,
+// keeping its ID. With etag set (see File.ETag) the content is only replaced
+// if the file has not changed since; otherwise an error satisfying
+// IsModified is returned.
+func (c *Client) ReplaceContent(ctx context.Context, id uint, etag string, size int64, r io.Reader) (*File, error) {
+	req := &request{
+		method:      http.MethodPut,
+		path:        fileURL(id) + "/content",
+		contentType: "application/octet-stream",
+		newBody: func() (io.Reader, error) {
+			return r, nil
+		},
+		header: http.Header{},
+	}
+	if size >= 0 {
+		req.contentLength = size
+	}
+	if etag != "" {
+		req.header.Set("If-Match", etag)
+	}
+
+	var file File
+	if _, err := c.do(ctx, req, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// Download opens a file's content. The caller closes the returned reader.
+// offset skips the first bytes of the file, for resuming a download.
+func (c *Client) Download(ctx context.Context, id uint, offset int64) (io.ReadCloser, int64, error) {
+	req := &request{
+		method: http.MethodGet,
+		path:   fileURL(id) + "/download",
+		header: http.Header{},
+	}
+	if offset > 0 {
+		req.header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	}
+
+	resp, err := c.send(ctx, req)
+	if err != nil {
+		return nil, 0, err
+	}
+	switch {
+	case resp.StatusCode == http.StatusOK && offset > 0:
+		resp.Body.Close()
+		return nil, 0, fmt.Errorf("server does not support resuming downloads")
+	case resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusPartialContent:
+		return resp.Body, resp.ContentLength, nil
+	default:
+		defer resp.Body.Close()
+		_, err := decode(resp, nil)
+		if err == nil {
+			err = &APIError{StatusCode: resp.StatusCode}
+		}
+		return nil, 0, err
+	}
+}
+
+// UpdateFile renames and/or moves a file
+func (c *Client) UpdateFile(ctx context.Context, id uint, req *UpdateFileRequest) (*File, error) {
+	var file File
+	if _, err := c.do(ctx, &request{method: http.MethodPatch, path: fileURL(id), body: req}, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// DeleteFile moves a file to the trash
+func (c *Client) DeleteFile(ctx context.Context, id uint) error {
+	_, err := c.do(ctx, &request{method: http.MethodDelete, path: fileURL(id)}, nil)
+	return err
+}
+
+// LockFile checks out a file, replacing the caller's previous lock on it.
+// It fails with a 423 APIError while another user holds a conflicting lock.
+func (c *Client) LockFile(ctx context.Context, id uint, req *LockRequest) (*FileLock, error) {
+	var lock FileLock
+	if _, err := c.do(ctx, &request{method: http.MethodPost, path: fileURL(id) + "/lock", body: req}, &lock); err != nil {
+		return nil, err
+	}
+	return &lock, nil
+}
+
+// UnlockFile releases the caller's lock on a file
+func (c *Client) UnlockFile(ctx context.Context, id uint) error {
+	_, err := c.do(ctx, &request{method: http.MethodDelete, path: fileURL(id) + "/lock"}, nil)
+	return err
+}
+
+// FileLocks returns the active locks on a file
+func (c *Client) FileLocks(ctx context.Context, id uint) ([]FileLock, error) {
+	var locks []FileLock
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: fileURL(id) + "/locks"}, &locks); err != nil {
+		return nil, err
+	}
+	return locks, nil
+}
+
+func fileURL(id uint) string {
+	return "/api/files/" + strconv.FormatUint(uint64(id), 10)
+}
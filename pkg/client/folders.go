@@ -0,0 +1,64 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// UpdateFolderRequest renames and/or moves a folder; nil fields are left alone
+type UpdateFolderRequest struct {
+	Name     *string `json:"name,omitempty"`
+	ParentID *uint   `json:"parent_id,omitempty"`
+}
+
+// Root returns the user's root folder and its children
+func (c *Client) Root(ctx context.Context) (*FolderContents, error) {
+	var contents FolderContents
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: "/api/folders/root"}, &contents); err != nil {
+		return nil, err
+	}
+	return &contents, nil
+}
+
+// Folder returns a folder and its children
+func (c *Client) Folder(ctx context.Context, id uint) (*FolderContents, error) {
+	var contents FolderContents
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: folderURL(id)}, &contents); err != nil {
+		return nil, err
+	}
+	return &contents, nil
+}
+
+// CreateFolder makes a folder inside parentID (zero for the root folder)
+func (c *Client) CreateFolder(ctx context.Context, parentID uint, name string) (*Folder, error) {
+	body := struct {
+		Name     string `json:"name"`
+		ParentID uint   `json:"parent_id,omitempty"`
+	}{name, parentID}
+
+	var folder Folder
+	if _, err := c.do(ctx, &request{method: http.MethodPost, path: "/api/folders", body: body}, &folder); err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// UpdateFolder renames and/or moves a folder
+func (c *Client) UpdateFolder(ctx context.Context, id uint, req *UpdateFolderRequest) (*Folder, error) {
+	var folder Folder
+	if _, err := c.do(ctx, &request{method: http.MethodPatch, path: folderURL(id), body: req}, &folder); err != nil {
+		return nil, err
+	}
+	return &folder, nil
+}
+
+// DeleteFolder moves a folder and everything beneath it to the trash
+func (c *Client) DeleteFolder(ctx context.Context, id uint) error {
+	_, err := c.do(ctx, &request{method: http.MethodDelete, path: folderURL(id)}, nil)
+	return err
+}
+
+func folderURL(id uint) string {
+	return "/api/folders/" + strconv.FormatUint(uint64(id), 10)
+}
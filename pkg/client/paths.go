@@ -0,0 +1,130 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"path"
+	"strings"
+)
+
+var (
+	// ErrPathNotFound is returned when a path does not resolve to a file or folder
+	ErrPathNotFound = errors.New("no such file or folder")
+	// ErrNotFolder is returned when a path that must be a folder names a file
+	ErrNotFolder = errors.New("not a folder")
+)
+
+// Stat resolves a slash-separated path from the user's root folder, the
+// same way the WebDAV view does: a folder wins over a file of the same
+// name and, among files sharing a name, the oldest wins.
+func (c *Client) Stat(ctx context.Context, p string) (*Entry, error) {
+	root, err := c.Root(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	current := root
+	segments := splitPath(p)
+	for i, name := range segments {
+		last := i == len(segments)-1
+		if folder := findFolder(current.Folders, name); folder != nil {
+			if last {
+				return &Entry{Folder: folder}, nil
+			}
+			if current, err = c.Folder(ctx, folder.ID); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if file := findFile(current.Files, name); file != nil && last {
+			return &Entry{File: file}, nil
+		}
+		return nil, &pathError{path: p, err: ErrPathNotFound}
+	}
+	return &Entry{Folder: root.Folder}, nil
+}
+
+// List returns the contents of the folder at path p
+func (c *Client) List(ctx context.Context, p string) (*FolderContents, error) {
+	entry, err := c.Stat(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.IsDir() {
+		return nil, &pathError{path: p, err: ErrNotFolder}
+	}
+	return c.Folder(ctx, entry.Folder.ID)
+}
+
+// MkdirAll creates the folder at path p along with any missing parents and
+// returns it
+func (c *Client) MkdirAll(ctx context.Context, p string) (*Folder, error) {
+	current, err := c.Root(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	folder := current.Folder
+	for _, name := range splitPath(p) {
+		if existing := findFolder(current.Folders, name); existing != nil {
+			folder = existing
+		} else {
+			if findFile(current.Files, name) != nil {
+				return nil, &pathError{path: p, err: ErrNotFolder}
+			}
+			if folder, err = c.CreateFolder(ctx, folder.ID, name); err != nil {
+				return nil, err
+			}
+		}
+		if current, err = c.Folder(ctx, folder.ID); err != nil {
+			return nil, err
+		}
+	}
+	return folder, nil
+}
+
+// pathError names the path a lookup failed on
+type pathError struct {
+	path string
+	err  error
+}
+
+func (e *pathError) Error() string {
+	return e.path + ": " + e.err.Error()
+}
+
+func (e *pathError) Unwrap() error {
+	return e.err
+}
+
+// splitPath cleans a path into its segments
+func splitPath(p string) []string {
+	p = strings.Trim(path.Clean("/"+p), "/")
+	if p == "" {
+		return nil
+	}
+	return strings.Split(p, "/")
+}
+
+func findFolder(folders []Folder, name string) *Folder {
+	for i := range folders {
+		if folders[i].FolderName == name {
+			return &folders[i]
+		}
+	}
+	return nil
+}
+
+func findFile(files []File, name string) *File {
+	var found *File
+	for i := range files {
+		if files[i].FileName != name {
+			continue
+		}
+		if found == nil || files[i].CreatedAt.Before(found.CreatedAt) ||
+			(files[i].CreatedAt.Equal(found.CreatedAt) && files[i].ID < found.ID) {
+			found = &files[i]
+		}
+	}
+	return found
+}
@@ -0,0 +1,33 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// Search finds files and folders the user can read
+func (c *Client) Search(ctx context.Context, query *SearchQuery) ([]SearchResult, *Page, error) {
+	q := url.Values{}
+	q.Set("q", query.Query)
+	if query.FileType != "" {
+		q.Set("type", query.FileType)
+	}
+	if query.FolderID != 0 {
+		q.Set("folder_id", strconv.FormatUint(uint64(query.FolderID), 10))
+	}
+	if query.Page > 0 {
+		q.Set("page", strconv.Itoa(query.Page))
+	}
+	if query.PerPage > 0 {
+		q.Set("per_page", strconv.Itoa(query.PerPage))
+	}
+
+	var results []SearchResult
+	page, err := c.do(ctx, &request{method: http.MethodGet, path: "/api/search", query: q}, &results)
+	if err != nil {
+		return nil, nil, err
+	}
+	return results, page, nil
+}
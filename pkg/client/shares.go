@@ -0,0 +1,15 @@
+package client
+
+import (
+	"context"
+	"net/http"
+)
+
+// CreateShare shares a file or folder with another user
+func (c *Client) CreateShare(ctx context.Context, req *ShareRequest) (*Share, error) {
+	var share Share
+	if _, err := c.do(ctx, &request{method: http.MethodPost, path: "/api/shares", body: req}, &share); err != nil {
+		return nil, err
+	}
+	return &share, nil
+}
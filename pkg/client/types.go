@@ -0,0 +1,176 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// Tokens are the credentials the client sends with each request
+type Tokens struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+// User is the account the client is signed in as
+type User struct {
+	ID           uint      `json:"id"`
+	Email        string    `json:"email"`
+	Username     string    `json:"username"`
+	FirstName    string    `json:"first_name"`
+	LastName     string    `json:"last_name"`
+	StorageUsed  float64   `json:"storage_used"`
+	StorageLimit float64   `json:"storage_limit"`
+	IsAdmin      bool      `json:"is_admin"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// File is a stored file
+type File struct {
+	ID         uint      `json:"id"`
+	FileName   string    `json:"file_name"`
+	FileType   string    `json:"file_type"`
+	FileSize   int64     `json:"file_size"`
+	MimeType   string    `json:"mime_type"`
+	FolderID   uint      `json:"folder_id"`
+	UserID     uint      `json:"user_id"`
+	ScanStatus string    `json:"scan_status"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ETag returns the entity tag the server uses for this version of the file,
+// for ReplaceContent
+func (f *File) ETag() string {
+	return fmt.Sprintf(`"%x-%x-%x"`, f.UpdatedAt.Truncate(time.Microsecond).UnixNano(), f.FileSize, f.ID)
+}
+
+// Folder is a folder in the drive. The root folder has no parent.
+type Folder struct {
+	ID             uint      `json:"id"`
+	FolderName     string    `json:"folder_name"`
+	ParentFolderID *uint     `json:"parent_folder_id"`
+	UserID         uint      `json:"user_id"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+	// VaultID is set on the folders of an end-to-end encrypted vault
+	VaultID *uint `json:"vault_id,omitempty"`
+}
+
+// FolderContents is a folder with its immediate children and the caller's
+// permission on it
+type FolderContents struct {
+	Folder     *Folder  `json:"folder"`
+	Permission string   `json:"permission"`
+	Folders    []Folder `json:"folders"`
+	Files      []File   `json:"files"`
+}
+
+// Entry is a file or a folder found by path; exactly one field is set
+type Entry struct {
+	Folder *Folder `json:"folder,omitempty"`
+	File   *File   `json:"file,omitempty"`
+}
+
+// IsDir reports whether the entry is a folder
+func (e *Entry) IsDir() bool {
+	return e.Folder != nil
+}
+
+// Share grants another user access to a file or folder
+type Share struct {
+	ID           uint      `json:"id"`
+	FolderID     uint      `json:"folder_id"`
+	FileID       *uint     `json:"file_id"`
+	OwnerID      uint      `json:"owner_id"`
+	SharedWithID uint      `json:"shared_with_id"`
+	GroupID      uint      `json:"group_id,omitempty"`
+	Permission   string    `json:"permission"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// FileLock is a check-out of a file. While a file is locked only the lock
+// holders can change it.
+type FileLock struct {
+	ID        uint      `json:"id"`
+	FileID    uint      `json:"file_id"`
+	UserID    uint      `json:"user_id"`
+	Scope     string    `json:"scope"`
+	Note      string    `json:"note,omitempty"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	User      *User     `json:"user,omitempty"`
+}
+
+// LockRequest checks out a file. Scope is "exclusive" (the default) or
+// "shared"; a zero duration means an hour.
+type LockRequest struct {
+	Scope           string `json:"scope,omitempty"`
+	Note            string `json:"note,omitempty"`
+	DurationSeconds int64  `json:"duration_seconds,omitempty"`
+}
+
+// ShareRequest shares a file or folder. Set exactly one of FileID and
+// FolderID, and one of SharedWithID, SharedWithEmail and GroupID.
+type ShareRequest struct {
+	FileID          uint   `json:"file_id,omitempty"`
+	FolderID        uint   `json:"folder_id,omitempty"`
+	SharedWithID    uint   `json:"shared_with_id,omitempty"`
+	SharedWithEmail string `json:"shared_with_email,omitempty"`
+	GroupID         uint   `json:"group_id,omitempty"`
+	Permission      string `json:"permission"`
+	// WrappedKey is the vault key sealed for the recipient, required when
+	// sharing an item in a vault; Vault.Share fills it in
+	WrappedKey []byte `json:"wrapped_key,omitempty"`
+}
+
+// SearchQuery holds the filters accepted by Search; zero values are ignored
+type SearchQuery struct {
+	Query    string
+	FileType string
+	FolderID uint
+	Page     int
+	PerPage  int
+}
+
+// SearchResult is a file or folder matched by a search
+type SearchResult struct {
+	Type      string    `json:"type"`
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Highlight string    `json:"highlight"`
+	Snippet   string    `json:"snippet,omitempty"`
+	FileType  string    `json:"file_type,omitempty"`
+	FileSize  int64     `json:"file_size,omitempty"`
+	FolderID  *uint     `json:"folder_id"`
+	OwnerID   uint      `json:"owner_id"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Page describes where a page of results sits in the full result set
+type Page struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"per_page"`
+	TotalPages int `json:"total_pages"`
+	TotalCount int `json:"total_count"`
+}
+
+// Change is an entry in the change journal
+type Change struct {
+	ID         uint       `json:"id"`
+	Change     string     `json:"change"`
+	ItemType   string     `json:"item_type"`
+	ItemID     uint       `json:"item_id"`
+	ParentID   *uint      `json:"parent_id"`
+	Name       string     `json:"name"`
+	Size       int64      `json:"size,omitempty"`
+	MimeType   string     `json:"mime_type,omitempty"`
+	ModifiedAt time.Time  `json:"modified_at"`
+	DeletedAt  *time.Time `json:"deleted_at,omitempty"`
+}
+
+// ChangeSet is a page of the change journal
+type ChangeSet struct {
+	Changes []Change `json:"changes"`
+	Cursor  string   `json:"cursor"`
+	HasMore bool     `json:"has_more"`
+}
@@ -0,0 +1,515 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"drive/internal/encryption"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/curve25519"
+	"golang.org/x/crypto/nacl/box"
+)
+
+// VaultKeyAlgorithm names the public key scheme used to wrap vault keys:
+// X25519 keys with NaCl anonymous sealed boxes
+const VaultKeyAlgorithm = "x25519-sealedbox"
+
+const (
+	// vaultKeySize is the length of vault and file keys
+	vaultKeySize = 32
+	// wrappedFileKeySize is the length of the wrapped file key stored at
+	// the start of every vault blob: a nonce, the key and the GCM tag
+	wrappedFileKeySize = 12 + vaultKeySize + 16
+)
+
+// Additional data binding ciphertexts to their purpose, so a wrapped file
+// key cannot be passed off as a name or the other way round
+var (
+	nameAAD    = []byte("drive-vault-name")
+	fileKeyAAD = []byte("drive-vault-file")
+)
+
+var (
+	// ErrNoIdentity is returned by vault operations when no identity is set
+	ErrNoIdentity = errors.New("no vault identity configured")
+	// ErrVaultDecrypt is returned when vault data cannot be decrypted with
+	// the keys at hand
+	ErrVaultDecrypt = errors.New("vault data could not be decrypted")
+)
+
+// Identity is a user's vault key pair. The private key never leaves the
+// client; the public key is registered with the server so other users can
+// wrap vault keys for it.
+type Identity struct {
+	public  [32]byte
+	private [32]byte
+}
+
+// GenerateIdentity creates a new random identity
+func GenerateIdentity() (*Identity, error) {
+	public, private, err := box.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	return &Identity{public: *public, private: *private}, nil
+}
+
+// ParseIdentity reads an identity saved with Identity.String
+func ParseIdentity(s string) (*Identity, error) {
+	raw, err := base64.StdEncoding.DecodeString(s)
+	if err != nil || len(raw) != 32 {
+		return nil, errors.New("invalid vault identity")
+	}
+	id := &Identity{}
+	copy(id.private[:], raw)
+	public, err := curve25519.X25519(id.private[:], curve25519.Basepoint)
+	if err != nil {
+		return nil, err
+	}
+	copy(id.public[:], public)
+	return id, nil
+}
+
+// String encodes the private key for storage. Keep it secret: it opens
+// every vault shared with the user.
+func (id *Identity) String() string {
+	return base64.StdEncoding.EncodeToString(id.private[:])
+}
+
+// PublicKey returns the public half of the identity
+func (id *Identity) PublicKey() []byte {
+	return bytes.Clone(id.public[:])
+}
+
+// PublicKey is a user's registered vault public key
+type PublicKey struct {
+	UserID    uint      `json:"user_id"`
+	Algorithm string    `json:"algorithm"`
+	PublicKey []byte    `json:"public_key"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// vaultKey is the caller's wrapped key for a vault
+type vaultKey struct {
+	VaultID    uint   `json:"vault_id"`
+	UserID     uint   `json:"user_id"`
+	WrappedKey []byte `json:"wrapped_key"`
+}
+
+// RegisterPublicKey publishes the identity's public key. Vaults shared
+// with an earlier key can no longer be opened once it is replaced.
+func (c *Client) RegisterPublicKey(ctx context.Context, id *Identity) (*PublicKey, error) {
+	body := struct {
+		Algorithm string `json:"algorithm"`
+		PublicKey []byte `json:"public_key"`
+	}{VaultKeyAlgorithm, id.PublicKey()}
+
+	var key PublicKey
+	if _, err := c.do(ctx, &request{method: http.MethodPut, path: "/api/vault/public-key", body: body}, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// PublicKey returns the public key of the user with the given ID or, when
+// the ID is zero, email
+func (c *Client) PublicKey(ctx context.Context, userID uint, email string) (*PublicKey, error) {
+	query := url.Values{}
+	if userID != 0 {
+		query.Set("user_id", strconv.FormatUint(uint64(userID), 10))
+	} else {
+		query.Set("email", email)
+	}
+
+	var key PublicKey
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: "/api/vault/public-keys", query: query}, &key); err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Vault is an open end-to-end encrypted vault. Names and content are
+// encrypted and decrypted on the client with the vault key, which the
+// server only holds wrapped for each member's public key.
+type Vault struct {
+	// ID is the vault's root folder
+	ID uint
+
+	client *Client
+	key    cipher.AEAD
+	raw    []byte
+}
+
+// CreateVault makes a new vault folder named name inside parentID (zero for
+// the root folder), with a fresh key wrapped for the identity
+func (c *Client) CreateVault(ctx context.Context, id *Identity, parentID uint, name string) (*Vault, *Folder, error) {
+	if id == nil {
+		return nil, nil, ErrNoIdentity
+	}
+	raw := make([]byte, vaultKeySize)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, nil, err
+	}
+	v, err := newVault(c, 0, raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	encryptedName, err := v.EncryptName(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := box.SealAnonymous(nil, raw, &id.public, rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	body := struct {
+		Name       string `json:"name"`
+		ParentID   uint   `json:"parent_id,omitempty"`
+		Vault      bool   `json:"vault"`
+		WrappedKey []byte `json:"wrapped_key"`
+	}{encryptedName, parentID, true, wrapped}
+
+	var folder Folder
+	if _, err := c.do(ctx, &request{method: http.MethodPost, path: "/api/folders", body: body}, &folder); err != nil {
+		return nil, nil, err
+	}
+	v.ID = folder.ID
+	folder.FolderName = name
+	return v, &folder, nil
+}
+
+// OpenVault fetches and unwraps the caller's key for the vault holding
+// folderID
+func (c *Client) OpenVault(ctx context.Context, id *Identity, folderID uint) (*Vault, error) {
+	if id == nil {
+		return nil, ErrNoIdentity
+	}
+	var key vaultKey
+	path := "/api/vault/keys/" + strconv.FormatUint(uint64(folderID), 10)
+	if _, err := c.do(ctx, &request{method: http.MethodGet, path: path}, &key); err != nil {
+		return nil, err
+	}
+	raw, ok := box.OpenAnonymous(nil, key.WrappedKey, &id.public, &id.private)
+	if !ok || len(raw) != vaultKeySize {
+		return nil, fmt.Errorf("%w: vault key was wrapped for another public key", ErrVaultDecrypt)
+	}
+	return newVault(c, key.VaultID, raw)
+}
+
+func newVault(c *Client, id uint, raw []byte) (*Vault, error) {
+	key, err := newGCM(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &Vault{ID: id, client: c, key: key, raw: raw}, nil
+}
+
+// EncryptName encrypts a file or folder name for storage on the server
+func (v *Vault) EncryptName(name string) (string, error) {
+	if !validName(name) {
+		return "", fmt.Errorf("invalid name %q", name)
+	}
+	sealed, err := seal(v.key, []byte(name), nameAAD)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptName recovers a name encrypted with EncryptName
+func (v *Vault) DecryptName(encrypted string) (string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", fmt.Errorf("%w: name is not encrypted", ErrVaultDecrypt)
+	}
+	name, err := open(v.key, sealed, nameAAD)
+	if err != nil {
+		return "", err
+	}
+	// Names come from other vault members; never let one act as a path
+	if !validName(string(name)) {
+		return "", fmt.Errorf("%w: invalid name", ErrVaultDecrypt)
+	}
+	return string(name), nil
+}
+
+// List returns a folder in the vault with its children, names decrypted. A
+// zero folderID lists the vault's root folder.
+func (v *Vault) List(ctx context.Context, folderID uint) (*FolderContents, error) {
+	if folderID == 0 {
+		folderID = v.ID
+	}
+	contents, err := v.client.Folder(ctx, folderID)
+	if err != nil {
+		return nil, err
+	}
+	if err := v.decryptFolder(contents.Folder); err != nil {
+		return nil, err
+	}
+	for i := range contents.Folders {
+		if err := v.decryptFolder(&contents.Folders[i]); err != nil {
+			return nil, err
+		}
+	}
+	for i := range contents.Files {
+		if err := v.decryptFile(&contents.Files[i]); err != nil {
+			return nil, err
+		}
+	}
+	return contents, nil
+}
+
+// Mkdir makes a folder named name inside a vault folder (zero for the
+// vault's root folder)
+func (v *Vault) Mkdir(ctx context.Context, parentID uint, name string) (*Folder, error) {
+	if parentID == 0 {
+		parentID = v.ID
+	}
+	encryptedName, err := v.EncryptName(name)
+	if err != nil {
+		return nil, err
+	}
+	folder, err := v.client.CreateFolder(ctx, parentID, encryptedName)
+	if err != nil {
+		return nil, err
+	}
+	folder.FolderName = name
+	return folder, nil
+}
+
+// Upload encrypts size bytes read from r and stores them as a new file
+// named name in a vault folder (zero for the vault's root folder). Each file
+// is encrypted with its own key, stored at the start of the blob wrapped
+// with the vault key.
+func (v *Vault) Upload(ctx context.Context, folderID uint, name string, size int64, r io.Reader) (*File, error) {
+	if folderID == 0 {
+		folderID = v.ID
+	}
+	encryptedName, err := v.EncryptName(name)
+	if err != nil {
+		return nil, err
+	}
+	fileKey := make([]byte, vaultKeySize)
+	if _, err := rand.Read(fileKey); err != nil {
+		return nil, err
+	}
+	wrapped, err := seal(v.key, fileKey, fileKeyAAD)
+	if err != nil {
+		return nil, err
+	}
+	content, err := encryption.Encrypt(r, fileKey)
+	if err != nil {
+		return nil, err
+	}
+
+	sealedSize := int64(-1)
+	if size >= 0 {
+		sealedSize = wrappedFileKeySize + encryption.SealedSize(size)
+	}
+	file, err := v.client.Upload(ctx, folderID, encryptedName, sealedSize, io.MultiReader(bytes.NewReader(wrapped), content))
+	if err != nil {
+		return nil, err
+	}
+	if err := v.decryptFile(file); err != nil {
+		return nil, err
+	}
+	return file, nil
+}
+
+// Download opens the decrypted content of a file in the vault. The caller
+// closes the returned reader; reading fails if the content was tampered
+// with.
+func (v *Vault) Download(ctx context.Context, fileID uint) (io.ReadCloser, error) {
+	body, _, err := v.client.Download(ctx, fileID, 0)
+	if err != nil {
+		return nil, err
+	}
+	wrapped := make([]byte, wrappedFileKeySize)
+	if _, err := io.ReadFull(body, wrapped); err != nil {
+		body.Close()
+		return nil, fmt.Errorf("%w: reading file key: %v", ErrVaultDecrypt, err)
+	}
+	fileKey, err := open(v.key, wrapped, fileKeyAAD)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	content, err := encryption.DecryptStream(body, fileKey)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{content, body}, nil
+}
+
+// Share shares a vault folder or file, wrapping the vault key for the
+// recipient's registered public key
+func (v *Vault) Share(ctx context.Context, req *ShareRequest) (*Share, error) {
+	recipient, err := v.client.PublicKey(ctx, req.SharedWithID, req.SharedWithEmail)
+	if err != nil {
+		return nil, fmt.Errorf("finding recipient's public key: %w", err)
+	}
+	if recipient.Algorithm != VaultKeyAlgorithm || len(recipient.PublicKey) != 32 {
+		return nil, fmt.Errorf("unsupported public key algorithm %q", recipient.Algorithm)
+	}
+	var public [32]byte
+	copy(public[:], recipient.PublicKey)
+	wrapped, err := box.SealAnonymous(nil, v.raw, &public, rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+
+	shared := *req
+	shared.WrappedKey = wrapped
+	return v.client.CreateShare(ctx, &shared)
+}
+
+// VaultStat resolves a path like Stat, decrypting names inside vaults on
+// the way, so paths can use the names the vault members see. It returns the
+// vault holding the entry, or nil if it is outside any vault. Vaults the
+// identity cannot open are skipped.
+func (c *Client) VaultStat(ctx context.Context, id *Identity, p string) (*Entry, *Vault, error) {
+	current, err := c.Root(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var vault *Vault
+	segments := splitPath(p)
+	for i, name := range segments {
+		last := i == len(segments)-1
+		opened := map[uint]*Vault{}
+		if vault == nil {
+			// Vault roots sit among plain folders with encrypted names
+			for j := range current.Folders {
+				folder := &current.Folders[j]
+				if folder.VaultID == nil {
+					continue
+				}
+				if v, err := c.OpenVault(ctx, id, folder.ID); err == nil && v.decryptFolder(folder) == nil {
+					opened[folder.ID] = v
+				}
+			}
+		}
+
+		if folder := findFolder(current.Folders, name); folder != nil {
+			if vault == nil && folder.VaultID != nil {
+				if vault = opened[folder.ID]; vault == nil {
+					return nil, nil, &pathError{path: p, err: ErrPathNotFound}
+				}
+			}
+			if last {
+				return &Entry{Folder: folder}, vault, nil
+			}
+			if current, err = c.Folder(ctx, folder.ID); err != nil {
+				return nil, nil, err
+			}
+			if vault != nil {
+				vault.decryptContents(current)
+			}
+			continue
+		}
+		if file := findFile(current.Files, name); file != nil && last {
+			return &Entry{File: file}, vault, nil
+		}
+		return nil, nil, &pathError{path: p, err: ErrPathNotFound}
+	}
+	return &Entry{Folder: current.Folder}, nil, nil
+}
+
+// decryptContents decrypts the names in a listing, leaving names that fail
+// to decrypt as they are
+func (v *Vault) decryptContents(contents *FolderContents) {
+	v.decryptFolder(contents.Folder)
+	for i := range contents.Folders {
+		v.decryptFolder(&contents.Folders[i])
+	}
+	for i := range contents.Files {
+		v.decryptFile(&contents.Files[i])
+	}
+}
+
+// decryptFolder replaces a folder's encrypted name with the plain one
+func (v *Vault) decryptFolder(folder *Folder) error {
+	name, err := v.DecryptName(folder.FolderName)
+	if err != nil {
+		return err
+	}
+	folder.FolderName = name
+	return nil
+}
+
+// decryptFile replaces a file's encrypted name with the plain one and its
+// size with that of the plaintext
+func (v *Vault) decryptFile(file *File) error {
+	name, err := v.DecryptName(file.FileName)
+	if err != nil {
+		return err
+	}
+	file.FileName = name
+	file.FileSize = plainSize(file.FileSize)
+	return nil
+}
+
+// plainSize returns the plaintext length of a vault blob of the given size
+func plainSize(size int64) int64 {
+	body := size - wrappedFileKeySize - encryption.SealedSize(0) + 16
+	sealedChunk := int64(encryption.ChunkSize + 16)
+	chunks, rem := body/sealedChunk, body%sealedChunk
+	if rem == 0 {
+		return chunks * encryption.ChunkSize
+	}
+	return max(0, chunks*encryption.ChunkSize+rem-16)
+}
+
+// validName reports whether a name can be used as a single path segment
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\\\x00")
+}
+
+// seal encrypts plaintext with a random nonce, which is prefixed
+func seal(aead cipher.AEAD, plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+// open decrypts data sealed with seal
+func open(aead cipher.AEAD, sealed, additionalData []byte) ([]byte, error) {
+	size := aead.NonceSize()
+	if len(sealed) < size {
+		return nil, ErrVaultDecrypt
+	}
+	plaintext, err := aead.Open(nil, sealed[:size], sealed[size:], additionalData)
+	if err != nil {
+		return nil, ErrVaultDecrypt
+	}
+	return plaintext, nil
+}
+
+// newGCM returns AES-256-GCM keyed with key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
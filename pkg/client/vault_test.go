@@ -0,0 +1,438 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"drive/internal/encryption"
+	"drive/internal/response"
+)
+
+// blobServer stores what vault clients upload without looking inside, as
+// the real server does, and lets tests tamper with the stored blobs
+type blobServer struct {
+	mu    sync.Mutex
+	ids   uint
+	keys  map[uint][]byte
+	names map[uint]string
+	blobs map[uint][]byte
+}
+
+func newBlobServer(t *testing.T) (*blobServer, *Client) {
+	t.Helper()
+	s := &blobServer{keys: make(map[uint][]byte), names: make(map[uint]string), blobs: make(map[uint][]byte)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/folders", s.createFolder)
+	mux.HandleFunc("GET /api/vault/keys/{id}", s.vaultKey)
+	mux.HandleFunc("POST /api/files", s.upload)
+	mux.HandleFunc("GET /api/files/{id}/download", s.download)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return s, New(server.URL, WithTokens(Tokens{AccessToken: "token"}))
+}
+
+func (s *blobServer) createFolder(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name       string `json:"name"`
+		WrappedKey []byte `json:"wrapped_key"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ids++
+	s.keys[s.ids] = req.WrappedKey
+	s.names[s.ids] = req.Name
+	response.JSON(w, http.StatusCreated, Folder{ID: s.ids, FolderName: req.Name, VaultID: &s.ids})
+}
+
+func (s *blobServer) vaultKey(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key, ok := s.keys[uint(id)]
+	if !ok {
+		response.NotFound(w, "vault key not found")
+		return
+	}
+	response.JSON(w, http.StatusOK, vaultKey{VaultID: uint(id), WrappedKey: key})
+}
+
+func (s *blobServer) upload(w http.ResponseWriter, r *http.Request) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		response.BadRequest(w, err.Error())
+		return
+	}
+	for {
+		part, err := reader.NextPart()
+		if err != nil {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		if part.FormName() != "file" {
+			continue
+		}
+		blob, err := io.ReadAll(part)
+		if err != nil {
+			response.BadRequest(w, err.Error())
+			return
+		}
+		s.mu.Lock()
+		s.ids++
+		s.blobs[s.ids] = blob
+		s.names[s.ids] = part.FileName()
+		file := File{ID: s.ids, FileName: part.FileName(), FileSize: int64(len(blob))}
+		s.mu.Unlock()
+		response.JSON(w, http.StatusCreated, file)
+		return
+	}
+}
+
+func (s *blobServer) download(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.ParseUint(r.PathValue("id"), 10, 64)
+	s.mu.Lock()
+	blob, ok := s.blobs[uint(id)]
+	s.mu.Unlock()
+	if !ok {
+		response.NotFound(w, "file not found")
+		return
+	}
+	w.Write(blob)
+}
+
+// blob returns a copy of a stored blob
+func (s *blobServer) blob(id uint) []byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return bytes.Clone(s.blobs[id])
+}
+
+// name returns the name an item was stored under
+func (s *blobServer) name(id uint) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.names[id]
+}
+
+func (s *blobServer) setBlob(id uint, blob []byte) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.blobs[id] = blob
+}
+
+func newIdentity(t *testing.T) *Identity {
+	t.Helper()
+	id, err := GenerateIdentity()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func newTestVault(t *testing.T, c *Client, id *Identity) *Vault {
+	t.Helper()
+	v, _, err := c.CreateVault(context.Background(), id, 0, "Legal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+// readAll downloads and decrypts a file in full
+func readAll(v *Vault, fileID uint) ([]byte, error) {
+	body, err := v.Download(context.Background(), fileID)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
+
+func TestVaultRoundTrip(t *testing.T) {
+	sizes := []int{0, 1, 100, encryption.ChunkSize - 1, encryption.ChunkSize, encryption.ChunkSize + 1, 3*encryption.ChunkSize + 7}
+
+	server, c := newBlobServer(t)
+	id := newIdentity(t)
+	v := newTestVault(t, c, id)
+	ctx := context.Background()
+
+	for _, size := range sizes {
+		t.Run(strconv.Itoa(size), func(t *testing.T) {
+			content := randomBytes(t, size)
+			file, err := v.Upload(ctx, 0, "contract.pdf", int64(size), bytes.NewReader(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if file.FileName != "contract.pdf" || file.FileSize != int64(size) {
+				t.Errorf("uploaded file = %q of %d bytes, want %q of %d", file.FileName, file.FileSize, "contract.pdf", size)
+			}
+
+			// The server sees neither the name nor the content
+			blob := server.blob(file.ID)
+			if got, want := int64(len(blob)), wrappedFileKeySize+encryption.SealedSize(int64(size)); got != want {
+				t.Errorf("blob is %d bytes, want %d", got, want)
+			}
+			if size >= 16 && bytes.Contains(blob, content[:16]) {
+				t.Error("blob contains plaintext")
+			}
+			if server.name(file.ID) == "contract.pdf" {
+				t.Error("name stored in the clear")
+			}
+
+			// Opened afresh from the wrapped key, as another session would
+			opened, err := c.OpenVault(ctx, id, v.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := readAll(opened, file.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, content) {
+				t.Errorf("downloaded %d bytes that differ from the %d uploaded", len(got), size)
+			}
+		})
+	}
+}
+
+func TestOpenVaultIdentity(t *testing.T) {
+	_, c := newBlobServer(t)
+	owner := newIdentity(t)
+	v := newTestVault(t, c, owner)
+	ctx := context.Background()
+
+	// A saved identity opens the vault
+	restored, err := ParseIdentity(owner.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.OpenVault(ctx, restored, v.ID); err != nil {
+		t.Errorf("restored identity: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		id   *Identity
+		want error
+	}{
+		{name: "other identity", id: newIdentity(t), want: ErrVaultDecrypt},
+		{name: "no identity", want: ErrNoIdentity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := c.OpenVault(ctx, tt.id, v.ID); !errors.Is(err, tt.want) {
+				t.Errorf("err = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseIdentityRejects(t *testing.T) {
+	for _, s := range []string{"", "not base64!", base64.StdEncoding.EncodeToString(make([]byte, 31))} {
+		if _, err := ParseIdentity(s); err == nil {
+			t.Errorf("ParseIdentity(%q) succeeded", s)
+		}
+	}
+}
+
+func TestVaultDownloadTampered(t *testing.T) {
+	const (
+		header     = wrappedFileKeySize
+		firstChunk = header + 8
+		chunk      = encryption.ChunkSize + 16
+	)
+	flip := func(offset int) func(blob []byte) []byte {
+		return func(blob []byte) []byte {
+			if offset < 0 {
+				offset += len(blob)
+			}
+			blob[offset] ^= 0x01
+			return blob
+		}
+	}
+	truncate := func(n int) func(blob []byte) []byte {
+		return func(blob []byte) []byte {
+			if n < 0 {
+				n += len(blob)
+			}
+			return blob[:n]
+		}
+	}
+
+	tests := []struct {
+		name   string
+		tamper func(blob []byte) []byte
+	}{
+		{name: "file key nonce flipped", tamper: flip(0)},
+		{name: "file key flipped", tamper: flip(20)},
+		{name: "file key tag flipped", tamper: flip(header - 1)},
+		{name: "stream header flipped", tamper: flip(header)},
+		{name: "chunk size flipped", tamper: flip(header + 7)},
+		{name: "first chunk flipped", tamper: flip(firstChunk + 10)},
+		{name: "first chunk tag flipped", tamper: flip(firstChunk + chunk - 1)},
+		{name: "last chunk flipped", tamper: flip(firstChunk + 2*chunk + 1)},
+		{name: "last tag flipped", tamper: flip(-1)},
+		{name: "empty", tamper: truncate(0)},
+		{name: "cut in file key", tamper: truncate(header / 2)},
+		{name: "cut after file key", tamper: truncate(header)},
+		{name: "cut after header", tamper: truncate(firstChunk)},
+		{name: "cut in first chunk", tamper: truncate(firstChunk + 100)},
+		{name: "last chunk dropped", tamper: truncate(firstChunk + 2*chunk)},
+		{name: "last byte dropped", tamper: truncate(-1)},
+		{name: "byte appended", tamper: func(blob []byte) []byte { return append(blob, 0) }},
+		{name: "chunks swapped", tamper: func(blob []byte) []byte {
+			first := bytes.Clone(blob[firstChunk : firstChunk+chunk])
+			copy(blob[firstChunk:], blob[firstChunk+chunk:firstChunk+2*chunk])
+			copy(blob[firstChunk+chunk:], first)
+			return blob
+		}},
+	}
+
+	server, c := newBlobServer(t)
+	v := newTestVault(t, c, newIdentity(t))
+	ctx := context.Background()
+	content := randomBytes(t, 2*encryption.ChunkSize+100)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := v.Upload(ctx, 0, "evidence.bin", int64(len(content)), bytes.NewReader(content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			server.setBlob(file.ID, tt.tamper(server.blob(file.ID)))
+
+			got, err := readAll(v, file.ID)
+			if err == nil {
+				t.Fatalf("tampered blob decrypted to %d bytes without an error", len(got))
+			}
+			if !errors.Is(err, ErrVaultDecrypt) && !errors.Is(err, encryption.ErrCorrupt) {
+				t.Errorf("err = %v, want a decryption error", err)
+			}
+		})
+	}
+}
+
+func TestVaultDownloadWrongKey(t *testing.T) {
+	server, c := newBlobServer(t)
+	id := newIdentity(t)
+	v := newTestVault(t, c, id)
+	other := newTestVault(t, c, id)
+	ctx := context.Background()
+
+	a, err := v.Upload(ctx, 0, "a.txt", 5, bytes.NewReader([]byte("alpha")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := v.Upload(ctx, 0, "b.txt", 4, bytes.NewReader([]byte("beta")))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A file from another vault
+	if _, err := readAll(other, a.ID); !errors.Is(err, ErrVaultDecrypt) {
+		t.Errorf("other vault: err = %v, want %v", err, ErrVaultDecrypt)
+	}
+
+	// One file's content behind another file's key
+	spliced := append(server.blob(a.ID)[:wrappedFileKeySize], server.blob(b.ID)[wrappedFileKeySize:]...)
+	server.setBlob(a.ID, spliced)
+	if _, err := readAll(v, a.ID); !errors.Is(err, encryption.ErrCorrupt) {
+		t.Errorf("spliced blob: err = %v, want %v", err, encryption.ErrCorrupt)
+	}
+}
+
+func TestVaultNames(t *testing.T) {
+	_, c := newBlobServer(t)
+	id := newIdentity(t)
+	v := newTestVault(t, c, id)
+	other := newTestVault(t, c, id)
+
+	sealed := func(name string, aad []byte) string {
+		b, err := seal(v.key, []byte(name), aad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(b)
+	}
+	encrypted, err := v.EncryptName("Übersicht 2026.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, _ := base64.RawURLEncoding.DecodeString(encrypted)
+	flipped := bytes.Clone(raw)
+	flipped[len(flipped)/2] ^= 0x80
+
+	tests := []struct {
+		name      string
+		vault     *Vault
+		encrypted string
+		want      string
+		err       error
+	}{
+		{name: "round trip", vault: v, encrypted: encrypted, want: "Übersicht 2026.xlsx"},
+		{name: "other vault", vault: other, encrypted: encrypted, err: ErrVaultDecrypt},
+		{name: "bit flipped", vault: v, encrypted: base64.RawURLEncoding.EncodeToString(flipped), err: ErrVaultDecrypt},
+		{name: "truncated", vault: v, encrypted: base64.RawURLEncoding.EncodeToString(raw[:len(raw)-1]), err: ErrVaultDecrypt},
+		{name: "shorter than a nonce", vault: v, encrypted: base64.RawURLEncoding.EncodeToString(raw[:5]), err: ErrVaultDecrypt},
+		{name: "plain name", vault: v, encrypted: "notes.txt", err: ErrVaultDecrypt},
+		{name: "not base64", vault: v, encrypted: "not base64!", err: ErrVaultDecrypt},
+		{name: "file key passed off as a name", vault: v, encrypted: sealed("notes.txt", fileKeyAAD), err: ErrVaultDecrypt},
+		{name: "path traversal", vault: v, encrypted: sealed("..", nameAAD), err: ErrVaultDecrypt},
+		{name: "separator", vault: v, encrypted: sealed("a/b", nameAAD), err: ErrVaultDecrypt},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.vault.DecryptName(tt.encrypted)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("name = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// Every encryption uses a fresh nonce
+	again, err := v.EncryptName("Übersicht 2026.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == encrypted {
+		t.Error("encrypting a name twice gave the same ciphertext")
+	}
+	for _, name := range []string{"", ".", "..", "a/b", `a\b`, "a\x00b"} {
+		if _, err := v.EncryptName(name); err == nil {
+			t.Errorf("EncryptName(%q) succeeded", name)
+		}
+	}
+}
+
+func TestPlainSize(t *testing.T) {
+	for _, size := range []int64{0, 1, encryption.ChunkSize - 1, encryption.ChunkSize, encryption.ChunkSize + 1, 5*encryption.ChunkSize + 3} {
+		if got := plainSize(wrappedFileKeySize + encryption.SealedSize(size)); got != size {
+			t.Errorf("plainSize of a %d byte file = %d", size, got)
+		}
+	}
+}